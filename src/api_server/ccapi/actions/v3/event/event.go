@@ -95,6 +95,101 @@ func (cli *procAction) Telnet(req *restful.Request, resp *restful.Response) {
 	io.WriteString(resp, rsp)
 }
 
+// search dead letters
+func (cli *procAction) SearchDeadLetter(req *restful.Request, resp *restful.Response) {
+	blog.Info("search dead letter")
+	pathParams := req.PathParameters()
+	ownerID := pathParams["owner_id"]
+	appID := pathParams["app_id"]
+	subscribeID := pathParams["subscribe_id"]
+	url := cli.CC.EventAPI() + "/event/v1/subscribe/" + ownerID + "/" + appID + "/" + subscribeID + "/deadletter/search"
+	rsp, _ := httpcli.ReqForward(req, url, common.HTTPSelectPost)
+	io.WriteString(resp, rsp)
+}
+
+// replay dead letters
+func (cli *procAction) ReplayDeadLetter(req *restful.Request, resp *restful.Response) {
+	blog.Info("replay dead letter")
+	pathParams := req.PathParameters()
+	ownerID := pathParams["owner_id"]
+	appID := pathParams["app_id"]
+	subscribeID := pathParams["subscribe_id"]
+	url := cli.CC.EventAPI() + "/event/v1/subscribe/" + ownerID + "/" + appID + "/" + subscribeID + "/deadletter/replay"
+	rsp, _ := httpcli.ReqForward(req, url, common.HTTPUpdate)
+	io.WriteString(resp, rsp)
+}
+
+// purge dead letters
+func (cli *procAction) PurgeDeadLetter(req *restful.Request, resp *restful.Response) {
+	blog.Info("purge dead letter")
+	pathParams := req.PathParameters()
+	ownerID := pathParams["owner_id"]
+	appID := pathParams["app_id"]
+	subscribeID := pathParams["subscribe_id"]
+	url := cli.CC.EventAPI() + "/event/v1/subscribe/" + ownerID + "/" + appID + "/" + subscribeID + "/deadletter"
+	rsp, _ := httpcli.ReqForward(req, url, common.HTTPDelete)
+	io.WriteString(resp, rsp)
+}
+
+// replay persisted events
+func (cli *procAction) Replay(req *restful.Request, resp *restful.Response) {
+	blog.Info("replay events")
+	pathParams := req.PathParameters()
+	ownerID := pathParams["owner_id"]
+	appID := pathParams["app_id"]
+	subscribeID := pathParams["subscribe_id"]
+	url := cli.CC.EventAPI() + "/event/v1/subscribe/" + ownerID + "/" + appID + "/" + subscribeID + "/replay"
+	rsp, _ := httpcli.ReqForward(req, url, common.HTTPCreate)
+	io.WriteString(resp, rsp)
+}
+
+// search delivery history
+func (cli *procAction) SearchDelivery(req *restful.Request, resp *restful.Response) {
+	blog.Info("search delivery")
+	pathParams := req.PathParameters()
+	ownerID := pathParams["owner_id"]
+	appID := pathParams["app_id"]
+	subscribeID := pathParams["subscribe_id"]
+	url := cli.CC.EventAPI() + "/event/v1/subscribe/" + ownerID + "/" + appID + "/" + subscribeID + "/deliveries"
+	rsp, _ := httpcli.ReqForward(req, url, common.HTTPSelectPost)
+	io.WriteString(resp, rsp)
+}
+
+// pause subscription
+func (cli *procAction) Pause(req *restful.Request, resp *restful.Response) {
+	blog.Info("pause subscription")
+	pathParams := req.PathParameters()
+	ownerID := pathParams["owner_id"]
+	appID := pathParams["app_id"]
+	subscribeID := pathParams["subscribe_id"]
+	url := cli.CC.EventAPI() + "/event/v1/subscribe/" + ownerID + "/" + appID + "/" + subscribeID + "/pause"
+	rsp, _ := httpcli.ReqForward(req, url, common.HTTPUpdate)
+	io.WriteString(resp, rsp)
+}
+
+// resume subscription
+func (cli *procAction) Resume(req *restful.Request, resp *restful.Response) {
+	blog.Info("resume subscription")
+	pathParams := req.PathParameters()
+	ownerID := pathParams["owner_id"]
+	appID := pathParams["app_id"]
+	subscribeID := pathParams["subscribe_id"]
+	url := cli.CC.EventAPI() + "/event/v1/subscribe/" + ownerID + "/" + appID + "/" + subscribeID + "/resume"
+	rsp, _ := httpcli.ReqForward(req, url, common.HTTPUpdate)
+	io.WriteString(resp, rsp)
+}
+
+// watch events
+func (cli *procAction) Watch(req *restful.Request, resp *restful.Response) {
+	blog.Info("watch events")
+	pathParams := req.PathParameters()
+	ownerID := pathParams["owner_id"]
+	appID := pathParams["app_id"]
+	url := cli.CC.EventAPI() + "/event/v1/watch/" + ownerID + "/" + appID
+	rsp, _ := httpcli.ReqForward(req, url, common.HTTPSelectPost)
+	io.WriteString(resp, rsp)
+}
+
 func init() {
 
 	actions.RegisterNewAction(actions.Action{Verb: common.HTTPSelectPost, Path: "/event/subscribe/ping", Params: nil, Handler: event.Ping, FilterHandler: nil, Version: v3.APIVersion})
@@ -103,6 +198,14 @@ func init() {
 	actions.RegisterNewAction(actions.Action{Verb: common.HTTPCreate, Path: "/event/subscribe/{owner_id}/{app_id}", Params: nil, Handler: event.Subscribe, FilterHandler: nil, Version: v3.APIVersion})
 	actions.RegisterNewAction(actions.Action{Verb: common.HTTPDelete, Path: "/event/subscribe/{owner_id}/{app_id}/{subscribe_id}", Params: nil, Handler: event.UnSubscribe, FilterHandler: nil, Version: v3.APIVersion})
 	actions.RegisterNewAction(actions.Action{Verb: common.HTTPUpdate, Path: "/event/subscribe/{owner_id}/{app_id}/{subscribe_id}", Params: nil, Handler: event.Rebook, FilterHandler: nil, Version: v3.APIVersion})
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPSelectPost, Path: "/event/subscribe/{owner_id}/{app_id}/{subscribe_id}/deadletter/search", Params: nil, Handler: event.SearchDeadLetter, FilterHandler: nil, Version: v3.APIVersion})
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPUpdate, Path: "/event/subscribe/{owner_id}/{app_id}/{subscribe_id}/deadletter/replay", Params: nil, Handler: event.ReplayDeadLetter, FilterHandler: nil, Version: v3.APIVersion})
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPDelete, Path: "/event/subscribe/{owner_id}/{app_id}/{subscribe_id}/deadletter", Params: nil, Handler: event.PurgeDeadLetter, FilterHandler: nil, Version: v3.APIVersion})
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPCreate, Path: "/event/subscribe/{owner_id}/{app_id}/{subscribe_id}/replay", Params: nil, Handler: event.Replay, FilterHandler: nil, Version: v3.APIVersion})
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPSelectPost, Path: "/event/subscribe/{owner_id}/{app_id}/{subscribe_id}/deliveries", Params: nil, Handler: event.SearchDelivery, FilterHandler: nil, Version: v3.APIVersion})
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPUpdate, Path: "/event/subscribe/{owner_id}/{app_id}/{subscribe_id}/pause", Params: nil, Handler: event.Pause, FilterHandler: nil, Version: v3.APIVersion})
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPUpdate, Path: "/event/subscribe/{owner_id}/{app_id}/{subscribe_id}/resume", Params: nil, Handler: event.Resume, FilterHandler: nil, Version: v3.APIVersion})
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPSelectPost, Path: "/event/watch/{owner_id}/{app_id}", Params: nil, Handler: event.Watch, FilterHandler: nil, Version: v3.APIVersion})
 	// set cc api interface
 	event.CreateAction()
 }
@@ -20,6 +20,7 @@ import (
 	"configcenter/src/common/core/cc/actions"
 	"configcenter/src/common/http/httpclient"
 	"configcenter/src/scene_server/api"
+	"io"
 
 	"github.com/emicklei/go-restful"
 )
@@ -40,6 +41,9 @@ func init() {
 	actions.RegisterNewAction(actions.Action{Verb: common.HTTPUpdate, Path: "/object/{id}", Params: nil, Handler: obj.UpdateObject, Version: v3.APIVersion})
 	actions.RegisterNewAction(actions.Action{Verb: common.HTTPSelectPost, Path: "/objects", Params: nil, Handler: obj.SelectObjectWithParams, Version: v3.APIVersion})
 	actions.RegisterNewAction(actions.Action{Verb: common.HTTPSelectPost, Path: "/objects/topo", Params: nil, Handler: obj.SelectObjectTopo, Version: v3.APIVersion})
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPSelectPost, Path: "/object/bundle/export", Params: nil, Handler: obj.ExportModelBundle, Version: v3.APIVersion})
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPCreate, Path: "/object/bundle/import", Params: nil, Handler: obj.ImportModelBundle, Version: v3.APIVersion})
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPSelectPost, Path: "/object/impact", Params: nil, Handler: obj.AnalyzeImpact, Version: v3.APIVersion})
 
 	// init
 	obj.CreateAction()
@@ -95,15 +99,12 @@ func (cli *objectAction) DeleteObject(req *restful.Request, resp *restful.Respon
 
 	blog.Info("delete object")
 
-	id := req.PathParameter("id")
-	senceCLI := api.NewClient(module.CC.TopoAPI())
-
-	cli.CallResponse(
-		senceCLI.ReForwardDeleteMetaObject(func(url, method string) (string, error) {
-			return httpclient.ReqForward(req, url, method)
-		}, id),
-		resp)
-
+	// the query parameter impact_token is passed on, the impact is returned along with the unconfirmed deletion
+	url := cli.CC.TopoAPI() + "/topo/v1/object/" + req.PathParameter("id")
+	if "" != req.Request.URL.RawQuery {
+		url += "?" + req.Request.URL.RawQuery
+	}
+	forwardRaw(&cli.BaseAction, req, resp, url, common.HTTPDelete)
 }
 
 // UpdateObject update some object information
@@ -150,3 +151,42 @@ func (cli *objectAction) SelectObjectWithParams(req *restful.Request, resp *rest
 		resp)
 
 }
+
+// ExportModelBundle export the models as a bundle
+func (cli *objectAction) ExportModelBundle(req *restful.Request, resp *restful.Response) {
+	blog.Info("export model bundle")
+	url := cli.CC.TopoAPI() + "/topo/v1/object/bundle/export"
+	if "" != req.Request.URL.RawQuery {
+		url += "?" + req.Request.URL.RawQuery
+	}
+	forwardRaw(&cli.BaseAction, req, resp, url, common.HTTPSelectPost)
+}
+
+// ImportModelBundle import the models of a bundle
+func (cli *objectAction) ImportModelBundle(req *restful.Request, resp *restful.Response) {
+	blog.Info("import model bundle")
+	url := cli.CC.TopoAPI() + "/topo/v1/object/bundle/import"
+	if "" != req.Request.URL.RawQuery {
+		url += "?" + req.Request.URL.RawQuery
+	}
+	forwardRaw(&cli.BaseAction, req, resp, url, common.HTTPCreate)
+}
+
+// AnalyzeImpact analyze the impact of deleting an object or an attribute
+func (cli *objectAction) AnalyzeImpact(req *restful.Request, resp *restful.Response) {
+	blog.Info("analyze the impact of the deletion")
+	url := cli.CC.TopoAPI() + "/topo/v1/object/impact"
+	forwardRaw(&cli.BaseAction, req, resp, url, common.HTTPSelectPost)
+}
+
+// forwardRaw forwards the request and writes the reply as it is, the data of a failed reply is kept for the
+// caller, the failure of the request itself is reported as the CallResponse does
+func forwardRaw(cli *base.BaseAction, req *restful.Request, resp *restful.Response, url, method string) {
+	rsp, err := httpclient.ReqForward(req, url, method)
+	if nil != err {
+		blog.Error("request failed, error:%v", err)
+		cli.ResponseFailed(common.CC_Err_Comm_http_DO, err.Error(), resp)
+		return
+	}
+	io.WriteString(resp, rsp)
+}
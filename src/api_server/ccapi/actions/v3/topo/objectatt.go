@@ -37,6 +37,7 @@ func init() {
 	actions.RegisterNewAction(actions.Action{Verb: common.HTTPDelete, Path: "/object/attr/{attr_id}", Params: nil, Handler: objatt.DeleteObjectAtt, Version: v3.APIVersion})
 	actions.RegisterNewAction(actions.Action{Verb: common.HTTPUpdate, Path: "/object/attr/{attr_id}", Params: nil, Handler: objatt.UpdateObjectAtt, Version: v3.APIVersion})
 	actions.RegisterNewAction(actions.Action{Verb: common.HTTPSelectPost, Path: "/object/attr/search", Params: nil, Handler: objatt.SelectObjectAttWithParams, Version: v3.APIVersion})
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPSelectGet, Path: "/object/attr/computed/{bk_obj_id}", Params: nil, Handler: objatt.SelectComputedBackfill, Version: v3.APIVersion})
 
 	// init
 	objatt.CreateAction()
@@ -60,14 +61,12 @@ func (cli *objectAttAction) DeleteObjectAtt(req *restful.Request, resp *restful.
 
 	blog.Info("delete objectatt")
 
-	attrID := req.PathParameter("attr_id")
-	senceCLI := api.NewClient(module.CC.TopoAPI())
-	cli.CallResponse(
-		senceCLI.ReForwardDeleteMetaObjAtt(func(url, method string) (string, error) {
-			return httpclient.ReqForward(req, url, method)
-		}, attrID),
-		resp)
-
+	// the query parameter impact_token is passed on, the impact is returned along with the unconfirmed deletion
+	url := cli.CC.TopoAPI() + "/topo/v1/objectattr/" + req.PathParameter("attr_id")
+	if "" != req.Request.URL.RawQuery {
+		url += "?" + req.Request.URL.RawQuery
+	}
+	forwardRaw(&cli.BaseAction, req, resp, url, common.HTTPDelete)
 }
 
 // UpdateObjectAtt update some object's attributes
@@ -97,3 +96,12 @@ func (cli *objectAttAction) SelectObjectAttWithParams(req *restful.Request, resp
 		}), resp)
 
 }
+
+// SelectComputedBackfill search the progress of computing the instances of the object again
+func (cli *objectAttAction) SelectComputedBackfill(req *restful.Request, resp *restful.Response) {
+
+	blog.Info("select the progress of computing the instances")
+
+	url := cli.CC.TopoAPI() + "/topo/v1/objectattr/computed/" + req.PathParameter("bk_obj_id")
+	forwardRaw(&cli.BaseAction, req, resp, url, common.HTTPSelectGet)
+}
@@ -65,6 +65,10 @@ func (cc *cliResource) GetDataCli(config map[string]string, dType string) error
 	if err != nil {
 		return err
 	}
+	err = dbcli.Configure(dataCli, config, dType)
+	if err != nil {
+		return err
+	}
 	err = dataCli.Open()
 	if err != nil {
 		return err
@@ -78,6 +78,9 @@ type APIResource struct {
 	EventAPI     func() string
 	APIAddr      func() string
 	AddrSrv      AddrSrv
+
+	// SearchReadPreference the read preference of the searches which tolerate the replication lag
+	SearchReadPreference storage.ReadPreference
 }
 
 // AddrSrv get server address interface
@@ -163,7 +166,16 @@ func (a *APIResource) GetDataCli(config map[string]string, dType string) error {
 	pwd := config[dType+".pwd"]
 	dbName := config[dType+".database"]
 	mechanism := config[dType+".mechanism"]
-	dataCli, err := dbclient.NewDB(host, port, user, pwd, mechanism, dbName, dType)
+	// driver replaces the storage of the section, sqlite3 for example
+	driver := config[dType+".driver"]
+	if "" == driver {
+		driver = dType
+	}
+	dataCli, err := dbclient.NewDB(host, port, user, pwd, mechanism, dbName, driver)
+	if err != nil {
+		return err
+	}
+	err = dbclient.Configure(dataCli, config, dType)
 	if err != nil {
 		return err
 	}
@@ -171,12 +183,22 @@ func (a *APIResource) GetDataCli(config map[string]string, dType string) error {
 	if err != nil {
 		return err
 	}
+	if dType != storage.DI_REDIS {
+		dataCli, err = dbclient.Instrument(dataCli, config, dType)
+		if err != nil {
+			return err
+		}
+	}
 	if dType == storage.DI_MYSQL {
 		a.MetaCli = dataCli
 	} else if dType == storage.DI_REDIS {
 		a.CacheCli = dataCli
 	} else {
 		a.InstCli = dataCli
+		a.SearchReadPreference, err = storage.ParseReadPreference(config[dType+".searchReadPreference"])
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
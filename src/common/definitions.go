@@ -104,6 +104,9 @@ const (
 	// BKDBOR the db operator
 	BKDBOR = "$or"
 
+	// BKDBAND the db operator
+	BKDBAND = "$and"
+
 	// BKDBLIKE the db operator
 	BKDBLIKE = "$regex"
 
@@ -112,6 +115,27 @@ const (
 
 	// BKDBNE the db operator
 	BKDBNE = "$ne"
+
+	// BKDBNIN the db operator
+	BKDBNIN = "$nin"
+
+	// BKDBExists the db operator
+	BKDBExists = "$exists"
+
+	// BKDBGT the db operator
+	BKDBGT = "$gt"
+
+	// BKDBGTE the db operator
+	BKDBGTE = "$gte"
+
+	// BKDBLT the db operator
+	BKDBLT = "$lt"
+
+	// BKDBLTE the db operator
+	BKDBLTE = "$lte"
+
+	// BKDBALL the db operator
+	BKDBALL = "$all"
 )
 
 const (
@@ -459,6 +483,33 @@ const (
 	// FiledTypeBool the bool type
 	FiledTypeBool string = "bool"
 
+	// FieldTypeComputed the computed field type, the value is computed from the other fields on write
+	FieldTypeComputed string = "computed"
+
+	// FieldTypeFloat the float field type
+	FieldTypeFloat string = "float"
+
+	// FieldTypeIPv4 the ipv4 address field type
+	FieldTypeIPv4 string = "ipv4"
+
+	// FieldTypeIPv6 the ipv6 address field type
+	FieldTypeIPv6 string = "ipv6"
+
+	// FieldTypeCIDR the network field type in cidr notation
+	FieldTypeCIDR string = "cidr"
+
+	// FieldTypeURL the url field type
+	FieldTypeURL string = "url"
+
+	// FieldTypeEmail the email address field type
+	FieldTypeEmail string = "email"
+
+	// FieldTypeList the field type of the list of scalars
+	FieldTypeList string = "list"
+
+	// FieldTypeJSON the json object field type
+	FieldTypeJSON string = "json"
+
 	// FiledTypeSingleCharName the single char data type name
 	FiledTypeSingleCharName string = "短字符"
 
@@ -492,6 +543,33 @@ const (
 	// FiledTypeBoolName the bool data type name
 	FiledTypeBoolName string = "布尔"
 
+	// FieldTypeComputedName the computed data type name
+	FieldTypeComputedName string = "计算"
+
+	// FieldTypeFloatName the float data type name
+	FieldTypeFloatName string = "浮点"
+
+	// FieldTypeIPv4Name the ipv4 address data type name
+	FieldTypeIPv4Name string = "IPv4地址"
+
+	// FieldTypeIPv6Name the ipv6 address data type name
+	FieldTypeIPv6Name string = "IPv6地址"
+
+	// FieldTypeCIDRName the network data type name
+	FieldTypeCIDRName string = "网段"
+
+	// FieldTypeURLName the url data type name
+	FieldTypeURLName string = "网址"
+
+	// FieldTypeEmailName the email address data type name
+	FieldTypeEmailName string = "邮箱"
+
+	// FieldTypeListName the list data type name
+	FieldTypeListName string = "列表"
+
+	// FieldTypeJSONName the json object data type name
+	FieldTypeJSONName string = "JSON对象"
+
 	// FiledTypeSingleLenChar the single char length limit
 	FiledTypeSingleLenChar int = 48
 
@@ -539,6 +617,18 @@ const (
 
 	// BKTableNameObjClassifiction the table name of the object classification
 	BKTableNameObjClassifiction = "cc_ObjClassification"
+
+	// BKTableNameUserAPI the table name of the saved user api
+	BKTableNameUserAPI = "cc_UserAPI"
+
+	// BKTableNameHostFavorite the table name of the host favorite
+	BKTableNameHostFavorite = "cc_HostFavourite"
+
+	// BKTableNameUserCustom the table name of the user custom
+	BKTableNameUserCustom = "cc_UserCustom"
+
+	// BKTableNameSubscription the table name of the event subscription
+	BKTableNameSubscription = "cc_Subscription"
 )
 
 const (
@@ -589,4 +679,8 @@ const (
 	// BKHTTPOwnerID the owner id
 	BKHTTPOwnerID = "HTTP_BLUEKING_SUPPLIER_ID"
 	//BKHTTPOwnerID = "HTTP_BLUEKING_OWNERID"
+	// BKHTTPTxnID the transaction id, the storage operations of the request join the transaction
+	BKHTTPTxnID = "HTTP_BLUEKING_TXN_ID"
+	// BKHTTPTolerateLag the read only caller sets it to true when the search may miss the latest writes
+	BKHTTPTolerateLag = "HTTP_BLUEKING_TOLERATE_LAG"
 )
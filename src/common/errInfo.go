@@ -124,6 +124,21 @@ const (
 	// CCErrCommParams should be string
 	CCErrCommParamsShouldBeString = 1199032
 
+	// CCErrCommTxnBeginFailed failed to begin the transaction
+	CCErrCommTxnBeginFailed = 1199033
+
+	// CCErrCommTxnCommitFailed failed to commit the transaction
+	CCErrCommTxnCommitFailed = 1199034
+
+	// CCErrCommTxnAbortFailed failed to abort the transaction
+	CCErrCommTxnAbortFailed = 1199035
+
+	// CCErrCommTxnNotFound the transaction is finished or expired
+	CCErrCommTxnNotFound = 1199036
+
+	// CCErrCommValidationRuleFailed the data breaks the validation rule of the model
+	CCErrCommValidationRuleFailed = 1199037
+
 	// apiserver 1100XXX
 
 	// toposerver 1101XXX
@@ -244,6 +259,27 @@ const (
 	CCErrTopoMulueIDNotfoundFailed = 1101080
 	CCErrTopoBkAppNotAllowedDelete = 1101081
 
+	// CCErrTopoModelBundleExportFailed failed to export the model bundle
+	CCErrTopoModelBundleExportFailed = 1101082
+	// CCErrTopoModelBundleImportFailed failed to import the model bundle
+	CCErrTopoModelBundleImportFailed = 1101083
+	// CCErrTopoModelBundleInvalid the model bundle is invalid
+	CCErrTopoModelBundleInvalid = 1101084
+
+	// CCErrTopoDeleteImpactFailed failed to analyze the impact of the deletion
+	CCErrTopoDeleteImpactFailed = 1101085
+	// CCErrTopoDeleteNotConfirmed the deletion is not confirmed by the token of the current impact
+	CCErrTopoDeleteNotConfirmed = 1101086
+
+	// CCErrTopoComputedAttributeInvalid the computed attribute is invalid
+	CCErrTopoComputedAttributeInvalid = 1101087
+	// CCErrTopoComputedValueFailed failed to compute the value of the computed attribute
+	CCErrTopoComputedValueFailed = 1101088
+	// CCErrTopoAttributeOptionInvalid the option does not match the attribute type
+	CCErrTopoAttributeOptionInvalid = 1101089
+	// CCErrTopoValidationRuleInvalid the validation rule of the model is invalid
+	CCErrTopoValidationRuleInvalid = 1101090
+
 	// objectcontroller 1102XXX
 
 	// CCErrObjectPropertyGroupInsertFailed failed to save the property group
@@ -279,6 +315,36 @@ const (
 	// CCErrEventSubscribePingFailed failed to ping the filed
 	CCErrEventSubscribePingFailed = 1103004
 
+	// CCErrEventDeadLetterSelectFailed failed to select the dead letters
+	CCErrEventDeadLetterSelectFailed = 1103010
+
+	// CCErrEventDeadLetterReplayFailed failed to replay the dead letters
+	CCErrEventDeadLetterReplayFailed = 1103011
+
+	// CCErrEventDeadLetterPurgeFailed failed to purge the dead letters
+	CCErrEventDeadLetterPurgeFailed = 1103012
+
+	// CCErrEventWatchFailed failed to watch the events
+	CCErrEventWatchFailed = 1103013
+
+	// CCErrEventWatchCursorExpired the events after the watch cursor are no longer retained
+	CCErrEventWatchCursorExpired = 1103014
+
+	// CCErrEventReplayFailed failed to replay the events
+	CCErrEventReplayFailed = 1103015
+
+	// CCErrEventDeliverySelectFailed failed to search the delivery history
+	CCErrEventDeliverySelectFailed = 1103016
+
+	// CCErrEventSubscribePauseFailed failed to pause the subscription
+	CCErrEventSubscribePauseFailed = 1103017
+
+	// CCErrEventSubscribeResumeFailed failed to resume the subscription
+	CCErrEventSubscribeResumeFailed = 1103018
+
+	// CCErrEventReplayRunning the last replay of the subscription is still running
+	CCErrEventReplayRunning = 1103019
+
 	// host 1104XXX
 	CCErrHostModuleRelationAddFailed = 1104000
 
@@ -0,0 +1,207 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fieldtype
+
+import (
+	"configcenter/src/common"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	// OperatorWithin the operator of the ipv4 fields matching the addresses in one of the networks
+	OperatorWithin = "$within"
+
+	// OperatorContains the operator of the cidr fields matching the networks containing the address
+	OperatorContains = "$contains"
+)
+
+// the operators the field types can be searched by
+var operators = map[string][]string{
+	common.FieldTypeFloat: {common.BKDBEQ, common.BKDBNE, common.BKDBGT, common.BKDBGTE, common.BKDBLT, common.BKDBLTE, common.BKDBIN, common.BKDBNIN},
+	common.FieldTypeIPv4:  {common.BKDBEQ, common.BKDBNE, common.BKDBIN, common.BKDBNIN, OperatorWithin},
+	common.FieldTypeIPv6:  {common.BKDBEQ, common.BKDBNE, common.BKDBIN, common.BKDBNIN},
+	common.FieldTypeCIDR:  {common.BKDBEQ, common.BKDBNE, common.BKDBIN, common.BKDBNIN, OperatorContains},
+	common.FieldTypeURL:   {common.BKDBEQ, common.BKDBNE, common.BKDBIN, common.BKDBNIN, common.BKDBLIKE},
+	common.FieldTypeEmail: {common.BKDBEQ, common.BKDBNE, common.BKDBIN, common.BKDBNIN, common.BKDBLIKE},
+	common.FieldTypeList:  {common.BKDBEQ, common.BKDBNE, common.BKDBIN, common.BKDBNIN, common.BKDBALL},
+	common.FieldTypeJSON:  {common.BKDBExists},
+}
+
+// Condition returns the condition on the field of the type by the operator, the condition of $eq is the value
+// itself, the values are normalized like the stored ones but not checked against the option, the list fields
+// are matched by their items, $eq and $ne take an item and $in, $nin and $all take the items
+func Condition(fieldType, option, operator string, value interface{}) (interface{}, error) {
+	allowed := false
+	for _, item := range operators[fieldType] {
+		if item == operator {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, fmt.Errorf("the %s field can not be searched by %s", fieldType, operator)
+	}
+
+	single := func(val interface{}) (interface{}, error) {
+		switch fieldType {
+		case common.FieldTypeFloat:
+			return toFloat(val)
+		case common.FieldTypeList:
+			opt := ListOption{}
+			if err := parseOption(option, &opt); nil != err {
+				return nil, err
+			}
+			if "" == opt.ItemType {
+				opt.ItemType = common.FiledTypeSingleChar
+			}
+			return normalizeItem(opt.ItemType, val)
+		}
+		return Normalize(fieldType, "", val)
+	}
+
+	switch operator {
+	case common.BKDBEQ:
+		return single(value)
+	case common.BKDBNE, common.BKDBGT, common.BKDBGTE, common.BKDBLT, common.BKDBLTE:
+		val, err := single(value)
+		if nil != err {
+			return nil, err
+		}
+		return map[string]interface{}{operator: val}, nil
+	case common.BKDBIN, common.BKDBNIN, common.BKDBALL:
+		items, err := toSlice(value)
+		if nil != err {
+			return nil, err
+		}
+		vals := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			val, err := single(item)
+			if nil != err {
+				return nil, err
+			}
+			vals = append(vals, val)
+		}
+		return map[string]interface{}{operator: vals}, nil
+	case common.BKDBLIKE:
+		if _, ok := value.(string); !ok {
+			return nil, fmt.Errorf("the pattern %v should be a string", value)
+		}
+		return map[string]interface{}{operator: value}, nil
+	case common.BKDBExists:
+		if _, ok := value.(bool); !ok {
+			return nil, fmt.Errorf("the value of %s should be a bool", operator)
+		}
+		return map[string]interface{}{operator: value}, nil
+	case OperatorWithin:
+		return withinCondition(value)
+	case OperatorContains:
+		return containsCondition(value)
+	}
+	return nil, fmt.Errorf("the %s field can not be searched by %s", fieldType, operator)
+}
+
+func toSlice(value interface{}) ([]interface{}, error) {
+	rv := reflect.ValueOf(value)
+	if reflect.Slice != rv.Kind() && reflect.Array != rv.Kind() {
+		return nil, fmt.Errorf("%v should be an array", value)
+	}
+	items := make([]interface{}, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		items = append(items, rv.Index(i).Interface())
+	}
+	return items, nil
+}
+
+// withinCondition matches the ipv4 addresses in one of the networks, the value is a network or the networks,
+// the stored addresses are in the canonical form so the networks are matched by the pattern of their addresses
+func withinCondition(value interface{}) (interface{}, error) {
+	items := []interface{}{value}
+	if _, ok := value.(string); !ok {
+		var err error
+		if items, err = toSlice(value); nil != err {
+			return nil, err
+		}
+	}
+	patterns := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("the network %v should be a string", item)
+		}
+		network, err := parseNetwork(s)
+		if nil != err {
+			return nil, err
+		}
+		if nil == network.IP.To4() {
+			return nil, fmt.Errorf("the network %s is not an ipv4 network", s)
+		}
+		patterns = append(patterns, ipv4Pattern(network))
+	}
+	if 0 == len(patterns) {
+		return nil, fmt.Errorf("no network to search within")
+	}
+	return map[string]interface{}{common.BKDBLIKE: "^(" + strings.Join(patterns, "|") + ")$"}, nil
+}
+
+// ipv4Pattern returns the pattern of the addresses in the ipv4 network, the octet the prefix ends in matches
+// the numbers the network covers
+func ipv4Pattern(network *net.IPNet) string {
+	ones, _ := network.Mask.Size()
+	ip := network.IP.To4()
+	octets := make([]string, 4)
+	for i := range octets {
+		bits := ones - 8*i
+		switch {
+		case 8 <= bits:
+			octets[i] = strconv.Itoa(int(ip[i]))
+		case 0 >= bits:
+			octets[i] = `\d{1,3}`
+		default:
+			low := int(ip[i])
+			high := low | (0xff >> uint(bits))
+			nums := make([]string, 0, high-low+1)
+			for num := low; num <= high; num++ {
+				nums = append(nums, strconv.Itoa(num))
+			}
+			octets[i] = "(" + strings.Join(nums, "|") + ")"
+		}
+	}
+	return strings.Join(octets, `\.`)
+}
+
+// containsCondition matches the networks containing the address, they are the networks of the address with
+// every prefix length in the canonical form
+func containsCondition(value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("the address %v should be a string", value)
+	}
+	ip := net.ParseIP(strings.TrimSpace(s))
+	if nil == ip {
+		return nil, fmt.Errorf("%s is not an ip address", s)
+	}
+	bits := 8 * net.IPv6len
+	if ipv4 := ip.To4(); nil != ipv4 {
+		ip, bits = ipv4, 8*net.IPv4len
+	}
+	networks := make([]interface{}, 0, bits+1)
+	for ones := 0; ones <= bits; ones++ {
+		mask := net.CIDRMask(ones, bits)
+		networks = append(networks, (&net.IPNet{IP: ip.Mask(mask), Mask: mask}).String())
+	}
+	return map[string]interface{}{common.BKDBIN: networks}, nil
+}
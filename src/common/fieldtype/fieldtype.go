@@ -0,0 +1,205 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package fieldtype checks the options of the extended attribute types, normalizes their values before they
+// are stored and builds the search conditions on them.
+package fieldtype
+
+import (
+	"configcenter/src/common"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// DefaultJSONMaxSize the size limit of the json object fields without max_size
+const DefaultJSONMaxSize = 4096
+
+// MaxJSONMaxSize the max_size of the json object fields can not exceed it
+const MaxJSONMaxSize = 65536
+
+var extendedTypes = map[string]bool{
+	common.FieldTypeFloat: true,
+	common.FieldTypeIPv4:  true,
+	common.FieldTypeIPv6:  true,
+	common.FieldTypeCIDR:  true,
+	common.FieldTypeURL:   true,
+	common.FieldTypeEmail: true,
+	common.FieldTypeList:  true,
+	common.FieldTypeJSON:  true,
+}
+
+// the types the items of the lists can be
+var itemTypes = map[string]bool{
+	common.FiledTypeSingleChar: true,
+	common.FiledTypeInt:        true,
+	common.FieldTypeFloat:      true,
+	common.FiledTypeBool:       true,
+	common.FieldTypeIPv4:       true,
+	common.FieldTypeIPv6:       true,
+	common.FieldTypeCIDR:       true,
+	common.FieldTypeURL:        true,
+	common.FieldTypeEmail:      true,
+}
+
+// FloatOption the option of the float field, the empty bound is not checked
+type FloatOption struct {
+	Min string `json:"min"`
+	Max string `json:"max"`
+}
+
+// NetOption the option of the ipv4, ipv6 and cidr fields, the values must be in one of the networks if any is set
+type NetOption struct {
+	Within []string `json:"within"`
+}
+
+// URLOption the option of the url field, http and https are allowed if no scheme is set
+type URLOption struct {
+	Schemes []string `json:"schemes"`
+}
+
+// EmailOption the option of the email field, any domain is allowed if no domain is set
+type EmailOption struct {
+	Domains []string `json:"domains"`
+}
+
+// ListOption the option of the list field, the items are singlechar if the item type is not set and the number
+// of the items is not limited if max_items is 0
+type ListOption struct {
+	ItemType string `json:"item_type"`
+	MaxItems int    `json:"max_items"`
+}
+
+// JSONOption the option of the json object field, the size is the length of the object in json
+type JSONOption struct {
+	RequiredKeys []string `json:"required_keys"`
+	MaxSize      int      `json:"max_size"`
+}
+
+// IsExtended reports whether the field type is one of the types the package handles
+func IsExtended(fieldType string) bool {
+	return extendedTypes[fieldType]
+}
+
+// CheckOption checks the option of the field type, the options of the other types are not checked
+func CheckOption(fieldType, option string) error {
+	switch fieldType {
+	case common.FieldTypeFloat:
+		opt := FloatOption{}
+		if err := parseOption(option, &opt); nil != err {
+			return err
+		}
+		min, max, err := opt.bounds()
+		if nil != err {
+			return err
+		}
+		if nil != min && nil != max && *min > *max {
+			return fmt.Errorf("the min %s is greater than the max %s", opt.Min, opt.Max)
+		}
+	case common.FieldTypeIPv4, common.FieldTypeIPv6, common.FieldTypeCIDR:
+		opt := NetOption{}
+		if err := parseOption(option, &opt); nil != err {
+			return err
+		}
+		if _, err := opt.networks(fieldType); nil != err {
+			return err
+		}
+	case common.FieldTypeURL:
+		opt := URLOption{}
+		if err := parseOption(option, &opt); nil != err {
+			return err
+		}
+		for _, scheme := range opt.Schemes {
+			if "" == scheme || strings.ContainsAny(scheme, ":/") {
+				return fmt.Errorf("invalid scheme %q", scheme)
+			}
+		}
+	case common.FieldTypeEmail:
+		opt := EmailOption{}
+		if err := parseOption(option, &opt); nil != err {
+			return err
+		}
+		for _, domain := range opt.Domains {
+			if "" == domain || strings.ContainsAny(domain, "@ ") {
+				return fmt.Errorf("invalid domain %q", domain)
+			}
+		}
+	case common.FieldTypeList:
+		opt := ListOption{}
+		if err := parseOption(option, &opt); nil != err {
+			return err
+		}
+		if "" != opt.ItemType && !itemTypes[opt.ItemType] {
+			return fmt.Errorf("the items can not be %s", opt.ItemType)
+		}
+		if 0 > opt.MaxItems {
+			return fmt.Errorf("invalid max_items %d", opt.MaxItems)
+		}
+	case common.FieldTypeJSON:
+		opt := JSONOption{}
+		if err := parseOption(option, &opt); nil != err {
+			return err
+		}
+		if 0 > opt.MaxSize || MaxJSONMaxSize < opt.MaxSize {
+			return fmt.Errorf("the max_size should be between 0 and %d", MaxJSONMaxSize)
+		}
+	}
+	return nil
+}
+
+// parseOption unmarshals the option, the empty option is the default one
+func parseOption(option string, opt interface{}) error {
+	if "" == strings.TrimSpace(option) {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(option), opt); nil != err {
+		return fmt.Errorf("invalid option, %s", err.Error())
+	}
+	return nil
+}
+
+func (opt FloatOption) bounds() (min, max *float64, err error) {
+	parse := func(bound string) (*float64, error) {
+		if "" == bound {
+			return nil, nil
+		}
+		val, err := strconv.ParseFloat(bound, 64)
+		if nil != err {
+			return nil, fmt.Errorf("invalid bound %s", bound)
+		}
+		return &val, nil
+	}
+	if min, err = parse(opt.Min); nil != err {
+		return nil, nil, err
+	}
+	if max, err = parse(opt.Max); nil != err {
+		return nil, nil, err
+	}
+	return min, max, nil
+}
+
+func (opt NetOption) networks(fieldType string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(opt.Within))
+	for _, within := range opt.Within {
+		network, err := parseNetwork(within)
+		if nil != err {
+			return nil, err
+		}
+		if (common.FieldTypeIPv4 == fieldType && nil == network.IP.To4()) || (common.FieldTypeIPv6 == fieldType && nil != network.IP.To4()) {
+			return nil, fmt.Errorf("the network %s does not match %s", within, fieldType)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
@@ -0,0 +1,176 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fieldtype
+
+import (
+	"configcenter/src/common"
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckOption(t *testing.T) {
+	valid := []struct{ fieldType, option string }{
+		{common.FieldTypeFloat, ""},
+		{common.FieldTypeFloat, `{"min": "-1.5", "max": "2"}`},
+		{common.FieldTypeFloat, `{"max": "2"}`},
+		{common.FieldTypeIPv4, `{"within": ["10.0.0.0/8"]}`},
+		{common.FieldTypeIPv6, `{"within": ["fd00::/8"]}`},
+		{common.FieldTypeCIDR, `{"within": ["10.0.0.0/8", "fd00::/8"]}`},
+		{common.FieldTypeURL, `{"schemes": ["ftp"]}`},
+		{common.FieldTypeEmail, `{"domains": ["example.com"]}`},
+		{common.FieldTypeList, `{"item_type": "ipv4", "max_items": 3}`},
+		{common.FieldTypeJSON, `{"required_keys": ["a"], "max_size": 100}`},
+		{common.FiledTypeSingleChar, `^\d+$`},
+	}
+	for _, c := range valid {
+		assert.NoError(t, CheckOption(c.fieldType, c.option), c.option)
+	}
+
+	invalid := []struct{ fieldType, option string }{
+		{common.FieldTypeFloat, `not json`},
+		{common.FieldTypeFloat, `{"min": "3", "max": "2"}`},
+		{common.FieldTypeFloat, `{"min": "a"}`},
+		{common.FieldTypeIPv4, `{"within": ["fd00::/8"]}`},
+		{common.FieldTypeIPv6, `{"within": ["10.0.0.0/8"]}`},
+		{common.FieldTypeCIDR, `{"within": ["10.0.0.1/8"]}`},
+		{common.FieldTypeURL, `{"schemes": ["http://"]}`},
+		{common.FieldTypeEmail, `{"domains": ["a@example.com"]}`},
+		{common.FieldTypeList, `{"item_type": "enum"}`},
+		{common.FieldTypeList, `{"max_items": -1}`},
+		{common.FieldTypeJSON, `{"max_size": 1000000}`},
+	}
+	for _, c := range invalid {
+		assert.Error(t, CheckOption(c.fieldType, c.option), c.option)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	valid := []struct {
+		fieldType, option string
+		val, value        interface{}
+	}{
+		{common.FieldTypeFloat, "", "1.5", 1.5},
+		{common.FieldTypeFloat, `{"min": "0", "max": "10"}`, json.Number("10"), float64(10)},
+		{common.FieldTypeFloat, "", 3, float64(3)},
+		{common.FieldTypeIPv4, `{"within": ["10.0.0.0/8"]}`, " 10.1.2.3", "10.1.2.3"},
+		{common.FieldTypeIPv4, "", "::ffff:1.2.3.4", "1.2.3.4"},
+		{common.FieldTypeIPv6, "", "FD00:0:0::1", "fd00::1"},
+		{common.FieldTypeCIDR, `{"within": ["10.0.0.0/8"]}`, "10.1.0.0/16", "10.1.0.0/16"},
+		{common.FieldTypeCIDR, "", "2001:DB8::/32", "2001:db8::/32"},
+		{common.FieldTypeURL, "", "https://example.com/a?b=c", "https://example.com/a?b=c"},
+		{common.FieldTypeEmail, `{"domains": ["example.com"]}`, "Ops@Example.COM", "Ops@example.com"},
+		{common.FieldTypeList, "", "a, b,,c", []interface{}{"a", "b", "c"}},
+		{common.FieldTypeList, `{"item_type": "int"}`, `[1, "2", 3.0]`, []interface{}{int64(1), int64(2), int64(3)}},
+		{common.FieldTypeList, `{"item_type": "ipv4"}`, []interface{}{"10.0.0.1"}, []interface{}{"10.0.0.1"}},
+		{common.FieldTypeList, `{"item_type": "bool"}`, []string{"true", "false"}, []interface{}{true, false}},
+		{common.FieldTypeJSON, `{"required_keys": ["a"]}`, `{"a": {"b": 1}}`, map[string]interface{}{"a": map[string]interface{}{"b": float64(1)}}},
+		{common.FieldTypeJSON, "", map[string]interface{}{"a": json.Number("2")}, map[string]interface{}{"a": int64(2)}},
+	}
+	for _, c := range valid {
+		value, err := Normalize(c.fieldType, c.option, c.val)
+		require.NoError(t, err, "%s %v", c.fieldType, c.val)
+		assert.Equal(t, c.value, value, "%s %v", c.fieldType, c.val)
+	}
+
+	invalid := []struct {
+		fieldType, option string
+		val               interface{}
+	}{
+		{common.FieldTypeFloat, "", "abc"},
+		{common.FieldTypeFloat, "", "NaN"},
+		{common.FieldTypeFloat, `{"max": "10"}`, 10.5},
+		{common.FieldTypeFloat, "", true},
+		{common.FieldTypeIPv4, "", "fd00::1"},
+		{common.FieldTypeIPv4, "", "10.0.0.256"},
+		{common.FieldTypeIPv4, `{"within": ["10.0.0.0/8"]}`, "11.0.0.1"},
+		{common.FieldTypeIPv6, "", "10.0.0.1"},
+		{common.FieldTypeCIDR, "", "10.0.0.1/8"},
+		{common.FieldTypeCIDR, `{"within": ["10.0.0.0/16"]}`, "10.0.0.0/8"},
+		{common.FieldTypeURL, "", "example.com/a"},
+		{common.FieldTypeURL, "", "ftp://example.com"},
+		{common.FieldTypeEmail, "", "ops"},
+		{common.FieldTypeEmail, "", "Ops <ops@example.com>"},
+		{common.FieldTypeEmail, `{"domains": ["example.com"]}`, "ops@example.org"},
+		{common.FieldTypeList, `{"max_items": 2}`, "a,b,c"},
+		{common.FieldTypeList, `{"item_type": "int"}`, "1,2.5"},
+		{common.FieldTypeList, "", []interface{}{1}},
+		{common.FieldTypeList, "", 1},
+		{common.FieldTypeJSON, "", "[1]"},
+		{common.FieldTypeJSON, `{"required_keys": ["a"]}`, `{"b": 1}`},
+		{common.FieldTypeJSON, `{"max_size": 10}`, `{"a": "0123456789"}`},
+	}
+	for _, c := range invalid {
+		_, err := Normalize(c.fieldType, c.option, c.val)
+		assert.Error(t, err, "%s %v", c.fieldType, c.val)
+	}
+}
+
+func TestCondition(t *testing.T) {
+	cond, err := Condition(common.FieldTypeFloat, "", common.BKDBGTE, "1.5")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{common.BKDBGTE: 1.5}, cond)
+
+	cond, err = Condition(common.FieldTypeIPv6, "", common.BKDBEQ, "FD00::0:1")
+	require.NoError(t, err)
+	assert.Equal(t, "fd00::1", cond)
+
+	cond, err = Condition(common.FieldTypeList, `{"item_type": "int"}`, common.BKDBALL, []interface{}{"1", float64(2)})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{common.BKDBALL: []interface{}{int64(1), int64(2)}}, cond)
+
+	cond, err = Condition(common.FieldTypeCIDR, "", OperatorContains, "10.1.2.3")
+	require.NoError(t, err)
+	networks := cond.(map[string]interface{})[common.BKDBIN].([]interface{})
+	assert.Len(t, networks, 33)
+	assert.Contains(t, networks, "0.0.0.0/0")
+	assert.Contains(t, networks, "10.0.0.0/8")
+	assert.Contains(t, networks, "10.1.2.0/23")
+	assert.Contains(t, networks, "10.1.2.3/32")
+
+	cond, err = Condition(common.FieldTypeIPv4, "", OperatorWithin, []interface{}{"10.0.0.0/8", "192.168.4.0/22"})
+	require.NoError(t, err)
+	pattern := regexp.MustCompile(cond.(map[string]interface{})[common.BKDBLIKE].(string))
+	for ip, match := range map[string]bool{
+		"10.0.0.1":      true,
+		"10.255.1.1":    true,
+		"192.168.4.1":   true,
+		"192.168.7.255": true,
+		"192.168.8.1":   false,
+		"192.168.3.1":   false,
+		"110.0.0.1":     false,
+		"1.10.0.1":      false,
+	} {
+		assert.Equal(t, match, pattern.MatchString(ip), ip)
+	}
+
+	for _, c := range []struct {
+		fieldType, operator string
+		value               interface{}
+	}{
+		{common.FieldTypeFloat, common.BKDBLIKE, "1"},
+		{common.FieldTypeFloat, common.BKDBIN, 1.5},
+		{common.FieldTypeIPv4, common.BKDBGT, "10.0.0.1"},
+		{common.FieldTypeIPv4, OperatorWithin, "fd00::/8"},
+		{common.FieldTypeIPv6, OperatorWithin, "fd00::/8"},
+		{common.FieldTypeCIDR, OperatorContains, "10.0.0.0/8"},
+		{common.FieldTypeJSON, common.BKDBEQ, map[string]interface{}{}},
+		{common.FiledTypeSingleChar, common.BKDBEQ, "a"},
+	} {
+		_, err := Condition(c.fieldType, "", c.operator, c.value)
+		assert.Error(t, err, "%s %s %v", c.fieldType, c.operator, c.value)
+	}
+}
@@ -0,0 +1,387 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fieldtype
+
+import (
+	"configcenter/src/common"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Normalize checks the value of the field type against the option and returns the value to store, the ip
+// addresses and the networks are in the canonical form, the lists and the json objects may be given in json,
+// the lists also as the items separated by comma
+func Normalize(fieldType, option string, val interface{}) (interface{}, error) {
+	switch fieldType {
+	case common.FieldTypeFloat:
+		opt := FloatOption{}
+		if err := parseOption(option, &opt); nil != err {
+			return nil, err
+		}
+		return normalizeFloat(val, opt)
+	case common.FieldTypeIPv4, common.FieldTypeIPv6, common.FieldTypeCIDR:
+		opt := NetOption{}
+		if err := parseOption(option, &opt); nil != err {
+			return nil, err
+		}
+		return normalizeNet(fieldType, val, opt)
+	case common.FieldTypeURL:
+		opt := URLOption{}
+		if err := parseOption(option, &opt); nil != err {
+			return nil, err
+		}
+		return normalizeURL(val, opt)
+	case common.FieldTypeEmail:
+		opt := EmailOption{}
+		if err := parseOption(option, &opt); nil != err {
+			return nil, err
+		}
+		return normalizeEmail(val, opt)
+	case common.FieldTypeList:
+		opt := ListOption{}
+		if err := parseOption(option, &opt); nil != err {
+			return nil, err
+		}
+		return normalizeList(val, opt)
+	case common.FieldTypeJSON:
+		opt := JSONOption{}
+		if err := parseOption(option, &opt); nil != err {
+			return nil, err
+		}
+		return normalizeJSON(val, opt)
+	}
+	return nil, fmt.Errorf("unknown field type %s", fieldType)
+}
+
+func toFloat(val interface{}) (float64, error) {
+	var f float64
+	switch v := val.(type) {
+	case json.Number:
+		var err error
+		if f, err = v.Float64(); nil != err {
+			return 0, fmt.Errorf("%s is not a number", v)
+		}
+	case string:
+		var err error
+		if f, err = strconv.ParseFloat(strings.TrimSpace(v), 64); nil != err {
+			return 0, fmt.Errorf("%s is not a number", v)
+		}
+	default:
+		rv := reflect.ValueOf(val)
+		switch rv.Kind() {
+		case reflect.Float32, reflect.Float64:
+			f = rv.Float()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			f = float64(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			f = float64(rv.Uint())
+		default:
+			return 0, fmt.Errorf("%v is not a number", val)
+		}
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("%v is not a finite number", val)
+	}
+	return f, nil
+}
+
+func normalizeFloat(val interface{}, opt FloatOption) (interface{}, error) {
+	f, err := toFloat(val)
+	if nil != err {
+		return nil, err
+	}
+	min, max, err := opt.bounds()
+	if nil != err {
+		return nil, err
+	}
+	if (nil != min && f < *min) || (nil != max && f > *max) {
+		return nil, fmt.Errorf("%v is out of the range [%s, %s]", val, opt.Min, opt.Max)
+	}
+	return f, nil
+}
+
+// parseNetwork parses the network in cidr notation, the bits out of the prefix must be 0
+func parseNetwork(val string) (*net.IPNet, error) {
+	ip, network, err := net.ParseCIDR(strings.TrimSpace(val))
+	if nil != err {
+		return nil, fmt.Errorf("%s is not a network in cidr notation", val)
+	}
+	if !ip.Equal(network.IP) {
+		return nil, fmt.Errorf("%s is not a network address, did you mean %s", val, network.String())
+	}
+	return network, nil
+}
+
+// parseIP parses the ip address of the version of the field type
+func parseIP(fieldType, val string) (net.IP, error) {
+	val = strings.TrimSpace(val)
+	ip := net.ParseIP(val)
+	if nil == ip {
+		return nil, fmt.Errorf("%s is not an ip address", val)
+	}
+	// the ipv4 mapped ipv6 addresses are ipv4 addresses like they are printed
+	if isV4 := nil != ip.To4(); (common.FieldTypeIPv4 == fieldType) != isV4 {
+		return nil, fmt.Errorf("%s is not an %s address", val, fieldType)
+	}
+	return ip, nil
+}
+
+func normalizeNet(fieldType string, val interface{}, opt NetOption) (interface{}, error) {
+	s, ok := val.(string)
+	if !ok {
+		return nil, fmt.Errorf("%v should be a string", val)
+	}
+	networks, err := opt.networks(fieldType)
+	if nil != err {
+		return nil, err
+	}
+
+	var value string
+	contained := func(*net.IPNet) bool { return true }
+	if common.FieldTypeCIDR == fieldType {
+		network, err := parseNetwork(s)
+		if nil != err {
+			return nil, err
+		}
+		ones, _ := network.Mask.Size()
+		value = network.String()
+		contained = func(within *net.IPNet) bool {
+			withinOnes, _ := within.Mask.Size()
+			return len(within.IP) == len(network.IP) && within.Contains(network.IP) && withinOnes <= ones
+		}
+	} else {
+		ip, err := parseIP(fieldType, s)
+		if nil != err {
+			return nil, err
+		}
+		value = ip.String()
+		contained = func(within *net.IPNet) bool {
+			return within.Contains(ip)
+		}
+	}
+
+	if 0 == len(networks) {
+		return value, nil
+	}
+	for _, within := range networks {
+		if contained(within) {
+			return value, nil
+		}
+	}
+	return nil, fmt.Errorf("%s is not in %s", value, strings.Join(opt.Within, ","))
+}
+
+func normalizeURL(val interface{}, opt URLOption) (interface{}, error) {
+	s, ok := val.(string)
+	if !ok {
+		return nil, fmt.Errorf("%v should be a string", val)
+	}
+	s = strings.TrimSpace(s)
+	if common.FiledTypeLongLenChar < len(s) {
+		return nil, fmt.Errorf("the url is longer than %d", common.FiledTypeLongLenChar)
+	}
+	u, err := url.Parse(s)
+	if nil != err || "" == u.Host {
+		return nil, fmt.Errorf("%s is not an absolute url", s)
+	}
+	schemes := opt.Schemes
+	if 0 == len(schemes) {
+		schemes = []string{"http", "https"}
+	}
+	for _, scheme := range schemes {
+		if strings.EqualFold(scheme, u.Scheme) {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("the scheme of %s is not one of %s", s, strings.Join(schemes, ","))
+}
+
+func normalizeEmail(val interface{}, opt EmailOption) (interface{}, error) {
+	s, ok := val.(string)
+	if !ok {
+		return nil, fmt.Errorf("%v should be a string", val)
+	}
+	s = strings.TrimSpace(s)
+	addr, err := mail.ParseAddress(s)
+	if nil != err || addr.Address != s || "" != addr.Name {
+		return nil, fmt.Errorf("%s is not an email address", s)
+	}
+	if common.FiledTypeLongLenChar < len(s) {
+		return nil, fmt.Errorf("the email address is longer than %d", common.FiledTypeLongLenChar)
+	}
+	at := strings.LastIndex(s, "@")
+	domain := strings.ToLower(s[at+1:])
+	if 0 != len(opt.Domains) {
+		allowed := false
+		for _, item := range opt.Domains {
+			if strings.EqualFold(item, domain) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return nil, fmt.Errorf("the domain of %s is not one of %s", s, strings.Join(opt.Domains, ","))
+		}
+	}
+	// the domain is case insensitive, the local part is kept as it is
+	return s[:at+1] + domain, nil
+}
+
+// normalizeItem checks the item of the list of the item type
+func normalizeItem(itemType string, val interface{}) (interface{}, error) {
+	switch itemType {
+	case common.FiledTypeSingleChar:
+		s, ok := val.(string)
+		if !ok {
+			return nil, fmt.Errorf("%v should be a string", val)
+		}
+		if common.FiledTypeSingleLenChar < len(s) {
+			return nil, fmt.Errorf("%s is longer than %d", s, common.FiledTypeSingleLenChar)
+		}
+		return s, nil
+	case common.FiledTypeInt:
+		f, err := toFloat(val)
+		if nil != err {
+			return nil, err
+		}
+		if f != math.Trunc(f) || math.MaxInt64 < f || math.MinInt64 > f {
+			return nil, fmt.Errorf("%v is not an integer", val)
+		}
+		return int64(f), nil
+	case common.FiledTypeBool:
+		switch v := val.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); nil == err {
+				return b, nil
+			}
+		}
+		return nil, fmt.Errorf("%v is not a bool", val)
+	}
+	return Normalize(itemType, "", val)
+}
+
+func normalizeList(val interface{}, opt ListOption) (interface{}, error) {
+	itemType := opt.ItemType
+	if "" == itemType {
+		itemType = common.FiledTypeSingleChar
+	}
+	if !itemTypes[itemType] {
+		return nil, fmt.Errorf("the items can not be %s", itemType)
+	}
+
+	var items []interface{}
+	switch v := val.(type) {
+	case []interface{}:
+		items = v
+	case string:
+		if s := strings.TrimSpace(v); strings.HasPrefix(s, "[") {
+			if err := json.Unmarshal([]byte(s), &items); nil != err {
+				return nil, fmt.Errorf("%s is not a json array", v)
+			}
+			break
+		}
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); "" != item {
+				items = append(items, item)
+			}
+		}
+	default:
+		rv := reflect.ValueOf(val)
+		if reflect.Slice != rv.Kind() && reflect.Array != rv.Kind() {
+			return nil, fmt.Errorf("%v is not a list", val)
+		}
+		for i := 0; i < rv.Len(); i++ {
+			items = append(items, rv.Index(i).Interface())
+		}
+	}
+	if 0 != opt.MaxItems && opt.MaxItems < len(items) {
+		return nil, fmt.Errorf("the list has more than %d items", opt.MaxItems)
+	}
+
+	result := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		value, err := normalizeItem(itemType, item)
+		if nil != err {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+	return result, nil
+}
+
+func normalizeJSON(val interface{}, opt JSONOption) (interface{}, error) {
+	var obj map[string]interface{}
+	switch v := val.(type) {
+	case map[string]interface{}:
+		obj = plainNumbers(v).(map[string]interface{})
+	case string:
+		if err := json.Unmarshal([]byte(v), &obj); nil != err || nil == obj {
+			return nil, fmt.Errorf("%s is not a json object", v)
+		}
+	default:
+		return nil, fmt.Errorf("%v is not a json object", val)
+	}
+	for _, key := range opt.RequiredKeys {
+		if _, ok := obj[key]; !ok {
+			return nil, fmt.Errorf("the key %s is required", key)
+		}
+	}
+	maxSize := opt.MaxSize
+	if 0 == maxSize {
+		maxSize = DefaultJSONMaxSize
+	}
+	content, err := json.Marshal(obj)
+	if nil != err {
+		return nil, err
+	}
+	if maxSize < len(content) {
+		return nil, fmt.Errorf("the json object is larger than %d", maxSize)
+	}
+	return obj, nil
+}
+
+// plainNumbers replaces the json numbers decoded from the request by the numbers stored as they are
+func plainNumbers(val interface{}) interface{} {
+	switch v := val.(type) {
+	case json.Number:
+		if i, err := v.Int64(); nil == err {
+			return i
+		}
+		if f, err := v.Float64(); nil == err {
+			return f
+		}
+		return v.String()
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = plainNumbers(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = plainNumbers(item)
+		}
+		return result
+	}
+	return val
+}
@@ -14,6 +14,7 @@ package params
 
 import (
 	"configcenter/src/common"
+	"configcenter/src/common/fieldtype"
 	"errors"
 	"fmt"
 	"reflect"
@@ -99,3 +100,49 @@ func ParseAppSearchParams(input map[string]interface{}) map[string]interface{} {
 	}
 	return output
 }
+
+// ParseTypedSearchParams parses the condition like ParseAppSearchParams, the fields of the extended types in the
+// rules are matched exactly by the value or by the operators of the value, the rules are keyed by the property id
+// and hold the property type and the option
+func ParseTypedSearchParams(input map[string]interface{}, rules map[string]map[string]interface{}) (map[string]interface{}, error) {
+	others := make(map[string]interface{})
+	typed := make(map[string]interface{})
+	for field, value := range input {
+		propertyType, _ := rules[field][common.BKPropertyTypeField].(string)
+		if !fieldtype.IsExtended(propertyType) || nil == value {
+			others[field] = value
+			continue
+		}
+		option, _ := rules[field][common.BKOptionField].(string)
+		ops, ok := value.(map[string]interface{})
+		if !ok {
+			cond, err := fieldtype.Condition(propertyType, option, common.BKDBEQ, value)
+			if nil != err {
+				return nil, fmt.Errorf("condition error, %s: %s", field, err.Error())
+			}
+			typed[field] = cond
+			continue
+		}
+		merged := make(map[string]interface{})
+		for operator, val := range ops {
+			cond, err := fieldtype.Condition(propertyType, option, operator, val)
+			if nil != err {
+				return nil, fmt.Errorf("condition error, %s: %s", field, err.Error())
+			}
+			if common.BKDBEQ == operator {
+				merged[common.BKDBEQ] = cond
+				continue
+			}
+			for key, item := range cond.(map[string]interface{}) {
+				merged[key] = item
+			}
+		}
+		typed[field] = merged
+	}
+
+	output := ParseAppSearchParams(others)
+	for field, cond := range typed {
+		output[field] = cond
+	}
+	return output, nil
+}
@@ -14,6 +14,7 @@ package params
 
 import (
 	"configcenter/src/common"
+	"configcenter/src/common/fieldtype"
 	"configcenter/src/common/util"
 	"errors"
 	"fmt"
@@ -48,6 +49,8 @@ type PageInfo struct {
 	Start int    `json:"start"`
 	Limit int    `json:"limit"`
 	Sort  string `json:"sort"`
+	// Cursor pages by the next_cursor of the previous page instead of start if it is set, "" for the first page
+	Cursor *string `json:"cursor,omitempty"`
 }
 
 //search condition
@@ -58,6 +61,13 @@ type SearchCondition struct {
 }
 
 func ParseHostParams(input []interface{}, output map[string]interface{}) error {
+	return ParseHostTypedParams(input, nil, output)
+}
+
+// ParseHostTypedParams parses the conditions like ParseHostParams, the conditions on the fields of the extended
+// types in the rules are parsed by their types, the rules are keyed by the property id and hold the property type
+// and the option
+func ParseHostTypedParams(input []interface{}, rules map[string]map[string]interface{}, output map[string]interface{}) error {
 	fmt.Println(input)
 	for _, i := range input {
 		j, ok := i.(map[string]interface{})
@@ -74,6 +84,19 @@ func ParseHostParams(input []interface{}, output map[string]interface{}) error {
 		}
 		value := j["value"]
 
+		if rule, ok := rules[field]; ok {
+			propertyType, _ := rule[common.BKPropertyTypeField].(string)
+			option, _ := rule[common.BKOptionField].(string)
+			if fieldtype.IsExtended(propertyType) {
+				cond, err := fieldtype.Condition(propertyType, option, operator, value)
+				if nil != err {
+					return fmt.Errorf("condition error, %s: %s", field, err.Error())
+				}
+				output[field] = cond
+				continue
+			}
+		}
+
 		switch operator {
 		case common.BKDBEQ:
 			output[field] = value
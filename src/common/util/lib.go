@@ -106,6 +106,11 @@ func GetActionOnwerID(req *restful.Request) string {
 	return ownerID
 }
 
+// IsLagTolerant returns whether the caller accepts the search taken off the primary
+func IsLagTolerant(req *restful.Request) bool {
+	return "true" == req.HeaderParameter(common.BKHTTPTolerateLag)
+}
+
 // GetActionOnwerID returns owner_uin and user form hender
 func GetActionOnwerIDAndUser(req *restful.Request) (string, string) {
 	user := GetActionUser(req)
@@ -0,0 +1,84 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package host
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/common/bkbase"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/core/cc/actions"
+	"configcenter/src/common/util"
+	"configcenter/src/storage"
+	"encoding/json"
+	"io/ioutil"
+
+	"configcenter/src/scene_server/admin_server/migrate_service/logics"
+
+	"github.com/emicklei/go-restful"
+)
+
+var index *indexAction = &indexAction{}
+
+type indexAction struct {
+	base.BaseAction
+}
+
+// reconcileParams the body of the index reconciliation
+type reconcileParams struct {
+	DryRun    bool `json:"dry_run"`
+	DropExtra bool `json:"drop_extra"`
+}
+
+func init() {
+	index.CreateAction()
+
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPSelectGet, Path: "/indexes/drift", Params: nil, Handler: index.drift})
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPCreate, Path: "/indexes/reconcile", Params: nil, Handler: index.reconcile})
+}
+
+// drift reports the difference between the index spec and the live indexes
+func (cli *indexAction) drift(req *restful.Request, resp *restful.Response) {
+	cli.run(req, resp, storage.ReconcileOptions{DryRun: true})
+}
+
+// reconcile makes the live indexes the same as the index spec
+func (cli *indexAction) reconcile(req *restful.Request, resp *restful.Response) {
+	language := util.GetActionLanguage(req)
+	defErr := cli.CC.Error.CreateDefaultCCErrorIf(language)
+
+	value, err := ioutil.ReadAll(req.Request.Body)
+	if nil != err {
+		blog.Errorf("read reconcile params error: %v", err)
+		cli.ResponseFailed(common.CCErrCommHTTPReadBodyFailed, defErr.Error(common.CCErrCommHTTPReadBodyFailed), resp)
+		return
+	}
+	params := reconcileParams{}
+	if 0 != len(value) {
+		if err := json.Unmarshal(value, &params); nil != err {
+			blog.Errorf("unmarshal reconcile params error: %v", err)
+			cli.ResponseFailed(common.CCErrCommJSONUnmarshalFailed, defErr.Error(common.CCErrCommJSONUnmarshalFailed), resp)
+			return
+		}
+	}
+	cli.run(req, resp, storage.ReconcileOptions{DryRun: params.DryRun, DropExtra: params.DropExtra})
+}
+
+func (cli *indexAction) run(req *restful.Request, resp *restful.Response, opts storage.ReconcileOptions) {
+	drifts, err := logics.ReconcileIndexes(req.Request.Context(), cli.CC.InstCli, opts)
+	if nil != err {
+		blog.Errorf("reconcile indexes error: %v", err)
+		cli.ResponseFailedWithData(common.CCErrCommMigrateFailed, err.Error(), drifts, resp)
+		return
+	}
+	cli.ResponseSuccess(drifts, resp)
+}
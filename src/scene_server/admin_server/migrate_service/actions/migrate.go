@@ -85,7 +85,7 @@ func (cli *migrateAction) migrate(req *restful.Request, resp *restful.Response)
 		return
 	}
 
-	err = logics.Upgrade(migrate.CC.InstCli)
+	err = logics.Upgrade(req.Request.Context(), ownerID, migrate.CC.InstCli)
 	if nil != err {
 		blog.Errorf("db upgrade error: %v", err)
 		cli.ResponseFailed(common.CCErrCommMigrateFailed, defErr.Error(common.CCErrCommMigrateFailed), resp)
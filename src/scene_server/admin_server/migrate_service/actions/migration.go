@@ -0,0 +1,131 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package host
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/common/bkbase"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/core/cc/actions"
+	"configcenter/src/common/util"
+	"encoding/json"
+	"io/ioutil"
+
+	"configcenter/src/scene_server/admin_server/migrateregister"
+
+	"github.com/emicklei/go-restful"
+)
+
+var migration *migrationAction = &migrationAction{}
+
+type migrationAction struct {
+	base.BaseAction
+}
+
+// migrationParams the body of the migration run
+type migrationParams struct {
+	// TargetVersion the version to migrate to, up runs to the latest version when it is empty
+	TargetVersion string `json:"target_version"`
+	DryRun        bool   `json:"dry_run"`
+}
+
+func init() {
+	migration.CreateAction()
+
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPSelectGet, Path: "/migrations", Params: nil, Handler: migration.list})
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPCreate, Path: "/migrations/up", Params: nil, Handler: migration.up})
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPCreate, Path: "/migrations/down", Params: nil, Handler: migration.down})
+}
+
+// list returns the applied and pending migrations
+func (cli *migrationAction) list(req *restful.Request, resp *restful.Response) {
+	language := util.GetActionLanguage(req)
+	defErr := cli.CC.Error.CreateDefaultCCErrorIf(language)
+
+	ownerID := util.GetActionOnwerID(req)
+	if "" == ownerID {
+		ownerID = common.BKDefaultOwnerID
+	}
+	status, err := migrateregister.NewMigrator(cli.CC.InstCli).Status(req.Request.Context(), ownerID)
+	if nil != err {
+		blog.Errorf("get migration status error: %v", err)
+		cli.ResponseFailed(common.CCErrCommDBSelectFailed, defErr.Error(common.CCErrCommDBSelectFailed), resp)
+		return
+	}
+
+	applied, pending := make([]migrateregister.MigrationStatus, 0), make([]migrateregister.MigrationStatus, 0)
+	for _, s := range status {
+		if s.Applied {
+			applied = append(applied, s)
+		} else {
+			pending = append(pending, s)
+		}
+	}
+	cli.ResponseSuccess(map[string]interface{}{"applied": applied, "pending": pending}, resp)
+}
+
+// up applies the pending migrations up to the target version
+func (cli *migrationAction) up(req *restful.Request, resp *restful.Response) {
+	cli.run(req, resp, migrateregister.MigrationDirectionUp)
+}
+
+// down reverts the applied migrations newer than the target version
+func (cli *migrationAction) down(req *restful.Request, resp *restful.Response) {
+	cli.run(req, resp, migrateregister.MigrationDirectionDown)
+}
+
+func (cli *migrationAction) run(req *restful.Request, resp *restful.Response, direction string) {
+	language := util.GetActionLanguage(req)
+	defErr := cli.CC.Error.CreateDefaultCCErrorIf(language)
+
+	value, err := ioutil.ReadAll(req.Request.Body)
+	if nil != err {
+		blog.Errorf("read migration params error: %v", err)
+		cli.ResponseFailed(common.CCErrCommHTTPReadBodyFailed, defErr.Error(common.CCErrCommHTTPReadBodyFailed), resp)
+		return
+	}
+	params := migrationParams{}
+	if 0 != len(value) {
+		if err := json.Unmarshal(value, &params); nil != err {
+			blog.Errorf("unmarshal migration params error: %v", err)
+			cli.ResponseFailed(common.CCErrCommJSONUnmarshalFailed, defErr.Error(common.CCErrCommJSONUnmarshalFailed), resp)
+			return
+		}
+	}
+	if ("" != params.TargetVersion || migrateregister.MigrationDirectionDown == direction) &&
+		nil != migrateregister.ValidateVersion(params.TargetVersion) {
+		blog.Errorf("invalid migration target version %q", params.TargetVersion)
+		cli.ResponseFailed(common.CCErrCommParamsInvalid, defErr.Errorf(common.CCErrCommParamsInvalid, "target_version"), resp)
+		return
+	}
+
+	ownerID := util.GetActionOnwerID(req)
+	if "" == ownerID {
+		ownerID = common.BKDefaultOwnerID
+	}
+
+	migrator := migrateregister.NewMigrator(cli.CC.InstCli)
+	var result *migrateregister.MigrationResult
+	if migrateregister.MigrationDirectionUp == direction {
+		result, err = migrator.Up(req.Request.Context(), ownerID, params.TargetVersion, params.DryRun)
+	} else {
+		result, err = migrator.Down(req.Request.Context(), ownerID, params.TargetVersion, params.DryRun)
+	}
+	if nil != err {
+		blog.Errorf("migrate %s to %q error: %v", direction, params.TargetVersion, err)
+		cli.ResponseFailedWithData(common.CCErrCommMigrateFailed, err.Error(), result, resp)
+		return
+	}
+
+	cli.ResponseSuccess(result, resp)
+}
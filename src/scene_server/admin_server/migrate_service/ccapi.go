@@ -20,6 +20,9 @@ import (
 	"configcenter/src/common/http/httpserver"
 	"configcenter/src/common/rdapi"
 	"configcenter/src/common/types"
+	"configcenter/src/scene_server/admin_server/migrate_service/logics"
+	"configcenter/src/storage"
+	"context"
 	"sync"
 	"time"
 	// migrateCommon "configcenter/src/scene_server/admin_server/common"
@@ -122,6 +125,23 @@ func (ccAPI *CCAPIServer) Start() error {
 		return err
 	}
 
+	// the indexes are reconciled in background, report only logs the drift
+	reconcileMode := config["mongodb.indexReconcile"]
+	if "" == reconcileMode {
+		reconcileMode = logics.IndexReconcileReport
+	}
+	if logics.IndexReconcileOff != reconcileMode {
+		opts := storage.ReconcileOptions{
+			DryRun:    logics.IndexReconcileApply != reconcileMode,
+			DropExtra: "true" == config["mongodb.indexDropExtra"],
+		}
+		go func() {
+			if _, err := logics.ReconcileIndexes(context.Background(), a.InstCli, opts); err != nil {
+				blog.Errorf("reconcile indexes failed, err:%v", err)
+			}
+		}()
+	}
+
 	go func() {
 		err := ccAPI.rd.Start()
 		blog.Errorf("rdiscover start failed! err:%s", err.Error())
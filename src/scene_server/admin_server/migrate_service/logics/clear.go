@@ -32,6 +32,7 @@ var (
 		"cc_Process",
 		"cc_SetBase",
 		"cc_Subscription",
+		"cc_EventLog",
 		"cc_UserAPI",
 		"cc_UserCustom",
 		"cc_UserGroup",
@@ -46,4 +46,7 @@ func (m *migrateEvent) createTable(ownerID string, metaData dbStorage.DI, instDa
 func init() {
 	m := &migrateEvent{tableName: "cc_Subscription"}
 	migrateregister.RegisterMigrateAction(m.createTable, migrateregister.MigrateTypeCreateTable)
+
+	l := &migrateEvent{tableName: "cc_EventLog"}
+	migrateregister.RegisterMigrateAction(l.createTable, migrateregister.MigrateTypeCreateTable)
 }
@@ -13,111 +13,168 @@
 package logics
 
 import (
+	"configcenter/src/common/blog"
 	"configcenter/src/common/core/cc/api"
+	"configcenter/src/scene_server/admin_server/migrateregister"
+	eventtypes "configcenter/src/scene_server/event_server/types"
+	"configcenter/src/source_controller/common/instdata"
 	"configcenter/src/storage"
+	"context"
+	"fmt"
 )
 
-func CreateIndex() error {
-	a := api.GetAPIResource()
+// indexSpecs the declarative indexes of the collections, the unnamed index takes the mongo default name
+var indexSpecs = map[string][]storage.Index{
+	"cc_ApplicationBase": {
+		{Columns: []string{"bk_biz_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_biz_name"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"default"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_supplier_account"}, Type: storage.INDEX_TYPE_BACKGROUP},
+	},
+	"cc_HostBase": {
+		{Columns: []string{"bk_host_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_host_name"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_host_innerip"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_host_outerip"}, Type: storage.INDEX_TYPE_BACKGROUP},
+	},
+	"cc_ModuleBase": {
+		{Columns: []string{"bk_module_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_module_name"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"default"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_biz_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_supplier_account"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_set_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_parent_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+	},
+	"cc_ModuleHostConfig": {
+		{Columns: []string{"bk_biz_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_host_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_module_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_set_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_host_id", "bk_module_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+	},
+	"cc_ObjAsst": {
+		{Columns: []string{"bk_obj_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_asst_obj_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_supplier_account"}, Type: storage.INDEX_TYPE_BACKGROUP},
+	},
+	"cc_ObjAttDes": {
+		{Columns: []string{"bk_obj_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_supplier_account"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+	},
+	"cc_ObjClassification": {
+		{Columns: []string{"bk_classification_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_classification_name"}, Type: storage.INDEX_TYPE_BACKGROUP},
+	},
+	"cc_ObjDes": {
+		{Columns: []string{"bk_obj_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_classification_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_obj_name"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_supplier_account"}, Type: storage.INDEX_TYPE_BACKGROUP},
+	},
+	"cc_ObjectBase": {
+		{Columns: []string{"bk_obj_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_supplier_account"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_inst_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+	},
+	"cc_OperationLog": {
+		{Columns: []string{"bk_obj_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_supplier_account"}, Type: storage.INDEX_TYPE_BACKGROUP},
+	},
+	"cc_PlatBase": {
+		{Columns: []string{"bk_supplier_account"}, Type: storage.INDEX_TYPE_BACKGROUP},
+	},
+	"cc_Proc2Module": {
+		{Columns: []string{"bk_biz_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_process_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+	},
+	"cc_Process": {
+		{Columns: []string{"bk_process_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_biz_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_supplier_account"}, Type: storage.INDEX_TYPE_BACKGROUP},
+	},
+	"cc_PropertyGroup": {
+		{Columns: []string{"bk_obj_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_supplier_account"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_group_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+	},
+	"cc_SetBase": {
+		{Columns: []string{"bk_set_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_parent_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_biz_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_supplier_account"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Columns: []string{"bk_set_name"}, Type: storage.INDEX_TYPE_BACKGROUP},
+	},
+	// the same as the ones event_server ensures at its startup
+	eventtypes.TableNameEventLog: {
+		{Name: "idx_event_id", Columns: []string{"event_id"}, Type: storage.INDEX_TYPE_BACKGROUP_UNIQUE},
+		{Name: "idx_action_time", Columns: []string{"action_time"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Name: "idx_supplier_account_event_id", Columns: []string{"bk_supplier_account", "event_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		{Name: "idx_create_time_ttl", Columns: []string{"create_time"}, Type: storage.INDEX_TYPE_BACKGROUP, ExpireAfter: eventtypes.EventLogRetention},
+	},
+	// the same as the ones the migrator ensures before a run
+	migrateregister.MigrationLedgerTable: migrateregister.MigrationIndexes[migrateregister.MigrationLedgerTable],
+	migrateregister.MigrationLockTable:   migrateregister.MigrationIndexes[migrateregister.MigrationLockTable],
+	"cc_Subscription": {
+		{Columns: []string{"subscription_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+	},
+}
+
+// the modes of the index reconciliation at the startup
+const (
+	IndexReconcileOff    = "off"
+	IndexReconcileReport = "report"
+	IndexReconcileApply  = "apply"
+)
 
-	tableindexs := getIndex()
-	for tablename, indexs := range tableindexs {
-		for _, index := range indexs {
-			ii := index
-			if err := a.InstCli.Index(tablename, &ii); err != nil {
-				return err
-			}
+// CreateIndex creates the missing and recreates the changed indexes of the spec
+func CreateIndex() error {
+	drifts, err := ReconcileIndexes(context.Background(), api.GetAPIResource().InstCli, storage.ReconcileOptions{})
+	if nil != err {
+		return err
+	}
+	for _, drift := range drifts {
+		if "" != drift.Error {
+			return fmt.Errorf("%s index %s of %s failed: %s", drift.Action, drift.Name, drift.Collection, drift.Error)
 		}
 	}
 	return nil
 }
 
-func getIndex() map[string][]storage.Index {
-	index := map[string][]storage.Index{}
-
-	index["cc_ApplicationBase"] = []storage.Index{
-		storage.Index{Name: "", Columns: []string{"bk_biz_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_biz_name"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"default"}, Type: storage.INDEX_TYPE_BACKGROUP},
+// ReconcileIndexes makes the live indexes the same as IndexSpecs and returns the drift
+func ReconcileIndexes(ctx context.Context, db storage.DI, opts storage.ReconcileOptions) ([]storage.IndexDrift, error) {
+	specs, err := IndexSpecs(ctx, db)
+	if nil != err {
+		return nil, err
+	}
+	// the unique indexes of the attributes no longer bk_isonly are always dropped
+	opts.Prune = instdata.IsOnlyIndexPruned
+	drifts, err := storage.ReconcileIndexes(ctx, db, specs, opts)
+	for _, drift := range drifts {
+		if "" != drift.Error {
+			blog.Errorf("%s index %s of %s failed: %s", drift.Action, drift.Name, drift.Collection, drift.Error)
+		} else if drift.Applied {
+			blog.Infof("%s index %s of %s %s", drift.Action, drift.Name, drift.Collection, drift.Reason)
+		} else {
+			blog.Warnf("index drift, %s index %s of %s %s", drift.Action, drift.Name, drift.Collection, drift.Reason)
+		}
 	}
+	return drifts, err
+}
 
-	index["cc_HostBase"] = []storage.Index{
-		storage.Index{Name: "", Columns: []string{"bk_host_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_host_name"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_host_innerip"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_host_outerip"}, Type: storage.INDEX_TYPE_BACKGROUP},
-	}
-	index["cc_ModuleBase"] = []storage.Index{
-		storage.Index{Name: "", Columns: []string{"bk_module_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_module_name"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"default"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_biz_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_supplier_account"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_set_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_parent_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-	}
-	index["cc_ModuleHostConfig"] = []storage.Index{
-		storage.Index{Name: "", Columns: []string{"bk_biz_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_host_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_module_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_set_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-	}
-	index["cc_ObjAsst"] = []storage.Index{
-		storage.Index{Name: "", Columns: []string{"bk_obj_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_asst_obj_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_supplier_account"}, Type: storage.INDEX_TYPE_BACKGROUP},
-	}
-	index["cc_ObjAttDes"] = []storage.Index{
-		storage.Index{Name: "", Columns: []string{"bk_obj_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_supplier_account"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-	}
-	index["cc_ObjClassification"] = []storage.Index{
-		storage.Index{Name: "", Columns: []string{"bk_classification_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_classification_name"}, Type: storage.INDEX_TYPE_BACKGROUP},
-	}
-	index["cc_ObjDes"] = []storage.Index{
-		storage.Index{Name: "", Columns: []string{"bk_obj_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_classification_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_obj_name"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_supplier_account"}, Type: storage.INDEX_TYPE_BACKGROUP},
-	}
-	index["cc_ObjectBase"] = []storage.Index{
-		storage.Index{Name: "", Columns: []string{"bk_obj_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_supplier_account"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_inst_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-	}
-	index["cc_OperationLog"] = []storage.Index{
-		storage.Index{Name: "", Columns: []string{"bk_obj_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_supplier_account"}, Type: storage.INDEX_TYPE_BACKGROUP},
-	}
-	index["cc_PlatBase"] = []storage.Index{
-		storage.Index{Name: "", Columns: []string{"bk_supplier_account"}, Type: storage.INDEX_TYPE_BACKGROUP},
-	}
-	index["cc_Proc2Module"] = []storage.Index{
-		storage.Index{Name: "", Columns: []string{"bk_biz_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_process_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-	}
-	index["cc_ApplicationBase"] = []storage.Index{
-		storage.Index{Name: "", Columns: []string{"bk_process_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_biz_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_supplier_account"}, Type: storage.INDEX_TYPE_BACKGROUP},
-	}
-	index["cc_PropertyGroup"] = []storage.Index{
-		storage.Index{Name: "", Columns: []string{"bk_obj_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_supplier_account"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_group_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-	}
-	index["cc_SetBase"] = []storage.Index{
-		storage.Index{Name: "", Columns: []string{"bk_set_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_parent_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_biz_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_supplier_account"}, Type: storage.INDEX_TYPE_BACKGROUP},
-		storage.Index{Name: "", Columns: []string{"bk_set_name"}, Type: storage.INDEX_TYPE_BACKGROUP},
-	}
-	index["cc_Subscription"] = []storage.Index{
-		storage.Index{Name: "", Columns: []string{"subscription_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+// IndexSpecs returns the index specs together with the unique indexes of the bk_isonly attributes of
+// the custom objects, see instdata.IsOnlyIndexes
+func IndexSpecs(ctx context.Context, db storage.DI) (map[string][]storage.Index, error) {
+	specs := make(map[string][]storage.Index, len(indexSpecs))
+	for cName, indexes := range indexSpecs {
+		specs[cName] = append([]storage.Index{}, indexes...)
 	}
 
-	return index
+	isonly, err := instdata.IsOnlyIndexes(ctx, db)
+	if nil != err {
+		return nil, err
+	}
+	specs[instdata.BaseInstTable] = append(specs[instdata.BaseInstTable], isonly...)
+	return specs, nil
 }
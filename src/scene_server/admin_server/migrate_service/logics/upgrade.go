@@ -2,12 +2,36 @@ package logics
 
 import (
 	"configcenter/src/common"
+	"configcenter/src/scene_server/admin_server/migrateregister"
 	dbStorage "configcenter/src/storage"
+	"context"
 )
 
-func Upgrade(instData dbStorage.DI) error {
+func init() {
+	migrateregister.RegisterMigration(migrateregister.Migration{
+		ID:          "app_timezone_language_required",
+		Version:     "3.0.8",
+		Description: "time_zone and language of the business are required",
+		Up: func(ctx context.Context, ownerID string, db dbStorage.DI) error {
+			return setAppAttrRequired(ctx, ownerID, db, true)
+		},
+		Down: func(ctx context.Context, ownerID string, db dbStorage.DI) error {
+			return setAppAttrRequired(ctx, ownerID, db, false)
+		},
+	})
+}
+
+// Upgrade applies all the pending versioned migrations
+func Upgrade(ctx context.Context, ownerID string, instData dbStorage.DI) error {
+	_, err := migrateregister.NewMigrator(instData).Up(ctx, ownerID, "", false)
+	return err
+}
+
+// setAppAttrRequired changes the time_zone and language attributes of the business of the owner
+func setAppAttrRequired(ctx context.Context, ownerID string, instData dbStorage.DI, required bool) error {
 	condition := map[string]interface{}{
-		common.BKObjIDField: common.BKInnerObjIDApp,
+		common.BKOwnerIDField: ownerID,
+		common.BKObjIDField:   common.BKInnerObjIDApp,
 		common.BKPropertyIDField: map[string]interface{}{
 			"$in": []string{
 				"time_zone",
@@ -16,8 +40,7 @@ func Upgrade(instData dbStorage.DI) error {
 		},
 	}
 	data := map[string]interface{}{
-		"isrequired": true,
+		"isrequired": required,
 	}
-	instData.UpdateByCondition(common.BKTableNameObjAttDes, data, condition)
-	return nil
+	return dbStorage.ContextOf(instData).UpdateByConditionCtx(ctx, common.BKTableNameObjAttDes, data, condition)
 }
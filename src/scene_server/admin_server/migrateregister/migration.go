@@ -0,0 +1,123 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package migrateregister
+
+import (
+	dbStorage "configcenter/src/storage"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MigrationFunc one step of the migration, it must be idempotent because
+// the step runs again if the ledger fails to record it
+type MigrationFunc func(ctx context.Context, ownerID string, db dbStorage.DI) error
+
+// Migration the versioned migration
+type Migration struct {
+	// ID the unique id of the migration
+	ID string
+	// Version the dot separated numeric version, 3.0.8 for example, the migrations run in the order of the version
+	Version     string
+	Description string
+	Up          MigrationFunc
+	// Down reverts Up, the migration without Down could not be reverted
+	Down MigrationFunc
+}
+
+var (
+	migrationsLock sync.RWMutex
+	migrations     = map[string]Migration{}
+)
+
+// RegisterMigration registers the versioned migration, it panics on the invalid or duplicate migration
+func RegisterMigration(m Migration) {
+	if "" == m.ID {
+		panic("migration id must not be empty")
+	}
+	if nil == m.Up {
+		panic(fmt.Sprintf("migration %s has no up step", m.ID))
+	}
+	if _, err := parseVersion(m.Version); nil != err {
+		panic(fmt.Sprintf("migration %s: %v", m.ID, err))
+	}
+
+	migrationsLock.Lock()
+	defer migrationsLock.Unlock()
+	if _, ok := migrations[m.ID]; ok {
+		panic(fmt.Sprintf("migration %s registered twice", m.ID))
+	}
+	migrations[m.ID] = m
+}
+
+// GetMigrations returns the registered migrations ordered by version and id
+func GetMigrations() []Migration {
+	migrationsLock.RLock()
+	ms := make([]Migration, 0, len(migrations))
+	for _, m := range migrations {
+		ms = append(ms, m)
+	}
+	migrationsLock.RUnlock()
+
+	sort.Slice(ms, func(i, j int) bool {
+		if c := CompareVersion(ms[i].Version, ms[j].Version); 0 != c {
+			return c < 0
+		}
+		return ms[i].ID < ms[j].ID
+	})
+	return ms
+}
+
+// CompareVersion compares the dot separated numeric versions, the missing parts are taken as zero
+func CompareVersion(a, b string) int {
+	va, _ := parseVersion(a)
+	vb, _ := parseVersion(b)
+	for i := 0; i < len(va) || i < len(vb); i++ {
+		var x, y int
+		if i < len(va) {
+			x = va[i]
+		}
+		if i < len(vb) {
+			y = vb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// ValidateVersion checks the version is the dot separated numbers
+func ValidateVersion(version string) error {
+	_, err := parseVersion(version)
+	return err
+}
+
+func parseVersion(version string) ([]int, error) {
+	parts := strings.Split(strings.TrimPrefix(version, "v"), ".")
+	nums := make([]int, 0, len(parts))
+	for _, part := range parts {
+		num, err := strconv.Atoi(part)
+		if nil != err || num < 0 {
+			return nil, fmt.Errorf("invalid migration version %q", version)
+		}
+		nums = append(nums, num)
+	}
+	return nums, nil
+}
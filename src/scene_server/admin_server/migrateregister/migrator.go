@@ -0,0 +1,374 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package migrateregister
+
+import (
+	"configcenter/src/common/blog"
+	dbStorage "configcenter/src/storage"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// MigrationLedgerTable the collection of the applied migrations
+	MigrationLedgerTable = "cc_MigrationLedger"
+	// MigrationHistoryTable the collection of the migration runs, kept for audit
+	MigrationHistoryTable = "cc_MigrationHistory"
+	// MigrationLockTable the collection of the lock document which serializes the runs of all the processes
+	MigrationLockTable = "cc_MigrationLock"
+
+	// migrationLockName the name of the lock document
+	migrationLockName = "migration"
+	// migrationLockTTL the lock of a crashed run is taken over after it
+	migrationLockTTL = time.Hour
+
+	// MigrationDirectionUp applies the migration
+	MigrationDirectionUp = "up"
+	// MigrationDirectionDown reverts the migration
+	MigrationDirectionDown = "down"
+)
+
+// MigrationRecord the ledger record of the applied migration
+type MigrationRecord struct {
+	ID          string    `json:"migration_id" bson:"migration_id"`
+	Version     string    `json:"version" bson:"version"`
+	Description string    `json:"description" bson:"description"`
+	OwnerID     string    `json:"bk_supplier_account" bson:"bk_supplier_account"`
+	AppliedAt   time.Time `json:"applied_at" bson:"applied_at"`
+}
+
+// MigrationEvent the history record of one migration step
+type MigrationEvent struct {
+	ID        string    `json:"migration_id" bson:"migration_id"`
+	Version   string    `json:"version" bson:"version"`
+	Direction string    `json:"direction" bson:"direction"`
+	OwnerID   string    `json:"bk_supplier_account" bson:"bk_supplier_account"`
+	StartAt   time.Time `json:"start_at" bson:"start_at"`
+	Duration  int64     `json:"duration_ms" bson:"duration_ms"`
+	Error     string    `json:"error" bson:"error"`
+}
+
+// MigrationStatus the state of the registered migration
+type MigrationStatus struct {
+	ID          string     `json:"migration_id"`
+	Version     string     `json:"version"`
+	Description string     `json:"description"`
+	Applied     bool       `json:"applied"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty"`
+	Reversible  bool       `json:"reversible"`
+}
+
+// MigrationResult the result of the migration run
+type MigrationResult struct {
+	Direction string `json:"direction"`
+	DryRun    bool   `json:"dry_run"`
+	// Migrations the migrations run, or would run when DryRun
+	Migrations []MigrationStatus `json:"migrations"`
+}
+
+// ErrMigrationRunning another run holds the migration lock
+var ErrMigrationRunning = errors.New("another migration is running")
+
+// MigrationIndexes the indexes of the ledger and the lock, the ledger is kept per owner
+var MigrationIndexes = map[string][]dbStorage.Index{
+	MigrationLedgerTable: {
+		{Name: "idx_migration_id", Columns: []string{"migration_id", "bk_supplier_account"}, Type: dbStorage.INDEX_TYPE_BACKGROUP_UNIQUE},
+	},
+	MigrationLockTable: {
+		{Name: "idx_lock_name", Columns: []string{"lock_name"}, Type: dbStorage.INDEX_TYPE_BACKGROUP_UNIQUE},
+	},
+}
+
+// migrationLock the lock document, the unique lock_name fails the insert while it is held
+type migrationLock struct {
+	Name      string    `bson:"lock_name"`
+	Holder    string    `bson:"holder"`
+	OwnerID   string    `bson:"bk_supplier_account"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// Migrator runs the registered migrations against db and records them in the ledger
+type Migrator struct {
+	db         dbStorage.DI
+	migrations func() []Migration
+}
+
+// NewMigrator returns the migrator of the registered migrations
+func NewMigrator(db dbStorage.DI) *Migrator {
+	return &Migrator{db: db, migrations: GetMigrations}
+}
+
+// ensureLedger creates the indexes of the ledger and the lock, the ledger index of the earlier
+// versions is keyed by the migration only and gets recreated
+func (m *Migrator) ensureLedger(ctx context.Context) error {
+	drifts, err := dbStorage.ReconcileIndexes(ctx, m.db, MigrationIndexes, dbStorage.ReconcileOptions{})
+	if nil != err {
+		return err
+	}
+	for _, drift := range drifts {
+		if "" != drift.Error {
+			return fmt.Errorf("%s index %s of %s failed: %s", drift.Action, drift.Name, drift.Collection, drift.Error)
+		}
+	}
+	return nil
+}
+
+// lock takes the lock document and returns the function releasing it, ErrMigrationRunning is
+// returned while another run holds it
+func (m *Migrator) lock(ctx context.Context, ownerID string) (func(), error) {
+	hostname, _ := os.Hostname()
+	holder := fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano())
+	lock := migrationLock{
+		Name:      migrationLockName,
+		Holder:    holder,
+		OwnerID:   ownerID,
+		ExpiresAt: time.Now().UTC().Add(migrationLockTTL),
+	}
+
+	cdb := dbStorage.ContextOf(m.db)
+	if _, err := cdb.InsertCtx(ctx, MigrationLockTable, lock); nil != err {
+		held, cerr := cdb.GetCntByConditionCtx(ctx, MigrationLockTable, map[string]interface{}{"lock_name": migrationLockName})
+		if nil != cerr {
+			return nil, cerr
+		}
+		if 0 == held {
+			return nil, err
+		}
+		// take over the lock of a crashed run
+		expired := map[string]interface{}{
+			"lock_name":  migrationLockName,
+			"expires_at": map[string]interface{}{"$lt": time.Now().UTC()},
+		}
+		cnt, cerr := cdb.GetCntByConditionCtx(ctx, MigrationLockTable, expired)
+		if nil != cerr {
+			return nil, cerr
+		}
+		if 0 == cnt {
+			return nil, ErrMigrationRunning
+		}
+		blog.Warnf("take over the expired migration lock")
+		if err := cdb.DelByConditionCtx(ctx, MigrationLockTable, expired); nil != err {
+			return nil, err
+		}
+		if _, err := cdb.InsertCtx(ctx, MigrationLockTable, lock); nil != err {
+			return nil, ErrMigrationRunning
+		}
+	}
+
+	return func() {
+		cond := map[string]interface{}{"lock_name": migrationLockName, "holder": holder}
+		if err := cdb.DelByConditionCtx(context.Background(), MigrationLockTable, cond); nil != err {
+			blog.Errorf("release the migration lock failed: %v", err)
+		}
+	}, nil
+}
+
+// applied returns the ledger records of the owner
+func (m *Migrator) applied(ctx context.Context, ownerID string) (map[string]MigrationRecord, error) {
+	records := make([]MigrationRecord, 0)
+	cond := map[string]interface{}{"bk_supplier_account": ownerID}
+	err := dbStorage.ContextOf(m.db).GetMutilByConditionCtx(ctx, MigrationLedgerTable, nil, cond, &records, "", 0, 0)
+	if nil != err {
+		return nil, err
+	}
+	result := make(map[string]MigrationRecord, len(records))
+	for _, record := range records {
+		result[record.ID] = record
+	}
+	return result, nil
+}
+
+// Status returns all the registered migrations in order together with their states of the owner,
+// the ledger records of the unregistered migrations are left out
+func (m *Migrator) Status(ctx context.Context, ownerID string) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx, ownerID)
+	if nil != err {
+		return nil, err
+	}
+	ms := m.migrations()
+	status := make([]MigrationStatus, 0, len(ms))
+	for _, migration := range ms {
+		status = append(status, statusOf(migration, applied))
+	}
+	return status, nil
+}
+
+func statusOf(migration Migration, applied map[string]MigrationRecord) MigrationStatus {
+	s := MigrationStatus{
+		ID:          migration.ID,
+		Version:     migration.Version,
+		Description: migration.Description,
+		Reversible:  nil != migration.Down,
+	}
+	if record, ok := applied[migration.ID]; ok {
+		appliedAt := record.AppliedAt
+		s.Applied = true
+		s.AppliedAt = &appliedAt
+	}
+	return s
+}
+
+// Up applies the pending migrations whose version is not greater than target in order,
+// the empty target means the latest version, the pending migrations are only listed when dryRun
+func (m *Migrator) Up(ctx context.Context, ownerID, target string, dryRun bool) (*MigrationResult, error) {
+	if "" != target {
+		if err := ValidateVersion(target); nil != err {
+			return nil, err
+		}
+	}
+
+	if !dryRun {
+		unlock, err := m.run(ctx, ownerID)
+		if nil != err {
+			return nil, err
+		}
+		defer unlock()
+	}
+
+	applied, err := m.applied(ctx, ownerID)
+	if nil != err {
+		return nil, err
+	}
+	plan := make([]Migration, 0)
+	for _, migration := range m.migrations() {
+		if "" != target && CompareVersion(migration.Version, target) > 0 {
+			break
+		}
+		if _, ok := applied[migration.ID]; !ok {
+			plan = append(plan, migration)
+		}
+	}
+
+	result := &MigrationResult{Direction: MigrationDirectionUp, DryRun: dryRun, Migrations: make([]MigrationStatus, 0, len(plan))}
+	if dryRun {
+		for _, migration := range plan {
+			result.Migrations = append(result.Migrations, statusOf(migration, applied))
+		}
+		return result, nil
+	}
+
+	for _, migration := range plan {
+		if err := m.step(ctx, ownerID, migration, MigrationDirectionUp); nil != err {
+			return result, err
+		}
+		record := MigrationRecord{
+			ID:          migration.ID,
+			Version:     migration.Version,
+			Description: migration.Description,
+			OwnerID:     ownerID,
+			AppliedAt:   time.Now().UTC(),
+		}
+		if _, err := dbStorage.ContextOf(m.db).InsertCtx(ctx, MigrationLedgerTable, record); nil != err {
+			return result, fmt.Errorf("migration %s applied but not recorded: %v", migration.ID, err)
+		}
+		applied[migration.ID] = record
+		result.Migrations = append(result.Migrations, statusOf(migration, applied))
+	}
+	return result, nil
+}
+
+// Down reverts the applied migrations whose version is greater than target in reverse order,
+// it refuses to start when any of them could not be reverted
+func (m *Migrator) Down(ctx context.Context, ownerID, target string, dryRun bool) (*MigrationResult, error) {
+	if err := ValidateVersion(target); nil != err {
+		return nil, err
+	}
+
+	if !dryRun {
+		unlock, err := m.run(ctx, ownerID)
+		if nil != err {
+			return nil, err
+		}
+		defer unlock()
+	}
+
+	applied, err := m.applied(ctx, ownerID)
+	if nil != err {
+		return nil, err
+	}
+	ms := m.migrations()
+	plan := make([]Migration, 0)
+	for i := len(ms) - 1; i >= 0; i-- {
+		migration := ms[i]
+		if CompareVersion(migration.Version, target) <= 0 {
+			break
+		}
+		if _, ok := applied[migration.ID]; !ok {
+			continue
+		}
+		if nil == migration.Down {
+			return nil, fmt.Errorf("migration %s could not be reverted", migration.ID)
+		}
+		plan = append(plan, migration)
+	}
+
+	result := &MigrationResult{Direction: MigrationDirectionDown, DryRun: dryRun, Migrations: make([]MigrationStatus, 0, len(plan))}
+	for _, migration := range plan {
+		if !dryRun {
+			if err := m.step(ctx, ownerID, migration, MigrationDirectionDown); nil != err {
+				return result, err
+			}
+			cond := map[string]interface{}{"migration_id": migration.ID, "bk_supplier_account": ownerID}
+			if err := dbStorage.ContextOf(m.db).DelByConditionCtx(ctx, MigrationLedgerTable, cond); nil != err {
+				return result, fmt.Errorf("migration %s reverted but not recorded: %v", migration.ID, err)
+			}
+			delete(applied, migration.ID)
+		}
+		result.Migrations = append(result.Migrations, statusOf(migration, applied))
+	}
+	return result, nil
+}
+
+// run prepares the ledger and takes the lock for a run
+func (m *Migrator) run(ctx context.Context, ownerID string) (func(), error) {
+	if err := m.ensureLedger(ctx); nil != err {
+		return nil, err
+	}
+	return m.lock(ctx, ownerID)
+}
+
+// step runs one direction of the migration and appends the run to the history
+func (m *Migrator) step(ctx context.Context, ownerID string, migration Migration, direction string) error {
+	fn := migration.Up
+	if MigrationDirectionDown == direction {
+		fn = migration.Down
+	}
+
+	blog.Infof("migration %s(%s) %s start", migration.ID, migration.Version, direction)
+	event := MigrationEvent{
+		ID:        migration.ID,
+		Version:   migration.Version,
+		Direction: direction,
+		OwnerID:   ownerID,
+		StartAt:   time.Now().UTC(),
+	}
+	err := fn(ctx, ownerID, m.db)
+	event.Duration = int64(time.Since(event.StartAt) / time.Millisecond)
+	if nil != err {
+		event.Error = err.Error()
+		blog.Errorf("migration %s(%s) %s failed: %v", migration.ID, migration.Version, direction, err)
+	} else {
+		blog.Infof("migration %s(%s) %s done in %dms", migration.ID, migration.Version, direction, event.Duration)
+	}
+
+	if _, herr := dbStorage.ContextOf(m.db).InsertCtx(ctx, MigrationHistoryTable, event); nil != herr {
+		blog.Errorf("record the history of migration %s failed: %v", migration.ID, herr)
+	}
+	if nil != err {
+		return fmt.Errorf("migration %s %s failed: %v", migration.ID, direction, err)
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package migrateregister
+
+import (
+	"configcenter/src/storage"
+	"configcenter/src/storage/memclient"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMigrator(t *testing.T, ms ...Migration) (*Migrator, storage.DI) {
+	db, err := memclient.NewMemCli(t.Name())
+	require.NoError(t, err)
+	require.NoError(t, db.Open())
+	t.Cleanup(func() { memclient.Drop(t.Name()) })
+	return &Migrator{db: db, migrations: func() []Migration { return ms }}, db
+}
+
+func counterMigration(id, version string, counter map[string]int) Migration {
+	return Migration{
+		ID:      id,
+		Version: version,
+		Up: func(ctx context.Context, ownerID string, db storage.DI) error {
+			counter[id]++
+			return nil
+		},
+		Down: func(ctx context.Context, ownerID string, db storage.DI) error {
+			counter[id]--
+			return nil
+		},
+	}
+}
+
+func ids(result *MigrationResult) []string {
+	out := make([]string, 0)
+	for _, s := range result.Migrations {
+		out = append(out, s.ID)
+	}
+	return out
+}
+
+func TestCompareVersion(t *testing.T) {
+	require.Equal(t, 0, CompareVersion("3.0.8", "v3.0.8.0"))
+	require.Equal(t, -1, CompareVersion("3.0.8", "3.0.10"))
+	require.Equal(t, 1, CompareVersion("3.1", "3.0.10"))
+	require.Error(t, ValidateVersion("3.x"))
+	require.Error(t, ValidateVersion(""))
+}
+
+func TestMigratorUp(t *testing.T) {
+	counter := map[string]int{}
+	m, db := newTestMigrator(t,
+		counterMigration("a", "3.0.8", counter),
+		counterMigration("b", "3.0.9", counter),
+		counterMigration("c", "3.1.0", counter),
+	)
+	ctx := context.Background()
+
+	result, err := m.Up(ctx, "0", "3.0.9", true)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, ids(result))
+	require.Empty(t, counter)
+	cnt, err := db.GetCntByCondition(MigrationLedgerTable, nil)
+	require.NoError(t, err)
+	require.Equal(t, 0, cnt)
+
+	result, err = m.Up(ctx, "0", "3.0.9", false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, ids(result))
+	require.Equal(t, map[string]int{"a": 1, "b": 1}, counter)
+
+	// the applied migrations are not run again
+	result, err = m.Up(ctx, "0", "", false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"c"}, ids(result))
+	require.Equal(t, map[string]int{"a": 1, "b": 1, "c": 1}, counter)
+
+	result, err = m.Up(ctx, "0", "", false)
+	require.NoError(t, err)
+	require.Empty(t, result.Migrations)
+
+	status, err := m.Status(ctx, "0")
+	require.NoError(t, err)
+	require.Len(t, status, 3)
+	for _, s := range status {
+		require.True(t, s.Applied, s.ID)
+		require.NotNil(t, s.AppliedAt)
+	}
+
+	cnt, err = db.GetCntByCondition(MigrationHistoryTable, map[string]interface{}{"direction": MigrationDirectionUp})
+	require.NoError(t, err)
+	require.Equal(t, 3, cnt)
+}
+
+func TestMigratorUpFailure(t *testing.T) {
+	counter := map[string]int{}
+	broken := counterMigration("b", "3.0.9", counter)
+	broken.Up = func(ctx context.Context, ownerID string, db storage.DI) error {
+		return errors.New("broken")
+	}
+	m, db := newTestMigrator(t,
+		counterMigration("a", "3.0.8", counter),
+		broken,
+		counterMigration("c", "3.1.0", counter),
+	)
+	ctx := context.Background()
+
+	result, err := m.Up(ctx, "0", "", false)
+	require.Error(t, err)
+	require.Equal(t, []string{"a"}, ids(result))
+	require.Equal(t, map[string]int{"a": 1}, counter)
+
+	status, err := m.Status(ctx, "0")
+	require.NoError(t, err)
+	require.True(t, status[0].Applied)
+	require.False(t, status[1].Applied)
+	require.False(t, status[2].Applied)
+
+	cnt, err := db.GetCntByCondition(MigrationHistoryTable, map[string]interface{}{"migration_id": "b", "error": "broken"})
+	require.NoError(t, err)
+	require.Equal(t, 1, cnt)
+}
+
+func TestMigratorDown(t *testing.T) {
+	counter := map[string]int{}
+	irreversible := counterMigration("a", "3.0.8", counter)
+	irreversible.Down = nil
+	m, _ := newTestMigrator(t,
+		irreversible,
+		counterMigration("b", "3.0.9", counter),
+		counterMigration("c", "3.1.0", counter),
+	)
+	ctx := context.Background()
+
+	_, err := m.Up(ctx, "0", "", false)
+	require.NoError(t, err)
+
+	result, err := m.Down(ctx, "0", "3.0.8", true)
+	require.NoError(t, err)
+	require.Equal(t, []string{"c", "b"}, ids(result))
+	require.Equal(t, map[string]int{"a": 1, "b": 1, "c": 1}, counter)
+
+	result, err = m.Down(ctx, "0", "3.0.8", false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"c", "b"}, ids(result))
+	require.Equal(t, map[string]int{"a": 1, "b": 0, "c": 0}, counter)
+
+	// nothing left above the target
+	result, err = m.Down(ctx, "0", "3.0.8", false)
+	require.NoError(t, err)
+	require.Empty(t, result.Migrations)
+
+	_, err = m.Down(ctx, "0", "0", false)
+	require.Error(t, err)
+	require.Equal(t, 1, counter["a"])
+
+	// the reverted migrations are pending again
+	result, err = m.Up(ctx, "0", "", false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"b", "c"}, ids(result))
+}
+
+func TestMigratorOwners(t *testing.T) {
+	counter := map[string]int{}
+	m, db := newTestMigrator(t, counterMigration("a", "3.0.8", counter))
+	ctx := context.Background()
+
+	// the ledger index of the earlier versions is keyed by the migration only
+	require.NoError(t, db.Index(MigrationLedgerTable, storage.GetMongoIndex("idx_migration_id", []string{"migration_id"}, true, false)))
+
+	_, err := m.Up(ctx, "0", "", false)
+	require.NoError(t, err)
+	status, err := m.Status(ctx, "1")
+	require.NoError(t, err)
+	require.False(t, status[0].Applied)
+
+	result, err := m.Up(ctx, "1", "", false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a"}, ids(result))
+	require.Equal(t, 2, counter["a"])
+
+	_, err = m.Down(ctx, "1", "3.0.7", false)
+	require.NoError(t, err)
+	status, err = m.Status(ctx, "0")
+	require.NoError(t, err)
+	require.True(t, status[0].Applied)
+}
+
+func TestMigratorLock(t *testing.T) {
+	counter := map[string]int{}
+	m, db := newTestMigrator(t, counterMigration("a", "3.0.8", counter))
+	ctx := context.Background()
+
+	held := migrationLock{Name: migrationLockName, Holder: "other", ExpiresAt: time.Now().UTC().Add(time.Minute)}
+	_, err := db.Insert(MigrationLockTable, held)
+	require.NoError(t, err)
+	_, err = m.Up(ctx, "0", "", false)
+	require.Equal(t, ErrMigrationRunning, err)
+	require.Empty(t, counter)
+
+	// the dry run takes no lock
+	result, err := m.Up(ctx, "0", "", true)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a"}, ids(result))
+
+	// the lock of a crashed run is taken over once expired
+	require.NoError(t, db.DelByCondition(MigrationLockTable, nil))
+	held.ExpiresAt = time.Now().UTC().Add(-time.Minute)
+	_, err = db.Insert(MigrationLockTable, held)
+	require.NoError(t, err)
+	_, err = m.Up(ctx, "0", "", false)
+	require.NoError(t, err)
+	require.Equal(t, 1, counter["a"])
+
+	cnt, err := db.GetCntByCondition(MigrationLockTable, nil)
+	require.NoError(t, err)
+	require.Equal(t, 0, cnt, "the lock is released after the run")
+}
+
+func TestRegisterMigration(t *testing.T) {
+	noop := func(ctx context.Context, ownerID string, db storage.DI) error { return nil }
+	RegisterMigration(Migration{ID: "test_register", Version: "0.0.1", Up: noop})
+	defer func() {
+		migrationsLock.Lock()
+		delete(migrations, "test_register")
+		migrationsLock.Unlock()
+	}()
+
+	require.Panics(t, func() { RegisterMigration(Migration{ID: "test_register", Version: "0.0.2", Up: noop}) })
+	require.Panics(t, func() { RegisterMigration(Migration{ID: "test_bad_version", Version: "x", Up: noop}) })
+	require.Panics(t, func() { RegisterMigration(Migration{ID: "test_no_up", Version: "0.0.3"}) })
+}
@@ -19,6 +19,7 @@ import (
 	"configcenter/src/common/core/cc/actions"
 	"configcenter/src/scene_server/datacollection/datacollection/logics"
 	"configcenter/src/source_controller/common/instdata"
+	"context"
 	"fmt"
 	"gopkg.in/redis.v5"
 	"strconv"
@@ -70,7 +71,7 @@ func (d *dataCollectionAction) AutoExectueAction(config map[string]string) error
 func getChanName() (string, error) {
 	condition := map[string]interface{}{common.BKAppNameField: common.BKAppName}
 	results := []map[string]interface{}{}
-	if err := instdata.GetObjectByCondition(common.BKInnerObjIDApp, nil, condition, &results, "", 0, 0); err != nil {
+	if err := instdata.GetObjectByCondition(context.Background(), common.BKInnerObjIDApp, nil, condition, &results, "", 0, 0); err != nil {
 		return "", err
 	}
 	if len(results) <= 0 {
@@ -25,6 +25,7 @@ import (
 	"configcenter/src/scene_server/event_server/app/options"
 
 	_ "configcenter/src/scene_server/event_server/event_service/actions/subscription"
+	_ "configcenter/src/scene_server/event_server/event_service/actions/watch"
 	_ "configcenter/src/scene_server/event_server/event_service/distribution"
 	"github.com/spf13/pflag"
 	// _ "net/http/pprof"
@@ -0,0 +1,130 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package subscription
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/core/cc/actions"
+	paraparse "configcenter/src/common/paraparse"
+	"configcenter/src/common/util"
+	"configcenter/src/scene_server/event_server/event_service/distribution"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/emicklei/go-restful"
+	"github.com/tidwall/gjson"
+)
+
+// SearchDeadLetter list the events which used up their retries
+func (cli *subscriptionAction) SearchDeadLetter(req *restful.Request, resp *restful.Response) {
+	// get the language
+	language := util.GetActionLanguage(req)
+	// get the error factory by the language
+	defErr := cli.CC.Error.CreateDefaultCCErrorIf(language)
+
+	cli.CallResponseEx(func() (int, interface{}, error) {
+		var id int64
+		pathParameters := req.PathParameters()
+		if nil != cli.GetParams(cli.CC, &pathParameters, "subscribeID", &id, resp) {
+			return http.StatusBadRequest, nil, defErr.Errorf(common.CCErrCommParamsNeedSet, "subscription_id")
+		}
+
+		value, err := ioutil.ReadAll(req.Request.Body)
+		if err != nil {
+			blog.Error("read request body failed, error information is %s", err.Error())
+			return http.StatusBadRequest, nil, defErr.Error(common.CCErrCommHTTPReadBodyFailed)
+		}
+		var dat paraparse.SubscribeCommonSearch
+		if len(value) > 0 {
+			if err = json.Unmarshal(value, &dat); err != nil {
+				blog.Error("get dead letter: input:%s error:%v", value, err)
+				return http.StatusBadRequest, nil, defErr.Error(common.CCErrCommJSONUnmarshalFailed)
+			}
+		}
+
+		letters, count, err := distribution.ListDeadLetters(id, dat.Page.Start, dat.Page.Limit)
+		if err != nil {
+			blog.Errorf("list dead letter of subscription %d error: %v", id, err)
+			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrEventDeadLetterSelectFailed)
+		}
+
+		info := make(map[string]interface{})
+		info["count"] = count
+		info["info"] = letters
+		return http.StatusOK, info, nil
+	}, resp)
+}
+
+// ReplayDeadLetter push the dead letters back to the distribute queue
+func (cli *subscriptionAction) ReplayDeadLetter(req *restful.Request, resp *restful.Response) {
+	// get the language
+	language := util.GetActionLanguage(req)
+	// get the error factory by the language
+	defErr := cli.CC.Error.CreateDefaultCCErrorIf(language)
+
+	cli.CallResponseEx(func() (int, interface{}, error) {
+		var id int64
+		pathParameters := req.PathParameters()
+		if nil != cli.GetParams(cli.CC, &pathParameters, "subscribeID", &id, resp) {
+			return http.StatusBadRequest, nil, defErr.Errorf(common.CCErrCommParamsNeedSet, "subscription_id")
+		}
+
+		value, err := ioutil.ReadAll(req.Request.Body)
+		if err != nil {
+			blog.Error("read request body failed, error information is %s", err.Error())
+			return http.StatusBadRequest, nil, defErr.Error(common.CCErrCommHTTPReadBodyFailed)
+		}
+		limit := int(gjson.GetBytes(value, "limit").Int())
+
+		replayed, err := distribution.ReplayDeadLetters(id, limit)
+		if err != nil {
+			blog.Errorf("replay dead letter of subscription %d error: %v", id, err)
+			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrEventDeadLetterReplayFailed)
+		}
+		blog.Infof("replayed %d dead letters of subscription %d", replayed, id)
+
+		info := make(map[string]interface{})
+		info["count"] = replayed
+		return http.StatusOK, info, nil
+	}, resp)
+}
+
+// PurgeDeadLetter drop all the dead letters of the subscription
+func (cli *subscriptionAction) PurgeDeadLetter(req *restful.Request, resp *restful.Response) {
+	// get the language
+	language := util.GetActionLanguage(req)
+	// get the error factory by the language
+	defErr := cli.CC.Error.CreateDefaultCCErrorIf(language)
+
+	cli.CallResponseEx(func() (int, interface{}, error) {
+		var id int64
+		pathParameters := req.PathParameters()
+		if nil != cli.GetParams(cli.CC, &pathParameters, "subscribeID", &id, resp) {
+			return http.StatusBadRequest, nil, defErr.Errorf(common.CCErrCommParamsNeedSet, "subscription_id")
+		}
+
+		if err := distribution.PurgeDeadLetters(id); err != nil {
+			blog.Errorf("purge dead letter of subscription %d error: %v", id, err)
+			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrEventDeadLetterPurgeFailed)
+		}
+		return http.StatusOK, nil, nil
+	}, resp)
+}
+
+func init() {
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPSelectPost, Path: "/subscribe/{ownerID}/{appID}/{subscribeID}/deadletter/search", Params: nil, Handler: eventSubscription.SearchDeadLetter})
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPUpdate, Path: "/subscribe/{ownerID}/{appID}/{subscribeID}/deadletter/replay", Params: nil, Handler: eventSubscription.ReplayDeadLetter})
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPDelete, Path: "/subscribe/{ownerID}/{appID}/{subscribeID}/deadletter", Params: nil, Handler: eventSubscription.PurgeDeadLetter})
+}
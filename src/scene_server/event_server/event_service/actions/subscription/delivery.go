@@ -0,0 +1,85 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package subscription
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/core/cc/actions"
+	paraparse "configcenter/src/common/paraparse"
+	"configcenter/src/common/util"
+	"configcenter/src/scene_server/event_server/event_service/distribution"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/emicklei/go-restful"
+)
+
+// SearchDelivery list the recent callback attempts of the subscription, newest first
+func (cli *subscriptionAction) SearchDelivery(req *restful.Request, resp *restful.Response) {
+	// get the language
+	language := util.GetActionLanguage(req)
+	// get the error factory by the language
+	defErr := cli.CC.Error.CreateDefaultCCErrorIf(language)
+
+	cli.CallResponseEx(func() (int, interface{}, error) {
+		var id int64
+		pathParameters := req.PathParameters()
+		if nil != cli.GetParams(cli.CC, &pathParameters, "subscribeID", &id, resp) {
+			return http.StatusBadRequest, nil, defErr.Errorf(common.CCErrCommParamsNeedSet, "subscription_id")
+		}
+
+		value, err := ioutil.ReadAll(req.Request.Body)
+		if err != nil {
+			blog.Error("read request body failed, error information is %s", err.Error())
+			return http.StatusBadRequest, nil, defErr.Error(common.CCErrCommHTTPReadBodyFailed)
+		}
+		var dat paraparse.SubscribeCommonSearch
+		if len(value) > 0 {
+			if err = json.Unmarshal(value, &dat); err != nil {
+				blog.Error("get delivery: input:%s error:%v", value, err)
+				return http.StatusBadRequest, nil, defErr.Error(common.CCErrCommJSONUnmarshalFailed)
+			}
+		}
+
+		cond := distribution.DeliveryCondition{}
+		if eventID, ok := dat.Condition["event_id"]; ok {
+			if cond.EventID, err = util.GetInt64ByInterface(eventID); err != nil {
+				return http.StatusBadRequest, nil, defErr.Errorf(common.CCErrCommParamsInvalid, "event_id")
+			}
+		}
+		if success, ok := dat.Condition["success"]; ok {
+			val, isBool := success.(bool)
+			if !isBool {
+				return http.StatusBadRequest, nil, defErr.Errorf(common.CCErrCommParamsInvalid, "success")
+			}
+			cond.Success = &val
+		}
+
+		deliveries, count, err := distribution.ListDeliveries(id, cond, dat.Page.Start, dat.Page.Limit)
+		if err != nil {
+			blog.Errorf("list delivery of subscription %d error: %v", id, err)
+			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrEventDeliverySelectFailed)
+		}
+
+		info := make(map[string]interface{})
+		info["count"] = count
+		info["info"] = deliveries
+		return http.StatusOK, info, nil
+	}, resp)
+}
+
+func init() {
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPSelectPost, Path: "/subscribe/{ownerID}/{appID}/{subscribeID}/deliveries", Params: nil, Handler: eventSubscription.SearchDelivery})
+}
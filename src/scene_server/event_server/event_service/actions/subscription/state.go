@@ -0,0 +1,91 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package subscription
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/core/cc/actions"
+	"configcenter/src/common/util"
+	"configcenter/src/scene_server/event_server/event_service/distribution"
+	"configcenter/src/scene_server/event_server/types"
+	"configcenter/src/source_controller/common/instdata"
+	"net/http"
+
+	"github.com/emicklei/go-restful"
+)
+
+// Pause stop delivering the events to the subscription, the events are buffered until resumed
+func (cli *subscriptionAction) Pause(req *restful.Request, resp *restful.Response) {
+	// get the language
+	language := util.GetActionLanguage(req)
+	// get the error factory by the language
+	defErr := cli.CC.Error.CreateDefaultCCErrorIf(language)
+
+	cli.CallResponseEx(func() (int, interface{}, error) {
+		var id int64
+		pathParameters := req.PathParameters()
+		if nil != cli.GetParams(cli.CC, &pathParameters, "subscribeID", &id, resp) {
+			return http.StatusBadRequest, nil, defErr.Errorf(common.CCErrCommParamsNeedSet, "subscription_id")
+		}
+
+		sub := types.Subscription{}
+		condiction := util.NewMapBuilder(common.BKSubscriptionIDField, id).Build()
+		if err := instdata.GetOneSubscriptionByCondition(condiction, &sub); err != nil {
+			blog.Error("fail to get subscription by id %v, error information is %v", id, err)
+			return http.StatusBadRequest, nil, defErr.Error(common.CCErrEventSubscribePauseFailed)
+		}
+
+		if err := distribution.PauseSubscription(id); err != nil {
+			blog.Errorf("pause subscription %d error: %v", id, err)
+			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrEventSubscribePauseFailed)
+		}
+		blog.Infof("subscription %d paused", id)
+		return http.StatusOK, nil, nil
+	}, resp)
+}
+
+// Resume restart delivering the events to the paused or tripped subscription
+func (cli *subscriptionAction) Resume(req *restful.Request, resp *restful.Response) {
+	// get the language
+	language := util.GetActionLanguage(req)
+	// get the error factory by the language
+	defErr := cli.CC.Error.CreateDefaultCCErrorIf(language)
+
+	cli.CallResponseEx(func() (int, interface{}, error) {
+		var id int64
+		pathParameters := req.PathParameters()
+		if nil != cli.GetParams(cli.CC, &pathParameters, "subscribeID", &id, resp) {
+			return http.StatusBadRequest, nil, defErr.Errorf(common.CCErrCommParamsNeedSet, "subscription_id")
+		}
+
+		sub := types.Subscription{}
+		condiction := util.NewMapBuilder(common.BKSubscriptionIDField, id).Build()
+		if err := instdata.GetOneSubscriptionByCondition(condiction, &sub); err != nil {
+			blog.Error("fail to get subscription by id %v, error information is %v", id, err)
+			return http.StatusBadRequest, nil, defErr.Error(common.CCErrEventSubscribeResumeFailed)
+		}
+
+		if err := distribution.ResumeSubscription(id); err != nil {
+			blog.Errorf("resume subscription %d error: %v", id, err)
+			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrEventSubscribeResumeFailed)
+		}
+		blog.Infof("subscription %d resumed", id)
+		return http.StatusOK, nil, nil
+	}, resp)
+}
+
+func init() {
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPUpdate, Path: "/subscribe/{ownerID}/{appID}/{subscribeID}/pause", Params: nil, Handler: eventSubscription.Pause})
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPUpdate, Path: "/subscribe/{ownerID}/{appID}/{subscribeID}/resume", Params: nil, Handler: eventSubscription.Resume})
+}
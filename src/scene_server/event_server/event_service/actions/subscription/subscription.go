@@ -23,6 +23,8 @@ import (
 	commontypes "configcenter/src/common/types"
 	"configcenter/src/common/util"
 	sencecommon "configcenter/src/scene_server/common"
+	"configcenter/src/scene_server/event_server/event_service/distribution"
+	"configcenter/src/scene_server/event_server/event_service/filter"
 	"configcenter/src/scene_server/event_server/types"
 	"configcenter/src/source_controller/common/instdata"
 	"encoding/json"
@@ -76,6 +78,10 @@ func (cli *subscriptionAction) Subscribe(req *restful.Request, resp *restful.Res
 		}
 		sub.LastTime = &now
 		sub.SubscriptionForm = strings.Replace(sub.SubscriptionForm, " ", "", 0)
+		if field, err := validateSubscription(sub); err != nil {
+			blog.Errorf("invalid subscription %s: %v", field, err)
+			return http.StatusBadRequest, nil, defErr.Errorf(common.CCErrCommParamsInvalid, field)
+		}
 
 		count, err := instdata.GetSubscriptionCntByCondition(map[string]interface{}{"subscription_name": sub.SubscriptionName})
 		if err != nil || count > 0 {
@@ -100,8 +106,15 @@ func (cli *subscriptionAction) Subscribe(req *restful.Request, resp *restful.Res
 			}
 		}
 
-		mesg, _ := json.Marshal(&sub)
 		redisCli := cli.CC.CacheCli.GetSession().(*redis.Client)
+		if sub.Filter != "" {
+			if err := redisCli.Set(types.EventCacheSubscribeFilterPrefix+fmt.Sprint(sub.SubscriptionID), sub.Filter, 0).Err(); err != nil {
+				blog.Error("create subscription failed, error:%s", err.Error())
+				return http.StatusInternalServerError, nil, defErr.Error(common.CCErrEventSubscribeInsertFailed)
+			}
+		}
+
+		mesg, _ := json.Marshal(&sub)
 		redisCli.Publish(types.EventCacheProcessChannel, "create"+string(mesg))
 		redisCli.Del(types.EventCacheDistCallBackCountPrefix + fmt.Sprint(sub.SubscriptionID))
 
@@ -151,9 +164,17 @@ func (cli *subscriptionAction) UnSubscribe(req *restful.Request, resp *restful.R
 			}
 		}
 
-		redisCli.Del(types.EventCacheDistIDPrefix+subID,
-			types.EventCacheDistQueuePrefix+subID,
-			types.EventCacheDistDonePrefix+subID)
+		keys := []string{types.EventCacheDistIDPrefix + subID,
+			types.EventCacheDistQueuePrefix + subID,
+			types.EventCacheDistDonePrefix + subID,
+			types.EventCacheDistDeadLetterPrefix + subID,
+			types.EventCacheDistDeliveryPrefix + subID,
+			types.EventCacheDistStatePrefix + subID,
+			types.EventCacheSubscribeFilterPrefix + subID}
+		for partition := 0; partition < types.MaxPartitions; partition++ {
+			keys = append(keys, types.DistPartitionKey(subID, partition))
+		}
+		redisCli.Del(keys...)
 
 		mesg, _ := json.Marshal(&sub)
 		redisCli.Publish(types.EventCacheProcessChannel, "delete"+string(mesg))
@@ -208,12 +229,22 @@ func (cli *subscriptionAction) Rebook(req *restful.Request, resp *restful.Respon
 		}
 
 		sub.SubscriptionID = oldsub.SubscriptionID
+		// the secret is never returned by query, keep the old one unless it is set, an empty secret clears it
+		fields := map[string]interface{}{}
+		json.Unmarshal(value, &fields)
+		if _, ok := fields["secret"]; !ok {
+			sub.Secret = oldsub.Secret
+		}
 		if sub.TimeOut <= 0 {
 			sub.TimeOut = 10
 		}
 		now := commontypes.Now()
 		sub.LastTime = &now
 		sub.SubscriptionForm = strings.Replace(sub.SubscriptionForm, " ", "", 0)
+		if field, err := validateSubscription(sub); err != nil {
+			blog.Errorf("invalid subscription %s: %v", field, err)
+			return http.StatusBadRequest, nil, defErr.Errorf(common.CCErrCommParamsInvalid, field)
+		}
 		sub.Operator = sencecommon.GetUserFromHeader(req)
 		if updateerr := instdata.UpdateSubscriptionByCondition(sub, util.NewMapBuilder(common.BKSubscriptionIDField, id).Build()); nil != updateerr {
 			blog.Error("fail update subscription by condition, error information is %s", updateerr.Error())
@@ -247,14 +278,73 @@ func (cli *subscriptionAction) Rebook(req *restful.Request, resp *restful.Respon
 			}
 		}
 
-		mesg, _ := json.Marshal(&sub)
 		redisCli := cli.CC.CacheCli.GetSession().(*redis.Client)
+		filterKey := types.EventCacheSubscribeFilterPrefix + fmt.Sprint(sub.SubscriptionID)
+		if sub.Filter != "" {
+			err = redisCli.Set(filterKey, sub.Filter, 0).Err()
+		} else {
+			err = redisCli.Del(filterKey).Err()
+		}
+		if err != nil {
+			blog.Error("update subscription filter failed, error:%s", err.Error())
+			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrEventSubscribeUpdateFailed)
+		}
+
+		mesg, _ := json.Marshal(&sub)
 		redisCli.Publish(types.EventCacheProcessChannel, "update"+string(mesg))
 
 		return http.StatusOK, nil, nil
 	}, resp)
 }
 
+// Replay push the persisted events in the time range or id range to the subscription
+func (cli *subscriptionAction) Replay(req *restful.Request, resp *restful.Response) {
+	// get the language
+	language := util.GetActionLanguage(req)
+	// get the error factory by the language
+	defErr := cli.CC.Error.CreateDefaultCCErrorIf(language)
+
+	cli.CallResponseEx(func() (int, interface{}, error) {
+		var id int64
+		pathParameters := req.PathParameters()
+		if nil != cli.GetParams(cli.CC, &pathParameters, "subscribeID", &id, resp) {
+			return http.StatusBadRequest, nil, defErr.Errorf(common.CCErrCommParamsNeedSet, "subscription_id")
+		}
+
+		value, err := ioutil.ReadAll(req.Request.Body)
+		if err != nil {
+			blog.Error("read request body failed, error information is %s", err.Error())
+			return http.StatusBadRequest, nil, defErr.Error(common.CCErrCommHTTPReadBodyFailed)
+		}
+		params := types.ReplayParams{}
+		if jserr := json.Unmarshal(value, &params); nil != jserr {
+			blog.Error("unmarshal json failed, error information is %v", jserr)
+			return http.StatusBadRequest, nil, defErr.Error(common.CCErrCommJSONUnmarshalFailed)
+		}
+		if params.StartID <= 0 && params.EndID <= 0 && params.StartTime == nil && params.EndTime == nil {
+			return http.StatusBadRequest, nil, defErr.Errorf(common.CCErrCommParamsNeedSet, "start_time")
+		}
+
+		sub := types.Subscription{}
+		condiction := util.NewMapBuilder(common.BKSubscriptionIDField, id).Build()
+		if err := instdata.GetOneSubscriptionByCondition(condiction, &sub); err != nil {
+			blog.Error("fail to get subscription by id %v, error information is %v", id, err)
+			return http.StatusBadRequest, nil, defErr.Error(common.CCErrEventReplayFailed)
+		}
+
+		err = distribution.ReplayEvents(sub, params)
+		if err == distribution.ErrReplayRunning {
+			return http.StatusBadRequest, nil, defErr.Error(common.CCErrEventReplayRunning)
+		}
+		if err != nil {
+			blog.Errorf("replay events to subscription %d error: %v", id, err)
+			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrEventReplayFailed)
+		}
+		blog.Infof("start replaying events to subscription %d", id)
+		return http.StatusOK, nil, nil
+	}, resp)
+}
+
 func (cli *subscriptionAction) Query(req *restful.Request, resp *restful.Response) {
 	// get the language
 	language := util.GetActionLanguage(req)
@@ -309,6 +399,10 @@ func (cli *subscriptionAction) Query(req *restful.Request, resp *restful.Respons
 				Total:   total,
 				Failure: failue,
 			}
+			sub.Secret = ""
+			if sub.State, err = distribution.GetSubscriptionState(sub.SubscriptionID); err != nil {
+				blog.Errorf("get state of subscription %d error: %v", sub.SubscriptionID, err)
+			}
 		}
 
 		info := make(map[string]interface{})
@@ -398,6 +492,76 @@ func getDailAddress(URL string) (string, error) {
 	return uri.Hostname() + ":" + port, err
 }
 
+// validateSubscription checks the settings of the subscription to create or update, it returns the invalid
+// field and why, the filter is trimmed and the unset payload mode takes the full mode
+func validateSubscription(sub *types.Subscription) (string, error) {
+	sub.Filter = strings.TrimSpace(sub.Filter)
+	if _, err := filter.Compile(sub.Filter); sub.Filter != "" && err != nil {
+		return "filter", err
+	}
+	if sub.PayloadMode == "" {
+		sub.PayloadMode = types.PayloadModeFull
+	}
+	if sub.PayloadMode != types.PayloadModeFull && sub.PayloadMode != types.PayloadModeCompact {
+		return "payload_mode", fmt.Errorf("unknown payload mode %s", sub.PayloadMode)
+	}
+	if sub.Partitions < 0 || sub.Partitions > types.MaxPartitions {
+		return "partitions", fmt.Errorf("%d out of [0, %d]", sub.Partitions, types.MaxPartitions)
+	}
+	if field := checkSink(sub); field != "" {
+		return field, fmt.Errorf("invalid %s sink %+v", sub.GetSinkType(), sub.SinkConfig)
+	}
+	if policy := sub.RetryPolicy; policy != nil {
+		if policy.MaxAttempts < 0 || policy.MaxAttempts > types.MaxRetryAttempts {
+			return "retry_policy.max_attempts", fmt.Errorf("%d out of [0, %d]", policy.MaxAttempts, types.MaxRetryAttempts)
+		}
+		if policy.BackoffBase < 0 || policy.BackoffBase > types.RetryBackoffMaxTotal {
+			return "retry_policy.backoff_base", fmt.Errorf("%d out of [0, %d]", policy.BackoffBase, types.RetryBackoffMaxTotal)
+		}
+		if policy.BackoffCap < 0 || policy.BackoffCap > types.RetryBackoffMaxTotal {
+			return "retry_policy.backoff_cap", fmt.Errorf("%d out of [0, %d]", policy.BackoffCap, types.RetryBackoffMaxTotal)
+		}
+		if policy.BackoffCap > 0 && policy.BackoffCap < sub.GetRetryPolicy().BackoffBase {
+			return "retry_policy.backoff_cap", fmt.Errorf("%d less than the backoff base", policy.BackoffCap)
+		}
+	}
+	if breaker := sub.CircuitBreaker; breaker != nil {
+		// the negative threshold disables the breaker
+		if breaker.FailureThreshold > types.MaxBreakerFailureThreshold {
+			return "circuit_breaker.failure_threshold", fmt.Errorf("%d over %d", breaker.FailureThreshold, types.MaxBreakerFailureThreshold)
+		}
+		if breaker.ProbeInterval < 0 || breaker.ProbeInterval > types.MaxBreakerProbeInterval {
+			return "circuit_breaker.probe_interval", fmt.Errorf("%d out of [0, %d]", breaker.ProbeInterval, types.MaxBreakerProbeInterval)
+		}
+	}
+	return "", nil
+}
+
+// checkSink returns the invalid field name of the sink settings, empty means valid
+func checkSink(sub *types.Subscription) string {
+	switch sub.GetSinkType() {
+	case types.SinkTypeHTTP:
+		return ""
+	case types.SinkTypeRedisStream:
+		if sub.SinkConfig == nil || sub.SinkConfig.Stream == "" {
+			return "sink_config.stream"
+		}
+	case types.SinkTypeKafka:
+		if sub.SinkConfig == nil || strings.TrimSpace(sub.SinkConfig.Brokers) == "" {
+			return "sink_config.brokers"
+		}
+		if sub.SinkConfig.Topic == "" {
+			return "sink_config.topic"
+		}
+		if sub.SinkConfig.Partition < 0 {
+			return "sink_config.partition"
+		}
+	default:
+		return "sink_type"
+	}
+	return ""
+}
+
 func init() {
 	actions.RegisterNewAction(actions.Action{Verb: common.HTTPSelectPost, Path: "/subscribe/search/{ownerID}/{appID}", Params: nil, Handler: eventSubscription.Query})
 	actions.RegisterNewAction(actions.Action{Verb: common.HTTPSelectPost, Path: "/subscribe/ping", Params: nil, Handler: eventSubscription.Ping})
@@ -405,6 +569,7 @@ func init() {
 	actions.RegisterNewAction(actions.Action{Verb: common.HTTPCreate, Path: "/subscribe/{ownerID}/{appID}", Params: nil, Handler: eventSubscription.Subscribe})
 	actions.RegisterNewAction(actions.Action{Verb: common.HTTPDelete, Path: "/subscribe/{ownerID}/{appID}/{subscribeID}", Params: nil, Handler: eventSubscription.UnSubscribe})
 	actions.RegisterNewAction(actions.Action{Verb: common.HTTPUpdate, Path: "/subscribe/{ownerID}/{appID}/{subscribeID}", Params: nil, Handler: eventSubscription.Rebook})
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPCreate, Path: "/subscribe/{ownerID}/{appID}/{subscribeID}/replay", Params: nil, Handler: eventSubscription.Replay})
 
 	// create cc subscription
 	eventSubscription.CreateAction()
@@ -122,3 +122,31 @@ func TestTelnet(t *testing.T) {
 	require.Equal(t, "www.qq.com:80", uri)
 
 }
+
+func TestValidateSubscription(t *testing.T) {
+	sub := &types.Subscription{Filter: ` bk_os_type == "Linux" `, RetryPolicy: &types.RetryPolicy{MaxAttempts: 5}}
+	field, err := validateSubscription(sub)
+	require.NoError(t, err, field)
+	require.Equal(t, types.PayloadModeFull, sub.PayloadMode)
+	require.Equal(t, `bk_os_type == "Linux"`, sub.Filter)
+
+	// the values out of range are rejected rather than replaced by the defaults
+	invalid := map[string]types.Subscription{
+		"payload_mode":                      {PayloadMode: "none"},
+		"partitions":                        {Partitions: types.MaxPartitions + 1},
+		"retry_policy.max_attempts":         {RetryPolicy: &types.RetryPolicy{MaxAttempts: -1}},
+		"retry_policy.backoff_base":         {RetryPolicy: &types.RetryPolicy{BackoffBase: types.RetryBackoffMaxTotal + 1}},
+		"retry_policy.backoff_cap":          {RetryPolicy: &types.RetryPolicy{BackoffBase: 2000, BackoffCap: 1000}},
+		"circuit_breaker.failure_threshold": {CircuitBreaker: &types.CircuitBreaker{FailureThreshold: types.MaxBreakerFailureThreshold + 1}},
+		"circuit_breaker.probe_interval":    {CircuitBreaker: &types.CircuitBreaker{ProbeInterval: -1}},
+	}
+	for want, sub := range invalid {
+		field, err := validateSubscription(&sub)
+		require.Error(t, err, want)
+		require.Equal(t, want, field)
+	}
+
+	// the negative threshold disables the breaker
+	field, err = validateSubscription(&types.Subscription{CircuitBreaker: &types.CircuitBreaker{FailureThreshold: -1}})
+	require.NoError(t, err, field)
+}
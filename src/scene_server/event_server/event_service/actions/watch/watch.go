@@ -0,0 +1,134 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/common/bkbase"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/core/cc/actions"
+	"configcenter/src/common/util"
+	"configcenter/src/scene_server/event_server/event_service/distribution"
+	"configcenter/src/scene_server/event_server/types"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/emicklei/go-restful"
+)
+
+var eventWatch = &watchAction{}
+
+type watchAction struct {
+	base.BaseAction
+}
+
+// pollInterval how often the event log is checked while waiting for new events
+var pollInterval = time.Millisecond * 500
+
+// Watch block until there are new events after the cursor or timeout
+func (cli *watchAction) Watch(req *restful.Request, resp *restful.Response) {
+	// get the language
+	language := util.GetActionLanguage(req)
+	// get the error factory by the language
+	defErr := cli.CC.Error.CreateDefaultCCErrorIf(language)
+
+	cli.CallResponseEx(func() (int, interface{}, error) {
+		value, err := ioutil.ReadAll(req.Request.Body)
+		if err != nil {
+			blog.Error("read request body failed, error information is %s", err.Error())
+			return http.StatusBadRequest, nil, defErr.Error(common.CCErrCommHTTPReadBodyFailed)
+		}
+		params := types.WatchParams{}
+		if len(value) > 0 {
+			if err = json.Unmarshal(value, &params); err != nil {
+				blog.Error("unmarshal json failed, error information is %v", err)
+				return http.StatusBadRequest, nil, defErr.Error(common.CCErrCommJSONUnmarshalFailed)
+			}
+		}
+		normalizeWatchParams(&params)
+		if params.Cursor < 0 {
+			if params.Cursor, err = distribution.LatestEventID(); err != nil {
+				blog.Errorf("get latest event id error: %v", err)
+				return http.StatusInternalServerError, nil, defErr.Error(common.CCErrEventWatchFailed)
+			}
+		}
+		result := types.WatchResult{Cursor: params.Cursor, Events: []types.EventInst{}}
+		deadline := time.Now().Add(time.Second * time.Duration(params.Timeout))
+		for {
+			events, next, err := distribution.ReadEventLog(result.Cursor, params.Limit)
+			if err == distribution.ErrCursorExpired {
+				blog.Errorf("watch cursor %d expired", result.Cursor)
+				return http.StatusBadRequest, nil, defErr.Error(common.CCErrEventWatchCursorExpired)
+			}
+			if err != nil {
+				blog.Errorf("read event log error: %v", err)
+				return http.StatusInternalServerError, nil, defErr.Error(common.CCErrEventWatchFailed)
+			}
+			result.Cursor = next
+			result.Events = append(result.Events, filterWatchEvents(events, params.EventTypes)...)
+			if len(result.Events) > 0 || time.Now().After(deadline) {
+				return http.StatusOK, result, nil
+			}
+			if len(events) == 0 {
+				select {
+				case <-req.Request.Context().Done():
+					return http.StatusOK, result, nil
+				case <-time.After(pollInterval):
+				}
+			}
+		}
+	}, resp)
+}
+
+// normalizeWatchParams fill the default limit and timeout and bound them to the max
+func normalizeWatchParams(params *types.WatchParams) {
+	if params.Limit <= 0 {
+		params.Limit = types.WatchDefaultLimit
+	} else if params.Limit > types.WatchMaxLimit {
+		params.Limit = types.WatchMaxLimit
+	}
+	if params.Timeout <= 0 {
+		params.Timeout = types.WatchDefaultTimeout
+	} else if params.Timeout > types.WatchMaxTimeout {
+		params.Timeout = types.WatchMaxTimeout
+	}
+}
+
+// filterWatchEvents returns the events of the watched event types, all types are watched if eventTypes is empty
+func filterWatchEvents(events []types.EventInst, eventTypes []string) []types.EventInst {
+	watched := map[string]bool{}
+	for _, eventType := range eventTypes {
+		watched[eventType] = true
+	}
+	result := []types.EventInst{}
+	for _, event := range events {
+		dist := event.GetDistInst()
+		if dist == nil {
+			continue
+		}
+		if len(watched) > 0 && !watched[dist.GetType()] {
+			continue
+		}
+		result = append(result, dist.EventInst)
+	}
+	return result
+}
+
+func init() {
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPSelectPost, Path: "/watch/{ownerID}/{appID}", Params: nil, Handler: eventWatch.Watch})
+
+	// create cc watch
+	eventWatch.CreateAction()
+}
@@ -0,0 +1,59 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/scene_server/event_server/types"
+	"testing"
+)
+
+func TestNormalizeWatchParams(t *testing.T) {
+	params := types.WatchParams{}
+	normalizeWatchParams(&params)
+	if params.Limit != types.WatchDefaultLimit || params.Timeout != types.WatchDefaultTimeout {
+		t.Fatalf("should fill the defaults, got %+v", params)
+	}
+
+	params = types.WatchParams{Limit: types.WatchMaxLimit + 1, Timeout: types.WatchMaxTimeout + 1}
+	normalizeWatchParams(&params)
+	if params.Limit != types.WatchMaxLimit || params.Timeout != types.WatchMaxTimeout {
+		t.Fatalf("should bound to the max, got %+v", params)
+	}
+
+	params = types.WatchParams{Limit: 10, Timeout: 3}
+	normalizeWatchParams(&params)
+	if params.Limit != 10 || params.Timeout != 3 {
+		t.Fatalf("should keep the valid values, got %+v", params)
+	}
+}
+
+func TestFilterWatchEvents(t *testing.T) {
+	events := []types.EventInst{
+		{ID: 1, EventType: types.EventTypeInstData, Action: "create", ObjType: "host"},
+		{ID: 2, EventType: types.EventTypeInstData, Action: "delete", ObjType: "host"},
+		{ID: 3, EventType: types.EventTypeInstData, Action: "create", ObjType: common.BKINnerObjIDObject, CurData: map[string]interface{}{common.BKObjIDField: "switch"}},
+		// an object instance without data can not be resolved
+		{ID: 4, EventType: types.EventTypeInstData, Action: "create", ObjType: common.BKINnerObjIDObject},
+	}
+
+	result := filterWatchEvents(events, nil)
+	if len(result) != 3 {
+		t.Fatalf("should watch all the resolved events, got %+v", result)
+	}
+
+	result = filterWatchEvents(events, []string{"hostcreate", "switchcreate"})
+	if len(result) != 2 || result[0].ID != 1 || result[1].ID != 3 || result[1].ObjType != "switch" {
+		t.Fatalf("should keep the watched event types only, got %+v", result)
+	}
+}
@@ -17,6 +17,9 @@ import (
 	"configcenter/src/common/core/cc/api"
 	"configcenter/src/common/http/httpclient"
 	"configcenter/src/scene_server/event_server/types"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	redis "gopkg.in/redis.v5"
 	"io/ioutil"
@@ -27,6 +30,45 @@ import (
 )
 
 func SendCallback(receiver *types.Subscription, event string) (err error) {
+	_, _, err = sendCallback(receiver, event, nil)
+	return err
+}
+
+// SendDistCallback send the dist to the receiver with the delivery headers,
+// the request will be signed when the receiver has a secret
+func SendDistCallback(receiver *types.Subscription, dist *types.DistInstCtx) (err error) {
+	_, _, err = sendDistCallback(receiver, dist)
+	return err
+}
+
+// sendDistCallback returns the response status code and body as well, they are kept in the delivery history
+func sendDistCallback(receiver *types.Subscription, dist *types.DistInstCtx) (statusCode int, respdata []byte, err error) {
+	return sendCallback(receiver, dist.Raw, deliveryHeader(receiver, dist))
+}
+
+// deliveryHeader returns the delivery id, the event id and the signature of the dist,
+// they are sent along with the event by all the sinks
+func deliveryHeader(receiver *types.Subscription, dist *types.DistInstCtx) http.Header {
+	header := http.Header{}
+	header.Set(types.CallbackHeaderDeliveryID, fmt.Sprintf("%d-%d", dist.SubscriptionID, dist.DstbID))
+	header.Set(types.CallbackHeaderEventID, fmt.Sprint(dist.ID))
+	if receiver.Secret != "" {
+		timestamp := time.Now().Unix()
+		header.Set(types.CallbackHeaderTimestamp, fmt.Sprint(timestamp))
+		header.Set(types.CallbackHeaderSignature, SignPayload(receiver.Secret, timestamp, []byte(dist.Raw)))
+	}
+	return header
+}
+
+// SignPayload returns the HMAC-SHA256 signature of "timestamp.body"
+func SignPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(fmt.Sprintf("%d.", timestamp)))
+	mac.Write(body)
+	return types.CallbackSignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+func sendCallback(receiver *types.Subscription, event string, header http.Header) (statusCode int, respdata []byte, err error) {
 	redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
 	redisCli.HIncrBy(types.EventCacheDistCallBackCountPrefix+fmt.Sprint(receiver.SubscriptionID), "total", 1)
 
@@ -34,7 +76,10 @@ func SendCallback(receiver *types.Subscription, event string) (err error) {
 	req, err := http.NewRequest("POST", receiver.CallbackURL, body)
 	if err != nil {
 		redisCli.HIncrBy(types.EventCacheDistCallBackCountPrefix+fmt.Sprint(receiver.SubscriptionID), "failue", 1)
-		return fmt.Errorf("event distribute fail, build request error: %v, date=[%s]", err, event)
+		return 0, nil, fmt.Errorf("event distribute fail, build request error: %v, date=[%s]", err, event)
+	}
+	for key := range header {
+		req.Header.Set(key, header.Get(key))
 	}
 	var duration time.Duration
 	if receiver.TimeOut == 0 {
@@ -45,25 +90,26 @@ func SendCallback(receiver *types.Subscription, event string) (err error) {
 	resp, err := httpCli.DoWithTimeout(duration, req)
 	if err != nil {
 		redisCli.HIncrBy(types.EventCacheDistCallBackCountPrefix+fmt.Sprint(receiver.SubscriptionID), "failue", 1)
-		return fmt.Errorf("event distribute fail, send request error: %v, date=[%s]", err, event)
+		return 0, nil, fmt.Errorf("event distribute fail, send request error: %v, date=[%s]", err, event)
 	}
 	defer resp.Body.Close()
-	respdata, _ := ioutil.ReadAll(resp.Body)
+	statusCode = resp.StatusCode
+	respdata, _ = ioutil.ReadAll(resp.Body)
 	if receiver.ConfirmMode == types.ConfirmmodeHttpstatus {
 		if strconv.Itoa(resp.StatusCode) != receiver.ConfirmPattern {
 			redisCli.HIncrBy(types.EventCacheDistCallBackCountPrefix+fmt.Sprint(receiver.SubscriptionID), "failue", 1)
-			return fmt.Errorf("event distribute fail, received response %s, date=[%s]", respdata, event)
+			return statusCode, respdata, fmt.Errorf("event distribute fail, received response %s, date=[%s]", respdata, event)
 		}
 	} else if receiver.ConfirmMode == types.ConfirmmodeRegular {
 		pattern, err := regexp.Compile(receiver.ConfirmPattern)
 		if err != nil {
-			return fmt.Errorf("event distribute fail, build regexp error: %v", err)
+			return statusCode, respdata, fmt.Errorf("event distribute fail, build regexp error: %v", err)
 		}
 		if !pattern.Match(respdata) {
 			redisCli.HIncrBy(types.EventCacheDistCallBackCountPrefix+fmt.Sprint(receiver.SubscriptionID), "failue", 1)
-			return fmt.Errorf("event distribute fail, received response %s, date=[%s]", respdata, event)
+			return statusCode, respdata, fmt.Errorf("event distribute fail, received response %s, date=[%s]", respdata, event)
 		}
-		return nil
+		return statusCode, respdata, nil
 	}
 
 	return
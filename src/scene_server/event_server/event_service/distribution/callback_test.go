@@ -18,6 +18,7 @@ import (
 	"configcenter/src/storage"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -56,3 +57,33 @@ func TestSendCallback(t *testing.T) {
 	}
 
 }
+
+func TestSignPayload(t *testing.T) {
+	body := []byte(`{"event_type":"instdata"}`)
+	sign := SignPayload("secret", 1521187027, body)
+	if sign != SignPayload("secret", 1521187027, body) {
+		t.Fatalf("signature should be stable")
+	}
+	if sign == SignPayload("secret", 1521187028, body) {
+		t.Fatalf("signature should change with timestamp")
+	}
+	if sign == SignPayload("another", 1521187027, body) {
+		t.Fatalf("signature should change with secret")
+	}
+	if !strings.HasPrefix(sign, types.CallbackSignaturePrefix) {
+		t.Fatalf("signature should start with %s, but got %s", types.CallbackSignaturePrefix, sign)
+	}
+}
+
+func TestDeliveryHeader(t *testing.T) {
+	event := &types.EventInst{ID: 42, EventType: types.EventTypeInstData, Action: "create", ObjType: "host", CurData: map[string]interface{}{}}
+	dist := &types.DistInstCtx{DistInst: *event.GetDistInst(), Raw: "{}"}
+	dist.DstbID, dist.SubscriptionID = 5, 1
+	header := deliveryHeader(&types.Subscription{}, dist)
+	if header.Get(types.CallbackHeaderEventID) != "42" || header.Get(types.CallbackHeaderDeliveryID) != "1-5" {
+		t.Fatalf("unexpected delivery header %v", header)
+	}
+	if header.Get(types.CallbackHeaderSignature) != "" {
+		t.Fatalf("the dist should not be signed without secret")
+	}
+}
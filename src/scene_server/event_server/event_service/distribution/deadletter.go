@@ -0,0 +1,144 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package distribution
+
+import (
+	"configcenter/src/common/blog"
+	"configcenter/src/common/core/cc/api"
+	commontypes "configcenter/src/common/types"
+	"configcenter/src/scene_server/event_server/types"
+	"encoding/json"
+	"fmt"
+	redis "gopkg.in/redis.v5"
+	"time"
+)
+
+// sendWithRetry send callback until success or the retry policy used up,
+// the failed dist will be moved to the dead letter queue of the subscriber
+// and counted by the circuit breaker, it lasts deliveryTimeout(sub) at most
+func sendWithRetry(sub *types.Subscription, dist *types.DistInstCtx) (err error) {
+	policy := sub.GetRetryPolicy()
+	attempts := policy.Attempts()
+	var attempt int64
+	for attempt = 1; attempt <= attempts; attempt++ {
+		start := time.Now()
+		statusCode, respdata, senderr := deliver(sub, dist)
+		if recerr := recordDelivery(dist, attempt, statusCode, respdata, time.Since(start), senderr); recerr != nil {
+			blog.Errorf("record delivery of dist %d error: %v", dist.DstbID, recerr)
+		}
+		if err = senderr; err == nil {
+			countDeliveryResult(sub, nil)
+			return nil
+		}
+		blog.Errorf("send callback to %d attempt %d/%d error: %v", sub.SubscriptionID, attempt, attempts, err)
+		if attempt < attempts {
+			time.Sleep(policy.Backoff(attempt))
+		}
+	}
+
+	if dlqerr := pushDeadLetter(dist, attempts, err); dlqerr != nil {
+		blog.Errorf("push dist %d to dead letter queue error: %v", dist.DstbID, dlqerr)
+	}
+	countDeliveryResult(sub, err)
+	return err
+}
+
+func pushDeadLetter(dist *types.DistInstCtx, attempts int64, senderr error) error {
+	letter := types.DeadLetter{
+		DistInst:   dist.DistInst,
+		Attempts:   attempts,
+		FailedTime: commontypes.Now(),
+	}
+	if senderr != nil {
+		letter.Error = senderr.Error()
+	}
+	out, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+	key := types.EventCacheDistDeadLetterPrefix + fmt.Sprint(dist.SubscriptionID)
+	redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
+	if err = redisCli.RPush(key, string(out)).Err(); err != nil {
+		return err
+	}
+	return redisCli.LTrim(key, -types.DeadLetterMaxLength, -1).Err()
+}
+
+// ListDeadLetters returns the dead letters of the subscriber and the total count
+func ListDeadLetters(subID int64, start, limit int) ([]types.DeadLetter, int64, error) {
+	key := types.EventCacheDistDeadLetterPrefix + fmt.Sprint(subID)
+	redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
+	count, err := redisCli.LLen(key).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	stop := int64(-1)
+	if limit > 0 {
+		stop = int64(start+limit) - 1
+	}
+	values, err := redisCli.LRange(key, int64(start), stop).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	letters := make([]types.DeadLetter, 0, len(values))
+	for _, value := range values {
+		letter := types.DeadLetter{}
+		if err := json.Unmarshal([]byte(value), &letter); err != nil {
+			blog.Errorf("unmarshal dead letter error: %v, data=[%s]", err, value)
+			continue
+		}
+		letters = append(letters, letter)
+	}
+	return letters, count, nil
+}
+
+// ReplayDeadLetters moves at most limit dead letters back to the distribute queue, limit <= 0 means all,
+// every replayed dist will get a new dist id so that it is handled as a new one
+func ReplayDeadLetters(subID int64, limit int) (replayed int, err error) {
+	subscriber := fmt.Sprint(subID)
+	key := types.EventCacheDistDeadLetterPrefix + subscriber
+	redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
+	for limit <= 0 || replayed < limit {
+		value, err := redisCli.LPop(key).Result()
+		if err == redis.Nil {
+			return replayed, nil
+		}
+		if err != nil {
+			return replayed, err
+		}
+
+		letter := types.DeadLetter{}
+		if err := json.Unmarshal([]byte(value), &letter); err != nil {
+			blog.Errorf("unmarshal dead letter error: %v, data=[%s]", err, value)
+			continue
+		}
+		dist := letter.DistInst
+		if dist.DstbID, err = nextDistID(subscriber); err != nil {
+			redisCli.LPush(key, value)
+			return replayed, err
+		}
+		distByte, _ := json.Marshal(dist)
+		if err = pushToQueue(types.EventCacheDistQueuePrefix+subscriber, string(distByte)); err != nil {
+			redisCli.LPush(key, value)
+			return replayed, err
+		}
+		replayed++
+	}
+	return replayed, nil
+}
+
+// PurgeDeadLetters drops all the dead letters of the subscriber
+func PurgeDeadLetters(subID int64) error {
+	redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
+	return redisCli.Del(types.EventCacheDistDeadLetterPrefix + fmt.Sprint(subID)).Err()
+}
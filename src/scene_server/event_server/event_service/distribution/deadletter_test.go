@@ -0,0 +1,82 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package distribution
+
+import (
+	"configcenter/src/common/core/cc/api"
+	"configcenter/src/scene_server/event_server/types"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	redis "gopkg.in/redis.v5"
+)
+
+func TestDeliveryTimeoutDefault(t *testing.T) {
+	sub := &types.Subscription{RetryPolicy: &types.RetryPolicy{MaxAttempts: 2, BackoffBase: 1000, BackoffCap: 1000}}
+	if got := deliveryTimeout(sub); got != 2*timeout+time.Second {
+		t.Fatalf("the unset timeout should fall back to %v but got delivery timeout %v", timeout, got)
+	}
+
+	sub.TimeOut = 3
+	if got := deliveryTimeout(sub); got != 7*time.Second {
+		t.Fatalf("unexpected delivery timeout %v", got)
+	}
+}
+
+func TestSendWithRetryDeadLetter(t *testing.T) {
+	initTester()
+	var hits int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer s.Close()
+
+	sub := &types.Subscription{
+		SubscriptionID: 9999,
+		CallbackURL:    s.URL,
+		ConfirmMode:    types.ConfirmmodeHttpstatus,
+		ConfirmPattern: "200",
+		TimeOut:        1,
+		RetryPolicy:    &types.RetryPolicy{MaxAttempts: 3, BackoffBase: 10, BackoffCap: 20},
+	}
+	dist := &types.DistInstCtx{DistInst: types.DistInst{DstbID: 1, SubscriptionID: sub.SubscriptionID}, Raw: "{}"}
+	redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
+	redisCli.Del(types.EventCacheDistDeadLetterPrefix+"9999", stateKey(sub.SubscriptionID))
+	defer redisCli.Del(types.EventCacheDistDeadLetterPrefix+"9999", stateKey(sub.SubscriptionID), types.EventCacheDistDeliveryPrefix+"9999")
+
+	start := time.Now()
+	if err := sendWithRetry(sub, dist); err == nil {
+		t.Fatal("the rejected dist should fail")
+	}
+	if elapsed := time.Since(start); elapsed > deliveryTimeout(sub) {
+		t.Fatalf("the retries took %v longer than the delivery timeout %v", elapsed, deliveryTimeout(sub))
+	}
+	if atomic.LoadInt32(&hits) != 3 {
+		t.Fatalf("expected 3 attempts but got %d", hits)
+	}
+
+	letters, count, err := ListDeadLetters(sub.SubscriptionID, 0, 0)
+	if err != nil || count != 1 || len(letters) != 1 || letters[0].Attempts != 3 {
+		t.Fatalf("expected one dead letter of 3 attempts but got %+v, %d, %v", letters, count, err)
+	}
+	if err = PurgeDeadLetters(sub.SubscriptionID); err != nil {
+		t.Fatal(err)
+	}
+	if _, count, _ = ListDeadLetters(sub.SubscriptionID, 0, 0); count != 0 {
+		t.Fatalf("the dead letters should be purged but %d left", count)
+	}
+}
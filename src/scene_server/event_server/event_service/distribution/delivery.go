@@ -0,0 +1,102 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package distribution
+
+import (
+	"configcenter/src/common/blog"
+	"configcenter/src/common/core/cc/api"
+	commontypes "configcenter/src/common/types"
+	"configcenter/src/scene_server/event_server/types"
+	"encoding/json"
+	"fmt"
+	redis "gopkg.in/redis.v5"
+	"time"
+)
+
+// DeliveryCondition define which delivery records to list, the zero value fields are ignored
+type DeliveryCondition struct {
+	EventID int64
+	Success *bool
+}
+
+// recordDelivery keep the callback attempt in the bounded delivery history of the subscriber,
+// the newest record is in the front
+func recordDelivery(dist *types.DistInstCtx, attempt int64, statusCode int, respdata []byte, latency time.Duration, senderr error) error {
+	delivery := newDelivery(dist, attempt, statusCode, respdata, latency, senderr)
+	out, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+	key := types.EventCacheDistDeliveryPrefix + fmt.Sprint(dist.SubscriptionID)
+	redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
+	if err = redisCli.LPush(key, string(out)).Err(); err != nil {
+		return err
+	}
+	return redisCli.LTrim(key, 0, types.DeliveryHistoryLength-1).Err()
+}
+
+func newDelivery(dist *types.DistInstCtx, attempt int64, statusCode int, respdata []byte, latency time.Duration, senderr error) types.Delivery {
+	delivery := types.Delivery{
+		SubscriptionID: dist.SubscriptionID,
+		DstbID:         dist.DstbID,
+		EventID:        dist.ID,
+		EventType:      dist.EventType,
+		Action:         dist.Action,
+		ObjType:        dist.ObjType,
+		Attempt:        attempt,
+		Success:        senderr == nil,
+		StatusCode:     statusCode,
+		Latency:        int64(latency / time.Millisecond),
+		DeliveryTime:   commontypes.Now(),
+	}
+	if len(respdata) > types.DeliveryResponseMaxLength {
+		respdata = respdata[:types.DeliveryResponseMaxLength]
+	}
+	delivery.Response = string(respdata)
+	if senderr != nil {
+		delivery.Error = senderr.Error()
+	}
+	return delivery
+}
+
+// ListDeliveries returns the delivery records of the subscriber which match the condition and the matched count
+func ListDeliveries(subID int64, cond DeliveryCondition, start, limit int) ([]types.Delivery, int64, error) {
+	key := types.EventCacheDistDeliveryPrefix + fmt.Sprint(subID)
+	redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
+	values, err := redisCli.LRange(key, 0, -1).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	deliveries := []types.Delivery{}
+	var count int64
+	for _, value := range values {
+		delivery := types.Delivery{}
+		if err := json.Unmarshal([]byte(value), &delivery); err != nil {
+			blog.Errorf("unmarshal delivery error: %v, data=[%s]", err, value)
+			continue
+		}
+		if cond.EventID > 0 && delivery.EventID != cond.EventID {
+			continue
+		}
+		if cond.Success != nil && delivery.Success != *cond.Success {
+			continue
+		}
+		count++
+		if count <= int64(start) || (limit > 0 && len(deliveries) >= limit) {
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, count, nil
+}
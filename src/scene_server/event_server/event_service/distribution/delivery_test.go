@@ -0,0 +1,55 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package distribution
+
+import (
+	"configcenter/src/scene_server/event_server/types"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewDelivery(t *testing.T) {
+	dist := &types.DistInstCtx{DistInst: types.DistInst{
+		EventInst:      types.EventInst{ID: 10, EventType: types.EventTypeInstData, Action: "update", ObjType: "host"},
+		DstbID:         3,
+		SubscriptionID: 1,
+	}}
+	respdata := []byte(strings.Repeat("x", types.DeliveryResponseMaxLength+10))
+	delivery := newDelivery(dist, 2, 500, respdata, time.Millisecond*15, errors.New("rejected"))
+	if delivery.EventID != 10 || delivery.DstbID != 3 || delivery.Attempt != 2 || delivery.StatusCode != 500 {
+		t.Fatalf("unexpected delivery %+v", delivery)
+	}
+	if delivery.Success || delivery.Error != "rejected" || delivery.Latency != 15 {
+		t.Fatalf("unexpected delivery result %+v", delivery)
+	}
+	if len(delivery.Response) != types.DeliveryResponseMaxLength {
+		t.Fatalf("response should be truncated to %d but got %d", types.DeliveryResponseMaxLength, len(delivery.Response))
+	}
+
+	delivery = newDelivery(dist, 1, 200, []byte("ok"), 0, nil)
+	if !delivery.Success || delivery.Error != "" || delivery.Response != "ok" {
+		t.Fatalf("unexpected delivery result %+v", delivery)
+	}
+}
+
+func TestNewDeliveryFromEvent(t *testing.T) {
+	event := &types.EventInst{ID: 42, EventType: types.EventTypeInstData, Action: "create", ObjType: "host", CurData: map[string]interface{}{}}
+	dist := &types.DistInstCtx{DistInst: *event.GetDistInst()}
+	dist.DstbID, dist.SubscriptionID = 5, 1
+	delivery := newDelivery(dist, 1, 200, nil, 0, nil)
+	if delivery.EventID != 42 {
+		t.Fatalf("the delivery should keep the event id 42 but got %d", delivery.EventID)
+	}
+}
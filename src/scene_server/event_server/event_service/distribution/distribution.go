@@ -16,6 +16,7 @@ import (
 	"configcenter/src/common"
 	"configcenter/src/common/core/cc/api"
 	"configcenter/src/common/blog"
+	"configcenter/src/scene_server/event_server/event_service/filter"
 	"configcenter/src/scene_server/event_server/types"
 	"encoding/json"
 	"fmt"
@@ -99,6 +100,7 @@ func StartDistribute() (err error) {
 					delete(routines, subscriber.SubscriptionID)
 					delete(renewMaps, subscriber.SubscriptionID)
 				}
+				filter.Forget(fmt.Sprint(subscriber.SubscriptionID))
 			}
 		}
 	}()
@@ -129,16 +131,64 @@ func distToSubscribe(param types.Subscription, chNew chan types.Subscription, do
 		}
 	}()
 	defer blog.Infof("ended handle dist %v", sub.SubscriptionID)
+
+	// the partition workers are started only when the subscription opt in partitioned distribution,
+	// or the dists are left in the partitions, which are delivered before the next dist
+	var workers *partitionWorkers
+	if partitions := sub.GetPartitions(); partitions > 1 {
+		workers = newPartitionWorkers(sub, partitions)
+	} else if hasPartitionedDists(sub.SubscriptionID) {
+		workers = newPartitionWorkers(sub, types.MaxPartitions)
+	}
+	defer func() {
+		if workers != nil {
+			workers.stop(false)
+		}
+	}()
 	for {
 		select {
 		case sub = <-chNew:
 		case <-done:
 			return
 		default:
+			status, ready := readyToDeliver(sub.SubscriptionID)
+			if !ready {
+				// the events are kept in the distribute queue while paused or tripped
+				time.Sleep(types.StateCheckInterval)
+				continue
+			}
 			dist := popDistInst(sub.SubscriptionID)
 			if dist == nil {
 				continue
 			}
+			if status == types.SubscriptionStatusTripped {
+				if err = probeDist(&sub, dist); err != nil {
+					blog.Errorf("probe subscriber %v failed: %v", sub.SubscriptionID, err)
+				}
+				continue
+			}
+			if partitions := sub.GetPartitions(); partitions > 1 {
+				if workers != nil && workers.size != partitions {
+					workers.stop(true)
+					workers = nil
+				}
+				if workers == nil {
+					blog.Infof("start %d partitions for subscriber %v", partitions, sub.SubscriptionID)
+					workers = newPartitionWorkers(sub, partitions)
+				}
+				if err = workers.dispatch(sub, dist); err != nil {
+					// put it back to the head of the queue to keep the order
+					blog.Errorf("dispatch dist error: %v, %v", err, dist)
+					redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
+					redisCli.LPush(types.EventCacheDistQueuePrefix+fmt.Sprint(sub.SubscriptionID), dist.Raw)
+					time.Sleep(waitperiod)
+				}
+				continue
+			}
+			if workers != nil {
+				workers.stop(true)
+				workers = nil
+			}
 			if err = handleDist(&sub, dist); err != nil {
 				blog.Errorf("error handle dist: %v, %v", err, dist)
 			}
@@ -151,7 +201,7 @@ func handleDist(sub *types.Subscription, dist *types.DistInstCtx) (err error) {
 	distID := fmt.Sprint(dist.DstbID - 1)
 	subscriberID := fmt.Sprint(dist.SubscriptionID)
 	runningkey := types.EventCacheDistRunningPrefix + subscriberID + "_" + distID
-	if err = saveRunning(runningkey, timeout+sub.GetTimeout()); err != nil {
+	if err = saveRunning(runningkey, timeout+deliveryTimeout(sub)); err != nil {
 		if ERR_PROCESS_EXISTS == err {
 			blog.Infof("process exist, continue")
 			return nil
@@ -183,7 +233,7 @@ func handleDist(sub *types.Subscription, dist *types.DistInstCtx) (err error) {
 		if running {
 			// if previous running, wait it
 			blog.Infof("waitting previous id: " + priviousID)
-			if err = waitPreviousDone(types.EventCacheDistDonePrefix+subscriberID, priviousID, sub.GetTimeout()); err != nil && err != ERR_WAIT_TIMEOUT {
+			if err = waitPreviousDone(types.EventCacheDistDonePrefix+subscriberID, priviousID, deliveryTimeout(sub)); err != nil && err != ERR_WAIT_TIMEOUT {
 				return err
 			}
 			if err == ERR_WAIT_TIMEOUT {
@@ -192,14 +242,49 @@ func handleDist(sub *types.Subscription, dist *types.DistInstCtx) (err error) {
 		}
 	}
 
+	// if previous done then begin send callback
+	return sendDist(sub, dist)
+}
+
+// handlePartitionedDist delivers the dist without waiting the previous one,
+// the order inside the partition is kept by the partition worker
+func handlePartitionedDist(sub *types.Subscription, dist *types.DistInstCtx) (err error) {
+	blog.Infof("handling partitioned dist %s", dist.Raw)
+	runningkey := types.EventCacheDistRunningPrefix + fmt.Sprintf("%d_%d", dist.SubscriptionID, dist.DstbID)
+	if err = saveRunning(runningkey, timeout+deliveryTimeout(sub)); err != nil {
+		if ERR_PROCESS_EXISTS == err {
+			blog.Infof("process exist, continue")
+			return nil
+		}
+		return err
+	}
+	return sendDist(sub, dist)
+}
+
+// deliveryTimeout returns how long the delivery of the dist lasts at most, the running key lives
+// and the next dist waits that long, the unset timeout falls back to the default one
+func deliveryTimeout(sub *types.Subscription) time.Duration {
+	if sub.TimeOut == 0 {
+		withDefault := *sub
+		withDefault.TimeOut = int64(timeout / time.Second)
+		return withDefault.DeliveryTimeout()
+	}
+	return sub.DeliveryTimeout()
+}
+
+// sendDist send the callback of the dist and mark it done
+func sendDist(sub *types.Subscription, dist *types.DistInstCtx) (err error) {
 	defer func() {
 		if err = saveDistDone(dist); err != nil {
 			return
 		}
 		blog.Info("done event dist : %v", dist.DstbID)
 	}()
-	// if previous done then begin send callback
-	if err = SendCallback(sub, dist.Raw); err != nil {
+	payload := dist
+	if sub.PayloadMode == types.PayloadModeCompact {
+		payload = compactDist(dist)
+	}
+	if err = sendWithRetry(sub, payload); err != nil {
 		blog.Errorf("send callback error: %v", err)
 		return
 	}
@@ -218,16 +303,17 @@ func popDistInst(subID int64) *types.DistInstCtx {
 	if len(eventslice) <= 0 {
 		return nil
 	}
+	return decodeDist(eventslice[1])
+}
 
-	// Unmarshal event
-	eventbytes := []byte(eventslice[1])
+// decodeDist returns the dist of the raw value, or nil if it is malformed
+func decodeDist(raw string) *types.DistInstCtx {
 	event := types.DistInst{}
-	if err := json.Unmarshal(eventbytes, &event); err != nil {
-		blog.Errorf("event distribute fail, unmarshal error: %v, date=[%s]", err, eventbytes)
+	if err := json.Unmarshal([]byte(raw), &event); err != nil {
+		blog.Errorf("event distribute fail, unmarshal error: %v, date=[%s]", err, raw)
 		return nil
 	}
-
-	return &types.DistInstCtx{DistInst: event, Raw: eventslice[1]}
+	return &types.DistInstCtx{DistInst: event, Raw: raw}
 }
 
 func saveDistDone(dist *types.DistInstCtx) (err error) {
@@ -0,0 +1,119 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package distribution
+
+import (
+	"configcenter/src/common/blog"
+	"configcenter/src/common/core/cc/api"
+	"configcenter/src/scene_server/event_server/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	redis "gopkg.in/redis.v5"
+	"time"
+)
+
+// ErrCursorExpired the events after the cursor are no longer retained
+var ErrCursorExpired = errors.New("cursor expired")
+
+// trimEventLogScript drops the oldest events beyond the max length and raises the trimmed
+// watermark to the largest dropped id in one step
+var trimEventLogScript = redis.NewScript(`
+local trimmed = redis.call('ZRANGE', KEYS[1], 0, -tonumber(ARGV[1]) - 1, 'WITHSCORES')
+if #trimmed == 0 then
+	return 0
+end
+local score = tonumber(trimmed[#trimmed])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', score)
+if score > tonumber(redis.call('GET', KEYS[2]) or '0') then
+	redis.call('SET', KEYS[2], score)
+end
+return score
+`)
+
+// appendEventLog retain the event in the event log, the oldest events are dropped
+// when the log exceeds EventLogMaxLength
+func appendEventLog(event *types.EventInstCtx) error {
+	redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
+	if err := redisCli.ZAdd(types.EventCacheEventLogKey, redis.Z{Score: float64(event.ID), Member: event.Raw}).Err(); err != nil {
+		return err
+	}
+	keys := []string{types.EventCacheEventLogKey, types.EventCacheEventLogTrimmedKey}
+	return trimEventLogScript.Run(redisCli, keys, types.EventLogMaxLength).Err()
+}
+
+// LatestEventID returns the id of the newest retained event
+func LatestEventID() (int64, error) {
+	redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
+	latest, err := redisCli.ZRangeWithScores(types.EventCacheEventLogKey, -1, -1).Result()
+	if err != nil || len(latest) == 0 {
+		return 0, err
+	}
+	return int64(latest[0].Score), nil
+}
+
+// ReadEventLog returns at most limit events after the cursor and the next cursor, the events
+// are returned in id order and stops at a missing id until it exceeds the EventLogGapGrace,
+// ErrCursorExpired is returned if some events after the cursor are dropped from the log
+func ReadEventLog(cursor int64, limit int64) ([]types.EventInst, int64, error) {
+	redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
+	if cursor > 0 {
+		trimmed, err := redisCli.Get(types.EventCacheEventLogTrimmedKey).Int64()
+		if err != nil && err != redis.Nil {
+			return nil, cursor, err
+		}
+		if cursorExpired(cursor, trimmed) {
+			return nil, cursor, ErrCursorExpired
+		}
+	}
+
+	values, err := redisCli.ZRangeByScoreWithScores(types.EventCacheEventLogKey, redis.ZRangeBy{
+		Min:   fmt.Sprintf("(%d", cursor),
+		Max:   "+inf",
+		Count: limit,
+	}).Result()
+	if err != nil {
+		return nil, cursor, err
+	}
+	events, next := collectEventLog(cursor, values)
+	return events, next, nil
+}
+
+// cursorExpired check whether some events after the cursor are trimmed, the ids may have gaps
+// so the cursor is only compared with the largest trimmed id
+func cursorExpired(cursor, trimmed int64) bool {
+	return cursor > 0 && cursor < trimmed
+}
+
+// collectEventLog returns the events of the log entries after the cursor and the next cursor
+func collectEventLog(cursor int64, values []redis.Z) ([]types.EventInst, int64) {
+	events := []types.EventInst{}
+	next := cursor
+	for _, value := range values {
+		id := int64(value.Score)
+		event := types.EventInst{}
+		raw, _ := value.Member.(string)
+		if err := json.Unmarshal([]byte(raw), &event); err != nil {
+			blog.Errorf("unmarshal event log error: %v, data=[%s]", err, raw)
+			next = id
+			continue
+		}
+		if next > 0 && id > next+1 && time.Since(event.ActionTime.Time) < types.EventLogGapGrace {
+			// the previous events may be still on the way
+			break
+		}
+		events = append(events, event)
+		next = id
+	}
+	return events, next
+}
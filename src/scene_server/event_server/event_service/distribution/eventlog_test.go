@@ -0,0 +1,76 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package distribution
+
+import (
+	cctypes "configcenter/src/common/types"
+	"configcenter/src/scene_server/event_server/types"
+	"encoding/json"
+	"testing"
+	"time"
+
+	redis "gopkg.in/redis.v5"
+)
+
+func eventLogEntry(t *testing.T, id int64, actionTime time.Time) redis.Z {
+	event := types.EventInst{ID: id, EventType: types.EventTypeInstData, Action: "create", ObjType: "host", ActionTime: cctypes.Time{Time: actionTime}}
+	raw, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return redis.Z{Score: float64(id), Member: string(raw)}
+}
+
+func TestCursorExpired(t *testing.T) {
+	cases := []struct {
+		cursor, trimmed int64
+		expired         bool
+	}{
+		{cursor: 0, trimmed: 100, expired: false},
+		{cursor: 10, trimmed: 0, expired: false},
+		{cursor: 99, trimmed: 100, expired: true},
+		{cursor: 100, trimmed: 100, expired: false},
+		// the oldest retained id is far after the cursor because of the id gaps, nothing is trimmed after the cursor
+		{cursor: 120, trimmed: 100, expired: false},
+	}
+	for _, c := range cases {
+		if expired := cursorExpired(c.cursor, c.trimmed); expired != c.expired {
+			t.Errorf("cursor %d with trimmed %d should be expired=%v", c.cursor, c.trimmed, c.expired)
+		}
+	}
+}
+
+func TestCollectEventLog(t *testing.T) {
+	old := time.Now().Add(-types.EventLogGapGrace * 2)
+	values := []redis.Z{
+		eventLogEntry(t, 11, old),
+		{Score: 12, Member: "{malformed"},
+		eventLogEntry(t, 15, old),
+		eventLogEntry(t, 20, time.Now()),
+	}
+
+	events, next := collectEventLog(10, values)
+	if next != 15 || len(events) != 2 || events[0].ID != 11 || events[1].ID != 15 {
+		t.Fatalf("should skip the malformed and the stale gap then wait at 15, got next %d events %+v", next, events)
+	}
+
+	events, next = collectEventLog(0, values[3:])
+	if next != 20 || len(events) != 1 {
+		t.Fatalf("a fresh gap after the zero cursor should not block, got next %d events %+v", next, events)
+	}
+
+	events, next = collectEventLog(30, nil)
+	if next != 30 || len(events) != 0 {
+		t.Fatalf("no new events should keep the cursor, got next %d events %+v", next, events)
+	}
+}
@@ -0,0 +1,193 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package distribution
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/core/cc/api"
+	"configcenter/src/scene_server/event_server/event_service/filter"
+	"configcenter/src/scene_server/event_server/types"
+	"configcenter/src/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+	redis "gopkg.in/redis.v5"
+	"strings"
+	"time"
+)
+
+// replayBatchSize how many events are loaded from the event log once while replaying
+const replayBatchSize = 500
+
+// ErrReplayRunning the last replay of the subscription is still running
+var ErrReplayRunning = errors.New("replay running")
+
+// ensureEventLogIndex create the indexes of the persistent event log, the create_time
+// index expires the events after EventLogRetention
+func ensureEventLogIndex(db storage.DI) error {
+	indexes := []storage.Index{
+		storage.Index{Name: "idx_event_id", Columns: []string{"event_id"}, Type: storage.INDEX_TYPE_BACKGROUP_UNIQUE},
+		storage.Index{Name: "idx_action_time", Columns: []string{"action_time"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		storage.Index{Name: "idx_supplier_account_event_id", Columns: []string{common.BKOwnerIDField, "event_id"}, Type: storage.INDEX_TYPE_BACKGROUP},
+		storage.Index{Name: "idx_create_time_ttl", Columns: []string{"create_time"}, Type: storage.INDEX_TYPE_BACKGROUP, ExpireAfter: types.EventLogRetention},
+	}
+	for _, index := range indexes {
+		ii := index
+		if err := db.Index(types.TableNameEventLog, &ii); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// persistEvent save the event into the persistent event log, the event handled again
+// after a failure is saved once, the unique event_id index rejects it
+func persistEvent(db storage.DI, event *types.EventInstCtx) error {
+	_, err := db.Insert(types.TableNameEventLog, &types.EventLog{
+		ID:         event.ID,
+		EventType:  event.EventType,
+		Action:     event.Action,
+		ObjType:    event.ObjType,
+		ActionTime: event.ActionTime.Time,
+		OwnerID:    eventOwner(&event.EventInst),
+		Data:       []byte(event.Raw),
+		CreateTime: time.Now().UTC(),
+	})
+	if err == nil {
+		return nil
+	}
+	cnt, cerr := db.GetCntByCondition(types.TableNameEventLog, map[string]interface{}{"event_id": event.ID})
+	if cerr != nil || cnt == 0 {
+		return err
+	}
+	return nil
+}
+
+// eventOwner returns the supplier account of the instance of the event, the one of the request
+// making the event if the instance has none
+func eventOwner(event *types.EventInst) string {
+	for _, data := range []interface{}{event.CurData, event.PreData} {
+		if m, ok := data.(map[string]interface{}); ok {
+			if owner, ok := m[common.BKOwnerIDField].(string); ok && owner != "" {
+				return owner
+			}
+		}
+	}
+	return event.OwnerID
+}
+
+// ReplayEvents starts pushing the persisted events in the range to the distribute queue of the
+// subscription in background, ErrReplayRunning is returned if the last replay is not finished
+func ReplayEvents(sub types.Subscription, params types.ReplayParams) error {
+	key := types.EventCacheDistReplayPrefix + fmt.Sprint(sub.SubscriptionID)
+	redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
+	set, err := redisCli.SetNX(key, time.Now().UTC().Format(time.RFC3339), types.ReplayTimeout).Result()
+	if err != nil {
+		return err
+	}
+	if !set {
+		return ErrReplayRunning
+	}
+
+	go func() {
+		defer redisCli.Del(key)
+		replayed, err := replayEvents(api.GetAPIResource().InstCli, sub, params, queueDist)
+		if err != nil {
+			blog.Errorf("replay events to subscription %d error: %v, %d replayed", sub.SubscriptionID, err, replayed)
+			return
+		}
+		blog.Infof("replayed %d events to subscription %d", replayed, sub.SubscriptionID)
+	}()
+	return nil
+}
+
+// queueDist push the dist to the distribute queue as a new one of the subscriber
+func queueDist(dist *types.DistInst) (err error) {
+	subscriber := fmt.Sprint(dist.SubscriptionID)
+	if dist.DstbID, err = nextDistID(subscriber); err != nil {
+		return err
+	}
+	distByte, _ := json.Marshal(dist)
+	return pushToQueue(types.EventCacheDistQueuePrefix+subscriber, string(distByte))
+}
+
+// replayEvents passes the persisted events of the owner of the subscription in the range to push, the events
+// without an owner are passed to all the owners as they are distributed, the events are filtered by the
+// subscription form and the filter expression of the subscription
+func replayEvents(db storage.DI, sub types.Subscription, params types.ReplayParams, push func(dist *types.DistInst) error) (replayed int64, err error) {
+	condition := map[string]interface{}{common.BKOwnerIDField: map[string]interface{}{"$in": []string{sub.OwnerID, ""}}}
+	idCond := map[string]interface{}{}
+	if params.StartID > 0 {
+		idCond["$gte"] = params.StartID
+	}
+	if params.EndID > 0 {
+		idCond["$lte"] = params.EndID
+	}
+	if len(idCond) > 0 {
+		condition["event_id"] = idCond
+	}
+	timeCond := map[string]interface{}{}
+	if params.StartTime != nil {
+		timeCond["$gte"] = params.StartTime.Time
+	}
+	if params.EndTime != nil {
+		timeCond["$lte"] = params.EndTime.Time
+	}
+	if len(timeCond) > 0 {
+		condition["action_time"] = timeCond
+	}
+
+	limit := params.Limit
+	if limit <= 0 || limit > types.ReplayMaxLimit {
+		limit = types.ReplayMaxLimit
+	}
+
+	eventTypes := map[string]bool{}
+	for _, eventType := range strings.Split(sub.SubscriptionForm, ",") {
+		eventTypes[strings.TrimSpace(eventType)] = true
+	}
+	subscriber := fmt.Sprint(sub.SubscriptionID)
+
+	for skip := 0; replayed < limit; skip += replayBatchSize {
+		logs := []types.EventLog{}
+		if err = db.GetMutilByCondition(types.TableNameEventLog, nil, condition, &logs, "event_id", skip, replayBatchSize); err != nil {
+			return replayed, err
+		}
+		for _, log := range logs {
+			if replayed >= limit {
+				break
+			}
+			event := types.EventInst{}
+			if err := json.Unmarshal(log.Data, &event); err != nil {
+				blog.Errorf("unmarshal event log %d error: %v", log.ID, err)
+				continue
+			}
+			dist := event.GetDistInst()
+			if dist == nil || !eventTypes[dist.GetType()] {
+				continue
+			}
+			if matched, err := filter.Match(subscriber, sub.Filter, &dist.EventInst); err == nil && !matched {
+				continue
+			}
+			dist.SubscriptionID = sub.SubscriptionID
+			if err = push(dist); err != nil {
+				return replayed, err
+			}
+			replayed++
+		}
+		if len(logs) < replayBatchSize {
+			break
+		}
+	}
+	return replayed, nil
+}
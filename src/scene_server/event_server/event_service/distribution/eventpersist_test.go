@@ -0,0 +1,97 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package distribution
+
+import (
+	"configcenter/src/scene_server/event_server/types"
+	"configcenter/src/storage/memclient"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEventCtxOf(id int64, action, owner string) *types.EventInstCtx {
+	event := types.EventInst{
+		ID:        id,
+		EventType: types.EventTypeInstData,
+		Action:    action,
+		ObjType:   "host",
+		CurData:   map[string]interface{}{"bk_host_id": float64(id), "bk_supplier_account": owner},
+	}
+	raw, _ := json.Marshal(event)
+	return &types.EventInstCtx{EventInst: event, Raw: string(raw)}
+}
+
+func TestPersistAndReplayEvents(t *testing.T) {
+	db, err := memclient.NewMemCli(t.Name())
+	require.NoError(t, err)
+	require.NoError(t, db.Open())
+	defer memclient.Drop(t.Name())
+	require.NoError(t, ensureEventLogIndex(db))
+
+	events := []*types.EventInstCtx{
+		testEventCtxOf(1, types.EventActionCreate, "0"),
+		testEventCtxOf(2, types.EventActionCreate, "tencent"),
+		testEventCtxOf(3, types.EventActionDelete, "0"),
+		testEventCtxOf(4, types.EventActionCreate, "0"),
+		testEventCtxOf(5, types.EventActionCreate, ""),
+		testEventCtxOf(6, types.EventActionCreate, ""),
+	}
+	// the owner of the request is taken when the instance has none
+	events[4].OwnerID = "tencent"
+	for _, event := range events {
+		require.NoError(t, persistEvent(db, event))
+	}
+	// the event handled again is persisted once
+	require.NoError(t, persistEvent(db, events[0]))
+	cnt, err := db.GetCntByCondition(types.TableNameEventLog, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 6, cnt)
+
+	// the insert racing with another one is taken as saved
+	_, err = db.Insert(types.TableNameEventLog, &types.EventLog{ID: 7})
+	require.NoError(t, err)
+	require.NoError(t, persistEvent(db, testEventCtxOf(7, types.EventActionCreate, "0")))
+
+	replay := func(sub types.Subscription, params types.ReplayParams) []int64 {
+		ids := []int64{}
+		replayed, err := replayEvents(db, sub, params, func(dist *types.DistInst) error {
+			assert.Equal(t, sub.SubscriptionID, dist.SubscriptionID)
+			ids = append(ids, dist.ID)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(ids)), replayed)
+		return ids
+	}
+
+	sub := types.Subscription{SubscriptionID: 1, OwnerID: "0", SubscriptionForm: "hostcreate"}
+	assert.Equal(t, []int64{1, 4, 6}, replay(sub, types.ReplayParams{StartID: 1, EndID: 6}), "the events of the other owner or type are skipped")
+	assert.Equal(t, []int64{4}, replay(sub, types.ReplayParams{StartID: 2, EndID: 4}))
+	assert.Equal(t, []int64{1}, replay(sub, types.ReplayParams{StartID: 1, Limit: 1}))
+
+	sub.SubscriptionForm = "hostcreate,hostdelete"
+	sub.Filter = "bk_host_id >= 3"
+	assert.Equal(t, []int64{3, 4, 6}, replay(sub, types.ReplayParams{StartID: 1, EndID: 6}))
+
+	other := types.Subscription{SubscriptionID: 2, OwnerID: "tencent", SubscriptionForm: "hostcreate"}
+	assert.Equal(t, []int64{2, 5, 6}, replay(other, types.ReplayParams{StartID: 1, EndID: 6}))
+
+	_, err = replayEvents(db, other, types.ReplayParams{StartID: 1}, func(dist *types.DistInst) error {
+		return fmt.Errorf("queue unavailable")
+	})
+	assert.Error(t, err)
+}
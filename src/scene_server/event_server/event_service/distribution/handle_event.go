@@ -16,6 +16,7 @@ import (
 	"configcenter/src/common"
 	"configcenter/src/common/core/cc/api"
 	"configcenter/src/common/blog"
+	"configcenter/src/scene_server/event_server/event_service/filter"
 	"configcenter/src/scene_server/event_server/types"
 	"encoding/json"
 	"fmt"
@@ -50,6 +51,10 @@ func StartHandleInsts() (err error) {
 		}
 	}()
 
+	if indexErr := ensureEventLogIndex(api.GetAPIResource().InstCli); indexErr != nil {
+		blog.Errorf("create event log index error: %v", indexErr)
+	}
+
 	blog.Info("event inst handle process started")
 	for {
 		// pod one event from cache
@@ -66,6 +71,12 @@ func StartHandleInsts() (err error) {
 func handleInst(event *types.EventInstCtx) (err error) {
 	blog.Info("handling event inst : %v", event.Raw)
 	defer blog.Info("done event inst : %v", event.ID)
+	if logErr := appendEventLog(event); logErr != nil {
+		blog.Errorf("append event %v to event log error: %v", event.ID, logErr)
+	}
+	if logErr := persistEvent(api.GetAPIResource().InstCli, event); logErr != nil {
+		blog.Errorf("persist event %v error: %v", event.ID, logErr)
+	}
 	if err = saveRunning(types.EventCacheEventRunningPrefix+fmt.Sprint(event.ID), timeout); err != nil {
 		if ERR_PROCESS_EXISTS == err {
 			blog.Infof("%v process exist, continue", event.ID)
@@ -125,6 +136,10 @@ func handleInst(event *types.EventInstCtx) (err error) {
 		var dstbID, subscribeID int64
 
 		distinst := *origindist
+		if !matchSubscriberFilter(subscriber, &distinst.EventInst) {
+			blog.Infof("event %v filtered by subscriber %s", event.ID, subscriber)
+			continue
+		}
 		dstbID, err = nextDistID(subscriber)
 		if err != nil {
 			return err
@@ -257,6 +272,26 @@ func findEventTypeSubscribers(eventtype string) []string {
 	return redisCli.SMembers(types.EventCacheSubscribeformKey + eventtype).Val()
 }
 
+// matchSubscriberFilter check the event with the filter expression of the subscriber,
+// the event will be delivered if the filter can not be loaded or evaluated
+func matchSubscriberFilter(subscriber string, event *types.EventInst) bool {
+	redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
+	expr, err := redisCli.Get(types.EventCacheSubscribeFilterPrefix + subscriber).Result()
+	if err == redis.Nil {
+		return true
+	}
+	if err != nil {
+		blog.Errorf("get filter of subscriber %s error: %v", subscriber, err)
+		return true
+	}
+	matched, err := filter.Match(subscriber, expr, event)
+	if err != nil {
+		blog.Errorf("invalid filter of subscriber %s: %v", subscriber, err)
+		return true
+	}
+	return matched
+}
+
 func popEventInst() *types.EventInstCtx {
 	eventseletor := common.KvMap{
 		"expire": time.Second * 60,
@@ -0,0 +1,87 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package distribution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	kafka "github.com/segmentio/kafka-go"
+)
+
+// kafkaProducer writes the messages to the leader of the given partition, it keeps one
+// connection for each partition and redials after the connection fails
+type kafkaProducer struct {
+	dialer *kafka.Dialer
+	mu     sync.Mutex
+	conns  map[string]*kafkaConn
+}
+
+type kafkaConn struct {
+	mu   sync.Mutex
+	conn *kafka.Conn
+}
+
+func newKafkaProducer(clientID string) *kafkaProducer {
+	return &kafkaProducer{dialer: &kafka.Dialer{ClientID: clientID}, conns: map[string]*kafkaConn{}}
+}
+
+// Produce writes the message, the brokers are tried in order to find the leader of the partition
+func (p *kafkaProducer) Produce(brokers []string, topic string, partition int32, msg kafka.Message, timeout time.Duration) error {
+	if len(brokers) == 0 {
+		return errors.New("kafka brokers not set")
+	}
+	c := p.conn(fmt.Sprintf("%s/%s/%d", strings.Join(brokers, ","), topic, partition))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		conn, err := p.dialLeader(brokers, topic, int(partition), timeout)
+		if err != nil {
+			return err
+		}
+		c.conn = conn
+	}
+	c.conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := c.conn.WriteMessages(msg); err != nil {
+		c.conn.Close()
+		c.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (p *kafkaProducer) conn(key string) *kafkaConn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c, ok := p.conns[key]
+	if !ok {
+		c = &kafkaConn{}
+		p.conns[key] = c
+	}
+	return c
+}
+
+func (p *kafkaProducer) dialLeader(brokers []string, topic string, partition int, timeout time.Duration) (conn *kafka.Conn, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	for _, addr := range brokers {
+		if conn, err = p.dialer.DialLeader(ctx, "tcp", addr, topic, partition); err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
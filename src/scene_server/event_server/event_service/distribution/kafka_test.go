@@ -0,0 +1,58 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package distribution
+
+import (
+	"configcenter/src/scene_server/event_server/types"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestKafkaProduceUnreachable(t *testing.T) {
+	producer := newKafkaProducer("test")
+	if err := producer.Produce(nil, "cmdb", 0, kafkaMessage(&types.Subscription{}, &types.DistInstCtx{}), time.Second); err == nil {
+		t.Fatal("produce without brokers should fail")
+	}
+
+	closed, _ := net.Listen("tcp", "127.0.0.1:0")
+	closed.Close()
+	start := time.Now()
+	if err := producer.Produce([]string{closed.Addr().String()}, "cmdb", 0, kafkaMessage(&types.Subscription{}, &types.DistInstCtx{}), time.Second); err == nil {
+		t.Fatal("produce to the unreachable broker should fail")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("produce should give up in the timeout but took %v", elapsed)
+	}
+}
+
+func TestKafkaMessage(t *testing.T) {
+	sub := &types.Subscription{SubscriptionID: 1, Secret: "secret"}
+	dist := &types.DistInstCtx{DistInst: types.DistInst{EventInst: types.EventInst{
+		ID:        7,
+		EventType: types.EventTypeInstData,
+		ObjType:   "host",
+		CurData:   map[string]interface{}{"bk_host_id": float64(1)},
+	}, DstbID: 3, SubscriptionID: 1}, Raw: `{"event_id":7}`}
+
+	msg := kafkaMessage(sub, dist)
+	if string(msg.Key) != "host:1" || string(msg.Value) != dist.Raw {
+		t.Fatalf("unexpected message key %s value %s", msg.Key, msg.Value)
+	}
+	headers := map[string]string{}
+	for _, header := range msg.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+	if headers[types.CallbackHeaderDeliveryID] != "1-3" || headers[types.CallbackHeaderEventID] != "7" || headers[types.CallbackHeaderSignature] == "" {
+		t.Fatalf("the delivery headers should be kept but got %v", headers)
+	}
+}
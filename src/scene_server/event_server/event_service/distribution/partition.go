@@ -0,0 +1,184 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package distribution
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/core/cc/api"
+	"configcenter/src/scene_server/event_server/types"
+	"configcenter/src/source_controller/common/instdata"
+	"fmt"
+	"hash/fnv"
+	redis "gopkg.in/redis.v5"
+	"sync"
+	"time"
+)
+
+// partitionWorkers deliver the dists of one subscriber concurrently, the dists of the same
+// instance always go to the same partition so that they are still delivered in order, the
+// partitions are buffered in the redis lists so that the dists survive the restarts
+type partitionWorkers struct {
+	subID    string
+	size     int
+	mu       sync.Mutex
+	sub      types.Subscription
+	stopping chan struct{}
+	drain    bool
+	wg       sync.WaitGroup
+}
+
+func newPartitionWorkers(sub types.Subscription, size int) *partitionWorkers {
+	workers := &partitionWorkers{subID: fmt.Sprint(sub.SubscriptionID), size: size, sub: sub, stopping: make(chan struct{})}
+	workers.recover()
+	for i := 0; i < size; i++ {
+		workers.wg.Add(1)
+		go workers.run(types.DistPartitionKey(workers.subID, i))
+	}
+	return workers
+}
+
+// run delivers the dists of the partition until the workers stop
+func (w *partitionWorkers) run(key string) {
+	defer w.wg.Done()
+	redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
+	for {
+		select {
+		case <-w.stopping:
+			if !w.drain {
+				return
+			}
+		default:
+		}
+		values, err := redisCli.BLPop(types.PartitionPopTimeout, key).Result()
+		if err == redis.Nil {
+			select {
+			case <-w.stopping:
+				return
+			default:
+				continue
+			}
+		}
+		if err != nil {
+			blog.Errorf("pop partition %s error: %v", key, err)
+			time.Sleep(types.PartitionPopTimeout)
+			continue
+		}
+		dist := decodeDist(values[1])
+		if dist == nil {
+			continue
+		}
+		sub := w.subscription()
+		if err := handlePartitionedDist(&sub, dist); err != nil {
+			blog.Errorf("error handle dist: %v, %v", err, dist)
+		}
+	}
+}
+
+func (w *partitionWorkers) subscription() types.Subscription {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.sub
+}
+
+// recover moves the dists left in the partitions beyond the size, which are buffered
+// before the partitions of the subscription changed, to the partitions in use
+func (w *partitionWorkers) recover() {
+	redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
+	for i := w.size; i < types.MaxPartitions; i++ {
+		key := types.DistPartitionKey(w.subID, i)
+		for {
+			value, err := redisCli.LPop(key).Result()
+			if err == redis.Nil {
+				break
+			}
+			if err != nil {
+				blog.Errorf("recover partition %s error: %v", key, err)
+				break
+			}
+			if dist := decodeDist(value); dist != nil {
+				if err = pushToQueue(types.DistPartitionKey(w.subID, partitionOf(dist, w.size)), value); err != nil {
+					blog.Errorf("recover dist of partition %s error: %v", key, err)
+				}
+			}
+		}
+	}
+}
+
+// dispatch blocks when the partition of the dist is full
+func (w *partitionWorkers) dispatch(sub types.Subscription, dist *types.DistInstCtx) error {
+	w.mu.Lock()
+	w.sub = sub
+	w.mu.Unlock()
+
+	key := types.DistPartitionKey(w.subID, partitionOf(dist, w.size))
+	redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
+	for {
+		length, err := redisCli.LLen(key).Result()
+		if err != nil {
+			return err
+		}
+		if length < types.PartitionBufferSize {
+			break
+		}
+		time.Sleep(waitperiod)
+	}
+	return pushToQueue(key, dist.Raw)
+}
+
+// stop stops all the workers, the buffered dists are delivered first if drain, or else
+// they are kept in the partitions for the next workers
+func (w *partitionWorkers) stop(drain bool) {
+	w.drain = drain
+	close(w.stopping)
+	w.wg.Wait()
+}
+
+// hasPartitionedDists returns true if any dist of the subscriber is left in the partitions
+func hasPartitionedDists(subID int64) bool {
+	redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
+	for i := 0; i < types.MaxPartitions; i++ {
+		if exists, _ := redisCli.Exists(types.DistPartitionKey(fmt.Sprint(subID), i)).Result(); exists {
+			return true
+		}
+	}
+	return false
+}
+
+func partitionOf(dist *types.DistInstCtx, size int) int {
+	if size <= 1 {
+		return 0
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(partitionKey(dist)))
+	return int(hash.Sum32() % uint32(size))
+}
+
+// partitionKey returns the instance the dist belongs to, such as host:1 or set:2,
+// the relation events are keyed by the host so that they are ordered with the host events
+func partitionKey(dist *types.DistInstCtx) string {
+	data, ok := dist.CurData.(map[string]interface{})
+	if !ok || len(data) == 0 {
+		data, _ = dist.PreData.(map[string]interface{})
+	}
+
+	objType, idField := dist.ObjType, instdata.GetIDNameByType(dist.ObjType)
+	if dist.EventType == types.EventTypeRelation {
+		objType, idField = common.BKInnerObjIDHost, common.BKHostIDField
+	}
+	if id, ok := data[idField]; ok {
+		return fmt.Sprintf("%s:%v", objType, id)
+	}
+	// events without instance id fallback to be ordered with the same object type
+	return dist.ObjType
+}
@@ -0,0 +1,114 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package distribution
+
+import (
+	"configcenter/src/common/core/cc/api"
+	"configcenter/src/scene_server/event_server/types"
+	"encoding/json"
+	"fmt"
+	redis "gopkg.in/redis.v5"
+	"testing"
+)
+
+func TestPartitionKey(t *testing.T) {
+	host := &types.DistInstCtx{DistInst: types.DistInst{EventInst: types.EventInst{
+		EventType: types.EventTypeInstData,
+		ObjType:   "host",
+		CurData:   map[string]interface{}{"bk_host_id": float64(1), "bk_host_innerip": "127.0.0.1"},
+	}}}
+	deleted := &types.DistInstCtx{DistInst: types.DistInst{EventInst: types.EventInst{
+		EventType: types.EventTypeInstData,
+		ObjType:   "set",
+		PreData:   map[string]interface{}{"bk_set_id": float64(2)},
+	}}}
+	relation := &types.DistInstCtx{DistInst: types.DistInst{EventInst: types.EventInst{
+		EventType: types.EventTypeRelation,
+		ObjType:   "moduletransfer",
+		CurData:   map[string]interface{}{"bk_host_id": float64(1), "bk_module_id": float64(3)},
+	}}}
+	unknown := &types.DistInstCtx{DistInst: types.DistInst{EventInst: types.EventInst{
+		EventType: types.EventTypeInstData,
+		ObjType:   "switch",
+	}}}
+
+	for dist, expect := range map[*types.DistInstCtx]string{
+		host:     "host:1",
+		deleted:  "set:2",
+		relation: "host:1",
+		unknown:  "switch",
+	} {
+		if key := partitionKey(dist); key != expect {
+			t.Errorf("partition key of %s is %s, expect %s", dist.ObjType, key, expect)
+		}
+	}
+
+	if partitionOf(host, 8) != partitionOf(relation, 8) {
+		t.Errorf("the host and its relation events should be in the same partition")
+	}
+	if partitionOf(host, 1) != 0 {
+		t.Errorf("single partition should always be 0")
+	}
+}
+
+func TestDecodeDist(t *testing.T) {
+	dist := decodeDist(`{"event_id":7,"DstbID":3,"SubscriptionID":1}`)
+	if dist == nil || dist.ID != 7 || dist.DstbID != 3 || dist.SubscriptionID != 1 {
+		t.Fatalf("unexpected dist %+v", dist)
+	}
+	if decodeDist("malformed") != nil {
+		t.Fatal("the malformed dist should be dropped")
+	}
+}
+
+func TestPartitionWorkersRecover(t *testing.T) {
+	initTester()
+	redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
+	sub := types.Subscription{SubscriptionID: 9998}
+	subID := fmt.Sprint(sub.SubscriptionID)
+	keys := []string{}
+	for i := 0; i < types.MaxPartitions; i++ {
+		keys = append(keys, types.DistPartitionKey(subID, i))
+	}
+	redisCli.Del(keys...)
+	defer redisCli.Del(keys...)
+
+	// the dist left in the partition beyond the size is moved to the partition in use
+	dist := &types.DistInstCtx{DistInst: types.DistInst{EventInst: types.EventInst{
+		EventType: types.EventTypeInstData,
+		ObjType:   "host",
+		CurData:   map[string]interface{}{"bk_host_id": float64(1)},
+	}, SubscriptionID: sub.SubscriptionID}}
+	raw, _ := json.Marshal(dist.DistInst)
+	dist.Raw = string(raw)
+	redisCli.RPush(types.DistPartitionKey(subID, types.MaxPartitions-1), dist.Raw)
+	if !hasPartitionedDists(sub.SubscriptionID) {
+		t.Fatal("the dist left in the partition should be found")
+	}
+
+	workers := &partitionWorkers{subID: subID, size: 2, sub: sub, stopping: make(chan struct{})}
+	workers.recover()
+	if n := redisCli.LLen(types.DistPartitionKey(subID, partitionOf(dist, 2))).Val(); n != 1 {
+		t.Fatalf("the dist should be moved to partition %d but got %d dists there", partitionOf(dist, 2), n)
+	}
+	if n := redisCli.LLen(types.DistPartitionKey(subID, types.MaxPartitions-1)).Val(); n != 0 {
+		t.Fatalf("the partition beyond the size should be empty but got %d dists", n)
+	}
+
+	if err := workers.dispatch(sub, dist); err != nil {
+		t.Fatal(err)
+	}
+	if n := redisCli.LLen(types.DistPartitionKey(subID, partitionOf(dist, 2))).Val(); n != 2 {
+		t.Fatalf("the dist should be buffered in partition %d but got %d dists there", partitionOf(dist, 2), n)
+	}
+}
@@ -0,0 +1,50 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package distribution
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/scene_server/event_server/types"
+	"configcenter/src/source_controller/common/instdata"
+	"encoding/json"
+)
+
+// compactDist keeps only the identifying keys of the instance and the changed fields,
+// the relation events are returned as it is
+func compactDist(dist *types.DistInstCtx) *types.DistInstCtx {
+	if dist.EventType != types.EventTypeInstData {
+		return dist
+	}
+
+	data, ok := dist.CurData.(map[string]interface{})
+	if !ok || len(data) == 0 {
+		data, _ = dist.PreData.(map[string]interface{})
+	}
+	keys := map[string]interface{}{}
+	for _, field := range []string{instdata.GetIDNameByType(dist.ObjType), common.BKObjIDField, common.BKAppIDField, common.BKOwnerIDField} {
+		if val, ok := data[field]; ok {
+			keys[field] = val
+		}
+	}
+
+	compact := dist.DistInst
+	compact.CurData = keys
+	compact.PreData = nil
+	raw, err := json.Marshal(compact)
+	if err != nil {
+		blog.Errorf("compact dist %d error: %v, send full data instead", dist.DstbID, err)
+		return dist
+	}
+	return &types.DistInstCtx{DistInst: compact, Raw: string(raw)}
+}
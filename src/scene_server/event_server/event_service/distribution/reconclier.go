@@ -17,6 +17,7 @@ import (
 	"configcenter/src/common/core/cc/api"
 	"configcenter/src/common/blog"
 	"configcenter/src/common/util"
+	"configcenter/src/scene_server/event_server/event_service/filter"
 	"configcenter/src/scene_server/event_server/types"
 	"configcenter/src/source_controller/common/instdata"
 	"configcenter/src/storage"
@@ -32,6 +33,7 @@ type reconciler struct {
 	persisted            map[string][]string
 	cachedSubscribers    []string
 	persistedSubscribers []string
+	persistedFilters     map[string]string
 	processID            string
 }
 
@@ -40,11 +42,15 @@ func newReconciler() *reconciler {
 		cached:               map[string][]string{},
 		persisted:            map[string][]string{},
 		persistedSubscribers: []string{},
+		persistedFilters:     map[string]string{},
 	}
 }
 
 var MsgChan = make(chan string, 3)
 
+// reconcileScanCount how many keys are asked for by each SCAN of the reconciler
+const reconcileScanCount = 100
+
 func (r *reconciler) loadAll() {
 	r.loadAllCached()
 	r.loadAllPersisted()
@@ -68,6 +74,9 @@ func (r *reconciler) loadAllPersisted() {
 	for _, sub := range subscriptions {
 		eventnames := strings.Split(sub.SubscriptionForm, ",")
 		r.persistedSubscribers = append(r.persistedSubscribers, sub.GetCacheKey())
+		if sub.Filter != "" {
+			r.persistedFilters[fmt.Sprint(sub.SubscriptionID)] = sub.Filter
+		}
 		for _, eventname := range eventnames {
 			r.persisted[eventname] = append(r.persisted[eventname], fmt.Sprint(sub.SubscriptionID))
 		}
@@ -97,6 +106,23 @@ func (r *reconciler) reconcile() {
 	for k := range r.cached {
 		redisCli.Del(types.EventCacheSubscribeformKey + k)
 	}
+
+	iter := redisCli.Scan(0, types.EventCacheSubscribeFilterPrefix+"*", reconcileScanCount).Iterator()
+	for iter.Next() {
+		subID := strings.TrimPrefix(iter.Val(), types.EventCacheSubscribeFilterPrefix)
+		if _, ok := r.persistedFilters[subID]; !ok {
+			redisCli.Del(iter.Val())
+			filter.Forget(subID)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		blog.Errorf("reconcile err: %v", err)
+	}
+	for subID, expr := range r.persistedFilters {
+		if err := redisCli.Set(types.EventCacheSubscribeFilterPrefix+subID, expr, 0).Err(); err != nil {
+			blog.Errorf("reconcile err: %v", err)
+		}
+	}
 }
 
 func SubscribeChannel(config map[string]string) (err error) {
@@ -0,0 +1,116 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package distribution
+
+import (
+	"configcenter/src/common/core/cc/api"
+	"configcenter/src/scene_server/event_server/types"
+	"errors"
+	"fmt"
+	redis "gopkg.in/redis.v5"
+	"strings"
+	"time"
+
+	kafka "github.com/segmentio/kafka-go"
+)
+
+var kafkaCli = newKafkaProducer("bk-cmdb-event")
+
+// deliver writes the dist to the sink of the subscription,
+// the status code and the response are only returned by the http sink
+func deliver(sub *types.Subscription, dist *types.DistInstCtx) (statusCode int, respdata []byte, err error) {
+	switch sub.GetSinkType() {
+	case types.SinkTypeRedisStream:
+		err = countSinkResult(sub, sendToRedisStream(sub, dist))
+	case types.SinkTypeKafka:
+		err = countSinkResult(sub, sendToKafka(sub, dist))
+	default:
+		return sendDistCallback(sub, dist)
+	}
+	return 0, nil, err
+}
+
+// countSinkResult keep the statistics of the sinks the same as the http callbacks
+func countSinkResult(sub *types.Subscription, err error) error {
+	redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
+	redisCli.HIncrBy(types.EventCacheDistCallBackCountPrefix+fmt.Sprint(sub.SubscriptionID), "total", 1)
+	if err != nil {
+		redisCli.HIncrBy(types.EventCacheDistCallBackCountPrefix+fmt.Sprint(sub.SubscriptionID), "failue", 1)
+	}
+	return err
+}
+
+// sendToRedisStream XADD the dist to the stream, the delivery headers are saved as the entry fields
+func sendToRedisStream(sub *types.Subscription, dist *types.DistInstCtx) error {
+	if sub.SinkConfig == nil || sub.SinkConfig.Stream == "" {
+		return errors.New("event distribute fail, redis stream not set")
+	}
+	args := []interface{}{"XADD", sub.SinkConfig.Stream}
+	if sub.SinkConfig.MaxLen > 0 {
+		args = append(args, "MAXLEN", "~", sub.SinkConfig.MaxLen)
+	}
+	args = append(args, "*")
+	header := deliveryHeader(sub, dist)
+	for key := range header {
+		args = append(args, key, header.Get(key))
+	}
+	args = append(args, "event", dist.Raw)
+
+	cmd := redis.NewStringCmd(args...)
+	redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
+	if err := redisCli.Process(cmd); err != nil {
+		return fmt.Errorf("event distribute fail, xadd to %s error: %v", sub.SinkConfig.Stream, err)
+	}
+	return nil
+}
+
+// sendToKafka produce the dist to the topic
+func sendToKafka(sub *types.Subscription, dist *types.DistInstCtx) error {
+	if sub.SinkConfig == nil || sub.SinkConfig.Brokers == "" || sub.SinkConfig.Topic == "" {
+		return errors.New("event distribute fail, kafka brokers or topic not set")
+	}
+
+	duration := sub.GetTimeout()
+	if sub.TimeOut == 0 {
+		duration = timeout
+	}
+	if err := kafkaCli.Produce(splitBrokers(sub.SinkConfig.Brokers), sub.SinkConfig.Topic, sub.SinkConfig.Partition, kafkaMessage(sub, dist), duration); err != nil {
+		return fmt.Errorf("event distribute fail, produce to %s error: %v", sub.SinkConfig.Topic, err)
+	}
+	return nil
+}
+
+// kafkaMessage returns the message of the dist, it is keyed by the instance of the dist
+// and the delivery headers are saved as the message headers
+func kafkaMessage(sub *types.Subscription, dist *types.DistInstCtx) kafka.Message {
+	msg := kafka.Message{
+		Key:   []byte(partitionKey(dist)),
+		Value: []byte(dist.Raw),
+		Time:  time.Now(),
+	}
+	header := deliveryHeader(sub, dist)
+	for key := range header {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: key, Value: []byte(header.Get(key))})
+	}
+	return msg
+}
+
+func splitBrokers(brokers string) []string {
+	addrs := []string{}
+	for _, addr := range strings.Split(brokers, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
@@ -0,0 +1,153 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package distribution
+
+import (
+	"configcenter/src/common/blog"
+	"configcenter/src/common/core/cc/api"
+	commontypes "configcenter/src/common/types"
+	"configcenter/src/scene_server/event_server/types"
+	"fmt"
+	redis "gopkg.in/redis.v5"
+	"strconv"
+	"time"
+)
+
+// the fields of the subscription state hash in cache
+const (
+	stateFieldStatus      = "status"
+	stateFieldFailures    = "failures"
+	stateFieldTrippedTime = "tripped_time"
+	stateFieldNextProbe   = "next_probe"
+)
+
+func stateKey(subID int64) string {
+	return types.EventCacheDistStatePrefix + fmt.Sprint(subID)
+}
+
+// GetSubscriptionState returns the delivery state of the subscriber
+func GetSubscriptionState(subID int64) (*types.SubscriptionState, error) {
+	redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
+	val, err := redisCli.HGetAll(stateKey(subID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	state := &types.SubscriptionState{Status: val[stateFieldStatus]}
+	if state.Status == "" {
+		state.Status = types.SubscriptionStatusActive
+	}
+	state.ConsecutiveFailures, _ = strconv.ParseInt(val[stateFieldFailures], 10, 64)
+	if sec, _ := strconv.ParseInt(val[stateFieldTrippedTime], 10, 64); sec > 0 {
+		state.TrippedTime = &commontypes.Time{Time: time.Unix(sec, 0).UTC()}
+	}
+	if sec, _ := strconv.ParseInt(val[stateFieldNextProbe], 10, 64); sec > 0 {
+		state.NextProbeTime = &commontypes.Time{Time: time.Unix(sec, 0).UTC()}
+	}
+	return state, nil
+}
+
+// PauseSubscription stops the deliveries of the subscriber, the events are kept in the distribute queue
+func PauseSubscription(subID int64) error {
+	redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
+	return redisCli.HSet(stateKey(subID), stateFieldStatus, types.SubscriptionStatusPaused).Err()
+}
+
+// ResumeSubscription restarts the deliveries of the paused or tripped subscriber
+func ResumeSubscription(subID int64) error {
+	redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
+	return redisCli.Del(stateKey(subID)).Err()
+}
+
+// readyToDeliver returns the status of the subscriber and whether a dist could be sent now,
+// the tripped subscriber is ready only when its probe time arrives
+func readyToDeliver(subID int64) (string, bool) {
+	state, err := GetSubscriptionState(subID)
+	if err != nil {
+		// do not stop the deliveries because of the cache failure
+		blog.Errorf("get state of subscriber %d error: %v", subID, err)
+		return types.SubscriptionStatusActive, true
+	}
+	switch state.Status {
+	case types.SubscriptionStatusPaused:
+		return state.Status, false
+	case types.SubscriptionStatusTripped:
+		return state.Status, state.NextProbeTime == nil || !time.Now().Before(state.NextProbeTime.Time)
+	}
+	return state.Status, true
+}
+
+// countDeliveryResult counts the consecutive failed deliveries and trips the circuit breaker
+func countDeliveryResult(sub *types.Subscription, senderr error) {
+	redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
+	key := stateKey(sub.SubscriptionID)
+	if senderr == nil {
+		redisCli.HDel(key, stateFieldFailures)
+		return
+	}
+
+	failures, err := redisCli.HIncrBy(key, stateFieldFailures, 1).Result()
+	if err != nil {
+		blog.Errorf("count failures of subscriber %d error: %v", sub.SubscriptionID, err)
+		return
+	}
+	breaker := sub.GetCircuitBreaker()
+	if !breaker.Enabled() || failures < breaker.FailureThreshold {
+		return
+	}
+	status := redisCli.HGet(key, stateFieldStatus).Val()
+	if status != "" && status != types.SubscriptionStatusActive {
+		return
+	}
+	now := time.Now()
+	blog.Warnf("subscriber %d failed %d times in a row, circuit breaker tripped", sub.SubscriptionID, failures)
+	redisCli.HMSet(key, map[string]string{
+		stateFieldStatus:      types.SubscriptionStatusTripped,
+		stateFieldTrippedTime: fmt.Sprint(now.Unix()),
+		stateFieldNextProbe:   fmt.Sprint(now.Add(time.Second * time.Duration(breaker.ProbeInterval)).Unix()),
+	})
+}
+
+// probeDist sends the dist once to the tripped subscriber, the circuit breaker is closed when it succeeds,
+// otherwise the dist is put back to the head of the distribute queue and the next probe is scheduled
+func probeDist(sub *types.Subscription, dist *types.DistInstCtx) error {
+	blog.Infof("probing subscriber %d with dist %d", sub.SubscriptionID, dist.DstbID)
+	payload := dist
+	if sub.PayloadMode == types.PayloadModeCompact {
+		payload = compactDist(dist)
+	}
+	start := time.Now()
+	statusCode, respdata, senderr := deliver(sub, payload)
+	if err := recordDelivery(payload, 1, statusCode, respdata, time.Since(start), senderr); err != nil {
+		blog.Errorf("record delivery of dist %d error: %v", dist.DstbID, err)
+	}
+
+	redisCli := api.GetAPIResource().CacheCli.GetSession().(*redis.Client)
+	key := stateKey(sub.SubscriptionID)
+	if senderr != nil {
+		breaker := sub.GetCircuitBreaker()
+		next := time.Now().Add(time.Second * time.Duration(breaker.ProbeInterval))
+		redisCli.HIncrBy(key, stateFieldFailures, 1)
+		redisCli.HSet(key, stateFieldNextProbe, fmt.Sprint(next.Unix()))
+		if err := redisCli.LPush(types.EventCacheDistQueuePrefix+fmt.Sprint(dist.SubscriptionID), dist.Raw).Err(); err != nil {
+			return err
+		}
+		return senderr
+	}
+
+	blog.Infof("subscriber %d recovered, circuit breaker closed", sub.SubscriptionID)
+	if err := redisCli.HMSet(key, map[string]string{stateFieldStatus: types.SubscriptionStatusActive, stateFieldFailures: "0"}).Err(); err != nil {
+		return err
+	}
+	redisCli.HDel(key, stateFieldTrippedTime, stateFieldNextProbe)
+	return saveDistDone(dist)
+}
@@ -0,0 +1,348 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package filter implements the field level filter expressions of the event subscriptions.
+//
+// An expression is evaluated over the cur_data and pre_data of an event, for example:
+//
+//	bk_os_type == "Linux"
+//	bk_biz_id in [3, 5] && changed(bk_host_innerip)
+//	pre.bk_host_name =~ "^db-" || not exists(bk_cloud_id)
+//
+// Fields without prefix are read from cur_data, or pre_data when cur_data is empty (delete events),
+// the "cur." and "pre." prefixes select the data explicitly, and nested fields are separated by ".".
+package filter
+
+import (
+	"configcenter/src/scene_server/event_server/types"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Filter a compiled filter expression
+type Filter struct {
+	expr string
+	root node
+}
+
+// Compile parse the expression into a filter
+func Compile(expr string) (*Filter, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &Filter{expr: expr, root: root}, nil
+}
+
+// String returns the source expression
+func (f *Filter) String() string {
+	return f.expr
+}
+
+// Match check whether the event matches the filter
+func (f *Filter) Match(event *types.EventInst) bool {
+	ctx := &evalContext{
+		cur: toMap(event.CurData),
+		pre: toMap(event.PreData),
+	}
+	return f.root.eval(ctx)
+}
+
+// cache keeps the compiled filter of each subscriber, it is compiled again when the expression
+// of the subscriber changes and dropped by Forget when the subscriber is deleted
+var (
+	cache     = map[string]*Filter{}
+	cacheLock sync.RWMutex
+)
+
+// Match compile the expression of the subscriber with cache and check whether the event matches it,
+// an empty expression matches everything
+func Match(subscriber, expr string, event *types.EventInst) (bool, error) {
+	if strings.TrimSpace(expr) == "" {
+		return true, nil
+	}
+	cacheLock.RLock()
+	f, ok := cache[subscriber]
+	cacheLock.RUnlock()
+	if !ok || f.expr != expr {
+		var err error
+		if f, err = Compile(expr); err != nil {
+			return false, err
+		}
+		cacheLock.Lock()
+		cache[subscriber] = f
+		cacheLock.Unlock()
+	}
+	return f.Match(event), nil
+}
+
+// Forget drops the compiled filter of the subscriber
+func Forget(subscriber string) {
+	cacheLock.Lock()
+	delete(cache, subscriber)
+	cacheLock.Unlock()
+}
+
+type evalContext struct {
+	cur map[string]interface{}
+	pre map[string]interface{}
+}
+
+func toMap(data interface{}) map[string]interface{} {
+	switch d := data.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		return d
+	}
+	out, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+	m := map[string]interface{}{}
+	if err = json.Unmarshal(out, &m); err != nil {
+		return nil
+	}
+	return m
+}
+
+type node interface {
+	eval(ctx *evalContext) bool
+}
+
+type operand interface {
+	resolve(ctx *evalContext) (interface{}, bool)
+}
+
+type literal struct {
+	value interface{}
+}
+
+func (l literal) resolve(ctx *evalContext) (interface{}, bool) {
+	return l.value, true
+}
+
+type fieldRef struct {
+	source string
+	path   []string
+}
+
+func newFieldRef(name string) fieldRef {
+	ref := fieldRef{}
+	switch {
+	case strings.HasPrefix(name, "cur."):
+		ref.source, name = "cur", strings.TrimPrefix(name, "cur.")
+	case strings.HasPrefix(name, "pre."):
+		ref.source, name = "pre", strings.TrimPrefix(name, "pre.")
+	}
+	ref.path = strings.Split(name, ".")
+	return ref
+}
+
+func (f fieldRef) withSource(source string) fieldRef {
+	return fieldRef{source: source, path: f.path}
+}
+
+func (f fieldRef) resolve(ctx *evalContext) (interface{}, bool) {
+	var data map[string]interface{}
+	switch f.source {
+	case "cur":
+		data = ctx.cur
+	case "pre":
+		data = ctx.pre
+	default:
+		data = ctx.cur
+		if len(data) == 0 {
+			data = ctx.pre
+		}
+	}
+
+	var val interface{} = data
+	for _, key := range f.path {
+		m, ok := val.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if val, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return val, true
+}
+
+type orNode struct {
+	left, right node
+}
+
+func (n *orNode) eval(ctx *evalContext) bool {
+	return n.left.eval(ctx) || n.right.eval(ctx)
+}
+
+type andNode struct {
+	left, right node
+}
+
+func (n *andNode) eval(ctx *evalContext) bool {
+	return n.left.eval(ctx) && n.right.eval(ctx)
+}
+
+type notNode struct {
+	node node
+}
+
+func (n *notNode) eval(ctx *evalContext) bool {
+	return !n.node.eval(ctx)
+}
+
+type compareNode struct {
+	op          string
+	left, right operand
+}
+
+func (n *compareNode) eval(ctx *evalContext) bool {
+	left, _ := n.left.resolve(ctx)
+	right, _ := n.right.resolve(ctx)
+	switch n.op {
+	case "==":
+		return equal(left, right)
+	case "!=":
+		return !equal(left, right)
+	}
+
+	cmp, ok := compare(left, right)
+	if !ok {
+		return false
+	}
+	switch n.op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+type inNode struct {
+	operand operand
+	list    []operand
+}
+
+func (n *inNode) eval(ctx *evalContext) bool {
+	val, ok := n.operand.resolve(ctx)
+	if !ok {
+		return false
+	}
+	for _, item := range n.list {
+		itemVal, _ := item.resolve(ctx)
+		if equal(val, itemVal) {
+			return true
+		}
+	}
+	return false
+}
+
+type matchNode struct {
+	operand operand
+	regexp  *regexp.Regexp
+}
+
+func (n *matchNode) eval(ctx *evalContext) bool {
+	val, ok := n.operand.resolve(ctx)
+	if !ok || val == nil {
+		return false
+	}
+	return n.regexp.MatchString(fmt.Sprint(val))
+}
+
+type changedNode struct {
+	field fieldRef
+}
+
+func (n *changedNode) eval(ctx *evalContext) bool {
+	cur, curOK := n.field.withSource("cur").resolve(ctx)
+	pre, preOK := n.field.withSource("pre").resolve(ctx)
+	if curOK != preOK {
+		return true
+	}
+	return !equal(cur, pre)
+}
+
+type existsNode struct {
+	field fieldRef
+}
+
+func (n *existsNode) eval(ctx *evalContext) bool {
+	val, ok := n.field.resolve(ctx)
+	return ok && val != nil
+}
+
+// toFloat converts the numeric value to float64
+func toFloat(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func equal(left, right interface{}) bool {
+	lf, lok := toFloat(left)
+	rf, rok := toFloat(right)
+	if lok && rok {
+		return lf == rf
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+func compare(left, right interface{}) (int, bool) {
+	lf, lok := toFloat(left)
+	rf, rok := toFloat(right)
+	if lok && rok {
+		switch {
+		case lf < rf:
+			return -1, true
+		case lf > rf:
+			return 1, true
+		}
+		return 0, true
+	}
+	ls, lok := left.(string)
+	rs, rok := right.(string)
+	if lok && rok {
+		return strings.Compare(ls, rs), true
+	}
+	return 0, false
+}
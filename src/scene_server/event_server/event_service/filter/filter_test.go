@@ -0,0 +1,112 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"configcenter/src/scene_server/event_server/types"
+	"encoding/json"
+	"testing"
+)
+
+func testEvent(action, cur, pre string) *types.EventInst {
+	event := &types.EventInst{Action: action}
+	if cur != "" {
+		json.Unmarshal([]byte(cur), &event.CurData)
+	}
+	if pre != "" {
+		json.Unmarshal([]byte(pre), &event.PreData)
+	}
+	return event
+}
+
+func TestMatch(t *testing.T) {
+	update := testEvent(types.EventActionUpdate,
+		`{"bk_host_id":1,"bk_os_type":"Linux","bk_biz_id":3,"bk_host_innerip":"127.0.0.2","bk_host_name":"db-1","bk_cpu":{"num":8}}`,
+		`{"bk_host_id":1,"bk_os_type":"Linux","bk_biz_id":3,"bk_host_innerip":"127.0.0.1","bk_host_name":"web-1","bk_cpu":{"num":4}}`)
+	remove := testEvent(types.EventActionDelete, "", `{"bk_host_id":1,"bk_os_type":"Windows","bk_biz_id":5}`)
+
+	cases := []struct {
+		expr   string
+		event  *types.EventInst
+		expect bool
+	}{
+		{``, update, true},
+		{`bk_os_type == "Linux"`, update, true},
+		{`bk_os_type == "Linux"`, remove, false},
+		{`bk_os_type != 'Linux'`, remove, true},
+		{`bk_biz_id in [3, 5]`, update, true},
+		{`bk_biz_id in [3, 5]`, remove, true},
+		{`bk_biz_id not in [3, 5]`, update, false},
+		{`changed(bk_host_innerip)`, update, true},
+		{`changed(bk_os_type)`, update, false},
+		{`changed(bk_cpu.num) && bk_cpu.num >= 8`, update, true},
+		{`pre.bk_cpu.num < 8 and cur.bk_cpu.num > 4`, update, true},
+		{`bk_host_name =~ "^db-" || bk_biz_id == 5`, update, true},
+		{`pre.bk_host_name =~ "^db-"`, update, false},
+		{`!(bk_os_type == "Linux") || exists(bk_not_exists)`, update, false},
+		{`not exists(bk_cloud_id)`, update, true},
+		{`bk_cloud_id == null`, update, true},
+	}
+	for _, c := range cases {
+		got, err := Match("1", c.expr, c.event)
+		if err != nil {
+			t.Fatalf("match %s error: %v", c.expr, err)
+		}
+		if got != c.expect {
+			t.Fatalf("match %s expect %v but got %v", c.expr, c.expect, got)
+		}
+	}
+}
+
+func TestMatchCache(t *testing.T) {
+	event := testEvent(types.EventActionCreate, `{"bk_host_id":1,"bk_os_type":"Linux"}`, "")
+	if matched, _ := Match("2", `bk_os_type == "Linux"`, event); !matched {
+		t.Fatal("the event should match")
+	}
+	// the filter is compiled again after the expression of the subscriber changes
+	if matched, _ := Match("2", `bk_os_type == "Windows"`, event); matched {
+		t.Fatal("the event should not match the changed expression")
+	}
+	cacheLock.RLock()
+	f, ok := cache["2"]
+	cacheLock.RUnlock()
+	if !ok || f.String() != `bk_os_type == "Windows"` {
+		t.Fatalf("the cache should keep the latest filter of the subscriber but got %v", f)
+	}
+
+	Forget("2")
+	cacheLock.RLock()
+	_, ok = cache["2"]
+	cacheLock.RUnlock()
+	if ok {
+		t.Fatal("the filter of the forgotten subscriber should be dropped")
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	exprs := []string{
+		`bk_os_type ==`,
+		`bk_os_type = "Linux"`,
+		`(bk_biz_id == 1`,
+		`bk_biz_id in 3`,
+		`bk_host_name =~ "["`,
+		`unknown(bk_host_name)`,
+		`bk_os_type == "Linux`,
+		`bk_os_type == "Linux" bk_biz_id`,
+	}
+	for _, expr := range exprs {
+		if _, err := Compile(expr); err == nil {
+			t.Fatalf("compile %s expect error but got nil", expr)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isKeyword(word string) bool {
+	t := p.peek()
+	return t.kind == tokenIdent && strings.ToLower(t.value) == word
+}
+
+func (p *parser) isOperator(op string) bool {
+	t := p.peek()
+	return t.kind == tokenOperator && t.value == op
+}
+
+func (p *parser) parse() (node, error) {
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s", t)
+	}
+	return n, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("||") || p.isKeyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("&&") || p.isKeyword("and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOperator("!") || p.isKeyword("not") {
+		p.next()
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{node: n}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.peek()
+	if t.kind == tokenLParen {
+		p.next()
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokenRParen {
+			return nil, fmt.Errorf("expect ')' but got %s", t)
+		}
+		return n, nil
+	}
+
+	if t.kind == tokenIdent && p.tokens[p.pos+1].kind == tokenLParen {
+		return p.parseFunc()
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.isKeyword("in") {
+		p.next()
+		list, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return &inNode{operand: left, list: list}, nil
+	}
+	if p.isKeyword("not") && p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].kind == tokenIdent && strings.ToLower(p.tokens[p.pos+1].value) == "in" {
+		p.next()
+		p.next()
+		list, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{node: &inNode{operand: left, list: list}}, nil
+	}
+
+	op := p.next()
+	if op.kind != tokenOperator {
+		return nil, fmt.Errorf("expect comparison operator but got %s", op)
+	}
+	switch op.value {
+	case "==", "!=", ">", ">=", "<", "<=":
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &compareNode{op: op.value, left: left, right: right}, nil
+	case "=~":
+		pattern := p.next()
+		if pattern.kind != tokenString {
+			return nil, fmt.Errorf("expect regular expression string but got %s", pattern)
+		}
+		reg, err := regexp.Compile(pattern.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %s: %v", pattern, err)
+		}
+		return &matchNode{operand: left, regexp: reg}, nil
+	}
+	return nil, fmt.Errorf("unexpected operator %s", op)
+}
+
+func (p *parser) parseFunc() (node, error) {
+	name := p.next()
+	p.next() // (
+	field := p.next()
+	if field.kind != tokenIdent {
+		return nil, fmt.Errorf("expect field name but got %s", field)
+	}
+	if t := p.next(); t.kind != tokenRParen {
+		return nil, fmt.Errorf("expect ')' but got %s", t)
+	}
+	ref := newFieldRef(field.value)
+	switch strings.ToLower(name.value) {
+	case "changed":
+		return &changedNode{field: ref}, nil
+	case "exists":
+		return &existsNode{field: ref}, nil
+	}
+	return nil, fmt.Errorf("unknown function %s", name)
+}
+
+func (p *parser) parseList() ([]operand, error) {
+	if t := p.next(); t.kind != tokenLBracket {
+		return nil, fmt.Errorf("expect '[' but got %s", t)
+	}
+	list := []operand{}
+	if p.peek().kind == tokenRBracket {
+		p.next()
+		return list, nil
+	}
+	for {
+		item, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+		t := p.next()
+		if t.kind == tokenRBracket {
+			return list, nil
+		}
+		if t.kind != tokenComma {
+			return nil, fmt.Errorf("expect ',' or ']' but got %s", t)
+		}
+	}
+}
+
+func (p *parser) parseOperand() (operand, error) {
+	t := p.peek()
+	if t.kind == tokenIdent {
+		switch strings.ToLower(t.value) {
+		case "true", "false", "null":
+			return p.parseLiteral()
+		}
+		p.next()
+		return newFieldRef(t.value), nil
+	}
+	return p.parseLiteral()
+}
+
+func (p *parser) parseLiteral() (operand, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return literal{value: t.value}, nil
+	case tokenNumber:
+		f, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s", t)
+		}
+		return literal{value: f}, nil
+	case tokenIdent:
+		switch strings.ToLower(t.value) {
+		case "true":
+			return literal{value: true}, nil
+		case "false":
+			return literal{value: false}, nil
+		case "null":
+			return literal{value: nil}, nil
+		}
+	}
+	return nil, fmt.Errorf("expect literal value but got %s", t)
+}
@@ -0,0 +1,95 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// FieldChange define the old and new value of a changed field
+type FieldChange struct {
+	Pre interface{} `json:"pre"`
+	Cur interface{} `json:"cur"`
+}
+
+// DiffData returns the changed fields between pre and cur, the nested maps are compared
+// field by field and the keys are joined by ".", a field which only exists on one side is
+// treated as changed with the other side nil.
+func DiffData(pre, cur interface{}) map[string]FieldChange {
+	changes := map[string]FieldChange{}
+	preMap, ok := normalizeData(pre).(map[string]interface{})
+	if !ok {
+		preMap = map[string]interface{}{}
+	}
+	curMap, ok := normalizeData(cur).(map[string]interface{})
+	if !ok {
+		curMap = map[string]interface{}{}
+	}
+	diffValue("", preMap, curMap, changes)
+	return changes
+}
+
+// ChangedFieldNames returns the sorted names of the changed fields
+func ChangedFieldNames(changes map[string]FieldChange) []string {
+	names := make([]string, 0, len(changes))
+	for name := range changes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// normalizeData converts the data into the json types, so that the bson.M, typed maps, ints and
+// time values produced by the controllers are compared with the same representation
+func normalizeData(data interface{}) interface{} {
+	if data == nil {
+		return nil
+	}
+	out, err := json.Marshal(data)
+	if err != nil {
+		return data
+	}
+	var normalized interface{}
+	if err = json.Unmarshal(out, &normalized); err != nil {
+		return data
+	}
+	return normalized
+}
+
+func diffValue(prefix string, pre, cur interface{}, changes map[string]FieldChange) {
+	preMap, preIsMap := pre.(map[string]interface{})
+	curMap, curIsMap := cur.(map[string]interface{})
+	if preIsMap && curIsMap {
+		for key, preVal := range preMap {
+			diffValue(joinField(prefix, key), preVal, curMap[key], changes)
+		}
+		for key, curVal := range curMap {
+			if _, ok := preMap[key]; !ok {
+				diffValue(joinField(prefix, key), nil, curVal, changes)
+			}
+		}
+		return
+	}
+	if !reflect.DeepEqual(pre, cur) {
+		changes[prefix] = FieldChange{Pre: pre, Cur: cur}
+	}
+}
+
+func joinField(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
@@ -16,23 +16,69 @@ import (
 	"configcenter/src/common"
 	"configcenter/src/common/types"
 	"encoding/json"
+	"fmt"
 	"time"
 )
 
 // Subscription define
 type Subscription struct {
-	SubscriptionID   int64       `bson:"subscription_id" json:"subscription_id"`
-	SubscriptionName string      `bson:"subscription_name" json:"subscription_name"`
-	SystemName       string      `bson:"system_name" json:"system_name"`
-	CallbackURL      string      `bson:"callback_url" json:"callback_url"`
-	ConfirmMode      string      `bson:"confirm_mode" json:"confirm_mode"`
-	ConfirmPattern   string      `bson:"confirm_pattern" json:"confirm_pattern"`
-	TimeOut          int64       `bson:"time_out" json:"time_out"`                   // second
-	SubscriptionForm string      `bson:"subscription_form" json:"subscription_form"` // json format
-	Operator         string      `bson:"operator" json:"operator"`
-	OwnerID          string      `bson:"supplier_account" json:"supplier_account"`
-	LastTime         *types.Time `bson:"last_time" json:"last_time"`
-	Statistics       *Statistics `bson:"-" json:"statistics"`
+	SubscriptionID   int64        `bson:"subscription_id" json:"subscription_id"`
+	SubscriptionName string       `bson:"subscription_name" json:"subscription_name"`
+	SystemName       string       `bson:"system_name" json:"system_name"`
+	CallbackURL      string       `bson:"callback_url" json:"callback_url"`
+	ConfirmMode      string       `bson:"confirm_mode" json:"confirm_mode"`
+	ConfirmPattern   string       `bson:"confirm_pattern" json:"confirm_pattern"`
+	TimeOut          int64        `bson:"time_out" json:"time_out"`                   // second
+	SubscriptionForm string       `bson:"subscription_form" json:"subscription_form"` // json format
+	Operator         string       `bson:"operator" json:"operator"`
+	OwnerID          string       `bson:"supplier_account" json:"supplier_account"`
+	LastTime         *types.Time  `bson:"last_time" json:"last_time"`
+	RetryPolicy      *RetryPolicy `bson:"retry_policy" json:"retry_policy"`
+	Secret           string       `bson:"secret" json:"secret,omitempty"`   // used to sign the callback request
+	Filter           string       `bson:"filter" json:"filter"`             // filter expression over cur_data and pre_data
+	PayloadMode      string       `bson:"payload_mode" json:"payload_mode"` // full or compact
+	Partitions       int64        `bson:"partitions" json:"partitions"`     // 0 or 1 means strict ordering
+	SinkType         string       `bson:"sink_type" json:"sink_type"`       // http, redis_stream or kafka
+	SinkConfig       *SinkConfig  `bson:"sink_config" json:"sink_config"`
+	Statistics       *Statistics  `bson:"-" json:"statistics"`
+
+	// CircuitBreaker stops the delivery when the subscription keeps failing, nil never trips, State is the current delivery state
+	CircuitBreaker *CircuitBreaker    `bson:"circuit_breaker" json:"circuit_breaker"`
+	State          *SubscriptionState `bson:"-" json:"state"`
+}
+
+// RetryPolicy define how a failed callback will be retried
+type RetryPolicy struct {
+	MaxAttempts int64 `bson:"max_attempts" json:"max_attempts"`
+	BackoffBase int64 `bson:"backoff_base" json:"backoff_base"` // millisecond
+	BackoffCap  int64 `bson:"backoff_cap" json:"backoff_cap"`   // millisecond
+}
+
+// SinkConfig define where the events are written to when the sink type is not http
+type SinkConfig struct {
+	// Stream the redis stream name, used by the redis_stream sink
+	Stream string `bson:"stream" json:"stream"`
+	// MaxLen the approximate max length of the redis stream, 0 means unlimited
+	MaxLen int64 `bson:"max_len" json:"max_len"`
+
+	// Brokers the comma separated host:port of the kafka brokers, used by the kafka sink
+	Brokers   string `bson:"brokers" json:"brokers"`
+	Topic     string `bson:"topic" json:"topic"`
+	Partition int32  `bson:"partition" json:"partition"`
+}
+
+// CircuitBreaker define when the deliveries of a failing subscription are stopped and probed
+type CircuitBreaker struct {
+	FailureThreshold int64 `bson:"failure_threshold" json:"failure_threshold"` // consecutive failures to trip, negative means disabled
+	ProbeInterval    int64 `bson:"probe_interval" json:"probe_interval"`       // second
+}
+
+// SubscriptionState define the delivery state of the subscription
+type SubscriptionState struct {
+	Status              string      `json:"status"` // active, paused or tripped
+	ConsecutiveFailures int64       `json:"consecutive_failures"`
+	TrippedTime         *types.Time `json:"tripped_time,omitempty"`
+	NextProbeTime       *types.Time `json:"next_probe_time,omitempty"`
 }
 
 // Report define sending statistic
@@ -53,6 +99,14 @@ func (s Subscription) GetCacheKey() string {
 		ConfirmPattern:   s.ConfirmPattern,
 		SubscriptionForm: s.SubscriptionForm,
 		TimeOut:          s.TimeOut,
+		RetryPolicy:      s.RetryPolicy,
+		Secret:           s.Secret,
+		Filter:           s.Filter,
+		PayloadMode:      s.PayloadMode,
+		Partitions:       s.Partitions,
+		SinkType:         s.SinkType,
+		SinkConfig:       s.SinkConfig,
+		CircuitBreaker:   s.CircuitBreaker,
 	}
 	b, _ := json.Marshal(ns)
 	return string(b)
@@ -62,6 +116,117 @@ func (s Subscription) GetTimeout() time.Duration {
 	return time.Second * time.Duration(s.TimeOut)
 }
 
+// GetSinkType returns the sink type of the subscription, empty means http callback
+func (s Subscription) GetSinkType() string {
+	if s.SinkType == "" {
+		return SinkTypeHTTP
+	}
+	return s.SinkType
+}
+
+// GetCircuitBreaker returns the circuit breaker of the subscription, the breaker is disabled unless
+// the subscription sets it, unset fields fallback to default
+func (s Subscription) GetCircuitBreaker() CircuitBreaker {
+	breaker := CircuitBreaker{
+		FailureThreshold: DefaultBreakerFailureThreshold,
+		ProbeInterval:    DefaultBreakerProbeInterval,
+	}
+	if s.CircuitBreaker == nil {
+		breaker.FailureThreshold = 0
+		return breaker
+	}
+	if s.CircuitBreaker.FailureThreshold != 0 {
+		breaker.FailureThreshold = s.CircuitBreaker.FailureThreshold
+	}
+	if s.CircuitBreaker.ProbeInterval > 0 {
+		breaker.ProbeInterval = s.CircuitBreaker.ProbeInterval
+	}
+	return breaker
+}
+
+// Enabled returns whether the circuit breaker trips
+func (b CircuitBreaker) Enabled() bool {
+	return b.FailureThreshold > 0
+}
+
+// GetPartitions returns how many partitions the events of the subscription are distributed in,
+// 1 means all the events are delivered one by one in order
+func (s Subscription) GetPartitions() int {
+	if s.Partitions <= 1 {
+		return 1
+	}
+	if s.Partitions > MaxPartitions {
+		return MaxPartitions
+	}
+	return int(s.Partitions)
+}
+
+// DistPartitionKey returns the list buffering the dists of the partition of the subscriber
+func DistPartitionKey(subID string, partition int) string {
+	return fmt.Sprintf("%s%s_%d", EventCacheDistPartitionPrefix, subID, partition)
+}
+
+// GetRetryPolicy returns the retry policy of the subscription, unset fields fallback to default
+func (s Subscription) GetRetryPolicy() RetryPolicy {
+	policy := RetryPolicy{
+		MaxAttempts: DefaultRetryMaxAttempts,
+		BackoffBase: DefaultRetryBackoffBase,
+		BackoffCap:  DefaultRetryBackoffCap,
+	}
+	if s.RetryPolicy == nil {
+		return policy
+	}
+	if s.RetryPolicy.MaxAttempts > 0 {
+		policy.MaxAttempts = s.RetryPolicy.MaxAttempts
+	}
+	if s.RetryPolicy.BackoffBase > 0 {
+		policy.BackoffBase = s.RetryPolicy.BackoffBase
+	}
+	if s.RetryPolicy.BackoffCap > 0 {
+		policy.BackoffCap = s.RetryPolicy.BackoffCap
+	}
+	if policy.BackoffCap < policy.BackoffBase {
+		policy.BackoffCap = policy.BackoffBase
+	}
+	return policy
+}
+
+// Backoff returns the wait duration before the given retry attempt, attempt starts from 1
+func (p RetryPolicy) Backoff(attempt int64) time.Duration {
+	wait := p.BackoffBase
+	for i := int64(1); i < attempt && wait < p.BackoffCap; i++ {
+		wait *= 2
+	}
+	if wait > p.BackoffCap {
+		wait = p.BackoffCap
+	}
+	return time.Millisecond * time.Duration(wait)
+}
+
+// Attempts returns how many attempts are made at most, the attempts whose backoffs sum up over
+// RetryBackoffMaxTotal are dropped so that the delivery of a dist is bounded
+func (p RetryPolicy) Attempts() int64 {
+	var total time.Duration
+	for attempt := int64(1); attempt < p.MaxAttempts; attempt++ {
+		if total += p.Backoff(attempt); total > time.Millisecond*RetryBackoffMaxTotal {
+			return attempt
+		}
+	}
+	return p.MaxAttempts
+}
+
+// DeliveryTimeout returns how long the delivery of a dist lasts at most, all the attempts
+// and the backoffs between them included
+func (s Subscription) DeliveryTimeout() time.Duration {
+	policy := s.GetRetryPolicy()
+	attempts := policy.Attempts()
+	total := time.Duration(attempts) * s.GetTimeout()
+	for attempt := int64(1); attempt < attempts; attempt++ {
+		total += policy.Backoff(attempt)
+	}
+	return total
+}
+
 type EventInst struct {
 	ID          int64       `json:"event_id,omitempty"`
 	EventType   string      `json:"event_type"`
@@ -72,6 +237,11 @@ type EventInst struct {
 	PreData     interface{} `json:"pre_data"`
 	RequestID   string      `json:"request_id"`
 	RequestTime types.Time  `json:"request_time"`
+	// OwnerID the supplier account of the request making the event
+	OwnerID string `json:"bk_supplier_account,omitempty"`
+
+	// ChangedFields the diff between PreData and CurData of the update events
+	ChangedFields map[string]FieldChange `json:"changed_fields,omitempty"`
 }
 
 func (e *EventInst) GetType() string {
@@ -86,7 +256,9 @@ func (e *EventInst) GetDistInst() *DistInst {
 	distinst := DistInst{
 		EventInst: ne,
 	}
-	distinst.ID = 0
+	if e.Action == EventActionUpdate && e.ChangedFields == nil {
+		distinst.ChangedFields = DiffData(e.PreData, e.CurData)
+	}
 	if e.EventType == EventTypeInstData && e.ObjType == common.BKINnerObjIDObject {
 		var m map[string]interface{}
 		var ok bool
@@ -122,3 +294,63 @@ type DistInstCtx struct {
 	DistInst
 	Raw string
 }
+
+// DeadLetter define the dist inst which used up its retries
+type DeadLetter struct {
+	DistInst
+	Attempts   int64      `json:"attempts"`
+	Error      string     `json:"error"`
+	FailedTime types.Time `json:"failed_time"`
+}
+
+// Delivery define the record of one callback attempt
+type Delivery struct {
+	SubscriptionID int64      `json:"subscription_id"`
+	DstbID         int64      `json:"distribution_id"`
+	EventID        int64      `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Action         string     `json:"action"`
+	ObjType        string     `json:"obj_type"`
+	Attempt        int64      `json:"attempt"`
+	Success        bool       `json:"success"`
+	StatusCode     int        `json:"status_code"` // 0 means no response received
+	Latency        int64      `json:"latency"`     // millisecond
+	Response       string     `json:"response"`    // truncated to DeliveryResponseMaxLength
+	Error          string     `json:"error"`
+	DeliveryTime   types.Time `json:"delivery_time"`
+}
+
+// WatchParams define the watch request
+type WatchParams struct {
+	Cursor     int64    `json:"cursor"` // 0 means from the oldest retained event, -1 means from now
+	EventTypes []string `json:"event_types"`
+	Limit      int64    `json:"limit"`
+	Timeout    int64    `json:"timeout"` // second
+}
+
+// WatchResult define the watch response
+type WatchResult struct {
+	Cursor int64       `json:"cursor"`
+	Events []EventInst `json:"events"`
+}
+
+// EventLog define the persisted event
+type EventLog struct {
+	ID         int64     `bson:"event_id" json:"event_id"`
+	EventType  string    `bson:"event_type" json:"event_type"`
+	Action     string    `bson:"action" json:"action"`
+	ObjType    string    `bson:"obj_type" json:"obj_type"`
+	ActionTime time.Time `bson:"action_time" json:"action_time"`
+	OwnerID    string    `bson:"bk_supplier_account" json:"bk_supplier_account"`
+	Data       []byte    `bson:"data" json:"data"`               // the raw event, kept in binary to skip the html escaping
+	CreateTime time.Time `bson:"create_time" json:"create_time"` // expired by ttl index
+}
+
+// ReplayParams define the range of the events to replay, the time range and the id range are combined
+type ReplayParams struct {
+	StartTime *types.Time `json:"start_time"`
+	EndTime   *types.Time `json:"end_time"`
+	StartID   int64       `json:"start_id"`
+	EndID     int64       `json:"end_id"`
+	Limit     int64       `json:"limit"`
+}
@@ -0,0 +1,129 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetRetryPolicy(t *testing.T) {
+	sub := Subscription{}
+	policy := sub.GetRetryPolicy()
+	if policy.MaxAttempts != DefaultRetryMaxAttempts || policy.BackoffBase != DefaultRetryBackoffBase || policy.BackoffCap != DefaultRetryBackoffCap {
+		t.Fatalf("expected default policy but got %+v", policy)
+	}
+
+	sub.RetryPolicy = &RetryPolicy{MaxAttempts: 5, BackoffBase: 2000, BackoffCap: 1000}
+	policy = sub.GetRetryPolicy()
+	if policy.MaxAttempts != 5 || policy.BackoffBase != 2000 || policy.BackoffCap != 2000 {
+		t.Fatalf("unexpected policy %+v", policy)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, BackoffBase: 100, BackoffCap: 1000}
+	expects := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for i, expect := range expects {
+		if got := policy.Backoff(int64(i + 1)); got != expect*time.Millisecond {
+			t.Fatalf("attempt %d expected %v but got %v", i+1, expect*time.Millisecond, got)
+		}
+	}
+}
+
+func TestRetryPolicyAttempts(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BackoffBase: 100, BackoffCap: 1000}
+	if got := policy.Attempts(); got != 3 {
+		t.Fatalf("expected 3 attempts but got %d", got)
+	}
+
+	// the backoffs of the first 10 attempts sum up to RetryBackoffMaxTotal
+	policy = RetryPolicy{MaxAttempts: 100, BackoffBase: RetryBackoffMaxTotal / 10, BackoffCap: RetryBackoffMaxTotal / 10}
+	if got := policy.Attempts(); got != 11 {
+		t.Fatalf("expected 11 attempts but got %d", got)
+	}
+}
+
+func TestDeliveryTimeout(t *testing.T) {
+	sub := Subscription{TimeOut: 10, RetryPolicy: &RetryPolicy{MaxAttempts: 3, BackoffBase: 1000, BackoffCap: 1500}}
+	if got := sub.DeliveryTimeout(); got != 30*time.Second+2500*time.Millisecond {
+		t.Fatalf("unexpected delivery timeout %v", got)
+	}
+
+	sub.RetryPolicy = &RetryPolicy{MaxAttempts: 1000, BackoffBase: DefaultRetryBackoffCap, BackoffCap: DefaultRetryBackoffCap}
+	if got, max := sub.DeliveryTimeout(), time.Millisecond*RetryBackoffMaxTotal+sub.GetTimeout()*11; got > max {
+		t.Fatalf("the delivery timeout %v should be bounded by %v", got, max)
+	}
+}
+
+func TestDiffData(t *testing.T) {
+	type typedMap map[string]interface{}
+	pre := map[string]interface{}{
+		"bk_host_id":      1,
+		"bk_host_innerip": "127.0.0.1",
+		"bk_cpu":          typedMap{"num": int64(4), "mhz": 2000},
+		"bk_removed":      "x",
+	}
+	cur := map[string]interface{}{
+		"bk_host_id":      float64(1),
+		"bk_host_innerip": "127.0.0.2",
+		"bk_cpu":          map[string]interface{}{"num": 8, "mhz": 2000},
+		"bk_added":        true,
+	}
+	changes := DiffData(pre, cur)
+	names := ChangedFieldNames(changes)
+	expect := []string{"bk_added", "bk_cpu.num", "bk_host_innerip", "bk_removed"}
+	if len(names) != len(expect) {
+		t.Fatalf("expect changed fields %v but got %v", expect, names)
+	}
+	for i := range expect {
+		if names[i] != expect[i] {
+			t.Fatalf("expect changed fields %v but got %v", expect, names)
+		}
+	}
+	if changes["bk_host_innerip"].Pre != "127.0.0.1" || changes["bk_host_innerip"].Cur != "127.0.0.2" {
+		t.Fatalf("unexpected change %+v", changes["bk_host_innerip"])
+	}
+	if changes["bk_removed"].Cur != nil || changes["bk_added"].Pre != nil {
+		t.Fatalf("unexpected change %+v", changes)
+	}
+}
+
+func TestGetPartitions(t *testing.T) {
+	for partitions, expect := range map[int64]int{0: 1, 1: 1, -1: 1, 4: 4, MaxPartitions + 1: MaxPartitions} {
+		sub := Subscription{Partitions: partitions}
+		if got := sub.GetPartitions(); got != expect {
+			t.Fatalf("partitions %d expected %d but got %d", partitions, expect, got)
+		}
+	}
+}
+
+func TestGetCircuitBreaker(t *testing.T) {
+	sub := Subscription{}
+	breaker := sub.GetCircuitBreaker()
+	if breaker.Enabled() {
+		t.Fatalf("the breaker should be disabled unless set but got %+v", breaker)
+	}
+
+	sub.CircuitBreaker = &CircuitBreaker{}
+	breaker = sub.GetCircuitBreaker()
+	if breaker.FailureThreshold != DefaultBreakerFailureThreshold || breaker.ProbeInterval != DefaultBreakerProbeInterval || !breaker.Enabled() {
+		t.Fatalf("expected default breaker but got %+v", breaker)
+	}
+
+	sub.CircuitBreaker = &CircuitBreaker{FailureThreshold: -1, ProbeInterval: 60}
+	breaker = sub.GetCircuitBreaker()
+	if breaker.Enabled() || breaker.ProbeInterval != 60 {
+		t.Fatalf("unexpected breaker %+v", breaker)
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"configcenter/src/common"
 	"database/sql/driver"
 	"errors"
+	"time"
 )
 
 // Event Cache Keys
@@ -35,18 +36,135 @@ const (
 	EventCacheDistRunningPrefix = common.BKCacheKeyV3Prefix + "event:dist_running_"
 	EventCacheDistTimeoutPrefix = common.BKCacheKeyV3Prefix + "event:dist_timeout_"
 	EventCacheDistDonePrefix    = common.BKCacheKeyV3Prefix + "event:dist_done_"
+	// EventCacheDistPartitionPrefix the lists buffering the dists of the partitions, followed by subid_partition
+	EventCacheDistPartitionPrefix = common.BKCacheKeyV3Prefix + "event:dist_partition_"
 
 	EventCacheDistCallBackCountPrefix = common.BKCacheKeyV3Prefix + "event:dist_callback_"
+	EventCacheDistDeadLetterPrefix    = common.BKCacheKeyV3Prefix + "event:dist_deadletter_"
+	EventCacheDistDeliveryPrefix      = common.BKCacheKeyV3Prefix + "event:dist_delivery_"
+	EventCacheDistStatePrefix         = common.BKCacheKeyV3Prefix + "event:dist_state_"
+	EventCacheDistReplayPrefix        = common.BKCacheKeyV3Prefix + "event:dist_replay_"
 
 	// EventCacheSubscribeformKey the key prefix in cache
 	EventCacheSubscribeformKey = common.BKCacheKeyV3Prefix + "event:subscribeform_"
 	EventCacheSubscribesKey    = common.BKCacheKeyV3Prefix + "event:subscribers"
 	EventCacheProcessChannel   = common.BKCacheKeyV3Prefix + "event_process_channel"
+
+	// EventCacheEventLogKey the retained event log sorted by event id, used by the watch api
+	EventCacheEventLogKey = common.BKCacheKeyV3Prefix + "event:inst_log"
+	// EventCacheEventLogTrimmedKey the largest event id dropped from the event log
+	EventCacheEventLogTrimmedKey = common.BKCacheKeyV3Prefix + "event:inst_log_trimmed"
+
+	// EventCacheSubscribeFilterPrefix the filter expression of the subscriber in cache
+	EventCacheSubscribeFilterPrefix = common.BKCacheKeyV3Prefix + "event:subscribefilter_"
+)
+
+// Callback request headers
+const (
+	CallbackHeaderDeliveryID = "X-Bk-Cmdb-Delivery-Id"
+	CallbackHeaderEventID    = "X-Bk-Cmdb-Event-Id"
+	CallbackHeaderTimestamp  = "X-Bk-Cmdb-Timestamp"
+	CallbackHeaderSignature  = "X-Bk-Cmdb-Signature"
+
+	// CallbackSignaturePrefix the algorithm prefix of the signature header value
+	CallbackSignaturePrefix = "sha256="
+)
+
+// PayloadMode define what the callback body contains
+const (
+	// PayloadModeFull send the whole cur_data and pre_data, it is the default mode
+	PayloadModeFull = "full"
+	// PayloadModeCompact send only the changed fields and the identifying keys of the instance
+	PayloadModeCompact = "compact"
+)
+
+// Event log and watch limits
+const (
+	// EventLogMaxLength the max event count retained in the event log
+	EventLogMaxLength = 100000
+	// EventLogGapGrace how long a missing event id is waited before it is skipped by the watchers
+	EventLogGapGrace = time.Second * 5
+
+	// EventLogRetention how long the events are kept in the persistent event log
+	EventLogRetention = time.Hour * 24 * 7
+	// ReplayMaxLimit the max events replayed in one request
+	ReplayMaxLimit = 100000
+	// ReplayTimeout how long a replay of the subscription lasts at most before another one is accepted
+	ReplayTimeout = time.Minute * 30
+
+	WatchDefaultLimit   = 200
+	WatchMaxLimit       = 1000
+	WatchDefaultTimeout = 20 // second
+	// WatchMaxTimeout keep it below the response header timeout of the api server forwarding client
+	WatchMaxTimeout = 25 // second
+)
+
+// SinkType define where the events of the subscription are delivered to
+const (
+	// SinkTypeHTTP post the events to the callback url, it is the default sink
+	SinkTypeHTTP = "http"
+	// SinkTypeRedisStream XADD the events to a redis stream
+	SinkTypeRedisStream = "redis_stream"
+	// SinkTypeKafka produce the events to a kafka topic
+	SinkTypeKafka = "kafka"
+)
+
+// SubscriptionStatus define whether the events are delivered to the subscription
+const (
+	// SubscriptionStatusActive the events are delivered as usual
+	SubscriptionStatusActive = "active"
+	// SubscriptionStatusPaused the events are buffered in the distribute queue until resumed
+	SubscriptionStatusPaused = "paused"
+	// SubscriptionStatusTripped the circuit breaker tripped, only the probe deliveries are made
+	SubscriptionStatusTripped = "tripped"
+)
+
+// CircuitBreaker default values of the subscription which sets the circuit breaker
+const (
+	DefaultBreakerFailureThreshold = 5
+	DefaultBreakerProbeInterval    = 30 // second
+	// MaxBreakerFailureThreshold the max failure threshold the subscription sets
+	MaxBreakerFailureThreshold = 1000
+	// MaxBreakerProbeInterval the max probe interval the subscription sets
+	MaxBreakerProbeInterval = 3600 // second
+
+	// StateCheckInterval how often the paused or tripped subscription checks its state
+	StateCheckInterval = time.Second
+)
+
+// Partitioned distribution limits
+const (
+	// MaxPartitions the max partition count of a subscription
+	MaxPartitions = 32
+	// PartitionBufferSize how many events are buffered for each partition
+	PartitionBufferSize = 16
+	// PartitionPopTimeout how long a partition worker waits the next buffered event
+	PartitionPopTimeout = time.Second
+)
+
+// RetryPolicy default values
+const (
+	DefaultRetryMaxAttempts = 3
+	DefaultRetryBackoffBase = 1000  // millisecond
+	DefaultRetryBackoffCap  = 30000 // millisecond
+	// MaxRetryAttempts the max attempts the subscription sets
+	MaxRetryAttempts = 100
+	// RetryBackoffMaxTotal the backoffs of a dist sum up to it at most, the attempts beyond are not made
+	RetryBackoffMaxTotal = 300000 // millisecond
+
+	// DeadLetterMaxLength the max dead letter count kept for each subscriber
+	DeadLetterMaxLength = 10000
+
+	// DeliveryHistoryLength the max delivery records kept for each subscriber
+	DeliveryHistoryLength = 1000
+	// DeliveryResponseMaxLength the response body longer than it will be truncated in the delivery record
+	DeliveryResponseMaxLength = 1024
 )
 
 // TableNames
 const (
 	TableNameSubscription = "cc_Subscription"
+	TableNameEventLog     = "cc_EventLog"
 )
 
 // EventAction
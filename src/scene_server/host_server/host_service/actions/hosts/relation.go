@@ -19,6 +19,7 @@ import (
 	"configcenter/src/common/core/cc/actions"
 	"configcenter/src/common/util"
 	"configcenter/src/scene_server/host_server/host_service/logics"
+	"configcenter/src/source_controller/api/transaction"
 	"net/http"
 	"strings"
 
@@ -315,6 +316,12 @@ func (m *hostModuleConfigAction) AssignHostToAppModule(req *restful.Request, res
 			data.ModuleName = common.DefaultResModuleName
 
 		}
+
+		// the hosts are assigned as a unit, none of them is left behind if one fails
+		txn, err := transaction.Begin(req, m.CC.HostCtrl()+"/host/v1/txn")
+		if nil != err {
+			return http.StatusBadGateway, nil, defErr.Errorf(common.CCErrHostModuleRelationAddFailed, err.Error())
+		}
 		var errmsg []string
 		for index, ip := range data.Ips {
 			if index < len(data.HostName) {
@@ -330,11 +337,14 @@ func (m *hostModuleConfigAction) AssignHostToAppModule(req *restful.Request, res
 				errmsg = append(errmsg, fmt.Sprintf("%s add host error: %s", ip, err.Error()))
 			}
 		}
-		if 0 == len(errmsg) {
-			return http.StatusOK, nil, nil
-		} else {
+		if 0 != len(errmsg) {
+			txn.Abort()
 			return http.StatusBadGateway, nil, defErr.Errorf(common.CCErrHostModuleRelationAddFailed, strings.Join(errmsg, ","))
 		}
+		if err := txn.Commit(); nil != err {
+			return http.StatusBadGateway, nil, defErr.Errorf(common.CCErrHostModuleRelationAddFailed, err.Error())
+		}
+		return http.StatusOK, nil, nil
 	}, resp)
 
 }
@@ -56,6 +56,9 @@ func HostSearch(req *restful.Request, data hostParse.HostCommonSearch, hostCtrl,
 	body["start"] = start
 	body["limit"] = limit
 	body["sort"] = sort
+	if nil != data.Page.Cursor {
+		body["cursor"] = *data.Page.Cursor
+	}
 	for _, object := range data.Condition {
 		if object.ObjectID == common.BKInnerObjIDHost {
 			hostCond = object
@@ -146,7 +149,15 @@ func HostSearch(req *restful.Request, data hostParse.HostCommonSearch, hostCtrl,
 	}
 	body["fields"] = strings.Join(hostCond.Fields, ",")
 	condition := make(map[string]interface{})
-	hostParse.ParseHostParams(hostCond.Condition, condition)
+	var hostRules map[string]map[string]interface{}
+	if 0 != len(hostCond.Condition) {
+		// the fields of the extended types are searched by their types
+		hostRules = GetObjectFieldRules(common.BKDefaultOwnerID, common.BKInnerObjIDHost, objCtrl)
+	}
+	if err := hostParse.ParseHostTypedParams(hostCond.Condition, hostRules, condition); nil != err {
+		blog.Error("failed to parse the host condition, error info is %s", err.Error())
+		return nil, err
+	}
 	hostParse.ParseHostIPParams(data.Ip, condition)
 	body["condition"] = condition
 	bodyContent, _ := json.Marshal(body)
@@ -315,6 +326,9 @@ func HostSearch(req *restful.Request, data hostParse.HostCommonSearch, hostCtrl,
 
 	result["info"] = totalInfo
 	result["count"] = cnt
+	if next, ok := hostResult["next_cursor"]; ok {
+		result["next_cursor"] = next
+	}
 
 	return result, err
 }
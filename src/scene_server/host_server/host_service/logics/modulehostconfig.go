@@ -27,7 +27,9 @@ import (
 	sencecommon "configcenter/src/scene_server/common"
 	"configcenter/src/scene_server/validator"
 	sourceAuditAPI "configcenter/src/source_controller/api/auditlog"
+	"configcenter/src/source_controller/api/metadata"
 	sourceAPI "configcenter/src/source_controller/api/object"
+	"configcenter/src/source_controller/api/transaction"
 
 	"time"
 
@@ -102,7 +104,7 @@ func AddHost(req *restful.Request, ownerID string, appID int, hostInfos map[int]
 
 	user := sencecommon.GetUserFromHeader(req)
 
-	addHostURL := hostAddr + "/host/v1/insts/"
+	addHostsURL := hostAddr + "/host/v1/insts/batch"
 	uHostURL := ObjAddr + "/object/v1/insts/host"
 
 	addParams := make(map[string]interface{})
@@ -119,6 +121,8 @@ func AddHost(req *restful.Request, ownerID string, appID int, hostInfos map[int]
 	input := make(map[string]interface{}, 2)     //更新主机数据
 	condInput := make(map[string]interface{}, 1) //更新主机条件
 	var errMsg, succMsg, updateErrMsg []string   //新加错误， 成功，  更新失败
+	var newIndexes []int                         //新加主机的行
+	var newHosts []map[string]interface{}        //新加主机
 	iSubArea := common.BKDefaultDirSubArea
 
 	defaultFields := getHostFields(ownerID, ObjAddr)
@@ -203,35 +207,26 @@ func AddHost(req *restful.Request, ownerID string, appID int, hostInfos map[int]
 				continue
 			}
 
-			//prepare the log
-			logObj := NewHostLog(req, common.BKDefaultOwnerID, "", hostAddr, ObjAddr, hostLogFields)
-
-			isSuccess, message, retData := GetHttpResult(req, addHostURL, common.HTTPCreate, host)
-			if !isSuccess {
-				ret := fmt.Sprintf("%s新加失败%s;", host["InnerIP"].(string), message)
-				errMsg = append(errMsg, fmt.Sprintf("%d行%v", index, ret))
-				continue
-			}
+			// the new hosts are created in batch after all the rows are checked
+			newIndexes = append(newIndexes, index)
+			newHosts = append(newHosts, host)
+			continue
+		}
 
-			retHost := retData.(map[string]interface{})
-			hostID, _ := util.GetIntByInterface(retHost[common.BKHostIDField])
-			addParams[common.BKHostIDField] = hostID
-			innerIP := host[common.BKHostInnerIPField].(string)
+		succMsg = append(succMsg, fmt.Sprintf("%d", index))
+	}
 
-			isSuccess, message, _ = GetHttpResult(req, addModulesURL, common.HTTPCreate, addParams)
-			if !isSuccess {
-				blog.Error("add hosthostconfig error, params:%v, error:%s", addParams, message)
-				errMsg = append(errMsg, fmt.Sprintf("%d行%v", index, innerIP))
+	if 0 < len(newHosts) {
+		newLogs, failures := addNewHosts(req, newHosts, addParams, addHostsURL, addModulesURL, hostAddr, ObjAddr, hostLogFields)
+		logConents = append(logConents, newLogs...)
+		for i, host := range newHosts {
+			if err, failed := failures[i]; failed {
+				ret := fmt.Sprintf("%s新加失败%v;", host[common.BKHostInnerIPField], err)
+				errMsg = append(errMsg, fmt.Sprintf("%d行%v", newIndexes[i], ret))
 				continue
 			}
-			strHostID := fmt.Sprintf("%d", hostID)
-			logContent, _ := logObj.GetHostLog(strHostID, false)
-
-			logConents = append(logConents, auditoplog.AuditLogExt{ID: hostID, Content: logContent, ExtKey: innerIP})
-
+			succMsg = append(succMsg, fmt.Sprintf("%d", newIndexes[i]))
 		}
-
-		succMsg = append(succMsg, fmt.Sprintf("%d", index))
 	}
 
 	if 0 < len(logConents) {
@@ -250,6 +245,102 @@ func AddHost(req *restful.Request, ownerID string, appID int, hostInfos map[int]
 	return nil, succMsg, updateErrMsg, errMsg
 }
 
+// importChunkSize the count of the new hosts created in one transaction
+const importChunkSize = 100
+
+// addNewHosts creates the hosts in batch and adds them to the module of addParams, every chunk of the hosts is
+// created in a transaction, the hosts of a failed chunk are created again one by one so that only the rows which
+// fail by themselves are reported, the audit logs of the created hosts and the errors of the failed rows by their
+// positions in newHosts are returned
+func addNewHosts(req *restful.Request, newHosts []map[string]interface{}, addParams map[string]interface{}, addHostsURL, addModulesURL, hostAddr, ObjAddr string, hostLogFields []metadata.Header) ([]auditoplog.AuditLogExt, map[int]error) {
+	// the hosts are not created again in the transaction of the caller, its writes are not undone by the abort
+	retry := "" == req.Request.Header.Get(common.BKHTTPTxnID)
+
+	logConents := make([]auditoplog.AuditLogExt, 0, len(newHosts))
+	failures := make(map[int]error)
+	for start := 0; start < len(newHosts); start += importChunkSize {
+		end := start + importChunkSize
+		if end > len(newHosts) {
+			end = len(newHosts)
+		}
+		logs, err := addHostsInTxn(req, newHosts[start:end], addParams, addHostsURL, addModulesURL, hostAddr, ObjAddr, hostLogFields)
+		if nil == err {
+			logConents = append(logConents, logs...)
+			continue
+		}
+		if !retry || 1 == end-start {
+			for i := start; i < end; i++ {
+				failures[i] = err
+			}
+			continue
+		}
+
+		blog.Warnf("failed to add the hosts %d-%d in batch, add them one by one, error: %v", start, end-1, err)
+		for i := start; i < end; i++ {
+			logs, err := addHostsInTxn(req, newHosts[i:i+1], addParams, addHostsURL, addModulesURL, hostAddr, ObjAddr, hostLogFields)
+			if nil != err {
+				failures[i] = err
+				continue
+			}
+			logConents = append(logConents, logs...)
+		}
+	}
+	return logConents, failures
+}
+
+// addHostsInTxn creates the hosts and adds them to the module of addParams in a transaction, either all of them
+// are created or none
+func addHostsInTxn(req *restful.Request, newHosts []map[string]interface{}, addParams map[string]interface{}, addHostsURL, addModulesURL, hostAddr, ObjAddr string, hostLogFields []metadata.Header) ([]auditoplog.AuditLogExt, error) {
+	txn, err := transaction.Begin(req, hostAddr+"/host/v1/txn")
+	if nil != err {
+		return nil, err
+	}
+	logConents, err := addHostsToModule(req, newHosts, addParams, addHostsURL, addModulesURL, hostAddr, ObjAddr, hostLogFields)
+	if nil != err {
+		txn.Abort()
+		return nil, err
+	}
+	if err := txn.Commit(); nil != err {
+		return nil, err
+	}
+	return logConents, nil
+}
+
+// addHostsToModule creates the hosts and adds them to the module of addParams, it stops at the first failure
+func addHostsToModule(req *restful.Request, newHosts []map[string]interface{}, addParams map[string]interface{}, addHostsURL, addModulesURL, hostAddr, ObjAddr string, hostLogFields []metadata.Header) ([]auditoplog.AuditLogExt, error) {
+	isSuccess, message, retData := GetHttpResult(req, addHostsURL, common.HTTPCreate, newHosts)
+	if !isSuccess {
+		blog.Errorf("add hosts error, count:%d, error:%v", len(newHosts), message)
+		return nil, errors.New(message)
+	}
+	retHosts, _ := retData.(map[string]interface{})
+	hostIDs, _ := retHosts[common.BKHostIDField].([]interface{})
+	if len(hostIDs) != len(newHosts) {
+		blog.Errorf("add hosts error, count:%d, return ids:%v", len(newHosts), hostIDs)
+		return nil, fmt.Errorf("%d hosts are added, expect %d", len(hostIDs), len(newHosts))
+	}
+
+	var logConents []auditoplog.AuditLogExt
+	for i, retID := range hostIDs {
+		hostID, _ := util.GetIntByInterface(retID)
+		addParams[common.BKHostIDField] = hostID
+		innerIP := newHosts[i][common.BKHostInnerIPField].(string)
+
+		isSuccess, message, _ = GetHttpResult(req, addModulesURL, common.HTTPCreate, addParams)
+		if !isSuccess {
+			blog.Error("add hosthostconfig error, params:%v, error:%s", addParams, message)
+			return nil, fmt.Errorf("%s: %s", innerIP, message)
+		}
+		//prepare the log
+		logObj := NewHostLog(req, common.BKDefaultOwnerID, "", hostAddr, ObjAddr, hostLogFields)
+		strHostID := fmt.Sprintf("%d", hostID)
+		logContent, _ := logObj.GetHostLog(strHostID, false)
+
+		logConents = append(logConents, auditoplog.AuditLogExt{ID: hostID, Content: logContent, ExtKey: innerIP})
+	}
+	return logConents, nil
+}
+
 //EnterIP 将机器导入到制定模块或者空闲机器， 已经存在机器，不操作
 func EnterIP(req *restful.Request, ownerID string, appID, moduleID int, IP, osType, hostname, appName, setName, moduleName, hostAddr, ObjAddr, auditAddr string, errHandle errorHandle.DefaultCCErrorIf) error {
 
@@ -309,12 +400,15 @@ func EnterIP(req *restful.Request, ownerID string, appID, moduleID int, IP, osTy
 	hostLogFields, _ := GetHostLogFields(req, ownerID, ObjAddr)
 	logObj := NewHostLog(req, common.BKDefaultOwnerID, "", hostAddr, ObjAddr, hostLogFields)
 	content, _ := logObj.GetHostLog(fmt.Sprintf("%d", hostID), false)
-	logAPIClient := sourceAuditAPI.NewClient(auditAddr)
-	logAPIClient.AuditHostLog(hostID, content, "enter IP HOST", IP, ownerID, fmt.Sprintf("%d", appID), user, auditoplog.AuditOpTypeAdd)
 	logClient, err := NewHostModuleConfigLog(req, nil, hostAddr, ObjAddr, auditAddr)
 	logClient.SetHostID([]int{hostID})
 	logClient.SetDescPrefix("enter IP ")
-	logClient.SaveLog(fmt.Sprintf("%d", appID), user)
+	// the audit logs are written out of the transaction of req, so they wait for its commit
+	transaction.AfterCommit(req, func() {
+		logAPIClient := sourceAuditAPI.NewClient(auditAddr)
+		logAPIClient.AuditHostLog(hostID, content, "enter IP HOST", IP, ownerID, fmt.Sprintf("%d", appID), user, auditoplog.AuditOpTypeAdd)
+		logClient.SaveLog(fmt.Sprintf("%d", appID), user)
+	})
 	return nil
 
 }
@@ -360,6 +454,21 @@ func GetObjectFields(ownerID, objID, ObjAddr string) map[string]map[string]inter
 	return fields
 }
 
+//GetObjectFieldRules get the property type and the option of the object fields by the property id
+func GetObjectFieldRules(ownerID, objID, ObjAddr string) map[string]map[string]interface{} {
+	data := make(map[string]interface{})
+	data[common.BKOwnerIDField] = ownerID
+	data[common.BKObjIDField] = objID
+	info, _ := json.Marshal(data)
+	client := sourceAPI.NewClient(ObjAddr)
+	result, _ := client.SearchMetaObjectAtt([]byte(info))
+	rules := make(map[string]map[string]interface{})
+	for _, j := range result {
+		rules[j.PropertyID] = map[string]interface{}{common.BKPropertyTypeField: j.PropertyType, common.BKOptionField: j.Option}
+	}
+	return rules
+}
+
 //convertHostInfo convert host info，InnerIP+SubArea key map[string]interface
 func convertHostInfo(hosts []interface{}) map[string]interface{} {
 	var hostMap map[string]interface{} = make(map[string]interface{})
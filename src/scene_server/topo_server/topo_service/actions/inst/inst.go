@@ -23,6 +23,7 @@ import (
 	"configcenter/src/common/paraparse"
 	"configcenter/src/common/util"
 	"configcenter/src/scene_server/topo_server/topo_service/actions/object"
+	"configcenter/src/scene_server/topo_server/topo_service/logics"
 	"configcenter/src/scene_server/topo_server/topo_service/manager"
 	"configcenter/src/scene_server/validator"
 	"configcenter/src/source_controller/api/auditlog"
@@ -74,7 +75,10 @@ func (cli *instAction) subCreateInst(req *restful.Request, defErr errors.Default
 	ignorItems := make([]string, 0)
 	for _, item := range attDes {
 		if _, ok := targetInput[item.PropertyID]; !ok {
-			nonExistsFiled = append(nonExistsFiled, item)
+			// the computed attributes are set by ComputeInstance
+			if common.FieldTypeComputed != item.PropertyType {
+				nonExistsFiled = append(nonExistsFiled, item)
+			}
 			ignorItems = append(ignorItems, item.PropertyID)
 		}
 	}
@@ -107,6 +111,8 @@ func (cli *instAction) subCreateInst(req *restful.Request, defErr errors.Default
 					asstInst.AsstInstID = iID
 					asstInst.AsstObjectID = asstDes[idxItem].AsstObjID
 					asstInst.ObjectID = objID
+					asstInst.ObjectAttID = item.ObjectAttID
+					asstInst.OwnerID = ownerID
 					asstFieldVal = append(asstFieldVal, asstInst)
 				}
 
@@ -116,10 +122,19 @@ func (cli *instAction) subCreateInst(req *restful.Request, defErr errors.Default
 		}
 	}
 
+	// the computed attributes are validated and stored like the others
+	if err := logics.ComputeInstance(req.Request.Context(), cli.CC.InstCli, ownerID, objID, 0, targetInput); nil != err {
+		blog.Error("failed to compute the attributes, error info is %s", err.Error())
+		return http.StatusBadRequest, nil, isUpdate, defErr.Errorf(common.CCErrTopoComputedValueFailed, err.Error())
+	}
+
 	// check
 	_, err := valid.ValidMap(targetInput, common.ValidCreate, 0)
 	targetMethod := common.HTTPSelectPost
 	input := make(map[string]interface{})
+	// the snapshot of the instance the batch updates
+	preData := map[string]interface{}{}
+	var instID int
 	switch e := err.(type) {
 	case nil:
 		// clear the association field
@@ -161,7 +176,20 @@ func (cli *instAction) subCreateInst(req *restful.Request, defErr errors.Default
 				return http.StatusBadRequest, nil, isUpdate, defErr.Errorf(common.CCErrCommParamsLostField, common.BKInstNameField)
 			}
 
-			if _, err = valid.ValidMap(targetInput, common.ValidUpdate, 0); nil != err {
+			// the instance matched by the name is updated, its computed attributes read its stored fields
+			var retStrErr int
+			preData, retStrErr = cli.getInstDeteilByCondition(req, objID, ownerID, condition)
+			if common.CCSuccess != retStrErr {
+				blog.Errorf("get inst detail error: %v", retStrErr)
+				return http.StatusInternalServerError, nil, isUpdate, defErr.Error(retStrErr)
+			}
+			instID, _ = strconv.Atoi(fmt.Sprint(preData[common.BKInstIDField]))
+			if err := logics.ComputeInstance(req.Request.Context(), cli.CC.InstCli, ownerID, objID, instID, targetInput); nil != err {
+				blog.Error("failed to compute the attributes, error info is %s", err.Error())
+				return http.StatusBadRequest, nil, isUpdate, defErr.Errorf(common.CCErrTopoComputedValueFailed, err.Error())
+			}
+
+			if _, err = valid.ValidMap(targetInput, common.ValidUpdate, instID); nil != err {
 				switch e := err.(type) {
 				case nil:
 					break
@@ -199,20 +227,6 @@ func (cli *instAction) subCreateInst(req *restful.Request, defErr errors.Default
 		return http.StatusBadRequest, nil, isUpdate, err
 	}
 
-	// take snapshot before operation if is update
-	preData := map[string]interface{}{}
-	var instID int
-	var retStrErr int
-	if targetMethod == common.HTTPUpdate {
-		preData, retStrErr = cli.getInstDeteilByCondition(req, objID, ownerID, input["condition"].(map[string]interface{}))
-		if common.CCSuccess != retStrErr {
-			blog.Errorf("get inst detail error: %v", retStrErr)
-			return http.StatusInternalServerError, nil, isUpdate, defErr.Error(retStrErr)
-		}
-
-		instID, _ = strconv.Atoi(fmt.Sprint(preData[common.BKInstIDField]))
-	}
-
 	// set default InstaName value if not set
 	if _, ok := targetInput[common.BKInstNameField]; !ok {
 		searchObjIDCond := make(map[string]interface{})
@@ -643,6 +657,12 @@ func (cli *instAction) UpdateInst(req *restful.Request, resp *restful.Response)
 			return http.StatusBadRequest, "", defErr.Error(common.CCErrCommJSONUnmarshalFailed)
 		}
 
+		// the computed attributes are computed over the stored fields and the updated ones
+		if err := logics.ComputeInstance(req.Request.Context(), cli.CC.InstCli, ownerID, objID, instID, data); nil != err {
+			blog.Error("failed to compute the attributes, error info is %s", err.Error())
+			return http.StatusBadRequest, "", defErr.Errorf(common.CCErrTopoComputedValueFailed, err.Error())
+		}
+
 		valid := validator.NewValidMap(ownerID, objID, cli.CC.ObjCtrl(), defErr)
 		_, err = valid.ValidMap(data, common.ValidUpdate, instID)
 		if nil != err {
@@ -1430,7 +1450,20 @@ func (cli *instAction) SelectInsts(req *restful.Request, resp *restful.Response)
 				return http.StatusBadRequest, "", defErr.Error(common.CCErrCommJSONUnmarshalFailed)
 			}
 
-			condition := params.ParseAppSearchParams(js.Condition)
+			// the fields of the extended types are searched by their types
+			attDes, retStrErr := cli.getObjAttDes(ownerID, objID)
+			if common.CCSuccess != retStrErr {
+				return http.StatusInternalServerError, "", defErr.Error(retStrErr)
+			}
+			rules := make(map[string]map[string]interface{}, len(attDes))
+			for _, att := range attDes {
+				rules[att.PropertyID] = map[string]interface{}{common.BKPropertyTypeField: att.PropertyType, common.BKOptionField: att.Option}
+			}
+			condition, err := params.ParseTypedSearchParams(js.Condition, rules)
+			if nil != err {
+				blog.Error("failed to parse the condition, error info is %s", err.Error())
+				return http.StatusBadRequest, "", defErr.Errorf(common.CCErrCommParamsInvalid, err.Error())
+			}
 
 			condition[common.BKOwnerIDField] = ownerID
 			condition[common.BKObjIDField] = objID
@@ -1442,6 +1475,9 @@ func (cli *instAction) SelectInsts(req *restful.Request, resp *restful.Response)
 			searchParams["start"] = page["start"]
 			searchParams["limit"] = page["limit"]
 			searchParams["sort"] = page["sort"]
+			if cursor, ok := page["cursor"]; ok {
+				searchParams["cursor"] = cursor
+			}
 
 		} else {
 			condition := make(map[string]interface{}, 0)
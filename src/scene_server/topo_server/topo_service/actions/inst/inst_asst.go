@@ -86,6 +86,8 @@ func (cli *instAction) updateInstAssociation(instID int, ownerID, objID string,
 					asstInst.AsstInstID = iID
 					asstInst.AsstObjectID = asstDes[idxItem].AsstObjID
 					asstInst.ObjectID = objID
+					asstInst.ObjectAttID = item.ObjectAttID
+					asstInst.OwnerID = ownerID
 					asstFieldVal = append(asstFieldVal, asstInst)
 				}
 
@@ -95,6 +97,8 @@ func (cli *instAction) updateInstAssociation(instID int, ownerID, objID string,
 				asstInst.AsstInstID, _ = util.GetInt64ByInterface(t)
 				asstInst.AsstObjectID = asstDes[idxItem].AsstObjID
 				asstInst.ObjectID = objID
+				asstInst.ObjectAttID = item.ObjectAttID
+				asstInst.OwnerID = ownerID
 				asstFieldVal = append(asstFieldVal, asstInst)
 			case json.Number:
 				asstInst := metadata.InstAsst{}
@@ -102,6 +106,8 @@ func (cli *instAction) updateInstAssociation(instID int, ownerID, objID string,
 				asstInst.AsstInstID, _ = t.Int64()
 				asstInst.AsstObjectID = asstDes[idxItem].AsstObjID
 				asstInst.ObjectID = objID
+				asstInst.ObjectAttID = item.ObjectAttID
+				asstInst.OwnerID = ownerID
 				asstFieldVal = append(asstFieldVal, asstInst)
 
 			default:
@@ -17,6 +17,7 @@ import (
 	"configcenter/src/common/bkbase"
 	"configcenter/src/common/blog"
 	"configcenter/src/common/core/cc/actions"
+	"configcenter/src/common/errors"
 	"configcenter/src/common/util"
 	"configcenter/src/scene_server/topo_server/topo_service/manager"
 	"strings"
@@ -590,6 +591,9 @@ func (cli *objectAction) CreateObject(req *restful.Request, resp *restful.Respon
 		}
 
 		blog.Error("create object failed, error information is %v", idErr.Error())
+		if e, ok := idErr.(errors.CCErrorCoder); ok && common.CCErrTopoValidationRuleInvalid == e.GetCode() {
+			return http.StatusBadRequest, nil, idErr
+		}
 		return http.StatusOK, nil, defErr.Error(common.CCErrTopoObjectCreateFailed)
 
 	}, resp)
@@ -769,6 +773,9 @@ func (cli *objectAction) UpdateObject(req *restful.Request, resp *restful.Respon
 		}
 
 		blog.Error("failed to update object, error info is %s", updateErr.Error())
+		if e, ok := updateErr.(errors.CCErrorCoder); ok && common.CCErrTopoValidationRuleInvalid == e.GetCode() {
+			return http.StatusBadRequest, nil, updateErr
+		}
 		return http.StatusInternalServerError, nil, defErr.Error(common.CCErrTopoObjectUpdateFailed)
 
 	}, resp)
@@ -784,6 +791,11 @@ func (cli *objectAction) DeleteObject(req *restful.Request, resp *restful.Respon
 	// get the error info by the language
 	defErr := cli.CC.Error.CreateDefaultCCErrorIf(language)
 
+	// the deletion goes ahead only when the caller confirms the current impact
+	if !objimpact.confirmDeletion(req, resp, false) {
+		return
+	}
+
 	// execute
 	cli.CallResponseEx(func() (int, interface{}, error) {
 
@@ -15,12 +15,21 @@ package object
 import (
 	"configcenter/src/common"
 	"configcenter/src/common/core/cc/actions"
+	"configcenter/src/common/auditoplog"
 	"configcenter/src/common/bkbase"
 	"configcenter/src/common/blog"
+	"configcenter/src/common/errors"
+	"configcenter/src/common/fieldtype"
+	"configcenter/src/common/http/httpclient"
 	"configcenter/src/common/util"
+	"configcenter/src/scene_server/topo_server/topo_service/logics"
 	"configcenter/src/scene_server/topo_server/topo_service/manager"
+	"configcenter/src/source_controller/api/auditlog"
+	"configcenter/src/source_controller/api/metadata"
 	api "configcenter/src/source_controller/api/object"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strconv"
@@ -44,6 +53,7 @@ func init() {
 	actions.RegisterNewAction(actions.Action{Verb: common.HTTPSelectPost, Path: "/objectattr/search", Params: nil, Handler: objatt.SelectObjectAttWithParams})
 	actions.RegisterNewAction(actions.Action{Verb: common.HTTPUpdate, Path: "/objectattr/{id}", Params: nil, Handler: objatt.UpdateObjectAtt})
 	actions.RegisterNewAction(actions.Action{Verb: common.HTTPDelete, Path: "/objectattr/{id}", Params: nil, Handler: objatt.DeleteObjectAtt})
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPSelectGet, Path: "/objectattr/computed/{obj_id}", Params: nil, Handler: objatt.SelectComputedBackfill})
 
 	// set object att
 	objatt.CreateAction()
@@ -83,9 +93,27 @@ func (cli *objattAction) CreateObjectAtt(req *restful.Request, resp *restful.Res
 
 		blog.Debug("create %s", string(val))
 
+		if err := fieldtype.CheckOption(obj.PropertyType, obj.Option); nil != err {
+			blog.Error("the option of the attribute %s.%s is invalid, error info is %s", obj.ObjectID, obj.PropertyID, err.Error())
+			return http.StatusBadRequest, nil, defErr.Errorf(common.CCErrTopoAttributeOptionInvalid, err.Error())
+		}
+
+		// the computed attribute is filled on write only
+		if common.FieldTypeComputed == obj.PropertyType {
+			if err := logics.CheckComputedAttribute(req.Request.Context(), cli.CC.InstCli, attrOwnerID(req, obj.OwnerID), obj.ObjectID, obj.PropertyID, obj.Option); nil != err {
+				blog.Error("the computed attribute %s.%s is invalid, error info is %s", obj.ObjectID, obj.PropertyID, err.Error())
+				return http.StatusBadRequest, nil, defErr.Errorf(common.CCErrTopoComputedAttributeInvalid, err.Error())
+			}
+			obj.IsRequired = false
+			obj.Editable = false
+		}
+
 		// deal data
 		result, ctrErr := cli.mgr.CreateObjectAtt(obj, defErr)
 		if nil == ctrErr {
+			if common.FieldTypeComputed == obj.PropertyType {
+				cli.backfillComputed(req, attrOwnerID(req, obj.OwnerID), obj.ObjectID)
+			}
 			return http.StatusOK, map[string]int{"id": result}, nil
 		}
 		blog.Error("create objectatt failed, error information is %s", ctrErr.Error())
@@ -147,9 +175,18 @@ func (cli *objattAction) UpdateObjectAtt(req *restful.Request, resp *restful.Res
 			return http.StatusBadRequest, nil, defErr.Errorf(common.CCErrCommParamsNeedInt, "id")
 		}
 
+		// check the attribute against the saved one
+		attr, err := cli.checkAttributeUpdate(req, defErr, attrID, val)
+		if nil != err {
+			return http.StatusBadRequest, nil, err
+		}
+
 		// deal data
 		ctrErr := cli.mgr.UpdateObjectAtt(attrID, val, defErr)
 		if nil == ctrErr {
+			if nil != attr {
+				cli.backfillComputed(req, attr.OwnerID, attr.ObjectID)
+			}
 			return http.StatusOK, nil, nil
 		}
 
@@ -169,6 +206,11 @@ func (cli *objattAction) DeleteObjectAtt(req *restful.Request, resp *restful.Res
 	// get the error info by the language
 	defErr := cli.CC.Error.CreateDefaultCCErrorIf(language)
 
+	// the deletion goes ahead only when the caller confirms the current impact
+	if !objimpact.confirmDeletion(req, resp, true) {
+		return
+	}
+
 	// execute
 	cli.CallResponseEx(func() (int, interface{}, error) {
 
@@ -196,3 +238,135 @@ func (cli *objattAction) DeleteObjectAtt(req *restful.Request, resp *restful.Res
 	}, resp)
 
 }
+
+// checkAttributeUpdate checks the option and the computed attribute the update saves, the fields missing in the
+// update are the ones of the saved attribute, the attribute is returned when the update saves a computed attribute
+func (cli *objattAction) checkAttributeUpdate(req *restful.Request, defErr errors.DefaultCCErrorIf, attrID int, val []byte) (*metadata.ObjectAttDes, error) {
+	update := make(map[string]interface{})
+	if err := json.Unmarshal(val, &update); nil != err {
+		blog.Error("unmarshal json failed, error information is %v", err)
+		return nil, defErr.Error(common.CCErrCommJSONUnmarshalFailed)
+	}
+	propertyType, hasType := update[common.BKPropertyTypeField].(string)
+	option, hasOption := update[common.BKOptionField].(string)
+	if !hasType && !hasOption {
+		return nil, nil
+	}
+
+	attr, err := logics.FindAttribute(req.Request.Context(), cli.CC.InstCli, attrID)
+	if nil != err {
+		blog.Error("failed to find the attribute %d, error info is %s", attrID, err.Error())
+		return nil, defErr.Error(common.CCErrTopoObjectAttributeUpdateFailed)
+	}
+	if !hasType {
+		propertyType = attr.PropertyType
+	}
+	if !hasOption {
+		option = attr.Option
+	}
+	if err := fieldtype.CheckOption(propertyType, option); nil != err {
+		blog.Error("the option of the attribute %d is invalid, error info is %s", attrID, err.Error())
+		return nil, defErr.Errorf(common.CCErrTopoAttributeOptionInvalid, err.Error())
+	}
+	if common.FieldTypeComputed != propertyType {
+		return nil, nil
+	}
+	if err := logics.CheckComputedAttribute(req.Request.Context(), cli.CC.InstCli, attr.OwnerID, attr.ObjectID, attr.PropertyID, option); nil != err {
+		blog.Error("the computed attribute %d is invalid, error info is %s", attrID, err.Error())
+		return nil, defErr.Errorf(common.CCErrTopoComputedAttributeInvalid, err.Error())
+	}
+	return attr, nil
+}
+
+// backfillComputed computes the computed attributes of the instances of objID in the background once a computed
+// attribute is saved, the values are written through the object controller so that the events and the audit
+// logs are made like the updates of the instances, the progress is returned by SelectComputedBackfill
+func (cli *objattAction) backfillComputed(req *restful.Request, ownerID, objID string) {
+	header := req.Request.Header.Clone()
+	user := util.GetActionUser(req)
+	go func() {
+		ctx := context.Background()
+		headers, err := logics.AttributeHeaders(ctx, cli.CC.InstCli, ownerID, objID)
+		if nil != err {
+			blog.Error("failed to search the attributes of %s, error info is %s", objID, err.Error())
+			return
+		}
+		update := func(ctx context.Context, instID int64, preData, data map[string]interface{}) error {
+			return cli.updateComputed(header, user, headers, ownerID, objID, instID, preData, data)
+		}
+		progress, err := logics.BackfillComputed(ctx, cli.CC.InstCli, ownerID, objID, update)
+		if nil != err {
+			blog.Error("failed to compute the attributes of the instances of %s, error info is %s", objID, err.Error())
+			return
+		}
+		blog.Info("computed the attributes of %d instances of %s, %d failed", progress.Done, objID, progress.Failed)
+	}()
+}
+
+// updateComputed writes the computed values of the instance by the object controller and saves the change log
+func (cli *objattAction) updateComputed(header http.Header, user string, headers []metadata.Header, ownerID, objID string, instID int64, preData, data map[string]interface{}) error {
+	input := map[string]interface{}{
+		"condition": map[string]interface{}{
+			common.BKOwnerIDField: ownerID,
+			common.BKObjIDField:   objID,
+			common.BKInstIDField:  instID,
+		},
+		"data": data,
+	}
+	inputJSON, err := json.Marshal(input)
+	if nil != err {
+		return err
+	}
+	httpcli := httpclient.NewHttpClient()
+	httpcli.SetHeader("Content-Type", "application/json")
+	httpcli.SetHeader("Accept", "application/json")
+	objRes, err := httpcli.Request(cli.CC.ObjCtrl()+"/object/v1/insts/object", common.HTTPUpdate, header, inputJSON)
+	if nil != err {
+		return err
+	}
+	if _, ok := cli.IsSuccess(objRes); !ok {
+		return fmt.Errorf("failed to update the instance, %s", string(objRes))
+	}
+
+	curData := make(map[string]interface{}, len(preData))
+	for key, val := range preData {
+		curData[key] = val
+	}
+	for key, val := range data {
+		curData[key] = val
+	}
+	auditContent := metadata.Content{
+		PreData: preData,
+		CurData: curData,
+		Headers: headers,
+	}
+	auditlog.NewClient(cli.CC.AuditCtrl()).AuditObjLog(instID, auditContent, "update inst", objID, ownerID, "0", user, auditoplog.AuditOpTypeModify)
+	return nil
+}
+
+// SelectComputedBackfill returns the progress of computing the instances of the object again
+func (cli *objattAction) SelectComputedBackfill(req *restful.Request, resp *restful.Response) {
+
+	blog.Info("select the progress of computing the instances")
+	// get the language
+	language := util.GetActionLanguage(req)
+	// get the error info by the language
+	defErr := cli.CC.Error.CreateDefaultCCErrorIf(language)
+
+	cli.CallResponseEx(func() (int, interface{}, error) {
+		progress, err := logics.FindBackfillProgress(req.Request.Context(), cli.CC.InstCli, util.GetActionOnwerID(req), req.PathParameter("obj_id"))
+		if nil != err {
+			blog.Error("failed to search the progress of %s, error info is %s", req.PathParameter("obj_id"), err.Error())
+			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrCommDBSelectFailed)
+		}
+		return http.StatusOK, progress, nil
+	}, resp)
+}
+
+// attrOwnerID returns the owner of the attribute, the one of the request when the attribute has none
+func attrOwnerID(req *restful.Request, ownerID string) string {
+	if "" == ownerID {
+		return util.GetActionOnwerID(req)
+	}
+	return ownerID
+}
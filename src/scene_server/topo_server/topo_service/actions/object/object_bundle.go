@@ -0,0 +1,147 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package object
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/common/bkbase"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/core/cc/actions"
+	"configcenter/src/common/util"
+	"configcenter/src/scene_server/topo_server/topo_service/manager"
+	"encoding/json"
+	"io/ioutil"
+
+	restful "github.com/emicklei/go-restful"
+)
+
+var objbundle = &objectBundleAction{}
+
+// objectBundleAction export and import the models as bundles
+type objectBundleAction struct {
+	base.BaseAction
+	mgr manager.Manager
+}
+
+func init() {
+
+	// register actions
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPSelectPost, Path: "/object/bundle/export", Params: nil, Handler: objbundle.ExportModelBundle})
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPCreate, Path: "/object/bundle/import", Params: nil, Handler: objbundle.ImportModelBundle})
+
+	// create action
+	objbundle.CreateAction()
+
+	// set httpclient
+	manager.SetManager(objbundle)
+}
+
+// SetManager implement the manager's Hooker interface
+func (cli *objectBundleAction) SetManager(mgr manager.Manager) error {
+	cli.mgr = mgr
+	return nil
+}
+
+// ExportModelBundle export the selected classifications and objects, the query parameter format=yaml
+// returns the bundle as a yaml document instead of the json response
+func (cli *objectBundleAction) ExportModelBundle(req *restful.Request, resp *restful.Response) {
+
+	blog.Info("export model bundle")
+
+	// get the language
+	language := util.GetActionLanguage(req)
+
+	// get the error info by the language
+	defErr := cli.CC.Error.CreateDefaultCCErrorIf(language)
+
+	ownerID := util.GetActionOnwerID(req)
+	encoding := req.QueryParameter("format")
+
+	val, err := ioutil.ReadAll(req.Request.Body)
+	if nil != err {
+		blog.Error("failed to read request body, error info is %s", err.Error())
+		cli.ResponseFailed(common.CCErrCommHTTPReadBodyFailed, defErr.Error(common.CCErrCommHTTPReadBodyFailed), resp)
+		return
+	}
+
+	selector := manager.BundleSelector{}
+	if jsErr := json.Unmarshal(val, &selector); nil != jsErr {
+		blog.Error("failed to unmarshal the data, data is %s, error info is %s", string(val), jsErr.Error())
+		cli.ResponseFailed(common.CCErrCommJSONUnmarshalFailed, defErr.Error(common.CCErrCommJSONUnmarshalFailed), resp)
+		return
+	}
+
+	bundle, err := cli.mgr.WithHeader(req.Request.Header).ExportModelBundle(ownerID, selector, defErr)
+	if nil != err {
+		blog.Error("failed to export the model bundle, error info is %s", err.Error())
+		cli.ResponseFailed(common.CCErrTopoModelBundleExportFailed, err.Error(), resp)
+		return
+	}
+
+	if manager.BundleEncodingYAML != encoding {
+		cli.ResponseSuccess(bundle, resp)
+		return
+	}
+
+	data, err := manager.EncodeModelBundle(bundle, encoding)
+	if nil != err {
+		blog.Error("failed to encode the model bundle, error info is %s", err.Error())
+		cli.ResponseFailed(common.CCErrTopoModelBundleExportFailed, err.Error(), resp)
+		return
+	}
+	resp.Header().Set("Content-Type", "application/x-yaml")
+	cli.ResponseNative(string(data), resp)
+}
+
+// ImportModelBundle import a bundle in json or yaml, the query parameter dry_run=true only returns the changes
+func (cli *objectBundleAction) ImportModelBundle(req *restful.Request, resp *restful.Response) {
+
+	blog.Info("import model bundle")
+
+	// get the language
+	language := util.GetActionLanguage(req)
+
+	// get the error info by the language
+	defErr := cli.CC.Error.CreateDefaultCCErrorIf(language)
+
+	ownerID := util.GetActionOnwerID(req)
+	dryRun := "true" == req.QueryParameter("dry_run")
+
+	val, err := ioutil.ReadAll(req.Request.Body)
+	if nil != err {
+		blog.Error("failed to read request body, error info is %s", err.Error())
+		cli.ResponseFailed(common.CCErrCommHTTPReadBodyFailed, defErr.Error(common.CCErrCommHTTPReadBodyFailed), resp)
+		return
+	}
+
+	bundle, err := manager.DecodeModelBundle(val)
+	if nil != err {
+		blog.Error("failed to decode the model bundle, error info is %s", err.Error())
+		cli.ResponseFailed(common.CCErrTopoModelBundleInvalid, defErr.Error(common.CCErrTopoModelBundleInvalid), resp)
+		return
+	}
+	if err := bundle.Validate(); nil != err {
+		blog.Error("the model bundle is invalid, error info is %s", err.Error())
+		cli.ResponseFailed(common.CCErrTopoModelBundleInvalid, err.Error(), resp)
+		return
+	}
+
+	result, err := cli.mgr.WithHeader(req.Request.Header).ImportModelBundle(ownerID, bundle, dryRun, defErr)
+	if nil != err {
+		// the changes applied before the failure are returned
+		blog.Error("failed to import the model bundle, error info is %s", err.Error())
+		cli.ResponseFailedWithData(common.CCErrTopoModelBundleImportFailed, err.Error(), result, resp)
+		return
+	}
+	cli.ResponseSuccess(result, resp)
+}
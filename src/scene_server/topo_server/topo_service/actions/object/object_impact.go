@@ -0,0 +1,161 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package object
+
+import (
+	"bytes"
+	"configcenter/src/common"
+	"configcenter/src/common/bkbase"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/core/cc/actions"
+	"configcenter/src/common/util"
+	"configcenter/src/scene_server/topo_server/topo_service/logics"
+	"encoding/json"
+	"io/ioutil"
+	"strconv"
+
+	restful "github.com/emicklei/go-restful"
+)
+
+var objimpact = &objectImpactAction{}
+
+// objectImpactAction analyze the impact of deleting an object or an attribute
+type objectImpactAction struct {
+	base.BaseAction
+}
+
+func init() {
+
+	// register actions
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPSelectPost, Path: "/object/impact", Params: nil, Handler: objimpact.AnalyzeImpact})
+
+	// create action
+	objimpact.CreateAction()
+}
+
+// AnalyzeImpact returns the impact of deleting the object, or the attribute when bk_property_id is set, the
+// token of the report confirms the deletion
+func (cli *objectImpactAction) AnalyzeImpact(req *restful.Request, resp *restful.Response) {
+
+	blog.Info("analyze the impact of the deletion")
+
+	// get the language
+	language := util.GetActionLanguage(req)
+
+	// get the error info by the language
+	defErr := cli.CC.Error.CreateDefaultCCErrorIf(language)
+
+	val, err := ioutil.ReadAll(req.Request.Body)
+	if nil != err {
+		blog.Error("failed to read request body, error info is %s", err.Error())
+		cli.ResponseFailed(common.CCErrCommHTTPReadBodyFailed, defErr.Error(common.CCErrCommHTTPReadBodyFailed), resp)
+		return
+	}
+
+	target := logics.ImpactTarget{}
+	if jsErr := json.Unmarshal(val, &target); nil != jsErr {
+		blog.Error("failed to unmarshal the data, data is %s, error info is %s", string(val), jsErr.Error())
+		cli.ResponseFailed(common.CCErrCommJSONUnmarshalFailed, defErr.Error(common.CCErrCommJSONUnmarshalFailed), resp)
+		return
+	}
+	if "" == target.ObjectID {
+		cli.ResponseFailed(common.CCErrCommParamsNeedSet, defErr.Errorf(common.CCErrCommParamsNeedSet, common.BKObjIDField), resp)
+		return
+	}
+
+	cond := map[string]interface{}{common.BKOwnerIDField: util.GetActionOnwerID(req), common.BKObjIDField: target.ObjectID}
+	if "" != target.PropertyID {
+		cond[common.BKPropertyIDField] = target.PropertyID
+	}
+	report, _, err := cli.analyze(req, "" != target.PropertyID, cond)
+	if nil != err {
+		blog.Error("failed to analyze the impact of deleting %v, error info is %s", cond, err.Error())
+		cli.ResponseFailed(common.CCErrTopoDeleteImpactFailed, err.Error(), resp)
+		return
+	}
+	cli.ResponseSuccess(report, resp)
+}
+
+// confirmDeletion checks the query parameter impact_token against the current impact of the deletion, the
+// deletion is selected by the path parameter id or by the condition in the body when the id is 0, the impact
+// is returned to the caller along with the failure when the token is missing or out of date, the condition
+// selecting several items is confirmed by one token of all their impacts
+func (cli *objectImpactAction) confirmDeletion(req *restful.Request, resp *restful.Response, attribute bool) bool {
+
+	// get the language
+	language := util.GetActionLanguage(req)
+
+	// get the error info by the language
+	defErr := cli.CC.Error.CreateDefaultCCErrorIf(language)
+
+	id, idErr := strconv.Atoi(req.PathParameter("id"))
+	if nil != idErr || 0 > id {
+		blog.Error("the path param(id:%s) is invalid", req.PathParameter("id"))
+		cli.ResponseFailed(common.CCErrCommParamsNeedInt, defErr.Errorf(common.CCErrCommParamsNeedInt, "id"), resp)
+		return false
+	}
+
+	// the body is read again by the deletion
+	val, err := ioutil.ReadAll(req.Request.Body)
+	if nil != err {
+		blog.Error("failed to read request body, error info is %s", err.Error())
+		cli.ResponseFailed(common.CCErrCommHTTPReadBodyFailed, defErr.Error(common.CCErrCommHTTPReadBodyFailed), resp)
+		return false
+	}
+	req.Request.Body = ioutil.NopCloser(bytes.NewReader(val))
+
+	cond := map[string]interface{}{"id": id}
+	if 0 == id {
+		cond = make(map[string]interface{})
+		if jsErr := json.Unmarshal(val, &cond); nil != jsErr {
+			blog.Error("failed to unmarshal the data, data is %s, error info is %s", string(val), jsErr.Error())
+			cli.ResponseFailed(common.CCErrCommJSONUnmarshalFailed, defErr.Error(common.CCErrCommJSONUnmarshalFailed), resp)
+			return false
+		}
+	}
+
+	report, expect, err := cli.analyze(req, attribute, cond)
+	if nil != err {
+		blog.Error("failed to analyze the impact of deleting %v, error info is %s", cond, err.Error())
+		cli.ResponseFailed(common.CCErrTopoDeleteImpactFailed, err.Error(), resp)
+		return false
+	}
+	if token := req.QueryParameter("impact_token"); token != expect {
+		blog.Warn("the deletion of %v is not confirmed, token is %s, expect %s", cond, token, expect)
+		cli.ResponseFailedWithData(common.CCErrTopoDeleteNotConfirmed, defErr.Error(common.CCErrTopoDeleteNotConfirmed), report, resp)
+		return false
+	}
+	return true
+}
+
+// analyze returns the impact of the deletion and the token confirming it, the impact is the report of the
+// item when the deletion selects one, otherwise it is the reports of all the items with one token
+func (cli *objectImpactAction) analyze(req *restful.Request, attribute bool, cond map[string]interface{}) (interface{}, string, error) {
+	targets, err := logics.FindImpactTargets(req.Request.Context(), cli.CC.InstCli, attribute, cond)
+	if nil != err {
+		return nil, "", err
+	}
+	reports := make([]*logics.ImpactReport, 0, len(targets))
+	for _, target := range targets {
+		report, err := logics.AnalyzeImpact(req.Request.Context(), cli.CC.InstCli, target)
+		if nil != err {
+			return nil, "", err
+		}
+		reports = append(reports, report)
+	}
+	token := logics.ImpactsToken(reports)
+	if 1 == len(reports) {
+		return reports[0], token, nil
+	}
+	return &logics.ImpactReports{Reports: reports, Token: token}, token, nil
+}
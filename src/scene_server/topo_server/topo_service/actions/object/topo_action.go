@@ -22,6 +22,7 @@ import (
 	"configcenter/src/common/util"
 	"configcenter/src/scene_server/topo_server/topo_service/manager"
 	api "configcenter/src/source_controller/api/object"
+	"configcenter/src/source_controller/api/transaction"
 	"encoding/json"
 	"fmt"
 	"github.com/bitly/go-simplejson"
@@ -177,17 +178,18 @@ func (cli *topoAction) updateOldInstParentID(ownerID string, parentInstID int, c
 		inputJSON, jsErr := json.Marshal(input)
 		if nil != jsErr {
 			blog.Error("failed to create json object, error info is %s", jsErr.Error())
-			continue
+			return jsErr
 		}
 
 		objRes, err := httpcli.ReqHttp(req, uURL, "PUT", []byte(inputJSON))
 		if nil != err {
 			blog.Error("failed to update the inst, error info is %s", err.Error())
-			continue
+			return err
 		}
 
 		if rsp, rspOk := cli.IsSuccess([]byte(objRes)); !rspOk {
 			blog.Error("failed to update the object, error info is %+v ", rsp.Message)
+			return fmt.Errorf("failed to update the inst, error info is %+v", rsp.Message)
 		}
 	}
 
@@ -222,17 +224,18 @@ func (cli *topoAction) deleteInsts(ownerID string, target []manager.TopoInstRst,
 		inputJSON, jsErr := json.Marshal(condition)
 		if nil != jsErr {
 			blog.Error("failed to create json object, error info is %s", jsErr.Error())
-			continue
+			return jsErr
 		}
 
 		objRes, err := httpcli.ReqHttp(req, uURL, "DELETE", []byte(inputJSON))
 		if nil != err {
 			blog.Error("failed to delete the inst, error info is %s", err.Error())
-			continue
+			return err
 		}
 
 		if rsp, rspOk := cli.IsSuccess([]byte(objRes)); !rspOk {
 			blog.Error("failed to delete the object, error info is %+v ", rsp.Message)
+			return fmt.Errorf("failed to delete the inst, error info is %+v", rsp.Message)
 		}
 	}
 
@@ -250,7 +253,9 @@ func (cli *topoAction) updateInsts(ownerID string, child []manager.TopoInstRst,
 
 				// find the same inst, update the inst's child
 				if subParentItem.InstID == childItem.InstID {
-					cli.updateOldInstParentID(ownerID, parentItem.InstID, childItem.Child, req)
+					if err := cli.updateOldInstParentID(ownerID, parentItem.InstID, childItem.Child, req); nil != err {
+						return err
+					}
 				}
 			}
 
@@ -269,14 +274,13 @@ func (cli *topoAction) createInsts(ownerID, objectID, objectName string, cacheIn
 		instID, instIDErr := cli.createDefaultInst(ownerID, objectID, objectName, inst.InstID, req)
 		if nil != instIDErr {
 			blog.Error("failed to create the default inst, error info is %s ", instIDErr.Error())
-			//return instIDErr
-			continue
+			return instIDErr
 		}
 
 		// update the old child inst parentid
 		if updateInstErr := cli.updateOldInstParentID(ownerID, instID, inst.Child, req); nil != updateInstErr {
 			blog.Error("failed to update the old inst parentid, error info is %s", updateInstErr.Error())
-			continue
+			return updateInstErr
 		}
 	}
 
@@ -284,7 +288,7 @@ func (cli *topoAction) createInsts(ownerID, objectID, objectName string, cacheIn
 }
 
 // updateMainModule update the mainline object topo
-func (cli *topoAction) updateMainModule(oldAsstItems []api.ObjAsstDes, objectID string, errProxy errors.DefaultCCErrorIf) error {
+func (cli *topoAction) updateMainModule(mgr manager.Manager, oldAsstItems []api.ObjAsstDes, objectID string, errProxy errors.DefaultCCErrorIf) error {
 
 	// to update the old main line object association
 	for _, objAsst := range oldAsstItems {
@@ -293,8 +297,9 @@ func (cli *topoAction) updateMainModule(oldAsstItems []api.ObjAsstDes, objectID
 		tmpData, _ := json.Marshal(objAsst)
 		js, _ := simplejson.NewJson(tmpData)
 		cond, _ := js.Map()
-		if err := cli.mgr.UpdateObjectAsst(cond, newObj, errProxy); nil != err {
+		if err := mgr.UpdateObjectAsst(cond, newObj, errProxy); nil != err {
 			blog.Error("failed to update the mainline association, error info is %s ", err.Error())
+			return err
 		}
 	}
 
@@ -325,12 +330,25 @@ func (cli *topoAction) CreateModel(req *restful.Request, resp *restful.Response)
 			return http.StatusBadRequest, nil, defErr.Error(common.CCErrCommJSONUnmarshalFailed)
 		}
 
+		// the mainline object, its association and default insts are created as a unit
+		txn, txnErr := transaction.Begin(req, cli.CC.ObjCtrl()+"/object/v1/txn")
+		if nil != txnErr {
+			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrTopoMainlineCreatFailed)
+		}
+		committed := false
+		defer func() {
+			if !committed {
+				txn.Abort()
+			}
+		}()
+		mgr := cli.mgr.WithHeader(req.Request.Header)
+
 		// to cache the old main association by the parent
 		asstSearch := map[string]interface{}{}
 		asstSearch[common.BKOwnerIDField] = obj.OwnerID
 		asstSearch["bk_asst_obj_id"] = obj.AssociationID
 		asstSearch["bk_object_att_id"] = common.BKChildStr
-		asstDesItems, asstDesItemsErr := cli.mgr.SelectObjectAsst(asstSearch, defErr)
+		asstDesItems, asstDesItemsErr := mgr.SelectObjectAsst(asstSearch, defErr)
 		if nil != asstDesItemsErr {
 			blog.Error("failed to cache the old asst, error info is %s", asstDesItemsErr.Error())
 			return http.StatusBadRequest, nil, defErr.Error(common.CCErrCommJSONUnmarshalFailed)
@@ -343,7 +361,7 @@ func (cli *topoAction) CreateModel(req *restful.Request, resp *restful.Response)
 		}
 
 		// create a new main line object
-		if _, err := cli.mgr.CreateObject(val, defErr); nil != err {
+		if _, err := mgr.CreateObject(val, defErr); nil != err {
 			blog.Error("failed to create the main line object, error info is %s", err.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrTopoMainlineCreatFailed)
 		}
@@ -354,18 +372,25 @@ func (cli *topoAction) CreateModel(req *restful.Request, resp *restful.Response)
 		objAtt.OwnerID = obj.OwnerID
 		objAtt.AssociationID = obj.AssociationID
 		objAtt.AssoType = common.BKChild
-		if _, ctrErr := cli.mgr.CreateTopoModel(objAtt, defErr); nil != ctrErr {
+		if _, ctrErr := mgr.CreateTopoModel(objAtt, defErr); nil != ctrErr {
 			blog.Error("create objectatt failed, error information is %s", ctrErr.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrTopoMainlineCreatFailed)
 		}
 		// to update the old asst, must be first
-		cli.updateMainModule(asstDesItems, obj.ObjectID, defErr)
+		if err := cli.updateMainModule(mgr, asstDesItems, obj.ObjectID, defErr); nil != err {
+			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrTopoMainlineCreatFailed)
+		}
 
 		// to create insts, must be second
 		if err := cli.createInsts(obj.OwnerID, obj.ObjectID, obj.ObjectName, asstInstItems, req); nil != err {
 			blog.Error("failed to create the default inst , error info is %s", err.Error())
+			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrTopoMainlineCreatFailed)
 		}
 
+		if err := txn.Commit(); nil != err {
+			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrTopoMainlineCreatFailed)
+		}
+		committed = true
 		return http.StatusOK, nil, nil
 	}, resp)
 }
@@ -391,12 +416,25 @@ func (cli *topoAction) DeleteModel(req *restful.Request, resp *restful.Response)
 			return http.StatusBadRequest, nil, defErr.Error(common.CCErrTopoForbiddenToDeleteModelFailed)
 		}
 
+		// the mainline object, its association and insts are removed as a unit
+		txn, txnErr := transaction.Begin(req, cli.CC.ObjCtrl()+"/object/v1/txn")
+		if nil != txnErr {
+			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrTopoMainlineDeleteFailed)
+		}
+		committed := false
+		defer func() {
+			if !committed {
+				txn.Abort()
+			}
+		}()
+		mgr := cli.mgr.WithHeader(req.Request.Header)
+
 		// to cache the old main association by the parent
 		asstSearch := map[string]interface{}{}
 		asstSearch[common.BKOwnerIDField] = ownerID
 		asstSearch["bk_asst_obj_id"] = objID
 		asstSearch["bk_object_att_id"] = common.BKChildStr
-		asstChildDesItems, asstDesItemsErr := cli.mgr.SelectObjectAsst(asstSearch, defErr)
+		asstChildDesItems, asstDesItemsErr := mgr.SelectObjectAsst(asstSearch, defErr)
 		if nil != asstDesItemsErr {
 			blog.Error("failed to cache the old asst, error info is %s", asstDesItemsErr.Error())
 			cli.ResponseFailed(common.CC_Err_Comm_http_DO, asstDesItemsErr.Error(), resp)
@@ -404,7 +442,7 @@ func (cli *topoAction) DeleteModel(req *restful.Request, resp *restful.Response)
 		}
 		delete(asstSearch, "bk_asst_obj_id")
 		asstSearch[common.BKObjIDField] = objID
-		asstParentDesItems, asstDesItemsErr := cli.mgr.SelectObjectAsst(asstSearch, defErr)
+		asstParentDesItems, asstDesItemsErr := mgr.SelectObjectAsst(asstSearch, defErr)
 		if nil != asstDesItemsErr {
 			blog.Error("failed to cache the old asst, error info is %s", asstDesItemsErr.Error())
 			cli.ResponseFailed(common.CC_Err_Comm_http_DO, asstDesItemsErr.Error(), resp)
@@ -431,31 +469,42 @@ func (cli *topoAction) DeleteModel(req *restful.Request, resp *restful.Response)
 		}
 
 		// deal data
-		if ctrErr := cli.mgr.DeleteTopoModel(ownerID, objID, common.BKChild, defErr); nil == ctrErr {
-
-			// to delete the old main line object
-			objDes := map[string]interface{}{}
-			objDes[common.BKOwnerIDField] = ownerID
-			objDes[common.BKObjIDField] = objID
-			objDesJSON, _ := json.Marshal(objDes)
-			if err := cli.mgr.DeleteObject(0, objDesJSON, defErr); nil != err {
-				blog.Error("failed to delete the object module(%s), data(%s) error info is %s", objID, string(objDesJSON), err.Error())
-			}
+		if ctrErr := mgr.DeleteTopoModel(ownerID, objID, common.BKChild, defErr); nil != ctrErr {
+			blog.Error("create objectatt failed, error information is %s", ctrErr.Error())
+			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrTopoMainlineDeleteFailed)
+		}
 
-			// update the main line module association
-			cli.updateMainModule(asstChildDesItems, asstParentDesItems[0].AsstObjID, defErr)
+		// to delete the old main line object
+		objDes := map[string]interface{}{}
+		objDes[common.BKOwnerIDField] = ownerID
+		objDes[common.BKObjIDField] = objID
+		objDesJSON, _ := json.Marshal(objDes)
+		if err := mgr.DeleteObject(0, objDesJSON, defErr); nil != err {
+			blog.Error("failed to delete the object module(%s), data(%s) error info is %s", objID, string(objDesJSON), err.Error())
+			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrTopoMainlineDeleteFailed)
+		}
 
-			// update the main inst association
-			cli.updateInsts(ownerID, asstChildInstItems, asstParentInstItems, req)
+		// update the main line module association
+		if err := cli.updateMainModule(mgr, asstChildDesItems, asstParentDesItems[0].AsstObjID, defErr); nil != err {
+			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrTopoMainlineDeleteFailed)
+		}
 
-			// deleete the old main inst
-			cli.deleteInsts(ownerID, asstChildInstItems, req)
+		// update the main inst association
+		if err := cli.updateInsts(ownerID, asstChildInstItems, asstParentInstItems, req); nil != err {
+			blog.Error("failed to update the child insts, error info is %s", err.Error())
+			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrTopoMainlineDeleteFailed)
+		}
 
-		} else {
-			blog.Error("create objectatt failed, error information is %s", ctrErr.Error())
-			cli.ResponseFailed(common.CC_Err_Comm_http_DO, ctrErr.Error(), resp)
+		// deleete the old main inst
+		if err := cli.deleteInsts(ownerID, asstChildInstItems, req); nil != err {
+			blog.Error("failed to delete the old insts, error info is %s", err.Error())
+			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrTopoMainlineDeleteFailed)
+		}
+
+		if err := txn.Commit(); nil != err {
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrTopoMainlineDeleteFailed)
 		}
+		committed = true
 		return http.StatusOK, nil, nil
 
 	}, resp)
@@ -0,0 +1,278 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package computed implements the expressions of the computed attributes.
+//
+// The option of a computed attribute holds either an expression or a string template, and the type of
+// the stored value, for example:
+//
+//	{"expression": "cpu * 2 + memory / 1024", "type": "int"}
+//	{"template": "${vendor}-${upper(model)}"}
+//
+// The expressions support the numbers, the strings, the fields of the instance, the fields of the
+// associated instances written as association.field, the operators + - * / % and the functions round,
+// upper, lower and default. A missing field makes the expression empty unless it is given a default,
+// and in a template it is replaced by the empty string.
+package computed
+
+import (
+	"configcenter/src/common"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Option the option of the computed attribute
+type Option struct {
+	Expression string `json:"expression,omitempty"`
+	Template   string `json:"template,omitempty"`
+	Type       string `json:"type,omitempty"`
+}
+
+// Expression a compiled computed attribute
+type Expression struct {
+	option Option
+	root   node
+	refs   []string
+}
+
+// Compile parse the option of the computed attribute
+func Compile(option string) (*Expression, error) {
+	opt := Option{}
+	if err := json.Unmarshal([]byte(option), &opt); nil != err {
+		return nil, fmt.Errorf("invalid option of the computed attribute, %s", err.Error())
+	}
+	if ("" == opt.Expression) == ("" == opt.Template) {
+		return nil, fmt.Errorf("the computed attribute needs either an expression or a template")
+	}
+	switch opt.Type {
+	case "":
+		opt.Type = common.FiledTypeSingleChar
+	case common.FiledTypeInt, common.FiledTypeSingleChar, common.FiledTypeLongChar:
+	default:
+		return nil, fmt.Errorf("unsupported type %s of the computed attribute", opt.Type)
+	}
+	if "" != opt.Template && common.FiledTypeInt == opt.Type {
+		return nil, fmt.Errorf("the value of a template is not an int")
+	}
+
+	refs := make(map[string]bool)
+	var root node
+	if "" != opt.Template {
+		var err error
+		if root, err = parseTemplate(opt.Template, refs); nil != err {
+			return nil, err
+		}
+	} else {
+		tokens, err := tokenize(opt.Expression)
+		if nil != err {
+			return nil, err
+		}
+		p := &parser{tokens: tokens, refs: refs}
+		if root, err = p.parse(); nil != err {
+			return nil, err
+		}
+	}
+
+	expr := &Expression{option: opt, root: root, refs: make([]string, 0, len(refs))}
+	for ref := range refs {
+		expr.refs = append(expr.refs, ref)
+	}
+	sort.Strings(expr.refs)
+	return expr, nil
+}
+
+// Type returns the type of the computed value
+func (e *Expression) Type() string {
+	return e.option.Type
+}
+
+// Refs returns the fields the expression reads, the fields of the associated instances are returned as
+// association.field
+func (e *Expression) Refs() []string {
+	return e.refs
+}
+
+// Eval computes the value from the values of the fields returned by Refs, the value is nil when it is empty
+func (e *Expression) Eval(values map[string]interface{}) (interface{}, error) {
+	val, err := e.root.eval(values)
+	if nil != err || nil == val {
+		return nil, err
+	}
+	switch e.option.Type {
+	case common.FiledTypeInt:
+		f, ok := toNumber(val)
+		if !ok {
+			return nil, fmt.Errorf("the value %v is not a number", val)
+		}
+		return int64(f), nil
+	case common.FiledTypeSingleChar:
+		if str := toString(val); common.FiledTypeSingleLenChar >= len(str) {
+			return str, nil
+		}
+		return nil, fmt.Errorf("the value is longer than %d", common.FiledTypeSingleLenChar)
+	}
+	if str := toString(val); common.FiledTypeLongLenChar >= len(str) {
+		return str, nil
+	}
+	return nil, fmt.Errorf("the value is longer than %d", common.FiledTypeLongLenChar)
+}
+
+type node interface {
+	eval(values map[string]interface{}) (interface{}, error)
+}
+
+type literal struct {
+	value interface{}
+}
+
+func (l literal) eval(values map[string]interface{}) (interface{}, error) {
+	return l.value, nil
+}
+
+type fieldRef struct {
+	name string
+}
+
+func (f fieldRef) eval(values map[string]interface{}) (interface{}, error) {
+	val := values[f.name]
+	if str, ok := val.(string); ok && "" == str {
+		return nil, nil
+	}
+	return val, nil
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+func (n *binaryNode) eval(values map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(values)
+	if nil != err {
+		return nil, err
+	}
+	right, err := n.right.eval(values)
+	if nil != err || nil == left || nil == right {
+		return nil, err
+	}
+
+	_, leftStr := left.(string)
+	_, rightStr := right.(string)
+	if "+" == n.op && (leftStr || rightStr) {
+		return toString(left) + toString(right), nil
+	}
+	l, lok := toNumber(left)
+	r, rok := toNumber(right)
+	if !lok || !rok {
+		return nil, fmt.Errorf("can not apply %s to %v and %v", n.op, left, right)
+	}
+	switch n.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if 0 == r {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return l / r, nil
+	}
+	if 0 == r {
+		return nil, fmt.Errorf("division by zero")
+	}
+	return math.Mod(l, r), nil
+}
+
+type callNode struct {
+	name string
+	args []node
+}
+
+func (n *callNode) eval(values map[string]interface{}) (interface{}, error) {
+	args := make([]interface{}, 0, len(n.args))
+	for _, arg := range n.args {
+		val, err := arg.eval(values)
+		if nil != err {
+			return nil, err
+		}
+		if "default" == n.name && nil != val {
+			return val, nil
+		}
+		args = append(args, val)
+	}
+	if nil == args[0] {
+		return nil, nil
+	}
+	switch n.name {
+	case "round":
+		f, ok := toNumber(args[0])
+		if !ok {
+			return nil, fmt.Errorf("can not round %v", args[0])
+		}
+		return math.Round(f), nil
+	case "upper":
+		return strings.ToUpper(toString(args[0])), nil
+	case "lower":
+		return strings.ToLower(toString(args[0])), nil
+	}
+	return nil, nil
+}
+
+type templateNode struct {
+	parts []node
+}
+
+func (n *templateNode) eval(values map[string]interface{}) (interface{}, error) {
+	buf := strings.Builder{}
+	for _, part := range n.parts {
+		val, err := part.eval(values)
+		if nil != err {
+			return nil, err
+		}
+		if nil != val {
+			buf.WriteString(toString(val))
+		}
+	}
+	return buf.String(), nil
+}
+
+func toNumber(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, nil == err
+	}
+	return 0, false
+}
+
+func toString(val interface{}) string {
+	if f, ok := toNumber(val); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(val)
+}
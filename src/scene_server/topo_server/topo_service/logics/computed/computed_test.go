@@ -0,0 +1,87 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package computed
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEval(t *testing.T) {
+	values := map[string]interface{}{
+		"cpu":                   json.Number("4"),
+		"memory":                float64(8192),
+		"vendor":                "cisco",
+		"model":                 "c3750",
+		"note":                  "",
+		"uplink.bk_inst_name":   "core-1",
+		"uplink.bk_asset_id":    "A1",
+		"downlink.bk_inst_name": nil,
+	}
+	cases := []struct {
+		option string
+		value  interface{}
+	}{
+		{`{"expression": "cpu * 2 + memory / 1024", "type": "int"}`, int64(16)},
+		{`{"expression": "(cpu + 1) % 3", "type": "int"}`, int64(2)},
+		{`{"expression": "-cpu + 10 / 4", "type": "int"}`, int64(-1)},
+		{`{"expression": "vendor + '-' + cpu"}`, "cisco-4"},
+		{`{"expression": "round(memory / 3000)"}`, "3"},
+		{`{"expression": "default(note, downlink.bk_inst_name, 'none')"}`, "none"},
+		{`{"expression": "vendor + note"}`, nil},
+		{`{"template": "${vendor}-${upper(model)} @ ${uplink.bk_inst_name}${note}"}`, "cisco-C3750 @ core-1"},
+		{`{"template": "plain", "type": "longchar"}`, "plain"},
+	}
+	for _, c := range cases {
+		expr, err := Compile(c.option)
+		require.NoError(t, err, c.option)
+		val, err := expr.Eval(values)
+		require.NoError(t, err, c.option)
+		assert.Equal(t, c.value, val, c.option)
+	}
+
+	expr, err := Compile(`{"template": "${vendor}/${uplink.bk_inst_name}", "type": "singlechar"}`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"uplink.bk_inst_name", "vendor"}, expr.Refs())
+
+	for _, option := range []string{`{"expression": "cpu / 0"}`, `{"expression": "vendor * 2"}`, `{"expression": "vendor", "type": "int"}`} {
+		expr, err := Compile(option)
+		require.NoError(t, err, option)
+		_, err = expr.Eval(values)
+		assert.Error(t, err, option)
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	for _, option := range []string{
+		`not json`,
+		`{}`,
+		`{"expression": "cpu", "template": "${cpu}"}`,
+		`{"expression": "cpu", "type": "date"}`,
+		`{"template": "${cpu}", "type": "int"}`,
+		`{"expression": "cpu +"}`,
+		`{"expression": "(cpu"}`,
+		`{"expression": "sum(cpu)"}`,
+		`{"expression": "round(cpu, 2)"}`,
+		`{"expression": "a.b.c"}`,
+		`{"expression": "'open"}`,
+		`{"template": "${cpu"}`,
+		`{"template": "${cpu +}"}`,
+	} {
+		_, err := Compile(option)
+		assert.Error(t, err, option)
+	}
+}
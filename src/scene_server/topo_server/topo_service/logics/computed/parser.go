@@ -0,0 +1,204 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package computed
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// the functions of the expressions and their number of arguments, -1 means one or more
+var functions = map[string]int{
+	"round":   1,
+	"upper":   1,
+	"lower":   1,
+	"default": -1,
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	refs   map[string]bool
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if tokenEOF != t.kind {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOperator(ops ...string) bool {
+	t := p.peek()
+	if tokenOperator != t.kind {
+		return false
+	}
+	for _, op := range ops {
+		if t.value == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) parse() (node, error) {
+	n, err := p.parseSum()
+	if nil != err {
+		return nil, err
+	}
+	if t := p.peek(); tokenEOF != t.kind {
+		return nil, fmt.Errorf("unexpected %s", t)
+	}
+	return n, nil
+}
+
+func (p *parser) parseSum() (node, error) {
+	left, err := p.parseProduct()
+	if nil != err {
+		return nil, err
+	}
+	for p.isOperator("+", "-") {
+		op := p.next().value
+		right, err := p.parseProduct()
+		if nil != err {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseProduct() (node, error) {
+	left, err := p.parseUnary()
+	if nil != err {
+		return nil, err
+	}
+	for p.isOperator("*", "/", "%") {
+		op := p.next().value
+		right, err := p.parseUnary()
+		if nil != err {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOperator("-") {
+		p.next()
+		n, err := p.parseUnary()
+		if nil != err {
+			return nil, err
+		}
+		return &binaryNode{op: "-", left: literal{value: float64(0)}, right: n}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		f, err := strconv.ParseFloat(t.value, 64)
+		if nil != err {
+			return nil, fmt.Errorf("invalid number %s", t)
+		}
+		return literal{value: f}, nil
+	case tokenString:
+		return literal{value: t.value}, nil
+	case tokenLParen:
+		n, err := p.parseSum()
+		if nil != err {
+			return nil, err
+		}
+		if t := p.next(); tokenRParen != t.kind {
+			return nil, fmt.Errorf("expect ) but got %s", t)
+		}
+		return n, nil
+	case tokenIdent:
+		if tokenLParen == p.peek().kind {
+			return p.parseCall(t)
+		}
+		if strings.HasPrefix(t.value, ".") || strings.HasSuffix(t.value, ".") || 1 < strings.Count(t.value, ".") {
+			// a field of the instance, or a field of the associated instances as association.field
+			return nil, fmt.Errorf("invalid field %s", t)
+		}
+		p.refs[t.value] = true
+		return fieldRef{name: t.value}, nil
+	}
+	return nil, fmt.Errorf("unexpected %s", t)
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	arity, ok := functions[name.value]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+	p.next()
+	args := []node{}
+	for tokenRParen != p.peek().kind {
+		if 0 != len(args) {
+			if t := p.next(); tokenComma != t.kind {
+				return nil, fmt.Errorf("expect , but got %s", t)
+			}
+		}
+		arg, err := p.parseSum()
+		if nil != err {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	p.next()
+	if (0 <= arity && len(args) != arity) || 0 == len(args) {
+		return nil, fmt.Errorf("wrong number of arguments for %s", name)
+	}
+	return &callNode{name: name.value, args: args}, nil
+}
+
+// parseTemplate parse the template into the text and the expressions in ${}
+func parseTemplate(tmpl string, refs map[string]bool) (node, error) {
+	parts := []node{}
+	for rest, offset := tmpl, 0; "" != rest; {
+		start := strings.Index(rest, "${")
+		if 0 > start {
+			parts = append(parts, literal{value: rest})
+			break
+		}
+		end := strings.Index(rest[start:], "}")
+		if 0 > end {
+			return nil, fmt.Errorf("unterminated ${ at %d", offset+start)
+		}
+		if 0 < start {
+			parts = append(parts, literal{value: rest[:start]})
+		}
+		tokens, err := tokenize(rest[start+2 : start+end])
+		if nil != err {
+			return nil, err
+		}
+		p := &parser{tokens: tokens, refs: refs}
+		n, err := p.parse()
+		if nil != err {
+			return nil, fmt.Errorf("invalid expression at %d: %s", offset+start, err.Error())
+		}
+		parts = append(parts, n)
+		rest, offset = rest[start+end+1:], offset+start+end+1
+	}
+	return &templateNode{parts: parts}, nil
+}
@@ -0,0 +1,425 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logics
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/common/util"
+	"configcenter/src/scene_server/topo_server/topo_service/logics/computed"
+	"configcenter/src/source_controller/api/metadata"
+	"configcenter/src/source_controller/common/commondata"
+	"configcenter/src/storage"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// backfillPageSize the count of the instances computed again in one page
+const backfillPageSize = 200
+
+// TableNameComputedBackfill the table keeps the progress of computing the instances of the objects again
+const TableNameComputedBackfill = "cc_ComputedBackfill"
+
+// the states of the backfill
+const (
+	BackfillRunning  = "running"
+	BackfillFinished = "finished"
+	BackfillFailed   = "failed"
+)
+
+// BackfillProgress the progress of computing the computed attributes of the instances of an object again, the
+// instances which fail are counted in Failed and the error of the last one is kept
+type BackfillProgress struct {
+	OwnerID    string    `bson:"bk_supplier_account" json:"bk_supplier_account"`
+	ObjectID   string    `bson:"bk_obj_id" json:"bk_obj_id"`
+	Status     string    `bson:"status" json:"status"`
+	Total      int       `bson:"total" json:"total"`
+	Done       int       `bson:"done" json:"done"`
+	Failed     int       `bson:"failed" json:"failed"`
+	Error      string    `bson:"error" json:"error"`
+	StartTime  time.Time `bson:"start_time" json:"start_time"`
+	UpdateTime time.Time `bson:"update_time" json:"update_time"`
+}
+
+// FindAttribute returns the attribute of the id
+func FindAttribute(ctx context.Context, db storage.DI, id int) (*metadata.ObjectAttDes, error) {
+	attrs := make([]metadata.ObjectAttDes, 0)
+	cond := map[string]interface{}{"id": id}
+	if err := storage.ContextOf(db).GetMutilByConditionCtx(ctx, common.BKTableNameObjAttDes, nil, cond, &attrs, "", 0, 1); nil != err {
+		return nil, err
+	}
+	if 0 == len(attrs) {
+		return nil, fmt.Errorf("the attribute %d does not exist", id)
+	}
+	return &attrs[0], nil
+}
+
+// CheckComputedAttribute compiles the option of the computed attribute objID.propertyID, checks the fields it reads
+// exist and no computed attribute reads itself through the others once the attribute is saved
+func CheckComputedAttribute(ctx context.Context, db storage.DI, ownerID, objID, propertyID, option string) error {
+	if table, ok := commondata.ObjTypeTableMap[objID]; ok && commondata.ObjTypeTableMap[common.BKINnerObjIDObject] != table {
+		return fmt.Errorf("the computed attributes are only supported by the custom objects")
+	}
+	expr, err := computed.Compile(option)
+	if nil != err {
+		return err
+	}
+
+	cdb := storage.ContextOf(db)
+	attrs := make([]metadata.ObjectAttDes, 0)
+	if err := cdb.GetMutilByConditionCtx(ctx, common.BKTableNameObjAttDes, nil, map[string]interface{}{common.BKOwnerIDField: ownerID}, &attrs, "", 0, 0); nil != err {
+		return err
+	}
+	assts := make([]metadata.ObjectAsst, 0)
+	if err := cdb.GetMutilByConditionCtx(ctx, metadata.ObjectAsst{}.TableName(), nil, map[string]interface{}{common.BKOwnerIDField: ownerID}, &assts, "", 0, 0); nil != err {
+		return err
+	}
+
+	// the fields of the objects and the objects the association attributes point to
+	fields := make(map[string]bool)
+	asstObjs := make(map[string]string)
+	for _, attr := range attrs {
+		fields[attr.ObjectID+"."+attr.PropertyID] = true
+	}
+	for _, asst := range assts {
+		asstObjs[asst.ObjectID+"."+asst.ObjectAttID] = asst.AsstObjID
+	}
+	field := func(objID, ref string) (string, error) {
+		parts := strings.SplitN(ref, ".", 2)
+		if 2 == len(parts) {
+			asstObjID, ok := asstObjs[objID+"."+parts[0]]
+			if !ok {
+				return "", fmt.Errorf("%s is not an association of %s", parts[0], objID)
+			}
+			objID, ref = asstObjID, parts[1]
+		}
+		if !fields[objID+"."+ref] && util.GetObjIDByType(objID) != ref {
+			return "", fmt.Errorf("%s has no field %s", objID, ref)
+		}
+		return objID + "." + ref, nil
+	}
+
+	// the computed attributes and the fields they read, the saved attribute replaces the stored one
+	deps := make(map[string][]string)
+	for _, ref := range expr.Refs() {
+		dep, err := field(objID, ref)
+		if nil != err {
+			return err
+		}
+		deps[objID+"."+propertyID] = append(deps[objID+"."+propertyID], dep)
+	}
+	for _, attr := range attrs {
+		key := attr.ObjectID + "." + attr.PropertyID
+		if common.FieldTypeComputed != attr.PropertyType || objID+"."+propertyID == key {
+			continue
+		}
+		other, err := computed.Compile(attr.Option)
+		if nil != err {
+			continue
+		}
+		for _, ref := range other.Refs() {
+			if dep, err := field(attr.ObjectID, ref); nil == err {
+				deps[key] = append(deps[key], dep)
+			}
+		}
+	}
+
+	// the attribute must not be reached from the fields it reads
+	start := objID + "." + propertyID
+	visited := make(map[string]bool)
+	var reach func(key string, path []string) error
+	reach = func(key string, path []string) error {
+		for _, dep := range deps[key] {
+			if start == dep {
+				return fmt.Errorf("the computed attribute %s reads itself through %s", start, strings.Join(append(path, dep), " -> "))
+			}
+			if visited[dep] {
+				continue
+			}
+			visited[dep] = true
+			if err := reach(dep, append(path, dep)); nil != err {
+				return err
+			}
+		}
+		return nil
+	}
+	return reach(start, []string{start})
+}
+
+// ComputeInstance sets the values of the computed attributes of objID into data, data holds the fields written to
+// the instance instID, or all the fields of a new instance when instID is 0, the values supplied by the caller
+// are replaced. The values read through the association attributes are taken when the instance is written, a
+// later change of the associated instances leaves them stale until the instance or the attribute is saved again
+func ComputeInstance(ctx context.Context, db storage.DI, ownerID, objID string, instID int, data map[string]interface{}) error {
+	cdb := storage.ContextOf(db)
+	exprs, err := computedExpressions(ctx, cdb, ownerID, objID)
+	if nil != err || 0 == len(exprs) {
+		return err
+	}
+
+	// the written fields over the stored ones
+	inst := make(map[string]interface{})
+	if 0 != instID {
+		insts := make([]map[string]interface{}, 0)
+		cond := map[string]interface{}{common.BKOwnerIDField: ownerID, common.BKObjIDField: objID, common.BKInstIDField: instID}
+		if err := cdb.GetMutilByConditionCtx(ctx, commondata.ObjTypeTableMap[common.BKINnerObjIDObject], nil, cond, &insts, "", 0, 1); nil != err {
+			return err
+		}
+		if 0 != len(insts) {
+			inst = insts[0]
+		}
+	}
+	for key, val := range data {
+		inst[key] = val
+	}
+	return computeValues(ctx, cdb, ownerID, objID, exprs, inst, data)
+}
+
+// InstUpdater writes the computed values of the instance, preData is the stored instance
+type InstUpdater func(ctx context.Context, instID int64, preData, data map[string]interface{}) error
+
+// BackfillComputed computes the computed attributes of all the instances of objID again and writes the changed
+// values by update, it runs once a computed attribute is saved so the instances written before hold its value,
+// the instances which fail are counted and skipped, the progress is kept in TableNameComputedBackfill
+func BackfillComputed(ctx context.Context, db storage.DI, ownerID, objID string, update InstUpdater) (*BackfillProgress, error) {
+	cdb := storage.ContextOf(db)
+	progress := &BackfillProgress{OwnerID: ownerID, ObjectID: objID, Status: BackfillRunning, StartTime: time.Now().UTC()}
+	exprs, err := computedExpressions(ctx, cdb, ownerID, objID)
+	if nil != err {
+		return progress, saveBackfillProgress(ctx, cdb, progress, err)
+	}
+
+	table := commondata.ObjTypeTableMap[common.BKINnerObjIDObject]
+	cond := map[string]interface{}{common.BKOwnerIDField: ownerID, common.BKObjIDField: objID}
+	if progress.Total, err = cdb.GetCntByConditionCtx(ctx, table, cond); nil != err {
+		return progress, saveBackfillProgress(ctx, cdb, progress, err)
+	}
+	if err := saveBackfillProgress(ctx, cdb, progress, nil); nil != err {
+		return progress, err
+	}
+	for start := 0; 0 != len(exprs); start += backfillPageSize {
+		insts := make([]map[string]interface{}, 0)
+		if err := cdb.GetMutilByConditionCtx(ctx, table, nil, cond, &insts, common.BKInstIDField, start, backfillPageSize); nil != err {
+			return progress, saveBackfillProgress(ctx, cdb, progress, err)
+		}
+		for _, inst := range insts {
+			if err := backfillInstance(ctx, cdb, ownerID, objID, exprs, inst, update); nil != err {
+				progress.Failed++
+				progress.Error = fmt.Sprintf("instance %v, %s", inst[common.BKInstIDField], err.Error())
+			}
+			progress.Done++
+		}
+		if len(insts) < backfillPageSize {
+			break
+		}
+		if err := saveBackfillProgress(ctx, cdb, progress, nil); nil != err {
+			return progress, err
+		}
+	}
+	progress.Status = BackfillFinished
+	return progress, saveBackfillProgress(ctx, cdb, progress, nil)
+}
+
+// backfillInstance computes the instance and writes the values which change
+func backfillInstance(ctx context.Context, db storage.ContextDI, ownerID, objID string, exprs map[string]*computed.Expression, inst map[string]interface{}, update InstUpdater) error {
+	instID, err := util.GetInt64ByInterface(inst[common.BKInstIDField])
+	if nil != err {
+		return err
+	}
+	preData := make(map[string]interface{}, len(inst))
+	for key, val := range inst {
+		preData[key] = val
+	}
+	data := make(map[string]interface{})
+	if err := computeValues(ctx, db, ownerID, objID, exprs, inst, data); nil != err {
+		return err
+	}
+	for key, val := range data {
+		if fmt.Sprint(preData[key]) == fmt.Sprint(val) {
+			delete(data, key)
+		}
+	}
+	if 0 == len(data) {
+		return nil
+	}
+	return update(ctx, instID, preData, data)
+}
+
+// saveBackfillProgress records the progress, the failure of the backfill is recorded along with it
+func saveBackfillProgress(ctx context.Context, db storage.ContextDI, progress *BackfillProgress, failure error) error {
+	if nil != failure {
+		progress.Status = BackfillFailed
+		progress.Error = failure.Error()
+	}
+	progress.UpdateTime = time.Now().UTC()
+	cond := map[string]interface{}{common.BKOwnerIDField: progress.OwnerID, common.BKObjIDField: progress.ObjectID}
+	cnt, err := db.GetCntByConditionCtx(ctx, TableNameComputedBackfill, cond)
+	if nil != err {
+		return err
+	}
+	if 0 == cnt {
+		_, err = db.InsertCtx(ctx, TableNameComputedBackfill, progress)
+	} else {
+		err = db.UpdateByConditionCtx(ctx, TableNameComputedBackfill, progress, cond)
+	}
+	if nil != err {
+		return err
+	}
+	return failure
+}
+
+// FindBackfillProgress returns the progress of the latest backfill of objID, nil is returned if there is none
+func FindBackfillProgress(ctx context.Context, db storage.DI, ownerID, objID string) (*BackfillProgress, error) {
+	progress := make([]BackfillProgress, 0)
+	cond := map[string]interface{}{common.BKOwnerIDField: ownerID, common.BKObjIDField: objID}
+	if err := storage.ContextOf(db).GetMutilByConditionCtx(ctx, TableNameComputedBackfill, nil, cond, &progress, "", 0, 1); nil != err {
+		return nil, err
+	}
+	if 0 == len(progress) {
+		return nil, nil
+	}
+	return &progress[0], nil
+}
+
+// AttributeHeaders returns the headers of the audit logs of the instances of objID
+func AttributeHeaders(ctx context.Context, db storage.DI, ownerID, objID string) ([]metadata.Header, error) {
+	attrs := make([]metadata.ObjectAttDes, 0)
+	cond := map[string]interface{}{common.BKOwnerIDField: ownerID, common.BKObjIDField: objID}
+	if err := storage.ContextOf(db).GetMutilByConditionCtx(ctx, common.BKTableNameObjAttDes, nil, cond, &attrs, "", 0, 0); nil != err {
+		return nil, err
+	}
+	headers := make([]metadata.Header, 0, len(attrs))
+	for _, attr := range attrs {
+		headers = append(headers, metadata.Header{PropertyID: attr.PropertyID, PropertyName: attr.PropertyName})
+	}
+	return headers, nil
+}
+
+// computedExpressions compiles the computed attributes of objID
+func computedExpressions(ctx context.Context, db storage.ContextDI, ownerID, objID string) (map[string]*computed.Expression, error) {
+	attrs := make([]metadata.ObjectAttDes, 0)
+	cond := map[string]interface{}{common.BKOwnerIDField: ownerID, common.BKObjIDField: objID, common.BKPropertyTypeField: common.FieldTypeComputed}
+	if err := db.GetMutilByConditionCtx(ctx, common.BKTableNameObjAttDes, nil, cond, &attrs, "", 0, 0); nil != err {
+		return nil, err
+	}
+	exprs := make(map[string]*computed.Expression, len(attrs))
+	for _, attr := range attrs {
+		expr, err := computed.Compile(attr.Option)
+		if nil != err {
+			return nil, fmt.Errorf("the computed attribute %s is invalid, %s", attr.PropertyID, err.Error())
+		}
+		exprs[attr.PropertyID] = expr
+	}
+	return exprs, nil
+}
+
+// computeValues computes the expressions over the fields of inst, the values are set into inst and data
+func computeValues(ctx context.Context, db storage.ContextDI, ownerID, objID string, exprs map[string]*computed.Expression, inst, data map[string]interface{}) error {
+	// the computed attributes reading the other ones are computed after them
+	done := make(map[string]bool)
+	asstValues := make(map[string]interface{})
+	for len(done) != len(exprs) {
+		progress := false
+		for propertyID, expr := range exprs {
+			if done[propertyID] || !computedReady(expr, exprs, done, propertyID) {
+				continue
+			}
+			values := make(map[string]interface{})
+			for _, ref := range expr.Refs() {
+				if !strings.Contains(ref, ".") {
+					values[ref] = inst[ref]
+					continue
+				}
+				if _, ok := asstValues[ref]; !ok {
+					val, err := associatedValue(ctx, db, ownerID, objID, inst, ref)
+					if nil != err {
+						return err
+					}
+					asstValues[ref] = val
+				}
+				values[ref] = asstValues[ref]
+			}
+			val, err := expr.Eval(values)
+			if nil != err {
+				return fmt.Errorf("failed to compute %s, %s", propertyID, err.Error())
+			}
+			inst[propertyID] = val
+			data[propertyID] = val
+			done[propertyID] = true
+			progress = true
+		}
+		if !progress {
+			return fmt.Errorf("the computed attributes of %s read each other", objID)
+		}
+	}
+	return nil
+}
+
+// computedReady reports whether the computed attributes the expression reads are computed
+func computedReady(expr *computed.Expression, exprs map[string]*computed.Expression, done map[string]bool, propertyID string) bool {
+	for _, ref := range expr.Refs() {
+		if _, ok := exprs[ref]; ok && ref != propertyID && !done[ref] {
+			return false
+		}
+	}
+	return true
+}
+
+// associatedValue returns the field of the instances associated by the association attribute of ref, the values
+// of several instances are joined by comma
+func associatedValue(ctx context.Context, db storage.ContextDI, ownerID, objID string, inst map[string]interface{}, ref string) (interface{}, error) {
+	parts := strings.SplitN(ref, ".", 2)
+	ids := make([]int64, 0)
+	for _, item := range strings.Split(fmt.Sprint(inst[parts[0]]), ",") {
+		if id, err := util.GetInt64ByInterface(strings.TrimSpace(item)); nil == err {
+			ids = append(ids, id)
+		}
+	}
+	if 0 == len(ids) {
+		return nil, nil
+	}
+
+	assts := make([]metadata.ObjectAsst, 0)
+	cond := map[string]interface{}{common.BKOwnerIDField: ownerID, common.BKObjIDField: objID, common.BKObjAttIDField: parts[0]}
+	if err := db.GetMutilByConditionCtx(ctx, metadata.ObjectAsst{}.TableName(), nil, cond, &assts, "", 0, 1); nil != err {
+		return nil, err
+	}
+	if 0 == len(assts) {
+		return nil, fmt.Errorf("%s is not an association of %s", parts[0], objID)
+	}
+	asstObjID := assts[0].AsstObjID
+	table, ok := commondata.ObjTypeTableMap[asstObjID]
+	instCond := map[string]interface{}{util.GetObjIDByType(asstObjID): map[string]interface{}{common.BKDBIN: ids}}
+	if !ok {
+		table = commondata.ObjTypeTableMap[common.BKINnerObjIDObject]
+		instCond[common.BKObjIDField] = asstObjID
+	}
+	insts := make([]map[string]interface{}, 0)
+	if err := db.GetMutilByConditionCtx(ctx, table, []string{parts[1]}, instCond, &insts, util.GetObjIDByType(asstObjID), 0, 0); nil != err {
+		return nil, err
+	}
+	if 1 == len(insts) {
+		return insts[0][parts[1]], nil
+	}
+	values := make([]string, 0, len(insts))
+	for _, item := range insts {
+		if val, ok := item[parts[1]]; ok && nil != val && "" != val {
+			values = append(values, fmt.Sprint(val))
+		}
+	}
+	if 0 == len(values) {
+		return nil, nil
+	}
+	return strings.Join(values, ","), nil
+}
@@ -0,0 +1,190 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logics
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/storage"
+	"configcenter/src/storage/memclient"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newComputedDB(t *testing.T) storage.DI {
+	db, err := memclient.NewMemCli(t.Name())
+	require.NoError(t, err)
+	require.NoError(t, db.Open())
+
+	attr := func(id int, objID, propertyID, propertyType, option string) map[string]interface{} {
+		return map[string]interface{}{"id": id, common.BKOwnerIDField: "0", common.BKObjIDField: objID, common.BKPropertyIDField: propertyID,
+			common.BKPropertyTypeField: propertyType, common.BKOptionField: option}
+	}
+	require.NoError(t, db.InsertMuti(common.BKTableNameObjAttDes,
+		attr(1, "switch", "bk_inst_name", common.FiledTypeSingleChar, ""),
+		attr(2, "switch", "vendor", common.FiledTypeSingleChar, ""),
+		attr(3, "switch", "ports", common.FiledTypeInt, ""),
+		attr(4, "switch", "uplink", common.FiledTypeSingleAsst, ""),
+		attr(5, "switch", "label", common.FieldTypeComputed, `{"template": "${vendor}-${bk_inst_name} @ ${uplink.bk_inst_name}"}`),
+		attr(6, "switch", "capacity", common.FieldTypeComputed, `{"expression": "ports * speed", "type": "int"}`),
+		attr(7, "switch", "speed", common.FieldTypeComputed, `{"expression": "default(uplink.speed, 1)", "type": "int"}`),
+		attr(11, "router", "bk_inst_name", common.FiledTypeSingleChar, ""),
+		attr(12, "router", "speed", common.FiledTypeInt, ""),
+		attr(13, "router", "downlink", common.FiledTypeSingleAsst, ""),
+	))
+	require.NoError(t, db.InsertMuti("cc_ObjAsst",
+		map[string]interface{}{common.BKOwnerIDField: "0", common.BKObjIDField: "switch", common.BKObjAttIDField: "uplink", common.BKAsstObjIDField: "router"},
+		map[string]interface{}{common.BKOwnerIDField: "0", common.BKObjIDField: "router", common.BKObjAttIDField: "downlink", common.BKAsstObjIDField: "switch"},
+	))
+	require.NoError(t, db.InsertMuti("cc_ObjectBase",
+		map[string]interface{}{common.BKOwnerIDField: "0", common.BKObjIDField: "router", common.BKInstIDField: 1, common.BKInstNameField: "core-1", "speed": 10},
+		map[string]interface{}{common.BKOwnerIDField: "0", common.BKObjIDField: "router", common.BKInstIDField: 2, common.BKInstNameField: "core-2", "speed": 40},
+		map[string]interface{}{common.BKOwnerIDField: "0", common.BKObjIDField: "switch", common.BKInstIDField: 3, common.BKInstNameField: "sw-1",
+			"vendor": "cisco", "ports": 24, "uplink": "1"},
+	))
+	return db
+}
+
+func TestComputeInstance(t *testing.T) {
+	db := newComputedDB(t)
+	defer memclient.Drop(t.Name())
+	ctx := context.Background()
+
+	// a new instance
+	data := map[string]interface{}{common.BKInstNameField: "sw-2", "vendor": "h3c", "ports": float64(48), "uplink": "2", "label": "ignored"}
+	require.NoError(t, ComputeInstance(ctx, db, "0", "switch", 0, data))
+	assert.Equal(t, "h3c-sw-2 @ core-2", data["label"])
+	assert.Equal(t, int64(40), data["speed"])
+	assert.Equal(t, int64(1920), data["capacity"])
+
+	// the update is computed over the stored fields
+	data = map[string]interface{}{"ports": float64(48)}
+	require.NoError(t, ComputeInstance(ctx, db, "0", "switch", 3, data))
+	assert.Equal(t, map[string]interface{}{"ports": float64(48), "label": "cisco-sw-1 @ core-1", "speed": int64(10), "capacity": int64(480)}, data)
+
+	// the missing association leaves the template part empty and the default applies
+	data = map[string]interface{}{"uplink": "", "vendor": "h3c"}
+	require.NoError(t, ComputeInstance(ctx, db, "0", "switch", 3, data))
+	assert.Equal(t, "h3c-sw-1 @ ", data["label"])
+	assert.Equal(t, int64(24), data["capacity"])
+
+	// the objects without computed attributes are left as they are
+	data = map[string]interface{}{"speed": 100}
+	require.NoError(t, ComputeInstance(ctx, db, "0", "router", 1, data))
+	assert.Equal(t, map[string]interface{}{"speed": 100}, data)
+}
+
+func TestCheckComputedAttribute(t *testing.T) {
+	db := newComputedDB(t)
+	defer memclient.Drop(t.Name())
+	ctx := context.Background()
+
+	require.NoError(t, CheckComputedAttribute(ctx, db, "0", "switch", "summary", `{"template": "${label} (${capacity})"}`))
+	require.NoError(t, CheckComputedAttribute(ctx, db, "0", "router", "total", `{"expression": "speed + downlink.capacity", "type": "int"}`))
+
+	for _, c := range []struct {
+		objID, propertyID, option string
+	}{
+		{"switch", "summary", `{"template": "${unknown}"}`},
+		{"switch", "summary", `{"expression": "vendor.speed"}`},
+		{"switch", "summary", `{"expression": "uplink.unknown"}`},
+		{"switch", "summary", `{"expression": "ports +"}`},
+		{"host", "summary", `{"expression": "bk_host_innerip"}`},
+		// the attribute reads itself directly or through the others
+		{"switch", "label", `{"expression": "label + vendor"}`},
+		{"switch", "speed", `{"expression": "capacity / ports", "type": "int"}`},
+	} {
+		assert.Error(t, CheckComputedAttribute(ctx, db, "0", c.objID, c.propertyID, c.option), c.option)
+	}
+
+	// the cycle through the associated objects
+	require.NoError(t, db.InsertMuti(common.BKTableNameObjAttDes, map[string]interface{}{"id": 14, common.BKOwnerIDField: "0", common.BKObjIDField: "router",
+		common.BKPropertyIDField: "total", common.BKPropertyTypeField: common.FieldTypeComputed, common.BKOptionField: `{"expression": "downlink.capacity", "type": "int"}`}))
+	err := CheckComputedAttribute(ctx, db, "0", "switch", "speed", `{"expression": "uplink.total", "type": "int"}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "switch.speed -> router.total -> switch.capacity -> switch.speed")
+
+	attr, err := FindAttribute(ctx, db, 5)
+	require.NoError(t, err)
+	assert.Equal(t, "label", attr.PropertyID)
+	_, err = FindAttribute(ctx, db, 99)
+	assert.Error(t, err)
+}
+
+// storeUpdater writes the computed values into the table like the object controller, and records the updates
+func storeUpdater(db storage.DI, updated map[int64]map[string]interface{}) InstUpdater {
+	return func(ctx context.Context, instID int64, preData, data map[string]interface{}) error {
+		if _, ok := preData["label"]; !ok && "fail" == preData["vendor"] {
+			return errors.New("refused")
+		}
+		updated[instID] = data
+		return db.UpdateByCondition("cc_ObjectBase", data, map[string]interface{}{common.BKInstIDField: instID})
+	}
+}
+
+func TestBackfillComputed(t *testing.T) {
+	db := newComputedDB(t)
+	defer memclient.Drop(t.Name())
+	ctx := context.Background()
+
+	updated := make(map[int64]map[string]interface{})
+	progress, err := BackfillComputed(ctx, db, "0", "switch", storeUpdater(db, updated))
+	require.NoError(t, err)
+	assert.Equal(t, BackfillFinished, progress.Status)
+	assert.Equal(t, 1, progress.Total)
+	assert.Equal(t, 1, progress.Done)
+	assert.Equal(t, 0, progress.Failed)
+	inst := make(map[string]interface{})
+	require.NoError(t, db.GetOneByCondition("cc_ObjectBase", nil, map[string]interface{}{common.BKInstIDField: 3}, &inst))
+	assert.Equal(t, "cisco-sw-1 @ core-1", inst["label"])
+	assert.EqualValues(t, 240, inst["capacity"])
+
+	// the instances holding the values already are not written again
+	updated = make(map[int64]map[string]interface{})
+	_, err = BackfillComputed(ctx, db, "0", "switch", storeUpdater(db, updated))
+	require.NoError(t, err)
+	assert.Empty(t, updated)
+
+	// the instance which fails is counted and the others go on
+	require.NoError(t, db.InsertMuti("cc_ObjectBase",
+		map[string]interface{}{common.BKOwnerIDField: "0", common.BKObjIDField: "switch", common.BKInstIDField: 4, common.BKInstNameField: "sw-2", "vendor": "fail"},
+		map[string]interface{}{common.BKOwnerIDField: "0", common.BKObjIDField: "switch", common.BKInstIDField: 5, common.BKInstNameField: "sw-3", "vendor": "h3c"},
+	))
+	progress, err = BackfillComputed(ctx, db, "0", "switch", storeUpdater(db, updated))
+	require.NoError(t, err)
+	assert.Equal(t, 3, progress.Done)
+	assert.Equal(t, 1, progress.Failed)
+	assert.Contains(t, progress.Error, "instance 4")
+	assert.Contains(t, updated, int64(5))
+
+	saved, err := FindBackfillProgress(ctx, db, "0", "switch")
+	require.NoError(t, err)
+	assert.Equal(t, BackfillFinished, saved.Status)
+	assert.Equal(t, 3, saved.Done)
+	assert.Equal(t, 1, saved.Failed)
+
+	// the instances of the other objects are left as they are
+	progress, err = BackfillComputed(ctx, db, "0", "router", storeUpdater(db, updated))
+	require.NoError(t, err)
+	assert.Equal(t, BackfillFinished, progress.Status)
+	inst = make(map[string]interface{})
+	require.NoError(t, db.GetOneByCondition("cc_ObjectBase", nil, map[string]interface{}{common.BKInstIDField: 1}, &inst))
+	assert.NotContains(t, inst, "label")
+
+	none, err := FindBackfillProgress(ctx, db, "0", "host")
+	require.NoError(t, err)
+	assert.Nil(t, none)
+}
@@ -0,0 +1,442 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logics
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/source_controller/api/metadata"
+	"configcenter/src/source_controller/common/commondata"
+	"configcenter/src/storage"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// the kinds of the items which depend on the deleted object or attribute
+const (
+	ImpactKindSubscription   = "subscription"
+	ImpactKindUserAPI        = "user_api"
+	ImpactKindFavorite       = "favorite"
+	ImpactKindUserCustom     = "user_custom"
+	ImpactKindRolePrivilege  = "role_privilege"
+	ImpactKindGroupPrivilege = "group_privilege"
+	ImpactKindAttribute      = "attribute"
+)
+
+// ImpactTarget the object or the attribute to delete, the target of an object deletion has no property id
+type ImpactTarget struct {
+	OwnerID    string `bson:"bk_supplier_account" json:"bk_supplier_account"`
+	ObjectID   string `bson:"bk_obj_id" json:"bk_obj_id"`
+	PropertyID string `bson:"bk_property_id" json:"bk_property_id,omitempty"`
+}
+
+// ImpactDependent an item which refers to the deleted object or attribute
+type ImpactDependent struct {
+	Kind   string `json:"kind"`
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// ImpactReport the impact of deleting an object or an attribute, the token confirms the deletion as long as
+// the impact stays the same
+type ImpactReport struct {
+	ImpactTarget
+	Instances        int               `json:"instances"`
+	InstAssociations int               `json:"inst_associations"`
+	TemplateColumns  []string          `json:"template_columns"`
+	Dependents       []ImpactDependent `json:"dependents"`
+	Token            string            `json:"token"`
+}
+
+// impactSubscription the fields of the event subscription the analysis reads
+type impactSubscription struct {
+	SubscriptionID   int64  `bson:"subscription_id" json:"subscription_id"`
+	SubscriptionName string `bson:"subscription_name" json:"subscription_name"`
+	SubscriptionForm string `bson:"subscription_form" json:"subscription_form"`
+	Filter           string `bson:"filter" json:"filter"`
+}
+
+// impactQuery the fields of the saved user api and the host favorite the analysis reads
+type impactQuery struct {
+	ID          string `bson:"id" json:"id"`
+	Name        string `bson:"name" json:"name"`
+	Creator     string `bson:"create_user" json:"create_user"`
+	User        string `bson:"user" json:"user"`
+	Info        string `bson:"info" json:"info"`
+	QueryParams string `bson:"query_params" json:"query_params"`
+}
+
+// impactRolePrivilege the role privilege, the property is the role attribute of the object
+type impactRolePrivilege struct {
+	ObjectID   string   `bson:"bk_obj_id" json:"bk_obj_id"`
+	PropertyID string   `bson:"bk_property_id" json:"bk_property_id"`
+	Privilege  []string `bson:"privilege" json:"privilege"`
+}
+
+// impactGroupPrivilege the privilege of the user group, model_config maps the classifications to the objects
+type impactGroupPrivilege struct {
+	GroupID   string `bson:"group_id" json:"group_id"`
+	Privilege struct {
+		ModelConfig map[string]map[string][]string `bson:"model_config" json:"model_config"`
+	} `bson:"privilege" json:"privilege"`
+}
+
+// userCustomKeys the suffixes of the user settings keyed by the object id
+var userCustomKeys = []string{"DisplayColumn"}
+
+// FindImpactTargets returns the objects or the attributes the condition of the deletion selects, in the order
+// of their ids, the deletion must select one item at least
+func FindImpactTargets(ctx context.Context, db storage.DI, attribute bool, cond interface{}) ([]ImpactTarget, error) {
+	table := common.BKTableNameObjDes
+	if attribute {
+		table = common.BKTableNameObjAttDes
+	}
+	targets := make([]ImpactTarget, 0)
+	fields := []string{common.BKOwnerIDField, common.BKObjIDField, common.BKPropertyIDField}
+	if err := storage.ContextOf(db).GetMutilByConditionCtx(ctx, table, fields, cond, &targets, "id", 0, 0); nil != err {
+		return nil, err
+	}
+	if 0 == len(targets) {
+		return nil, fmt.Errorf("the deletion selects nothing")
+	}
+	if !attribute {
+		for idx := range targets {
+			targets[idx].PropertyID = ""
+		}
+	}
+	return targets, nil
+}
+
+// AnalyzeImpact counts the instances holding data of the target and lists the items referring to it, the
+// items are matched by the ids they contain since the saved queries and the custom columns are free form
+func AnalyzeImpact(ctx context.Context, db storage.DI, target ImpactTarget) (*ImpactReport, error) {
+	cdb := storage.ContextOf(db)
+	report := &ImpactReport{ImpactTarget: target, TemplateColumns: []string{}, Dependents: []ImpactDependent{}}
+	objID, propertyID := target.ObjectID, target.PropertyID
+
+	// the instances of the object, or the ones holding a value of the attribute
+	table, ok := commondata.ObjTypeTableMap[objID]
+	instCond := map[string]interface{}{common.BKOwnerIDField: target.OwnerID}
+	if !ok {
+		table = commondata.ObjTypeTableMap[common.BKINnerObjIDObject]
+		instCond[common.BKObjIDField] = objID
+	}
+	if "" != propertyID {
+		instCond[propertyID] = map[string]interface{}{common.BKDBExists: true, common.BKDBNIN: []interface{}{nil, ""}}
+	}
+
+	// the associations pointing to the object, or the one of the association attribute
+	asstCond := map[string]interface{}{common.BKOwnerIDField: target.OwnerID, common.BKAsstObjIDField: objID}
+	if "" != propertyID {
+		asstCond = map[string]interface{}{common.BKOwnerIDField: target.OwnerID, common.BKObjIDField: objID, common.BKObjAttIDField: propertyID}
+	}
+	assts := make([]metadata.ObjectAsst, 0)
+	if err := cdb.GetMutilByConditionCtx(ctx, metadata.ObjectAsst{}.TableName(), nil, asstCond, &assts, "", 0, 0); nil != err {
+		return nil, err
+	}
+
+	var err error
+	if "" != propertyID && 0 != len(assts) {
+		// the values of the association attribute are kept by the instance associations, the earlier ones
+		// keep no attribute and no owner, so they are counted for all the attributes to the same object
+		asst := assts[0]
+		cond := map[string]interface{}{
+			common.BKObjIDField:     objID,
+			common.BKAsstObjIDField: asst.AsstObjID,
+			common.BKDBAND: []map[string]interface{}{
+				ownedBy(target.OwnerID),
+				{common.BKDBOR: []map[string]interface{}{
+					{common.BKObjAttIDField: propertyID},
+					{common.BKObjAttIDField: map[string]interface{}{common.BKDBExists: false}},
+				}},
+			},
+		}
+		if report.InstAssociations, err = cdb.GetCntByConditionCtx(ctx, metadata.InstAsst{}.TableName(), cond); nil != err {
+			return nil, err
+		}
+		report.Instances = report.InstAssociations
+	} else if report.Instances, err = cdb.GetCntByConditionCtx(ctx, table, instCond); nil != err {
+		return nil, err
+	}
+	if "" == propertyID {
+		cond := map[string]interface{}{common.BKDBAND: []map[string]interface{}{
+			ownedBy(target.OwnerID),
+			{common.BKDBOR: []map[string]interface{}{
+				{common.BKObjIDField: objID},
+				{common.BKAsstObjIDField: objID},
+			}},
+		}}
+		if report.InstAssociations, err = cdb.GetCntByConditionCtx(ctx, metadata.InstAsst{}.TableName(), cond); nil != err {
+			return nil, err
+		}
+	}
+
+	// the excel template of the object loses the columns of the deleted attributes
+	if "" != propertyID {
+		report.TemplateColumns = append(report.TemplateColumns, propertyID)
+	} else {
+		attrs := make([]ImpactTarget, 0)
+		attrCond := map[string]interface{}{common.BKOwnerIDField: target.OwnerID, common.BKObjIDField: objID}
+		if err := cdb.GetMutilByConditionCtx(ctx, common.BKTableNameObjAttDes, []string{common.BKPropertyIDField}, attrCond, &attrs, "", 0, 0); nil != err {
+			return nil, err
+		}
+		for _, attr := range attrs {
+			report.TemplateColumns = append(report.TemplateColumns, attr.PropertyID)
+		}
+		sort.Strings(report.TemplateColumns)
+
+		// the association attributes of the other objects are left pointing to nothing
+		for _, asst := range assts {
+			if objID == asst.ObjectID {
+				continue
+			}
+			report.Dependents = append(report.Dependents, ImpactDependent{
+				Kind: ImpactKindAttribute,
+				ID:   asst.ObjectID + "." + asst.ObjectAttID,
+				Name: asst.ObjectAttID,
+			})
+		}
+	}
+
+	analyzers := []func(context.Context, storage.ContextDI, ImpactTarget) ([]ImpactDependent, error){
+		subscriptionImpact,
+		queryImpact,
+		userCustomImpact,
+		privilegeImpact,
+	}
+	for _, analyze := range analyzers {
+		dependents, err := analyze(ctx, cdb, target)
+		if nil != err {
+			return nil, err
+		}
+		report.Dependents = append(report.Dependents, dependents...)
+	}
+	sort.Slice(report.Dependents, func(i, j int) bool {
+		if report.Dependents[i].Kind != report.Dependents[j].Kind {
+			return report.Dependents[i].Kind < report.Dependents[j].Kind
+		}
+		return report.Dependents[i].ID < report.Dependents[j].ID
+	})
+
+	report.Token = ImpactToken(report)
+	return report, nil
+}
+
+// ImpactToken returns the fingerprint of the report, the deletion is confirmed by the token of the analysis
+// made right before it, so any change of the impact in between requires a new confirmation
+func ImpactToken(report *ImpactReport) string {
+	content := *report
+	content.Token = ""
+	data, _ := json.Marshal(content)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// ImpactReports the impact of a deletion selecting several items, the token confirms all of them
+type ImpactReports struct {
+	Reports []*ImpactReport `json:"reports"`
+	Token   string          `json:"token"`
+}
+
+// ImpactsToken returns the token confirming the deletion of all the items of the reports, it is the token
+// of the report when the deletion selects one item
+func ImpactsToken(reports []*ImpactReport) string {
+	if 1 == len(reports) {
+		return reports[0].Token
+	}
+	tokens := make([]string, 0, len(reports))
+	for _, report := range reports {
+		tokens = append(tokens, report.Token)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(tokens, ",")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// subscriptionImpact lists the subscriptions to the events of the object, the subscriptions of an attribute
+// are the ones whose filter refers to it
+func subscriptionImpact(ctx context.Context, db storage.ContextDI, target ImpactTarget) ([]ImpactDependent, error) {
+	subscriptions := make([]impactSubscription, 0)
+	cond := map[string]interface{}{"supplier_account": target.OwnerID}
+	if err := db.GetMutilByConditionCtx(ctx, common.BKTableNameSubscription, nil, cond, &subscriptions, "", 0, 0); nil != err {
+		return nil, err
+	}
+	objEvents := map[string]bool{
+		target.ObjectID + "create": true,
+		target.ObjectID + "update": true,
+		target.ObjectID + "delete": true,
+	}
+	dependents := make([]ImpactDependent, 0)
+	for _, sub := range subscriptions {
+		events := make([]string, 0)
+		for _, event := range strings.Split(sub.SubscriptionForm, ",") {
+			if objEvents[strings.TrimSpace(event)] {
+				events = append(events, strings.TrimSpace(event))
+			}
+		}
+		if 0 == len(events) {
+			continue
+		}
+		if "" != target.PropertyID && !strings.Contains(sub.Filter, target.PropertyID) {
+			continue
+		}
+		detail := "events: " + strings.Join(events, ",")
+		if "" != target.PropertyID {
+			detail = "filter: " + sub.Filter
+		}
+		dependents = append(dependents, ImpactDependent{
+			Kind:   ImpactKindSubscription,
+			ID:     strconv.FormatInt(sub.SubscriptionID, 10),
+			Name:   sub.SubscriptionName,
+			Detail: detail,
+		})
+	}
+	return dependents, nil
+}
+
+// queryImpact lists the saved user apis of the businesses of the owner and the favorites of the owner whose
+// conditions refer to the target
+func queryImpact(ctx context.Context, db storage.ContextDI, target ImpactTarget) ([]ImpactDependent, error) {
+	bizs := make([]map[string]interface{}, 0)
+	bizCond := map[string]interface{}{common.BKOwnerIDField: target.OwnerID}
+	if err := db.GetMutilByConditionCtx(ctx, commondata.ObjTypeTableMap[common.BKInnerObjIDApp], []string{common.BKAppIDField}, bizCond, &bizs, "", 0, 0); nil != err {
+		return nil, err
+	}
+	bizIDs := make([]interface{}, 0, len(bizs))
+	for _, biz := range bizs {
+		bizIDs = append(bizIDs, biz[common.BKAppIDField])
+	}
+
+	// the conditions are saved as strings, the quoted object id is searched in them
+	refer := map[string]interface{}{common.BKDBLIKE: regexp.QuoteMeta(strconv.Quote(target.ObjectID))}
+	refers := []map[string]interface{}{{"info": refer}, {"query_params": refer}}
+	conds := map[string]map[string]interface{}{
+		ImpactKindUserAPI: {common.BKAppIDField: map[string]interface{}{common.BKDBIN: bizIDs}, common.BKDBOR: refers},
+		ImpactKindFavorite: {common.BKDBAND: []map[string]interface{}{ownedBy(target.OwnerID), {common.BKDBOR: refers}}},
+	}
+	tables := map[string]string{ImpactKindUserAPI: common.BKTableNameUserAPI, ImpactKindFavorite: common.BKTableNameHostFavorite}
+
+	dependents := make([]ImpactDependent, 0)
+	for kind, table := range tables {
+		queries := make([]impactQuery, 0)
+		if err := db.GetMutilByConditionCtx(ctx, table, nil, conds[kind], &queries, "", 0, 0); nil != err {
+			return nil, err
+		}
+		for _, query := range queries {
+			if !refersTo(query.Info+query.QueryParams, target) {
+				continue
+			}
+			dependents = append(dependents, ImpactDependent{Kind: kind, ID: query.ID, Name: query.Name, Detail: query.Creator + query.User})
+		}
+	}
+	return dependents, nil
+}
+
+// userCustomImpact lists the settings of the users of the owner keyed by the object, such as the displayed
+// columns, which hold the deleted attribute or belong to the deleted object
+func userCustomImpact(ctx context.Context, db storage.ContextDI, target ImpactTarget) ([]ImpactDependent, error) {
+	keys := make([]string, 0, len(userCustomKeys))
+	hasKeys := make([]map[string]interface{}, 0, len(userCustomKeys))
+	for _, suffix := range userCustomKeys {
+		keys = append(keys, target.ObjectID+suffix)
+		hasKeys = append(hasKeys, map[string]interface{}{target.ObjectID + suffix: map[string]interface{}{common.BKDBExists: true}})
+	}
+	cond := map[string]interface{}{common.BKDBAND: []map[string]interface{}{ownedBy(target.OwnerID), {common.BKDBOR: hasKeys}}}
+	customs := make([]map[string]interface{}, 0)
+	if err := db.GetMutilByConditionCtx(ctx, common.BKTableNameUserCustom, nil, cond, &customs, "", 0, 0); nil != err {
+		return nil, err
+	}
+	dependents := make([]ImpactDependent, 0)
+	for _, custom := range customs {
+		user := fmt.Sprint(custom[common.BKUser])
+		for _, key := range keys {
+			value, ok := custom[key]
+			if !ok {
+				continue
+			}
+			if "" != target.PropertyID {
+				data, _ := json.Marshal(value)
+				if !strings.Contains(string(data), strconv.Quote(target.PropertyID)) {
+					continue
+				}
+			}
+			dependents = append(dependents, ImpactDependent{Kind: ImpactKindUserCustom, ID: user + "." + key, Name: key, Detail: user})
+		}
+	}
+	return dependents, nil
+}
+
+// ownedBy selects the items of the owner, the items saved before the owner was recorded belong to all the owners
+func ownedBy(ownerID string) map[string]interface{} {
+	return map[string]interface{}{common.BKDBOR: []map[string]interface{}{
+		{common.BKOwnerIDField: ownerID},
+		{common.BKOwnerIDField: map[string]interface{}{common.BKDBExists: false}},
+	}}
+}
+
+// privilegeImpact lists the role privileges of the target, and the user group privileges of a deleted object
+func privilegeImpact(ctx context.Context, db storage.ContextDI, target ImpactTarget) ([]ImpactDependent, error) {
+	roles := make([]impactRolePrivilege, 0)
+	cond := map[string]interface{}{common.BKOwnerIDField: target.OwnerID, common.BKObjIDField: target.ObjectID}
+	if "" != target.PropertyID {
+		cond[common.BKPropertyIDField] = target.PropertyID
+	}
+	if err := db.GetMutilByConditionCtx(ctx, common.BKTableNamePrivilege, nil, cond, &roles, "", 0, 0); nil != err {
+		return nil, err
+	}
+	dependents := make([]ImpactDependent, 0)
+	for _, role := range roles {
+		dependents = append(dependents, ImpactDependent{
+			Kind:   ImpactKindRolePrivilege,
+			ID:     role.ObjectID + "." + role.PropertyID,
+			Name:   role.PropertyID,
+			Detail: strings.Join(role.Privilege, ","),
+		})
+	}
+	if "" != target.PropertyID {
+		return dependents, nil
+	}
+
+	groups := make([]impactGroupPrivilege, 0)
+	cond = map[string]interface{}{common.BKOwnerIDField: target.OwnerID}
+	if err := db.GetMutilByConditionCtx(ctx, common.BKTableNameUserGroupPrivilege, nil, cond, &groups, "", 0, 0); nil != err {
+		return nil, err
+	}
+	for _, group := range groups {
+		for classificationID, objects := range group.Privilege.ModelConfig {
+			if privileges, ok := objects[target.ObjectID]; ok {
+				dependents = append(dependents, ImpactDependent{
+					Kind:   ImpactKindGroupPrivilege,
+					ID:     group.GroupID,
+					Name:   classificationID,
+					Detail: strings.Join(privileges, ","),
+				})
+			}
+		}
+	}
+	return dependents, nil
+}
+
+// refersTo reports whether the saved condition refers to the target by its quoted ids
+func refersTo(condition string, target ImpactTarget) bool {
+	if !strings.Contains(condition, strconv.Quote(target.ObjectID)) {
+		return false
+	}
+	return "" == target.PropertyID || strings.Contains(condition, strconv.Quote(target.PropertyID))
+}
@@ -0,0 +1,192 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logics
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/storage"
+	"configcenter/src/storage/memclient"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newImpactDB(t *testing.T) storage.DI {
+	db, err := memclient.NewMemCli(t.Name())
+	require.NoError(t, err)
+	require.NoError(t, db.Open())
+
+	insert := func(table string, docs ...interface{}) {
+		require.NoError(t, db.InsertMuti(table, docs...))
+	}
+	insert(common.BKTableNameObjDes,
+		map[string]interface{}{"id": 1, common.BKOwnerIDField: "0", common.BKObjIDField: "switch"},
+		map[string]interface{}{"id": 2, common.BKOwnerIDField: "0", common.BKObjIDField: "router"},
+	)
+	insert(common.BKTableNameObjAttDes,
+		map[string]interface{}{"id": 11, common.BKOwnerIDField: "0", common.BKObjIDField: "switch", common.BKPropertyIDField: "vendor"},
+		map[string]interface{}{"id": 12, common.BKOwnerIDField: "0", common.BKObjIDField: "switch", common.BKPropertyIDField: "uplink"},
+		map[string]interface{}{"id": 13, common.BKOwnerIDField: "0", common.BKObjIDField: "router", common.BKPropertyIDField: "switch_port"},
+	)
+	insert("cc_ObjAsst",
+		map[string]interface{}{common.BKOwnerIDField: "0", common.BKObjIDField: "switch", common.BKObjAttIDField: "uplink", common.BKAsstObjIDField: "router"},
+		map[string]interface{}{common.BKOwnerIDField: "0", common.BKObjIDField: "router", common.BKObjAttIDField: "switch_port", common.BKAsstObjIDField: "switch"},
+	)
+	insert("cc_ObjectBase",
+		map[string]interface{}{common.BKOwnerIDField: "0", common.BKObjIDField: "switch", "vendor": "cisco"},
+		map[string]interface{}{common.BKOwnerIDField: "0", common.BKObjIDField: "switch", "vendor": ""},
+		map[string]interface{}{common.BKOwnerIDField: "0", common.BKObjIDField: "switch"},
+		map[string]interface{}{common.BKOwnerIDField: "0", common.BKObjIDField: "router", "vendor": "juniper"},
+	)
+	insert("cc_InstAsst",
+		map[string]interface{}{common.BKObjIDField: "switch", common.BKAsstObjIDField: "router"},
+		map[string]interface{}{common.BKObjIDField: "router", common.BKAsstObjIDField: "switch"},
+		map[string]interface{}{common.BKObjIDField: "host", common.BKAsstObjIDField: "router"},
+		map[string]interface{}{common.BKOwnerIDField: "0", common.BKObjIDField: "switch", common.BKObjAttIDField: "uplink", common.BKAsstObjIDField: "router"},
+		map[string]interface{}{common.BKOwnerIDField: "0", common.BKObjIDField: "switch", common.BKObjAttIDField: "backup", common.BKAsstObjIDField: "router"},
+		map[string]interface{}{common.BKOwnerIDField: "1", common.BKObjIDField: "switch", common.BKObjAttIDField: "uplink", common.BKAsstObjIDField: "router"},
+	)
+	insert(common.BKTableNameSubscription,
+		map[string]interface{}{"subscription_id": 1, "subscription_name": "switch", "supplier_account": "0", "subscription_form": "switchcreate,switchupdate", "filter": `cur_data.vendor == "cisco"`},
+		map[string]interface{}{"subscription_id": 2, "subscription_name": "host", "supplier_account": "0", "subscription_form": "hostcreate", "filter": `cur_data.vendor == "cisco"`},
+		map[string]interface{}{"subscription_id": 3, "subscription_name": "switch_port", "supplier_account": "0", "subscription_form": "switch_portcreate", "filter": `cur_data.vendor == "cisco"`},
+	)
+	insert("cc_ApplicationBase",
+		map[string]interface{}{common.BKOwnerIDField: "0", common.BKAppIDField: 2},
+		map[string]interface{}{common.BKOwnerIDField: "1", common.BKAppIDField: 3},
+	)
+	insert(common.BKTableNameUserAPI,
+		map[string]interface{}{"id": "a1", common.BKAppIDField: 2, "name": "cisco switches", "info": `{"condition":[{"bk_obj_id":"switch","condition":[{"field":"vendor","value":"cisco"}]}]}`},
+		map[string]interface{}{"id": "a2", common.BKAppIDField: 2, "name": "hosts", "info": `{"condition":[{"bk_obj_id":"host","condition":[]}]}`},
+		map[string]interface{}{"id": "a3", common.BKAppIDField: 3, "name": "other owner", "info": `{"condition":[{"bk_obj_id":"switch","condition":[{"field":"vendor","value":"cisco"}]}]}`},
+	)
+	insert(common.BKTableNameHostFavorite,
+		map[string]interface{}{"id": "f1", "name": "switches", "user": "admin", "query_params": `[{"bk_obj_id":"switch","field":"bk_inst_name"}]`},
+		map[string]interface{}{"id": "f2", common.BKOwnerIDField: "1", "name": "switches", "user": "admin", "query_params": `[{"bk_obj_id":"switch","field":"bk_inst_name"}]`},
+	)
+	insert(common.BKTableNameUserCustom,
+		map[string]interface{}{common.BKUser: "admin", common.BKOwnerIDField: "0", "switchDisplayColumn": []interface{}{"bk_inst_name", "vendor"}, "host_display_column": []interface{}{"vendor"}},
+		map[string]interface{}{common.BKUser: "other", common.BKOwnerIDField: "1", "switchDisplayColumn": []interface{}{"vendor"}},
+	)
+	insert(common.BKTableNamePrivilege,
+		map[string]interface{}{common.BKOwnerIDField: "0", common.BKObjIDField: "switch", common.BKPropertyIDField: "vendor", common.BKPrivilegeField: []interface{}{"update"}},
+	)
+	insert(common.BKTableNameUserGroupPrivilege,
+		map[string]interface{}{common.BKOwnerIDField: "0", common.BKUserGroupIDField: "ops", common.BKPrivilegeField: map[string]interface{}{
+			"model_config": map[string]interface{}{"bk_network": map[string]interface{}{"switch": []interface{}{"search", "update"}}},
+		}},
+	)
+	return db
+}
+
+func dependentIDs(report *ImpactReport) map[string][]string {
+	ids := make(map[string][]string)
+	for _, dependent := range report.Dependents {
+		ids[dependent.Kind] = append(ids[dependent.Kind], dependent.ID)
+	}
+	return ids
+}
+
+func TestAnalyzeAttributeImpact(t *testing.T) {
+	db := newImpactDB(t)
+	defer memclient.Drop(t.Name())
+	ctx := context.Background()
+
+	targets, err := FindImpactTargets(ctx, db, true, map[string]interface{}{"id": 11})
+	require.NoError(t, err)
+	assert.Equal(t, []ImpactTarget{{OwnerID: "0", ObjectID: "switch", PropertyID: "vendor"}}, targets)
+
+	report, err := AnalyzeImpact(ctx, db, targets[0])
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Instances)
+	assert.Equal(t, []string{"vendor"}, report.TemplateColumns)
+	assert.Equal(t, map[string][]string{
+		ImpactKindSubscription:  {"1"},
+		ImpactKindUserAPI:       {"a1"},
+		ImpactKindUserCustom:    {"admin.switchDisplayColumn"},
+		ImpactKindRolePrivilege: {"switch.vendor"},
+	}, dependentIDs(report))
+
+	// the values of an association attribute are the instance associations of the attribute and the owner,
+	// and the earlier ones which keep neither
+	report, err = AnalyzeImpact(ctx, db, ImpactTarget{OwnerID: "0", ObjectID: "switch", PropertyID: "uplink"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Instances)
+	assert.Equal(t, 2, report.InstAssociations)
+}
+
+func TestAnalyzeObjectImpact(t *testing.T) {
+	db := newImpactDB(t)
+	defer memclient.Drop(t.Name())
+	ctx := context.Background()
+
+	targets, err := FindImpactTargets(ctx, db, false, map[string]interface{}{"id": 1})
+	require.NoError(t, err)
+	target := targets[0]
+	report, err := AnalyzeImpact(ctx, db, target)
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Instances)
+	assert.Equal(t, 4, report.InstAssociations)
+	assert.Equal(t, []string{"uplink", "vendor"}, report.TemplateColumns)
+	assert.Equal(t, map[string][]string{
+		ImpactKindAttribute:      {"router.switch_port"},
+		ImpactKindFavorite:       {"f1"},
+		ImpactKindGroupPrivilege: {"ops"},
+		ImpactKindRolePrivilege:  {"switch.vendor"},
+		ImpactKindSubscription:   {"1"},
+		ImpactKindUserAPI:        {"a1"},
+		ImpactKindUserCustom:     {"admin.switchDisplayColumn"},
+	}, dependentIDs(report))
+
+	// the token stays the same until the impact changes
+	again, err := AnalyzeImpact(ctx, db, target)
+	require.NoError(t, err)
+	assert.Equal(t, report.Token, again.Token)
+	require.NoError(t, db.InsertMuti("cc_ObjectBase", map[string]interface{}{common.BKOwnerIDField: "0", common.BKObjIDField: "switch"}))
+	again, err = AnalyzeImpact(ctx, db, target)
+	require.NoError(t, err)
+	assert.NotEqual(t, report.Token, again.Token)
+
+	_, err = FindImpactTargets(ctx, db, false, map[string]interface{}{"id": 3})
+	assert.Error(t, err)
+}
+
+func TestImpactsToken(t *testing.T) {
+	db := newImpactDB(t)
+	defer memclient.Drop(t.Name())
+	ctx := context.Background()
+
+	// the deletion by the condition selects several objects
+	targets, err := FindImpactTargets(ctx, db, false, map[string]interface{}{common.BKOwnerIDField: "0"})
+	require.NoError(t, err)
+	assert.Equal(t, []ImpactTarget{{OwnerID: "0", ObjectID: "switch"}, {OwnerID: "0", ObjectID: "router"}}, targets)
+
+	reports := make([]*ImpactReport, 0, len(targets))
+	for _, target := range targets {
+		report, err := AnalyzeImpact(ctx, db, target)
+		require.NoError(t, err)
+		reports = append(reports, report)
+	}
+	token := ImpactsToken(reports)
+	assert.Equal(t, reports[0].Token, ImpactsToken(reports[:1]))
+	assert.NotEqual(t, reports[0].Token, token)
+	assert.NotEqual(t, reports[1].Token, token)
+
+	// the change of any of the impacts requires a new confirmation
+	require.NoError(t, db.InsertMuti("cc_ObjectBase", map[string]interface{}{common.BKOwnerIDField: "0", common.BKObjIDField: "router"}))
+	changed, err := AnalyzeImpact(ctx, db, targets[1])
+	require.NoError(t, err)
+	assert.NotEqual(t, token, ImpactsToken([]*ImpactReport{reports[0], changed}))
+}
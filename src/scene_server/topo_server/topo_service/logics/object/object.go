@@ -19,9 +19,12 @@ import (
 	httpcli "configcenter/src/common/http/httpclient"
 	sencapi "configcenter/src/scene_server/api"
 	"configcenter/src/scene_server/topo_server/topo_service/manager"
+	"configcenter/src/scene_server/validator"
+	"configcenter/src/source_controller/api/metadata"
 	api "configcenter/src/source_controller/api/object"
 	"encoding/json"
 	"fmt"
+	"net/http"
 
 	simplejson "github.com/bitly/go-simplejson"
 )
@@ -52,6 +55,11 @@ func (cli *objLogic) SetManager(mgr manager.Manager) error {
 	return nil
 }
 
+// WithHeader implement the manager's HeaderScoper interface
+func (cli *objLogic) WithHeader(header http.Header, mgr manager.Manager) interface{} {
+	return &objLogic{objcli: cli.objcli.WithHeader(header), cfg: cli.cfg, mgr: mgr}
+}
+
 func (cli *objLogic) CreateObject(params []byte, errProxy errors.DefaultCCErrorIf) (int, error) {
 
 	// unmarshal json
@@ -88,6 +96,12 @@ func (cli *objLogic) CreateObject(params []byte, errProxy errors.DefaultCCErrorI
 		return 0, fmt.Errorf("the built-in model-id[%s], please use a new name", obj.ObjID)
 	}
 
+	// the new object has only the preset instance name attribute created below, the rules read it only
+	presetFields := map[string]string{common.BKInstNameField: common.FiledTypeSingleChar}
+	if err := cli.checkValidationRules(params, presetFields, errProxy); nil != err {
+		return 0, err
+	}
+
 	// check the classification id
 	checkClsCond := make(map[string]interface{})
 	checkClsCond["bk_classification_id"] = obj.ClassificationID
@@ -210,6 +224,7 @@ func (cli *objLogic) UpdateObject(id int, params []byte, errProxy errors.Default
 	}
 
 	checkObjNameCond := make(map[string]interface{})
+	var objID string
 
 	// 检查数据是否存在
 	checkIDCond := make(map[string]interface{})
@@ -224,6 +239,24 @@ func (cli *objLogic) UpdateObject(id int, params []byte, errProxy errors.Default
 		return fmt.Errorf("nothing can be updated, please check the condition")
 	} else {
 		checkObjNameCond[common.BKOwnerIDField] = items[0].OwnerID
+		objID = items[0].ObjectID
+	}
+
+	if _, ok := objMap["bk_validation_rules"]; ok {
+		cond, _ := json.Marshal(map[string]interface{}{common.BKOwnerIDField: checkObjNameCond[common.BKOwnerIDField], common.BKObjIDField: objID})
+		cli.objcli.SetAddress(cli.cfg.Get(cli))
+		attrs, err := cli.objcli.SearchMetaObjectAtt(cond)
+		if nil != err {
+			blog.Error("failed to search the attributes of %s, error:%s", objID, err.Error())
+			return err
+		}
+		fieldTypes := make(map[string]string)
+		for _, attr := range attrs {
+			fieldTypes[attr.PropertyID] = attr.PropertyType
+		}
+		if err := cli.checkValidationRules(params, fieldTypes, errProxy); nil != err {
+			return err
+		}
 	}
 
 	// 如果设置了ObjName 就要判断名字是否冲突
@@ -251,6 +284,23 @@ func (cli *objLogic) UpdateObject(id int, params []byte, errProxy errors.Default
 	return cli.objcli.UpdateMetaObject(id, params)
 }
 
+// checkValidationRules checks the validation rules in the params, the fields of the rules are checked against the
+// attributes unless fieldTypes is nil
+func (cli *objLogic) checkValidationRules(params []byte, fieldTypes map[string]string, errProxy errors.DefaultCCErrorIf) error {
+	input := struct {
+		ValidationRules []metadata.ValidationRule `json:"bk_validation_rules"`
+	}{}
+	if err := json.Unmarshal(params, &input); nil != err {
+		blog.Error("failed to unmarshal the validation rules, error:%s", err.Error())
+		return errProxy.Errorf(common.CCErrTopoValidationRuleInvalid, err.Error())
+	}
+	if err := validator.CheckValidationRules(input.ValidationRules, fieldTypes); nil != err {
+		blog.Error("the validation rules are invalid, error:%s", err.Error())
+		return errProxy.Errorf(common.CCErrTopoValidationRuleInvalid, err.Error())
+	}
+	return nil
+}
+
 func (cli *objLogic) DeleteObject(id int, params []byte, errProxy errors.DefaultCCErrorIf) error {
 	if id < 0 {
 		blog.Error("attrid is invalid, %d", id)
@@ -20,6 +20,7 @@ import (
 	api "configcenter/src/source_controller/api/object"
 	"encoding/json"
 	"fmt"
+	"net/http"
 )
 
 type objAssoLogic struct {
@@ -49,6 +50,11 @@ func (cli *objAssoLogic) SetManager(mgr manager.Manager) error {
 	return nil
 }
 
+// WithHeader implement the manager's HeaderScoper interface
+func (cli *objAssoLogic) WithHeader(header http.Header, mgr manager.Manager) interface{} {
+	return &objAssoLogic{objcli: cli.objcli.WithHeader(header), cfg: cli.cfg, mgr: mgr}
+}
+
 func (cli *objAssoLogic) CreateObjectAsst(obj map[string]interface{}, errProxy errors.DefaultCCErrorIf) (int, error) {
 
 	objasstval, jserr := json.Marshal(obj)
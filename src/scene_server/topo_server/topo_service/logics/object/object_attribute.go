@@ -20,6 +20,7 @@ import (
 	api "configcenter/src/source_controller/api/object"
 	"encoding/json"
 	"fmt"
+	"net/http"
 )
 
 type objAttLogic struct {
@@ -48,6 +49,11 @@ func (cli *objAttLogic) SetManager(mgr manager.Manager) error {
 	return nil
 }
 
+// WithHeader implement the manager's HeaderScoper interface
+func (cli *objAttLogic) WithHeader(header http.Header, mgr manager.Manager) interface{} {
+	return &objAttLogic{objcli: cli.objcli.WithHeader(header), cfg: cli.cfg, mgr: mgr}
+}
+
 // CreateModel create main line topo object
 func (cli *objAttLogic) CreateTopoModel(obj api.ObjAttDes, errProxy errors.DefaultCCErrorIf) (int, error) {
 
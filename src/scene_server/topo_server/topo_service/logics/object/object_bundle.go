@@ -0,0 +1,693 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package object
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/errors"
+	"configcenter/src/scene_server/topo_server/topo_service/manager"
+	api "configcenter/src/source_controller/api/object"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type bundleLogic struct {
+	objcli *api.Client
+	cfg    manager.Configer
+	mgr    manager.Manager
+}
+
+var _ manager.ModelBundleLogic = (*bundleLogic)(nil)
+
+// the fields which are only set by the creation, the import does not update them
+var bundleCreateOnlyFields = map[string][]string{
+	manager.BundleKindClassification: {"bk_classification_id"},
+	manager.BundleKindObject:         {common.BKObjIDField, "ispre"},
+	manager.BundleKindGroup:          {common.BKObjIDField, "bk_group_id", "bk_isdefault"},
+	manager.BundleKindAttribute:      {common.BKObjIDField, "bk_property_id", "bk_property_index", "bk_isapi", "ispre"},
+	manager.BundleKindAssociation:    {common.BKObjIDField, "bk_object_att_id"},
+}
+
+// the field of the validation rules of the objects
+const bundleValidationRulesField = "bk_validation_rules"
+
+func init() {
+	obj := &bundleLogic{}
+	obj.objcli = api.NewClient("")
+	manager.SetManager(obj)
+	manager.RegisterLogic(manager.ObjectBundle, obj)
+}
+
+// Set implement SetConfiger interface
+func (cli *bundleLogic) Set(cfg manager.Configer) {
+	cli.cfg = cfg
+}
+
+// SetManager implement the manager's Hooker interface
+func (cli *bundleLogic) SetManager(mgr manager.Manager) error {
+	cli.mgr = mgr
+	return nil
+}
+
+// WithHeader implement the manager's HeaderScoper interface
+func (cli *bundleLogic) WithHeader(header http.Header, mgr manager.Manager) interface{} {
+	return &bundleLogic{objcli: cli.objcli.WithHeader(header), cfg: cli.cfg, mgr: mgr}
+}
+
+// ExportModelBundle export the selected classifications and objects, the custom objects the associations
+// refer to are exported as well so the bundle is self-contained
+func (cli *bundleLogic) ExportModelBundle(ownerID string, selector manager.BundleSelector, errProxy errors.DefaultCCErrorIf) (*manager.ModelBundle, error) {
+
+	if 0 == len(selector.ClassificationIDs) && 0 == len(selector.ObjectIDs) {
+		return nil, fmt.Errorf("neither bk_classification_id nor bk_obj_id is set")
+	}
+
+	objects := make(map[string]api.ObjDes)
+	if 0 != len(selector.ClassificationIDs) {
+		items, err := cli.searchObjects(map[string]interface{}{
+			common.BKOwnerIDField:  ownerID,
+			"bk_classification_id": map[string]interface{}{common.BKDBIN: selector.ClassificationIDs},
+		})
+		if nil != err {
+			return nil, err
+		}
+		for _, item := range items {
+			objects[item.ObjectID] = item
+		}
+	}
+	if 0 != len(selector.ObjectIDs) {
+		items, err := cli.searchObjects(map[string]interface{}{
+			common.BKOwnerIDField: ownerID,
+			common.BKObjIDField:   map[string]interface{}{common.BKDBIN: selector.ObjectIDs},
+		})
+		if nil != err {
+			return nil, err
+		}
+		for _, item := range items {
+			objects[item.ObjectID] = item
+		}
+		for _, objID := range selector.ObjectIDs {
+			if _, ok := objects[objID]; !ok {
+				return nil, fmt.Errorf("bk_obj_id[%s] is not found", objID)
+			}
+		}
+	}
+
+	// follow the associations to the custom objects out of the selection, the preset objects exist in
+	// every cmdb and are left out
+	assts := make([]api.ObjAsstDes, 0)
+	known := make(map[string]bool)
+	pending := make([]string, 0)
+	for objID := range objects {
+		known[objID] = true
+		pending = append(pending, objID)
+	}
+	for 0 != len(pending) {
+		items, err := cli.searchAssociations(map[string]interface{}{
+			common.BKOwnerIDField: ownerID,
+			common.BKObjIDField:   map[string]interface{}{common.BKDBIN: pending},
+		})
+		if nil != err {
+			return nil, err
+		}
+		missing := make([]string, 0)
+		for _, item := range items {
+			// the mainline associations belong to the topology
+			if common.BKChildStr == item.ObjectAttID || common.BKParentStr == item.ObjectAttID {
+				continue
+			}
+			assts = append(assts, item)
+			if !known[item.AsstObjID] {
+				known[item.AsstObjID] = true
+				missing = append(missing, item.AsstObjID)
+			}
+		}
+
+		pending = make([]string, 0)
+		if 0 == len(missing) {
+			break
+		}
+		refs, err := cli.searchObjects(map[string]interface{}{
+			common.BKOwnerIDField: ownerID,
+			common.BKObjIDField:   map[string]interface{}{common.BKDBIN: missing},
+		})
+		if nil != err {
+			return nil, err
+		}
+		for _, ref := range refs {
+			if !ref.IsPre {
+				objects[ref.ObjectID] = ref
+				pending = append(pending, ref.ObjectID)
+			}
+		}
+	}
+
+	bundle := &manager.ModelBundle{
+		Format:          manager.ModelBundleFormat,
+		Version:         selector.Version,
+		OwnerID:         ownerID,
+		ExportTime:      time.Now().UTC(),
+		Classifications: make([]manager.BundleClassification, 0),
+		Objects:         make([]manager.BundleObject, 0),
+		Groups:          make([]manager.BundleGroup, 0),
+		Attributes:      make([]manager.BundleAttribute, 0),
+		Associations:    make([]manager.BundleAssociation, 0),
+	}
+
+	objIDs := make([]string, 0)
+	clsIDs := append([]string{}, selector.ClassificationIDs...)
+	for objID, item := range objects {
+		objIDs = append(objIDs, objID)
+		clsIDs = append(clsIDs, item.ObjCls)
+		obj := manager.BundleObject{}
+		convertBundleItem(item, &obj)
+		bundle.Objects = append(bundle.Objects, obj)
+	}
+
+	classifications, err := cli.searchClassifications(classificationCond(ownerID, clsIDs))
+	if nil != err {
+		return nil, err
+	}
+	for _, item := range classifications {
+		cls := manager.BundleClassification{}
+		convertBundleItem(item, &cls)
+		bundle.Classifications = append(bundle.Classifications, cls)
+	}
+
+	if 0 != len(objIDs) {
+		// the preset groups and attributes of the preset objects come with cmdb
+		groups, err := cli.searchGroups(map[string]interface{}{
+			common.BKOwnerIDField: ownerID,
+			common.BKObjIDField:   map[string]interface{}{common.BKDBIN: objIDs},
+		})
+		if nil != err {
+			return nil, err
+		}
+		for _, item := range groups {
+			if item.IsPre && objects[item.ObjectID].IsPre {
+				continue
+			}
+			group := manager.BundleGroup{}
+			convertBundleItem(item, &group)
+			bundle.Groups = append(bundle.Groups, group)
+		}
+
+		attrs, err := cli.searchAttributes(map[string]interface{}{
+			common.BKOwnerIDField: ownerID,
+			common.BKObjIDField:   map[string]interface{}{common.BKDBIN: objIDs},
+		})
+		if nil != err {
+			return nil, err
+		}
+		exported := make(map[string]bool)
+		for _, item := range attrs {
+			if item.IsPre && objects[item.ObjectID].IsPre {
+				continue
+			}
+			if common.BKChildStr == item.PropertyID || common.BKParentStr == item.PropertyID {
+				continue
+			}
+			attr := manager.BundleAttribute{}
+			convertBundleItem(item, &attr)
+			bundle.Attributes = append(bundle.Attributes, attr)
+			exported[attr.Key()] = true
+		}
+
+		for _, item := range assts {
+			asst := manager.BundleAssociation{}
+			convertBundleItem(item, &asst)
+			if exported[asst.Key()] {
+				bundle.Associations = append(bundle.Associations, asst)
+			}
+		}
+	}
+
+	bundle.Sort()
+	return bundle, nil
+}
+
+// ImportModelBundle create the items of the bundle which do not exist and update the changed ones, the
+// items out of the bundle are kept. Every step compares with the current models again, so importing a
+// bundle twice changes nothing the second time. A dry run changes nothing and reports the items of the new
+// objects to create.
+func (cli *bundleLogic) ImportModelBundle(ownerID string, bundle *manager.ModelBundle, dryRun bool, errProxy errors.DefaultCCErrorIf) (*manager.BundleImportResult, error) {
+
+	if err := bundle.Validate(); nil != err {
+		return nil, err
+	}
+
+	if err := cli.checkBundleReferences(ownerID, bundle); nil != err {
+		return nil, err
+	}
+
+	result := &manager.BundleImportResult{DryRun: dryRun, Changes: make([]manager.BundleChange, 0)}
+	steps := []func(string, *manager.ModelBundle, bool, *manager.BundleImportResult, errors.DefaultCCErrorIf) error{
+		cli.importClassifications,
+		cli.importObjects,
+		cli.importGroups,
+		cli.importAttributes,
+		cli.importValidationRules,
+		cli.importAssociations,
+	}
+	for _, step := range steps {
+		if err := step(ownerID, bundle, dryRun, result, errProxy); nil != err {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// checkBundleReferences checks the classifications and the objects the bundle refers to but does not carry
+func (cli *bundleLogic) checkBundleReferences(ownerID string, bundle *manager.ModelBundle) error {
+
+	bundleCls := make(map[string]bool)
+	for _, item := range bundle.Classifications {
+		bundleCls[item.ClassificationID] = true
+	}
+	bundleObjs := make(map[string]bool)
+	for _, item := range bundle.Objects {
+		bundleObjs[item.ObjectID] = true
+	}
+
+	clsIDs := make([]string, 0)
+	for _, item := range bundle.Objects {
+		if !bundleCls[item.ClassificationID] {
+			clsIDs = append(clsIDs, item.ClassificationID)
+		}
+	}
+	if 0 != len(clsIDs) {
+		items, err := cli.searchClassifications(classificationCond(ownerID, clsIDs))
+		if nil != err {
+			return err
+		}
+		for _, item := range items {
+			bundleCls[item.ClassificationID] = true
+		}
+		for _, clsID := range clsIDs {
+			if !bundleCls[clsID] {
+				return fmt.Errorf("bk_classification_id[%s] is neither in the bundle nor in cmdb", clsID)
+			}
+		}
+	}
+
+	// the preset objects are not created by the import, and the association targets out of the bundle
+	// have to exist
+	objIDs := make([]string, 0)
+	for _, item := range bundle.Objects {
+		if item.IsPre {
+			objIDs = append(objIDs, item.ObjectID)
+		}
+	}
+	for _, item := range bundle.Associations {
+		if !bundleObjs[item.AsstObjID] {
+			objIDs = append(objIDs, item.AsstObjID)
+		}
+	}
+	if 0 != len(objIDs) {
+		items, err := cli.searchObjects(map[string]interface{}{
+			common.BKOwnerIDField: ownerID,
+			common.BKObjIDField:   map[string]interface{}{common.BKDBIN: objIDs},
+		})
+		if nil != err {
+			return err
+		}
+		existing := make(map[string]bool)
+		for _, item := range items {
+			existing[item.ObjectID] = true
+		}
+		for _, objID := range objIDs {
+			if !existing[objID] {
+				return fmt.Errorf("bk_obj_id[%s] is preset or referred by an association, but it is not in cmdb", objID)
+			}
+		}
+	}
+	return nil
+}
+
+func (cli *bundleLogic) importClassifications(ownerID string, bundle *manager.ModelBundle, dryRun bool, result *manager.BundleImportResult, errProxy errors.DefaultCCErrorIf) error {
+
+	if 0 == len(bundle.Classifications) {
+		return nil
+	}
+	clsIDs := make([]string, 0)
+	for _, item := range bundle.Classifications {
+		clsIDs = append(clsIDs, item.ClassificationID)
+	}
+	items, err := cli.searchClassifications(classificationCond(ownerID, clsIDs))
+	if nil != err {
+		return err
+	}
+	current := make(map[string]api.ObjClsDes)
+	for _, item := range items {
+		current[item.ClassificationID] = item
+	}
+
+	for _, item := range bundle.Classifications {
+		cur, exists := current[item.Key()]
+		cls := manager.BundleClassification{}
+		convertBundleItem(cur, &cls)
+		action, data := planBundleChange(result, manager.BundleKindClassification, item.Key(), exists, cls, item)
+		if dryRun {
+			continue
+		}
+		switch action {
+		case manager.BundleActionCreate:
+			_, err = cli.mgr.CreateObjectClass(bundleItemParams(item, ownerID), errProxy)
+		case manager.BundleActionUpdate:
+			params, _ := json.Marshal(data)
+			err = cli.mgr.UpdateObjectClass(cur.ID, params, errProxy)
+		}
+		if nil != err {
+			blog.Error("failed to %s the classification %s, error info is %s", action, item.Key(), err.Error())
+			return fmt.Errorf("failed to %s the classification %s, %s", action, item.Key(), err.Error())
+		}
+	}
+	return nil
+}
+
+func (cli *bundleLogic) importObjects(ownerID string, bundle *manager.ModelBundle, dryRun bool, result *manager.BundleImportResult, errProxy errors.DefaultCCErrorIf) error {
+
+	current, err := cli.currentObjects(ownerID, bundle)
+	if nil != err {
+		return err
+	}
+
+	for _, item := range bundle.Objects {
+		cur, exists := current[item.Key()]
+		obj := manager.BundleObject{}
+		convertBundleItem(cur, &obj)
+		action, data := planBundleChange(result, manager.BundleKindObject, item.Key(), exists, obj, item)
+		if dryRun {
+			continue
+		}
+		switch action {
+		case manager.BundleActionCreate:
+			// the rules may read the attributes imported later, they are set by importValidationRules
+			_, err = cli.mgr.CreateObject(bundleItemParams(item, ownerID, bundleValidationRulesField), errProxy)
+		case manager.BundleActionUpdate:
+			// the rules may read the attributes imported later, they are updated by importValidationRules
+			delete(data, bundleValidationRulesField)
+			if 0 == len(data) {
+				continue
+			}
+			params, _ := json.Marshal(data)
+			err = cli.mgr.UpdateObject(cur.ID, params, errProxy)
+		}
+		if nil != err {
+			blog.Error("failed to %s the object %s, error info is %s", action, item.Key(), err.Error())
+			return fmt.Errorf("failed to %s the object %s, %s", action, item.Key(), err.Error())
+		}
+	}
+	return nil
+}
+
+// importValidationRules updates the validation rules of the objects once the attributes they read are imported,
+// the changes are reported by importObjects
+func (cli *bundleLogic) importValidationRules(ownerID string, bundle *manager.ModelBundle, dryRun bool, result *manager.BundleImportResult, errProxy errors.DefaultCCErrorIf) error {
+
+	if dryRun {
+		return nil
+	}
+	current, err := cli.currentObjects(ownerID, bundle)
+	if nil != err {
+		return err
+	}
+
+	for _, item := range bundle.Objects {
+		cur, exists := current[item.Key()]
+		if !exists || nil == item.ValidationRules {
+			continue
+		}
+		obj := manager.BundleObject{}
+		convertBundleItem(cur, &obj)
+		if _, changed := manager.DiffBundleItem(obj, item)[bundleValidationRulesField]; !changed {
+			continue
+		}
+		params, _ := json.Marshal(map[string]interface{}{bundleValidationRulesField: item.ValidationRules})
+		if err := cli.mgr.UpdateObject(cur.ID, params, errProxy); nil != err {
+			blog.Error("failed to update the validation rules of the object %s, error info is %s", item.Key(), err.Error())
+			return fmt.Errorf("failed to update the validation rules of the object %s, %s", item.Key(), err.Error())
+		}
+	}
+	return nil
+}
+
+func (cli *bundleLogic) importGroups(ownerID string, bundle *manager.ModelBundle, dryRun bool, result *manager.BundleImportResult, errProxy errors.DefaultCCErrorIf) error {
+
+	if 0 == len(bundle.Groups) {
+		return nil
+	}
+	items, err := cli.searchGroups(map[string]interface{}{
+		common.BKOwnerIDField: ownerID,
+		common.BKObjIDField:   map[string]interface{}{common.BKDBIN: bundleObjectIDs(bundle)},
+	})
+	if nil != err {
+		return err
+	}
+	current := make(map[string]api.ObjAttGroupDes)
+	for _, item := range items {
+		current[item.ObjectID+"."+item.GroupID] = item
+	}
+
+	for _, item := range bundle.Groups {
+		cur, exists := current[item.Key()]
+		group := manager.BundleGroup{}
+		convertBundleItem(cur, &group)
+		action, data := planBundleChange(result, manager.BundleKindGroup, item.Key(), exists, group, item)
+		if dryRun {
+			continue
+		}
+		switch action {
+		case manager.BundleActionCreate:
+			_, err = cli.mgr.CreateObjectGroup(bundleItemParams(item, ownerID), errProxy)
+		case manager.BundleActionUpdate:
+			params, _ := json.Marshal(map[string]interface{}{
+				"condition": map[string]interface{}{
+					common.BKOwnerIDField: ownerID,
+					common.BKObjIDField:   item.ObjectID,
+					"bk_group_id":         item.GroupID,
+				},
+				"data": data,
+			})
+			err = cli.mgr.UpdateObjectGroup(params, errProxy)
+		}
+		if nil != err {
+			blog.Error("failed to %s the group %s, error info is %s", action, item.Key(), err.Error())
+			return fmt.Errorf("failed to %s the group %s, %s", action, item.Key(), err.Error())
+		}
+	}
+	return nil
+}
+
+func (cli *bundleLogic) importAttributes(ownerID string, bundle *manager.ModelBundle, dryRun bool, result *manager.BundleImportResult, errProxy errors.DefaultCCErrorIf) error {
+
+	if 0 == len(bundle.Attributes) {
+		return nil
+	}
+	items, err := cli.searchAttributes(map[string]interface{}{
+		common.BKOwnerIDField: ownerID,
+		common.BKObjIDField:   map[string]interface{}{common.BKDBIN: bundleObjectIDs(bundle)},
+	})
+	if nil != err {
+		return err
+	}
+	current := make(map[string]api.ObjAttDes)
+	for _, item := range items {
+		current[item.ObjectID+"."+item.PropertyID] = item
+	}
+	assts := make(map[string]manager.BundleAssociation)
+	for _, item := range bundle.Associations {
+		assts[item.Key()] = item
+	}
+
+	for _, item := range bundle.Attributes {
+		cur, exists := current[item.Key()]
+		attr := manager.BundleAttribute{}
+		convertBundleItem(cur, &attr)
+		action, data := planBundleChange(result, manager.BundleKindAttribute, item.Key(), exists, attr, item)
+		if dryRun {
+			continue
+		}
+		switch action {
+		case manager.BundleActionCreate:
+			// the attribute creates its association
+			att := api.ObjAttDes{}
+			json.Unmarshal(bundleItemParams(item, ownerID), &att)
+			if asst, ok := assts[item.Key()]; ok {
+				att.AssociationID = asst.AsstObjID
+				att.AsstForward = asst.AsstForward
+			}
+			_, err = cli.mgr.CreateObjectAtt(att, errProxy)
+		case manager.BundleActionUpdate:
+			params, _ := json.Marshal(data)
+			err = cli.mgr.UpdateObjectAtt(cur.ID, params, errProxy)
+		}
+		if nil != err {
+			blog.Error("failed to %s the attribute %s, error info is %s", action, item.Key(), err.Error())
+			return fmt.Errorf("failed to %s the attribute %s, %s", action, item.Key(), err.Error())
+		}
+	}
+	return nil
+}
+
+func (cli *bundleLogic) importAssociations(ownerID string, bundle *manager.ModelBundle, dryRun bool, result *manager.BundleImportResult, errProxy errors.DefaultCCErrorIf) error {
+
+	if 0 == len(bundle.Associations) {
+		return nil
+	}
+	items, err := cli.searchAssociations(map[string]interface{}{
+		common.BKOwnerIDField: ownerID,
+		common.BKObjIDField:   map[string]interface{}{common.BKDBIN: bundleObjectIDs(bundle)},
+	})
+	if nil != err {
+		return err
+	}
+	current := make(map[string]api.ObjAsstDes)
+	for _, item := range items {
+		current[item.ObjectID+"."+item.ObjectAttID] = item
+	}
+
+	for _, item := range bundle.Associations {
+		cur, exists := current[item.Key()]
+		asst := manager.BundleAssociation{}
+		convertBundleItem(cur, &asst)
+		action, data := planBundleChange(result, manager.BundleKindAssociation, item.Key(), exists, asst, item)
+		if dryRun {
+			continue
+		}
+		switch action {
+		case manager.BundleActionCreate:
+			params := make(map[string]interface{})
+			json.Unmarshal(bundleItemParams(item, ownerID), &params)
+			_, err = cli.mgr.CreateObjectAsst(params, errProxy)
+		case manager.BundleActionUpdate:
+			selector := map[string]interface{}{
+				common.BKOwnerIDField: ownerID,
+				common.BKObjIDField:   item.ObjectID,
+				"bk_object_att_id":    item.ObjectAttID,
+			}
+			err = cli.mgr.UpdateObjectAsst(selector, data, errProxy)
+		}
+		if nil != err {
+			blog.Error("failed to %s the association %s, error info is %s", action, item.Key(), err.Error())
+			return fmt.Errorf("failed to %s the association %s, %s", action, item.Key(), err.Error())
+		}
+	}
+	return nil
+}
+
+func (cli *bundleLogic) currentObjects(ownerID string, bundle *manager.ModelBundle) (map[string]api.ObjDes, error) {
+	current := make(map[string]api.ObjDes)
+	if 0 == len(bundle.Objects) {
+		return current, nil
+	}
+	items, err := cli.searchObjects(map[string]interface{}{
+		common.BKOwnerIDField: ownerID,
+		common.BKObjIDField:   map[string]interface{}{common.BKDBIN: bundleObjectIDs(bundle)},
+	})
+	if nil != err {
+		return nil, err
+	}
+	for _, item := range items {
+		current[item.ObjectID] = item
+	}
+	return current, nil
+}
+
+func (cli *bundleLogic) searchClassifications(cond map[string]interface{}) ([]api.ObjClsDes, error) {
+	val, _ := json.Marshal(cond)
+	cli.objcli.SetAddress(cli.cfg.Get(cli))
+	return cli.objcli.SearchMetaObjectCls(val)
+}
+
+func (cli *bundleLogic) searchObjects(cond map[string]interface{}) ([]api.ObjDes, error) {
+	val, _ := json.Marshal(cond)
+	cli.objcli.SetAddress(cli.cfg.Get(cli))
+	return cli.objcli.SearchMetaObject(val)
+}
+
+func (cli *bundleLogic) searchGroups(cond map[string]interface{}) ([]api.ObjAttGroupDes, error) {
+	val, _ := json.Marshal(cond)
+	cli.objcli.SetAddress(cli.cfg.Get(cli))
+	return cli.objcli.SelectPropertyGroup(val)
+}
+
+func (cli *bundleLogic) searchAttributes(cond map[string]interface{}) ([]api.ObjAttDes, error) {
+	val, _ := json.Marshal(cond)
+	cli.objcli.SetAddress(cli.cfg.Get(cli))
+	return cli.objcli.SearchMetaObjectAtt(val)
+}
+
+func (cli *bundleLogic) searchAssociations(cond map[string]interface{}) ([]api.ObjAsstDes, error) {
+	val, _ := json.Marshal(cond)
+	cli.objcli.SetAddress(cli.cfg.Get(cli))
+	return cli.objcli.SearchMetaObjectAsst(val)
+}
+
+// planBundleChange records the change of the bundle item, it returns the action and the fields to update
+func planBundleChange(result *manager.BundleImportResult, kind, key string, exists bool, current, item interface{}) (string, map[string]interface{}) {
+	if !exists {
+		result.Changes = append(result.Changes, manager.BundleChange{Kind: kind, Key: key, Action: manager.BundleActionCreate})
+		return manager.BundleActionCreate, nil
+	}
+
+	fields := manager.DiffBundleItem(current, item, bundleCreateOnlyFields[kind]...)
+	if 0 == len(fields) {
+		result.Unchanged++
+		return "", nil
+	}
+	result.Changes = append(result.Changes, manager.BundleChange{Kind: kind, Key: key, Action: manager.BundleActionUpdate, Fields: fields})
+	data := make(map[string]interface{}, len(fields))
+	for field, change := range fields {
+		data[field] = change.To
+	}
+	return manager.BundleActionUpdate, data
+}
+
+// convertBundleItem copies the fields of the stored item to the bundle item, they share the json names
+func convertBundleItem(item, target interface{}) {
+	data, _ := json.Marshal(item)
+	json.Unmarshal(data, target)
+}
+
+// bundleItemParams returns the creation params of the bundle item without the omitted fields
+func bundleItemParams(item interface{}, ownerID string, omitted ...string) []byte {
+	params := make(map[string]interface{})
+	convertBundleItem(item, &params)
+	for _, field := range omitted {
+		delete(params, field)
+	}
+	params[common.BKOwnerIDField] = ownerID
+	data, _ := json.Marshal(params)
+	return data
+}
+
+// classificationCond returns the condition of the classifications of the owner together with the shared ones
+// which no owner is set for, the preset classifications for example
+func classificationCond(ownerID string, clsIDs []string) map[string]interface{} {
+	return map[string]interface{}{
+		common.BKOwnerIDField:  map[string]interface{}{common.BKDBIN: []interface{}{ownerID, "", nil}},
+		"bk_classification_id": map[string]interface{}{common.BKDBIN: clsIDs},
+	}
+}
+
+func bundleObjectIDs(bundle *manager.ModelBundle) []string {
+	objIDs := make([]string, 0, len(bundle.Objects))
+	for _, item := range bundle.Objects {
+		objIDs = append(objIDs, item.ObjectID)
+	}
+	return objIDs
+}
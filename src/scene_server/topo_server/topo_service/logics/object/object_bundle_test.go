@@ -0,0 +1,351 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package object
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/common/errors"
+	"configcenter/src/scene_server/topo_server/topo_service/manager"
+	"configcenter/src/source_controller/api/metadata"
+	api "configcenter/src/source_controller/api/object"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeModels the models of the fake object controller, the items keep their json fields
+type fakeModels struct {
+	manager.Manager
+
+	lock   sync.Mutex
+	nextID int
+	tables map[string][]map[string]interface{}
+	calls  []string
+}
+
+// the tables of the fake object controller, keyed by the search path
+const (
+	fakeClassifications = "/object/v1/meta/object/classification/search"
+	fakeObjects         = "/object/v1/meta/objects"
+	fakeGroups          = "/object/v1/meta/objectatt/group/search"
+	fakeAttributes      = "/object/v1/meta/objectatts"
+	fakeAssociations    = "/object/v1/meta/objectassts"
+)
+
+type fakeConfiger string
+
+func (c fakeConfiger) Get(target interface{}) string {
+	return string(c)
+}
+
+func newFakeModels() *fakeModels {
+	return &fakeModels{tables: map[string][]map[string]interface{}{}}
+}
+
+func (f *fakeModels) add(table string, item interface{}) map[string]interface{} {
+	row := make(map[string]interface{})
+	convertBundleItem(item, &row)
+	f.nextID++
+	row["id"] = f.nextID
+	f.tables[table] = append(f.tables[table], row)
+	return row
+}
+
+func (f *fakeModels) update(table string, cond, data map[string]interface{}) error {
+	for _, row := range f.tables[table] {
+		if fakeMatch(row, cond) {
+			for key, val := range data {
+				row[key] = val
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("nothing of %v found in %s", cond, table)
+}
+
+// fakeMatch supports the equal and $in conditions, nil in $in matches the missing field
+func fakeMatch(row, cond map[string]interface{}) bool {
+	for key, want := range cond {
+		val, exists := row[key]
+		ops, ok := want.(map[string]interface{})
+		if !ok {
+			if !exists || fmt.Sprint(want) != fmt.Sprint(val) {
+				return false
+			}
+			continue
+		}
+		items, _ := ops[common.BKDBIN].([]interface{})
+		found := false
+		for _, item := range items {
+			if (nil == item && nil == val) || (exists && nil != item && fmt.Sprint(item) == fmt.Sprint(val)) {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *fakeModels) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	cond := make(map[string]interface{})
+	json.NewDecoder(r.Body).Decode(&cond)
+	data := make([]map[string]interface{}, 0)
+	for _, row := range f.tables[r.URL.Path] {
+		if fakeMatch(row, cond) {
+			data = append(data, row)
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"result": true, "code": common.CCSuccess, "data": data})
+}
+
+func (f *fakeModels) record(format string, args ...interface{}) {
+	f.calls = append(f.calls, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeModels) CreateObjectClass(params []byte, errProxy errors.DefaultCCErrorIf) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	row := f.add(fakeClassifications, json.RawMessage(params))
+	f.record("create classification %v", row["bk_classification_id"])
+	return row["id"].(int), nil
+}
+
+func (f *fakeModels) UpdateObjectClass(id int, params []byte, errProxy errors.DefaultCCErrorIf) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	data := make(map[string]interface{})
+	json.Unmarshal(params, &data)
+	f.record("update classification %d", id)
+	return f.update(fakeClassifications, map[string]interface{}{"id": id}, data)
+}
+
+func (f *fakeModels) CreateObject(params []byte, errProxy errors.DefaultCCErrorIf) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	row := f.add(fakeObjects, json.RawMessage(params))
+	f.record("create object %v", row[common.BKObjIDField])
+	return row["id"].(int), nil
+}
+
+func (f *fakeModels) UpdateObject(id int, params []byte, errProxy errors.DefaultCCErrorIf) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	data := make(map[string]interface{})
+	json.Unmarshal(params, &data)
+	f.record("update object %d", id)
+	return f.update(fakeObjects, map[string]interface{}{"id": id}, data)
+}
+
+func (f *fakeModels) CreateObjectGroup(params []byte, errProxy errors.DefaultCCErrorIf) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	row := f.add(fakeGroups, json.RawMessage(params))
+	f.record("create group %v.%v", row[common.BKObjIDField], row["bk_group_id"])
+	return row["id"].(int), nil
+}
+
+func (f *fakeModels) UpdateObjectGroup(params []byte, errProxy errors.DefaultCCErrorIf) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	input := struct {
+		Condition map[string]interface{} `json:"condition"`
+		Data      map[string]interface{} `json:"data"`
+	}{}
+	json.Unmarshal(params, &input)
+	f.record("update group %v.%v", input.Condition[common.BKObjIDField], input.Condition["bk_group_id"])
+	return f.update(fakeGroups, input.Condition, input.Data)
+}
+
+func (f *fakeModels) CreateObjectAtt(params api.ObjAttDes, errProxy errors.DefaultCCErrorIf) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	row := f.add(fakeAttributes, params)
+	f.record("create attribute %v.%v", row[common.BKObjIDField], row["bk_property_id"])
+	if "" != params.AssociationID {
+		// the controller creates the association of the attribute
+		f.add(fakeAssociations, map[string]interface{}{
+			common.BKOwnerIDField: params.OwnerID,
+			common.BKObjIDField:   params.ObjectID,
+			"bk_object_att_id":    params.PropertyID,
+			"bk_asst_obj_id":      params.AssociationID,
+			"bk_asst_forward":     params.AsstForward,
+		})
+	}
+	return row["id"].(int), nil
+}
+
+func (f *fakeModels) UpdateObjectAtt(id int, params []byte, errProxy errors.DefaultCCErrorIf) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	data := make(map[string]interface{})
+	json.Unmarshal(params, &data)
+	f.record("update attribute %d", id)
+	return f.update(fakeAttributes, map[string]interface{}{"id": id}, data)
+}
+
+func (f *fakeModels) CreateObjectAsst(params map[string]interface{}, errProxy errors.DefaultCCErrorIf) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	row := f.add(fakeAssociations, params)
+	f.record("create association %v.%v", row[common.BKObjIDField], row["bk_object_att_id"])
+	return row["id"].(int), nil
+}
+
+func (f *fakeModels) UpdateObjectAsst(selector, data map[string]interface{}, errProxy errors.DefaultCCErrorIf) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.record("update association %v.%v", selector[common.BKObjIDField], selector["bk_object_att_id"])
+	return f.update(fakeAssociations, selector, data)
+}
+
+func newTestBundleLogic(t *testing.T, models *fakeModels) *bundleLogic {
+	srv := httptest.NewServer(models)
+	t.Cleanup(srv.Close)
+	return &bundleLogic{objcli: api.NewClient(""), cfg: fakeConfiger(srv.URL), mgr: models}
+}
+
+func testModelBundle() *manager.ModelBundle {
+	return &manager.ModelBundle{
+		Format:  manager.ModelBundleFormat,
+		Version: "1.0.0",
+		OwnerID: "0",
+		Classifications: []manager.BundleClassification{
+			{ClassificationID: "bk_network", ClassificationName: "network"},
+		},
+		Objects: []manager.BundleObject{
+			{ObjectID: "switch", ObjectName: "switch", ClassificationID: "bk_network"},
+			{ObjectID: "router", ObjectName: "router", ClassificationID: "bk_network"},
+		},
+		Groups: []manager.BundleGroup{
+			{ObjectID: "switch", GroupID: "default", GroupName: "Default", GroupIndex: -1, IsDefault: true},
+		},
+		Attributes: []manager.BundleAttribute{
+			{ObjectID: "switch", PropertyID: "uplink", PropertyName: "uplink", PropertyType: common.FiledTypeSingleAsst, PropertyGroup: "default"},
+		},
+		Associations: []manager.BundleAssociation{
+			{ObjectID: "switch", ObjectAttID: "uplink", AsstObjID: "router"},
+		},
+	}
+}
+
+func TestImportModelBundle(t *testing.T) {
+	models := newFakeModels()
+	logic := newTestBundleLogic(t, models)
+
+	result, err := logic.ImportModelBundle("0", testModelBundle(), true, nil)
+	require.NoError(t, err)
+	assert.Len(t, result.Changes, 6)
+	assert.Empty(t, models.calls, "the dry run changes nothing")
+
+	result, err = logic.ImportModelBundle("0", testModelBundle(), false, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"create classification bk_network",
+		"create object switch",
+		"create object router",
+		"create group switch.default",
+		"create attribute switch.uplink",
+	}, models.calls, "the association is created with its attribute")
+	cls := models.tables[fakeClassifications][0]
+	assert.Equal(t, "0", cls[common.BKOwnerIDField], "the classification is created for the owner")
+
+	// importing again changes nothing
+	models.calls = nil
+	result, err = logic.ImportModelBundle("0", testModelBundle(), false, nil)
+	require.NoError(t, err)
+	assert.Empty(t, result.Changes)
+	assert.Equal(t, 6, result.Unchanged)
+	assert.Empty(t, models.calls)
+
+	// the changed fields are updated
+	bundle := testModelBundle()
+	bundle.Objects[1].ObjectName = "edge router"
+	result, err = logic.ImportModelBundle("0", bundle, false, nil)
+	require.NoError(t, err)
+	require.Len(t, result.Changes, 1)
+	assert.Equal(t, manager.BundleActionUpdate, result.Changes[0].Action)
+	assert.Equal(t, "edge router", result.Changes[0].Fields["bk_obj_name"].To)
+	assert.Len(t, models.calls, 1)
+	assert.True(t, strings.HasPrefix(models.calls[0], "update object"))
+
+	// the referred classification has to exist
+	bundle = testModelBundle()
+	bundle.Classifications = nil
+	bundle.Objects[0].ClassificationID = "bk_missing"
+	_, err = logic.ImportModelBundle("0", bundle, true, nil)
+	assert.Error(t, err)
+}
+
+func TestImportModelBundleValidationRules(t *testing.T) {
+	models := newFakeModels()
+	logic := newTestBundleLogic(t, models)
+
+	bundle := testModelBundle()
+	bundle.Objects[0].ValidationRules = []metadata.ValidationRule{{ID: "uplink", Require: []string{"uplink"}}}
+	_, err := logic.ImportModelBundle("0", bundle, false, nil)
+	require.NoError(t, err)
+
+	// the object is created without the rules reading its attributes, they are set once imported
+	assert.Equal(t, "create object switch", models.calls[1])
+	assert.Equal(t, "create attribute switch.uplink", models.calls[4])
+	require.Len(t, models.calls, 6)
+	assert.True(t, strings.HasPrefix(models.calls[5], "update object"))
+	assert.NotNil(t, models.tables[fakeObjects][0][bundleValidationRulesField])
+}
+
+func TestExportModelBundle(t *testing.T) {
+	models := newFakeModels()
+	logic := newTestBundleLogic(t, models)
+	_, err := logic.ImportModelBundle("0", testModelBundle(), false, nil)
+	require.NoError(t, err)
+
+	// the classifications of the other owners are left out, the shared ones are exported
+	models.add(fakeClassifications, map[string]interface{}{"bk_classification_id": "bk_private", common.BKOwnerIDField: "1"})
+	models.add(fakeClassifications, map[string]interface{}{"bk_classification_id": "bk_middleware"})
+	models.add(fakeObjects, map[string]interface{}{common.BKObjIDField: "redis", "bk_classification_id": "bk_middleware", common.BKOwnerIDField: "0"})
+
+	bundle, err := logic.ExportModelBundle("0", manager.BundleSelector{
+		ClassificationIDs: []string{"bk_private", "bk_middleware"},
+		ObjectIDs:         []string{"switch"},
+	}, nil)
+	require.NoError(t, err)
+
+	clsIDs := make([]string, 0)
+	for _, cls := range bundle.Classifications {
+		clsIDs = append(clsIDs, cls.ClassificationID)
+	}
+	assert.Equal(t, []string{"bk_middleware", "bk_network"}, clsIDs)
+
+	// router is followed by the association of switch
+	objIDs := make([]string, 0)
+	for _, obj := range bundle.Objects {
+		objIDs = append(objIDs, obj.ObjectID)
+	}
+	assert.Equal(t, []string{"redis", "router", "switch"}, objIDs)
+	require.Len(t, bundle.Associations, 1)
+	assert.Equal(t, "router", bundle.Associations[0].AsstObjID)
+
+	_, err = logic.ExportModelBundle("0", manager.BundleSelector{ObjectIDs: []string{"missing"}}, nil)
+	assert.Error(t, err)
+}
@@ -20,6 +20,7 @@ import (
 	api "configcenter/src/source_controller/api/object"
 	"encoding/json"
 	"fmt"
+	"net/http"
 
 	simplejson "github.com/bitly/go-simplejson"
 )
@@ -50,6 +51,11 @@ func (cli *objClsLogic) SetManager(mgr manager.Manager) error {
 	return nil
 }
 
+// WithHeader implement the manager's HeaderScoper interface
+func (cli *objClsLogic) WithHeader(header http.Header, mgr manager.Manager) interface{} {
+	return &objClsLogic{objcli: cli.objcli.WithHeader(header), cfg: cli.cfg, mgr: mgr}
+}
+
 func (cli *objClsLogic) CreateObjectClass(val []byte, errProxy errors.DefaultCCErrorIf) (int, error) {
 
 	var obj sencapi.ObjectClsDes
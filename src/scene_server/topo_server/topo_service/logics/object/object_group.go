@@ -19,6 +19,7 @@ import (
 	api "configcenter/src/source_controller/api/object"
 	"encoding/json"
 	"fmt"
+	"net/http"
 )
 
 type objAttGroupLogic struct {
@@ -47,6 +48,11 @@ func (cli *objAttGroupLogic) SetManager(mgr manager.Manager) error {
 	return nil
 }
 
+// WithHeader implement the manager's HeaderScoper interface
+func (cli *objAttGroupLogic) WithHeader(header http.Header, mgr manager.Manager) interface{} {
+	return &objAttGroupLogic{objcli: cli.objcli.WithHeader(header), cfg: cli.cfg, mgr: mgr}
+}
+
 func (cli *objAttGroupLogic) CreateObjectGroup(data []byte, errProxy errors.DefaultCCErrorIf) (int, error) {
 	cli.objcli.SetAddress(cli.cfg.Get(cli))
 	var selector api.ObjAttGroupDes
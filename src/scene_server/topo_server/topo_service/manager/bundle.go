@@ -0,0 +1,348 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package manager
+
+import (
+	"configcenter/src/source_controller/api/metadata"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ModelBundleFormat the format version of the model bundle, a bundle of another format is rejected
+const ModelBundleFormat = 1
+
+// the formats a model bundle is encoded in
+const (
+	BundleEncodingJSON = "json"
+	BundleEncodingYAML = "yaml"
+)
+
+// the kinds of the items in a model bundle
+const (
+	BundleKindClassification = "classification"
+	BundleKindObject         = "object"
+	BundleKindGroup          = "group"
+	BundleKindAttribute      = "attribute"
+	BundleKindAssociation    = "association"
+)
+
+// the changes the import of a model bundle makes
+const (
+	BundleActionCreate = "create"
+	BundleActionUpdate = "update"
+)
+
+// BundleSelector select the classifications and the objects to export
+type BundleSelector struct {
+	ClassificationIDs []string `json:"bk_classification_id"`
+	ObjectIDs         []string `json:"bk_obj_id"`
+	Version           string   `json:"version"`
+}
+
+// BundleClassification the classification in a model bundle
+type BundleClassification struct {
+	ClassificationID   string `json:"bk_classification_id"`
+	ClassificationName string `json:"bk_classification_name"`
+	ClassificationType string `json:"bk_classification_type"`
+	ClassificationIcon string `json:"bk_classification_icon"`
+}
+
+// BundleObject the object in a model bundle
+type BundleObject struct {
+	ObjectID         string `json:"bk_obj_id"`
+	ObjectName       string `json:"bk_obj_name"`
+	ClassificationID string `json:"bk_classification_id"`
+	ObjIcon          string `json:"bk_obj_icon"`
+	Position         string `json:"position"`
+	Description      string `json:"description"`
+	IsPaused         bool   `json:"bk_ispaused"`
+	IsPre            bool   `json:"ispre"`
+
+	ValidationRules []metadata.ValidationRule `json:"bk_validation_rules,omitempty"`
+}
+
+// BundleGroup the property group in a model bundle
+type BundleGroup struct {
+	ObjectID   string `json:"bk_obj_id"`
+	GroupID    string `json:"bk_group_id"`
+	GroupName  string `json:"bk_group_name"`
+	GroupIndex int    `json:"bk_group_index"`
+	IsDefault  bool   `json:"bk_isdefault"`
+}
+
+// BundleAttribute the attribute in a model bundle
+type BundleAttribute struct {
+	ObjectID      string `json:"bk_obj_id"`
+	PropertyID    string `json:"bk_property_id"`
+	PropertyName  string `json:"bk_property_name"`
+	PropertyGroup string `json:"bk_property_group"`
+	PropertyIndex int    `json:"bk_property_index"`
+	PropertyType  string `json:"bk_property_type"`
+	Option        string `json:"option"`
+	Unit          string `json:"unit"`
+	Placeholder   string `json:"placeholder"`
+	Description   string `json:"description"`
+	Editable      bool   `json:"editable"`
+	IsRequired    bool   `json:"isrequired"`
+	IsReadOnly    bool   `json:"isreadonly"`
+	IsOnly        bool   `json:"isonly"`
+	IsAPI         bool   `json:"bk_isapi"`
+	IsPre         bool   `json:"ispre"`
+}
+
+// BundleAssociation the object association in a model bundle, it belongs to the attribute bk_object_att_id
+type BundleAssociation struct {
+	ObjectID    string `json:"bk_obj_id"`
+	ObjectAttID string `json:"bk_object_att_id"`
+	AsstObjID   string `json:"bk_asst_obj_id"`
+	AsstForward string `json:"bk_asst_forward"`
+	AsstName    string `json:"bk_asst_name"`
+}
+
+// ModelBundle a self-contained set of models, the items are identified by their ids rather than the storage ids
+type ModelBundle struct {
+	Format          int                    `json:"format"`
+	Version         string                 `json:"version"`
+	OwnerID         string                 `json:"bk_supplier_account"`
+	ExportTime      time.Time              `json:"export_time"`
+	Classifications []BundleClassification `json:"classifications"`
+	Objects         []BundleObject         `json:"objects"`
+	Groups          []BundleGroup          `json:"groups"`
+	Attributes      []BundleAttribute      `json:"attributes"`
+	Associations    []BundleAssociation    `json:"associations"`
+}
+
+// BundleFieldChange the value of a field before and after the import
+type BundleFieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// BundleChange a change the import of a model bundle makes
+type BundleChange struct {
+	Kind   string                       `json:"kind"`
+	Key    string                       `json:"key"`
+	Action string                       `json:"action"`
+	Fields map[string]BundleFieldChange `json:"fields,omitempty"`
+}
+
+// BundleImportResult the changes of the import, nothing is changed by a dry run
+type BundleImportResult struct {
+	DryRun    bool           `json:"dry_run"`
+	Changes   []BundleChange `json:"changes"`
+	Unchanged int            `json:"unchanged"`
+}
+
+// Key returns the key of the classification in the bundle
+func (c BundleClassification) Key() string {
+	return c.ClassificationID
+}
+
+// Key returns the key of the object in the bundle
+func (o BundleObject) Key() string {
+	return o.ObjectID
+}
+
+// Key returns the key of the group in the bundle
+func (g BundleGroup) Key() string {
+	return g.ObjectID + "." + g.GroupID
+}
+
+// Key returns the key of the attribute in the bundle
+func (a BundleAttribute) Key() string {
+	return a.ObjectID + "." + a.PropertyID
+}
+
+// Key returns the key of the association in the bundle
+func (a BundleAssociation) Key() string {
+	return a.ObjectID + "." + a.ObjectAttID
+}
+
+// Sort orders the items of the bundle, so the bundles of the same models are the same
+func (b *ModelBundle) Sort() {
+	sort.Slice(b.Classifications, func(i, j int) bool {
+		return b.Classifications[i].Key() < b.Classifications[j].Key()
+	})
+	sort.Slice(b.Objects, func(i, j int) bool {
+		return b.Objects[i].Key() < b.Objects[j].Key()
+	})
+	sort.Slice(b.Groups, func(i, j int) bool {
+		if b.Groups[i].ObjectID != b.Groups[j].ObjectID {
+			return b.Groups[i].ObjectID < b.Groups[j].ObjectID
+		}
+		if b.Groups[i].GroupIndex != b.Groups[j].GroupIndex {
+			return b.Groups[i].GroupIndex < b.Groups[j].GroupIndex
+		}
+		return b.Groups[i].GroupID < b.Groups[j].GroupID
+	})
+	sort.Slice(b.Attributes, func(i, j int) bool {
+		if b.Attributes[i].ObjectID != b.Attributes[j].ObjectID {
+			return b.Attributes[i].ObjectID < b.Attributes[j].ObjectID
+		}
+		if b.Attributes[i].PropertyIndex != b.Attributes[j].PropertyIndex {
+			return b.Attributes[i].PropertyIndex < b.Attributes[j].PropertyIndex
+		}
+		return b.Attributes[i].PropertyID < b.Attributes[j].PropertyID
+	})
+	sort.Slice(b.Associations, func(i, j int) bool {
+		return b.Associations[i].Key() < b.Associations[j].Key()
+	})
+}
+
+// Validate checks the format of the bundle, the keys and the references between the items of the bundle
+func (b *ModelBundle) Validate() error {
+	if ModelBundleFormat != b.Format {
+		return fmt.Errorf("unsupported bundle format %d, expect %d", b.Format, ModelBundleFormat)
+	}
+
+	keys := make(map[string]bool)
+	check := func(kind, key string, ids ...string) error {
+		for _, id := range ids {
+			if "" == id {
+				return fmt.Errorf("the %s[%s] has an empty id", kind, key)
+			}
+		}
+		if keys[kind+":"+key] {
+			return fmt.Errorf("the %s[%s] is repeated", kind, key)
+		}
+		keys[kind+":"+key] = true
+		return nil
+	}
+	for _, item := range b.Classifications {
+		if err := check(BundleKindClassification, item.Key(), item.ClassificationID); nil != err {
+			return err
+		}
+	}
+	for _, item := range b.Objects {
+		if err := check(BundleKindObject, item.Key(), item.ObjectID, item.ClassificationID); nil != err {
+			return err
+		}
+	}
+	for _, item := range b.Groups {
+		if err := check(BundleKindGroup, item.Key(), item.ObjectID, item.GroupID); nil != err {
+			return err
+		}
+	}
+	for _, item := range b.Attributes {
+		if err := check(BundleKindAttribute, item.Key(), item.ObjectID, item.PropertyID); nil != err {
+			return err
+		}
+	}
+	for _, item := range b.Associations {
+		if err := check(BundleKindAssociation, item.Key(), item.ObjectID, item.ObjectAttID, item.AsstObjID); nil != err {
+			return err
+		}
+	}
+
+	// the groups, the attributes and the associations belong to the objects of the bundle
+	for _, item := range b.Groups {
+		if !keys[BundleKindObject+":"+item.ObjectID] {
+			return fmt.Errorf("the object of the group[%s] is not in the bundle", item.Key())
+		}
+	}
+	for _, item := range b.Attributes {
+		if !keys[BundleKindObject+":"+item.ObjectID] {
+			return fmt.Errorf("the object of the attribute[%s] is not in the bundle", item.Key())
+		}
+	}
+	for _, item := range b.Associations {
+		if !keys[BundleKindAttribute+":"+item.Key()] {
+			return fmt.Errorf("the attribute of the association[%s] is not in the bundle", item.Key())
+		}
+	}
+	return nil
+}
+
+// EncodeModelBundle encodes the bundle in json or yaml, the yaml keys are the same as the json ones
+func EncodeModelBundle(bundle *ModelBundle, encoding string) ([]byte, error) {
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if nil != err {
+		return nil, err
+	}
+	switch encoding {
+	case BundleEncodingJSON, "":
+		return data, nil
+	case BundleEncodingYAML:
+		var doc interface{}
+		if err := json.Unmarshal(data, &doc); nil != err {
+			return nil, err
+		}
+		return yaml.Marshal(doc)
+	}
+	return nil, fmt.Errorf("unsupported bundle encoding %s", encoding)
+}
+
+// DecodeModelBundle decodes a bundle encoded in json or yaml
+func DecodeModelBundle(data []byte) (*ModelBundle, error) {
+	// a json document is a yaml document as well
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); nil != err {
+		return nil, err
+	}
+	data, err := json.Marshal(stringKeys(doc))
+	if nil != err {
+		return nil, err
+	}
+	bundle := &ModelBundle{}
+	if err := json.Unmarshal(data, bundle); nil != err {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// stringKeys converts the maps decoded by yaml to the maps json is able to encode
+func stringKeys(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for key, item := range val {
+			out[fmt.Sprint(key)] = stringKeys(item)
+		}
+		return out
+	case []interface{}:
+		for idx, item := range val {
+			val[idx] = stringKeys(item)
+		}
+		return val
+	}
+	return v
+}
+
+// DiffBundleItem returns the fields of the bundle item which differ from the current one, the fields in
+// ignore are not compared
+func DiffBundleItem(current, item interface{}, ignore ...string) map[string]BundleFieldChange {
+	from, to := itemFields(current), itemFields(item)
+	for _, field := range ignore {
+		delete(from, field)
+		delete(to, field)
+	}
+	fields := make(map[string]BundleFieldChange)
+	for field, value := range to {
+		if !reflect.DeepEqual(from[field], value) {
+			fields[field] = BundleFieldChange{From: from[field], To: value}
+		}
+	}
+	return fields
+}
+
+func itemFields(item interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	data, _ := json.Marshal(item)
+	json.Unmarshal(data, &fields)
+	return fields
+}
@@ -0,0 +1,108 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package manager
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBundle() *ModelBundle {
+	return &ModelBundle{
+		Format:  ModelBundleFormat,
+		Version: "1.0.0",
+		OwnerID: "0",
+		Classifications: []BundleClassification{
+			{ClassificationID: "bk_network", ClassificationName: "network"},
+		},
+		Objects: []BundleObject{
+			{ObjectID: "switch", ObjectName: "switch", ClassificationID: "bk_network"},
+			{ObjectID: "router", ObjectName: "router", ClassificationID: "bk_network"},
+		},
+		Groups: []BundleGroup{
+			{ObjectID: "switch", GroupID: "default", GroupName: "Default", GroupIndex: -1, IsDefault: true},
+		},
+		Attributes: []BundleAttribute{
+			{ObjectID: "switch", PropertyID: "uplink", PropertyName: "uplink", PropertyType: "singleasst", PropertyIndex: 2},
+			{ObjectID: "switch", PropertyID: "bk_inst_name", PropertyName: "name", PropertyType: "singlechar", PropertyIndex: -1, IsPre: true},
+		},
+		Associations: []BundleAssociation{
+			{ObjectID: "switch", ObjectAttID: "uplink", AsstObjID: "router"},
+		},
+	}
+}
+
+func TestModelBundleEncoding(t *testing.T) {
+	bundle := testBundle()
+	bundle.Sort()
+	assert.Equal(t, "router", bundle.Objects[0].ObjectID)
+	assert.Equal(t, "bk_inst_name", bundle.Attributes[0].PropertyID)
+
+	for _, encoding := range []string{BundleEncodingJSON, BundleEncodingYAML} {
+		data, err := EncodeModelBundle(bundle, encoding)
+		require.NoError(t, err)
+		decoded, err := DecodeModelBundle(data)
+		require.NoError(t, err)
+		assert.Equal(t, bundle, decoded, encoding)
+	}
+
+	data, err := EncodeModelBundle(bundle, BundleEncodingYAML)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "bk_obj_id: switch")
+
+	_, err = EncodeModelBundle(bundle, "xml")
+	assert.Error(t, err)
+}
+
+func TestModelBundleValidate(t *testing.T) {
+	require.NoError(t, testBundle().Validate())
+
+	bundle := testBundle()
+	bundle.Format = 2
+	assert.Error(t, bundle.Validate())
+
+	bundle = testBundle()
+	bundle.Objects = append(bundle.Objects, bundle.Objects[0])
+	err := bundle.Validate()
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "repeated"))
+
+	bundle = testBundle()
+	bundle.Attributes[0].ObjectID = "firewall"
+	assert.Error(t, bundle.Validate())
+
+	bundle = testBundle()
+	bundle.Associations[0].ObjectAttID = "downlink"
+	assert.Error(t, bundle.Validate())
+
+	bundle = testBundle()
+	bundle.Groups[0].GroupID = ""
+	assert.Error(t, bundle.Validate())
+}
+
+func TestDiffBundleItem(t *testing.T) {
+	current := BundleAttribute{ObjectID: "switch", PropertyID: "uplink", PropertyName: "uplink", PropertyIndex: 2}
+	item := current
+	assert.Empty(t, DiffBundleItem(current, item))
+
+	item.PropertyName = "up link"
+	item.PropertyIndex = 3
+	item.IsRequired = true
+	assert.Equal(t, map[string]BundleFieldChange{
+		"bk_property_name": {From: "uplink", To: "up link"},
+		"isrequired":       {From: false, To: true},
+	}, DiffBundleItem(current, item, "bk_property_index"))
+}
@@ -16,6 +16,7 @@ import (
 	"configcenter/src/common/errors"
 	api "configcenter/src/source_controller/api/object"
 	"fmt"
+	"net/http"
 )
 
 var mgr = &topoMgr{logics: make(map[string]interface{})}
@@ -57,6 +58,18 @@ func (cli *topoMgr) Get(target interface{}) string {
 	return cli.objctr() // TODO: need to delete
 }
 
+// WithHeader implement the Manager interface
+func (cli *topoMgr) WithHeader(header http.Header) Manager {
+	scoped := &topoMgr{objctr: cli.objctr, logics: make(map[string]interface{}, len(cli.logics))}
+	for logicName, logic := range cli.logics {
+		if t, ok := logic.(HeaderScoper); ok {
+			logic = t.WithHeader(header, scoped)
+		}
+		scoped.logics[logicName] = logic
+	}
+	return scoped
+}
+
 // object asst interface
 func (cli *topoMgr) CreateObjectAsst(obj map[string]interface{}, errProxy errors.DefaultCCErrorIf) (int, error) {
 	target := cli.logics[ObjectAsst].(ObjectAsstLogic)
@@ -190,3 +203,15 @@ func (cli *topoMgr) DeleteObject(id int, params []byte, errProxy errors.DefaultC
 	target := cli.logics[Object].(ObjectLogic)
 	return target.DeleteObject(id, params, errProxy)
 }
+
+// ExportModelBundle export the selected models as a bundle
+func (cli *topoMgr) ExportModelBundle(ownerID string, selector BundleSelector, errProxy errors.DefaultCCErrorIf) (*ModelBundle, error) {
+	target := cli.logics[ObjectBundle].(ModelBundleLogic)
+	return target.ExportModelBundle(ownerID, selector, errProxy)
+}
+
+// ImportModelBundle import the models of the bundle
+func (cli *topoMgr) ImportModelBundle(ownerID string, bundle *ModelBundle, dryRun bool, errProxy errors.DefaultCCErrorIf) (*BundleImportResult, error) {
+	target := cli.logics[ObjectBundle].(ModelBundleLogic)
+	return target.ImportModelBundle(ownerID, bundle, dryRun, errProxy)
+}
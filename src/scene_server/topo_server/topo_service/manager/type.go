@@ -15,6 +15,7 @@ package manager
 import (
 	"configcenter/src/common/errors"
 	api "configcenter/src/source_controller/api/object"
+	"net/http"
 )
 
 // ObjectAsst const definition
@@ -32,6 +33,9 @@ const ObjectGroup = "object_group"
 // Object const definition
 const Object = "object"
 
+// ObjectBundle const definition
+const ObjectBundle = "object_bundle"
+
 // TopoModelRsp 拓扑模型结构
 type TopoModelRsp struct {
 	ObjID      string `json:"bk_obj_id"`
@@ -105,6 +109,12 @@ type ObjectAttGroupLogic interface {
 	SelectPropertyGroupByObjectID(ownerID, objectID string, data []byte, errProxy errors.DefaultCCErrorIf) ([]api.ObjAttGroupDes, error)
 }
 
+// ModelBundleLogic define the logic interface
+type ModelBundleLogic interface {
+	ExportModelBundle(ownerID string, selector BundleSelector, errProxy errors.DefaultCCErrorIf) (*ModelBundle, error)
+	ImportModelBundle(ownerID string, bundle *ModelBundle, dryRun bool, errProxy errors.DefaultCCErrorIf) (*BundleImportResult, error)
+}
+
 // Manager define manager interface
 type Manager interface {
 
@@ -122,6 +132,12 @@ type Manager interface {
 
 	// object attribute group interface
 	ObjectAttGroupLogic
+
+	// model bundle interface
+	ModelBundleLogic
+
+	// WithHeader returns a copy of the manager whose logics send the header to the controllers
+	WithHeader(header http.Header) Manager
 }
 
 // Hooker define callback hook
@@ -138,3 +154,8 @@ type Configer interface {
 type SetConfiger interface {
 	Set(cfg Configer)
 }
+
+// HeaderScoper define the logic which is able to send the header to the controllers
+type HeaderScoper interface {
+	WithHeader(header http.Header, mgr Manager) interface{}
+}
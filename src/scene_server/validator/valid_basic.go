@@ -16,6 +16,7 @@ import (
 	"configcenter/src/common"
 	"configcenter/src/common/blog"
 	"configcenter/src/common/errors"
+	"configcenter/src/common/fieldtype"
 	"configcenter/src/common/http/httpclient"
 	"configcenter/src/common/util"
 	"encoding/json"
@@ -74,7 +75,9 @@ func NewValidMapWithKeyFileds(ownerID, objID, objCtrl string, keyFileds []string
 func (valid *ValidMap) ValidMap(valData map[string]interface{}, validType string, instID int) (bool, error) {
 	valRule := NewValRule(valid.ownerID, valid.objCtrl)
 
-	valRule.GetObjAttrByID(valid.objID)
+	if err := valRule.GetObjAttrByID(valid.objID); nil != err {
+		return false, valid.ccError.Error(common.CCErrCommHTTPDoRequestFailed)
+	}
 	valid.IsRequireArr = valRule.IsRequireArr
 	valid.IsOnlyArr = valRule.IsOnlyArr
 	valid.PropertyKv = valRule.PropertyKv
@@ -156,6 +159,9 @@ func (valid *ValidMap) ValidMap(valData map[string]interface{}, validType string
 			result, err = valid.validTimeZone(val, key)
 		case common.FiledTypeBool:
 			result, err = valid.validBool(val, key)
+		case common.FieldTypeFloat, common.FieldTypeIPv4, common.FieldTypeIPv6, common.FieldTypeCIDR,
+			common.FieldTypeURL, common.FieldTypeEmail, common.FieldTypeList, common.FieldTypeJSON:
+			result, err = valid.validExtended(valData, key, fieldType, option)
 		default:
 			continue
 		}
@@ -176,6 +182,10 @@ func (valid *ValidMap) ValidMap(valData map[string]interface{}, validType string
 			return false, valid.ccError.Errorf(common.CCErrCommParamsLostField, keyStr)
 		}
 	}
+	//valid the rules of the model
+	if result, err = valid.validRules(valData, valRule.Rules, validType, instID); !result {
+		return result, err
+	}
 	//fmt.Printf("valdata:%+v\n", valData)
 	//valid unique
 	if validType == common.ValidCreate {
@@ -642,3 +652,26 @@ func (valid *ValidMap) validTime(val interface{}, key string) (bool, error) {
 	return true, nil
 
 }
+
+// valid the extended types, the value is replaced by the normalized one to store
+func (valid *ValidMap) validExtended(valData map[string]interface{}, key, fieldType, option string) (bool, error) {
+	val := valData[key]
+	if nil == val || "" == val {
+		if util.InArray(key, valid.IsRequireArr) {
+			blog.Error("params can not be empty")
+			return false, valid.ccError.Errorf(common.CCErrCommParamsNeedSet, key)
+		}
+		return true, nil
+	}
+	value, err := fieldtype.Normalize(fieldType, option, val)
+	if nil != err {
+		blog.Errorf("params %s is not a valid %s, error info is %s", key, fieldType, err.Error())
+		return false, valid.ccError.Errorf(common.CCErrCommParamsInvalid, key)
+	}
+	if items, ok := value.([]interface{}); ok && 0 == len(items) && util.InArray(key, valid.IsRequireArr) {
+		blog.Error("params can not be empty")
+		return false, valid.ccError.Errorf(common.CCErrCommParamsNeedSet, key)
+	}
+	valData[key] = value
+	return true, nil
+}
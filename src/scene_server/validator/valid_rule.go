@@ -0,0 +1,442 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package validator
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/http/httpclient"
+	"configcenter/src/common/util"
+	"configcenter/src/source_controller/api/metadata"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// the operators of the conditions of the validation rules
+var ruleConditionOperators = map[string]bool{
+	common.BKDBEQ:     true,
+	common.BKDBNE:     true,
+	common.BKDBIN:     true,
+	common.BKDBNIN:    true,
+	common.BKDBExists: true,
+}
+
+// the operators of the comparisons of the validation rules
+var ruleCompareOperators = map[string]bool{
+	common.BKDBEQ:  true,
+	common.BKDBNE:  true,
+	common.BKDBGT:  true,
+	common.BKDBGTE: true,
+	common.BKDBLT:  true,
+	common.BKDBLTE: true,
+}
+
+// CheckValidationRules checks the validation rules of an object, the fields of the rules must be the attributes of
+// fieldTypes unless it is nil
+func CheckValidationRules(rules []metadata.ValidationRule, fieldTypes map[string]string) error {
+	checkField := func(id, field string) error {
+		if "" == field {
+			return fmt.Errorf("the rule %s has an empty field", id)
+		}
+		if nil == fieldTypes {
+			return nil
+		}
+		if _, ok := fieldTypes[field]; !ok {
+			return fmt.Errorf("the field %s of the rule %s is not an attribute of the object", field, id)
+		}
+		return nil
+	}
+
+	ids := make(map[string]bool)
+	for _, rule := range rules {
+		if "" == rule.ID {
+			return fmt.Errorf("the rule id is not set")
+		}
+		if ids[rule.ID] {
+			return fmt.Errorf("the rule id %s is duplicated", rule.ID)
+		}
+		ids[rule.ID] = true
+
+		for _, cond := range rule.When {
+			if err := checkField(rule.ID, cond.Field); nil != err {
+				return err
+			}
+			if !ruleConditionOperators[cond.Operator] {
+				return fmt.Errorf("the condition of the rule %s can not be %s", rule.ID, cond.Operator)
+			}
+			switch cond.Operator {
+			case common.BKDBIN, common.BKDBNIN:
+				if nil == cond.Value || reflect.Slice != reflect.TypeOf(cond.Value).Kind() {
+					return fmt.Errorf("the value of %s in the rule %s should be an array", cond.Operator, rule.ID)
+				}
+			case common.BKDBExists:
+				if _, ok := cond.Value.(bool); !ok {
+					return fmt.Errorf("the value of %s in the rule %s should be a bool", cond.Operator, rule.ID)
+				}
+			}
+		}
+
+		assertions := 0
+		for _, fields := range [][]string{rule.Require, rule.Forbid, rule.AtMostOne, rule.AtLeastOne} {
+			if 0 == len(fields) {
+				continue
+			}
+			assertions++
+			for _, field := range fields {
+				if err := checkField(rule.ID, field); nil != err {
+					return err
+				}
+			}
+		}
+		if 1 == len(rule.AtMostOne) || 1 == len(rule.AtLeastOne) {
+			return fmt.Errorf("the rule %s should list two fields at least", rule.ID)
+		}
+		if nil != rule.Compare {
+			assertions++
+			cmp := rule.Compare
+			if err := checkField(rule.ID, cmp.Field); nil != err {
+				return err
+			}
+			if !ruleCompareOperators[cmp.Operator] {
+				return fmt.Errorf("the comparison of the rule %s can not be %s", rule.ID, cmp.Operator)
+			}
+			if ("" == cmp.Other) == !isRuleValueSet(cmp.Value) {
+				return fmt.Errorf("the rule %s should compare with either the other field or the value", rule.ID)
+			}
+			if "" != cmp.Other {
+				if err := checkField(rule.ID, cmp.Other); nil != err {
+					return err
+				}
+			}
+		}
+		if 1 != assertions {
+			return fmt.Errorf("the rule %s should have one and only one of require, forbid, at_most_one, at_least_one and compare", rule.ID)
+		}
+	}
+	return nil
+}
+
+// BrokenValidationRule returns the first rule the data breaks, nil if the data passes all the rules
+func BrokenValidationRule(rules []metadata.ValidationRule, data map[string]interface{}) *metadata.ValidationRule {
+	for idx := range rules {
+		if !matchRule(rules[idx], data) {
+			return &rules[idx]
+		}
+	}
+	return nil
+}
+
+// BrokenRuleFields returns the fields breaking the rule, the unset required fields for example
+func BrokenRuleFields(rule metadata.ValidationRule, data map[string]interface{}) []string {
+	fields := make([]string, 0)
+	pick := func(candidates []string, set bool) {
+		for _, field := range candidates {
+			if set == isRuleValueSet(data[field]) {
+				fields = append(fields, field)
+			}
+		}
+	}
+	switch {
+	case 0 != len(rule.Require):
+		pick(rule.Require, false)
+	case 0 != len(rule.Forbid):
+		pick(rule.Forbid, true)
+	case 0 != len(rule.AtMostOne):
+		pick(rule.AtMostOne, true)
+	case 0 != len(rule.AtLeastOne):
+		fields = append(fields, rule.AtLeastOne...)
+	case nil != rule.Compare:
+		fields = append(fields, rule.Compare.Field)
+		if "" != rule.Compare.Other {
+			fields = append(fields, rule.Compare.Other)
+		}
+	}
+	return fields
+}
+
+// ruleFields returns the fields the rule reads
+func ruleFields(rule metadata.ValidationRule) []string {
+	fields := make([]string, 0)
+	for _, cond := range rule.When {
+		fields = append(fields, cond.Field)
+	}
+	fields = append(fields, rule.Require...)
+	fields = append(fields, rule.Forbid...)
+	fields = append(fields, rule.AtMostOne...)
+	fields = append(fields, rule.AtLeastOne...)
+	if nil != rule.Compare {
+		fields = append(fields, rule.Compare.Field)
+		if "" != rule.Compare.Other {
+			fields = append(fields, rule.Compare.Other)
+		}
+	}
+	return fields
+}
+
+// matchRule reports whether the data passes the rule, the rule passes if any of its conditions does not match
+func matchRule(rule metadata.ValidationRule, data map[string]interface{}) bool {
+	for _, cond := range rule.When {
+		if !matchCondition(cond, data) {
+			return true
+		}
+	}
+
+	countSet := func(fields []string) int {
+		cnt := 0
+		for _, field := range fields {
+			if isRuleValueSet(data[field]) {
+				cnt++
+			}
+		}
+		return cnt
+	}
+	switch {
+	case 0 != len(rule.Require):
+		return len(rule.Require) == countSet(rule.Require)
+	case 0 != len(rule.Forbid):
+		return 0 == countSet(rule.Forbid)
+	case 0 != len(rule.AtMostOne):
+		return 1 >= countSet(rule.AtMostOne)
+	case 0 != len(rule.AtLeastOne):
+		return 1 <= countSet(rule.AtLeastOne)
+	case nil != rule.Compare:
+		cmp := rule.Compare
+		val, other := data[cmp.Field], cmp.Value
+		if "" != cmp.Other {
+			other = data[cmp.Other]
+		}
+		// the unset fields are left to the required checks
+		if !isRuleValueSet(val) || !isRuleValueSet(other) {
+			return true
+		}
+		result, ok := compareRuleValues(val, other)
+		if !ok {
+			return common.BKDBNE == cmp.Operator
+		}
+		switch cmp.Operator {
+		case common.BKDBEQ:
+			return 0 == result
+		case common.BKDBNE:
+			return 0 != result
+		case common.BKDBGT:
+			return 0 < result
+		case common.BKDBGTE:
+			return 0 <= result
+		case common.BKDBLT:
+			return 0 > result
+		case common.BKDBLTE:
+			return 0 >= result
+		}
+	}
+	return true
+}
+
+func matchCondition(cond metadata.RuleCondition, data map[string]interface{}) bool {
+	val := data[cond.Field]
+	switch cond.Operator {
+	case common.BKDBExists:
+		exists, _ := cond.Value.(bool)
+		return exists == isRuleValueSet(val)
+	case common.BKDBEQ:
+		return equalRuleValues(val, cond.Value)
+	case common.BKDBNE:
+		return !equalRuleValues(val, cond.Value)
+	case common.BKDBIN, common.BKDBNIN:
+		in := false
+		if items := reflect.ValueOf(cond.Value); reflect.Slice == items.Kind() {
+			for i := 0; i < items.Len(); i++ {
+				if equalRuleValues(val, items.Index(i).Interface()) {
+					in = true
+					break
+				}
+			}
+		}
+		return in == (common.BKDBIN == cond.Operator)
+	}
+	return false
+}
+
+// isRuleValueSet reports whether the field is set, the null values, the blank strings and the empty lists and
+// objects are not
+func isRuleValueSet(val interface{}) bool {
+	if nil == val {
+		return false
+	}
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.String:
+		return "" != strings.TrimSpace(rv.String())
+	case reflect.Slice, reflect.Map:
+		return 0 != rv.Len()
+	case reflect.Ptr, reflect.Interface:
+		return !rv.IsNil()
+	}
+	return true
+}
+
+func equalRuleValues(val, other interface{}) bool {
+	if !isRuleValueSet(val) || !isRuleValueSet(other) {
+		return isRuleValueSet(val) == isRuleValueSet(other)
+	}
+	result, ok := compareRuleValues(val, other)
+	return ok && 0 == result
+}
+
+// compareRuleValues compares the numbers by their values and the strings by their bytes, the dates and the times
+// are in the fixed width formats so they are ordered as strings, ok is false if the values are not comparable
+func compareRuleValues(val, other interface{}) (result int, ok bool) {
+	num, isNum := ruleNumber(val, false)
+	otherNum, isOtherNum := ruleNumber(other, false)
+	switch {
+	case isNum && !isOtherNum:
+		otherNum, isOtherNum = ruleNumber(other, true)
+	case !isNum && isOtherNum:
+		num, isNum = ruleNumber(val, true)
+	}
+	if isNum && isOtherNum {
+		switch {
+		case num < otherNum:
+			return -1, true
+		case num > otherNum:
+			return 1, true
+		}
+		return 0, true
+	}
+
+	str, isStr := val.(string)
+	otherStr, isOtherStr := other.(string)
+	if isStr && isOtherStr {
+		return strings.Compare(strings.TrimSpace(str), strings.TrimSpace(otherStr)), true
+	}
+	if reflect.DeepEqual(val, other) {
+		return 0, true
+	}
+	return 0, false
+}
+
+// ruleNumber returns the number of the value, the numeric strings are numbers only if parseString is set
+func ruleNumber(val interface{}, parseString bool) (float64, bool) {
+	switch v := val.(type) {
+	case json.Number:
+		num, err := v.Float64()
+		return num, nil == err
+	case string:
+		if !parseString {
+			return 0, false
+		}
+		num, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return num, nil == err
+	}
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// valid the validation rules of the model
+func (valid *ValidMap) validRules(valData map[string]interface{}, rules []metadata.ValidationRule, validType string, instID int) (bool, error) {
+	if 0 == len(rules) {
+		return true, nil
+	}
+
+	data := valData
+	if common.ValidUpdate == validType {
+		// only the rules reading the updated fields are checked, the instances saved before the rules are kept
+		updated := make([]metadata.ValidationRule, 0, len(rules))
+		for _, rule := range rules {
+			for _, field := range ruleFields(rule) {
+				if _, ok := valData[field]; ok {
+					updated = append(updated, rule)
+					break
+				}
+			}
+		}
+		if 0 == len(updated) {
+			return true, nil
+		}
+		rules = updated
+
+		// the rules read the stored fields the update leaves
+		if 0 < instID {
+			inst, err := valid.getInst(instID)
+			if nil != err {
+				return false, err
+			}
+			data = make(map[string]interface{}, len(inst)+len(valData))
+			for key, val := range inst {
+				data[key] = val
+			}
+			for key, val := range valData {
+				data[key] = val
+			}
+		}
+	}
+
+	if rule := BrokenValidationRule(rules, data); nil != rule {
+		fields := strings.Join(BrokenRuleFields(*rule, data), ",")
+		blog.Errorf("the data breaks the validation rule %s of %s, fields: %s", rule.ID, valid.objID, fields)
+		return false, valid.ccError.Errorf(common.CCErrCommValidationRuleFailed, rule.ID, fields)
+	}
+	return true, nil
+}
+
+// get the stored instance
+func (valid *ValidMap) getInst(instID int) (map[string]interface{}, error) {
+	urlID := valid.objID
+	searchCond := map[string]interface{}{util.GetObjIDByType(valid.objID): instID}
+	if !util.InArray(valid.objID, innerObject) {
+		urlID = "object"
+		searchCond[common.BKObjIDField] = valid.objID
+	}
+	info, _ := json.Marshal(map[string]interface{}{"condition": searchCond})
+	httpCli := httpclient.NewHttpClient()
+	httpCli.SetHeader("Content-Type", "application/json")
+	httpCli.SetHeader("Accept", "application/json")
+	url := fmt.Sprintf("%s/object/v1/insts/%s/search", valid.objCtrl, urlID)
+	if !strings.HasPrefix(url, "http://") {
+		url = fmt.Sprintf("http://%s", url)
+	}
+	rst, err := httpCli.POST(url, nil, info)
+	if nil != err {
+		blog.Error("request failed, error:%v", err)
+		return nil, valid.ccError.Error(common.CCErrCommHTTPDoRequestFailed)
+	}
+
+	var rstRes struct {
+		Result bool `json:"result"`
+		Data   struct {
+			Info []map[string]interface{} `json:"info"`
+		} `json:"data"`
+	}
+	if jserr := json.Unmarshal(rst, &rstRes); nil != jserr {
+		blog.Error("can not unmarshal the result , error information is %v", jserr)
+		return nil, valid.ccError.Error(common.CCErrCommJSONUnmarshalFailed)
+	}
+	if !rstRes.Result {
+		blog.Error("failed to get the instance %d of %s, reply: %s", instID, valid.objID, string(rst))
+		return nil, valid.ccError.Error(common.CCErrCommDBSelectFailed)
+	}
+	if 0 == len(rstRes.Data.Info) {
+		return map[string]interface{}{}, nil
+	}
+	return rstRes.Data.Info[0], nil
+}
@@ -0,0 +1,119 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package validator
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/source_controller/api/metadata"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseRules(t *testing.T, data string) []metadata.ValidationRule {
+	rules := make([]metadata.ValidationRule, 0)
+	require.NoError(t, json.Unmarshal([]byte(data), &rules))
+	return rules
+}
+
+func TestCheckValidationRules(t *testing.T) {
+	fieldTypes := map[string]string{
+		"bk_os_type":    common.FiledTypeEnum,
+		"bk_os_version": common.FiledTypeSingleChar,
+		"start_date":    common.FiledTypeDate,
+		"end_date":      common.FiledTypeDate,
+		"x":             common.FiledTypeSingleChar,
+		"y":             common.FiledTypeSingleChar,
+	}
+	rules := parseRules(t, `[
+		{"id": "windows_version", "when": [{"field": "bk_os_type", "operator": "$eq", "value": "2"}], "require": ["bk_os_version"]},
+		{"id": "date_order", "compare": {"field": "end_date", "operator": "$gt", "other": "start_date"}},
+		{"id": "x_or_y", "at_most_one": ["x", "y"]},
+		{"id": "since", "when": [{"field": "x", "operator": "$exists", "value": true}], "compare": {"field": "start_date", "operator": "$gte", "value": "2018-01-01"}}
+	]`)
+	require.NoError(t, CheckValidationRules(rules, fieldTypes))
+	// the fields are not checked without the attributes
+	require.NoError(t, CheckValidationRules(parseRules(t, `[{"id": "a", "forbid": ["unknown"]}]`), nil))
+
+	for _, data := range []string{
+		`[{"require": ["x"]}]`,
+		`[{"id": "a", "require": ["x"]}, {"id": "a", "forbid": ["y"]}]`,
+		`[{"id": "a"}]`,
+		`[{"id": "a", "require": ["x"], "forbid": ["y"]}]`,
+		`[{"id": "a", "require": ["unknown"]}]`,
+		`[{"id": "a", "at_least_one": ["x"]}]`,
+		`[{"id": "a", "when": [{"field": "x", "operator": "$regex", "value": "a"}], "require": ["y"]}]`,
+		`[{"id": "a", "when": [{"field": "x", "operator": "$in", "value": "a"}], "require": ["y"]}]`,
+		`[{"id": "a", "when": [{"field": "x", "operator": "$exists", "value": "true"}], "require": ["y"]}]`,
+		`[{"id": "a", "compare": {"field": "end_date", "operator": "$like", "other": "start_date"}}]`,
+		`[{"id": "a", "compare": {"field": "end_date", "operator": "$gt"}}]`,
+		`[{"id": "a", "compare": {"field": "end_date", "operator": "$gt", "other": "start_date", "value": "2018-01-01"}}]`,
+	} {
+		assert.Error(t, CheckValidationRules(parseRules(t, data), fieldTypes), data)
+	}
+}
+
+func TestBrokenValidationRule(t *testing.T) {
+	rules := parseRules(t, `[
+		{"id": "windows_version", "when": [{"field": "bk_os_type", "operator": "$in", "value": ["2", "3"]}], "require": ["bk_os_version"]},
+		{"id": "date_order", "compare": {"field": "end_date", "operator": "$gt", "other": "start_date"}},
+		{"id": "x_or_y", "at_most_one": ["x", "y"]},
+		{"id": "small", "when": [{"field": "size", "operator": "$ne", "value": 0}], "compare": {"field": "size", "operator": "$lte", "value": 10}}
+	]`)
+
+	for _, c := range []struct {
+		data   map[string]interface{}
+		broken string
+	}{
+		{map[string]interface{}{}, ""},
+		{map[string]interface{}{"bk_os_type": "1"}, ""},
+		{map[string]interface{}{"bk_os_type": "2", "bk_os_version": "2012"}, ""},
+		{map[string]interface{}{"bk_os_type": "3", "bk_os_version": " "}, "windows_version"},
+		{map[string]interface{}{"bk_os_type": "2"}, "windows_version"},
+		{map[string]interface{}{"start_date": "2018-01-01", "end_date": "2018-02-01"}, ""},
+		{map[string]interface{}{"start_date": "2018-01-01"}, ""},
+		{map[string]interface{}{"start_date": "2018-01-01", "end_date": "2018-01-01"}, "date_order"},
+		{map[string]interface{}{"x": "a", "y": ""}, ""},
+		{map[string]interface{}{"x": "a", "y": []interface{}{"b"}}, "x_or_y"},
+		{map[string]interface{}{"size": 0}, ""},
+		{map[string]interface{}{"size": "8"}, ""},
+		{map[string]interface{}{"size": json.Number("10")}, ""},
+		{map[string]interface{}{"size": 10.5}, "small"},
+		{map[string]interface{}{"size": "large"}, "small"},
+	} {
+		rule := BrokenValidationRule(rules, c.data)
+		if "" == c.broken {
+			assert.Nil(t, rule, "%v", c.data)
+			continue
+		}
+		if assert.NotNil(t, rule, "%v", c.data) {
+			assert.Equal(t, c.broken, rule.ID, "%v", c.data)
+		}
+	}
+}
+
+func TestBrokenRuleFields(t *testing.T) {
+	rules := parseRules(t, `[
+		{"id": "required", "require": ["a", "b", "c"]},
+		{"id": "forbidden", "forbid": ["a", "b"]},
+		{"id": "x_or_y", "at_most_one": ["x", "y", "z"]},
+		{"id": "date_order", "compare": {"field": "end_date", "operator": "$gt", "other": "start_date"}}
+	]`)
+	data := map[string]interface{}{"a": "1", "c": " ", "x": "1", "z": "2"}
+	assert.Equal(t, []string{"b", "c"}, BrokenRuleFields(rules[0], data))
+	assert.Equal(t, []string{"a"}, BrokenRuleFields(rules[1], data))
+	assert.Equal(t, []string{"x", "z"}, BrokenRuleFields(rules[2], data))
+	assert.Equal(t, []string{"end_date", "start_date"}, BrokenRuleFields(rules[3], data))
+}
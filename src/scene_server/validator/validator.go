@@ -17,6 +17,7 @@ import (
 	"configcenter/src/common/blog"
 	_ "configcenter/src/common/blog"
 	_ "configcenter/src/common/http/httpclient"
+	"configcenter/src/source_controller/api/metadata"
 	api "configcenter/src/source_controller/api/object"
 	"encoding/json"
 	_ "fmt"
@@ -29,6 +30,7 @@ type ValRule struct {
 	NoEnumFiledArr []string
 	PropertyKv     map[string]string
 	FieldRule      map[string]map[string]interface{}
+	Rules          []metadata.ValidationRule
 	ownerID        string
 	objID          string
 	objCtrl        string
@@ -80,6 +82,16 @@ func (valid *ValRule) GetObjAttrByID(objID string) error {
 		valid.PropertyKv[propertyID] = propertyName
 	}
 	valid.FieldRule = fieldRule
+
+	// the validation rules of the model
+	objs, err := client.SearchMetaObject([]byte(info))
+	if nil != err {
+		blog.Errorf("failed to search the object %s, error info is %s", objID, err.Error())
+		return err
+	}
+	for _, obj := range objs {
+		valid.Rules = append(valid.Rules, obj.ValidationRules...)
+	}
 	return nil
 
 }
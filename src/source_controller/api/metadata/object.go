@@ -82,6 +82,36 @@ type ObjectDes struct {
 	CreateTime  *time.Time `bson:"create_time"       json:"create_time"`
 	LastTime    *time.Time `bson:"last_time"         json:"last_time"`
 	Page        *BasePage  `bson:"-"                    json:"page,omitempty"`
+
+	ValidationRules []ValidationRule `bson:"bk_validation_rules" json:"bk_validation_rules"`
+}
+
+// ValidationRule the model-level rule the instances of the object are validated by besides the attributes, the
+// assertion applies when all the conditions in when match, one and only one assertion is set on a rule
+type ValidationRule struct {
+	ID         string          `bson:"id"           json:"id"`
+	When       []RuleCondition `bson:"when"         json:"when,omitempty"`
+	Require    []string        `bson:"require"      json:"require,omitempty"`
+	Forbid     []string        `bson:"forbid"       json:"forbid,omitempty"`
+	AtMostOne  []string        `bson:"at_most_one"  json:"at_most_one,omitempty"`
+	AtLeastOne []string        `bson:"at_least_one" json:"at_least_one,omitempty"`
+	Compare    *RuleComparison `bson:"compare"      json:"compare,omitempty"`
+}
+
+// RuleCondition the condition on a field of the instance, the operator is one of $eq, $ne, $in, $nin and $exists
+type RuleCondition struct {
+	Field    string      `bson:"field"    json:"field"`
+	Operator string      `bson:"operator" json:"operator"`
+	Value    interface{} `bson:"value"    json:"value"`
+}
+
+// RuleComparison compares a field with the other field or the value, the operator is one of $eq, $ne, $gt, $gte,
+// $lt and $lte
+type RuleComparison struct {
+	Field    string      `bson:"field"    json:"field"`
+	Operator string      `bson:"operator" json:"operator"`
+	Other    string      `bson:"other"    json:"other,omitempty"`
+	Value    interface{} `bson:"value"    json:"value,omitempty"`
 }
 
 // TableName return the table name
@@ -96,6 +126,7 @@ type ObjClassification struct {
 	ClassificationName string    `bson:"bk_classification_name"  json:"bk_classification_name"`
 	ClassificationType string    `bson:"bk_classification_type"  json:"bk_classification_type"`
 	ClassificationIcon string    `bson:"bk_classification_icon"  json:"bk_classification_icon"`
+	OwnerID            string    `bson:"bk_supplier_account,omitempty" json:"bk_supplier_account,omitempty"` // empty for the shared ones
 	Page               *BasePage `bson:"-"                       json:"page,omitempty"`
 }
 
@@ -151,6 +182,9 @@ type InstAsst struct {
 	ObjectID     string `bson:"bk_obj_id" json:"bk_obj_id"`
 	AsstInstID   int64  `bson:"bk_asst_inst_id" json:"bk_asst_inst_id"`
 	AsstObjectID string `bson:"bk_asst_obj_id" json:"bk_asst_obj_id"`
+	// ObjectAttID the association attribute keeping the association, the earlier associations have none
+	ObjectAttID string `bson:"bk_object_att_id,omitempty" json:"bk_object_att_id,omitempty"`
+	OwnerID     string `bson:"bk_supplier_account,omitempty" json:"bk_supplier_account,omitempty"`
 }
 
 // TableName return the table name
@@ -14,12 +14,14 @@ package object
 
 import (
 	"configcenter/src/common/bkbase"
+	"net/http"
 )
 
 // Client
 type Client struct {
 	base    base.BaseLogic
 	address string
+	header  http.Header
 }
 
 // SetAddress
@@ -41,3 +43,8 @@ func NewClient(address string) *Client {
 
 	return cli
 }
+
+// WithHeader returns a copy of the client which sends the header with the requests
+func (cli *Client) WithHeader(header http.Header) *Client {
+	return &Client{base: cli.base, address: cli.address, header: header}
+}
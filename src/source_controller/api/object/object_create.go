@@ -22,7 +22,7 @@ import (
 // CreateMetaObject 创建元对象, 如果成功则返回 新数据的ID
 func (cli *Client) CreateMetaObject(data []byte) (int, error) {
 
-	rst, err := cli.base.HttpCli.POST(fmt.Sprintf("%s/object/v1/meta/object", cli.address), nil, data)
+	rst, err := cli.base.HttpCli.POST(fmt.Sprintf("%s/object/v1/meta/object", cli.address), cli.header, data)
 	if nil != err {
 		blog.Error("request failed, error:%v", err)
 		return 0, Err_Request_Object
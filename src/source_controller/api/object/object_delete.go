@@ -29,7 +29,7 @@ func (cli *Client) DeleteMetaObject(objID int, data []byte) error {
 		}
 	}
 
-	rst, err := cli.base.HttpCli.DELETE(fmt.Sprintf("%s/object/v1/meta/object/%d", cli.address, objID), nil, data)
+	rst, err := cli.base.HttpCli.DELETE(fmt.Sprintf("%s/object/v1/meta/object/%d", cli.address, objID), cli.header, data)
 
 	if nil != err {
 		return err
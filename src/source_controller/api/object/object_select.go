@@ -22,7 +22,7 @@ import (
 // SearchMetaobject 查询元数据对象集合
 func (cli *Client) SearchMetaObject(data []byte) ([]ObjDes, error) {
 
-	rst, err := cli.base.HttpCli.POST(fmt.Sprintf("%s/object/v1/meta/objects", cli.address), nil, data)
+	rst, err := cli.base.HttpCli.POST(fmt.Sprintf("%s/object/v1/meta/objects", cli.address), cli.header, data)
 
 	if nil != err {
 		blog.Error("request failed, error:%v", err)
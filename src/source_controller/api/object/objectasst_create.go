@@ -26,7 +26,7 @@ func (cli *Client) CreateMetaObjectAsst(data []byte) (int, error) {
 		return 0, Err_Not_Set_Input
 	}
 	blog.Debug("object asst data: %s", string(data))
-	rst, err := cli.base.HttpCli.POST(fmt.Sprintf("%s/object/v1/meta/objectasst", cli.address), nil, data)
+	rst, err := cli.base.HttpCli.POST(fmt.Sprintf("%s/object/v1/meta/objectasst", cli.address), cli.header, data)
 	if nil != err {
 		blog.Error("request failed, error:%v", err)
 		return 0, Err_Request_Object
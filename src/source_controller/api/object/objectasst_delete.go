@@ -29,7 +29,7 @@ func (cli *Client) DeleteMetaObjectAsst(objAsstID int, data []byte) error {
 		}
 	}
 
-	rst, err := cli.base.HttpCli.DELETE(fmt.Sprintf("%s/object/v1/meta/objectasst/%d", cli.address, objAsstID), nil, data)
+	rst, err := cli.base.HttpCli.DELETE(fmt.Sprintf("%s/object/v1/meta/objectasst/%d", cli.address, objAsstID), cli.header, data)
 
 	if nil != err {
 		return Err_Request_Object
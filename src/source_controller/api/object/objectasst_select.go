@@ -26,7 +26,7 @@ func (cli *Client) SearchMetaObjectAsst(data []byte) ([]ObjAsstDes, error) {
 		return nil, Err_Not_Set_Input
 	}
 
-	rst, err := cli.base.HttpCli.POST(fmt.Sprintf("%s/object/v1/meta/objectassts", cli.address), nil, data)
+	rst, err := cli.base.HttpCli.POST(fmt.Sprintf("%s/object/v1/meta/objectassts", cli.address), cli.header, data)
 
 	if nil != err {
 		blog.Error("request failed, error:%v", err)
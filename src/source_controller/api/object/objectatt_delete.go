@@ -29,7 +29,7 @@ func (cli *Client) DeleteMetaObjectAtt(objAttID int, data []byte) error {
 		}
 	}
 
-	rst, err := cli.base.HttpCli.DELETE(fmt.Sprintf("%s/object/v1/meta/objectatt/%d", cli.address, objAttID), nil, data)
+	rst, err := cli.base.HttpCli.DELETE(fmt.Sprintf("%s/object/v1/meta/objectatt/%d", cli.address, objAttID), cli.header, data)
 
 	if nil != err {
 		return Err_Request_Object
@@ -26,7 +26,7 @@ func (cli *Client) CreateMetaObjectAttGroup(data []byte) (int, error) {
 		return 0, Err_Not_Set_Input
 	}
 
-	rst, err := cli.base.HttpCli.POST(fmt.Sprintf("%s/object/v1/meta/objectatt/group/new", cli.address), nil, data)
+	rst, err := cli.base.HttpCli.POST(fmt.Sprintf("%s/object/v1/meta/objectatt/group/new", cli.address), cli.header, data)
 	if nil != err {
 		blog.Error("request failed, error:%v", err)
 		return 0, Err_Request_Object
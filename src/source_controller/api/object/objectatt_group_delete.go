@@ -29,7 +29,7 @@ func (cli *Client) DeleteMetaObjectAttGroup(id int, data []byte) error {
 		}
 	}
 
-	rst, err := cli.base.HttpCli.DELETE(fmt.Sprintf("%s/object/v1/meta/objectatt/group/groupid/%d", cli.address, id), nil, data)
+	rst, err := cli.base.HttpCli.DELETE(fmt.Sprintf("%s/object/v1/meta/objectatt/group/groupid/%d", cli.address, id), cli.header, data)
 
 	if nil != err {
 		return Err_Request_Object
@@ -51,7 +51,7 @@ func (cli *Client) DeleteMetaObjectAttGroup(id int, data []byte) error {
 // DeleteMetaObjectAttGroupProperty delete the group property
 func (cli *Client) DeleteMetaObjectAttGroupProperty(ownerID, objectID, propertyID, groupID string) error {
 
-	rst, err := cli.base.HttpCli.DELETE(fmt.Sprintf("%s/object/v1/meta/objectatt/group/owner/%s/object/%s/propertyids/%s/groupids/%s", cli.address, ownerID, objectID, propertyID, groupID), nil, nil)
+	rst, err := cli.base.HttpCli.DELETE(fmt.Sprintf("%s/object/v1/meta/objectatt/group/owner/%s/object/%s/propertyids/%s/groupids/%s", cli.address, ownerID, objectID, propertyID, groupID), cli.header, nil)
 
 	if nil != err {
 		return Err_Request_Object
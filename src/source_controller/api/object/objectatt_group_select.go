@@ -21,7 +21,7 @@ import (
 )
 
 func (cli *Client) SelectPropertyGroup(data []byte) ([]ObjAttGroupDes, error) {
-	rst, err := cli.base.HttpCli.POST("/meta/objectatt/group/search", nil, data)
+	rst, err := cli.base.HttpCli.POST(fmt.Sprintf("%s/object/v1/meta/objectatt/group/search", cli.address), cli.header, data)
 	if nil != err {
 		blog.Error("request failed, error:%v", err)
 		return nil, Err_Request_Object
@@ -44,7 +44,7 @@ func (cli *Client) SelectPropertyGroup(data []byte) ([]ObjAttGroupDes, error) {
 func (cli *Client) SelectPropertyGroupByObjectID(ownerID, objectID string, data []byte) ([]ObjAttGroupDes, error) {
 
 	url := fmt.Sprintf("%s/object/v1/meta/objectatt/group/property/owner/%s/object/%s", cli.address, ownerID, objectID)
-	rst, err := cli.base.HttpCli.POST(url, nil, data)
+	rst, err := cli.base.HttpCli.POST(url, cli.header, data)
 	if nil != err {
 		blog.Error("request failed, error:%v", err)
 		return nil, Err_Request_Object
@@ -27,7 +27,7 @@ func (cli *Client) UpdateMetaObjectAttGroup(data []byte) error {
 		return Err_Not_Set_Input
 	}
 
-	rst, err := cli.base.HttpCli.PUT(fmt.Sprintf("%s/object/v1/meta/objectatt/group/update", cli.address), nil, data)
+	rst, err := cli.base.HttpCli.PUT(fmt.Sprintf("%s/object/v1/meta/objectatt/group/update", cli.address), cli.header, data)
 	if nil != err {
 		blog.Error("request failed, error:%v", err)
 		return Err_Request_Object
@@ -49,7 +49,7 @@ func (cli *Client) UpdateMetaObjectAttGroup(data []byte) error {
 // UpdateMetaObjectAttGroupProperty update object attribute group
 func (cli *Client) UpdateMetaObjectAttGroupProperty(data []byte) error {
 
-	rst, err := cli.base.HttpCli.PUT(fmt.Sprintf("%s/object/v1/meta/objectatt/group/property", cli.address), nil, data)
+	rst, err := cli.base.HttpCli.PUT(fmt.Sprintf("%s/object/v1/meta/objectatt/group/property", cli.address), cli.header, data)
 	if nil != err {
 		blog.Error("request failed, error:%v", err)
 		return Err_Request_Object
@@ -26,7 +26,7 @@ func (cli *Client) SearchMetaObjectAttByID(attrID int) (*ObjAttDes, error) {
 		return nil, Err_Not_Set_Input
 	}
 
-	rst, err := cli.base.HttpCli.POST(fmt.Sprintf("%s/object/v1/meta/objectatt/%d", cli.address, attrID), nil, nil)
+	rst, err := cli.base.HttpCli.POST(fmt.Sprintf("%s/object/v1/meta/objectatt/%d", cli.address, attrID), cli.header, nil)
 
 	if nil != err {
 		blog.Error("request failed, error:%v", err)
@@ -80,7 +80,7 @@ func (cli *Client) SearchMetaObjectAtt(data []byte) ([]ObjAttDes, error) {
 	if len(data) == 0 {
 		return nil, Err_Not_Set_Input
 	}
-	rst, err := cli.base.HttpCli.POST(fmt.Sprintf("%s/object/v1/meta/objectatts", cli.address), nil, data)
+	rst, err := cli.base.HttpCli.POST(fmt.Sprintf("%s/object/v1/meta/objectatts", cli.address), cli.header, data)
 
 	if nil != err {
 		blog.Error("request failed, error:%v", err)
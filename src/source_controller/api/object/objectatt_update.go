@@ -29,7 +29,7 @@ func (cli *Client) UpdateMetaObjectAtt(objAttID int, data []byte) error {
 		}
 	}
 
-	rst, err := cli.base.HttpCli.PUT(fmt.Sprintf("%s/object/v1/meta/objectatt/%d", cli.address, objAttID), nil, data)
+	rst, err := cli.base.HttpCli.PUT(fmt.Sprintf("%s/object/v1/meta/objectatt/%d", cli.address, objAttID), cli.header, data)
 
 	if nil != err {
 		blog.Error("update objectatt failed, error:%v", err)
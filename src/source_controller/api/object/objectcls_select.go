@@ -22,7 +22,7 @@ import (
 // SearchMetaobjectCls 仅返回分组信息
 func (cli *Client) SearchMetaObjectCls(data []byte) ([]ObjClsDes, error) {
 
-	rst, err := cli.base.HttpCli.POST(fmt.Sprintf("%s/object/v1/meta/object/classification/search", cli.address), nil, data)
+	rst, err := cli.base.HttpCli.POST(fmt.Sprintf("%s/object/v1/meta/object/classification/search", cli.address), cli.header, data)
 
 	if nil != err {
 		blog.Error("request failed, error:%v", err)
@@ -49,7 +49,7 @@ func (cli *Client) SearchMetaObjectClsObjects(ownerID string, data []byte) ([]Ob
 		return nil, Err_Not_Set_Input
 	}
 
-	rst, err := cli.base.HttpCli.POST(fmt.Sprintf("%s/object/v1/meta/object/classification/%s/objects", cli.address, ownerID), nil, data)
+	rst, err := cli.base.HttpCli.POST(fmt.Sprintf("%s/object/v1/meta/object/classification/%s/objects", cli.address, ownerID), cli.header, data)
 
 	if nil != err {
 		blog.Error("request failed, error:%v", err)
@@ -29,7 +29,7 @@ func (cli *Client) UpdateMetaObjectCls(objClsID int, data []byte) error {
 		}
 	}
 
-	rst, err := cli.base.HttpCli.PUT(fmt.Sprintf("%s/object/v1/meta/object/classification/%d", cli.address, objClsID), nil, data)
+	rst, err := cli.base.HttpCli.PUT(fmt.Sprintf("%s/object/v1/meta/object/classification/%d", cli.address, objClsID), cli.header, data)
 
 	if nil != err {
 		return Err_Request_Object
@@ -0,0 +1,129 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transaction
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/core/cc/api"
+	httpcli "configcenter/src/common/http/httpclient"
+	"encoding/json"
+	"fmt"
+
+	"github.com/emicklei/go-restful"
+)
+
+// afterCommitAttr the request attribute keeps the callbacks which run once the transaction begun
+// by the request commits
+const afterCommitAttr = "bk_txn_after_commit"
+
+// afterCommit the callbacks of the transaction
+type afterCommit struct {
+	fns []func()
+}
+
+// Txn the storage transaction of the controllers, the controller requests which forward
+// the transaction header join it
+type Txn struct {
+	req    *restful.Request
+	url    string
+	id     string
+	joined bool
+}
+
+// Begin begins a transaction by the transaction url of a controller, such as objctrl + "/object/v1/txn",
+// and sets the transaction id to the header of req, so that the following controller requests which
+// forward the header of req join the transaction. The transaction is joined if req is already in one,
+// its commit and abort are left to the one who begins it.
+func Begin(req *restful.Request, url string) (*Txn, error) {
+	if txnID := req.Request.Header.Get(common.BKHTTPTxnID); "" != txnID {
+		return &Txn{req: req, url: url, id: txnID, joined: true}, nil
+	}
+
+	data := struct {
+		TxnID string `json:"txn_id"`
+	}{}
+	if err := request(req, url, common.HTTPCreate, &data); nil != err {
+		blog.Errorf("failed to begin the transaction, error: %v", err)
+		return nil, err
+	}
+	req.Request.Header.Set(common.BKHTTPTxnID, data.TxnID)
+	req.SetAttribute(afterCommitAttr, &afterCommit{})
+	return &Txn{req: req, url: url, id: data.TxnID}, nil
+}
+
+// AfterCommit runs fn once the transaction begun by req commits, fn is dropped if it aborts,
+// fn runs at once if req begins no transaction, the audit logs written out of the controllers for example
+func AfterCommit(req *restful.Request, fn func()) {
+	if callbacks, ok := req.Attribute(afterCommitAttr).(*afterCommit); ok && nil != callbacks {
+		callbacks.fns = append(callbacks.fns, fn)
+		return
+	}
+	fn()
+}
+
+// ID returns the transaction id
+func (t *Txn) ID() string {
+	return t.id
+}
+
+// Commit commits the transaction, removes it from the header and runs the callbacks of AfterCommit
+func (t *Txn) Commit() error {
+	if t.joined {
+		return nil
+	}
+	callbacks, _ := t.req.Attribute(afterCommitAttr).(*afterCommit)
+	t.req.SetAttribute(afterCommitAttr, nil)
+	err := request(t.req, fmt.Sprintf("%s/%s/commit", t.url, t.id), common.HTTPUpdate, nil)
+	t.req.Request.Header.Del(common.BKHTTPTxnID)
+	if nil != err {
+		blog.Errorf("failed to commit the transaction %s, error: %v", t.id, err)
+		return err
+	}
+	// the callbacks run out of the transaction
+	if nil != callbacks {
+		for _, fn := range callbacks.fns {
+			fn()
+		}
+	}
+	return nil
+}
+
+// Abort aborts the transaction, removes it from the header and drops the callbacks of AfterCommit
+func (t *Txn) Abort() error {
+	if t.joined {
+		return nil
+	}
+	defer t.req.Request.Header.Del(common.BKHTTPTxnID)
+	t.req.SetAttribute(afterCommitAttr, nil)
+	if err := request(t.req, fmt.Sprintf("%s/%s/abort", t.url, t.id), common.HTTPUpdate, nil); nil != err {
+		blog.Errorf("failed to abort the transaction %s, error: %v", t.id, err)
+		return err
+	}
+	return nil
+}
+
+func request(req *restful.Request, url, method string, result interface{}) error {
+	reply, err := httpcli.ReqHttp(req, url, method, nil)
+	if nil != err {
+		return err
+	}
+	rsp := api.BKAPIRsp{Data: result}
+	if err := json.Unmarshal([]byte(reply), &rsp); nil != err {
+		return err
+	}
+	if !rsp.Result {
+		return fmt.Errorf("%v", rsp.Message)
+	}
+	return nil
+}
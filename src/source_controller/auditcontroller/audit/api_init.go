@@ -14,4 +14,5 @@ package ccapi
 
 import (
 	_ "configcenter/src/source_controller/auditcontroller/audit/actions"
+	_ "configcenter/src/source_controller/common/slowquery"
 )
@@ -249,6 +249,13 @@ func (m *mockMongo) GetMutilByCondition(cName string, fields []string, condictio
 	}
 	return nil
 }
+func (m *mockMongo) GetMutilByCursor(cName string, fields []string, condiction interface{}, result interface{}, sort, cursor string, limit int) (string, error) {
+	m.errTriggerStep++
+	if m.errTrigger == m.errTriggerStep {
+		return "", m.err
+	}
+	return "", nil
+}
 func (m *mockMongo) GetCntByCondition(cName string, condiction interface{}) (int, error) {
 	m.errTriggerStep++
 	if m.errTrigger == m.errTriggerStep {
@@ -13,18 +13,44 @@
 package eventdata
 
 import (
+	"bytes"
 	"configcenter/src/common"
+	"configcenter/src/common/blog"
 	"configcenter/src/common/core/cc/api"
 	commontypes "configcenter/src/common/types"
+	"configcenter/src/common/util"
 	"configcenter/src/scene_server/event_server/types"
+	"configcenter/src/storage"
+	"context"
 	"encoding/json"
 	"github.com/emicklei/go-restful"
 	"time"
 )
 
+// TableNameTxEvent the table keeps the events of the transactions until they commit
+const TableNameTxEvent = "cc_TxEvent"
+
+// TxEventRecoverDelay the events held longer than it are pushed by RecoverTxEvents if their transactions
+// are committed, the transactions are finished by then, so the commits have had the time to push them
+const TxEventRecoverDelay = 2 * storage.TxTimeout
+
 type EventContext struct {
 	RequestID   string
 	RequestTime commontypes.Time
+	// OwnerID the supplier account of the request, the owner of the events whose data has none
+	OwnerID string
+	// Ctx the context of the request, the events of the transaction bound to it are held until it commits
+	Ctx context.Context
+}
+
+// txEvent the event held until its transaction commits
+type txEvent struct {
+	TxID       string    `bson:"tx_id"`
+	Seq        int64     `bson:"seq"`
+	Event      string    `bson:"event"`
+	CreateTime time.Time `bson:"create_time"`
+	// ClaimTime the time the events of the transaction are taken by RecoverTxEvents
+	ClaimTime time.Time `bson:"claim_time"`
 }
 
 func NewEventContext(requestID string, requestTime time.Time) *EventContext {
@@ -39,20 +65,15 @@ func NewEventContextByReq(req *restful.Request) *EventContext {
 	return &EventContext{
 		RequestID:   "xxx-xxxx-xxx-xxx",
 		RequestTime: commontypes.Now(),
+		OwnerID:     util.GetActionOnwerID(req),
+		Ctx:         req.Request.Context(),
 	}
 }
 
+// InsertEvent pushes the event to the event queue, the event of a transaction is held in TableNameTxEvent
+// in the transaction, so it is pushed by PushTxEvents once the transaction commits and dropped with the abort
 func (c *EventContext) InsertEvent(eventType, objType, action string, curData interface{}, preData interface{}) (err error) {
-	ar := api.GetAPIResource()
-	eventIDseletor := common.KvMap{
-		"key": types.EventCacheEventIDKey,
-	}
-	eventID, err := ar.CacheCli.Insert("incr", eventIDseletor)
-	if err != nil {
-		return err
-	}
 	ei := &types.EventInst{
-		ID:          int64(eventID),
 		EventType:   eventType,
 		Action:      action,
 		ActionTime:  commontypes.Now(),
@@ -61,19 +82,163 @@ func (c *EventContext) InsertEvent(eventType, objType, action string, curData in
 		PreData:     preData,
 		RequestID:   c.RequestID,
 		RequestTime: c.RequestTime,
+		OwnerID:     c.OwnerID,
+	}
+	if action == types.EventActionUpdate {
+		ei.ChangedFields = types.DiffData(preData, curData)
 	}
 
-	value, err := json.Marshal(ei)
-	if err != nil {
-		return err
+	if nil != c.Ctx {
+		db := api.GetAPIResource().InstCli
+		if tx := storage.TxOf(c.Ctx, db); nil != tx {
+			return holdEvent(c.Ctx, db, tx.ID(), ei)
+		}
+	}
+	return pushEvents(ei)
+}
+
+// pushEvents gives the events their ids and pushes them to the event queue in order
+func pushEvents(events ...*types.EventInst) error {
+	if 0 == len(events) {
+		return nil
+	}
+	ar := api.GetAPIResource()
+	eventIDseletor := common.KvMap{
+		"key": types.EventCacheEventIDKey,
+	}
+	values := make([]string, 0, len(events))
+	for _, ei := range events {
+		eventID, err := ar.CacheCli.Insert("incr", eventIDseletor)
+		if err != nil {
+			return err
+		}
+		ei.ID = int64(eventID)
+		value, err := json.Marshal(ei)
+		if err != nil {
+			return err
+		}
+		values = append(values, string(value))
 	}
 	cacheValue := common.KvMap{
 		"key":    types.EventCacheEventQueueKey,
-		"values": []string{string(value)},
+		"values": values,
+	}
+	_, err := ar.CacheCli.Insert("rpush", cacheValue)
+	return err
+}
+
+// holdEvent keeps the event in the transaction of txID, the event id is given when it is pushed
+// so that the ids follow the order of the queue
+func holdEvent(ctx context.Context, db storage.DI, txID string, ei *types.EventInst) error {
+	value, err := json.Marshal(ei)
+	if err != nil {
+		return err
 	}
-	_, err = ar.CacheCli.Insert("rpush", cacheValue)
+	cdb := storage.ContextOf(db)
+	seq, err := cdb.GetIncIDCtx(ctx, TableNameTxEvent)
 	if err != nil {
-		return
+		return err
+	}
+	_, err = cdb.InsertCtx(ctx, TableNameTxEvent, &txEvent{TxID: txID, Seq: seq, Event: string(value), CreateTime: time.Now().UTC(), ClaimTime: time.Unix(0, 0).UTC()})
+	return err
+}
+
+// PushTxEvents pushes the events held by the committed transaction of txID and drops them
+func PushTxEvents(ctx context.Context, db storage.DI, txID string) error {
+	cdb := storage.ContextOf(db)
+	held := make([]txEvent, 0)
+	cond := map[string]interface{}{"tx_id": txID}
+	if err := cdb.GetMutilByConditionCtx(ctx, TableNameTxEvent, nil, cond, &held, "seq", 0, 0); err != nil {
+		return err
+	}
+	events := make([]*types.EventInst, 0, len(held))
+	for _, item := range held {
+		ei := &types.EventInst{}
+		decoder := json.NewDecoder(bytes.NewReader([]byte(item.Event)))
+		decoder.UseNumber()
+		if err := decoder.Decode(ei); err != nil {
+			return err
+		}
+		events = append(events, ei)
+	}
+	if err := pushEvents(events...); err != nil {
+		return err
+	}
+	return DropTxEvents(ctx, db, txID)
+}
+
+// DropTxEvents drops the events held by the transaction of txID
+func DropTxEvents(ctx context.Context, db storage.DI, txID string) error {
+	return storage.ContextOf(db).DelByConditionCtx(ctx, TableNameTxEvent, map[string]interface{}{"tx_id": txID})
+}
+
+// RecoverTxEvents pushes the events held longer than TxEventRecoverDelay by the committed transactions,
+// which are left when the push after the commit fails or the process stops, the events of the transactions
+// still running or being aborted are kept, every transaction is claimed first so it is pushed only once
+func RecoverTxEvents(ctx context.Context, db storage.DI) error {
+	cdb := storage.ContextOf(db)
+	now := time.Now().UTC()
+	held := make([]txEvent, 0)
+	cond := map[string]interface{}{"create_time": map[string]interface{}{"$lte": now.Add(-TxEventRecoverDelay)}}
+	if err := cdb.GetMutilByConditionCtx(ctx, TableNameTxEvent, []string{"tx_id", "seq"}, cond, &held, "seq", 0, 0); err != nil {
+		return err
+	}
+	recovered := make(map[string]bool)
+	for _, item := range held {
+		if recovered[item.TxID] {
+			continue
+		}
+		recovered[item.TxID] = true
+
+		pending, err := storage.TxPending(ctx, db, item.TxID)
+		if err != nil {
+			return err
+		}
+		if pending {
+			continue
+		}
+		claimed, err := claimTxEvents(ctx, db, item, now)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+		blog.Warnf("push the events left by the committed transaction %s", item.TxID)
+		if err := PushTxEvents(ctx, db, item.TxID); err != nil {
+			blog.Errorf("failed to push the events of the transaction %s, error: %v", item.TxID, err)
+		}
 	}
 	return nil
 }
+
+// claimTxEvents marks the first held event of the transaction so that the others leave it, the claim
+// expires in TxTimeout so the push which stops in the middle is taken again
+func claimTxEvents(ctx context.Context, db storage.DI, first txEvent, now time.Time) (bool, error) {
+	snap, ok := storage.Unwrap(db).(storage.Snapshotter)
+	if !ok {
+		return true, nil
+	}
+	cond := map[string]interface{}{
+		"tx_id":      first.TxID,
+		"seq":        first.Seq,
+		"claim_time": map[string]interface{}{"$lte": now.Add(-storage.TxTimeout)},
+	}
+	return snap.ClaimCtx(ctx, TableNameTxEvent, cond, map[string]interface{}{"claim_time": now})
+}
+
+// RecoverTxEventsLoop runs RecoverTxEvents every interval until ctx is done
+func RecoverTxEventsLoop(ctx context.Context, db storage.DI, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := RecoverTxEvents(ctx, db); err != nil {
+				blog.Errorf("failed to recover the events of the committed transactions, error: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,126 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package eventdata
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/common/core/cc/api"
+	"configcenter/src/scene_server/event_server/types"
+	"configcenter/src/storage"
+	"configcenter/src/storage/memclient"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeQueue records the events pushed to the cache
+type fakeQueue struct {
+	storage.DI
+	id     int
+	queued []string
+}
+
+func (q *fakeQueue) Insert(cmd string, data interface{}) (int, error) {
+	switch cmd {
+	case "incr":
+		q.id++
+		return q.id, nil
+	case "rpush":
+		q.queued = append(q.queued, data.(common.KvMap)["values"].([]string)...)
+	}
+	return 0, nil
+}
+
+func newTestDB(t *testing.T) (storage.DI, *fakeQueue) {
+	db, err := memclient.NewMemCli(t.Name())
+	require.NoError(t, err)
+	require.NoError(t, db.Open())
+	t.Cleanup(func() { memclient.Drop(t.Name()) })
+
+	queue := &fakeQueue{}
+	ar := api.GetAPIResource()
+	instCli, cacheCli := ar.InstCli, ar.CacheCli
+	ar.InstCli, ar.CacheCli = db, queue
+	t.Cleanup(func() { ar.InstCli, ar.CacheCli = instCli, cacheCli })
+	return db, queue
+}
+
+// holdTxEvent starts a transaction and holds an event in it
+func holdTxEvent(t *testing.T, db storage.DI, instID int) storage.Tx {
+	ctx := context.Background()
+	tx, err := storage.StartTx(ctx, db)
+	require.NoError(t, err)
+	ec := &EventContext{RequestID: "req", Ctx: storage.WithTx(ctx, db, tx)}
+	require.NoError(t, ec.InsertEvent(types.EventTypeInstData, common.BKInnerObjIDHost, types.EventActionCreate, map[string]interface{}{"bk_host_id": instID}, nil))
+	return tx
+}
+
+// ageTxEvents makes the held events older than TxEventRecoverDelay
+func ageTxEvents(t *testing.T, db storage.DI) {
+	created := time.Now().UTC().Add(-TxEventRecoverDelay - time.Second)
+	require.NoError(t, db.UpdateByCondition(TableNameTxEvent, map[string]interface{}{"create_time": created}, map[string]interface{}{}))
+}
+
+func TestRecoverTxEventsAfterCrash(t *testing.T) {
+	db, queue := newTestDB(t)
+	ctx := context.Background()
+
+	// the process stops after the commit, before the events are pushed
+	committed := holdTxEvent(t, db, 1)
+	require.NoError(t, committed.Commit(ctx))
+	running := holdTxEvent(t, db, 2)
+	require.Empty(t, queue.queued)
+
+	// the commit could still be pushing them
+	require.NoError(t, RecoverTxEvents(ctx, db))
+	require.Empty(t, queue.queued)
+
+	ageTxEvents(t, db)
+	require.NoError(t, RecoverTxEvents(ctx, db))
+	require.Len(t, queue.queued, 1)
+	require.Contains(t, queue.queued[0], `"bk_host_id":1`)
+
+	// the events of the running transaction are kept, the pushed ones are dropped
+	cnt, err := db.GetCntByCondition(TableNameTxEvent, map[string]interface{}{"tx_id": committed.ID()})
+	require.NoError(t, err)
+	require.Equal(t, 0, cnt)
+	cnt, err = db.GetCntByCondition(TableNameTxEvent, map[string]interface{}{"tx_id": running.ID()})
+	require.NoError(t, err)
+	require.Equal(t, 1, cnt)
+
+	require.NoError(t, RecoverTxEvents(ctx, db))
+	require.Len(t, queue.queued, 1)
+}
+
+func TestRecoverTxEventsClaimed(t *testing.T) {
+	db, queue := newTestDB(t)
+	ctx := context.Background()
+
+	tx := holdTxEvent(t, db, 1)
+	require.NoError(t, tx.Commit(ctx))
+	ageTxEvents(t, db)
+
+	// another process is pushing the events
+	claim := map[string]interface{}{"claim_time": time.Now().UTC()}
+	require.NoError(t, db.UpdateByCondition(TableNameTxEvent, claim, map[string]interface{}{"tx_id": tx.ID()}))
+	require.NoError(t, RecoverTxEvents(ctx, db))
+	require.Empty(t, queue.queued)
+
+	// the claim expires when the push stops in the middle
+	claim["claim_time"] = time.Now().UTC().Add(-storage.TxTimeout - time.Second)
+	require.NoError(t, db.UpdateByCondition(TableNameTxEvent, claim, map[string]interface{}{"tx_id": tx.ID()}))
+	require.NoError(t, RecoverTxEvents(ctx, db))
+	require.Len(t, queue.queued, 1)
+}
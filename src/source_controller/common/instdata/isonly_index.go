@@ -0,0 +1,137 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package instdata
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/util"
+	"configcenter/src/storage"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// IsOnlyIndexPrefix the name prefix of the unique indexes of the bk_isonly attributes
+const IsOnlyIndexPrefix = "bk_isonly_"
+
+// BaseInstTable the collection of the instances of the custom objects
+const BaseInstTable = "cc_ObjectBase"
+
+// innerObjIDs the objects which are not stored in cc_ObjectBase
+var innerObjIDs = []string{
+	common.BKInnerObjIDApp,
+	common.BKInnerObjIDSet,
+	common.BKInnerObjIDModule,
+	common.BKInnerObjIDHost,
+	common.BKInnerObjIDProc,
+	common.BKInnerObjIDPlat,
+}
+
+// IsOnlyIndexName returns the name of the unique index of the bk_isonly attributes of objID
+func IsOnlyIndexName(objID string) string {
+	return IsOnlyIndexPrefix + objID
+}
+
+// IsOnlyIndexes returns the unique indexes of the bk_isonly attributes of the custom objects, all the custom
+// objects if objIDs is empty. The attributes of an object make one composite index which only covers
+// the instances of the object having all of them
+func IsOnlyIndexes(ctx context.Context, db storage.DI, objIDs ...string) ([]storage.Index, error) {
+	objCond := map[string]interface{}{common.BKDBNIN: innerObjIDs}
+	if 0 != len(objIDs) {
+		objCond[common.BKDBIN] = objIDs
+	}
+	condition := map[string]interface{}{
+		"isonly":            true,
+		common.BKObjIDField: objCond,
+	}
+	attrs := make([]map[string]interface{}, 0)
+	fields := []string{common.BKObjIDField, common.BKPropertyIDField}
+	err := storage.ContextOf(db).GetMutilByConditionCtx(ctx, common.BKTableNameObjAttDes, fields, condition, &attrs, common.BKObjIDField, 0, 0)
+	if nil != err {
+		return nil, err
+	}
+
+	properties := make(map[string][]string)
+	for _, attr := range attrs {
+		objID := fmt.Sprint(attr[common.BKObjIDField])
+		properties[objID] = append(properties[objID], fmt.Sprint(attr[common.BKPropertyIDField]))
+	}
+	sorted := make([]string, 0, len(properties))
+	for objID := range properties {
+		sorted = append(sorted, objID)
+	}
+	sort.Strings(sorted)
+
+	indexes := make([]storage.Index, 0, len(sorted))
+	for _, objID := range sorted {
+		propertyIDs := uniqueSorted(properties[objID])
+		filter := map[string]interface{}{common.BKObjIDField: objID}
+		for _, propertyID := range propertyIDs {
+			filter[propertyID] = map[string]interface{}{common.BKDBExists: true}
+		}
+		indexes = append(indexes, storage.Index{
+			Name:          IsOnlyIndexName(objID),
+			Columns:       append([]string{common.BKObjIDField, common.BKOwnerIDField}, propertyIDs...),
+			Type:          storage.INDEX_TYPE_BACKGROUP_UNIQUE,
+			PartialFilter: filter,
+		})
+	}
+	return indexes, nil
+}
+
+// ReconcileIsOnlyIndex makes the unique index of the bk_isonly attributes of objID match its attributes,
+// it is called once the attributes of the object change
+func ReconcileIsOnlyIndex(ctx context.Context, db storage.DI, objID string) error {
+	if "" == objID || util.Contains(innerObjIDs, objID) {
+		return nil
+	}
+	indexes, err := IsOnlyIndexes(ctx, db, objID)
+	if nil != err {
+		return err
+	}
+	name := IsOnlyIndexName(objID)
+	opts := storage.ReconcileOptions{
+		Prune: IsOnlyIndexPruned,
+		Scope: func(index string) bool { return index == name },
+	}
+	drifts, err := storage.ReconcileIndexes(ctx, db, map[string][]storage.Index{BaseInstTable: indexes}, opts)
+	if nil != err {
+		return err
+	}
+	for _, drift := range drifts {
+		if "" != drift.Error {
+			return fmt.Errorf("%s index %s failed: %s", drift.Action, drift.Name, drift.Error)
+		}
+		blog.Infof("%s index %s of %s", drift.Action, drift.Name, drift.Collection)
+	}
+	return nil
+}
+
+// IsOnlyIndexPruned returns true for the unique indexes of the bk_isonly attributes, they are generated
+// from the attributes so they are dropped once out of the spec
+func IsOnlyIndexPruned(name string) bool {
+	return strings.HasPrefix(name, IsOnlyIndexPrefix)
+}
+
+func uniqueSorted(items []string) []string {
+	sort.Strings(items)
+	result := make([]string, 0, len(items))
+	for idx, item := range items {
+		if 0 == idx || items[idx-1] != item {
+			result = append(result, item)
+		}
+	}
+	return result
+}
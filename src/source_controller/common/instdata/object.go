@@ -15,13 +15,15 @@ package instdata
 import (
 	"configcenter/src/common"
 	"configcenter/src/source_controller/common/commondata"
+	"configcenter/src/storage"
+	"context"
 	"errors"
 )
 
 //GetCntByCondition get count by condition
-func GetCntByCondition(objType string, condition interface{}) (int, error) {
+func GetCntByCondition(ctx context.Context, objType string, condition interface{}) (int, error) {
 	tName := commondata.ObjTableMap[objType]
-	cnt, err := DataH.GetCntByCondition(tName, condition)
+	cnt, err := storage.ContextOf(DataH).GetCntByConditionCtx(ctx, tName, condition)
 	if nil != err {
 		return 0, err
 	}
@@ -29,9 +31,9 @@ func GetCntByCondition(objType string, condition interface{}) (int, error) {
 }
 
 //DelObjByCondition delete object by condition
-func DelObjByCondition(objType string, condition interface{}) error {
+func DelObjByCondition(ctx context.Context, objType string, condition interface{}) error {
 	tName := commondata.ObjTableMap[objType]
-	err := DataH.DelByCondition(tName, condition)
+	err := storage.ContextOf(DataH).DelByConditionCtx(ctx, tName, condition)
 	if nil != err {
 		return err
 	}
@@ -39,9 +41,9 @@ func DelObjByCondition(objType string, condition interface{}) error {
 }
 
 //UpdateObjByCondition update object by condition
-func UpdateObjByCondition(objType string, data interface{}, condition interface{}) error {
+func UpdateObjByCondition(ctx context.Context, objType string, data interface{}, condition interface{}) error {
 	tName := commondata.ObjTableMap[objType]
-	err := DataH.UpdateByCondition(tName, data, condition)
+	err := storage.ContextOf(DataH).UpdateByConditionCtx(ctx, tName, data, condition)
 	if nil != err {
 		return err
 	}
@@ -49,25 +51,57 @@ func UpdateObjByCondition(objType string, data interface{}, condition interface{
 }
 
 //GetObjectByCondition get object by condition
-func GetObjectByCondition(objType string, fields []string, condition, result interface{}, sort string, skip, limit int) error {
+func GetObjectByCondition(ctx context.Context, objType string, fields []string, condition, result interface{}, sort string, skip, limit int) error {
 	tName := commondata.ObjTableMap[objType]
-	return DataH.GetMutilByCondition(tName, fields, condition, result, sort, skip, limit)
+	return storage.ContextOf(DataH).GetMutilByConditionCtx(ctx, tName, fields, condition, result, sort, skip, limit)
+}
+
+//GetObjectByCursor get object by condition after the cursor, returns the cursor of the next page
+func GetObjectByCursor(ctx context.Context, objType string, fields []string, condition, result interface{}, sort, cursor string, limit int) (string, error) {
+	tName := commondata.ObjTableMap[objType]
+	return storage.ContextOf(DataH).GetMutilByCursorCtx(ctx, tName, fields, condition, result, sort, cursor, limit)
 }
 
 //CreateObject add new object
-func CreateObject(objType string, input interface{}, idName *string) (int, error) {
+func CreateObject(ctx context.Context, objType string, input interface{}, idName *string) (int, error) {
 	tName := commondata.ObjTableMap[objType]
-	objID, err := DataH.GetIncID(tName)
+	objID, err := storage.IDAllocatorOf(DataH).NextID(ctx, tName)
 	if err != nil {
 		return 0, err
 	}
 	inputc := input.(map[string]interface{})
 	*idName = GetIDNameByType(objType)
 	inputc[*idName] = objID
-	DataH.Insert(tName, inputc)
+	if _, err := storage.ContextOf(DataH).InsertCtx(ctx, tName, inputc); err != nil {
+		return 0, err
+	}
 	return int(objID), nil
 }
 
+//CreateObjects add new objects in batch, the IDs are reserved in one go and returned in the order of inputs
+func CreateObjects(ctx context.Context, objType string, inputs []map[string]interface{}, idName *string) ([]int, error) {
+	tName := commondata.ObjTableMap[objType]
+	objIDs, err := storage.IDAllocatorOf(DataH).Reserve(ctx, tName, len(inputs))
+	if err != nil {
+		return nil, err
+	}
+	*idName = GetIDNameByType(objType)
+	docs := make([]interface{}, 0, len(inputs))
+	ids := make([]int, 0, len(inputs))
+	for i, input := range inputs {
+		input[*idName] = objIDs[i]
+		docs = append(docs, input)
+		ids = append(ids, int(objIDs[i]))
+	}
+	if 0 == len(docs) {
+		return ids, nil
+	}
+	if err := storage.ContextOf(DataH).InsertMutiCtx(ctx, tName, docs...); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
 //GetIDNameByType get id name by type
 func GetIDNameByType(objType string) string {
 	switch objType {
@@ -91,7 +125,7 @@ func GetIDNameByType(objType string) string {
 }
 
 //GetObjectByID get object by id
-func GetObjectByID(objType string, fields []string, id int, result interface{}, sort string) error {
+func GetObjectByID(ctx context.Context, objType string, fields []string, id int, result interface{}, sort string) error {
 	tName := commondata.ObjTableMap[objType]
 	condition := make(map[string]interface{}, 1)
 	switch objType {
@@ -112,6 +146,6 @@ func GetObjectByID(objType string, fields []string, id int, result interface{},
 	default:
 		return errors.New("invalid object type")
 	}
-	err := DataH.GetOneByCondition(tName, fields, condition, result)
+	err := storage.ContextOf(DataH).GetOneByConditionCtx(ctx, tName, fields, condition, result)
 	return err
 }
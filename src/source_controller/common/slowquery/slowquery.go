@@ -0,0 +1,52 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package slowquery exposes the recent slow queries of the controller, a controller imports it to
+// register the action
+package slowquery
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/common/base"
+	"configcenter/src/common/core/cc/actions"
+	"configcenter/src/storage"
+	"net/http"
+
+	"github.com/emicklei/go-restful"
+)
+
+var slowQuery *slowQueryAction = &slowQueryAction{}
+
+type slowQueryAction struct {
+	base.BaseAction
+}
+
+func init() {
+	slowQuery.CreateAction()
+
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPSelectGet, Path: "/slowqueries", Params: nil, Handler: slowQuery.GetSlowQueries})
+}
+
+// GetSlowQueries get the recent slow queries of the instance storage, the latest first
+func (cli *slowQueryAction) GetSlowQueries(req *restful.Request, resp *restful.Response) {
+	cli.CallResponseEx(func() (int, interface{}, error) {
+		info := map[string]interface{}{
+			"enabled": false,
+			"queries": []storage.SlowQuery{},
+		}
+		if slowLog := storage.SlowLogOf(cli.CC.InstCli); nil != slowLog {
+			info["enabled"] = true
+			info["queries"] = slowLog.Recent()
+		}
+		return http.StatusOK, info, nil
+	}, resp)
+}
@@ -0,0 +1,132 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package txndata
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/common/base"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/core/cc/actions"
+	"configcenter/src/common/core/cc/api"
+	"configcenter/src/common/util"
+	"configcenter/src/source_controller/common/eventdata"
+	"configcenter/src/storage"
+	"context"
+	"net/http"
+
+	"github.com/emicklei/go-restful"
+)
+
+var txn = &txnAction{}
+
+type txnAction struct {
+	base.BaseAction
+}
+
+func init() {
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPCreate, Path: "/txn", Params: nil, Handler: txn.Begin})
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPUpdate, Path: "/txn/{txnID}/commit", Params: nil, Handler: txn.Commit})
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPUpdate, Path: "/txn/{txnID}/abort", Params: nil, Handler: txn.Abort})
+
+	// set cc api resource
+	txn.CC = api.NewAPIResource()
+}
+
+// Filter binds the transaction of the request header to the request context, so that the storage
+// operations of the request join the transaction
+func Filter(req *restful.Request, resp *restful.Response, fchain *restful.FilterChain) {
+	txnID := req.HeaderParameter(common.BKHTTPTxnID)
+	if "" == txnID {
+		fchain.ProcessFilter(req, resp)
+		return
+	}
+
+	tx, err := storage.ResumeTx(req.Request.Context(), txn.CC.InstCli, txnID)
+	if nil != err {
+		blog.Errorf("failed to resume the transaction %s, error: %v", txnID, err)
+		defErr := txn.CC.Error.CreateDefaultCCErrorIf(util.GetActionLanguage(req))
+		txn.CallResponseEx(func() (int, interface{}, error) {
+			if storage.ErrTxNotFound == err {
+				return http.StatusBadRequest, nil, defErr.Error(common.CCErrCommTxnNotFound)
+			}
+			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrCommDBSelectFailed)
+		}, resp)
+		return
+	}
+	req.Request = req.Request.WithContext(storage.WithTx(req.Request.Context(), txn.CC.InstCli, tx))
+	fchain.ProcessFilter(req, resp)
+}
+
+// Begin begins a transaction, the requests with the transaction id in the header join it
+func (cli *txnAction) Begin(req *restful.Request, resp *restful.Response) {
+	defErr := cli.CC.Error.CreateDefaultCCErrorIf(util.GetActionLanguage(req))
+
+	cli.CallResponseEx(func() (int, interface{}, error) {
+		tx, err := storage.StartTx(req.Request.Context(), cli.CC.InstCli)
+		if nil != err {
+			blog.Errorf("failed to begin the transaction, error: %v", err)
+			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrCommTxnBeginFailed)
+		}
+		blog.Infof("begin the transaction %s", tx.ID())
+		return http.StatusOK, map[string]interface{}{"txn_id": tx.ID()}, nil
+	}, resp)
+}
+
+// Commit commits the transaction and then pushes the events held by it
+func (cli *txnAction) Commit(req *restful.Request, resp *restful.Response) {
+	cli.finish(req, resp, "commit", common.CCErrCommTxnCommitFailed, func(ctx context.Context, tx storage.Tx) error {
+		if err := tx.Commit(ctx); nil != err {
+			return err
+		}
+		// the writes are committed, so the failure of the events is only logged
+		if err := eventdata.PushTxEvents(ctx, cli.CC.InstCli, tx.ID()); nil != err {
+			blog.Errorf("failed to push the events of the transaction %s, error: %v", tx.ID(), err)
+		}
+		return nil
+	})
+}
+
+// Abort aborts the transaction, the events held by it are dropped with its writes
+func (cli *txnAction) Abort(req *restful.Request, resp *restful.Response) {
+	cli.finish(req, resp, "abort", common.CCErrCommTxnAbortFailed, func(ctx context.Context, tx storage.Tx) error {
+		if err := tx.Abort(ctx); nil != err {
+			return err
+		}
+		if err := eventdata.DropTxEvents(ctx, cli.CC.InstCli, tx.ID()); nil != err {
+			blog.Errorf("failed to drop the events of the transaction %s, error: %v", tx.ID(), err)
+		}
+		return nil
+	})
+}
+
+func (cli *txnAction) finish(req *restful.Request, resp *restful.Response, op string, errcode int, fn func(ctx context.Context, tx storage.Tx) error) {
+	defErr := cli.CC.Error.CreateDefaultCCErrorIf(util.GetActionLanguage(req))
+
+	cli.CallResponseEx(func() (int, interface{}, error) {
+		txnID := req.PathParameter("txnID")
+		tx, err := storage.ResumeTx(req.Request.Context(), cli.CC.InstCli, txnID)
+		if storage.ErrTxNotFound == err {
+			blog.Errorf("failed to %s the transaction %s, not found", op, txnID)
+			return http.StatusBadRequest, nil, defErr.Error(common.CCErrCommTxnNotFound)
+		}
+		if nil == err {
+			err = fn(req.Request.Context(), tx)
+		}
+		if nil != err {
+			blog.Errorf("failed to %s the transaction %s, error: %v", op, txnID, err)
+			return http.StatusInternalServerError, nil, defErr.Error(errcode)
+		}
+		blog.Infof("%s the transaction %s", op, txnID)
+		return http.StatusOK, nil, nil
+	}, resp)
+}
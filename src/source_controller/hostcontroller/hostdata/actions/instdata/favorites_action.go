@@ -20,6 +20,7 @@ import (
 	"configcenter/src/common/core/cc/api"
 	"configcenter/src/common/util"
 	"configcenter/src/source_controller/common/commondata"
+	"configcenter/src/storage"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
@@ -65,7 +66,7 @@ func (cli *hostFavourite) AddHostFavourite(req *restful.Request, resp *restful.R
 		queryParams["user"] = req.PathParameter("user") //libraries.GetOperateUser(req)
 		queryParams["name"] = params["name"]
 
-		rowCount, err := cc.InstCli.GetCntByCondition(TABLENAME, queryParams)
+		rowCount, err := storage.ContextOf(cc.InstCli).GetCntByConditionCtx(req.Request.Context(), TABLENAME, queryParams)
 		if nil != err {
 			blog.Error("query host favorites fail, error information is %s, params:%v", err.Error(), queryParams)
 			return http.StatusBadRequest, nil, defErr.Error(common.CCErrHostFavouriteQueryFail)
@@ -79,7 +80,8 @@ func (cli *hostFavourite) AddHostFavourite(req *restful.Request, resp *restful.R
 		params["count"] = 1
 		params[common.CreateTimeField] = time.Now()
 		params["user"] = req.PathParameter("user") //libraries.GetOperateUser(req)
-		_, err = cc.InstCli.Insert(TABLENAME, params)
+		params[common.BKOwnerIDField] = util.GetActionOnwerID(req)
+		_, err = storage.ContextOf(cc.InstCli).InsertCtx(req.Request.Context(), TABLENAME, params)
 
 		if err != nil {
 			blog.Error("create host favorites type:data:%v error:%v", params, err)
@@ -121,7 +123,7 @@ func (cli *hostFavourite) UpdateHostFavouriteByID(req *restful.Request, resp *re
 		params := make(map[string]interface{})
 		params["user"] = req.PathParameter("user") //libraries.GetOperateUser(req)
 		params["id"] = ID
-		rowCount, err := cc.InstCli.GetCntByCondition(TABLENAME, params)
+		rowCount, err := storage.ContextOf(cc.InstCli).GetCntByConditionCtx(req.Request.Context(), TABLENAME, params)
 		if nil != err {
 			blog.Error("query host favorites fail, error information is %s, params:%v", err.Error(), params)
 			return http.StatusBadRequest, nil, defErr.Error(common.CCErrHostFavouriteQueryFail)
@@ -130,7 +132,7 @@ func (cli *hostFavourite) UpdateHostFavouriteByID(req *restful.Request, resp *re
 			blog.Info("host favorites not permissions or not exists, params:%v", params)
 			return http.StatusBadRequest, nil, defErr.Error(common.CCErrHostFavouriteUpdateFail)
 		}
-		err = cc.InstCli.UpdateByCondition(TABLENAME, data, params)
+		err = storage.ContextOf(cc.InstCli).UpdateByConditionCtx(req.Request.Context(), TABLENAME, data, params)
 		if nil != err {
 			blog.Error("updata host favorites fail, error information is %s, params:%v", err.Error(), params)
 			return http.StatusBadRequest, nil, defErr.Error(common.CCErrHostFavouriteUpdateFail)
@@ -153,7 +155,7 @@ func (cli *hostFavourite) DeleteHostFavouriteByID(req *restful.Request, resp *re
 		params["user"] = req.PathParameter("user") //libraries.GetOperateUser(req)
 		params["id"] = ID
 
-		rowCount, err := cc.InstCli.GetCntByCondition(TABLENAME, params)
+		rowCount, err := storage.ContextOf(cc.InstCli).GetCntByConditionCtx(req.Request.Context(), TABLENAME, params)
 		if nil != err {
 			blog.Error("query host favorites fail, error information is %s, params:%v", err.Error(), params)
 			return http.StatusBadRequest, nil, defErr.Error(common.CCErrHostFavouriteQueryFail)
@@ -162,7 +164,7 @@ func (cli *hostFavourite) DeleteHostFavouriteByID(req *restful.Request, resp *re
 			blog.Info("host favorites not permissions or not exists, params:%v", params)
 			return http.StatusBadRequest, nil, defErr.Error(common.CCErrHostFavouriteDeleteFail)
 		}
-		err = cc.InstCli.DelByCondition(TABLENAME, params)
+		err = storage.ContextOf(cc.InstCli).DelByConditionCtx(req.Request.Context(), TABLENAME, params)
 		if nil != err {
 			blog.Error("query host favourite fail, error information is %s, params:%v", err.Error(), params)
 			return http.StatusBadRequest, nil, defErr.Error(common.CCErrHostFavouriteDeleteFail)
@@ -219,12 +221,12 @@ func (cli *hostFavourite) GetHostFavourites(req *restful.Request, resp *restful.
 
 		condition["user"] = req.PathParameter("user") //libraries.GetOperateUser(req)
 		result := make([]interface{}, 0)
-		count, err := cc.InstCli.GetCntByCondition(TABLENAME, condition)
+		count, err := storage.ContextOf(cc.InstCli).GetCntByConditionCtx(req.Request.Context(), TABLENAME, condition)
 		if err != nil {
 			blog.Error("get host favorites infomation error,input:%v error:%v", string(value), err)
 			return http.StatusBadRequest, nil, defErr.Error(common.CCErrHostFavouriteQueryFail)
 		}
-		err = cc.InstCli.GetMutilByCondition(TABLENAME, fieldArr, condition, &result, sort, skip, limit)
+		err = storage.ContextOf(cc.InstCli).GetMutilByConditionCtx(req.Request.Context(), TABLENAME, fieldArr, condition, &result, sort, skip, limit)
 		if err != nil {
 			blog.Error("get host favorites infomation error,input:%v error:%v", string(value), err)
 			return http.StatusBadRequest, nil, defErr.Error(common.CCErrHostFavouriteQueryFail)
@@ -257,7 +259,7 @@ func (cli *hostFavourite) GetHostFavouriteByID(req *restful.Request, resp *restf
 		params["id"] = ID
 
 		result := make(map[string]interface{})
-		err := cc.InstCli.GetOneByCondition(TABLENAME, nil, params, &result)
+		err := storage.ContextOf(cc.InstCli).GetOneByConditionCtx(req.Request.Context(), TABLENAME, nil, params, &result)
 		if err != nil && mgo_on_not_found_error != err.Error() {
 			blog.Error("get host favourite infomation error,input:%v error:%v", ID, err)
 			return http.StatusBadRequest, nil, defErr.Error(common.CCErrHostFavouriteQueryFail)
@@ -19,6 +19,7 @@ import (
 	"configcenter/src/common/core/cc/actions"
 	"configcenter/src/common/core/cc/api"
 	"configcenter/src/common/util"
+	"configcenter/src/storage"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -68,7 +69,7 @@ func (cli *historyAction) AddHistory(req *restful.Request, resp *restful.Respons
 		id := xid.New()
 		data["id"] = id.String()
 
-		_, err = history.CC.InstCli.Insert("cc_History", data)
+		_, err = storage.ContextOf(history.CC.InstCli).InsertCtx(req.Request.Context(), "cc_History", data)
 		if nil != err {
 			blog.Error("Create  history fail, error information is %s, params:%v", err.Error(), data)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrCommDBInsertFailed)
@@ -98,13 +99,13 @@ func (cli *historyAction) GetHistorys(req *restful.Request, resp *restful.Respon
 		//GetMutilByCondition(cName string, fields []string, s interface{}, result interface{}, sort string, skip, limit int) error
 		var result []interface{}
 		sort := "-" + common.LastTimeField
-		err := history.CC.InstCli.GetMutilByCondition("cc_History", fields, conds, &result, sort, start, limit)
+		err := storage.ContextOf(history.CC.InstCli).GetMutilByConditionCtx(req.Request.Context(), "cc_History", fields, conds, &result, sort, start, limit)
 		if nil != err {
 			blog.Error("query  history fail, error information is %s, params:%v", err.Error(), conds)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrCommDBSelectFailed)
 		}
 
-		nums, err := history.CC.InstCli.GetCntByCondition("cc_History", conds)
+		nums, err := storage.ContextOf(history.CC.InstCli).GetCntByConditionCtx(req.Request.Context(), "cc_History", conds)
 		if nil != err {
 			blog.Error("query  history fail, error information is %s, params:%v", err.Error(), conds)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrCommDBInsertFailed)
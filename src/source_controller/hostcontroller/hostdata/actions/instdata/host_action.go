@@ -22,6 +22,7 @@ import (
 	"configcenter/src/source_controller/common/commondata"
 	"configcenter/src/source_controller/common/eventdata"
 	"configcenter/src/source_controller/common/instdata"
+	"configcenter/src/storage"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
@@ -58,7 +59,7 @@ func (cli *hostAction) AddHost(req *restful.Request, resp *restful.Response) {
 		blog.Info("create object type:%s,data:%v", objType, input)
 		input[common.CreateTimeField] = time.Now()
 		var idName string
-		ID, err := instdata.CreateObject(objType, input, &idName)
+		ID, err := instdata.CreateObject(req.Request.Context(), objType, input, &idName)
 		if err != nil {
 			blog.Error("create object type:%s,data:%v error:%v", objType, input, err)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrHostCreateInst)
@@ -66,7 +67,7 @@ func (cli *hostAction) AddHost(req *restful.Request, resp *restful.Response) {
 
 		// record event
 		originData := map[string]interface{}{}
-		if err := instdata.GetObjectByID(objType, nil, ID, originData, ""); err != nil {
+		if err := instdata.GetObjectByID(req.Request.Context(), objType, nil, ID, originData, ""); err != nil {
 			blog.Error("create event error:%v", err)
 		} else {
 			ec := eventdata.NewEventContextByReq(req)
@@ -82,6 +83,56 @@ func (cli *hostAction) AddHost(req *restful.Request, resp *restful.Response) {
 	}, resp)
 }
 
+//AddHosts add hosts to resource in batch, the host IDs are returned in the order of the input
+func (cli *hostAction) AddHosts(req *restful.Request, resp *restful.Response) {
+	// get the language
+	language := util.GetActionLanguage(req)
+	// get the error factory by the language
+	defErr := cli.CC.Error.CreateDefaultCCErrorIf(language)
+
+	cli.CallResponseEx(func() (int, interface{}, error) {
+		objType := common.BKInnerObjIDHost
+		instdata.DataH = cli.CC.InstCli
+		value, err := ioutil.ReadAll(req.Request.Body)
+		if nil != err {
+			return http.StatusBadRequest, nil, defErr.Error(common.CCErrCommHTTPReadBodyFailed)
+		}
+		inputs := make([]map[string]interface{}, 0)
+		if err := json.Unmarshal(value, &inputs); nil != err {
+			blog.Error("create object type:%s,data:%s error:%v", objType, value, err)
+			return http.StatusBadRequest, nil, defErr.Error(common.CCErrCommJSONUnmarshalFailed)
+		}
+		ts := time.Now()
+		for _, input := range inputs {
+			input[common.CreateTimeField] = ts
+		}
+		var idName string
+		IDs, err := instdata.CreateObjects(req.Request.Context(), objType, inputs, &idName)
+		if err != nil {
+			blog.Error("create object type:%s,count:%d error:%v", objType, len(inputs), err)
+			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrHostCreateInst)
+		}
+
+		// record event
+		originDatas := make([]map[string]interface{}, 0)
+		condition := map[string]interface{}{idName: map[string]interface{}{common.BKDBIN: IDs}}
+		if err := instdata.GetObjectByCondition(req.Request.Context(), objType, nil, condition, &originDatas, "", 0, 0); err != nil {
+			blog.Error("create event error:%v", err)
+		} else {
+			ec := eventdata.NewEventContextByReq(req)
+			for _, originData := range originDatas {
+				if err := ec.InsertEvent(eventtypes.EventTypeInstData, "host", eventtypes.EventActionCreate, originData, nil); err != nil {
+					blog.Error("create event error:%v", err)
+				}
+			}
+		}
+
+		info := make(map[string][]int)
+		info[idName] = IDs
+		return http.StatusOK, info, nil
+	}, resp)
+}
+
 //GetHostByID get host detail
 func (cli *hostAction) GetHostByID(req *restful.Request, resp *restful.Response) {
 
@@ -97,7 +148,7 @@ func (cli *hostAction) GetHostByID(req *restful.Request, resp *restful.Response)
 		condition := make(map[string]interface{})
 		condition[common.BKHostIDField] = hostID
 		fields := make([]string, 0)
-		err := cli.CC.InstCli.GetOneByCondition("cc_HostBase", fields, condition, &result)
+		err := storage.ContextOf(cli.CC.InstCli).GetOneByConditionCtx(req.Request.Context(), "cc_HostBase", fields, condition, &result)
 		if err != nil {
 			blog.Error("get GetHostByID err %v", err)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrCommDBSelectFailed)
@@ -117,6 +168,11 @@ func (cli *hostAction) GetHosts(req *restful.Request, resp *restful.Response) {
 	cli.CallResponseEx(func() (int, interface{}, error) {
 		objType := common.BKInnerObjIDHost
 		instdata.DataH = cli.CC.InstCli
+		// the search is taken off the primary only when the caller tolerates the replication lag
+		ctx := req.Request.Context()
+		if util.IsLagTolerant(req) {
+			ctx = storage.WithReadPreference(ctx, cli.CC.SearchReadPreference)
+		}
 
 		value, err := ioutil.ReadAll(req.Request.Body)
 		var dat commondata.ObjQueryInput
@@ -132,17 +188,27 @@ func (cli *hostAction) GetHosts(req *restful.Request, resp *restful.Response) {
 		sort := dat.Sort
 		fieldArr := strings.Split(fields, ",")
 		result := make([]interface{}, 0)
-		count, err := instdata.GetCntByCondition(objType, condition)
+		count, err := instdata.GetCntByCondition(ctx, objType, condition)
 		if err != nil {
 			blog.Error("get object type:%s,input:%v error:%v", objType, value, err)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrHostSelectInst)
 		}
-		err = instdata.GetObjectByCondition(objType, fieldArr, condition, &result, sort, start, limit)
+		info := make(map[string]interface{})
+		if nil != dat.Cursor {
+			var next string
+			next, err = instdata.GetObjectByCursor(ctx, objType, fieldArr, condition, &result, sort, *dat.Cursor, limit)
+			info["next_cursor"] = next
+		} else {
+			err = instdata.GetObjectByCondition(ctx, objType, fieldArr, condition, &result, sort, start, limit)
+		}
+		if storage.ErrInvalidCursor == err {
+			blog.Error("get object type:%s,input:%v error:%v", objType, value, err)
+			return http.StatusBadRequest, nil, defErr.Errorf(common.CCErrCommParamsInvalid, "cursor")
+		}
 		if err != nil {
 			blog.Error("get object type:%s,input:%v error:%v", objType, value, err)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrHostSelectInst)
 		}
-		info := make(map[string]interface{})
 		info["count"] = count
 		info["info"] = result
 		return http.StatusOK, info, nil
@@ -174,6 +240,7 @@ func init() {
 	actions.RegisterNewAction(actions.Action{Verb: common.HTTPSelectGet, Path: "/host/{bk_host_id}", Params: nil, Handler: host.GetHostByID})
 	actions.RegisterNewAction(actions.Action{Verb: common.HTTPSelectPost, Path: "/hosts/search", Params: nil, Handler: host.GetHosts})
 	actions.RegisterNewAction(actions.Action{Verb: common.HTTPCreate, Path: "/insts", Params: nil, Handler: host.AddHost})
+	actions.RegisterNewAction(actions.Action{Verb: common.HTTPCreate, Path: "/insts/batch", Params: nil, Handler: host.AddHosts})
 	actions.RegisterNewAction(actions.Action{Verb: common.HTTPSelectGet, Path: "/host/snapshot/{bk_host_id}", Params: nil, Handler: host.GetHostSnap})
 
 	// create cc object
@@ -22,6 +22,7 @@ import (
 	"configcenter/src/source_controller/common/eventdata"
 	"configcenter/src/source_controller/common/instdata"
 	"configcenter/src/source_controller/hostcontroller/hostdata/logics"
+	"configcenter/src/storage"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
@@ -73,7 +74,7 @@ func (cli *moduleHostConfigAction) AddModuleHostConfig(req *restful.Request, res
 		//add new relation ship
 		ec := eventdata.NewEventContextByReq(req)
 		for _, moduleID := range params.ModuleID {
-			_, err := logics.AddSingleHostModuleRelation(ec, cc, hostID, moduleID, params.ApplicationID)
+			_, err := logics.AddSingleHostModuleRelation(req.Request.Context(), ec, cc, hostID, moduleID, params.ApplicationID)
 			if nil != err {
 				return http.StatusInternalServerError, nil, defErr.Error(common.CCErrHostTransferModule)
 			}
@@ -105,7 +106,7 @@ func (cli *moduleHostConfigAction) DelDefaultModuleHostConfig(req *restful.Reque
 			return http.StatusBadRequest, nil, defErr.Error(common.CCErrCommJSONUnmarshalFailed)
 		}
 
-		defaultModuleIDs, err := logics.GetDefaultModuleIDs(cc, params.ApplicationID)
+		defaultModuleIDs, err := logics.GetDefaultModuleIDs(req.Request.Context(), cc, params.ApplicationID)
 		if nil != err {
 			blog.Errorf("defaultModuleIds appID:%d, error:%v", params.ApplicationID, err)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrGetModule)
@@ -116,7 +117,7 @@ func (cli *moduleHostConfigAction) DelDefaultModuleHostConfig(req *restful.Reque
 		//delete default host module relation
 		ec := eventdata.NewEventContextByReq(req)
 		for _, defaultModuleID := range defaultModuleIDs {
-			_, err := logics.DelSingleHostModuleRelation(ec, cc, hostID, defaultModuleID, params.ApplicationID)
+			_, err := logics.DelSingleHostModuleRelation(req.Request.Context(), ec, cc, hostID, defaultModuleID, params.ApplicationID)
 			if nil != err {
 				return http.StatusInternalServerError, nil, defErr.Error(common.CCErrDelDefaultModuleHostConfig)
 			}
@@ -151,14 +152,14 @@ func (cli *moduleHostConfigAction) DelModuleHostConfig(req *restful.Request, res
 		getModuleParams := make(map[string]interface{}, 2)
 		getModuleParams[common.BKHostIDField] = params.HostID
 		getModuleParams[common.BKAppIDField] = params.ApplicationID
-		moduleIDs, err := logics.GetModuleIDsByHostID(cc, getModuleParams) //params.HostID, params.ApplicationID)
+		moduleIDs, err := logics.GetModuleIDsByHostID(req.Request.Context(), cc, getModuleParams) //params.HostID, params.ApplicationID)
 		if nil != err {
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrGetOriginHostModuelRelationship)
 		}
 
 		ec := eventdata.NewEventContextByReq(req)
 		for _, moduleID := range moduleIDs {
-			_, err := logics.DelSingleHostModuleRelation(ec, cc, params.HostID, moduleID, params.ApplicationID)
+			_, err := logics.DelSingleHostModuleRelation(req.Request.Context(), ec, cc, params.HostID, moduleID, params.ApplicationID)
 			if nil != err {
 				return http.StatusInternalServerError, nil, defErr.Error(common.CCErrDelOriginHostModuelRelationship)
 			}
@@ -189,7 +190,7 @@ func (cli *moduleHostConfigAction) GetHostModulesIDs(req *restful.Request, resp
 			return http.StatusBadRequest, nil, defErr.Error(common.CCErrCommJSONUnmarshalFailed)
 		}
 
-		moduleIDs, err := logics.GetModuleIDsByHostID(cc, map[string]interface{}{common.BKAppIDField: params.ApplicationID, common.BKHostIDField: params.HostID}) //params.HostID, params.ApplicationID)
+		moduleIDs, err := logics.GetModuleIDsByHostID(req.Request.Context(), cc, map[string]interface{}{common.BKAppIDField: params.ApplicationID, common.BKHostIDField: params.HostID}) //params.HostID, params.ApplicationID)
 		if nil != err {
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrGetModule)
 		}
@@ -230,12 +231,12 @@ func (cli *moduleHostConfigAction) AssignHostToApp(req *restful.Request, resp *r
 		getModuleParams := make(map[string]interface{})
 		for _, hostID := range params.HostID {
 			//delete relation in default app module
-			_, err := logics.DelSingleHostModuleRelation(ec, cc, hostID, params.OwnerModuleID, params.OwnerApplicationID)
+			_, err := logics.DelSingleHostModuleRelation(req.Request.Context(), ec, cc, hostID, params.OwnerModuleID, params.OwnerApplicationID)
 			if nil != err {
 				return http.StatusInternalServerError, nil, defErr.Error(common.CCErrTransferHostFromPool)
 			}
 			getModuleParams[common.BKHostIDField] = hostID
-			moduleIDs, err := logics.GetModuleIDsByHostID(cc, getModuleParams)
+			moduleIDs, err := logics.GetModuleIDsByHostID(req.Request.Context(), cc, getModuleParams)
 			if nil != err {
 				return http.StatusInternalServerError, nil, defErr.Error(common.CCErrGetModule)
 			}
@@ -245,7 +246,7 @@ func (cli *moduleHostConfigAction) AssignHostToApp(req *restful.Request, resp *r
 			}
 
 			//add new host
-			_, err = logics.AddSingleHostModuleRelation(ec, cc, hostID, params.ModuleID, params.ApplicationID)
+			_, err = logics.AddSingleHostModuleRelation(req.Request.Context(), ec, cc, hostID, params.ModuleID, params.ApplicationID)
 			if nil != err {
 			}
 		}
@@ -284,7 +285,7 @@ func (cli *moduleHostConfigAction) GetModulesHostConfig(req *restful.Request, re
 		}
 		fields := []string{common.BKAppIDField, common.BKHostIDField, common.BKSetIDField, common.BKModuleIDField}
 		var result []interface{}
-		err = cc.InstCli.GetMutilByCondition("cc_ModuleHostConfig", fields, query, &result, common.BKHostIDField, 0, 100000)
+		err = storage.ContextOf(cc.InstCli).GetMutilByConditionCtx(req.Request.Context(), "cc_ModuleHostConfig", fields, query, &result, common.BKHostIDField, 0, 100000)
 		if err != nil {
 			blog.Error("fail to get module host config %v", err)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrCommDBSelectFailed)
@@ -321,12 +322,12 @@ func (cli *moduleHostConfigAction) MoveHost2ResourcePool(req *restful.Request, r
 			blog.Error("fail to unmarshal json, error information is %v", err)
 			return http.StatusBadRequest, nil, defErr.Error(common.CCErrCommJSONUnmarshalFailed)
 		}
-		idleModuleID, err := logics.GetIDleModuleID(cc, params.ApplicationID)
+		idleModuleID, err := logics.GetIDleModuleID(req.Request.Context(), cc, params.ApplicationID)
 		if nil != err {
 			blog.Error("获取业务默认模块失败 error:%s", err.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrGetModule)
 		}
-		errHostIDs, err := logics.CheckHostInIDle(cc, params.ApplicationID, idleModuleID, params.HostID)
+		errHostIDs, err := logics.CheckHostInIDle(req.Request.Context(), cc, params.ApplicationID, idleModuleID, params.HostID)
 		if nil != err {
 			blog.Error("获取主机模块关系失败， error:%s", err.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrGetModule)
@@ -339,13 +340,13 @@ func (cli *moduleHostConfigAction) MoveHost2ResourcePool(req *restful.Request, r
 		var succ, addErr, delErr []int
 		for _, hostID := range params.HostID {
 			//add new host
-			_, err = logics.AddSingleHostModuleRelation(ec, cc, hostID, params.OwnerModuleID, params.OwnerAppplicationID)
+			_, err = logics.AddSingleHostModuleRelation(req.Request.Context(), ec, cc, hostID, params.OwnerModuleID, params.OwnerAppplicationID)
 			if nil != err {
 				addErr = append(addErr, hostID)
 				continue
 			}
 			//delete origin relation
-			_, err := logics.DelSingleHostModuleRelation(ec, cc, hostID, idleModuleID, params.ApplicationID)
+			_, err := logics.DelSingleHostModuleRelation(req.Request.Context(), ec, cc, hostID, idleModuleID, params.ApplicationID)
 			if nil != err {
 				delErr = append(delErr, hostID)
 				continue
@@ -19,6 +19,7 @@ import (
 	"configcenter/src/common/blog"
 	"configcenter/src/common/util"
 	"configcenter/src/source_controller/common/commondata"
+	"configcenter/src/storage"
 	"encoding/json"
 	"io"
 	"io/ioutil"
@@ -77,7 +78,7 @@ func (u *userAPIAction) Add(req *restful.Request, resp *restful.Response) {
 	queryParams[common.BKAppIDField] = appID
 	queryParams["name"] = name
 
-	rowCount, err := userAPI.CC.InstCli.GetCntByCondition(u.tableName, queryParams)
+	rowCount, err := storage.ContextOf(userAPI.CC.InstCli).GetCntByConditionCtx(req.Request.Context(), u.tableName, queryParams)
 	if nil != err {
 		blog.Error("query user api fail, error information is %s, params:%v", err.Error(), queryParams)
 		userAPI.ResponseFailedEx(http.StatusBadGateway, common.CCErrCommDBSelectFailed, defErr.Error(common.CCErrCommDBSelectFailed).Error(), resp)
@@ -96,7 +97,7 @@ func (u *userAPIAction) Add(req *restful.Request, resp *restful.Response) {
 	params[common.CreateTimeField] = time.Now()
 	params["modify_user"] = ""
 	params[common.LastTimeField] = ""
-	_, err = u.CC.InstCli.Insert(u.tableName, params)
+	_, err = storage.ContextOf(u.CC.InstCli).InsertCtx(req.Request.Context(), u.tableName, params)
 
 	if err != nil {
 		blog.Error("create user api  error:data:%v error:%v", params, err)
@@ -135,7 +136,7 @@ func (u *userAPIAction) Update(req *restful.Request, resp *restful.Response) {
 	params["id"] = ID
 	params[common.BKAppIDField] = appID
 
-	rowCount, err := u.CC.InstCli.GetCntByCondition(u.tableName, params)
+	rowCount, err := storage.ContextOf(u.CC.InstCli).GetCntByConditionCtx(req.Request.Context(), u.tableName, params)
 	if nil != err {
 		blog.Error("query user api fail, error information is %s, params:%v", err.Error(), params)
 		userAPI.ResponseFailedEx(http.StatusBadGateway, common.CCErrCommDBSelectFailed, defErr.Error(common.CCErrCommDBSelectFailed).Error(), resp)
@@ -148,7 +149,7 @@ func (u *userAPIAction) Update(req *restful.Request, resp *restful.Response) {
 	}
 	//json 中的数字会被转换未doubule， 转换未int64
 	data[common.BKAppIDField] = appID
-	err = u.CC.InstCli.UpdateByCondition(u.tableName, data, params)
+	err = storage.ContextOf(u.CC.InstCli).UpdateByConditionCtx(req.Request.Context(), u.tableName, data, params)
 	if nil != err {
 		blog.Error("updata user api fail, error information is %s, params:%v", err.Error(), params)
 		userAPI.ResponseFailedEx(http.StatusBadGateway, common.CCErrCommDBUpdateFailed, defErr.Errorf(common.CCErrCommDBUpdateFailed).Error(), resp)
@@ -172,7 +173,7 @@ func (u *userAPIAction) Delete(req *restful.Request, resp *restful.Response) {
 	params[common.BKAppIDField] = appID
 	params["id"] = ID
 
-	rowCount, err := u.CC.InstCli.GetCntByCondition(u.tableName, params)
+	rowCount, err := storage.ContextOf(u.CC.InstCli).GetCntByConditionCtx(req.Request.Context(), u.tableName, params)
 	if nil != err {
 		blog.Error("query user api fail, error information is %s, params:%v", err.Error(), params)
 		userAPI.ResponseFailedEx(http.StatusBadGateway, common.CCErrCommDBSelectFailed, defErr.Error(common.CCErrCommDBSelectFailed).Error(), resp)
@@ -183,7 +184,7 @@ func (u *userAPIAction) Delete(req *restful.Request, resp *restful.Response) {
 		userAPI.ResponseFailedEx(http.StatusBadRequest, common.CCErrCommNotFound, defErr.Error(common.CCErrCommNotFound).Error(), resp)
 		return
 	}
-	err = u.CC.InstCli.DelByCondition(u.tableName, params)
+	err = storage.ContextOf(u.CC.InstCli).DelByConditionCtx(req.Request.Context(), u.tableName, params)
 	if nil != err {
 		blog.Error("delete user api fail, error information is %s, params:%v", err.Error(), params)
 		userAPI.ResponseFailedEx(http.StatusBadGateway, common.CCErrCommDBDeleteFailed, defErr.Errorf(common.CCErrCommDBDeleteFailed).Error(), resp)
@@ -244,14 +245,14 @@ func (u *userAPIAction) Get(req *restful.Request, resp *restful.Response) {
 
 	condition[common.BKAppIDField] = appID
 	//result := make([]interface{}, 0)
-	count, err := u.CC.InstCli.GetCntByCondition(u.tableName, condition)
+	count, err := storage.ContextOf(u.CC.InstCli).GetCntByConditionCtx(req.Request.Context(), u.tableName, condition)
 	if err != nil {
 		blog.Error("get user api infomation error,input:%v error:%v", string(value), err)
 		userAPI.ResponseFailedEx(http.StatusBadGateway, common.CCErrCommDBSelectFailed, defErr.Error(common.CCErrCommDBSelectFailed).Error(), resp)
 		return
 	}
 	var result []interface{}
-	err = u.CC.InstCli.GetMutilByCondition(u.tableName, fieldArr, condition, &result, sort, skip, limit)
+	err = storage.ContextOf(u.CC.InstCli).GetMutilByConditionCtx(req.Request.Context(), u.tableName, fieldArr, condition, &result, sort, skip, limit)
 	if err != nil {
 		blog.Error("get user api infomation error,input:%v error:%v", string(value), err)
 		userAPI.ResponseFailedEx(http.StatusBadGateway, common.CCErrCommDBSelectFailed, defErr.Error(common.CCErrCommDBSelectFailed).Error(), resp)
@@ -280,7 +281,7 @@ func (u *userAPIAction) Detail(req *restful.Request, resp *restful.Response) {
 	var fieldArr []string
 
 	result := make(map[string]interface{})
-	err := u.CC.InstCli.GetOneByCondition(u.tableName, fieldArr, params, &result)
+	err := storage.ContextOf(u.CC.InstCli).GetOneByConditionCtx(req.Request.Context(), u.tableName, fieldArr, params, &result)
 	if err != nil && mgo_on_not_found_error != err.Error() {
 		blog.Error("get user api infomation error,input:%v error:%v", ID, err)
 		userAPI.ResponseFailedEx(http.StatusBadGateway, common.CCErrCommDBSelectFailed, defErr.Error(common.CCErrCommDBSelectFailed).Error(), resp)
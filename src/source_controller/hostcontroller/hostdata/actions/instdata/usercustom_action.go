@@ -20,6 +20,7 @@ import (
 	"configcenter/src/common/core/cc/api"
 	"configcenter/src/common/util"
 	_ "configcenter/src/common/util"
+	"configcenter/src/storage"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
@@ -66,7 +67,8 @@ func (cli *userCustomAction) AddUserCustom(req *restful.Request, resp *restful.R
 		ID := xid.New()
 		data["id"] = ID.String()
 		data["bk_user"] = req.PathParameter("bk_user") //libraries.GetOperateUser(req)
-		_, err = cc.InstCli.Insert(userCustomTableName, data)
+		data[common.BKOwnerIDField] = util.GetActionOnwerID(req)
+		_, err = storage.ContextOf(cc.InstCli).InsertCtx(req.Request.Context(), userCustomTableName, data)
 		if nil != err {
 			blog.Error("Create  user custom fail, error information is %s, params:%v", err.Error(), data)
 			return http.StatusBadRequest, nil, defErr.Error(common.CCErrCreateUserCustom)
@@ -102,7 +104,7 @@ func (cli *userCustomAction) UpdateUserCustomByID(req *restful.Request, resp *re
 			return http.StatusBadRequest, nil, defErr.Error(common.CCErrCommJSONUnmarshalFailed)
 
 		}
-		err = cc.InstCli.UpdateByCondition(userCustomTableName, data, conditons)
+		err = storage.ContextOf(cc.InstCli).UpdateByConditionCtx(req.Request.Context(), userCustomTableName, data, conditons)
 		if nil != err {
 			blog.Error("add  user custom fail, error information is %s, params:%v", err.Error(), data)
 			return http.StatusBadRequest, nil, defErr.Error(common.CCErrCommDBUpdateFailed)
@@ -127,7 +129,7 @@ func (cli *userCustomAction) GetUserCustomByUser(req *restful.Request, resp *res
 		conds["bk_user"] = user
 
 		result := make(map[string]interface{})
-		err := cc.InstCli.GetOneByCondition(userCustomTableName, nil, conds, &result)
+		err := storage.ContextOf(cc.InstCli).GetOneByConditionCtx(req.Request.Context(), userCustomTableName, nil, conds, &result)
 		if nil != err && mgo_on_not_found_error != err.Error() { //get one row from mgo, not found是未找到数据
 			blog.Error("add  user custom fail, error information is %s, params:%v", err.Error(), conds)
 			return http.StatusBadRequest, nil, defErr.Error(common.CCErrCommDBSelectFailed)
@@ -150,7 +152,7 @@ func (cli *userCustomAction) GetDefaultUserCustom(req *restful.Request, resp *re
 		conds["is_default"] = 1
 
 		result := make(map[string]interface{})
-		err := cc.InstCli.GetOneByCondition(userCustomTableName, nil, conds, &result)
+		err := storage.ContextOf(cc.InstCli).GetOneByConditionCtx(req.Request.Context(), userCustomTableName, nil, conds, &result)
 		if nil != err {
 			blog.Error("add  user custom fail, error information is %s, params:%v", err.Error(), conds)
 			return http.StatusBadRequest, nil, defErr.Error(common.CCErrCommDBSelectFailed)
@@ -18,10 +18,13 @@ import (
 	"configcenter/src/common/core/cc/config"
 	"configcenter/src/common/errors"
 	"configcenter/src/common/http/httpserver"
+	"configcenter/src/source_controller/common/eventdata"
 	"configcenter/src/source_controller/common/instdata"
+	"configcenter/src/source_controller/common/txndata"
 	confCenter "configcenter/src/source_controller/hostcontroller/hostdata/config"
 	"configcenter/src/source_controller/hostcontroller/hostdata/rdiscover"
 	"configcenter/src/storage"
+	"context"
 	"sync"
 	"time"
 )
@@ -149,6 +152,8 @@ func (ccAPI *CCAPIServer) Start() error {
 	go func() {
 		wg.Wait()
 		ccAPI.initHttpServ()
+		// push the events left by the committed transactions
+		go eventdata.RecoverTxEventsLoop(context.Background(), a.InstCli, storage.TxTimeout)
 		err := ccAPI.httpServ.ListenAndServe()
 		blog.Error("http listen and serve failed! err:%s", err.Error())
 		chErr <- err
@@ -171,6 +176,6 @@ func (ccAPI *CCAPIServer) Start() error {
 
 func (ccAPI *CCAPIServer) initHttpServ() error {
 	a := api.NewAPIResource()
-	ccAPI.httpServ.RegisterWebServer("/host/{version}", nil, a.Actions)
+	ccAPI.httpServ.RegisterWebServer("/host/{version}", txndata.Filter, a.Actions)
 	return nil
 }
@@ -0,0 +1,82 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logics
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/common/core/cc/api"
+	"configcenter/src/storage/memclient"
+	"context"
+	"testing"
+)
+
+// newMemResource returns the resource of the in memory storage which is dropped by the returned function
+func newMemResource(t *testing.T) (*api.APIResource, func()) {
+	db, err := memclient.NewMemCli(t.Name())
+	if nil == err {
+		err = db.Open()
+	}
+	if nil != err {
+		t.Fatalf("failed to open the memory storage: %v", err)
+	}
+	return &api.APIResource{InstCli: db}, func() { memclient.Drop(t.Name()) }
+}
+
+func TestGetDefaultModuleIDsMemory(t *testing.T) {
+	cc, drop := newMemResource(t)
+	defer drop()
+
+	err := cc.InstCli.InsertMuti(moduleBaseTaleName,
+		map[string]interface{}{common.BKModuleIDField: 11, common.BKAppIDField: 1, common.BKDefaultField: common.DefaultResModuleFlag},
+		map[string]interface{}{common.BKModuleIDField: 12, common.BKAppIDField: 1, common.BKDefaultField: common.DefaultFaultModuleFlag},
+		map[string]interface{}{common.BKModuleIDField: 13, common.BKAppIDField: 1, common.BKDefaultField: 0},
+		map[string]interface{}{common.BKModuleIDField: 21, common.BKAppIDField: 2, common.BKDefaultField: common.DefaultResModuleFlag},
+	)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	ids, err := GetDefaultModuleIDs(context.Background(), cc, 1)
+	if nil != err {
+		t.Fatalf("error not as expected: %v", err)
+	}
+	if 2 != len(ids) || 11 != ids[0] || 12 != ids[1] {
+		t.Errorf("result not as expected: %v", ids)
+	}
+
+	if _, err := GetDefaultModuleIDs(context.Background(), cc, 3); nil == err {
+		t.Errorf("error not as expected, should not find the modules")
+	}
+}
+
+func TestGetModuleIDsByHostIDMemory(t *testing.T) {
+	cc, drop := newMemResource(t)
+	defer drop()
+
+	err := cc.InstCli.InsertMuti("cc_ModuleHostConfig",
+		map[string]interface{}{common.BKHostIDField: 1, common.BKModuleIDField: 11, common.BKAppIDField: 1},
+		map[string]interface{}{common.BKHostIDField: 1, common.BKModuleIDField: 12, common.BKAppIDField: 1},
+		map[string]interface{}{common.BKHostIDField: 2, common.BKModuleIDField: 11, common.BKAppIDField: 1},
+	)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	ids, err := GetModuleIDsByHostID(context.Background(), cc, map[string]interface{}{common.BKHostIDField: 1})
+	if nil != err {
+		t.Fatalf("error not as expected: %v", err)
+	}
+	if 2 != len(ids) || 11 != ids[0] || 12 != ids[1] {
+		t.Errorf("result not as expected: %v", ids)
+	}
+}
@@ -21,6 +21,8 @@ import (
 	metadataTable "configcenter/src/source_controller/api/metadata"
 	"configcenter/src/source_controller/common/eventdata"
 	"configcenter/src/source_controller/common/instdata"
+	"configcenter/src/storage"
+	"context"
 	"errors"
 
 	"gopkg.in/mgo.v2/bson"
@@ -37,12 +39,12 @@ type moduleHostConfigParams struct {
 }
 
 //DelSingleHostModuleRelation delete single host module relation
-func DelSingleHostModuleRelation(ec *eventdata.EventContext, cc *api.APIResource, hostID, moduleID, appID int) (bool, error) {
+func DelSingleHostModuleRelation(ctx context.Context, ec *eventdata.EventContext, cc *api.APIResource, hostID, moduleID, appID int) (bool, error) {
 
 	//get host info
 	hostFieldArr := []string{common.BKHostInnerIPField}
 	hostResult := make(map[string]interface{}, 0)
-	errHost := instdata.GetObjectByID(common.BKInnerObjIDHost, hostFieldArr, hostID, &hostResult, common.BKHostIDField)
+	errHost := instdata.GetObjectByID(ctx, common.BKInnerObjIDHost, hostFieldArr, hostID, &hostResult, common.BKHostIDField)
 	blog.Infof("DelSingleHostModuleRelation hostID:%d, hostinfo:%v", hostID, hostResult)
 	if errHost != nil {
 		blog.Error("delSingleHostModuleRelation get host error:%s, host:%v", errHost.Error(), hostID)
@@ -51,7 +53,7 @@ func DelSingleHostModuleRelation(ec *eventdata.EventContext, cc *api.APIResource
 
 	moduleFieldArr := []string{common.BKModuleNameField}
 	var moduleResult interface{}
-	errModule := instdata.GetObjectByID(common.BKInnerObjIDModule, moduleFieldArr, moduleID, &moduleResult, common.BKModuleNameField)
+	errModule := instdata.GetObjectByID(ctx, common.BKInnerObjIDModule, moduleFieldArr, moduleID, &moduleResult, common.BKModuleNameField)
 	blog.Infof("DelSingleHostModuleRelation module:%d, module info:%v", moduleID, moduleResult)
 	if errModule != nil {
 		blog.Error("delSingleHostModuleRelation get module moduleID:%d, error:%s,", moduleID, errModule.Error())
@@ -64,7 +66,7 @@ func DelSingleHostModuleRelation(ec *eventdata.EventContext, cc *api.APIResource
 	delCondition[common.BKAppIDField] = appID
 	delCondition[common.BKHostIDField] = hostID
 	delCondition[common.BKModuleIDField] = moduleID
-	num, numError := cc.InstCli.GetCntByCondition(tableName.TableName(), delCondition)
+	num, numError := storage.ContextOf(cc.InstCli).GetCntByConditionCtx(ctx, tableName.TableName(), delCondition)
 	blog.Infof("DelSingleHostModuleRelation  get module host relation condition:%v", delCondition)
 	if numError != nil {
 		blog.Error("delSingleHostModuleRelation get module host relation error:", numError.Error())
@@ -77,13 +79,13 @@ func DelSingleHostModuleRelation(ec *eventdata.EventContext, cc *api.APIResource
 
 	// retrieve original datas
 	origindatas := make([]map[string]interface{}, 0)
-	getErr := cc.InstCli.GetMutilByCondition(tableName.TableName(), nil, delCondition, &origindatas, "", 0, 0)
+	getErr := storage.ContextOf(cc.InstCli).GetMutilByConditionCtx(ctx, tableName.TableName(), nil, delCondition, &origindatas, "", 0, 0)
 	if getErr != nil {
 		blog.Error("retrieve original datas error:%v", getErr)
 		return false, getErr
 	}
 
-	delErr := cc.InstCli.DelByCondition(tableName.TableName(), delCondition)
+	delErr := storage.ContextOf(cc.InstCli).DelByConditionCtx(ctx, tableName.TableName(), delCondition)
 	blog.Infof("DelSingleHostModuleRelation delCondition:%v", delCondition)
 	if delErr != nil {
 		blog.Error("delSingleHostModuleRelation del module host relation error:", delErr.Error())
@@ -102,12 +104,12 @@ func DelSingleHostModuleRelation(ec *eventdata.EventContext, cc *api.APIResource
 }
 
 //AddSingleHostModuleRelation add single host module relation
-func AddSingleHostModuleRelation(ec *eventdata.EventContext, cc *api.APIResource, hostID, moduleID, appID int) (bool, error) {
+func AddSingleHostModuleRelation(ctx context.Context, ec *eventdata.EventContext, cc *api.APIResource, hostID, moduleID, appID int) (bool, error) {
 	//get host info
 	hostFieldArr := []string{common.BKHostInnerIPField}
 	hostResult := make(map[string]interface{})
 
-	errHost := instdata.GetObjectByID(common.BKInnerObjIDHost, hostFieldArr, hostID, &hostResult, common.BKHostIDField)
+	errHost := instdata.GetObjectByID(ctx, common.BKInnerObjIDHost, hostFieldArr, hostID, &hostResult, common.BKHostIDField)
 	if errHost != nil {
 		blog.Error("addSingleHostModuleRelation get host error:%s", errHost.Error())
 		return false, errHost
@@ -115,7 +117,7 @@ func AddSingleHostModuleRelation(ec *eventdata.EventContext, cc *api.APIResource
 
 	moduleFieldArr := []string{common.BKModuleNameField, common.BKSetIDField}
 	moduleResult := make(map[string]interface{})
-	errModule := instdata.GetObjectByID(common.BKInnerObjIDModule, moduleFieldArr, moduleID, &moduleResult, common.BKModuleIDField)
+	errModule := instdata.GetObjectByID(ctx, common.BKInnerObjIDModule, moduleFieldArr, moduleID, &moduleResult, common.BKModuleIDField)
 	if errModule != nil {
 		blog.Error("addSingleHostModuleRelation get module moduleid:%d, error:%s", moduleID, errModule.Error())
 		return false, errModule
@@ -135,7 +137,7 @@ func AddSingleHostModuleRelation(ec *eventdata.EventContext, cc *api.APIResource
 	moduleHostConfig[common.BKHostIDField] = hostID
 	moduleHostConfig[common.BKModuleIDField] = moduleID
 
-	num, numError := cc.InstCli.GetCntByCondition(tableName.TableName(), moduleHostConfig)
+	num, numError := storage.ContextOf(cc.InstCli).GetCntByConditionCtx(ctx, tableName.TableName(), moduleHostConfig)
 	if numError != nil {
 		blog.Error("addSingleHostModuleRelation get module host relation error:", numError.Error())
 		return false, numError
@@ -146,7 +148,7 @@ func AddSingleHostModuleRelation(ec *eventdata.EventContext, cc *api.APIResource
 	}
 
 	moduleHostConfig[common.BKSetIDField] = setID
-	_, err := cc.InstCli.Insert(tableName.TableName(), moduleHostConfig)
+	_, err := storage.ContextOf(cc.InstCli).InsertCtx(ctx, tableName.TableName(), moduleHostConfig)
 	if err != nil {
 		blog.Error("addSingleHostModuleRelation add module host relation error:", err.Error())
 		return false, err
@@ -161,14 +163,14 @@ func AddSingleHostModuleRelation(ec *eventdata.EventContext, cc *api.APIResource
 }
 
 //GetDefaultModuleIDs get default module ids
-func GetDefaultModuleIDs(cc *api.APIResource, appID int) ([]int, error) {
+func GetDefaultModuleIDs(ctx context.Context, cc *api.APIResource, appID int) ([]int, error) {
 	defaultModuleCond := make(map[string]interface{}, 2)
 	defaultModuleCond[common.BKDefaultField] = common.KvMap{common.BKDBIN: []int{common.DefaultFaultModuleFlag, common.DefaultResModuleFlag}}
 	defaultModuleCond[common.BKAppIDField] = appID
 	result := make([]interface{}, 0)
 	var ret []int
 
-	err := cc.InstCli.GetMutilByCondition(moduleBaseTaleName, []string{common.BKModuleIDField, common.BKDefaultField}, defaultModuleCond, &result, "ID", 0, 100)
+	err := storage.ContextOf(cc.InstCli).GetMutilByConditionCtx(ctx, moduleBaseTaleName, []string{common.BKModuleIDField, common.BKDefaultField}, defaultModuleCond, &result, "ID", 0, 100)
 	blog.Infof("defaultModuleCond:%v", defaultModuleCond)
 	if nil != err {
 		blog.Errorf("getDefaultModuleIds error:%s, params:%v, %v", err.Error(), defaultModuleCond, result)
@@ -191,12 +193,12 @@ func GetDefaultModuleIDs(cc *api.APIResource, appID int) ([]int, error) {
 }
 
 //GetModuleIDsByHostID get module id by hostid
-func GetModuleIDsByHostID(cc *api.APIResource, moduleCond interface{}) ([]int, error) {
+func GetModuleIDsByHostID(ctx context.Context, cc *api.APIResource, moduleCond interface{}) ([]int, error) {
 	result := make([]interface{}, 0)
 	var ret []int
 
 	tableName := metadataTable.ModuleHostConfig{}
-	err := cc.InstCli.GetMutilByCondition(tableName.TableName(), []string{common.BKModuleIDField}, moduleCond, &result, "", 0, 100)
+	err := storage.ContextOf(cc.InstCli).GetMutilByConditionCtx(ctx, tableName.TableName(), []string{common.BKModuleIDField}, moduleCond, &result, "", 0, 100)
 	blog.Infof("GetModuleIDsByHostID condition:%v", moduleCond)
 	blog.Infof("result:%v", result)
 	if nil != err {
@@ -215,13 +217,13 @@ func GetModuleIDsByHostID(cc *api.APIResource, moduleCond interface{}) ([]int, e
 }
 
 //GetResourcePoolApp get resource pool app
-func GetResourcePoolApp(cc *api.APIResource, ownerID int) (int, error) {
+func GetResourcePoolApp(ctx context.Context, cc *api.APIResource, ownerID int) (int, error) {
 	params := make(map[string]interface{})
 	params[common.BKOwnerIDField] = ownerID
 	params[common.BKDefaultField] = 1
 
 	result := make(map[string]interface{})
-	err := cc.InstCli.GetOneByCondition("cc_ApplicationBase", []string{common.BKAppIDField}, params, &result)
+	err := storage.ContextOf(cc.InstCli).GetOneByConditionCtx(ctx, "cc_ApplicationBase", []string{common.BKAppIDField}, params, &result)
 	if nil != err {
 		blog.Error("getModuleIDsByHostID error:%", err.Error())
 		return 0, errors.New("获取资源池业务失败")
@@ -236,14 +238,14 @@ func GetResourcePoolApp(cc *api.APIResource, ownerID int) (int, error) {
 }
 
 //check if host belong to empty module
-func CheckHostInIDle(cc *api.APIResource, appID, emptyModuleID int, hostIDs []int) ([]int, error) {
+func CheckHostInIDle(ctx context.Context, cc *api.APIResource, appID, emptyModuleID int, hostIDs []int) ([]int, error) {
 
 	moduleHostConfig := metadataTable.ModuleHostConfig{}
 	conds := make(map[string]interface{}, 1)
 	conds[common.BKHostIDField] = bson.M{common.BKDBIN: hostIDs}
 	result := make([]interface{}, 0)
 
-	err := cc.InstCli.GetMutilByCondition(moduleHostConfig.TableName(), []string{common.BKHostIDField, common.BKModuleIDField}, conds, &result, "", 0, common.BKNoLimit)
+	err := storage.ContextOf(cc.InstCli).GetMutilByConditionCtx(ctx, moduleHostConfig.TableName(), []string{common.BKHostIDField, common.BKModuleIDField}, conds, &result, "", 0, common.BKNoLimit)
 	if nil != err {
 		blog.Error("get modulehostconfig error:%s", err.Error())
 		return nil, errors.New("获取主机与模块关系失败")
@@ -269,12 +271,12 @@ func CheckHostInIDle(cc *api.APIResource, appID, emptyModuleID int, hostIDs []in
 }
 
 //获取业务下的默认模块
-func GetIDleModuleID(cc *api.APIResource, appID int) (int, error) {
+func GetIDleModuleID(ctx context.Context, cc *api.APIResource, appID int) (int, error) {
 	defaultModuleCond := make(map[string]interface{}, 2)
 	defaultModuleCond[common.BKDefaultField] = common.DefaultResModuleFlag
 	defaultModuleCond[common.BKAppIDField] = appID
 	result := make(map[string]interface{}, 0)
-	err := cc.InstCli.GetOneByCondition(moduleBaseTaleName, []string{common.BKModuleIDField}, defaultModuleCond, &result)
+	err := storage.ContextOf(cc.InstCli).GetOneByConditionCtx(ctx, moduleBaseTaleName, []string{common.BKModuleIDField}, defaultModuleCond, &result)
 
 	if nil != err {
 		blog.Error("getDefaultModuleIDs error:%s", err.Error())
@@ -17,6 +17,7 @@ import (
     "configcenter/src/source_controller/common/instdata"
     "configcenter/src/common/core/cc/api"
     "configcenter/src/storage"
+    "context"
     "testing"
     "errors"
     "flag"
@@ -57,6 +58,7 @@ func (m *MockDI) InsertMuti(cName string, data ...interface{}) error {return m.E
 func (m *MockDI) UpdateByCondition(cName string, data, condition interface{}) error {return m.ErrUpdateByCondition}
 func (m *MockDI) GetOneByCondition(cName string, fields []string, condition interface{}, result interface{}) error {return m.ErrGetOneByCondition}
 func (m *MockDI) GetMutilByCondition(cName string, fields []string, condition interface{}, result interface{}, sort string, start, limit int) error {return m.ErrGetMutilByCondition}
+func (m *MockDI) GetMutilByCursor(cName string, fields []string, condition interface{}, result interface{}, sort, cursor string, limit int) (string, error) {return "", m.ErrGetMutilByCondition}
 func (m *MockDI) GetCntByCondition(cName string, condition interface{}) (int, error) {return m.VarGetCntByCondition, m.ErrGetCntByCondition}
 func (m *MockDI) DelByCondition(cName string, condition interface{}) error {return m.ErrDelByCondition}
 func (m *MockDI) HasTable(cName string) (bool, error) {return m.VarHasTable, m.ErrHasTable}
@@ -79,7 +81,7 @@ func TestDelSingleHostModuleRelation(t *testing.T) {
 
     errFake := errors.New("fake error")
     instdata.DataH = &MockDI{ErrGetOneByCondition: errFake}
-    _, err := DelSingleHostModuleRelation(ec, cc, 1, 2, 3)
+    _, err := DelSingleHostModuleRelation(context.Background(), ec, cc, 1, 2, 3)
     if err != errFake {
         t.Errorf("error not as expected: %v", err)
     }
@@ -92,7 +94,7 @@ func TestDelSingleHostModuleRelation2(t *testing.T) {
     errFake := errors.New("fake error")
     cc.InstCli = &MockDI{ErrGetCntByCondition: errFake}
     instdata.DataH = &MockDI{}
-    _, err := DelSingleHostModuleRelation(ec, cc, 1, 2, 3)
+    _, err := DelSingleHostModuleRelation(context.Background(), ec, cc, 1, 2, 3)
     if err != errFake {
         t.Errorf("error not as expected: %v", err)
     }
@@ -104,7 +106,7 @@ func TestDelSingleHostModuleRelation3(t *testing.T) {
 
     cc.InstCli = &MockDI{VarGetCntByCondition: 0}
     instdata.DataH = &MockDI{}
-    r, err := DelSingleHostModuleRelation(ec, cc, 1, 2, 3)
+    r, err := DelSingleHostModuleRelation(context.Background(), ec, cc, 1, 2, 3)
     if !r {
         t.Errorf("result not as expected, should be true")
     }
@@ -120,7 +122,7 @@ func TestDelSingleHostModuleRelation4(t *testing.T) {
     errFake := errors.New("fake error")
     cc.InstCli = &MockDI{ErrGetMutilByCondition: errFake, VarGetCntByCondition: 1}
     instdata.DataH = &MockDI{}
-    _, err := DelSingleHostModuleRelation(ec, cc, 1, 2, 3)
+    _, err := DelSingleHostModuleRelation(context.Background(), ec, cc, 1, 2, 3)
     if err != errFake {
         t.Errorf("error not as expected: %v", err)
     }
@@ -133,7 +135,7 @@ func TestDelSingleHostModuleRelation5(t *testing.T) {
     errFake := errors.New("fake error")
     cc.InstCli = &MockDI{ErrDelByCondition: errFake, VarGetCntByCondition: 1}
     instdata.DataH = &MockDI{}
-    _, err := DelSingleHostModuleRelation(ec, cc, 1, 2, 3)
+    _, err := DelSingleHostModuleRelation(context.Background(), ec, cc, 1, 2, 3)
     if err != errFake {
         t.Errorf("error not as expected: %v", err)
     }
@@ -145,7 +147,7 @@ func TestDelSingleHostModuleRelation6(t *testing.T) {
 
     cc.InstCli = &MockDI{VarGetCntByCondition: 1}
     instdata.DataH = &MockDI{}
-    r, err := DelSingleHostModuleRelation(ec, cc, 1, 2, 3)
+    r, err := DelSingleHostModuleRelation(context.Background(), ec, cc, 1, 2, 3)
     if !r {
         t.Errorf("result not as expected, should be true")
     }
@@ -160,7 +162,7 @@ func TestAddSingleHostModuleRelation(t *testing.T) {
 
     errFake := errors.New("fake error")
     instdata.DataH = &MockDI{ErrGetOneByCondition: errFake}
-    _, err := AddSingleHostModuleRelation(ec, cc, 1, 2, 3)
+    _, err := AddSingleHostModuleRelation(context.Background(), ec, cc, 1, 2, 3)
     if err != errFake {
         t.Errorf("error not as expected: %v", err)
     }
@@ -171,7 +173,7 @@ func TestAddSingleHostModuleRelation2(t *testing.T) {
     cc := &api.APIResource{}
 
     instdata.DataH = &MockDI{}
-    r, err := AddSingleHostModuleRelation(ec, cc, 1, 2, 3)
+    r, err := AddSingleHostModuleRelation(context.Background(), ec, cc, 1, 2, 3)
     if r {
         t.Errorf("result not as expected, should be false")
     }
@@ -185,7 +187,7 @@ func TestGetDefaultModuleIDs(t *testing.T) {
 
     errFake := errors.New("fake error")
     cc.InstCli = &MockDI{ErrGetMutilByCondition: errFake}
-    _, err := GetDefaultModuleIDs(cc, 1)
+    _, err := GetDefaultModuleIDs(context.Background(), cc, 1)
     if err == nil {
         t.Errorf("error not as expected: %v", err)
     }
@@ -195,7 +197,7 @@ func TestGetDefaultModuleIDs2(t *testing.T) {
     cc := &api.APIResource{}
 
     cc.InstCli = &MockDI{}
-    _, err := GetDefaultModuleIDs(cc, 1)
+    _, err := GetDefaultModuleIDs(context.Background(), cc, 1)
     if err == nil {
         t.Errorf("error not as expected: %v", err)
     }
@@ -206,7 +208,7 @@ func TestGetModuleIDsByHostID(t *testing.T) {
 
     errFake := errors.New("fake error")
     cc.InstCli = &MockDI{ErrGetMutilByCondition: errFake}
-    _, err := GetModuleIDsByHostID(cc, 1)
+    _, err := GetModuleIDsByHostID(context.Background(), cc, 1)
     if err == nil {
         t.Errorf("error not as expected: %v", err)
     }
@@ -216,7 +218,7 @@ func TestGetModuleIDsByHostID2(t *testing.T) {
     cc := &api.APIResource{}
 
     cc.InstCli = &MockDI{}
-    _, err := GetModuleIDsByHostID(cc, 1)
+    _, err := GetModuleIDsByHostID(context.Background(), cc, 1)
     if err != nil {
         t.Errorf("error not as expected: %v", err)
     }
@@ -227,7 +229,7 @@ func TestGetResourcePoolApp(t *testing.T) {
 
     errFake := errors.New("fake error")
     cc.InstCli = &MockDI{ErrGetOneByCondition: errFake}
-    _, err := GetResourcePoolApp(cc, 1)
+    _, err := GetResourcePoolApp(context.Background(), cc, 1)
     if err == nil {
         t.Errorf("error not as expected: %v", err)
     }
@@ -237,7 +239,7 @@ func TestGetResourcePoolApp2(t *testing.T) {
     cc := &api.APIResource{}
 
     cc.InstCli = &MockDI{}
-    _, err := GetResourcePoolApp(cc, 1)
+    _, err := GetResourcePoolApp(context.Background(), cc, 1)
     if err == nil {
         t.Errorf("error not as expected: %v", err)
     }
@@ -248,7 +250,7 @@ func TestCheckHostInIDle(t *testing.T) {
 
     errFake := errors.New("fake error")
     cc.InstCli = &MockDI{ErrGetMutilByCondition: errFake}
-    _, err := CheckHostInIDle(cc, 1, 2, nil)
+    _, err := CheckHostInIDle(context.Background(), cc, 1, 2, nil)
     if err == nil {
         t.Errorf("error not as expected: %v", err)
     }
@@ -258,7 +260,7 @@ func TestCheckHostInIDle2(t *testing.T) {
     cc := &api.APIResource{}
 
     cc.InstCli = &MockDI{}
-    _, err := CheckHostInIDle(cc, 1, 2, nil)
+    _, err := CheckHostInIDle(context.Background(), cc, 1, 2, nil)
     if err != nil {
         t.Errorf("error not as expected: %v", err)
     }
@@ -269,7 +271,7 @@ func TestGetIDleModuleID(t *testing.T) {
 
     errFake := errors.New("fake error")
     cc.InstCli = &MockDI{ErrGetOneByCondition: errFake}
-    _, err := GetIDleModuleID(cc, 1)
+    _, err := GetIDleModuleID(context.Background(), cc, 1)
     if err == nil {
         t.Errorf("error not as expected: %v", err)
     }
@@ -279,7 +281,7 @@ func TestGetIDleModuleID2(t *testing.T) {
     cc := &api.APIResource{}
 
     cc.InstCli = &MockDI{}
-    _, err := GetIDleModuleID(cc, 1)
+    _, err := GetIDleModuleID(context.Background(), cc, 1)
     if err == nil {
         t.Errorf("error not as expected: %v", err)
     }
@@ -22,6 +22,7 @@ import (
 	"configcenter/src/source_controller/common/commondata"
 	"configcenter/src/source_controller/common/eventdata"
 	"configcenter/src/source_controller/common/instdata"
+	"configcenter/src/storage"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -68,13 +69,13 @@ func (cli *objectAction) DelObject(req *restful.Request, resp *restful.Response)
 
 		// retrieve original datas
 		originDatas := make([]map[string]interface{}, 0)
-		getErr := instdata.GetObjectByCondition(objType, nil, input, &originDatas, "", 0, 0)
+		getErr := instdata.GetObjectByCondition(req.Request.Context(), objType, nil, input, &originDatas, "", 0, 0)
 		if getErr != nil {
 			blog.Error("retrieve original data error:%v", getErr)
 		}
 
 		blog.Info("delete object type:%s,input:%v ", objType, input)
-		err := instdata.DelObjByCondition(objType, input)
+		err := instdata.DelObjByCondition(req.Request.Context(), objType, input)
 		if err != nil {
 			blog.Error("delete object type:%s,input:%v error:%v", objType, input, err)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDeleteInstFailed)
@@ -115,13 +116,13 @@ func (cli *objectAction) UpdateObject(req *restful.Request, resp *restful.Respon
 
 		// retrieve original datas
 		originDatas := make([]map[string]interface{}, 0)
-		getErr := instdata.GetObjectByCondition(objType, nil, condition, &originDatas, "", 0, 0)
+		getErr := instdata.GetObjectByCondition(req.Request.Context(), objType, nil, condition, &originDatas, "", 0, 0)
 		if getErr != nil {
 			blog.Error("retrieve original datas error:%v", getErr)
 		}
 
 		blog.Info("update object type:%s,data:%v,condition:%v", objType, data, condition)
-		err := instdata.UpdateObjByCondition(objType, data, condition)
+		err := instdata.UpdateObjByCondition(req.Request.Context(), objType, data, condition)
 		if err != nil {
 			blog.Error("update object type:%s,data:%v,condition:%v,error:%v", objType, data, condition, err)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectUpdateInstFailed)
@@ -130,7 +131,7 @@ func (cli *objectAction) UpdateObject(req *restful.Request, resp *restful.Respon
 		// record event
 		if len(originDatas) > 0 {
 			newdatas := []map[string]interface{}{}
-			if err := instdata.GetObjectByCondition(objType, nil, condition, &newdatas, "", 0, 0); err != nil {
+			if err := instdata.GetObjectByCondition(req.Request.Context(), objType, nil, condition, &newdatas, "", 0, 0); err != nil {
 				blog.Error("create event error:%v", err)
 			} else {
 				ec := eventdata.NewEventContextByReq(req)
@@ -142,7 +143,7 @@ func (cli *objectAction) UpdateObject(req *restful.Request, resp *restful.Respon
 						blog.Errorf("create event error:%v", err)
 						continue
 					}
-					if err := instdata.GetObjectByID(objType, nil, id, &newData, ""); err != nil {
+					if err := instdata.GetObjectByID(req.Request.Context(), objType, nil, id, &newData, ""); err != nil {
 						blog.Error("create event error:%v", err)
 					} else {
 						err := ec.InsertEvent(eventtypes.EventTypeInstData, objType, eventtypes.EventActionUpdate, newData, originData)
@@ -167,6 +168,11 @@ func (cli *objectAction) SearchObjects(req *restful.Request, resp *restful.Respo
 		pathParams := req.PathParameters()
 		objType := pathParams["obj_type"]
 		instdata.DataH = cli.CC.InstCli
+		// the search is taken off the primary only when the caller tolerates the replication lag
+		ctx := req.Request.Context()
+		if util.IsLagTolerant(req) {
+			ctx = storage.WithReadPreference(ctx, cli.CC.SearchReadPreference)
+		}
 
 		value, err := ioutil.ReadAll(req.Request.Body)
 		var dat commondata.ObjQueryInput
@@ -184,18 +190,28 @@ func (cli *objectAction) SearchObjects(req *restful.Request, resp *restful.Respo
 		sort := dat.Sort
 		fieldArr := strings.Split(fields, ",")
 		result := make([]interface{}, 0)
-		count, err := instdata.GetCntByCondition(objType, condition)
+		count, err := instdata.GetCntByCondition(ctx, objType, condition)
 		if err != nil {
 			blog.Error("get object type:%s,input:%v error:%v", objType, string(value), err)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectSelectInstFailed)
 
 		}
-		err = instdata.GetObjectByCondition(objType, fieldArr, condition, &result, sort, skip, limit)
+		info := make(map[string]interface{})
+		if nil != dat.Cursor {
+			var next string
+			next, err = instdata.GetObjectByCursor(ctx, objType, fieldArr, condition, &result, sort, *dat.Cursor, limit)
+			info["next_cursor"] = next
+		} else {
+			err = instdata.GetObjectByCondition(ctx, objType, fieldArr, condition, &result, sort, skip, limit)
+		}
+		if storage.ErrInvalidCursor == err {
+			blog.Error("get object type:%s,input:%v error:%v", string(objType), string(value), err)
+			return http.StatusBadRequest, nil, defErr.Errorf(common.CCErrCommParamsInvalid, "cursor")
+		}
 		if err != nil {
 			blog.Error("get object type:%s,input:%v error:%v", string(objType), string(value), err)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectSelectInstFailed)
 		}
-		info := make(map[string]interface{})
 		info["count"] = count
 		info["info"] = result
 		return http.StatusOK, info, nil
@@ -220,7 +236,7 @@ func (cli *objectAction) CreateObject(req *restful.Request, resp *restful.Respon
 		input[common.LastTimeField] = time.Now()
 		blog.Info("create object type:%s,data:%v", objType, input)
 		var idName string
-		id, err := instdata.CreateObject(objType, input, &idName)
+		id, err := instdata.CreateObject(req.Request.Context(), objType, input, &idName)
 		if err != nil {
 			blog.Error("create object type:%s,data:%v error:%v", objType, input, err)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectCreateInstFailed)
@@ -228,7 +244,7 @@ func (cli *objectAction) CreateObject(req *restful.Request, resp *restful.Respon
 
 		// record event
 		origindata := map[string]interface{}{}
-		if err := instdata.GetObjectByID(objType, nil, id, origindata, ""); err != nil {
+		if err := instdata.GetObjectByID(req.Request.Context(), objType, nil, id, origindata, ""); err != nil {
 			blog.Error("create event error:%v", err)
 		} else {
 			ec := eventdata.NewEventContextByReq(req)
@@ -20,6 +20,7 @@ import (
 	"configcenter/src/common/blog"
 	"configcenter/src/common/util"
 	"configcenter/src/source_controller/api/metadata"
+	"configcenter/src/storage"
 	"encoding/json"
 	simplejson "github.com/bitly/go-simplejson"
 	"github.com/emicklei/go-restful"
@@ -78,7 +79,7 @@ func (cli *objectAction) CreateObject(req *restful.Request, resp *restful.Respon
 		*obj.LastTime = time.Now()
 
 		// get id
-		id, err := cli.CC.InstCli.GetIncID(obj.TableName())
+		id, err := storage.ContextOf(cli.CC.InstCli).GetIncIDCtx(req.Request.Context(), obj.TableName())
 		if err != nil {
 			blog.Error("failed to get id , error info is %s", err.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
@@ -86,7 +87,7 @@ func (cli *objectAction) CreateObject(req *restful.Request, resp *restful.Respon
 		obj.ID = int(id)
 
 		// save
-		_, err = cli.CC.InstCli.Insert(obj.TableName(), obj)
+		_, err = storage.ContextOf(cli.CC.InstCli).InsertCtx(req.Request.Context(), obj.TableName(), obj)
 		if nil == err {
 			return http.StatusOK, []*metadata.ObjectDes{obj}, nil
 		}
@@ -130,7 +131,7 @@ func (cli *objectAction) DeleteObject(req *restful.Request, resp *restful.Respon
 			}
 
 		}
-		cnt, cntErr := cli.CC.InstCli.GetCntByCondition(metadata.ObjectDes{}.TableName(), condition)
+		cnt, cntErr := storage.ContextOf(cli.CC.InstCli).GetCntByConditionCtx(req.Request.Context(), metadata.ObjectDes{}.TableName(), condition)
 		if nil != cntErr {
 			blog.Error("failed to select object by condition(%+v), error is %d", cntErr)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
@@ -140,7 +141,7 @@ func (cli *objectAction) DeleteObject(req *restful.Request, resp *restful.Respon
 			return http.StatusOK, nil, nil
 		}
 		// execute delete command
-		if delErr := cli.CC.InstCli.DelByCondition(metadata.ObjectDes{}.TableName(), condition); nil != delErr {
+		if delErr := storage.ContextOf(cli.CC.InstCli).DelByConditionCtx(req.Request.Context(), metadata.ObjectDes{}.TableName(), condition); nil != delErr {
 			blog.Error("fail to delete object by id , error information is %s", delErr.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
 		}
@@ -183,7 +184,7 @@ func (cli *objectAction) UpdateObject(req *restful.Request, resp *restful.Respon
 			blog.Error("unmarshal json failed, error information is %v", jsErr)
 			return http.StatusBadRequest, nil, defErr.Error(common.CCErrCommJSONUnmarshalFailed)
 		}
-		err = cli.CC.InstCli.UpdateByCondition(metadata.ObjectDes{}.TableName(), data, map[string]interface{}{"id": appID})
+		err = storage.ContextOf(cli.CC.InstCli).UpdateByConditionCtx(req.Request.Context(), metadata.ObjectDes{}.TableName(), data, map[string]interface{}{"id": appID})
 		if nil != err {
 			blog.Error("fail update object by condition, error information is %s", err.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
@@ -231,7 +232,7 @@ func (cli *objectAction) SelectObjects(req *restful.Request, resp *restful.Respo
 		// select from storage
 		//blog.Debug("selector:%+v", selector)
 		selector, _ := js.Map()
-		if selErr := cli.CC.InstCli.GetMutilByCondition(metadata.ObjectDes{}.TableName(), nil, selector, &results, page.Sort, page.Start, page.Limit); nil != selErr {
+		if selErr := storage.ContextOf(cli.CC.InstCli).GetMutilByConditionCtx(req.Request.Context(), metadata.ObjectDes{}.TableName(), nil, selector, &results, page.Sort, page.Start, page.Limit); nil != selErr {
 			blog.Error("select data failed, error information is %s", selErr.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
 		}
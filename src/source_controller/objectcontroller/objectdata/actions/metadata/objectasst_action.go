@@ -20,6 +20,7 @@ import (
 	"configcenter/src/common/blog"
 	"configcenter/src/common/util"
 	"configcenter/src/source_controller/api/metadata"
+	"configcenter/src/storage"
 	"encoding/json"
 	simplejson "github.com/bitly/go-simplejson"
 	"github.com/emicklei/go-restful"
@@ -70,14 +71,14 @@ func (cli *objectAssociationAction) CreateObjectAssociation(req *restful.Request
 		}
 
 		// save to the storage
-		id, err := cli.CC.InstCli.GetIncID(obj.TableName())
+		id, err := storage.ContextOf(cli.CC.InstCli).GetIncIDCtx(req.Request.Context(), obj.TableName())
 		if err != nil {
 			blog.Error("failed to get id, error info is %s", err.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
 		}
 
 		obj.ID = int(id)
-		_, err = cli.CC.InstCli.Insert(obj.TableName(), obj)
+		_, err = storage.ContextOf(cli.CC.InstCli).InsertCtx(req.Request.Context(), obj.TableName(), obj)
 		if nil != err {
 			blog.Error("create objectasst failed, error:%s", err.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
@@ -121,7 +122,7 @@ func (cli *objectAssociationAction) DeleteObjectAssociation(req *restful.Request
 			}
 		}
 
-		cnt, cntErr := cli.CC.InstCli.GetCntByCondition(metadata.ObjectAsst{}.TableName(), condition)
+		cnt, cntErr := storage.ContextOf(cli.CC.InstCli).GetCntByConditionCtx(req.Request.Context(), metadata.ObjectAsst{}.TableName(), condition)
 		if nil != cntErr {
 			blog.Error("failed to select objectasst by condition(%+v), error is %d", cntErr)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
@@ -131,7 +132,7 @@ func (cli *objectAssociationAction) DeleteObjectAssociation(req *restful.Request
 			return http.StatusOK, nil, nil
 		}
 		// execute delete command
-		delErr := cli.CC.InstCli.DelByCondition(metadata.ObjectAsst{}.TableName(), condition)
+		delErr := storage.ContextOf(cli.CC.InstCli).DelByConditionCtx(req.Request.Context(), metadata.ObjectAsst{}.TableName(), condition)
 		if nil != delErr {
 			blog.Error("fail to delete object by id , error information is %s", delErr.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
@@ -174,7 +175,7 @@ func (cli *objectAssociationAction) UpdateObjectAssociation(req *restful.Request
 		}
 		blog.Debug("update:%+v", data)
 		// update object into storage
-		if updateErr := cli.CC.InstCli.UpdateByCondition(metadata.ObjectAsst{}.TableName(), data, map[string]interface{}{"id": id}); nil != updateErr {
+		if updateErr := storage.ContextOf(cli.CC.InstCli).UpdateByConditionCtx(req.Request.Context(), metadata.ObjectAsst{}.TableName(), data, map[string]interface{}{"id": id}); nil != updateErr {
 			blog.Error("fail update object by condition, error information is %s", updateErr.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
 		}
@@ -215,7 +216,7 @@ func (cli *objectAssociationAction) SelectObjectAssociations(req *restful.Reques
 		results := make([]metadata.ObjectAsst, 0)
 		selector, _ := js.Map()
 		// select from storage
-		if selErr := cli.CC.InstCli.GetMutilByCondition(metadata.ObjectAsst{}.TableName(), nil, selector, &results, page.Sort, page.Start, page.Limit); nil != selErr {
+		if selErr := storage.ContextOf(cli.CC.InstCli).GetMutilByConditionCtx(req.Request.Context(), metadata.ObjectAsst{}.TableName(), nil, selector, &results, page.Sort, page.Start, page.Limit); nil != selErr {
 			blog.Error("select data failed, error information is %s", selErr.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
 		}
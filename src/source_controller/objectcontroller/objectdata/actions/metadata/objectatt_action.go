@@ -20,6 +20,9 @@ import (
 	"configcenter/src/common/blog"
 	"configcenter/src/common/util"
 	"configcenter/src/source_controller/api/metadata"
+	"configcenter/src/source_controller/common/instdata"
+	"configcenter/src/storage"
+	"context"
 	"encoding/json"
 	"github.com/bitly/go-simplejson"
 	"io/ioutil"
@@ -86,17 +89,20 @@ func (cli *objectAttAction) CreateObjectAtt(req *restful.Request, resp *restful.
 		if 0 >= obj.PropertyIndex {
 			obj.PropertyIndex = -1 // not set any value
 		}
-		id, err := cli.CC.InstCli.GetIncID(obj.TableName())
+		id, err := storage.ContextOf(cli.CC.InstCli).GetIncIDCtx(req.Request.Context(), obj.TableName())
 		if err != nil {
 			blog.Errorf("failed to get id, error info is %s", err.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
 		}
 		obj.ID = int(id)
-		_, err = cli.CC.InstCli.Insert(obj.TableName(), obj)
+		_, err = storage.ContextOf(cli.CC.InstCli).InsertCtx(req.Request.Context(), obj.TableName(), obj)
 		if nil != err {
 			blog.Error("create objectatt failed, error:%s", err.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
 		}
+		if obj.IsOnly {
+			cli.reconcileIsOnlyIndex(req.Request.Context(), obj.ObjectID)
+		}
 
 		return http.StatusOK, []*metadata.ObjectAttDes{obj}, nil
 	}, resp)
@@ -136,7 +142,7 @@ func (cli *objectAttAction) DeleteObjectAttByID(req *restful.Request, resp *rest
 				return http.StatusBadRequest, nil, defErr.Error(common.CCErrCommJSONUnmarshalFailed)
 			}
 		}
-		cnt, cntErr := cli.CC.InstCli.GetCntByCondition(metadata.ObjectAttDes{}.TableName(), condition)
+		cnt, cntErr := storage.ContextOf(cli.CC.InstCli).GetCntByConditionCtx(req.Request.Context(), metadata.ObjectAttDes{}.TableName(), condition)
 		if nil != cntErr {
 			blog.Error("failed to select object by condition(%+v), error is %d", cntErr)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
@@ -145,11 +151,17 @@ func (cli *objectAttAction) DeleteObjectAttByID(req *restful.Request, resp *rest
 			// success
 			return http.StatusOK, nil, nil
 		}
-		delErr := cli.CC.InstCli.DelByCondition(metadata.ObjectAttDes{}.TableName(), condition)
+		objIDs, err := cli.attObjIDs(req.Request.Context(), condition)
+		if nil != err {
+			blog.Errorf("failed to select the objects of the attributes, error info is %s", err.Error())
+			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
+		}
+		delErr := storage.ContextOf(cli.CC.InstCli).DelByConditionCtx(req.Request.Context(), metadata.ObjectAttDes{}.TableName(), condition)
 		if nil != delErr {
 			blog.Error("failed to delete, error info is %s", delErr.Error())
 
 		}
+		cli.reconcileIsOnlyIndex(req.Request.Context(), objIDs...)
 
 		// success
 		return http.StatusOK, nil, nil
@@ -191,11 +203,16 @@ func (cli *objectAttAction) UpdateObjectAttByID(req *restful.Request, resp *rest
 		}
 
 		// update object into storage
-		updateErr := cli.CC.InstCli.UpdateByCondition(metadata.ObjectAttDes{}.TableName(), data, map[string]interface{}{"id": appID})
+		updateErr := storage.ContextOf(cli.CC.InstCli).UpdateByConditionCtx(req.Request.Context(), metadata.ObjectAttDes{}.TableName(), data, map[string]interface{}{"id": appID})
 		if nil != updateErr {
 			blog.Error("fail update object by condition, error information is %s", updateErr.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
 		}
+		objIDs, err := cli.attObjIDs(req.Request.Context(), map[string]interface{}{"id": appID})
+		if nil != err {
+			blog.Errorf("failed to select the object of the attribute %d, error info is %s", appID, err.Error())
+		}
+		cli.reconcileIsOnlyIndex(req.Request.Context(), objIDs...)
 
 		// success
 		return http.StatusOK, nil, nil
@@ -224,7 +241,7 @@ func (cli *objectAttAction) SelectObjectAttByID(req *restful.Request, resp *rest
 
 		// select from storage
 		result := make([]metadata.ObjectAttDes, 0)
-		if selErr := cli.CC.InstCli.GetMutilByCondition(metadata.ObjectAttDes{}.TableName(), nil, map[string]interface{}{"id": id}, &result, "", 0, 0); nil != selErr {
+		if selErr := storage.ContextOf(cli.CC.InstCli).GetMutilByConditionCtx(req.Request.Context(), metadata.ObjectAttDes{}.TableName(), nil, map[string]interface{}{"id": id}, &result, "", 0, 0); nil != selErr {
 			blog.Error("find object by selector failed, error:%s", selErr.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
 		}
@@ -265,7 +282,7 @@ func (cli *objectAttAction) SelectObjectAttWithParams(req *restful.Request, resp
 		// select from storage
 		selector, _ := js.Map()
 		blog.Debug("the condition: %+v the page:%+v", selector, page)
-		if selErr := cli.CC.InstCli.GetMutilByCondition(metadata.ObjectAttDes{}.TableName(), nil, selector, &results, page.Sort, page.Start, page.Limit); nil != selErr {
+		if selErr := storage.ContextOf(cli.CC.InstCli).GetMutilByConditionCtx(req.Request.Context(), metadata.ObjectAttDes{}.TableName(), nil, selector, &results, page.Sort, page.Start, page.Limit); nil != selErr {
 			blog.Error("find object by selector failed, error information is %s", selErr.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
 		}
@@ -275,3 +292,29 @@ func (cli *objectAttAction) SelectObjectAttWithParams(req *restful.Request, resp
 
 	}, resp)
 }
+
+// attObjIDs returns the objects of the attributes matching the condition
+func (cli *objectAttAction) attObjIDs(ctx context.Context, condition interface{}) ([]string, error) {
+	attrs := make([]metadata.ObjectAttDes, 0)
+	err := storage.ContextOf(cli.CC.InstCli).GetMutilByConditionCtx(ctx, metadata.ObjectAttDes{}.TableName(), []string{common.BKObjIDField}, condition, &attrs, "", 0, 0)
+	if nil != err {
+		return nil, err
+	}
+	objIDs := make([]string, 0, len(attrs))
+	for _, attr := range attrs {
+		if !util.Contains(objIDs, attr.ObjectID) {
+			objIDs = append(objIDs, attr.ObjectID)
+		}
+	}
+	return objIDs, nil
+}
+
+// reconcileIsOnlyIndex makes the unique index of the bk_isonly attributes follow the changed attributes,
+// the failure is only logged since the attributes are saved, the index reconciliation of the migration retries it
+func (cli *objectAttAction) reconcileIsOnlyIndex(ctx context.Context, objIDs ...string) {
+	for _, objID := range objIDs {
+		if err := instdata.ReconcileIsOnlyIndex(ctx, cli.CC.InstCli, objID); nil != err {
+			blog.Errorf("reconcile the unique index of %s failed, error: %v", objID, err)
+		}
+	}
+}
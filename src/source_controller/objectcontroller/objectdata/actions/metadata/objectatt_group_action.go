@@ -19,6 +19,7 @@ import (
 	"configcenter/src/common/blog"
 	"configcenter/src/common/util"
 	"configcenter/src/source_controller/api/metadata"
+	"configcenter/src/storage"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
@@ -82,13 +83,13 @@ func (cli *objectAttGroupAction) CreatePropertyGroup(req *restful.Request, resp
 
 		//  save the data
 		blog.Debug("store the property group: %+v ", propertyGroup)
-		id, err := cli.CC.InstCli.GetIncID(propertyGroup.TableName())
+		id, err := storage.ContextOf(cli.CC.InstCli).GetIncIDCtx(req.Request.Context(), propertyGroup.TableName())
 		if err != nil {
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectPropertyGroupInsertFailed)
 		}
 		propertyGroup.ID = int(id)
 
-		_, err = cli.CC.InstCli.Insert(propertyGroup.TableName(), propertyGroup)
+		_, err = storage.ContextOf(cli.CC.InstCli).InsertCtx(req.Request.Context(), propertyGroup.TableName(), propertyGroup)
 		if nil == err {
 			return http.StatusOK, []*metadata.PropertyGroup{propertyGroup}, nil
 		}
@@ -128,7 +129,7 @@ func (cli *objectAttGroupAction) UpdatePropertyGroup(req *restful.Request, resp
 		}
 
 		blog.Debug("property group:%+v", propertyGroup)
-		if updateerr := cli.CC.InstCli.UpdateByCondition(common.BKTableNamePropertyGroup, propertyGroup.Data, propertyGroup.Condition); nil != updateerr {
+		if updateerr := storage.ContextOf(cli.CC.InstCli).UpdateByConditionCtx(req.Request.Context(), common.BKTableNamePropertyGroup, propertyGroup.Data, propertyGroup.Condition); nil != updateerr {
 			blog.Error("fail update object by condition, error:%v", updateerr.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectPropertyGroupUpdateFailed)
 		}
@@ -167,7 +168,7 @@ func (cli *objectAttGroupAction) SelectGroup(req *restful.Request, resp *restful
 		delete(condition, "page")
 
 		results := make([]metadata.PropertyGroup, 0)
-		if selerr := cli.CC.InstCli.GetMutilByCondition(common.BKTableNamePropertyGroup, nil, condition, &results, page.Sort, page.Start, page.Limit); nil != selerr {
+		if selerr := storage.ContextOf(cli.CC.InstCli).GetMutilByConditionCtx(req.Request.Context(), common.BKTableNamePropertyGroup, nil, condition, &results, page.Sort, page.Start, page.Limit); nil != selerr {
 			blog.Error("find object by selector failed, error information is %s", selerr.Error())
 			return http.StatusBadRequest, nil, defErr.Error(common.CCErrObjectPropertyGroupSelectFailed)
 		}
@@ -198,7 +199,7 @@ func (cli *objectAttGroupAction) DeletePropertyGroup(req *restful.Request, resp
 		}
 
 		condition := map[string]interface{}{"id": id}
-		cnt, cntErr := cli.CC.InstCli.GetCntByCondition(common.BKTableNamePropertyGroup, condition)
+		cnt, cntErr := storage.ContextOf(cli.CC.InstCli).GetCntByConditionCtx(req.Request.Context(), common.BKTableNamePropertyGroup, condition)
 		if nil != cntErr {
 			blog.Error("failed to select object group by condition(%+v), error is %d", cntErr)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectPropertyGroupDeleteFailed)
@@ -206,7 +207,7 @@ func (cli *objectAttGroupAction) DeletePropertyGroup(req *restful.Request, resp
 		if 0 == cnt {
 			return http.StatusOK, nil, nil
 		}
-		if delErr := cli.CC.InstCli.DelByCondition(common.BKTableNamePropertyGroup, condition); nil != delErr {
+		if delErr := storage.ContextOf(cli.CC.InstCli).DelByConditionCtx(req.Request.Context(), common.BKTableNamePropertyGroup, condition); nil != delErr {
 			blog.Error("failed to delete property group  by condition, error:%v", delErr.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectPropertyGroupDeleteFailed)
 		}
@@ -260,7 +261,7 @@ func (cli *objectAttGroupAction) UpdatePropertyGroupObjectAtt(req *restful.Reque
 		}
 
 		// update the object attribute
-		if updateerr := cli.CC.InstCli.UpdateByCondition(common.BKTableNameObjAttDes, objectAttValue, objectAttSelector); nil != updateerr {
+		if updateerr := storage.ContextOf(cli.CC.InstCli).UpdateByConditionCtx(req.Request.Context(), common.BKTableNameObjAttDes, objectAttValue, objectAttSelector); nil != updateerr {
 			blog.Error("fail update object by condition, error:%v", updateerr.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectPropertyGroupUpdateFailed)
 		}
@@ -296,7 +297,7 @@ func (cli *objectAttGroupAction) DeletePropertyGroupObjectAtt(req *restful.Reque
 			"bk_property_group": "default",
 		}
 
-		cnt, cntErr := cli.CC.InstCli.GetCntByCondition(common.BKTableNameObjAttDes, objectAttSelector)
+		cnt, cntErr := storage.ContextOf(cli.CC.InstCli).GetCntByConditionCtx(req.Request.Context(), common.BKTableNameObjAttDes, objectAttSelector)
 		if nil != cntErr {
 			blog.Error("failed to select objectatt group by condition(%+v), error is %d", cntErr)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectPropertyGroupDeleteFailed)
@@ -306,7 +307,7 @@ func (cli *objectAttGroupAction) DeletePropertyGroupObjectAtt(req *restful.Reque
 		}
 		blog.Debug("group property selector %+v, value %+v", objectAttSelector, objectAttValue)
 		// update the object attribute
-		if updateerr := cli.CC.InstCli.UpdateByCondition(common.BKTableNameObjAttDes, objectAttValue, objectAttSelector); nil != updateerr {
+		if updateerr := storage.ContextOf(cli.CC.InstCli).UpdateByConditionCtx(req.Request.Context(), common.BKTableNameObjAttDes, objectAttValue, objectAttSelector); nil != updateerr {
 			blog.Error("fail update object by condition, error:%v", updateerr.Error())
 			return http.StatusInternalServerError, "", defErr.Error(common.CCErrObjectPropertyGroupUpdateFailed)
 		}
@@ -348,7 +349,7 @@ func (cli *objectAttGroupAction) SelectPropertyGroupByObjectID(req *restful.Requ
 		blog.Debug("group property selector %+v", groupSelector)
 		results := make([]metadata.PropertyGroup, 0)
 		// select the object group
-		if selerr := cli.CC.InstCli.GetMutilByCondition(common.BKTableNamePropertyGroup, nil, groupSelector, &results, page.Sort, page.Start, page.Limit); nil != selerr {
+		if selerr := storage.ContextOf(cli.CC.InstCli).GetMutilByConditionCtx(req.Request.Context(), common.BKTableNamePropertyGroup, nil, groupSelector, &results, page.Sort, page.Start, page.Limit); nil != selerr {
 			blog.Error("select data failed, error information is %s", selerr.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectPropertyGroupSelectFailed)
 		}
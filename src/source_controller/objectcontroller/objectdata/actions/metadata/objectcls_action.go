@@ -20,6 +20,7 @@ import (
 	"configcenter/src/common/blog"
 	"configcenter/src/common/util"
 	"configcenter/src/source_controller/api/metadata"
+	"configcenter/src/storage"
 	"encoding/json"
 	"github.com/emicklei/go-restful"
 	"io/ioutil"
@@ -71,13 +72,13 @@ func (cli *objectClassificationAction) CreateClassification(req *restful.Request
 		}
 
 		// save to the storage
-		id, err := cli.CC.InstCli.GetIncID(obj.TableName())
+		id, err := storage.ContextOf(cli.CC.InstCli).GetIncIDCtx(req.Request.Context(), obj.TableName())
 		if err != nil {
 			blog.Error("failed to get id, error info is %s", err.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
 		}
 		obj.ID = int(id)
-		_, err = cli.CC.InstCli.Insert(obj.TableName(), obj)
+		_, err = storage.ContextOf(cli.CC.InstCli).InsertCtx(req.Request.Context(), obj.TableName(), obj)
 		if nil != err {
 			blog.Error("create objectcls failed, error:%s", err.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
@@ -122,7 +123,7 @@ func (cli *objectClassificationAction) DeleteClassification(req *restful.Request
 				return http.StatusBadRequest, nil, defErr.Error(common.CCErrCommJSONUnmarshalFailed)
 			}
 		}
-		cnt, cntErr := cli.CC.InstCli.GetCntByCondition(common.BKTableNameObjClassifiction, condition)
+		cnt, cntErr := storage.ContextOf(cli.CC.InstCli).GetCntByConditionCtx(req.Request.Context(), common.BKTableNameObjClassifiction, condition)
 		if nil != cntErr {
 			blog.Error("failed to select object classification by condition(%+v), error is %d", cntErr)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
@@ -131,7 +132,7 @@ func (cli *objectClassificationAction) DeleteClassification(req *restful.Request
 			return http.StatusOK, nil, nil
 		}
 		// execute delete command
-		if delErr := cli.CC.InstCli.DelByCondition(common.BKTableNameObjClassifiction, condition); nil != delErr {
+		if delErr := storage.ContextOf(cli.CC.InstCli).DelByConditionCtx(req.Request.Context(), common.BKTableNameObjClassifiction, condition); nil != delErr {
 			blog.Error("fail to delete object by id , error: %s", delErr.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
 		}
@@ -168,7 +169,7 @@ func (cli *objectClassificationAction) UpdateClassification(req *restful.Request
 		}
 
 		// update object into storage
-		if updateErr := cli.CC.InstCli.UpdateByCondition(common.BKTableNameObjClassifiction, &data, selector); nil != updateErr {
+		if updateErr := storage.ContextOf(cli.CC.InstCli).UpdateByConditionCtx(req.Request.Context(), common.BKTableNameObjClassifiction, &data, selector); nil != updateErr {
 			blog.Error("fail update object by condition, error:%v", updateErr.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
 		}
@@ -201,7 +202,7 @@ func (cli *objectClassificationAction) SelectClassifications(req *restful.Reques
 		results := make([]metadata.ObjClassification, 0)
 
 		// select from storage
-		if selerr := cli.CC.InstCli.GetMutilByCondition(common.BKTableNameObjClassifiction, nil, selector, &results, page.Sort, page.Start, page.Limit); nil != selerr {
+		if selerr := storage.ContextOf(cli.CC.InstCli).GetMutilByConditionCtx(req.Request.Context(), common.BKTableNameObjClassifiction, nil, selector, &results, page.Sort, page.Start, page.Limit); nil != selerr {
 			blog.Error("select data failed, error: %s", selerr.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
 		}
@@ -235,7 +236,7 @@ func (cli *objectClassificationAction) SelectClassificationWithObject(req *restf
 
 		clsResults := make([]metadata.ObjClassificationObject, 0)
 		// select from storage
-		if selerr := cli.CC.InstCli.GetMutilByCondition(common.BKTableNameObjClassifiction, nil, selector, &clsResults, page.Sort, page.Start, page.Limit); nil != selerr {
+		if selerr := storage.ContextOf(cli.CC.InstCli).GetMutilByConditionCtx(req.Request.Context(), common.BKTableNameObjClassifiction, nil, selector, &clsResults, page.Sort, page.Start, page.Limit); nil != selerr {
 			blog.Error("select data failed, error:%s", selerr.Error())
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
 		}
@@ -247,7 +248,7 @@ func (cli *objectClassificationAction) SelectClassificationWithObject(req *restf
 				"bk_classification_id":   tmpobj.ClassificationID,
 				common.BKOwnerIDField: ownerID,
 			}
-			if selerr := cli.CC.InstCli.GetMutilByCondition(common.BKTableNameObjDes, nil, selector, &clsResults[tmpidx].Objects, "", 0, common.BKNoLimit); nil != selerr {
+			if selerr := storage.ContextOf(cli.CC.InstCli).GetMutilByConditionCtx(req.Request.Context(), common.BKTableNameObjDes, nil, selector, &clsResults[tmpidx].Objects, "", 0, common.BKNoLimit); nil != selerr {
 				blog.Error("select data failed, error:%s", selerr.Error())
 				continue
 			}
@@ -18,6 +18,7 @@ import (
 	"configcenter/src/common/blog"
 	"configcenter/src/common/core/cc/actions"
 	"configcenter/src/common/util"
+	"configcenter/src/storage"
 	"encoding/json"
 	"github.com/emicklei/go-restful"
 	"gopkg.in/mgo.v2/bson"
@@ -65,7 +66,7 @@ func (cli *procAction) GetProcessesByModuleName(req *restful.Request, resp *rest
 		blog.Debug("query;%v", query)
 		fields := []string{common.BKProcIDField, common.BKAppIDField, common.BKModuleNameField}
 		var result []interface{}
-		err = proc.CC.InstCli.GetMutilByCondition("cc_Proc2Module", fields, query, &result, common.BKHostIDField, 0, 100000)
+		err = storage.ContextOf(proc.CC.InstCli).GetMutilByConditionCtx(req.Request.Context(), "cc_Proc2Module", fields, query, &result, common.BKHostIDField, 0, 100000)
 		if err != nil {
 			blog.Error("fail to get module proc config %v", err)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrCommDBSelectFailed)
@@ -89,7 +90,7 @@ func (cli *procAction) GetProcessesByModuleName(req *restful.Request, resp *rest
 			"$in": processIdArr,
 		}
 		var resultProc []interface{}
-		err = proc.CC.InstCli.GetMutilByCondition("cc_Process", []string{}, procQuery, &resultProc, common.BKProcIDField, 0, 100000)
+		err = storage.ContextOf(proc.CC.InstCli).GetMutilByConditionCtx(req.Request.Context(), "cc_Process", []string{}, procQuery, &resultProc, common.BKProcIDField, 0, 100000)
 		blog.Infof("GetProcessesByModuleName params:%v, result:%v", procQuery, resultProc)
 		if err != nil {
 			blog.Error("fail to get proc %v", err)
@@ -20,6 +20,8 @@ import (
 	"configcenter/src/common/util"
 	eventtypes "configcenter/src/scene_server/event_server/types"
 	"configcenter/src/source_controller/common/eventdata"
+	"configcenter/src/storage"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -80,8 +82,8 @@ func (cli *setAction) DeleteSetHost(req *restful.Request, resp *restful.Response
 }
 
 // TODO
-func getModuleConfigCount(con map[string]interface{}) (int, error) {
-	count, err := set.CC.InstCli.GetCntByCondition("cc_ModuleHostConfig", con)
+func getModuleConfigCount(ctx context.Context, con map[string]interface{}) (int, error) {
+	count, err := storage.ContextOf(set.CC.InstCli).GetCntByConditionCtx(ctx, "cc_ModuleHostConfig", con)
 	if err != nil {
 		blog.Error("fail getModuleConfigCount error:%v", err)
 		return 0, err
@@ -98,19 +100,19 @@ func delModuleConfigSet(input map[string]interface{}, req *restful.Request) erro
 		return errors.New("params ApplicationID is required")
 	}
 	var oldContents []interface{}
-	getErr := set.CC.InstCli.GetMutilByCondition(tableName, nil, input, &oldContents, "", 0, common.BKNoLimit)
+	getErr := storage.ContextOf(set.CC.InstCli).GetMutilByConditionCtx(req.Request.Context(), tableName, nil, input, &oldContents, "", 0, common.BKNoLimit)
 	if getErr != nil {
 		blog.Errorf("fail to delSetConfigHost: %v", getErr)
 		return getErr
 	}
 
-	setID, moduleID, defErr := GetIdleModule(appID)
+	setID, moduleID, defErr := GetIdleModule(req.Request.Context(), appID)
 	if nil != defErr {
 		blog.Errorf("get idle module error:%v", defErr)
 		return defErr
 	}
 
-	err := set.CC.InstCli.DelByCondition(tableName, input)
+	err := storage.ContextOf(set.CC.InstCli).DelByConditionCtx(req.Request.Context(), tableName, input)
 	if err != nil {
 		blog.Error("fail to delSetConfigHost: %v", err)
 		return err
@@ -148,7 +150,7 @@ func delModuleConfigSet(input map[string]interface{}, req *restful.Request) erro
 	//del host from set, get host module relation
 	params := common.KvMap{common.BKAppIDField: appID, common.BKHostIDField: common.KvMap{"$in": hostIDs}}
 	var hostRelations []interface{}
-	getErr = set.CC.InstCli.GetMutilByCondition(tableName, nil, params, &hostRelations, "", 0, common.BKNoLimit)
+	getErr = storage.ContextOf(set.CC.InstCli).GetMutilByConditionCtx(req.Request.Context(), tableName, nil, params, &hostRelations, "", 0, common.BKNoLimit)
 	if getErr != nil {
 		blog.Error("fail to exist relation host error: %v", getErr)
 		return getErr
@@ -174,7 +176,7 @@ func delModuleConfigSet(input map[string]interface{}, req *restful.Request) erro
 
 	}
 	if 0 < len(addIdleModuleDatas) {
-		err := set.CC.InstCli.InsertMuti(tableName, addIdleModuleDatas...)
+		err := storage.ContextOf(set.CC.InstCli).InsertMutiCtx(req.Request.Context(), tableName, addIdleModuleDatas...)
 		if getErr != nil {
 			blog.Error("fail to exist relation host error: %v", err)
 			return err
@@ -192,10 +194,10 @@ func delModuleConfigSet(input map[string]interface{}, req *restful.Request) erro
 	return nil
 }
 
-func GetIdleModule(appID interface{}) (interface{}, interface{}, error) {
+func GetIdleModule(ctx context.Context, appID interface{}) (interface{}, interface{}, error) {
 	params := common.KvMap{common.BKAppIDField: appID, common.BKDefaultField: common.DefaultResModuleFlag, common.BKModuleNameField: common.DefaultResModuleName}
 	var result bson.M
-	err := set.CC.InstCli.GetOneByCondition("cc_ModuleBase", []string{common.BKModuleIDField, common.BKSetIDField}, params, &result)
+	err := storage.ContextOf(set.CC.InstCli).GetOneByConditionCtx(ctx, "cc_ModuleBase", []string{common.BKModuleIDField, common.BKSetIDField}, params, &result)
 
 	if nil != err {
 		return nil, nil, err
@@ -21,6 +21,7 @@ import (
 	"net/http"
 
 	"configcenter/src/common/util"
+	"configcenter/src/storage"
 
 	simplejson "github.com/bitly/go-simplejson"
 	"github.com/emicklei/go-restful"
@@ -77,7 +78,7 @@ func (cli *groupAction) CreateUserGroup(req *restful.Request, resp *restful.Resp
 		data[common.BKOwnerIDField] = ownerID
 		guid := xid.New()
 		data[common.BKUserGroupIDField] = guid.String()
-		_, err = cli.CC.InstCli.Insert(common.BKTableNameUserGroup, data)
+		_, err = storage.ContextOf(cli.CC.InstCli).InsertCtx(req.Request.Context(), common.BKTableNameUserGroup, data)
 		if nil != err {
 			blog.Error("create user group error :%v", err)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
@@ -119,7 +120,7 @@ func (cli *groupAction) UpdateUserGroup(req *restful.Request, resp *restful.Resp
 		cond := make(map[string]interface{})
 		cond[common.BKOwnerIDField] = ownerID
 		cond[common.BKUserGroupIDField] = groupID
-		err = cli.CC.InstCli.UpdateByCondition(common.BKTableNameUserGroup, data, cond)
+		err = storage.ContextOf(cli.CC.InstCli).UpdateByConditionCtx(req.Request.Context(), common.BKTableNameUserGroup, data, cond)
 		if nil != err {
 			blog.Error("update user group error :%v", err)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
@@ -145,7 +146,7 @@ func (cli *groupAction) DeleteUserGroup(req *restful.Request, resp *restful.Resp
 		cond := make(map[string]interface{})
 		cond[common.BKOwnerIDField] = ownerID
 		cond[common.BKUserGroupIDField] = groupID
-		err := cli.CC.InstCli.DelByCondition(common.BKTableNameUserGroup, cond)
+		err := storage.ContextOf(cli.CC.InstCli).DelByConditionCtx(req.Request.Context(), common.BKTableNameUserGroup, cond)
 		if nil != err {
 			blog.Error("delete user group error :%v", err)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
@@ -184,7 +185,7 @@ func (cli *groupAction) SearchUserGroup(req *restful.Request, resp *restful.Resp
 		}
 		cond[common.BKOwnerIDField] = ownerID
 		var result []interface{}
-		err = cli.CC.InstCli.GetMutilByCondition(common.BKTableNameUserGroup, []string{}, cond, &result, "", 0, 0)
+		err = storage.ContextOf(cli.CC.InstCli).GetMutilByConditionCtx(req.Request.Context(), common.BKTableNameUserGroup, []string{}, cond, &result, "", 0, 0)
 		if nil != err {
 			blog.Error("get user group error :%v", err)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
@@ -21,6 +21,7 @@ import (
 	"net/http"
 
 	"configcenter/src/common/util"
+	"configcenter/src/storage"
 
 	simplejson "github.com/bitly/go-simplejson"
 	"github.com/emicklei/go-restful"
@@ -74,7 +75,7 @@ func (cli *privilegeAction) CreateUserGroupPrivi(req *restful.Request, resp *res
 		data[common.BKOwnerIDField] = ownerID
 		data[common.BKUserGroupIDField] = groupID
 		data[common.BKPrivilegeField] = info
-		_, err = cli.CC.InstCli.Insert(common.BKTableNameUserGroupPrivilege, data)
+		_, err = storage.ContextOf(cli.CC.InstCli).InsertCtx(req.Request.Context(), common.BKTableNameUserGroupPrivilege, data)
 		if nil != err {
 			blog.Error("insert user group privi error :%v", err)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
@@ -117,7 +118,7 @@ func (cli *privilegeAction) UpdateUserGroupPrivi(req *restful.Request, resp *res
 		cond[common.BKOwnerIDField] = ownerID
 		cond[common.BKUserGroupIDField] = groupID
 		data[common.BKPrivilegeField] = info
-		err = cli.CC.InstCli.UpdateByCondition(common.BKTableNameUserGroupPrivilege, data, cond)
+		err = storage.ContextOf(cli.CC.InstCli).UpdateByConditionCtx(req.Request.Context(), common.BKTableNameUserGroupPrivilege, data, cond)
 		if nil != err {
 			blog.Error("update user group privi error :%v", err)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
@@ -146,7 +147,7 @@ func (cli *privilegeAction) GetUserGroupPrivi(req *restful.Request, resp *restfu
 		cond[common.BKOwnerIDField] = ownerID
 		cond[common.BKUserGroupIDField] = groupID
 		var result interface{}
-		err := cli.CC.InstCli.GetOneByCondition(common.BKTableNameUserGroupPrivilege, []string{}, cond, &result)
+		err := storage.ContextOf(cli.CC.InstCli).GetOneByConditionCtx(req.Request.Context(), common.BKTableNameUserGroupPrivilege, []string{}, cond, &result)
 		if nil != err {
 			data := make(map[string]interface{})
 			data[common.BKOwnerIDField] = ownerID
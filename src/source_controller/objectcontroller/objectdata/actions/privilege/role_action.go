@@ -18,6 +18,7 @@ import (
 	"configcenter/src/common/blog"
 	"configcenter/src/common/core/cc/actions"
 	"configcenter/src/common/util"
+	"configcenter/src/storage"
 	"encoding/json"
 	"errors"
 	"io/ioutil"
@@ -60,7 +61,7 @@ func (cli *roleAction) GetRolePri(req *restful.Request, resp *restful.Response)
 		cond[common.BKObjIDField] = objID
 		cond[common.BKPropertyIDField] = propertyID
 		var result map[string]interface{}
-		err := cli.CC.InstCli.GetOneByCondition(common.BKTableNamePrivilege, []string{}, cond, &result)
+		err := storage.ContextOf(cli.CC.InstCli).GetOneByConditionCtx(req.Request.Context(), common.BKTableNamePrivilege, []string{}, cond, &result)
 		if nil != err {
 			blog.Error("get role pri field error :%v", err)
 			info := make([]string, 0)
@@ -106,7 +107,7 @@ func (cli *roleAction) CreateRolePri(req *restful.Request, resp *restful.Respons
 		input[common.BKObjIDField] = objID
 		input[common.BKPropertyIDField] = propertyID
 		input[common.BKPrivilegeField] = roleJSON
-		_, err = cli.CC.InstCli.Insert(common.BKTableNamePrivilege, input)
+		_, err = storage.ContextOf(cli.CC.InstCli).InsertCtx(req.Request.Context(), common.BKTableNamePrivilege, input)
 		if nil != err {
 			blog.Error("create role privilege error :%v", err)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
@@ -146,7 +147,7 @@ func (cli *roleAction) UpdateRolePri(req *restful.Request, resp *restful.Respons
 		cond[common.BKObjIDField] = objID
 		cond[common.BKPropertyIDField] = propertyID
 		input[common.BKPrivilegeField] = roleJSON
-		err = cli.CC.InstCli.UpdateByCondition(common.BKTableNamePrivilege, input, cond)
+		err = storage.ContextOf(cli.CC.InstCli).UpdateByConditionCtx(req.Request.Context(), common.BKTableNamePrivilege, input, cond)
 		if nil != err {
 			blog.Error("update role privilege error :%v", err)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrObjectDBOpErrno)
@@ -13,6 +13,7 @@
 package ccapi
 
 import (
+	_ "configcenter/src/source_controller/common/slowquery"
 	_ "configcenter/src/source_controller/objectcontroller/objectdata/actions/instdata"
 	_ "configcenter/src/source_controller/objectcontroller/objectdata/actions/metadata"
 	_ "configcenter/src/source_controller/objectcontroller/objectdata/actions/privilege"
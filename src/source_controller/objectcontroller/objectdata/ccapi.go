@@ -18,9 +18,12 @@ import (
 	"configcenter/src/common/core/cc/config"
 	"configcenter/src/common/errors"
 	"configcenter/src/common/http/httpserver"
+	"configcenter/src/source_controller/common/eventdata"
+	"configcenter/src/source_controller/common/txndata"
 	confCenter "configcenter/src/source_controller/objectcontroller/objectdata/config"
 	"configcenter/src/source_controller/objectcontroller/objectdata/rdiscover"
 	"configcenter/src/storage"
+	"context"
 	"sync"
 	"time"
 )
@@ -106,6 +109,9 @@ func (ccAPI *CCAPIServer) Start() error {
 		return err
 	}
 
+	// push the events left by the committed transactions
+	go eventdata.RecoverTxEventsLoop(context.Background(), a.InstCli, storage.TxTimeout)
+
 	// load the errors resource
 	if errorres, ok := config["errors.res"]; ok {
 		if errif, err := errors.New(errorres); nil != err {
@@ -151,7 +157,7 @@ func (ccAPI *CCAPIServer) Start() error {
 func (ccAPI *CCAPIServer) InitHttpServ(config map[string]string) error {
 	chErr := make(chan error, 3)
 	a := api.NewAPIResource()
-	ccAPI.httpServ.RegisterWebServer("/object/{version}", nil, a.Actions)
+	ccAPI.httpServ.RegisterWebServer("/object/{version}", txndata.Filter, a.Actions)
 
 	wg := sync.WaitGroup{}
 
@@ -20,6 +20,7 @@ import (
 	"configcenter/src/common/util"
 	eventtypes "configcenter/src/scene_server/event_server/types"
 	"configcenter/src/source_controller/common/eventdata"
+	"configcenter/src/storage"
 	"io/ioutil"
 	"net/http"
 
@@ -66,13 +67,13 @@ func (cli *proc2moduleAction) DeleteProc2Module(req *restful.Request, resp *rest
 
 		// retrieve original data
 		var originals []interface{}
-		err = proc.CC.InstCli.GetMutilByCondition(common.BKTableNameProcModule, []string{}, input, &originals, "", 0, 0)
+		err = storage.ContextOf(proc.CC.InstCli).GetMutilByConditionCtx(req.Request.Context(), common.BKTableNameProcModule, []string{}, input, &originals, "", 0, 0)
 		if err != nil {
 			blog.Error("retrieve original error:%v", err)
 		}
 
 		blog.Info("delete proc module config %v", input)
-		err = proc.CC.InstCli.DelByCondition(common.BKTableNameProcModule, input)
+		err = storage.ContextOf(proc.CC.InstCli).DelByConditionCtx(req.Request.Context(), common.BKTableNameProcModule, input)
 		if err != nil {
 			blog.Error("delete proc module config error:%v", err)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrProcDeleteProc2Module)
@@ -120,7 +121,7 @@ func (cli *proc2moduleAction) CreateProc2Module(req *restful.Request, resp *rest
 		blog.Info("create proc module config ", input)
 		ec := eventdata.NewEventContextByReq(req)
 		for _, i := range input {
-			_, err = proc.CC.InstCli.Insert(common.BKTableNameProcModule, i)
+			_, err = storage.ContextOf(proc.CC.InstCli).InsertCtx(req.Request.Context(), common.BKTableNameProcModule, i)
 			if err != nil {
 				blog.Error("create proc module config error:%v", err)
 				return http.StatusInternalServerError, nil, defErr.Error(common.CCErrProcCreateProc2Module)
@@ -162,7 +163,7 @@ func (cli *proc2moduleAction) GetProc2Module(req *restful.Request, resp *restful
 
 		blog.Info("get proc module config condition ", input)
 		var result []interface{}
-		err = proc.CC.InstCli.GetMutilByCondition(common.BKTableNameProcModule, []string{}, input, &result, "", 0, 0)
+		err = storage.ContextOf(proc.CC.InstCli).GetMutilByConditionCtx(req.Request.Context(), common.BKTableNameProcModule, []string{}, input, &result, "", 0, 0)
 		if err != nil {
 			blog.Error("create proc module config error:%v", err)
 			return http.StatusInternalServerError, nil, defErr.Error(common.CCErrProcSelectProc2Module)
@@ -13,5 +13,6 @@
 package ccapi
 
 import (
+	_ "configcenter/src/source_controller/common/slowquery"
 	_ "configcenter/src/source_controller/proccontroller/procdata/actions"
 )
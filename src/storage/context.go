@@ -0,0 +1,255 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+	"reflect"
+)
+
+// ContextDI define the storage interface which gives up the reads once the context is done,
+// the writes are not started after the context is done but never given up once started,
+// so a write error always means the write did not take effect
+type ContextDI interface {
+	DI
+	GetIncIDCtx(ctx context.Context, cName string) (int64, error)
+	InsertCtx(ctx context.Context, cName string, data interface{}) (int, error)
+	InsertMutiCtx(ctx context.Context, cName string, data ...interface{}) error
+	UpdateByConditionCtx(ctx context.Context, cName string, data, condiction interface{}) error
+	GetOneByConditionCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}) error
+	GetMutilByConditionCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}, sort string, start, limit int) error
+	GetMutilByCursorCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}, sort, cursor string, limit int) (string, error)
+	GetCntByConditionCtx(ctx context.Context, cName string, condiction interface{}) (int, error)
+	DelByConditionCtx(ctx context.Context, cName string, condiction interface{}) error
+}
+
+// RunWithContext runs fn and waits until it returns or the context is done, whichever comes first,
+// fn keeps running in background after the context is done, so it must only write to the variables
+// which the caller reads after a nil error, the results decoded by fn go through ReadInto
+func RunWithContext(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if ctx.Done() == nil {
+		// the context could never be done
+		return fn()
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RunWrite runs the write fn unless the context is already done, the write is never given up
+// once started, the driver bounds it by the deadline of the context
+func RunWrite(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// ReadInto runs the read fn on a private copy of result and copies it to result only when fn
+// returns nil, so a read given up by RunWithContext never writes to result after it returns,
+// result which is neither a non-nil pointer nor a non-nil map is given to fn directly
+func ReadInto(result interface{}, fn func(result interface{}) error) error {
+	val := reflect.ValueOf(result)
+	switch {
+	case val.Kind() == reflect.Map && !val.IsNil():
+		private := reflect.MakeMap(val.Type())
+		if err := fn(private.Interface()); err != nil {
+			return err
+		}
+		mergeMap(val, private)
+		return nil
+	case val.Kind() == reflect.Ptr && !val.IsNil():
+		private := reflect.New(val.Elem().Type())
+		if err := fn(private.Interface()); err != nil {
+			return err
+		}
+		if elem := val.Elem(); elem.Kind() == reflect.Map && !elem.IsNil() && !private.Elem().IsNil() {
+			// keep the entries of the map like decoding into it does
+			mergeMap(elem, private.Elem())
+			return nil
+		}
+		val.Elem().Set(private.Elem())
+		return nil
+	}
+	return fn(result)
+}
+
+func mergeMap(dst, src reflect.Value) {
+	for _, key := range src.MapKeys() {
+		dst.SetMapIndex(key, src.MapIndex(key))
+	}
+}
+
+// ContextOf returns the context aware version of db, the storage which does not implement ContextDI
+// is wrapped so that its calls return once the context is done, the calls join the transaction
+// bound to the context by WithTx
+func ContextOf(db DI) ContextDI {
+	cdb, ok := db.(ContextDI)
+	if !ok {
+		cdb = &contextDI{DI: db}
+	}
+	return &txRouter{ContextDI: cdb, db: db}
+}
+
+// txRouter sends the calls to the transaction of db bound to the context
+type txRouter struct {
+	ContextDI
+	db DI
+}
+
+func (r *txRouter) target(ctx context.Context) ContextDI {
+	if tx := TxOf(ctx, r.db); tx != nil {
+		return tx
+	}
+	return r.ContextDI
+}
+
+func (r *txRouter) GetIncIDCtx(ctx context.Context, cName string) (int64, error) {
+	return r.target(ctx).GetIncIDCtx(ctx, cName)
+}
+
+func (r *txRouter) InsertCtx(ctx context.Context, cName string, data interface{}) (int, error) {
+	return r.target(ctx).InsertCtx(ctx, cName, data)
+}
+
+func (r *txRouter) InsertMutiCtx(ctx context.Context, cName string, data ...interface{}) error {
+	return r.target(ctx).InsertMutiCtx(ctx, cName, data...)
+}
+
+func (r *txRouter) UpdateByConditionCtx(ctx context.Context, cName string, data, condiction interface{}) error {
+	return r.target(ctx).UpdateByConditionCtx(ctx, cName, data, condiction)
+}
+
+func (r *txRouter) GetOneByConditionCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}) error {
+	return r.target(ctx).GetOneByConditionCtx(ctx, cName, fields, condiction, result)
+}
+
+func (r *txRouter) GetMutilByConditionCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}, sort string, start, limit int) error {
+	return r.target(ctx).GetMutilByConditionCtx(ctx, cName, fields, condiction, result, sort, start, limit)
+}
+
+func (r *txRouter) GetMutilByCursorCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}, sort, cursor string, limit int) (string, error) {
+	return r.target(ctx).GetMutilByCursorCtx(ctx, cName, fields, condiction, result, sort, cursor, limit)
+}
+
+func (r *txRouter) GetCntByConditionCtx(ctx context.Context, cName string, condiction interface{}) (int, error) {
+	return r.target(ctx).GetCntByConditionCtx(ctx, cName, condiction)
+}
+
+func (r *txRouter) DelByConditionCtx(ctx context.Context, cName string, condiction interface{}) error {
+	return r.target(ctx).DelByConditionCtx(ctx, cName, condiction)
+}
+
+// contextDI gives up the reads of the wrapped storage when the context is done,
+// the reads are not cancelled in the wrapped storage
+type contextDI struct {
+	DI
+}
+
+func (c *contextDI) GetIncIDCtx(ctx context.Context, cName string) (int64, error) {
+	var id int64
+	err := RunWrite(ctx, func() error {
+		var fnerr error
+		id, fnerr = c.GetIncID(cName)
+		return fnerr
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (c *contextDI) InsertCtx(ctx context.Context, cName string, data interface{}) (int, error) {
+	var id int
+	err := RunWrite(ctx, func() error {
+		var fnerr error
+		id, fnerr = c.Insert(cName, data)
+		return fnerr
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (c *contextDI) InsertMutiCtx(ctx context.Context, cName string, data ...interface{}) error {
+	return RunWrite(ctx, func() error {
+		return c.InsertMuti(cName, data...)
+	})
+}
+
+func (c *contextDI) UpdateByConditionCtx(ctx context.Context, cName string, data, condiction interface{}) error {
+	return RunWrite(ctx, func() error {
+		return c.UpdateByCondition(cName, data, condiction)
+	})
+}
+
+func (c *contextDI) GetOneByConditionCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}) error {
+	return ReadInto(result, func(result interface{}) error {
+		return RunWithContext(ctx, func() error {
+			return c.GetOneByCondition(cName, fields, condiction, result)
+		})
+	})
+}
+
+func (c *contextDI) GetMutilByConditionCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}, sort string, start, limit int) error {
+	return ReadInto(result, func(result interface{}) error {
+		return RunWithContext(ctx, func() error {
+			return c.GetMutilByCondition(cName, fields, condiction, result, sort, start, limit)
+		})
+	})
+}
+
+func (c *contextDI) GetMutilByCursorCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}, sort, cursor string, limit int) (string, error) {
+	var next string
+	err := ReadInto(result, func(result interface{}) error {
+		return RunWithContext(ctx, func() error {
+			var fnerr error
+			next, fnerr = c.GetMutilByCursor(cName, fields, condiction, result, sort, cursor, limit)
+			return fnerr
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+	return next, nil
+}
+
+func (c *contextDI) GetCntByConditionCtx(ctx context.Context, cName string, condiction interface{}) (int, error) {
+	var cnt int
+	err := RunWithContext(ctx, func() error {
+		var fnerr error
+		cnt, fnerr = c.GetCntByCondition(cName, condiction)
+		return fnerr
+	})
+	if err != nil {
+		return 0, err
+	}
+	return cnt, nil
+}
+
+func (c *contextDI) DelByConditionCtx(ctx context.Context, cName string, condiction interface{}) error {
+	return RunWrite(ctx, func() error {
+		return c.DelByCondition(cName, condiction)
+	})
+}
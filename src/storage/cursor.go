@@ -0,0 +1,91 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+	"errors"
+)
+
+// DefaultIteratorBatch the count of documents an iterator reads in a round trip
+const DefaultIteratorBatch = 500
+
+// ErrInvalidCursor the cursor is malformed or does not belong to the sort
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Iterator streams the documents which match a condiction page by page with the cursor,
+// the memory it takes is bounded by the batch however many documents match
+type Iterator struct {
+	ctx        context.Context
+	db         ContextDI
+	cName      string
+	fields     []string
+	condiction interface{}
+	sort       string
+	batch      int
+
+	cursor string
+	done   bool
+	docs   []map[string]interface{}
+	doc    map[string]interface{}
+	err    error
+}
+
+// NewIterator returns the iterator of the documents which match the condiction in the order of sort,
+// DefaultIteratorBatch is used if batch is not positive
+func NewIterator(ctx context.Context, db DI, cName string, fields []string, condiction interface{}, sort string, batch int) *Iterator {
+	if batch <= 0 {
+		batch = DefaultIteratorBatch
+	}
+	return &Iterator{
+		ctx:        ctx,
+		db:         ContextOf(db),
+		cName:      cName,
+		fields:     fields,
+		condiction: condiction,
+		sort:       sort,
+		batch:      batch,
+	}
+}
+
+// Next moves to the next document, it returns false when the documents are exhausted or an error occurs
+func (it *Iterator) Next() bool {
+	for 0 == len(it.docs) {
+		if it.done || it.err != nil {
+			it.doc = nil
+			return false
+		}
+		docs := make([]map[string]interface{}, 0)
+		next, err := it.db.GetMutilByCursorCtx(it.ctx, it.cName, it.fields, it.condiction, &docs, it.sort, it.cursor, it.batch)
+		if err != nil {
+			it.err = err
+			continue
+		}
+		it.docs = docs
+		it.cursor = next
+		it.done = "" == next
+	}
+	it.doc = it.docs[0]
+	it.docs = it.docs[1:]
+	return true
+}
+
+// Doc returns the current document
+func (it *Iterator) Doc() map[string]interface{} {
+	return it.doc
+}
+
+// Err returns the error which stops the iteration
+func (it *Iterator) Err() error {
+	return it.err
+}
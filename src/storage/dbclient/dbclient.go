@@ -14,11 +14,17 @@ package dbclient
 
 import (
 	"configcenter/src/storage"
+	"configcenter/src/storage/memclient"
 	"configcenter/src/storage/mgoclient"
 	"configcenter/src/storage/redisclient"
+	"configcenter/src/storage/sqlclient"
+	"fmt"
+	"strconv"
+	"time"
 )
 
-// NewDB return DI instance
+// NewDB return DI instance, mechanism is the connection option of the sql drivers,
+// the sslmode of postgres for example
 func NewDB(host, port, usr, pwd, mechanism, database, driverType string) (storage.DI, error) {
 	if driverType == storage.DI_MONGO {
 		db, err := mgoclient.NewMgoCli(host, port, usr, pwd, mechanism, database)
@@ -31,7 +37,57 @@ func NewDB(host, port, usr, pwd, mechanism, database, driverType string) (storag
 		if err == nil {
 			return db, err
 		}
+	} else if driverType == storage.DI_MEMORY {
+		return memclient.NewMemCli(database)
+	} else if dialect, ok := sqlDialects[driverType]; ok {
+		return sqlclient.NewSQLCli(dialect, host, port, usr, pwd, mechanism, database)
 	}
 	db, err := mgoclient.NewMgoCli(host, port, usr, pwd, mechanism, database)
 	return db, err
 }
+
+// sqlDialects the dialects of the sql drivers
+var sqlDialects = map[string]sqlclient.Dialect{
+	storage.DI_MYSQL:    sqlclient.MySQL{},
+	storage.DI_POSTGRES: sqlclient.Postgres{},
+	storage.DI_SQLITE:   sqlclient.SQLite{},
+}
+
+// Configure applies the settings of the configuration section to the driver before it is opened,
+// the drivers without any setting are left as they are
+func Configure(db storage.DI, config map[string]string, section string) error {
+	if mgocli, ok := db.(*mgoclient.MgoCli); ok {
+		opts, err := mgoclient.ParseOptions(config, section)
+		if err != nil {
+			return err
+		}
+		mgocli.SetOptions(opts)
+	}
+	return nil
+}
+
+// Instrument wraps the opened db by the slow query log when slowThreshold of the section is set,
+// explainSampleRate is the fraction of the slow reads whose plans are captured, slowLogSize is how many
+// recent slow queries are kept
+func Instrument(db storage.DI, config map[string]string, section string) (storage.DI, error) {
+	val := config[section+".slowThreshold"]
+	if "" == val {
+		return db, nil
+	}
+	opts := storage.SlowLogOptions{}
+	var err error
+	if opts.Threshold, err = time.ParseDuration(val); err != nil || opts.Threshold <= 0 {
+		return nil, fmt.Errorf("invalid %s.slowThreshold %s", section, val)
+	}
+	if val := config[section+".explainSampleRate"]; "" != val {
+		if opts.ExplainSampleRate, err = strconv.ParseFloat(val, 64); err != nil || opts.ExplainSampleRate < 0 || opts.ExplainSampleRate > 1 {
+			return nil, fmt.Errorf("invalid %s.explainSampleRate %s", section, val)
+		}
+	}
+	if val := config[section+".slowLogSize"]; "" != val {
+		if opts.Size, err = strconv.Atoi(val); err != nil || opts.Size <= 0 {
+			return nil, fmt.Errorf("invalid %s.slowLogSize %s", section, val)
+		}
+	}
+	return storage.NewSlowLogDI(db, opts), nil
+}
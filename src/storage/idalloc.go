@@ -0,0 +1,156 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// DefaultIDBlockSize the count of the sequence IDs leased by the allocator at a time
+const DefaultIDBlockSize = 100
+
+// IDReserver is implemented by the storages which are able to advance the sequence by a block atomically
+type IDReserver interface {
+	// ReserveIncIDCtx advances the sequence of cName by n and returns the last ID of the block,
+	// the IDs from last-n+1 to last belong to the caller
+	ReserveIncIDCtx(ctx context.Context, cName string, n int64) (int64, error)
+}
+
+// ReserveIncIDs reserves n sequence IDs of cName on db, the storage which does not implement
+// IDReserver allocates them one by one
+func ReserveIncIDs(ctx context.Context, db DI, cName string, n int) ([]int64, error) {
+	if n <= 0 {
+		return []int64{}, nil
+	}
+	if reserver, ok := Unwrap(db).(IDReserver); ok {
+		last, err := reserver.ReserveIncIDCtx(ctx, cName, int64(n))
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]int64, 0, n)
+		for id := last - int64(n) + 1; id <= last; id++ {
+			ids = append(ids, id)
+		}
+		return ids, nil
+	}
+
+	cdb := ContextOf(db)
+	ids := make([]int64, 0, n)
+	for i := 0; i < n; i++ {
+		id, err := cdb.GetIncIDCtx(ctx, cName)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// idLease the IDs from next to last are not handed out yet
+type idLease struct {
+	next int64
+	last int64
+}
+
+// IDAllocator hands out the sequence IDs from the blocks leased from the storage, the blocks never overlap
+// because the sequence in the storage is advanced by the whole block, so the IDs stay unique across the
+// processes and the restarts, the IDs of different processes interleave and the rest of a block is
+// skipped when the process exits
+type IDAllocator struct {
+	db        DI
+	blockSize int64
+
+	lock   sync.Mutex
+	leases map[string]*idLease
+}
+
+// NewIDAllocator returns the allocator which leases blockSize IDs at a time from db
+func NewIDAllocator(db DI, blockSize int) *IDAllocator {
+	if blockSize <= 0 {
+		blockSize = DefaultIDBlockSize
+	}
+	return &IDAllocator{db: db, blockSize: int64(blockSize), leases: map[string]*idLease{}}
+}
+
+var (
+	allocatorsLock sync.Mutex
+	allocators     = map[DI]*IDAllocator{}
+)
+
+// IDAllocatorOf returns the process wide allocator of db
+func IDAllocatorOf(db DI) *IDAllocator {
+	allocatorsLock.Lock()
+	defer allocatorsLock.Unlock()
+	alloc, ok := allocators[db]
+	if !ok {
+		alloc = NewIDAllocator(db, DefaultIDBlockSize)
+		allocators[db] = alloc
+	}
+	return alloc
+}
+
+// NextID returns the next sequence ID of cName
+func (a *IDAllocator) NextID(ctx context.Context, cName string) (int64, error) {
+	ids, err := a.Reserve(ctx, cName, 1)
+	if err != nil {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// Reserve returns n sequence IDs of cName in ascending order, the lease is used up first and
+// the rest is reserved from the storage in one call
+func (a *IDAllocator) Reserve(ctx context.Context, cName string, n int) ([]int64, error) {
+	if n < 0 {
+		return nil, errors.New("the count of the IDs must not be negative")
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	ids := make([]int64, 0, n)
+	lease := a.leases[cName]
+	for lease != nil && lease.next <= lease.last && len(ids) < n {
+		ids = append(ids, lease.next)
+		lease.next++
+	}
+	need := int64(n - len(ids))
+	if 0 == need {
+		return ids, nil
+	}
+
+	if _, ok := Unwrap(a.db).(IDReserver); !ok {
+		// the storage could not lease a block in one call, so nothing is leased ahead
+		block, err := ReserveIncIDs(ctx, a.db, cName, int(need))
+		if err != nil {
+			return nil, err
+		}
+		return append(ids, block...), nil
+	}
+
+	// the batch larger than a block takes its IDs without leasing more
+	count := a.blockSize
+	if need > count {
+		count = need
+	}
+	block, err := ReserveIncIDs(ctx, a.db, cName, int(count))
+	if err != nil {
+		return nil, err
+	}
+	ids = append(ids, block[:need]...)
+	if rest := block[need:]; 0 != len(rest) {
+		a.leases[cName] = &idLease{next: rest[0], last: rest[len(rest)-1]}
+	}
+	return ids, nil
+}
@@ -0,0 +1,243 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// IndexManager is implemented by the storages which are able to list and drop the live indexes
+type IndexManager interface {
+	// IndexesCtx returns the indexes of cName except the one of the row identity,
+	// the missing collection has no index
+	IndexesCtx(ctx context.Context, cName string) ([]Index, error)
+	// DropIndexCtx drops the index of the name
+	DropIndexCtx(ctx context.Context, cName, name string) error
+}
+
+// IndexName returns the name of the index, the unnamed index takes the mongo default name,
+// bk_biz_id_1_create_time_-1 for example
+func IndexName(index *Index) string {
+	if "" != index.Name {
+		return index.Name
+	}
+	parts := make([]string, 0, 2*len(index.Columns))
+	for _, column := range index.Columns {
+		if strings.HasPrefix(column, "-") {
+			parts = append(parts, strings.TrimPrefix(column, "-"), "-1")
+		} else {
+			parts = append(parts, strings.TrimPrefix(column, "+"), "1")
+		}
+	}
+	return strings.Join(parts, "_")
+}
+
+// IsUniqueIndex returns true if the index rejects the duplicate keys
+func IsUniqueIndex(index *Index) bool {
+	switch index.Type {
+	case INDEX_TYPE_UNIQUE, INDEX_TYPE_BACKGROUP_UNIQUE, INDEX_TYPE_PRIMAEY:
+		return true
+	}
+	return false
+}
+
+// the actions of the index drift
+const (
+	IndexDriftCreate   = "create"
+	IndexDriftRecreate = "recreate"
+	IndexDriftDrop     = "drop"
+	IndexDriftExtra    = "extra"
+)
+
+// IndexDrift the difference between the index spec and the live index
+type IndexDrift struct {
+	Collection string `json:"collection"`
+	Name       string `json:"name"`
+	// Action create for the missing index, recreate for the changed one, drop or extra for the one
+	// out of the spec, extra is only reported. The changed index is built before the live one is dropped,
+	// see recreateIndex
+	Action string `json:"action"`
+	Reason string `json:"reason,omitempty"`
+	// Applied is true once the action is taken
+	Applied bool   `json:"applied"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ReconcileOptions the options of the index reconciliation
+type ReconcileOptions struct {
+	// DryRun only reports the drift
+	DryRun bool
+	// DropExtra drops the live indexes of the collections in the spec which are out of the spec,
+	// they are only reported otherwise
+	DropExtra bool
+	// Prune returns true for the live indexes which are dropped once out of the spec even without DropExtra,
+	// the indexes generated from the data for example
+	Prune func(name string) bool
+	// Scope returns true for the indexes which are reconciled, nil for all, the others are left as they are
+	Scope func(name string) bool
+}
+
+// ReconcileIndexes makes the indexes of the collections in specs the same as the specs, the spec
+// collection is keyed by the index name, see IndexName. The failure of an index is put in its drift and
+// the others go on. The storage which does not implement IndexManager only gets the missing indexes created
+func ReconcileIndexes(ctx context.Context, db DI, specs map[string][]Index, opts ReconcileOptions) ([]IndexDrift, error) {
+	cNames := make([]string, 0, len(specs))
+	for cName := range specs {
+		cNames = append(cNames, cName)
+	}
+	sort.Strings(cNames)
+
+	manager, ok := Unwrap(db).(IndexManager)
+	drifts := make([]IndexDrift, 0)
+	for _, cName := range cNames {
+		if err := ctx.Err(); err != nil {
+			return drifts, err
+		}
+		if !ok {
+			for _, index := range specs[cName] {
+				if !opts.inScope(IndexName(&index)) {
+					continue
+				}
+				drift := IndexDrift{Collection: cName, Name: IndexName(&index), Action: IndexDriftCreate, Reason: "the storage could not list the indexes"}
+				applyDrift(&drift, opts, func() error { return db.Index(cName, &index) })
+				drifts = append(drifts, drift)
+			}
+			continue
+		}
+
+		live, err := manager.IndexesCtx(ctx, cName)
+		if err != nil {
+			return drifts, err
+		}
+		liveByName := make(map[string]Index, len(live))
+		for _, index := range live {
+			if name := IndexName(&index); opts.inScope(name) {
+				liveByName[name] = index
+			}
+		}
+
+		specNames := make(map[string]bool, len(specs[cName]))
+		for _, spec := range specs[cName] {
+			spec := spec
+			name := IndexName(&spec)
+			spec.Name = name
+			specNames[name] = true
+
+			if !opts.inScope(name) {
+				continue
+			}
+			current, exists := liveByName[name]
+			if !exists {
+				drift := IndexDrift{Collection: cName, Name: name, Action: IndexDriftCreate}
+				applyDrift(&drift, opts, func() error { return db.Index(cName, &spec) })
+				drifts = append(drifts, drift)
+				continue
+			}
+			if reason := indexDiff(&spec, &current); "" != reason {
+				drift := IndexDrift{Collection: cName, Name: name, Action: IndexDriftRecreate, Reason: reason}
+				applyDrift(&drift, opts, func() error { return recreateIndex(ctx, db, manager, cName, &spec) })
+				drifts = append(drifts, drift)
+			}
+		}
+
+		for _, index := range live {
+			name := IndexName(&index)
+			if specNames[name] || !opts.inScope(name) {
+				continue
+			}
+			if !opts.DropExtra && (nil == opts.Prune || !opts.Prune(name)) {
+				drifts = append(drifts, IndexDrift{Collection: cName, Name: name, Action: IndexDriftExtra})
+				continue
+			}
+			drift := IndexDrift{Collection: cName, Name: name, Action: IndexDriftDrop}
+			applyDrift(&drift, opts, func() error { return manager.DropIndexCtx(ctx, cName, name) })
+			drifts = append(drifts, drift)
+		}
+	}
+	return drifts, nil
+}
+
+// recreateIndex replaces the live index of the name of spec by spec. The spec is built first under a
+// temporary name with the first column in the other direction, which takes the same keys but does not clash
+// with the key pattern of spec, so the failure to build it leaves the live index as it is. The live index
+// is dropped only after, and the temporary one once spec is built, it is kept if the build fails
+func recreateIndex(ctx context.Context, db DI, manager IndexManager, cName string, spec *Index) error {
+	temp := *spec
+	temp.Name = spec.Name + "_reconcile"
+	temp.Columns = append([]string{reverseColumn(spec.Columns[0])}, spec.Columns[1:]...)
+	if err := db.Index(cName, &temp); err != nil {
+		return fmt.Errorf("build the index under %s failed, the live index is kept: %v", temp.Name, err)
+	}
+	if err := manager.DropIndexCtx(ctx, cName, spec.Name); err != nil {
+		if derr := manager.DropIndexCtx(ctx, cName, temp.Name); derr != nil {
+			return fmt.Errorf("%v, drop %s failed: %v", err, temp.Name, derr)
+		}
+		return err
+	}
+	if err := db.Index(cName, spec); err != nil {
+		return fmt.Errorf("build the index failed, %s is kept in place of it: %v", temp.Name, err)
+	}
+	return manager.DropIndexCtx(ctx, cName, temp.Name)
+}
+
+// reverseColumn returns the column in the other sort direction
+func reverseColumn(column string) string {
+	if strings.HasPrefix(column, "-") {
+		return strings.TrimPrefix(column, "-")
+	}
+	return "-" + strings.TrimPrefix(column, "+")
+}
+
+func (opts *ReconcileOptions) inScope(name string) bool {
+	return nil == opts.Scope || opts.Scope(name)
+}
+
+func applyDrift(drift *IndexDrift, opts ReconcileOptions, fn func() error) {
+	if opts.DryRun {
+		return
+	}
+	if err := fn(); err != nil {
+		drift.Error = err.Error()
+		return
+	}
+	drift.Applied = true
+}
+
+// indexDiff returns why the live index differs from the spec, the building options are not compared
+func indexDiff(spec, live *Index) string {
+	if !reflect.DeepEqual(normalColumns(spec.Columns), normalColumns(live.Columns)) {
+		return fmt.Sprintf("columns %v, expect %v", live.Columns, spec.Columns)
+	}
+	if IsUniqueIndex(spec) != IsUniqueIndex(live) {
+		return fmt.Sprintf("unique %v, expect %v", IsUniqueIndex(live), IsUniqueIndex(spec))
+	}
+	if spec.ExpireAfter != live.ExpireAfter {
+		return fmt.Sprintf("expire after %v, expect %v", live.ExpireAfter, spec.ExpireAfter)
+	}
+	if fmt.Sprint(spec.PartialFilter) != fmt.Sprint(live.PartialFilter) {
+		return fmt.Sprintf("partial filter %v, expect %v", live.PartialFilter, spec.PartialFilter)
+	}
+	return ""
+}
+
+func normalColumns(columns []string) []string {
+	normal := make([]string, 0, len(columns))
+	for _, column := range columns {
+		normal = append(normal, strings.TrimPrefix(column, "+"))
+	}
+	return normal
+}
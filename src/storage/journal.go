@@ -0,0 +1,406 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"configcenter/src/common/blog"
+)
+
+// TxJournalTable the table keeps the undo journal of the journal transactions
+const TxJournalTable = "cc_TxJournal"
+
+const journalTxPrefix = "j-"
+
+// Snapshotter is implemented by the storages which are able to take and restore the raw documents,
+// the journal transactions use it to undo the writes
+type Snapshotter interface {
+	// SnapshotCtx returns the raw documents which match the condiction, the row identity included
+	SnapshotCtx(ctx context.Context, cName string, condiction interface{}) ([]map[string]interface{}, error)
+	// RowID returns the row identity of the raw document
+	RowID(doc map[string]interface{}) interface{}
+	// InsertRowsCtx inserts the documents and returns their row identities
+	InsertRowsCtx(ctx context.Context, cName string, data ...interface{}) ([]interface{}, error)
+	// ClaimCtx sets data on the document which matches condiction and returns false if none matches,
+	// the match and the update are atomic, condiction matches one document at most
+	ClaimCtx(ctx context.Context, cName string, condiction, data interface{}) (bool, error)
+	// RestoreCtx removes the rows of ids and writes the raw documents back
+	RestoreCtx(ctx context.Context, cName string, ids []interface{}, docs []map[string]interface{}) error
+}
+
+// the states of the journal transaction kept by the entry of seq 0, the state is changed by ClaimCtx
+// so that only one of the commit and the aborts takes the transaction
+const (
+	journalRunning   = "running"
+	journalAborting  = "aborting"
+	journalCommitted = "committed"
+)
+
+// journalEntry the undo record of a write, the rows of RowIDs are removed and Docs are written back
+// when the transaction is aborted, the entry of seq 0 marks the start of the transaction and keeps its state
+type journalEntry struct {
+	TxID       string                   `bson:"tx_id"`
+	Seq        int64                    `bson:"seq"`
+	Collection string                   `bson:"collection"`
+	RowIDs     []interface{}            `bson:"row_ids"`
+	Docs       []map[string]interface{} `bson:"docs"`
+	CreateTime time.Time                `bson:"create_time"`
+	State      string                   `bson:"state,omitempty"`
+	ClaimTime  time.Time                `bson:"claim_time,omitempty"`
+}
+
+// journalTx runs the writes at once and keeps their undo journal in TxJournalTable,
+// so the writes are visible to the others before the transaction is committed
+type journalTx struct {
+	DI
+	db   ContextDI
+	snap Snapshotter
+	id   string
+}
+
+var _ Tx = (*journalTx)(nil)
+
+// StartJournalTx starts a transaction on db which undoes the writes by the journal when it is aborted,
+// the expired journal transactions are aborted at the same time
+func StartJournalTx(ctx context.Context, db DI) (Tx, error) {
+	tx, err := newJournalTx(db, "")
+	if err != nil {
+		return nil, err
+	}
+	tx.abortExpired(ctx)
+
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	tx.id = journalTxPrefix + hex.EncodeToString(buf)
+	entry := &journalEntry{TxID: tx.id, CreateTime: time.Now().UTC(), State: journalRunning}
+	if _, err := tx.db.InsertCtx(ctx, TxJournalTable, entry); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// ResumeJournalTx resumes the journal transaction of txID on db
+func ResumeJournalTx(ctx context.Context, db DI, txID string) (Tx, error) {
+	if !isJournalTxID(txID) {
+		return nil, ErrTxNotFound
+	}
+	tx, err := newJournalTx(db, txID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.check(ctx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// TxPending returns true if the journal transaction of txID is running or being aborted, the transactions
+// of the storage itself keep no journal, their writes are seen only after they commit
+func TxPending(ctx context.Context, db DI, txID string) (bool, error) {
+	if !isJournalTxID(txID) {
+		return false, nil
+	}
+	cond := map[string]interface{}{
+		"tx_id": txID,
+		"seq":   0,
+		"state": map[string]interface{}{"$ne": journalCommitted},
+	}
+	cnt, err := ContextOf(db).GetCntByConditionCtx(ctx, TxJournalTable, cond)
+	if err != nil {
+		return false, err
+	}
+	return 0 < cnt, nil
+}
+
+func isJournalTxID(txID string) bool {
+	return strings.HasPrefix(txID, journalTxPrefix)
+}
+
+func newJournalTx(db DI, txID string) (*journalTx, error) {
+	snap, ok := Unwrap(db).(Snapshotter)
+	if !ok {
+		return nil, ErrTxNotSupported
+	}
+	cdb, ok := db.(ContextDI)
+	if !ok {
+		cdb = &contextDI{DI: db}
+	}
+	return &journalTx{DI: db, db: cdb, snap: snap, id: txID}, nil
+}
+
+// ID returns the transaction id
+func (t *journalTx) ID() string {
+	return t.id
+}
+
+// check returns ErrTxNotFound if the transaction is finished or expired
+func (t *journalTx) check(ctx context.Context) error {
+	cond := map[string]interface{}{
+		"tx_id":       t.id,
+		"seq":         0,
+		"state":       journalRunning,
+		"create_time": map[string]interface{}{"$gt": time.Now().UTC().Add(-TxTimeout)},
+	}
+	cnt, err := t.db.GetCntByConditionCtx(ctx, TxJournalTable, cond)
+	if err != nil {
+		return err
+	}
+	if 0 == cnt {
+		return ErrTxNotFound
+	}
+	return nil
+}
+
+// Commit takes the running transaction which is not expired and drops its journal
+func (t *journalTx) Commit(ctx context.Context) error {
+	cond := map[string]interface{}{
+		"tx_id":       t.id,
+		"seq":         0,
+		"state":       journalRunning,
+		"create_time": map[string]interface{}{"$gt": time.Now().UTC().Add(-TxTimeout)},
+	}
+	claimed, err := t.snap.ClaimCtx(ctx, TxJournalTable, cond, map[string]interface{}{"state": journalCommitted})
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return ErrTxNotFound
+	}
+	return t.db.DelByConditionCtx(ctx, TxJournalTable, map[string]interface{}{"tx_id": t.id})
+}
+
+// Abort takes the running transaction and undoes its writes, ErrTxNotFound is returned
+// if it is committed or taken by another abort
+func (t *journalTx) Abort(ctx context.Context) error {
+	cond := map[string]interface{}{"tx_id": t.id, "seq": 0, "state": journalRunning}
+	if err := t.claimAbort(ctx, cond); err != nil {
+		return err
+	}
+	return t.undo(ctx)
+}
+
+// claimAbort changes the state of the transaction to aborting if it still matches cond
+func (t *journalTx) claimAbort(ctx context.Context, cond map[string]interface{}) error {
+	data := map[string]interface{}{"state": journalAborting, "claim_time": time.Now().UTC()}
+	claimed, err := t.snap.ClaimCtx(ctx, TxJournalTable, cond, data)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return ErrTxNotFound
+	}
+	return nil
+}
+
+// undo undoes the writes of the claimed transaction in the reverse order and drops its journal,
+// the undo is repeatable so the abort which stops in the middle is taken again once expired
+func (t *journalTx) undo(ctx context.Context) error {
+	entries := make([]journalEntry, 0)
+	cond := map[string]interface{}{"tx_id": t.id}
+	if err := t.db.GetMutilByConditionCtx(ctx, TxJournalTable, nil, cond, &entries, "-seq", 0, 0); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if 0 == entry.Seq {
+			continue
+		}
+		if err := t.snap.RestoreCtx(ctx, entry.Collection, entry.RowIDs, entry.Docs); err != nil {
+			blog.Errorf("failed to undo the write of transaction %s on %s, error: %v", t.id, entry.Collection, err)
+			return err
+		}
+	}
+	return t.db.DelByConditionCtx(ctx, TxJournalTable, cond)
+}
+
+// abortExpired aborts the journal transactions which are not finished in TxTimeout, and takes again the aborts
+// and the commits which stop for TxTimeout, every one of them is claimed first so it is taken only once
+func (t *journalTx) abortExpired(ctx context.Context) {
+	entries := make([]journalEntry, 0)
+	expire := time.Now().UTC().Add(-TxTimeout)
+	cond := map[string]interface{}{
+		"seq":         0,
+		"create_time": map[string]interface{}{"$lte": expire},
+	}
+	if err := t.db.GetMutilByConditionCtx(ctx, TxJournalTable, nil, cond, &entries, "", 0, 0); err != nil {
+		blog.Errorf("failed to search the expired transactions, error: %v", err)
+		return
+	}
+	for _, entry := range entries {
+		expired := &journalTx{DI: t.DI, db: t.db, snap: t.snap, id: entry.TxID}
+		claim := map[string]interface{}{"tx_id": entry.TxID, "seq": 0, "state": entry.State}
+		switch entry.State {
+		case journalCommitted:
+			// the commit stops before the journal is dropped
+			if err := t.db.DelByConditionCtx(ctx, TxJournalTable, map[string]interface{}{"tx_id": entry.TxID}); err != nil {
+				blog.Errorf("failed to drop the journal of the committed transaction %s, error: %v", entry.TxID, err)
+			}
+			continue
+		case journalAborting:
+			if entry.ClaimTime.After(expire) {
+				// the abort is still going on
+				continue
+			}
+			claim["claim_time"] = entry.ClaimTime
+		case journalRunning:
+		default:
+			// the journal of the earlier version has no state
+			claim["state"] = map[string]interface{}{"$exists": false}
+		}
+		if err := expired.claimAbort(ctx, claim); err != nil {
+			if ErrTxNotFound != err {
+				blog.Errorf("failed to claim the expired transaction %s, error: %v", entry.TxID, err)
+			}
+			continue
+		}
+		blog.Warnf("abort the expired transaction %s", entry.TxID)
+		if err := expired.undo(ctx); err != nil {
+			blog.Errorf("failed to abort the expired transaction %s, error: %v", entry.TxID, err)
+		}
+	}
+}
+
+// journal records the undo entry of a write
+func (t *journalTx) journal(ctx context.Context, cName string, ids []interface{}, docs []map[string]interface{}) error {
+	if 0 == len(ids) && 0 == len(docs) {
+		return nil
+	}
+	seq, err := t.db.GetIncIDCtx(ctx, TxJournalTable)
+	if err != nil {
+		return err
+	}
+	entry := &journalEntry{
+		TxID:       t.id,
+		Seq:        seq,
+		Collection: cName,
+		RowIDs:     ids,
+		Docs:       docs,
+		CreateTime: time.Now().UTC(),
+	}
+	_, err = t.db.InsertCtx(ctx, TxJournalTable, entry)
+	return err
+}
+
+// journalBefore records the documents which are going to be changed, the transaction expired or taken
+// by an abort takes no more writes
+func (t *journalTx) journalBefore(ctx context.Context, cName string, condiction interface{}) error {
+	if err := t.check(ctx); err != nil {
+		return err
+	}
+	docs, err := t.snap.SnapshotCtx(ctx, cName, condiction)
+	if err != nil {
+		return err
+	}
+	ids := make([]interface{}, 0, len(docs))
+	for _, doc := range docs {
+		ids = append(ids, t.snap.RowID(doc))
+	}
+	return t.journal(ctx, cName, ids, docs)
+}
+
+// insert inserts the documents and records the row identities given by the storage,
+// so that the abort removes exactly the inserted rows
+func (t *journalTx) insert(ctx context.Context, cName string, data ...interface{}) error {
+	if err := t.check(ctx); err != nil {
+		return err
+	}
+	ids, err := t.snap.InsertRowsCtx(ctx, cName, data...)
+	if err != nil {
+		return err
+	}
+	return t.journal(ctx, cName, ids, nil)
+}
+
+func (t *journalTx) GetIncID(cName string) (int64, error) {
+	return t.GetIncIDCtx(context.Background(), cName)
+}
+
+func (t *journalTx) GetIncIDCtx(ctx context.Context, cName string) (int64, error) {
+	return t.db.GetIncIDCtx(ctx, cName)
+}
+
+func (t *journalTx) Insert(cName string, data interface{}) (int, error) {
+	return t.InsertCtx(context.Background(), cName, data)
+}
+
+func (t *journalTx) InsertCtx(ctx context.Context, cName string, data interface{}) (int, error) {
+	return 0, t.insert(ctx, cName, data)
+}
+
+func (t *journalTx) InsertMuti(cName string, data ...interface{}) error {
+	return t.InsertMutiCtx(context.Background(), cName, data...)
+}
+
+func (t *journalTx) InsertMutiCtx(ctx context.Context, cName string, data ...interface{}) error {
+	return t.insert(ctx, cName, data...)
+}
+
+func (t *journalTx) UpdateByCondition(cName string, data, condiction interface{}) error {
+	return t.UpdateByConditionCtx(context.Background(), cName, data, condiction)
+}
+
+func (t *journalTx) UpdateByConditionCtx(ctx context.Context, cName string, data, condiction interface{}) error {
+	if err := t.journalBefore(ctx, cName, condiction); err != nil {
+		return err
+	}
+	return t.db.UpdateByConditionCtx(ctx, cName, data, condiction)
+}
+
+func (t *journalTx) GetOneByCondition(cName string, fields []string, condiction interface{}, result interface{}) error {
+	return t.GetOneByConditionCtx(context.Background(), cName, fields, condiction, result)
+}
+
+func (t *journalTx) GetOneByConditionCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}) error {
+	return t.db.GetOneByConditionCtx(ctx, cName, fields, condiction, result)
+}
+
+func (t *journalTx) GetMutilByCondition(cName string, fields []string, condiction interface{}, result interface{}, sort string, start, limit int) error {
+	return t.GetMutilByConditionCtx(context.Background(), cName, fields, condiction, result, sort, start, limit)
+}
+
+func (t *journalTx) GetMutilByConditionCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}, sort string, start, limit int) error {
+	return t.db.GetMutilByConditionCtx(ctx, cName, fields, condiction, result, sort, start, limit)
+}
+
+func (t *journalTx) GetMutilByCursor(cName string, fields []string, condiction interface{}, result interface{}, sort, cursor string, limit int) (string, error) {
+	return t.GetMutilByCursorCtx(context.Background(), cName, fields, condiction, result, sort, cursor, limit)
+}
+
+func (t *journalTx) GetMutilByCursorCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}, sort, cursor string, limit int) (string, error) {
+	return t.db.GetMutilByCursorCtx(ctx, cName, fields, condiction, result, sort, cursor, limit)
+}
+
+func (t *journalTx) GetCntByCondition(cName string, condiction interface{}) (int, error) {
+	return t.GetCntByConditionCtx(context.Background(), cName, condiction)
+}
+
+func (t *journalTx) GetCntByConditionCtx(ctx context.Context, cName string, condiction interface{}) (int, error) {
+	return t.db.GetCntByConditionCtx(ctx, cName, condiction)
+}
+
+func (t *journalTx) DelByCondition(cName string, condiction interface{}) error {
+	return t.DelByConditionCtx(context.Background(), cName, condiction)
+}
+
+func (t *journalTx) DelByConditionCtx(ctx context.Context, cName string, condiction interface{}) error {
+	if err := t.journalBefore(ctx, cName, condiction); err != nil {
+		return err
+	}
+	return t.db.DelByConditionCtx(ctx, cName, condiction)
+}
@@ -0,0 +1,138 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package memclient
+
+import (
+	"configcenter/src/storage"
+	"context"
+	"encoding/base64"
+	"strings"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// cursorToken the content of a cursor, the sort values of the last document of a page
+type cursorToken struct {
+	Sort   string        `bson:"s"`
+	Values []interface{} `bson:"v"`
+}
+
+// cursorKeys returns the keyset order of sort, _id is appended to break the ties
+func cursorKeys(sort string) []sortKey {
+	keys := sortKeys(sort)
+	for i, key := range keys {
+		if rowIDField == key.field {
+			// _id is unique, the keys after it never take effect
+			return keys[:i+1]
+		}
+	}
+	return append(keys, sortKey{field: rowIDField, order: 1})
+}
+
+// GetMutilByCursor get multiple document after the cursor
+func (m *MemCli) GetMutilByCursor(cName string, fields []string, condiction interface{}, result interface{}, sort, cursor string, limit int) (string, error) {
+	return m.GetMutilByCursorCtx(context.Background(), cName, fields, condiction, result, sort, cursor, limit)
+}
+
+// GetMutilByCursorCtx get multiple document after the cursor, the documents are ordered by sort and then _id
+func (m *MemCli) GetMutilByCursorCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}, sort, cursor string, limit int) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	keys := cursorKeys(sort)
+	var after []interface{}
+	if "" != cursor {
+		var err error
+		if after, err = decodeCursor(keys, cursor); err != nil {
+			return "", err
+		}
+	}
+
+	m.db.lock.RLock()
+	defer m.db.lock.RUnlock()
+	rows, err := filter(m.collection(cName, false), condiction)
+	if err != nil {
+		return "", err
+	}
+	sortRows(rows, keys)
+	if nil != after {
+		// the rows are sorted by the keys, so the rows after the cursor are a suffix
+		i := 0
+		for i < len(rows) && !isAfter(rows[i], keys, after) {
+			i++
+		}
+		rows = rows[i:]
+	}
+	rows = page(rows, 0, limit)
+
+	next := ""
+	if 0 < limit && len(rows) == limit {
+		if next, err = encodeCursor(keys, rows[len(rows)-1]); err != nil {
+			return "", err
+		}
+	}
+	docs := make([]bson.M, 0, len(rows))
+	for _, r := range rows {
+		docs = append(docs, project(r.doc, fields))
+	}
+	return next, decodeDocs(docs, result)
+}
+
+// isAfter returns true if the row is after the sort values in the order of the keys
+func isAfter(r *row, keys []sortKey, values []interface{}) bool {
+	for i, key := range keys {
+		if c := compare(sortValue(r, key.field), values[i]); 0 != c {
+			return c*key.order > 0
+		}
+	}
+	return false
+}
+
+func sortText(keys []sortKey) string {
+	fields := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key.order < 0 {
+			fields = append(fields, "-"+key.field)
+		} else {
+			fields = append(fields, key.field)
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+func encodeCursor(keys []sortKey, r *row) (string, error) {
+	token := cursorToken{Sort: sortText(keys), Values: make([]interface{}, 0, len(keys))}
+	for _, key := range keys {
+		token.Values = append(token.Values, sortValue(r, key.field))
+	}
+	data, err := bson.Marshal(&token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(keys []sortKey, cursor string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, storage.ErrInvalidCursor
+	}
+	token := cursorToken{}
+	if err := bson.Unmarshal(data, &token); err != nil {
+		return nil, storage.ErrInvalidCursor
+	}
+	if token.Sort != sortText(keys) || len(token.Values) != len(keys) {
+		return nil, storage.ErrInvalidCursor
+	}
+	return token.Values, nil
+}
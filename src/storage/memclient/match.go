@@ -0,0 +1,410 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package memclient
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// the type orders of mongo, the values of different types compare by the orders
+const (
+	rankNull = iota
+	rankNumber
+	rankString
+	rankObject
+	rankArray
+	rankObjectID
+	rankBool
+	rankTime
+	rankOther
+)
+
+// rank returns the type order of the value
+func rank(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return rankNull
+	case int, int32, int64, float64:
+		return rankNumber
+	case string:
+		return rankString
+	case bson.M:
+		return rankObject
+	case []interface{}:
+		return rankArray
+	case bson.ObjectId:
+		return rankObjectID
+	case bool:
+		return rankBool
+	case time.Time:
+		return rankTime
+	}
+	return rankOther
+}
+
+// number returns the float of the numeric value
+func number(v interface{}) float64 {
+	switch val := v.(type) {
+	case int:
+		return float64(val)
+	case int32:
+		return float64(val)
+	case int64:
+		return float64(val)
+	case float64:
+		return val
+	}
+	return math.NaN()
+}
+
+// compare returns the order of a and b in the same way as the sort of mongo
+func compare(a, b interface{}) int {
+	ra, rb := rank(a), rank(b)
+	if ra != rb {
+		return sign(ra - rb)
+	}
+	switch ra {
+	case rankNumber:
+		fa, fb := number(a), number(b)
+		if fa < fb {
+			return -1
+		} else if fa > fb {
+			return 1
+		}
+		return 0
+	case rankString:
+		return strings.Compare(a.(string), b.(string))
+	case rankObjectID:
+		return strings.Compare(string(a.(bson.ObjectId)), string(b.(bson.ObjectId)))
+	case rankBool:
+		if a.(bool) == b.(bool) {
+			return 0
+		} else if b.(bool) {
+			return -1
+		}
+		return 1
+	case rankTime:
+		ta, tb := a.(time.Time), b.(time.Time)
+		if ta.Before(tb) {
+			return -1
+		} else if ta.After(tb) {
+			return 1
+		}
+		return 0
+	case rankArray:
+		aa, ab := a.([]interface{}), b.([]interface{})
+		for i := 0; i < len(aa) && i < len(ab); i++ {
+			if c := compare(aa[i], ab[i]); 0 != c {
+				return c
+			}
+		}
+		return sign(len(aa) - len(ab))
+	case rankObject:
+		// the fields compare in the order of the names as bson.M keeps no order
+		da, db := a.(bson.M), b.(bson.M)
+		if c := compare(sortedFields(da), sortedFields(db)); 0 != c {
+			return c
+		}
+		return 0
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// sortedFields returns the names and the values of the document in the order of the names
+func sortedFields(doc bson.M) []interface{} {
+	keys := make([]string, 0, len(doc))
+	for key := range doc {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	fields := make([]interface{}, 0, len(doc)*2)
+	for _, key := range keys {
+		fields = append(fields, key, doc[key])
+	}
+	return fields
+}
+
+func sign(n int) int {
+	if n < 0 {
+		return -1
+	} else if n > 0 {
+		return 1
+	}
+	return 0
+}
+
+// resolve returns the values of the dotted field, the arrays on the path are walked through
+func resolve(v interface{}, path []string) []interface{} {
+	if 0 == len(path) {
+		return []interface{}{v}
+	}
+	switch val := v.(type) {
+	case bson.M:
+		sub, ok := val[path[0]]
+		if !ok {
+			return nil
+		}
+		return resolve(sub, path[1:])
+	case []interface{}:
+		values := make([]interface{}, 0)
+		for _, item := range val {
+			if _, ok := item.(bson.M); ok {
+				values = append(values, resolve(item, path)...)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// candidates returns the values compared with the condiction, the elements of the arrays included
+func candidates(values []interface{}) []interface{} {
+	result := make([]interface{}, 0, len(values))
+	for _, val := range values {
+		result = append(result, val)
+		if arr, ok := val.([]interface{}); ok {
+			result = append(result, arr...)
+		}
+	}
+	return result
+}
+
+// matcher matches the documents with a mongo style condiction
+type matcher struct {
+	regexps map[string]*regexp.Regexp
+}
+
+func newMatcher() *matcher {
+	return &matcher{regexps: make(map[string]*regexp.Regexp)}
+}
+
+// match returns true if the document matches the condiction
+func (m *matcher) match(doc bson.M, cond bson.M) (bool, error) {
+	for key, val := range cond {
+		var ok bool
+		var err error
+		switch key {
+		case "$and":
+			ok, err = m.logic(doc, val, true)
+		case "$or":
+			ok, err = m.logic(doc, val, false)
+		case "$nor":
+			ok, err = m.logic(doc, val, false)
+			ok = !ok
+		default:
+			if strings.HasPrefix(key, "$") {
+				return false, fmt.Errorf("unsupported operator %s", key)
+			}
+			ok, err = m.field(doc, key, val)
+		}
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// logic returns the and or the or of the condictions of the array
+func (m *matcher) logic(doc bson.M, v interface{}, and bool) (bool, error) {
+	items, ok := v.([]interface{})
+	if !ok || 0 == len(items) {
+		return false, errors.New("$and, $or and $nor need a nonempty array")
+	}
+	for _, item := range items {
+		cond, ok := item.(bson.M)
+		if !ok {
+			return false, errors.New("$and, $or and $nor need an array of documents")
+		}
+		matched, err := m.match(doc, cond)
+		if err != nil {
+			return false, err
+		}
+		if matched != and {
+			return matched, nil
+		}
+	}
+	return and, nil
+}
+
+// operators returns the operator document of the field condiction, or nil if it is a value
+func operators(v interface{}) bson.M {
+	doc, ok := v.(bson.M)
+	if !ok || 0 == len(doc) {
+		return nil
+	}
+	for key := range doc {
+		if !strings.HasPrefix(key, "$") {
+			return nil
+		}
+	}
+	return doc
+}
+
+// field returns true if the field of the document matches the condiction
+func (m *matcher) field(doc bson.M, name string, v interface{}) (bool, error) {
+	values := resolve(doc, strings.Split(name, "."))
+	ops := operators(v)
+	if nil == ops {
+		return eq(values, v), nil
+	}
+	for op, arg := range ops {
+		var ok bool
+		var err error
+		switch op {
+		case "$eq":
+			ok = eq(values, arg)
+		case "$ne":
+			ok = !eq(values, arg)
+		case "$gt", "$gte", "$lt", "$lte":
+			ok = compareAny(values, op, arg)
+		case "$in":
+			ok, err = in(values, arg)
+		case "$nin":
+			ok, err = in(values, arg)
+			ok = !ok
+		case "$all":
+			ok, err = all(values, arg)
+		case "$exists":
+			exists, _ := arg.(bool)
+			ok = exists == (0 != len(values))
+		case "$regex":
+			ok, err = m.regex(values, arg, ops["$options"])
+		case "$options":
+			if _, has := ops["$regex"]; !has {
+				err = errors.New("$options needs $regex")
+			}
+			ok = true
+		case "$not":
+			ok, err = m.field(doc, name, arg)
+			ok = !ok
+		default:
+			err = fmt.Errorf("unsupported operator %s", op)
+		}
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// eq returns true if any value equals v, the missing field equals nil
+func eq(values []interface{}, v interface{}) bool {
+	if nil == v && 0 == len(values) {
+		return true
+	}
+	for _, val := range candidates(values) {
+		if rank(val) == rank(v) && 0 == compare(val, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareAny returns true if any value of the same type satisfies the comparison
+func compareAny(values []interface{}, op string, v interface{}) bool {
+	for _, val := range candidates(values) {
+		if rank(val) != rank(v) {
+			continue
+		}
+		c := compare(val, v)
+		switch op {
+		case "$gt":
+			if c > 0 {
+				return true
+			}
+		case "$gte":
+			if c >= 0 {
+				return true
+			}
+		case "$lt":
+			if c < 0 {
+				return true
+			}
+		case "$lte":
+			if c <= 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// in returns true if any value equals an item of the array
+func in(values []interface{}, v interface{}) (bool, error) {
+	items, ok := v.([]interface{})
+	if !ok {
+		return false, errors.New("$in and $nin need an array")
+	}
+	for _, item := range items {
+		if eq(values, item) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// all returns true if every value of the array equals the field or an item of it, the empty
+// array matches nothing like mongo
+func all(values []interface{}, v interface{}) (bool, error) {
+	items, ok := v.([]interface{})
+	if !ok {
+		return false, errors.New("$all needs an array")
+	}
+	if 0 == len(items) {
+		return false, nil
+	}
+	for _, item := range items {
+		if !eq(values, item) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// regex returns true if any string value matches the pattern
+func (m *matcher) regex(values []interface{}, v, options interface{}) (bool, error) {
+	pattern, ok := v.(string)
+	if !ok {
+		if re, isRegEx := v.(bson.RegEx); isRegEx {
+			pattern, options, ok = re.Pattern, re.Options, true
+		}
+	}
+	if !ok {
+		return false, errors.New("$regex needs a string")
+	}
+	if opts, _ := options.(string); strings.Contains(opts, "i") {
+		pattern = "(?i)" + pattern
+	}
+	re, ok := m.regexps[pattern]
+	if !ok {
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			return false, err
+		}
+		m.regexps[pattern] = re
+	}
+	for _, val := range candidates(values) {
+		if text, isText := val.(string); isText && re.MatchString(text) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,749 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package memclient
+
+import (
+	"configcenter/src/storage"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// errNotFound the same text as mgo.ErrNotFound, the callers compare the text
+var errNotFound = errors.New("not found")
+
+// rowIDField the field of the row identity in the conditions and the snapshots, the same as mongo
+const rowIDField = "_id"
+
+var (
+	databases     = make(map[string]*database)
+	databasesLock sync.Mutex
+)
+
+// database the collections and the sequences of a database name
+type database struct {
+	lock        sync.RWMutex
+	collections map[string]*collection
+	seqs        map[string]int64
+}
+
+// collection keeps the rows in the order of the insertion
+type collection struct {
+	rows    []*row
+	lastID  int64
+	indexes map[string]*storage.Index
+}
+
+// row a document and its row identity
+type row struct {
+	id  int64
+	doc bson.M
+}
+
+// MemCli keeps the documents in memory, the clients of the same database name share the documents
+// in a process, so that the controllers run without any external storage in the tests and the demos
+type MemCli struct {
+	dbName string
+	db     *database
+}
+
+var (
+	_ storage.ContextDI   = (*MemCli)(nil)
+	_ storage.Snapshotter = (*MemCli)(nil)
+)
+
+// NewMemCli returns the client of the in memory database of the name
+func NewMemCli(database string) (*MemCli, error) {
+	return &MemCli{dbName: database}, nil
+}
+
+// Open joins the documents of the database name
+func (m *MemCli) Open() error {
+	databasesLock.Lock()
+	defer databasesLock.Unlock()
+	db, ok := databases[m.dbName]
+	if !ok {
+		db = &database{collections: make(map[string]*collection), seqs: make(map[string]int64)}
+		databases[m.dbName] = db
+	}
+	m.db = db
+	return nil
+}
+
+// Close keeps the documents for the other clients of the database name
+func (m *MemCli) Close() {
+}
+
+// GetSession returns nil, there is not any session
+func (m *MemCli) GetSession() interface{} {
+	return nil
+}
+
+// GetType returns the storage type
+func (m *MemCli) GetType() string {
+	return storage.DI_MEMORY
+}
+
+// Drop removes all the documents of the database name
+func Drop(database string) {
+	databasesLock.Lock()
+	defer databasesLock.Unlock()
+	delete(databases, database)
+}
+
+// toDoc converts the map or the struct into the document of the bson field names, the values are
+// normalized in the same way as they are written to mongo, the time is kept in milliseconds for example
+func toDoc(v interface{}) (bson.M, error) {
+	doc := bson.M{}
+	if nil == v {
+		return doc, nil
+	}
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// decodeDocs decodes the documents into result as an array
+func decodeDocs(docs []bson.M, result interface{}) error {
+	data, err := bson.Marshal(bson.M{"docs": docs})
+	if err != nil {
+		return err
+	}
+	wrapper := struct {
+		Docs bson.Raw `bson:"docs"`
+	}{}
+	if err := bson.Unmarshal(data, &wrapper); err != nil {
+		return err
+	}
+	return wrapper.Docs.Unmarshal(result)
+}
+
+// filter returns the rows of the collection which match the condiction, the row identity is _id
+// in the condiction
+func filter(coll *collection, condiction interface{}) ([]*row, error) {
+	if nil == coll {
+		return nil, nil
+	}
+	cond, err := toDoc(condiction)
+	if err != nil {
+		return nil, err
+	}
+	m := newMatcher()
+	rows := make([]*row, 0)
+	for _, r := range coll.rows {
+		ok, err := m.match(withID(r), cond)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			rows = append(rows, r)
+		}
+	}
+	return rows, nil
+}
+
+// withID returns the shallow copy of the document of the row with _id set
+func withID(r *row) bson.M {
+	doc := make(bson.M, len(r.doc)+1)
+	for key, val := range r.doc {
+		doc[key] = val
+	}
+	doc[rowIDField] = r.id
+	return doc
+}
+
+// sortKey a key of the order
+type sortKey struct {
+	field string
+	order int
+}
+
+// sortKeys returns the keys of the comma separated sort fields, "-" is the prefix of the descending order
+func sortKeys(sort string) []sortKey {
+	keys := make([]sortKey, 0)
+	for _, field := range strings.Split(sort, ",") {
+		field = strings.TrimSpace(field)
+		order := 1
+		if strings.HasPrefix(field, "-") {
+			order = -1
+		}
+		field = strings.TrimLeft(field, "+-")
+		if "" == field {
+			continue
+		}
+		keys = append(keys, sortKey{field: field, order: order})
+	}
+	return keys
+}
+
+// sortValue returns the value of the field which the rows are sorted by
+func sortValue(r *row, field string) interface{} {
+	if rowIDField == field {
+		return r.id
+	}
+	values := resolve(r.doc, strings.Split(field, "."))
+	if 0 == len(values) {
+		return nil
+	}
+	return values[0]
+}
+
+// sortRows sorts the rows by the keys, the rows of the same values are in the order of the insertion
+func sortRows(rows []*row, keys []sortKey) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, key := range keys {
+			if c := compare(sortValue(rows[i], key.field), sortValue(rows[j], key.field)); 0 != c {
+				return c*key.order < 0
+			}
+		}
+		return false
+	})
+}
+
+// page returns the rows of the page
+func page(rows []*row, start, limit int) []*row {
+	if start >= len(rows) {
+		return rows[:0]
+	}
+	if 0 < start {
+		rows = rows[start:]
+	}
+	if 0 < limit && limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+// project returns the copy of the document of the fields, all the fields are returned if there is not any field
+func project(doc bson.M, fields []string) bson.M {
+	wanted := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if "" != field && rowIDField != field {
+			wanted = append(wanted, field)
+		}
+	}
+	if 0 == len(wanted) {
+		result := make(bson.M, len(doc))
+		for key, val := range doc {
+			result[key] = val
+		}
+		return result
+	}
+	result := bson.M{}
+	for _, field := range wanted {
+		path := strings.Split(field, ".")
+		var val interface{} = doc
+		found := true
+		for _, name := range path {
+			sub, ok := val.(bson.M)
+			if !ok {
+				found = false
+				break
+			}
+			if val, ok = sub[name]; !ok {
+				found = false
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+		setField(result, path, val)
+	}
+	return result
+}
+
+// setField sets the field of the path, the missing parents are created
+func setField(doc bson.M, path []string, val interface{}) {
+	parent := doc
+	for _, name := range path[:len(path)-1] {
+		child, ok := parent[name].(bson.M)
+		if !ok {
+			child = bson.M{}
+			parent[name] = child
+		}
+		parent = child
+	}
+	parent[path[len(path)-1]] = val
+}
+
+// removeField removes the field of the path and returns its value
+func removeField(doc bson.M, path []string) (interface{}, bool) {
+	parent := doc
+	for _, name := range path[:len(path)-1] {
+		child, ok := parent[name].(bson.M)
+		if !ok {
+			return nil, false
+		}
+		parent = child
+	}
+	val, ok := parent[path[len(path)-1]]
+	delete(parent, path[len(path)-1])
+	return val, ok
+}
+
+// copyValue returns the deep copy of the value
+func copyValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case bson.M:
+		doc := make(bson.M, len(val))
+		for key, item := range val {
+			doc[key] = copyValue(item)
+		}
+		return doc
+	case []interface{}:
+		arr := make([]interface{}, len(val))
+		for i, item := range val {
+			arr[i] = copyValue(item)
+		}
+		return arr
+	}
+	return v
+}
+
+// checkUnique returns an error if the rows break the unique indexes of the collection
+func checkUnique(coll *collection, rows []*row) error {
+	for _, index := range coll.indexes {
+		if !isUnique(index) {
+			continue
+		}
+		partial, err := toDoc(index.PartialFilter)
+		if err != nil {
+			return err
+		}
+		m := newMatcher()
+		seen := make(map[string]int64, len(rows))
+		for _, r := range rows {
+			// the documents out of the partial filter are not indexed
+			if ok, err := m.match(r.doc, partial); err != nil {
+				return err
+			} else if !ok {
+				continue
+			}
+			values := make([]interface{}, 0, len(index.Columns))
+			for _, column := range index.Columns {
+				values = append(values, sortValue(r, strings.TrimLeft(column, "+-")))
+			}
+			key := fmt.Sprintf("%#v", values)
+			if id, ok := seen[key]; ok && id != r.id {
+				return fmt.Errorf("E11000 duplicate key error index: %s dup key: %v", index.Name, values)
+			}
+			seen[key] = r.id
+		}
+	}
+	return nil
+}
+
+func isUnique(index *storage.Index) bool {
+	switch index.Type {
+	case storage.INDEX_TYPE_UNIQUE, storage.INDEX_TYPE_BACKGROUP_UNIQUE, storage.INDEX_TYPE_PRIMAEY:
+		return true
+	}
+	return false
+}
+
+// collection returns the collection of the name, it is created if create is true
+func (m *MemCli) collection(cName string, create bool) *collection {
+	coll, ok := m.db.collections[cName]
+	if !ok && create {
+		coll = &collection{indexes: make(map[string]*storage.Index)}
+		m.db.collections[cName] = coll
+	}
+	return coll
+}
+
+// Insert insert one document
+func (m *MemCli) Insert(cName string, data interface{}) (int, error) {
+	return m.InsertCtx(context.Background(), cName, data)
+}
+
+// InsertCtx insert one document
+func (m *MemCli) InsertCtx(ctx context.Context, cName string, data interface{}) (int, error) {
+	if err := m.InsertMutiCtx(ctx, cName, data); err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
+
+// InsertMuti insert muti documents
+func (m *MemCli) InsertMuti(cName string, data ...interface{}) error {
+	return m.InsertMutiCtx(context.Background(), cName, data...)
+}
+
+// InsertMutiCtx insert muti documents, none of the documents is inserted if any one fails
+func (m *MemCli) InsertMutiCtx(ctx context.Context, cName string, data ...interface{}) error {
+	_, err := m.InsertRowsCtx(ctx, cName, data...)
+	return err
+}
+
+// InsertRowsCtx inserts the documents like InsertMutiCtx and returns their row identities
+func (m *MemCli) InsertRowsCtx(ctx context.Context, cName string, data ...interface{}) ([]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	docs := make([]bson.M, 0, len(data))
+	for _, item := range data {
+		doc, err := toDoc(item)
+		if err != nil {
+			return nil, err
+		}
+		storage.EscapeHtml(doc)
+		delete(doc, rowIDField)
+		docs = append(docs, doc)
+	}
+
+	m.db.lock.Lock()
+	defer m.db.lock.Unlock()
+	coll := m.collection(cName, true)
+	m.purgeExpired(coll)
+	rows := coll.rows
+	lastID := coll.lastID
+	ids := make([]interface{}, 0, len(docs))
+	for _, doc := range docs {
+		lastID++
+		rows = append(rows, &row{id: lastID, doc: doc})
+		ids = append(ids, lastID)
+	}
+	if err := checkUnique(coll, rows); err != nil {
+		return nil, err
+	}
+	coll.rows = rows
+	coll.lastID = lastID
+	return ids, nil
+}
+
+// purgeExpired removes the documents which are expired by the ttl indexes
+func (m *MemCli) purgeExpired(coll *collection) {
+	for _, index := range coll.indexes {
+		if 0 >= index.ExpireAfter || 0 == len(index.Columns) {
+			continue
+		}
+		deadline := time.Now().Add(-index.ExpireAfter)
+		path := strings.Split(index.Columns[0], ".")
+		rows := coll.rows[:0]
+		for _, r := range coll.rows {
+			values := resolve(r.doc, path)
+			if 0 != len(values) {
+				if t, ok := values[0].(time.Time); ok && t.Before(deadline) {
+					continue
+				}
+			}
+			rows = append(rows, r)
+		}
+		coll.rows = rows
+	}
+}
+
+// UpdateByCondition update documents by condiction
+func (m *MemCli) UpdateByCondition(cName string, data, condiction interface{}) error {
+	return m.UpdateByConditionCtx(context.Background(), cName, data, condiction)
+}
+
+// UpdateByConditionCtx sets the fields of data on the documents which match the condiction
+func (m *MemCli) UpdateByConditionCtx(ctx context.Context, cName string, data, condiction interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	doc, err := toDoc(data)
+	if err != nil {
+		return err
+	}
+	storage.EscapeHtml(doc)
+	delete(doc, rowIDField)
+	_, err = m.update(cName, condiction, func(r *row) {
+		for key, val := range doc {
+			setField(r.doc, strings.Split(key, "."), copyValue(val))
+		}
+	})
+	return err
+}
+
+// update changes the documents which match the condiction by fn and returns the number of them,
+// none of the documents is changed if the changes break the unique indexes
+func (m *MemCli) update(cName string, condiction interface{}, fn func(r *row)) (int, error) {
+	m.db.lock.Lock()
+	defer m.db.lock.Unlock()
+	coll := m.collection(cName, false)
+	rows, err := filter(coll, condiction)
+	if err != nil || 0 == len(rows) {
+		return 0, err
+	}
+	changed := make(map[int64]*row, len(rows))
+	for _, r := range rows {
+		changed[r.id] = &row{id: r.id, doc: copyValue(r.doc).(bson.M)}
+		fn(changed[r.id])
+	}
+	all := make([]*row, 0, len(coll.rows))
+	for _, r := range coll.rows {
+		if c, ok := changed[r.id]; ok {
+			r = c
+		}
+		all = append(all, r)
+	}
+	if err := checkUnique(coll, all); err != nil {
+		return 0, err
+	}
+	coll.rows = all
+	return len(rows), nil
+}
+
+// GetOneByCondition get one document by condiction
+func (m *MemCli) GetOneByCondition(cName string, fields []string, condiction interface{}, result interface{}) error {
+	return m.GetOneByConditionCtx(context.Background(), cName, fields, condiction, result)
+}
+
+// GetOneByConditionCtx get one document by condiction, the error text is "not found" if there is not any
+func (m *MemCli) GetOneByConditionCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}) error {
+	docs, err := m.find(ctx, cName, fields, condiction, nil, 0, 1)
+	if err != nil {
+		return err
+	}
+	if 0 == len(docs) {
+		return errNotFound
+	}
+	data, err := bson.Marshal(docs[0])
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(data, result)
+}
+
+// GetMutilByCondition get multiple document by condiction
+func (m *MemCli) GetMutilByCondition(cName string, fields []string, condiction interface{}, result interface{}, sort string, start, limit int) error {
+	return m.GetMutilByConditionCtx(context.Background(), cName, fields, condiction, result, sort, start, limit)
+}
+
+// GetMutilByConditionCtx get multiple document by condiction, the documents of the same sort values
+// are in the order of the insertion
+func (m *MemCli) GetMutilByConditionCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}, sort string, start, limit int) error {
+	docs, err := m.find(ctx, cName, fields, condiction, sortKeys(sort), start, limit)
+	if err != nil {
+		return err
+	}
+	return decodeDocs(docs, result)
+}
+
+// find returns the projections of the documents which match the condiction
+func (m *MemCli) find(ctx context.Context, cName string, fields []string, condiction interface{}, keys []sortKey, start, limit int) ([]bson.M, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.db.lock.RLock()
+	defer m.db.lock.RUnlock()
+	rows, err := filter(m.collection(cName, false), condiction)
+	if err != nil {
+		return nil, err
+	}
+	sortRows(rows, keys)
+	rows = page(rows, start, limit)
+	docs := make([]bson.M, 0, len(rows))
+	for _, r := range rows {
+		// the documents are copied by the bson decoding
+		docs = append(docs, project(r.doc, fields))
+	}
+	return docs, nil
+}
+
+// GetCntByCondition returns count number filter by condiction
+func (m *MemCli) GetCntByCondition(cName string, condiction interface{}) (int, error) {
+	return m.GetCntByConditionCtx(context.Background(), cName, condiction)
+}
+
+// GetCntByConditionCtx returns count number filter by condiction
+func (m *MemCli) GetCntByConditionCtx(ctx context.Context, cName string, condiction interface{}) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.db.lock.RLock()
+	defer m.db.lock.RUnlock()
+	rows, err := filter(m.collection(cName, false), condiction)
+	if err != nil {
+		return 0, err
+	}
+	return len(rows), nil
+}
+
+// GetIncID returns next sequence ID for cName collection
+func (m *MemCli) GetIncID(cName string) (int64, error) {
+	return m.GetIncIDCtx(context.Background(), cName)
+}
+
+// GetIncIDCtx returns next sequence ID for cName collection
+func (m *MemCli) GetIncIDCtx(ctx context.Context, cName string) (int64, error) {
+	return m.ReserveIncIDCtx(ctx, cName, 1)
+}
+
+// ReserveIncIDCtx advances the sequence of cName collection by n and returns the last ID of the block
+func (m *MemCli) ReserveIncIDCtx(ctx context.Context, cName string, n int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.db.lock.Lock()
+	defer m.db.lock.Unlock()
+	m.db.seqs[cName] += n
+	return m.db.seqs[cName], nil
+}
+
+// DelByCondition delete the documents by condiction
+func (m *MemCli) DelByCondition(cName string, condiction interface{}) error {
+	return m.DelByConditionCtx(context.Background(), cName, condiction)
+}
+
+// DelByConditionCtx delete the documents by condiction
+func (m *MemCli) DelByConditionCtx(ctx context.Context, cName string, condiction interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.db.lock.Lock()
+	defer m.db.lock.Unlock()
+	coll := m.collection(cName, false)
+	rows, err := filter(coll, condiction)
+	if err != nil || 0 == len(rows) {
+		return err
+	}
+	removed := make(map[int64]bool, len(rows))
+	for _, r := range rows {
+		removed[r.id] = true
+	}
+	kept := make([]*row, 0, len(coll.rows)-len(rows))
+	for _, r := range coll.rows {
+		if !removed[r.id] {
+			kept = append(kept, r)
+		}
+	}
+	coll.rows = kept
+	return nil
+}
+
+// HasTable returns true if the collection exists
+func (m *MemCli) HasTable(cName string) (bool, error) {
+	m.db.lock.RLock()
+	defer m.db.lock.RUnlock()
+	return nil != m.collection(cName, false), nil
+}
+
+// CreateTable creates the collection
+func (m *MemCli) CreateTable(cName string) error {
+	m.db.lock.Lock()
+	defer m.db.lock.Unlock()
+	m.collection(cName, true)
+	return nil
+}
+
+// ExecSql is not supported
+func (m *MemCli) ExecSql(cmd interface{}) error {
+	return errors.New("not support method")
+}
+
+// Index adds the index, only the unique and the ttl indexes take effect
+func (m *MemCli) Index(cName string, index *storage.Index) error {
+	if 0 == len(index.Columns) {
+		return errors.New("the index has no column")
+	}
+	m.db.lock.Lock()
+	defer m.db.lock.Unlock()
+	coll := m.collection(cName, true)
+	name := storage.IndexName(index)
+	idx := *index
+	idx.Name = name
+	if err := checkUnique(&collection{indexes: map[string]*storage.Index{name: &idx}}, coll.rows); err != nil {
+		return err
+	}
+	coll.indexes[name] = &idx
+	return nil
+}
+
+// IndexesCtx returns the indexes of the collection
+func (m *MemCli) IndexesCtx(ctx context.Context, cName string) ([]storage.Index, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.db.lock.RLock()
+	defer m.db.lock.RUnlock()
+	indexes := make([]storage.Index, 0)
+	if coll := m.collection(cName, false); nil != coll {
+		for _, index := range coll.indexes {
+			indexes = append(indexes, *index)
+		}
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i].Name < indexes[j].Name })
+	return indexes, nil
+}
+
+// DropIndexCtx drops the index of the name
+func (m *MemCli) DropIndexCtx(ctx context.Context, cName, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.db.lock.Lock()
+	defer m.db.lock.Unlock()
+	coll := m.collection(cName, false)
+	if nil == coll || nil == coll.indexes[name] {
+		return fmt.Errorf("index not found with name [%s]", name)
+	}
+	delete(coll.indexes, name)
+	return nil
+}
+
+// DropTable drops the collection
+func (m *MemCli) DropTable(cName string) error {
+	m.db.lock.Lock()
+	defer m.db.lock.Unlock()
+	delete(m.db.collections, cName)
+	return nil
+}
+
+// HasFields returns true if any document has the field
+func (m *MemCli) HasFields(cName, field string) (bool, error) {
+	cnt, err := m.GetCntByCondition(cName, bson.M{field: bson.M{"$exists": true}})
+	if err != nil {
+		return false, err
+	}
+	return cnt > 0, nil
+}
+
+// AddColumn sets the field to the value of Ext on the documents which do not have the field
+func (m *MemCli) AddColumn(cName string, column *storage.Column) error {
+	return m.UpdateByCondition(cName, bson.M{column.Name: column.Ext}, bson.M{column.Name: bson.M{"$exists": false}})
+}
+
+// ModifyColumn renames the field
+func (m *MemCli) ModifyColumn(cName, oldName, newColumn string) error {
+	oldPath, newPath := strings.Split(oldName, "."), strings.Split(newColumn, ".")
+	_, err := m.update(cName, bson.M{oldName: bson.M{"$exists": true}}, func(r *row) {
+		if val, ok := removeField(r.doc, oldPath); ok {
+			setField(r.doc, newPath, val)
+		}
+	})
+	return err
+}
+
+// DropColumn removes the field from all the documents
+func (m *MemCli) DropColumn(cName, field string) error {
+	path := strings.Split(field, ".")
+	_, err := m.update(cName, bson.M{field: bson.M{"$exists": true}}, func(r *row) {
+		removeField(r.doc, path)
+	})
+	return err
+}
@@ -0,0 +1,338 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package memclient
+
+import (
+	"configcenter/src/storage"
+	"configcenter/src/storage/storagetest"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestConformance(t *testing.T) {
+	defer Drop("conformance")
+	storagetest.Run(t, func(t *testing.T) storage.DI {
+		db, err := NewMemCli("conformance")
+		require.NoError(t, err)
+		require.NoError(t, db.Open())
+		return db
+	})
+}
+
+func TestArrayMatch(t *testing.T) {
+	defer Drop("array")
+	db, err := NewMemCli("array")
+	require.NoError(t, err)
+	require.NoError(t, db.Open())
+
+	require.NoError(t, db.InsertMuti("hosts",
+		bson.M{"id": 1, "tags": []string{"a", "b"}, "nics": []bson.M{{"ip": "10.0.0.1"}, {"ip": "10.0.0.2"}}},
+		bson.M{"id": 2, "tags": []string{"c"}, "nics": []bson.M{{"ip": "10.0.0.3"}}},
+	))
+	cases := []struct {
+		cond interface{}
+		want int
+	}{
+		{bson.M{"tags": "b"}, 1},
+		{bson.M{"tags": []string{"a", "b"}}, 1},
+		{bson.M{"tags": bson.M{"$in": []string{"b", "c"}}}, 2},
+		{bson.M{"tags": bson.M{"$nin": []string{"b"}}}, 1},
+		{bson.M{"nics.ip": "10.0.0.2"}, 1},
+		{bson.M{"nics.ip": bson.M{"$regex": "^10\\.0\\.0\\.[23]$"}}, 2},
+	}
+	for _, c := range cases {
+		cnt, err := db.GetCntByCondition("hosts", c.cond)
+		require.NoError(t, err)
+		assert.Equal(t, c.want, cnt, "%v", c.cond)
+	}
+}
+
+func TestSharedDatabase(t *testing.T) {
+	defer Drop("shared")
+	writer, _ := NewMemCli("shared")
+	reader, _ := NewMemCli("shared")
+	require.NoError(t, writer.Open())
+	require.NoError(t, reader.Open())
+
+	_, err := writer.Insert("objects", bson.M{"bk_obj_id": "host"})
+	require.NoError(t, err)
+	cnt, err := reader.GetCntByCondition("objects", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, cnt)
+}
+
+func TestPartialUniqueIndex(t *testing.T) {
+	defer Drop("partial")
+	db, err := NewMemCli("partial")
+	require.NoError(t, err)
+	require.NoError(t, db.Open())
+
+	require.NoError(t, db.Index("inst", &storage.Index{
+		Columns:       []string{"bk_obj_id", "name"},
+		Type:          storage.INDEX_TYPE_UNIQUE,
+		PartialFilter: map[string]interface{}{"bk_obj_id": "switch", "name": map[string]interface{}{"$exists": true}},
+	}))
+	// the documents out of the filter are not indexed
+	require.NoError(t, db.InsertMuti("inst", bson.M{"bk_obj_id": "switch"}, bson.M{"bk_obj_id": "switch"}))
+	require.NoError(t, db.InsertMuti("inst", bson.M{"bk_obj_id": "router", "name": "a"}, bson.M{"bk_obj_id": "router", "name": "a"}))
+	_, err = db.Insert("inst", bson.M{"bk_obj_id": "switch", "name": "a"})
+	require.NoError(t, err)
+	_, err = db.Insert("inst", bson.M{"bk_obj_id": "switch", "name": "a"})
+	assert.Error(t, err)
+}
+
+func TestReconcileIndexes(t *testing.T) {
+	defer Drop("reconcile")
+	db, err := NewMemCli("reconcile")
+	require.NoError(t, err)
+	require.NoError(t, db.Open())
+	ctx := context.Background()
+
+	require.NoError(t, db.Index("host", &storage.Index{Columns: []string{"bk_host_id"}, Type: storage.INDEX_TYPE_BACKGROUP}))
+	require.NoError(t, db.Index("host", &storage.Index{Columns: []string{"bk_host_name"}, Type: storage.INDEX_TYPE_BACKGROUP}))
+	require.NoError(t, db.Index("host", &storage.Index{Name: "idx_ttl", Columns: []string{"create_time"}, ExpireAfter: time.Hour}))
+
+	specs := map[string][]storage.Index{
+		"host": {
+			{Columns: []string{"bk_host_id"}, Type: storage.INDEX_TYPE_BACKGROUP_UNIQUE},
+			{Columns: []string{"bk_biz_id", "-create_time"}, Type: storage.INDEX_TYPE_BACKGROUP},
+			{Name: "idx_ttl", Columns: []string{"create_time"}, ExpireAfter: time.Hour},
+		},
+	}
+	actions := func(drifts []storage.IndexDrift) map[string]string {
+		result := map[string]string{}
+		for _, drift := range drifts {
+			assert.Empty(t, drift.Error)
+			result[drift.Name] = drift.Action
+		}
+		return result
+	}
+
+	drifts, err := storage.ReconcileIndexes(ctx, db, specs, storage.ReconcileOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"bk_host_id_1":               storage.IndexDriftRecreate,
+		"bk_biz_id_1_create_time_-1": storage.IndexDriftCreate,
+		"bk_host_name_1":             storage.IndexDriftExtra,
+	}, actions(drifts))
+	for _, drift := range drifts {
+		assert.False(t, drift.Applied)
+	}
+
+	drifts, err = storage.ReconcileIndexes(ctx, db, specs, storage.ReconcileOptions{DropExtra: true})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"bk_host_id_1":               storage.IndexDriftRecreate,
+		"bk_biz_id_1_create_time_-1": storage.IndexDriftCreate,
+		"bk_host_name_1":             storage.IndexDriftDrop,
+	}, actions(drifts))
+	for _, drift := range drifts {
+		assert.True(t, drift.Applied)
+	}
+
+	live, err := db.IndexesCtx(ctx, "host")
+	require.NoError(t, err)
+	names := make([]string, 0)
+	for _, index := range live {
+		names = append(names, index.Name)
+	}
+	assert.Equal(t, []string{"bk_biz_id_1_create_time_-1", "bk_host_id_1", "idx_ttl"}, names)
+
+	// nothing drifts once reconciled
+	drifts, err = storage.ReconcileIndexes(ctx, db, specs, storage.ReconcileOptions{DropExtra: true})
+	require.NoError(t, err)
+	assert.Empty(t, drifts)
+}
+
+func TestReconcilePruneScope(t *testing.T) {
+	defer Drop("reconcile_prune")
+	db, err := NewMemCli("reconcile_prune")
+	require.NoError(t, err)
+	require.NoError(t, db.Open())
+	ctx := context.Background()
+
+	require.NoError(t, db.Index("inst", &storage.Index{Columns: []string{"bk_obj_id"}, Type: storage.INDEX_TYPE_BACKGROUP}))
+	require.NoError(t, db.Index("inst", &storage.Index{Name: "bk_isonly_switch_name", Columns: []string{"name"}, Type: storage.INDEX_TYPE_BACKGROUP_UNIQUE}))
+	require.NoError(t, db.Index("inst", &storage.Index{Name: "bk_isonly_router", Columns: []string{"name"}, Type: storage.INDEX_TYPE_BACKGROUP_UNIQUE}))
+
+	prune := func(name string) bool { return strings.HasPrefix(name, "bk_isonly_") }
+	specs := map[string][]storage.Index{
+		"inst": {
+			{Name: "bk_isonly_switch", Columns: []string{"name", "sn"}, Type: storage.INDEX_TYPE_BACKGROUP_UNIQUE},
+		},
+	}
+
+	// the scope leaves the other indexes as they are
+	scope := func(name string) bool { return name == "bk_isonly_switch" || name == "bk_isonly_switch_name" }
+	drifts, err := storage.ReconcileIndexes(ctx, db, specs, storage.ReconcileOptions{Prune: prune, Scope: scope})
+	require.NoError(t, err)
+	result := map[string]string{}
+	for _, drift := range drifts {
+		assert.Empty(t, drift.Error)
+		result[drift.Name] = drift.Action
+	}
+	assert.Equal(t, map[string]string{
+		"bk_isonly_switch":      storage.IndexDriftCreate,
+		"bk_isonly_switch_name": storage.IndexDriftDrop,
+	}, result)
+
+	// the pruned indexes are dropped without DropExtra, the others are reported
+	drifts, err = storage.ReconcileIndexes(ctx, db, specs, storage.ReconcileOptions{Prune: prune})
+	require.NoError(t, err)
+	result = map[string]string{}
+	for _, drift := range drifts {
+		result[drift.Name] = drift.Action
+	}
+	assert.Equal(t, map[string]string{
+		"bk_obj_id_1":      storage.IndexDriftExtra,
+		"bk_isonly_router": storage.IndexDriftDrop,
+	}, result)
+
+	live, err := db.IndexesCtx(ctx, "inst")
+	require.NoError(t, err)
+	names := make([]string, 0)
+	for _, index := range live {
+		names = append(names, index.Name)
+	}
+	assert.Equal(t, []string{"bk_isonly_switch", "bk_obj_id_1"}, names)
+}
+
+func TestReconcileRecreateFailure(t *testing.T) {
+	defer Drop("reconcile_recreate")
+	db, err := NewMemCli("reconcile_recreate")
+	require.NoError(t, err)
+	require.NoError(t, db.Open())
+	ctx := context.Background()
+
+	require.NoError(t, db.Index("host", &storage.Index{Columns: []string{"bk_host_name"}, Type: storage.INDEX_TYPE_BACKGROUP}))
+	for i := 0; i < 2; i++ {
+		_, err = db.Insert("host", bson.M{"bk_host_name": "a"})
+		require.NoError(t, err)
+	}
+	specs := map[string][]storage.Index{
+		"host": {{Columns: []string{"bk_host_name"}, Type: storage.INDEX_TYPE_BACKGROUP_UNIQUE}},
+	}
+
+	// the unique index over the duplicated names fails to build and the live one is kept
+	drifts, err := storage.ReconcileIndexes(ctx, db, specs, storage.ReconcileOptions{})
+	require.NoError(t, err)
+	require.Len(t, drifts, 1)
+	assert.Equal(t, storage.IndexDriftRecreate, drifts[0].Action)
+	assert.False(t, drifts[0].Applied)
+	assert.NotEmpty(t, drifts[0].Error)
+	live, err := db.IndexesCtx(ctx, "host")
+	require.NoError(t, err)
+	require.Len(t, live, 1)
+	assert.Equal(t, "bk_host_name_1", live[0].Name)
+	assert.False(t, storage.IsUniqueIndex(&live[0]))
+
+	// it is recreated once the names are unique, the temporary index is dropped
+	_, err = db.Insert("host", bson.M{"bk_host_name": "b"})
+	require.NoError(t, err)
+	require.NoError(t, db.DelByCondition("host", bson.M{"bk_host_name": "a"}))
+	drifts, err = storage.ReconcileIndexes(ctx, db, specs, storage.ReconcileOptions{})
+	require.NoError(t, err)
+	require.Len(t, drifts, 1)
+	assert.True(t, drifts[0].Applied)
+	assert.Empty(t, drifts[0].Error)
+	live, err = db.IndexesCtx(ctx, "host")
+	require.NoError(t, err)
+	require.Len(t, live, 1)
+	assert.Equal(t, "bk_host_name_1", live[0].Name)
+	assert.True(t, storage.IsUniqueIndex(&live[0]))
+}
+
+func TestTxRouter(t *testing.T) {
+	defer Drop("tx_router")
+	db, err := NewMemCli("tx_router")
+	require.NoError(t, err)
+	require.NoError(t, db.Open())
+	other, err := NewMemCli("tx_router")
+	require.NoError(t, err)
+	require.NoError(t, other.Open())
+	ctx := context.Background()
+
+	tx, err := storage.StartJournalTx(ctx, db)
+	require.NoError(t, err)
+	txCtx := storage.WithTx(ctx, db, tx)
+	assert.Equal(t, tx, storage.TxOf(txCtx, db))
+	assert.Nil(t, storage.TxOf(txCtx, other), "the transaction is bound to its own storage")
+	assert.Nil(t, storage.TxOf(ctx, db))
+
+	// the calls with the bound context join the transaction, the others do not
+	_, err = storage.ContextOf(db).InsertCtx(txCtx, "host", bson.M{"bk_host_id": 1})
+	require.NoError(t, err)
+	_, err = storage.ContextOf(db).InsertCtx(ctx, "host", bson.M{"bk_host_id": 2})
+	require.NoError(t, err)
+	_, err = storage.ContextOf(other).InsertCtx(txCtx, "host", bson.M{"bk_host_id": 3})
+	require.NoError(t, err)
+	require.NoError(t, tx.Abort(ctx))
+
+	result := make([]bson.M, 0)
+	require.NoError(t, db.GetMutilByCondition("host", []string{"bk_host_id"}, nil, &result, "bk_host_id", 0, 0))
+	assert.Equal(t, []bson.M{{"bk_host_id": 2}, {"bk_host_id": 3}}, result)
+}
+
+func TestSlowLogConformance(t *testing.T) {
+	defer Drop("slowlog_conformance")
+	storagetest.Run(t, func(t *testing.T) storage.DI {
+		db, err := NewMemCli("slowlog_conformance")
+		require.NoError(t, err)
+		require.NoError(t, db.Open())
+		return storage.NewSlowLogDI(db, storage.SlowLogOptions{Threshold: time.Hour})
+	})
+}
+
+func TestSlowLog(t *testing.T) {
+	defer Drop("slowlog")
+	mem, err := NewMemCli("slowlog")
+	require.NoError(t, err)
+	require.NoError(t, mem.Open())
+	db := storage.NewSlowLogDI(mem, storage.SlowLogOptions{Size: 2})
+	assert.Equal(t, mem, storage.Unwrap(db))
+	assert.Nil(t, storage.SlowLogOf(mem))
+
+	_, err = db.Insert("host", map[string]interface{}{"bk_host_id": 1, "bk_host_innerip": "127.0.0.1"})
+	require.NoError(t, err)
+	result := make([]map[string]interface{}, 0)
+	condiction := map[string]interface{}{
+		"bk_host_innerip": "127.0.0.1",
+		"bk_host_id":      map[string]interface{}{"$in": []int{1, 2}},
+		"$or":             []interface{}{map[string]interface{}{"bk_os_type": "1"}},
+	}
+	require.NoError(t, db.GetMutilByCondition("host", nil, condiction, &result, "bk_host_id", 0, 10))
+	cnt, err := db.GetCntByCondition("host", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, cnt)
+
+	// the oldest call is dropped out of the size
+	recent := storage.SlowLogOf(db).Recent()
+	require.Len(t, recent, 2)
+	assert.Equal(t, "GetCntByCondition", recent[0].Operation)
+	search := recent[1]
+	assert.Equal(t, "GetMutilByCondition", search.Operation)
+	assert.Equal(t, "host", search.Collection)
+	assert.Equal(t, "bk_host_id", search.Sort)
+	assert.NotEmpty(t, search.Caller)
+	assert.Equal(t, map[string]interface{}{
+		"bk_host_innerip": "?",
+		"bk_host_id":      map[string]interface{}{"$in": "[? x 2]"},
+		"$or":             []interface{}{map[string]interface{}{"bk_os_type": "?"}},
+	}, search.Condition)
+}
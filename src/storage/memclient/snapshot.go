@@ -0,0 +1,127 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package memclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// SnapshotCtx returns the copies of the documents which match the condiction, _id is the row identity
+func (m *MemCli) SnapshotCtx(ctx context.Context, cName string, condiction interface{}) ([]map[string]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.db.lock.RLock()
+	defer m.db.lock.RUnlock()
+	rows, err := filter(m.collection(cName, false), condiction)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]map[string]interface{}, 0, len(rows))
+	for _, r := range rows {
+		docs = append(docs, copyValue(withID(r)).(bson.M))
+	}
+	return docs, nil
+}
+
+// RowID returns the _id of the document
+func (m *MemCli) RowID(doc map[string]interface{}) interface{} {
+	return doc[rowIDField]
+}
+
+// ClaimCtx sets data on the document which matches condiction under the lock of the database
+func (m *MemCli) ClaimCtx(ctx context.Context, cName string, condiction, data interface{}) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	doc, err := toDoc(data)
+	if err != nil {
+		return false, err
+	}
+	delete(doc, rowIDField)
+	cnt, err := m.update(cName, condiction, func(r *row) {
+		for key, val := range doc {
+			setField(r.doc, strings.Split(key, "."), copyValue(val))
+		}
+	})
+	return 0 < cnt, err
+}
+
+// RestoreCtx removes the rows of ids and puts the documents back with their row identities
+func (m *MemCli) RestoreCtx(ctx context.Context, cName string, ids []interface{}, docs []map[string]interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	restored := make([]*row, 0, len(docs))
+	for _, item := range docs {
+		doc, err := toDoc(item)
+		if err != nil {
+			return err
+		}
+		id, err := rowID(doc[rowIDField])
+		if err != nil {
+			return err
+		}
+		delete(doc, rowIDField)
+		restored = append(restored, &row{id: id, doc: doc})
+	}
+	removed := make(map[int64]bool, len(ids))
+	for _, item := range ids {
+		id, err := rowID(item)
+		if err != nil {
+			return err
+		}
+		removed[id] = true
+	}
+
+	m.db.lock.Lock()
+	defer m.db.lock.Unlock()
+	coll := m.collection(cName, true)
+	rows := make([]*row, 0, len(coll.rows)+len(restored))
+	for _, r := range coll.rows {
+		if !removed[r.id] {
+			rows = append(rows, r)
+		}
+	}
+	// the rows are kept in the order of the row identities which is the order of the insertion
+	for _, r := range restored {
+		i := len(rows)
+		for 0 < i && rows[i-1].id > r.id {
+			i--
+		}
+		rows = append(rows, nil)
+		copy(rows[i+1:], rows[i:])
+		rows[i] = r
+	}
+	coll.rows = rows
+	return nil
+}
+
+// rowID returns the row identity of the value
+func rowID(v interface{}) (int64, error) {
+	switch val := v.(type) {
+	case int:
+		return int64(val), nil
+	case int32:
+		return int64(val), nil
+	case int64:
+		return val, nil
+	case float64:
+		return int64(val), nil
+	}
+	return 0, fmt.Errorf("invalid row id %v", v)
+}
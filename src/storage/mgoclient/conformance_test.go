@@ -0,0 +1,36 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgoclient
+
+import (
+	"configcenter/src/storage"
+	"configcenter/src/storage/storagetest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestConformance runs the driver conformance suite on the mongo of CC_TEST_MONGO_ADDR, the database is cmdb_test
+func TestConformance(t *testing.T) {
+	addr := os.Getenv("CC_TEST_MONGO_ADDR")
+	if "" == addr {
+		t.Skip("CC_TEST_MONGO_ADDR is not set")
+	}
+	storagetest.Run(t, func(t *testing.T) storage.DI {
+		db, err := NewMgoCli(addr, "", os.Getenv("CC_TEST_MONGO_USR"), os.Getenv("CC_TEST_MONGO_PWD"), "", "cmdb_test")
+		require.NoError(t, err)
+		require.NoError(t, db.Open())
+		return db
+	})
+}
@@ -0,0 +1,262 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgoclient
+
+import (
+	"configcenter/src/storage"
+	"context"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// sortKey a key of the keyset order
+type sortKey struct {
+	field string
+	order int
+}
+
+// cursorToken the content of a cursor, the sort values of the last document of a page
+type cursorToken struct {
+	Sort   string        `bson:"s"`
+	Values []interface{} `bson:"v"`
+}
+
+// GetMutilByCursor get multiple document after the cursor
+func (m *MgoCli) GetMutilByCursor(cName string, fields []string, condiction interface{}, result interface{}, sort, cursor string, limit int) (string, error) {
+	return m.GetMutilByCursorCtx(context.Background(), cName, fields, condiction, result, sort, cursor, limit)
+}
+
+// GetMutilByCursorCtx get multiple document after the cursor, the documents are ordered by sort and then _id,
+// the documents missing the fields of sort are taken as null which is before any other value
+func (m *MgoCli) GetMutilByCursorCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}, sort, cursor string, limit int) (string, error) {
+	keys := cursorKeys(sort)
+	cond, err := cursorCondition(condiction, keys, cursor)
+	if err != nil {
+		return "", err
+	}
+	docs := make([]bson.M, 0)
+	err = m.read(ctx, func(db *mgo.Database, maxTime time.Duration) error {
+		query := db.C(cName).Find(cond).Sort(sortFields(keys)...)
+		if projection := cursorProjection(fields, keys); nil != projection {
+			query = query.Select(projection)
+		}
+		if 0 < limit {
+			query = query.Limit(limit)
+		}
+		if 0 < maxTime {
+			query = query.SetMaxTime(maxTime)
+		}
+		return query.All(&docs)
+	})
+	if err != nil {
+		return "", err
+	}
+	return cursorResult(docs, fields, keys, limit, result)
+}
+
+// cursorKeys returns the keyset order of sort, _id is appended to break the ties
+func cursorKeys(sort string) []sortKey {
+	keys := make([]sortKey, 0)
+	for _, field := range strings.Split(sort, ",") {
+		field = strings.TrimSpace(field)
+		order := 1
+		if strings.HasPrefix(field, "-") {
+			order = -1
+		}
+		field = strings.TrimLeft(field, "+-")
+		if "" == field {
+			continue
+		}
+		keys = append(keys, sortKey{field: field, order: order})
+		if "_id" == field {
+			// _id is unique, the keys after it never take effect
+			return keys
+		}
+	}
+	return append(keys, sortKey{field: "_id", order: 1})
+}
+
+// sortFields returns the sort fields of Query.Sort
+func sortFields(keys []sortKey) []string {
+	fields := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key.order < 0 {
+			fields = append(fields, "-"+key.field)
+		} else {
+			fields = append(fields, key.field)
+		}
+	}
+	return fields
+}
+
+// sortDoc returns the sort document of the find command
+func sortDoc(keys []sortKey) bson.D {
+	doc := bson.D{}
+	for _, key := range keys {
+		doc = append(doc, bson.DocElem{Name: key.field, Value: key.order})
+	}
+	return doc
+}
+
+// cursorFields returns nil if all the fields are wanted
+func cursorFields(fields []string) []string {
+	wanted := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if "" != field {
+			wanted = append(wanted, field)
+		}
+	}
+	if 0 == len(wanted) {
+		return nil
+	}
+	return wanted
+}
+
+// cursorProjection returns the projection of the fields, the sort keys are included to make the next cursor
+func cursorProjection(fields []string, keys []sortKey) bson.M {
+	fields = cursorFields(fields)
+	if nil == fields {
+		return nil
+	}
+	projection := bson.M{}
+	for _, field := range fields {
+		projection[field] = 1
+	}
+	for _, key := range keys {
+		projection[key.field] = 1
+	}
+	return projection
+}
+
+// cursorCondition returns the condiction of the documents after the cursor
+func cursorCondition(condiction interface{}, keys []sortKey, cursor string) (interface{}, error) {
+	if nil == condiction {
+		condiction = bson.M{}
+	}
+	if "" == cursor {
+		return condiction, nil
+	}
+	values, err := decodeCursor(keys, cursor)
+	if err != nil {
+		return nil, err
+	}
+	// (k1 > v1) or (k1 = v1 and k2 > v2) or ..., the missing or null value is the least the same as mongo
+	after := make([]interface{}, 0, len(keys))
+	for i, key := range keys {
+		branch := bson.M{}
+		for j := 0; j < i; j++ {
+			branch[keys[j].field] = values[j]
+		}
+		switch {
+		case key.order > 0 && nil == values[i]:
+			branch[key.field] = bson.M{"$ne": nil}
+		case key.order > 0:
+			branch[key.field] = bson.M{"$gt": values[i]}
+		case nil == values[i]:
+			// nothing is after the null in the descending order
+			continue
+		default:
+			branch["$or"] = []interface{}{bson.M{key.field: bson.M{"$lt": values[i]}}, bson.M{key.field: nil}}
+		}
+		after = append(after, branch)
+	}
+	return bson.M{"$and": []interface{}{condiction, bson.M{"$or": after}}}, nil
+}
+
+// cursorResult decodes the documents of a page into result and returns the cursor of the next page
+func cursorResult(docs []bson.M, fields []string, keys []sortKey, limit int, result interface{}) (string, error) {
+	next := ""
+	if 0 < limit && len(docs) == limit {
+		var err error
+		if next, err = encodeCursor(keys, docs[len(docs)-1]); err != nil {
+			return "", err
+		}
+	}
+
+	var wanted map[string]bool
+	if fields = cursorFields(fields); nil != fields {
+		wanted = make(map[string]bool)
+		for _, field := range fields {
+			wanted[strings.SplitN(field, ".", 2)[0]] = true
+		}
+	}
+	for _, doc := range docs {
+		// the same as GetMutilByCondition, _id is never returned
+		delete(doc, "_id")
+		for key := range doc {
+			if nil != wanted && !wanted[key] {
+				delete(doc, key)
+			}
+		}
+	}
+	return next, decodeDocs(docs, result)
+}
+
+// decodeDocs decodes the documents into result as an array
+func decodeDocs(docs interface{}, result interface{}) error {
+	data, err := bson.Marshal(bson.M{"docs": docs})
+	if err != nil {
+		return err
+	}
+	wrapper := struct {
+		Docs bson.Raw `bson:"docs"`
+	}{}
+	if err := bson.Unmarshal(data, &wrapper); err != nil {
+		return err
+	}
+	return wrapper.Docs.Unmarshal(result)
+}
+
+func encodeCursor(keys []sortKey, doc bson.M) (string, error) {
+	token := cursorToken{Sort: strings.Join(sortFields(keys), ","), Values: make([]interface{}, 0, len(keys))}
+	for _, key := range keys {
+		token.Values = append(token.Values, lookupField(doc, key.field))
+	}
+	data, err := bson.Marshal(&token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(keys []sortKey, cursor string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, storage.ErrInvalidCursor
+	}
+	token := cursorToken{}
+	if err := bson.Unmarshal(data, &token); err != nil {
+		return nil, storage.ErrInvalidCursor
+	}
+	if token.Sort != strings.Join(sortFields(keys), ",") || len(token.Values) != len(keys) {
+		return nil, storage.ErrInvalidCursor
+	}
+	return token.Values, nil
+}
+
+// lookupField returns the value of the dotted field
+func lookupField(doc bson.M, field string) interface{} {
+	var val interface{} = doc
+	for _, name := range strings.Split(field, ".") {
+		sub, ok := val.(bson.M)
+		if !ok {
+			return nil
+		}
+		val = sub[name]
+	}
+	return val
+}
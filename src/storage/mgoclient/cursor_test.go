@@ -0,0 +1,128 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgoclient
+
+import (
+	"configcenter/src/storage"
+	"reflect"
+	"testing"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestCursorKeys(t *testing.T) {
+	keys := cursorKeys("-bk_host_id, bk_host_name")
+	expect := []sortKey{{"bk_host_id", -1}, {"bk_host_name", 1}, {"_id", 1}}
+	if !reflect.DeepEqual(keys, expect) {
+		t.Fatalf("want %v, got %v", expect, keys)
+	}
+
+	keys = cursorKeys("")
+	if !reflect.DeepEqual(keys, []sortKey{{"_id", 1}}) {
+		t.Fatalf("want only _id, got %v", keys)
+	}
+}
+
+func TestCursorCondition(t *testing.T) {
+	keys := cursorKeys("-bk_host_id")
+	cursor, err := encodeCursor(keys, bson.M{"bk_host_id": 10, "_id": "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cond, err := cursorCondition(bson.M{"bk_cloud_id": 0}, keys, cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := bson.M{"$and": []interface{}{
+		bson.M{"bk_cloud_id": 0},
+		bson.M{"$or": []interface{}{
+			bson.M{"$or": []interface{}{bson.M{"bk_host_id": bson.M{"$lt": 10}}, bson.M{"bk_host_id": nil}}},
+			bson.M{"bk_host_id": 10, "_id": bson.M{"$gt": "a"}},
+		}},
+	}}
+	if !reflect.DeepEqual(cond, expect) {
+		t.Fatalf("want %v, got %v", expect, cond)
+	}
+
+	// the missing value is the least
+	cursor, err = encodeCursor(keys, bson.M{"_id": "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cond, err = cursorCondition(nil, keys, cursor); err != nil {
+		t.Fatal(err)
+	}
+	expect = bson.M{"$and": []interface{}{
+		bson.M{},
+		bson.M{"$or": []interface{}{bson.M{"bk_host_id": nil, "_id": bson.M{"$gt": "b"}}}},
+	}}
+	if !reflect.DeepEqual(cond, expect) {
+		t.Fatalf("want %v, got %v", expect, cond)
+	}
+	keys = cursorKeys("bk_host_id")
+	if cursor, err = encodeCursor(keys, bson.M{"_id": "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if cond, err = cursorCondition(nil, keys, cursor); err != nil {
+		t.Fatal(err)
+	}
+	expect = bson.M{"$and": []interface{}{
+		bson.M{},
+		bson.M{"$or": []interface{}{
+			bson.M{"bk_host_id": bson.M{"$ne": nil}},
+			bson.M{"bk_host_id": nil, "_id": bson.M{"$gt": "b"}},
+		}},
+	}}
+	if !reflect.DeepEqual(cond, expect) {
+		t.Fatalf("want %v, got %v", expect, cond)
+	}
+
+	// the cursor of another sort is rejected
+	if _, err := cursorCondition(nil, cursorKeys("bk_cpu"), cursor); err != storage.ErrInvalidCursor {
+		t.Fatalf("want invalid cursor, got %v", err)
+	}
+	if _, err := cursorCondition(nil, keys, "not a cursor"); err != storage.ErrInvalidCursor {
+		t.Fatalf("want invalid cursor, got %v", err)
+	}
+}
+
+func TestCursorResult(t *testing.T) {
+	keys := cursorKeys("bk_host_id")
+	docs := []bson.M{
+		{"_id": "a", "bk_host_id": 1, "bk_host_name": "a", "bk_os_type": "1"},
+		{"_id": "b", "bk_host_id": 2, "bk_host_name": "b", "bk_os_type": "1"},
+	}
+	result := make([]map[string]interface{}, 0)
+	next, err := cursorResult(docs, []string{"bk_host_name"}, keys, 2, &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []map[string]interface{}{{"bk_host_name": "a"}, {"bk_host_name": "b"}}
+	if !reflect.DeepEqual(result, expect) {
+		t.Fatalf("want %v, got %v", expect, result)
+	}
+	values, err := decodeCursor(keys, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, []interface{}{2, "b"}) {
+		t.Fatalf("the cursor should point to the last document, got %v", values)
+	}
+
+	// the last page has no next cursor
+	next, err = cursorResult([]bson.M{{"_id": "c", "bk_host_id": 3}}, nil, keys, 2, &result)
+	if err != nil || "" != next {
+		t.Fatalf("want no next cursor, got %q, %v", next, err)
+	}
+}
@@ -0,0 +1,38 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgoclient
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// ExplainCtx returns the query plan of the condiction sorted by sort
+func (m *MgoCli) ExplainCtx(ctx context.Context, cName string, condiction interface{}, sort string) (map[string]interface{}, error) {
+	plan := bson.M{}
+	err := m.read(ctx, func(db *mgo.Database, maxTime time.Duration) error {
+		query := db.C(cName).Find(condiction)
+		if "" != sort {
+			query = query.Sort(strings.Split(sort, ",")...)
+		}
+		return query.Explain(&plan)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
@@ -0,0 +1,115 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgoclient
+
+import (
+	"configcenter/src/storage"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// codeNamespaceNotFound the error code of listIndexes on the missing collection
+const codeNamespaceNotFound = 26
+
+// liveIndex the index document returned by listIndexes
+type liveIndex struct {
+	Name               string `bson:"name"`
+	Key                bson.D `bson:"key"`
+	Unique             bool   `bson:"unique"`
+	ExpireAfterSeconds *int64 `bson:"expireAfterSeconds"`
+	PartialFilter      bson.M `bson:"partialFilterExpression"`
+}
+
+// createPartialIndex creates the index by the createIndexes command, mgo.Index knows nothing about
+// partialFilterExpression
+func (m *MgoCli) createPartialIndex(tableName string, index *storage.Index, unique, background bool) error {
+	key := bson.D{}
+	for _, column := range index.Columns {
+		if strings.HasPrefix(column, "-") {
+			key = append(key, bson.DocElem{Name: strings.TrimPrefix(column, "-"), Value: -1})
+		} else {
+			key = append(key, bson.DocElem{Name: strings.TrimPrefix(column, "+"), Value: 1})
+		}
+	}
+	spec := bson.M{
+		"name":                    storage.IndexName(index),
+		"key":                     key,
+		"unique":                  unique,
+		"background":              background,
+		"partialFilterExpression": index.PartialFilter,
+	}
+	if 0 < index.ExpireAfter {
+		spec["expireAfterSeconds"] = int64(index.ExpireAfter / time.Second)
+	}
+	cmd := bson.D{{Name: "createIndexes", Value: tableName}, {Name: "indexes", Value: []bson.M{spec}}}
+	return m.run(context.Background(), func(db *mgo.Database, maxTime time.Duration) error {
+		return db.Run(cmd, nil)
+	})
+}
+
+// IndexesCtx returns the indexes of the collection except _id_
+func (m *MgoCli) IndexesCtx(ctx context.Context, cName string) ([]storage.Index, error) {
+	result := struct {
+		Cursor struct {
+			FirstBatch []liveIndex `bson:"firstBatch"`
+		} `bson:"cursor"`
+	}{}
+	err := m.run(ctx, func(db *mgo.Database, maxTime time.Duration) error {
+		return db.Run(bson.D{{Name: "listIndexes", Value: cName}}, &result)
+	})
+	if qerr, ok := err.(*mgo.QueryError); ok && codeNamespaceNotFound == qerr.Code {
+		return []storage.Index{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := make([]storage.Index, 0, len(result.Cursor.FirstBatch))
+	for _, live := range result.Cursor.FirstBatch {
+		if "_id_" == live.Name {
+			continue
+		}
+		index := storage.Index{Name: live.Name, Type: storage.INDEX_TYPE_GENERAL, PartialFilter: live.PartialFilter}
+		if live.Unique {
+			index.Type = storage.INDEX_TYPE_UNIQUE
+		}
+		if nil != live.ExpireAfterSeconds {
+			index.ExpireAfter = time.Duration(*live.ExpireAfterSeconds) * time.Second
+		}
+		for _, elem := range live.Key {
+			switch fmt.Sprint(elem.Value) {
+			case "-1":
+				index.Columns = append(index.Columns, "-"+elem.Name)
+			case "1":
+				index.Columns = append(index.Columns, elem.Name)
+			default:
+				// the special indexes keep their kind in the column, $text:name for example
+				index.Columns = append(index.Columns, fmt.Sprintf("$%v:%s", elem.Value, elem.Name))
+			}
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, nil
+}
+
+// DropIndexCtx drops the index of the name
+func (m *MgoCli) DropIndexCtx(ctx context.Context, cName, name string) error {
+	return m.run(ctx, func(db *mgo.Database, maxTime time.Duration) error {
+		return db.C(cName).DropIndexName(name)
+	})
+}
@@ -15,12 +15,15 @@ package mgoclient
 import (
 	"configcenter/src/common/blog"
 	"configcenter/src/storage"
+	"context"
 	"errors"
 	"fmt"
 
 	// "log"
 	// "os"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"gopkg.in/mgo.v2"
@@ -35,8 +38,19 @@ type MgoCli struct {
 	dbName    string
 	mechanism string
 	session   *mgo.Session
+	opts      Options
+
+	txLock      sync.Mutex
+	txChecked   bool
+	txSupported bool
 }
 
+var (
+	_ storage.ContextDI   = (*MgoCli)(nil)
+	_ storage.TxDI        = (*MgoCli)(nil)
+	_ storage.Snapshotter = (*MgoCli)(nil)
+)
+
 func NewMgoCli(host, port, usr, pwd, mechanism, database string) (*MgoCli, error) {
 	mgocli := new(MgoCli)
 	mgocli.host = host
@@ -45,6 +59,7 @@ func NewMgoCli(host, port, usr, pwd, mechanism, database string) (*MgoCli, error
 	mgocli.pwd = pwd
 	mgocli.dbName = database
 	mgocli.mechanism = mechanism
+	mgocli.opts = DefaultOptions()
 	return mgocli, nil
 }
 
@@ -53,22 +68,29 @@ func (m *MgoCli) Open() error {
 	// mgo.SetDebug(true)
 	// mgo.SetLogger(log.New(os.Stderr, "", log.LstdFlags))
 
+	// host is the comma separated members of the replica set
 	dialInfo := &mgo.DialInfo{
-		Addrs:     []string{m.host},
-		Direct:    false,
-		Timeout:   time.Second * 5,
-		Database:  m.dbName,
-		Source:    "",
-		Username:  m.usr,
-		Password:  m.pwd,
-		PoolLimit: 4096,
-		Mechanism: m.mechanism,
+		Addrs:          strings.Split(m.host, ","),
+		Direct:         false,
+		Timeout:        m.opts.ConnectTimeout,
+		Database:       m.dbName,
+		Source:         "",
+		Username:       m.usr,
+		Password:       m.pwd,
+		PoolLimit:      m.opts.PoolLimit,
+		Mechanism:      m.mechanism,
+		ReplicaSetName: m.opts.ReplicaSet,
 	}
 	session, err := mgo.DialWithInfo(dialInfo)
 	m.session = session
 	if err != nil {
 		return err
 	}
+	// the session is kept on the primary for the writes, the reads choose the members by themselves
+	session.SetMode(mgo.Primary, true)
+	if 0 < m.opts.SocketTimeout {
+		session.SetSocketTimeout(m.opts.SocketTimeout)
+	}
 	return nil
 }
 
@@ -84,12 +106,50 @@ func (m *MgoCli) Close() {
 	}
 }
 
+// run runs the write fn on a copy of the session, the remaining time of ctx is used as the socket timeout
+// of the copy and the max server time of the queries, fn is not started once ctx is done but never given up
+// after it starts, so the write error is always the one of the server
+func (m *MgoCli) run(ctx context.Context, fn func(db *mgo.Database, maxTime time.Duration) error) error {
+	return m.runMode(ctx, mgo.Primary, storage.RunWrite, fn)
+}
+
+// runMode runs fn in the same way as run on the members of the mode, wait decides whether fn is given up
+func (m *MgoCli) runMode(ctx context.Context, mode mgo.Mode, wait func(context.Context, func() error) error,
+	fn func(db *mgo.Database, maxTime time.Duration) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var maxTime time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		if maxTime = time.Until(deadline); maxTime <= 0 {
+			return context.DeadlineExceeded
+		}
+	}
+	session := m.session.Copy()
+	if mgo.Primary != mode {
+		session.SetMode(mode, true)
+	}
+	if maxTime > 0 {
+		session.SetSocketTimeout(maxTime)
+	}
+	return wait(ctx, func() error {
+		// the copy is released after fn returns even if ctx is done
+		defer session.Close()
+		return fn(session.DB(m.dbName), maxTime)
+	})
+}
+
 // Insert insert one document
 func (m *MgoCli) Insert(cName string, data interface{}) (int, error) {
-	m.session.Refresh()
-	c := m.session.DB(m.dbName).C(cName)
+	return m.InsertCtx(context.Background(), cName, data)
+}
+
+// InsertCtx insert one document
+func (m *MgoCli) InsertCtx(ctx context.Context, cName string, data interface{}) (int, error) {
 	EscapeHtml(data)
-	err := c.Insert(data)
+	err := m.run(ctx, func(db *mgo.Database, maxTime time.Duration) error {
+		return db.C(cName).Insert(data)
+	})
 	if err != nil {
 		return 0, err
 	}
@@ -98,33 +158,39 @@ func (m *MgoCli) Insert(cName string, data interface{}) (int, error) {
 
 // InsertMuti insert muti documents
 func (m *MgoCli) InsertMuti(cName string, data ...interface{}) error {
-	m.session.Refresh()
-	c := m.session.DB(m.dbName).C(cName)
+	return m.InsertMutiCtx(context.Background(), cName, data...)
+}
+
+// InsertMutiCtx insert muti documents
+func (m *MgoCli) InsertMutiCtx(ctx context.Context, cName string, data ...interface{}) error {
 	EscapeHtml(data...)
-	err := c.Insert(data...)
-	if err != nil {
-		return err
-	}
-	return nil
+	return m.run(ctx, func(db *mgo.Database, maxTime time.Duration) error {
+		return db.C(cName).Insert(data...)
+	})
 }
 
 // UpdateByCondition update documents by condiction
 func (m *MgoCli) UpdateByCondition(cName string, data, condition interface{}) error {
-	m.session.Refresh()
-	c := m.session.DB(m.dbName).C(cName)
+	return m.UpdateByConditionCtx(context.Background(), cName, data, condition)
+}
+
+// UpdateByConditionCtx update documents by condiction
+func (m *MgoCli) UpdateByConditionCtx(ctx context.Context, cName string, data, condition interface{}) error {
 	EscapeHtml(data)
 	datac := bson.M{"$set": data}
-	_, err := c.UpdateAll(condition, datac)
-	if err != nil {
+	return m.run(ctx, func(db *mgo.Database, maxTime time.Duration) error {
+		_, err := db.C(cName).UpdateAll(condition, datac)
 		return err
-	}
-	return nil
+	})
 }
 
 // GetOneByCondition get one document by condiction
 func (m *MgoCli) GetOneByCondition(cName string, fields []string, condiction interface{}, result interface{}) error {
-	m.session.Refresh()
-	c := m.session.DB(m.dbName).C(cName)
+	return m.GetOneByConditionCtx(context.Background(), cName, fields, condiction, result)
+}
+
+// GetOneByConditionCtx get one document by condiction
+func (m *MgoCli) GetOneByConditionCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}) error {
 	fieldmap := make(map[string]interface{})
 	if 0 != len(fields) {
 		for _, key := range fields {
@@ -133,24 +199,30 @@ func (m *MgoCli) GetOneByCondition(cName string, fields []string, condiction int
 	}
 
 	fieldmap["_id"] = 0
-	query := c.Find(condiction)
-	if 0 < len(fieldmap) {
-		query.Select(fieldmap)
-	}
-	err := query.One(result)
-	if err != nil {
-		return err
-	}
-	return nil
+	return storage.ReadInto(result, func(result interface{}) error {
+		return m.read(ctx, func(db *mgo.Database, maxTime time.Duration) error {
+			query := db.C(cName).Find(condiction)
+			if 0 < len(fieldmap) {
+				query.Select(fieldmap)
+			}
+			if 0 < maxTime {
+				query.SetMaxTime(maxTime)
+			}
+			return query.One(result)
+		})
+	})
 }
 
 // GetMutilByCondition get multiple document by condiction
 func (m *MgoCli) GetMutilByCondition(cName string, fields []string, condiction interface{}, result interface{}, sort string, start, limit int) error {
-	m.session.Refresh()
+	return m.GetMutilByConditionCtx(context.Background(), cName, fields, condiction, result, sort, start, limit)
+}
+
+// GetMutilByConditionCtx get multiple document by condiction
+func (m *MgoCli) GetMutilByConditionCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}, sort string, start, limit int) error {
 	if len(fields) == 1 && fields[0] == "" {
 		fields = nil
 	}
-	c := m.session.DB(m.dbName).C(cName)
 	fieldmap := make(map[string]interface{})
 	if 0 != len(fields) {
 		for _, key := range fields {
@@ -159,35 +231,56 @@ func (m *MgoCli) GetMutilByCondition(cName string, fields []string, condiction i
 	}
 
 	fieldmap["_id"] = 0
-	query := c.Find(condiction)
-	if 0 < len(fieldmap) {
-		query = query.Select(fieldmap)
-	}
-	if "" != sort {
-		query = query.Sort(sort)
-	}
+	return storage.ReadInto(result, func(result interface{}) error {
+		return m.read(ctx, func(db *mgo.Database, maxTime time.Duration) error {
+			query := db.C(cName).Find(condiction)
+			if 0 < len(fieldmap) {
+				query = query.Select(fieldmap)
+			}
+			if "" != sort {
+				query = query.Sort(sort)
+			}
 
-	if 0 < start {
-		query = query.Skip(start)
-	}
-	if 0 < limit {
-		query = query.Limit(limit)
-	}
-	err := query.All(result)
-	if err != nil {
-		return err
-	}
-	return nil
+			if 0 < start {
+				query = query.Skip(start)
+			}
+			if 0 < limit {
+				query = query.Limit(limit)
+			}
+			if 0 < maxTime {
+				query = query.SetMaxTime(maxTime)
+			}
+			return query.All(result)
+		})
+	})
 }
 
 // GetCntByCondition returns count number filter by condiction
 func (m *MgoCli) GetCntByCondition(cName string, condiction interface{}) (cnt int, err error) {
-	m.session.Refresh()
-	c := m.session.DB(m.dbName).C(cName)
+	return m.GetCntByConditionCtx(context.Background(), cName, condiction)
+}
+
+// GetCntByConditionCtx returns count number filter by condiction
+func (m *MgoCli) GetCntByConditionCtx(ctx context.Context, cName string, condiction interface{}) (int, error) {
 	count := 0
-	count, err = c.Find(condiction).Count()
+	err := m.read(ctx, func(db *mgo.Database, maxTime time.Duration) error {
+		if 0 == maxTime {
+			n, err := db.C(cName).Find(condiction).Count()
+			count = n
+			return err
+		}
+		// Query.Count ignores the max time, so run the count command directly
+		if condiction == nil {
+			condiction = bson.M{}
+		}
+		result := struct{ N int }{}
+		cmd := bson.D{{Name: "count", Value: cName}, {Name: "query", Value: condiction}, {Name: "maxTimeMS", Value: int64(maxTime / time.Millisecond)}}
+		err := db.Run(cmd, &result)
+		count = result.N
+		return err
+	})
 	if err != nil {
-		return count, err
+		return 0, err
 	}
 	return count, nil
 }
@@ -202,15 +295,26 @@ func (m *MgoCli) GetCntByCondition(cName string, condiction interface{}) (cnt in
 // 	new:true
 //  }).sequence_value
 func (m *MgoCli) GetIncID(cName string) (incID int64, err error) {
-	m.session.Refresh()
-	c := m.session.DB(m.dbName).C("cc_idgenerator")
+	return m.GetIncIDCtx(context.Background(), cName)
+}
+
+// GetIncIDCtx returns next sequence ID for cName collection
+func (m *MgoCli) GetIncIDCtx(ctx context.Context, cName string) (int64, error) {
+	return m.ReserveIncIDCtx(ctx, cName, 1)
+}
+
+// ReserveIncIDCtx advances the sequence of cName collection by n and returns the last ID of the block
+func (m *MgoCli) ReserveIncIDCtx(ctx context.Context, cName string, n int64) (int64, error) {
 	change := mgo.Change{
-		Update:    bson.M{"$inc": bson.M{"SequenceID": int64(1)}},
+		Update:    bson.M{"$inc": bson.M{"SequenceID": n}},
 		ReturnNew: true,
 		Upsert:    true,
 	}
 	doc := map[string]interface{}{}
-	_, err = c.Find(bson.M{"_id": cName}).Apply(change, &doc)
+	err := m.run(ctx, func(db *mgo.Database, maxTime time.Duration) error {
+		_, err := db.C("cc_idgenerator").Find(bson.M{"_id": cName}).Apply(change, &doc)
+		return err
+	})
 	if err != nil {
 		return 0, err
 	}
@@ -219,16 +323,15 @@ func (m *MgoCli) GetIncID(cName string) (incID int64, err error) {
 
 //按条件删除主句
 func (m *MgoCli) DelByCondition(cName string, condiction interface{}) error {
-	m.session.Refresh()
-	c := m.session.DB(m.dbName).C(cName)
+	return m.DelByConditionCtx(context.Background(), cName, condiction)
+}
 
-	// s = s.(map[string]interface{})
-	// selector := bson.M(s)
-	_, err := c.RemoveAll(condiction)
-	if err != nil {
+// DelByConditionCtx delete the documents by condiction
+func (m *MgoCli) DelByConditionCtx(ctx context.Context, cName string, condiction interface{}) error {
+	return m.run(ctx, func(db *mgo.Database, maxTime time.Duration) error {
+		_, err := db.C(cName).RemoveAll(condiction)
 		return err
-	}
-	return nil
+	})
 }
 
 //判断表是否存在
@@ -276,11 +379,15 @@ func (m *MgoCli) Index(tableName string, index *storage.Index) error {
 	case storage.INDEX_TYPE_BACKGROUP:
 		backgroud = true
 	}
+	if 0 != len(index.PartialFilter) {
+		return m.createPartialIndex(tableName, index, unique, backgroud)
+	}
 	return m.session.DB(m.dbName).C(tableName).EnsureIndex(mgo.Index{
-		Name:       index.Name,
-		Key:        index.Columns,
-		Unique:     unique,
-		Background: backgroud,
+		Name:        index.Name,
+		Key:         index.Columns,
+		Unique:      unique,
+		Background:  backgroud,
+		ExpireAfter: index.ExpireAfter,
 	})
 }
 
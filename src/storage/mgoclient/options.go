@@ -0,0 +1,112 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgoclient
+
+import (
+	"configcenter/src/storage"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gopkg.in/mgo.v2"
+)
+
+const (
+	defaultPoolLimit      = 4096
+	defaultConnectTimeout = 5 * time.Second
+)
+
+// Options the connection settings of MgoCli
+type Options struct {
+	// ReadPreference the default read preference of the reads, the writes always go to the primary
+	ReadPreference storage.ReadPreference
+	// PoolLimit the max count of the sockets to a server
+	PoolLimit int
+	// ConnectTimeout the timeout of connecting to the servers
+	ConnectTimeout time.Duration
+	// SocketTimeout the timeout of the socket reads and writes, 0 for the mgo default
+	SocketTimeout time.Duration
+	// ReplicaSet the name of the replica set, the servers out of the replica set are not used if it is set
+	ReplicaSet string
+}
+
+// DefaultOptions returns the settings which NewMgoCli uses
+func DefaultOptions() Options {
+	return Options{
+		ReadPreference: storage.ReadPrimary,
+		PoolLimit:      defaultPoolLimit,
+		ConnectTimeout: defaultConnectTimeout,
+	}
+}
+
+// ParseOptions reads the settings of the configuration section, the keys are
+// readPreference, maxOpenConns, connectTimeout, socketTimeout and replicaSet,
+// the timeouts are the durations such as "5s", the missing keys keep the defaults
+func ParseOptions(config map[string]string, section string) (Options, error) {
+	opts := DefaultOptions()
+	pref, err := storage.ParseReadPreference(config[section+".readPreference"])
+	if err != nil {
+		return opts, err
+	}
+	if "" != pref {
+		opts.ReadPreference = pref
+	}
+	if val := config[section+".maxOpenConns"]; "" != val {
+		if opts.PoolLimit, err = strconv.Atoi(val); err != nil || opts.PoolLimit <= 0 {
+			return opts, fmt.Errorf("invalid %s.maxOpenConns %s", section, val)
+		}
+	}
+	if val := config[section+".connectTimeout"]; "" != val {
+		if opts.ConnectTimeout, err = time.ParseDuration(val); err != nil || opts.ConnectTimeout <= 0 {
+			return opts, fmt.Errorf("invalid %s.connectTimeout %s", section, val)
+		}
+	}
+	if val := config[section+".socketTimeout"]; "" != val {
+		if opts.SocketTimeout, err = time.ParseDuration(val); err != nil || opts.SocketTimeout < 0 {
+			return opts, fmt.Errorf("invalid %s.socketTimeout %s", section, val)
+		}
+	}
+	opts.ReplicaSet = config[section+".replicaSet"]
+	return opts, nil
+}
+
+// SetOptions changes the settings, it takes effect on the next Open
+func (m *MgoCli) SetOptions(opts Options) {
+	m.opts = opts
+}
+
+// modeOf returns the mgo mode of the read preference
+func modeOf(pref storage.ReadPreference) mgo.Mode {
+	switch pref {
+	case storage.ReadPrimaryPreferred:
+		return mgo.PrimaryPreferred
+	case storage.ReadSecondary:
+		return mgo.Secondary
+	case storage.ReadSecondaryPreferred:
+		return mgo.SecondaryPreferred
+	case storage.ReadNearest:
+		return mgo.Nearest
+	}
+	return mgo.Primary
+}
+
+// read runs the read fn by the read preference of ctx, or the default one if ctx has none,
+// fn is given up when ctx is done so it must not decode into the caller's result, see storage.ReadInto
+func (m *MgoCli) read(ctx context.Context, fn func(db *mgo.Database, maxTime time.Duration) error) error {
+	pref := storage.ReadPreferenceOf(ctx)
+	if "" == pref {
+		pref = m.opts.ReadPreference
+	}
+	return m.runMode(ctx, modeOf(pref), storage.RunWithContext, fn)
+}
@@ -0,0 +1,67 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgoclient
+
+import (
+	"configcenter/src/storage"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2"
+)
+
+func TestParseOptions(t *testing.T) {
+	opts, err := ParseOptions(map[string]string{}, "mongodb")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultOptions(), opts)
+
+	opts, err = ParseOptions(map[string]string{
+		"mongodb.readPreference": "secondaryPreferred",
+		"mongodb.maxOpenConns":   "300",
+		"mongodb.connectTimeout": "3s",
+		"mongodb.socketTimeout":  "1m",
+		"mongodb.replicaSet":     "rs0",
+	}, "mongodb")
+	require.NoError(t, err)
+	assert.Equal(t, Options{
+		ReadPreference: storage.ReadSecondaryPreferred,
+		PoolLimit:      300,
+		ConnectTimeout: 3 * time.Second,
+		SocketTimeout:  time.Minute,
+		ReplicaSet:     "rs0",
+	}, opts)
+
+	for _, config := range []map[string]string{
+		{"mongodb.readPreference": "slave"},
+		{"mongodb.maxOpenConns": "0"},
+		{"mongodb.connectTimeout": "3"},
+		{"mongodb.socketTimeout": "-1s"},
+	} {
+		_, err := ParseOptions(config, "mongodb")
+		assert.Error(t, err, "%v", config)
+	}
+}
+
+func TestReadMode(t *testing.T) {
+	assert.Equal(t, mgo.Primary, modeOf(""))
+	assert.Equal(t, mgo.Secondary, modeOf(storage.ReadSecondary))
+	assert.Equal(t, mgo.Nearest, modeOf(storage.ReadNearest))
+
+	ctx := storage.WithReadPreference(context.Background(), storage.ReadPrimaryPreferred)
+	assert.Equal(t, storage.ReadPrimaryPreferred, storage.ReadPreferenceOf(ctx))
+	assert.Equal(t, ctx, storage.WithReadPreference(ctx, ""), "the empty read preference keeps the context")
+	assert.Equal(t, storage.ReadPreference(""), storage.ReadPreferenceOf(context.Background()))
+}
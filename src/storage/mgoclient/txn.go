@@ -0,0 +1,456 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgoclient
+
+import (
+	"configcenter/src/common/blog"
+	"configcenter/src/storage"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const sessionTxPrefix = "s-"
+
+// supportTx returns true if the server runs the multi-document transactions,
+// mongodb 4.0 replica set or 4.2 sharded cluster required
+func (m *MgoCli) supportTx() bool {
+	m.txLock.Lock()
+	defer m.txLock.Unlock()
+	if m.txChecked {
+		return m.txSupported
+	}
+	result := struct {
+		SetName        string `bson:"setName"`
+		Msg            string `bson:"msg"`
+		MaxWireVersion int    `bson:"maxWireVersion"`
+	}{}
+	if err := m.session.Run("isMaster", &result); err != nil {
+		blog.Errorf("failed to check the transaction support, error: %v", err)
+		return false
+	}
+	m.txChecked = true
+	m.txSupported = ("" != result.SetName && result.MaxWireVersion >= 7) ||
+		("isdbgrid" == result.Msg && result.MaxWireVersion >= 8)
+	return m.txSupported
+}
+
+// disableTx turns to the journal transactions
+func (m *MgoCli) disableTx() {
+	m.txLock.Lock()
+	defer m.txLock.Unlock()
+	m.txChecked = true
+	m.txSupported = false
+}
+
+// StartTx starts a server session transaction, storage.ErrTxNotSupported is returned
+// if the server is not able to run it
+func (m *MgoCli) StartTx(ctx context.Context) (storage.Tx, error) {
+	if !m.supportTx() {
+		return nil, storage.ErrTxNotSupported
+	}
+	lsid := make([]byte, 16)
+	if _, err := rand.Read(lsid); err != nil {
+		return nil, err
+	}
+	// uuid version 4
+	lsid[6] = (lsid[6] & 0x0f) | 0x40
+	lsid[8] = (lsid[8] & 0x3f) | 0x80
+	tx := &sessionTx{MgoCli: m, lsid: lsid, txnNumber: 1}
+
+	// starts the transaction on the server, so that it could be resumed by the others
+	cmd := bson.D{{Name: "find", Value: "cc_idgenerator"}, {Name: "limit", Value: 1}, {Name: "startTransaction", Value: true}}
+	if err := tx.command(ctx, m.dbName, cmd, nil); err != nil {
+		if _, ok := err.(*mgo.QueryError); ok {
+			// the server refuses the transaction, such as the transactions over the legacy protocol
+			blog.Errorf("the server refuses to start the transaction, turn to the journal transaction, error: %v", err)
+			m.disableTx()
+			return nil, storage.ErrTxNotSupported
+		}
+		return nil, err
+	}
+	return tx, nil
+}
+
+// ResumeTx resumes the server session transaction of txID
+func (m *MgoCli) ResumeTx(ctx context.Context, txID string) (storage.Tx, error) {
+	parts := strings.Split(strings.TrimPrefix(txID, sessionTxPrefix), "-")
+	if !strings.HasPrefix(txID, sessionTxPrefix) || 2 != len(parts) {
+		return nil, storage.ErrTxNotFound
+	}
+	lsid, err := hex.DecodeString(parts[0])
+	if err != nil || 16 != len(lsid) {
+		return nil, storage.ErrTxNotFound
+	}
+	txnNumber, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, storage.ErrTxNotFound
+	}
+	return &sessionTx{MgoCli: m, lsid: lsid, txnNumber: txnNumber}, nil
+}
+
+// SnapshotCtx returns the raw documents which match the condiction
+func (m *MgoCli) SnapshotCtx(ctx context.Context, cName string, condiction interface{}) ([]map[string]interface{}, error) {
+	docs := make([]map[string]interface{}, 0)
+	err := m.run(ctx, func(db *mgo.Database, maxTime time.Duration) error {
+		return db.C(cName).Find(condiction).All(&docs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// InsertRowsCtx inserts the documents and returns their _id, the missing _id is generated before the insert
+func (m *MgoCli) InsertRowsCtx(ctx context.Context, cName string, data ...interface{}) ([]interface{}, error) {
+	EscapeHtml(data...)
+	docs := make([]interface{}, 0, len(data))
+	ids := make([]interface{}, 0, len(data))
+	for _, item := range data {
+		raw, err := bson.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		doc := bson.M{}
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			return nil, err
+		}
+		if _, ok := doc["_id"]; !ok {
+			doc["_id"] = bson.NewObjectId()
+		}
+		docs = append(docs, doc)
+		ids = append(ids, doc["_id"])
+	}
+	err := m.run(ctx, func(db *mgo.Database, maxTime time.Duration) error {
+		return db.C(cName).Insert(docs...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// RowID returns the _id of the raw document
+func (m *MgoCli) RowID(doc map[string]interface{}) interface{} {
+	return doc["_id"]
+}
+
+// ClaimCtx sets data on the document which matches condiction by findAndModify
+func (m *MgoCli) ClaimCtx(ctx context.Context, cName string, condiction, data interface{}) (bool, error) {
+	claimed := true
+	err := m.run(ctx, func(db *mgo.Database, maxTime time.Duration) error {
+		_, err := db.C(cName).Find(condiction).Apply(mgo.Change{Update: bson.M{"$set": data}}, nil)
+		if mgo.ErrNotFound == err {
+			claimed = false
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return claimed, nil
+}
+
+// RestoreCtx removes the documents of ids and inserts the raw documents back
+func (m *MgoCli) RestoreCtx(ctx context.Context, cName string, ids []interface{}, docs []map[string]interface{}) error {
+	return m.run(ctx, func(db *mgo.Database, maxTime time.Duration) error {
+		if 0 != len(ids) {
+			if _, err := db.C(cName).RemoveAll(bson.M{"_id": bson.M{"$in": ids}}); err != nil {
+				return err
+			}
+		}
+		if 0 == len(docs) {
+			return nil
+		}
+		items := make([]interface{}, 0, len(docs))
+		for _, doc := range docs {
+			items = append(items, doc)
+		}
+		return db.C(cName).Insert(items...)
+	})
+}
+
+// sessionTx runs the operations in a server session transaction, the transaction is identified
+// by the session id and the transaction number, so that it could be resumed by any client
+type sessionTx struct {
+	*MgoCli
+	lsid      []byte
+	txnNumber int64
+}
+
+var _ storage.Tx = (*sessionTx)(nil)
+
+// ID returns the transaction id
+func (t *sessionTx) ID() string {
+	return fmt.Sprintf("%s%s-%d", sessionTxPrefix, hex.EncodeToString(t.lsid), t.txnNumber)
+}
+
+// command runs the command of database dbName in the transaction
+func (t *sessionTx) command(ctx context.Context, dbName string, cmd bson.D, result interface{}) error {
+	cmd = append(cmd,
+		bson.DocElem{Name: "lsid", Value: bson.M{"id": bson.Binary{Kind: 0x04, Data: t.lsid}}},
+		bson.DocElem{Name: "txnNumber", Value: t.txnNumber},
+		bson.DocElem{Name: "autocommit", Value: false},
+	)
+	if nil == result {
+		result = &bson.M{}
+	}
+	return t.run(ctx, func(db *mgo.Database, maxTime time.Duration) error {
+		return db.Session.DB(dbName).Run(cmd, result)
+	})
+}
+
+// write runs the write command and checks the write errors
+func (t *sessionTx) write(ctx context.Context, cmd bson.D) error {
+	result := struct {
+		WriteErrors []struct {
+			Code   int    `bson:"code"`
+			ErrMsg string `bson:"errmsg"`
+		} `bson:"writeErrors"`
+	}{}
+	if err := t.command(ctx, t.dbName, cmd, &result); err != nil {
+		return err
+	}
+	if 0 != len(result.WriteErrors) {
+		return &mgo.QueryError{Code: result.WriteErrors[0].Code, Message: result.WriteErrors[0].ErrMsg}
+	}
+	return nil
+}
+
+// find runs the find or aggregate command and reads all the batches of the cursor
+func (t *sessionTx) find(ctx context.Context, cName string, cmd bson.D) ([]bson.Raw, error) {
+	type cursorResult struct {
+		Cursor struct {
+			ID         int64      `bson:"id"`
+			FirstBatch []bson.Raw `bson:"firstBatch"`
+			NextBatch  []bson.Raw `bson:"nextBatch"`
+		} `bson:"cursor"`
+	}
+	result := cursorResult{}
+	if err := t.command(ctx, t.dbName, cmd, &result); err != nil {
+		return nil, err
+	}
+	docs := result.Cursor.FirstBatch
+	for 0 != result.Cursor.ID {
+		more := bson.D{{Name: "getMore", Value: result.Cursor.ID}, {Name: "collection", Value: cName}}
+		result = cursorResult{}
+		if err := t.command(ctx, t.dbName, more, &result); err != nil {
+			return nil, err
+		}
+		docs = append(docs, result.Cursor.NextBatch...)
+	}
+	return docs, nil
+}
+
+// findQuery returns the find command of the query
+func findQuery(cName string, fields []string, condiction interface{}, sort string, start, limit int) bson.D {
+	if nil == condiction {
+		condiction = bson.M{}
+	}
+	projection := bson.M{"_id": 0}
+	for _, key := range fields {
+		if "" != key {
+			projection[key] = 1
+		}
+	}
+	cmd := bson.D{{Name: "find", Value: cName}, {Name: "filter", Value: condiction}, {Name: "projection", Value: projection}}
+	if "" != sort {
+		sortDoc := bson.D{}
+		for _, field := range strings.Split(sort, ",") {
+			field = strings.TrimSpace(field)
+			order := 1
+			if strings.HasPrefix(field, "-") {
+				order = -1
+			}
+			field = strings.TrimLeft(field, "+-")
+			if "" != field {
+				sortDoc = append(sortDoc, bson.DocElem{Name: field, Value: order})
+			}
+		}
+		cmd = append(cmd, bson.DocElem{Name: "sort", Value: sortDoc})
+	}
+	if 0 < start {
+		cmd = append(cmd, bson.DocElem{Name: "skip", Value: start})
+	}
+	if 0 < limit {
+		cmd = append(cmd, bson.DocElem{Name: "limit", Value: limit})
+	}
+	return cmd
+}
+
+// Commit commits the transaction
+func (t *sessionTx) Commit(ctx context.Context) error {
+	defer t.endSession()
+	return t.command(ctx, "admin", bson.D{{Name: "commitTransaction", Value: 1}}, nil)
+}
+
+// Abort aborts the transaction
+func (t *sessionTx) Abort(ctx context.Context) error {
+	defer t.endSession()
+	return t.command(ctx, "admin", bson.D{{Name: "abortTransaction", Value: 1}}, nil)
+}
+
+// endSession releases the server session, the error is ignored as the session expires anyway
+func (t *sessionTx) endSession() {
+	cmd := bson.D{{Name: "endSessions", Value: []bson.M{{"id": bson.Binary{Kind: 0x04, Data: t.lsid}}}}}
+	if err := t.MgoCli.session.Run(cmd, &bson.M{}); err != nil {
+		blog.Warnf("failed to end the session of transaction %s, error: %v", t.ID(), err)
+	}
+}
+
+// GetIncID the sequence ID is not allocated in the transaction
+func (t *sessionTx) GetIncID(cName string) (int64, error) {
+	return t.MgoCli.GetIncIDCtx(context.Background(), cName)
+}
+
+// GetIncIDCtx the sequence ID is not allocated in the transaction
+func (t *sessionTx) GetIncIDCtx(ctx context.Context, cName string) (int64, error) {
+	return t.MgoCli.GetIncIDCtx(ctx, cName)
+}
+
+func (t *sessionTx) Insert(cName string, data interface{}) (int, error) {
+	return t.InsertCtx(context.Background(), cName, data)
+}
+
+func (t *sessionTx) InsertCtx(ctx context.Context, cName string, data interface{}) (int, error) {
+	return 0, t.InsertMutiCtx(ctx, cName, data)
+}
+
+func (t *sessionTx) InsertMuti(cName string, data ...interface{}) error {
+	return t.InsertMutiCtx(context.Background(), cName, data...)
+}
+
+func (t *sessionTx) InsertMutiCtx(ctx context.Context, cName string, data ...interface{}) error {
+	EscapeHtml(data...)
+	return t.write(ctx, bson.D{{Name: "insert", Value: cName}, {Name: "documents", Value: data}})
+}
+
+func (t *sessionTx) UpdateByCondition(cName string, data, condiction interface{}) error {
+	return t.UpdateByConditionCtx(context.Background(), cName, data, condiction)
+}
+
+func (t *sessionTx) UpdateByConditionCtx(ctx context.Context, cName string, data, condiction interface{}) error {
+	EscapeHtml(data)
+	if nil == condiction {
+		condiction = bson.M{}
+	}
+	update := bson.M{"q": condiction, "u": bson.M{"$set": data}, "multi": true}
+	return t.write(ctx, bson.D{{Name: "update", Value: cName}, {Name: "updates", Value: []bson.M{update}}})
+}
+
+func (t *sessionTx) GetOneByCondition(cName string, fields []string, condiction interface{}, result interface{}) error {
+	return t.GetOneByConditionCtx(context.Background(), cName, fields, condiction, result)
+}
+
+func (t *sessionTx) GetOneByConditionCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}) error {
+	cmd := append(findQuery(cName, fields, condiction, "", 0, 1), bson.DocElem{Name: "singleBatch", Value: true})
+	docs, err := t.find(ctx, cName, cmd)
+	if err != nil {
+		return err
+	}
+	if 0 == len(docs) {
+		return mgo.ErrNotFound
+	}
+	return docs[0].Unmarshal(result)
+}
+
+func (t *sessionTx) GetMutilByCondition(cName string, fields []string, condiction interface{}, result interface{}, sort string, start, limit int) error {
+	return t.GetMutilByConditionCtx(context.Background(), cName, fields, condiction, result, sort, start, limit)
+}
+
+func (t *sessionTx) GetMutilByConditionCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}, sort string, start, limit int) error {
+	docs, err := t.find(ctx, cName, findQuery(cName, fields, condiction, sort, start, limit))
+	if err != nil {
+		return err
+	}
+	return decodeDocs(docs, result)
+}
+
+func (t *sessionTx) GetMutilByCursor(cName string, fields []string, condiction interface{}, result interface{}, sort, cursor string, limit int) (string, error) {
+	return t.GetMutilByCursorCtx(context.Background(), cName, fields, condiction, result, sort, cursor, limit)
+}
+
+func (t *sessionTx) GetMutilByCursorCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}, sort, cursor string, limit int) (string, error) {
+	keys := cursorKeys(sort)
+	cond, err := cursorCondition(condiction, keys, cursor)
+	if err != nil {
+		return "", err
+	}
+	cmd := bson.D{{Name: "find", Value: cName}, {Name: "filter", Value: cond}, {Name: "sort", Value: sortDoc(keys)}}
+	if projection := cursorProjection(fields, keys); nil != projection {
+		cmd = append(cmd, bson.DocElem{Name: "projection", Value: projection})
+	}
+	if 0 < limit {
+		cmd = append(cmd, bson.DocElem{Name: "limit", Value: limit})
+	}
+	raws, err := t.find(ctx, cName, cmd)
+	if err != nil {
+		return "", err
+	}
+	docs := make([]bson.M, 0, len(raws))
+	for _, raw := range raws {
+		doc := bson.M{}
+		if err := raw.Unmarshal(&doc); err != nil {
+			return "", err
+		}
+		docs = append(docs, doc)
+	}
+	return cursorResult(docs, fields, keys, limit, result)
+}
+
+func (t *sessionTx) GetCntByCondition(cName string, condiction interface{}) (int, error) {
+	return t.GetCntByConditionCtx(context.Background(), cName, condiction)
+}
+
+// GetCntByConditionCtx the count command is not allowed in the transaction, the aggregation is used instead
+func (t *sessionTx) GetCntByConditionCtx(ctx context.Context, cName string, condiction interface{}) (int, error) {
+	if nil == condiction {
+		condiction = bson.M{}
+	}
+	pipeline := []bson.M{{"$match": condiction}, {"$count": "n"}}
+	cmd := bson.D{{Name: "aggregate", Value: cName}, {Name: "pipeline", Value: pipeline}, {Name: "cursor", Value: bson.M{}}}
+	docs, err := t.find(ctx, cName, cmd)
+	if err != nil {
+		return 0, err
+	}
+	if 0 == len(docs) {
+		return 0, nil
+	}
+	result := struct {
+		N int `bson:"n"`
+	}{}
+	if err := docs[0].Unmarshal(&result); err != nil {
+		return 0, err
+	}
+	return result.N, nil
+}
+
+func (t *sessionTx) DelByCondition(cName string, condiction interface{}) error {
+	return t.DelByConditionCtx(context.Background(), cName, condiction)
+}
+
+func (t *sessionTx) DelByConditionCtx(ctx context.Context, cName string, condiction interface{}) error {
+	if nil == condiction {
+		condiction = bson.M{}
+	}
+	del := bson.M{"q": condiction, "limit": 0}
+	return t.write(ctx, bson.D{{Name: "delete", Value: cName}, {Name: "deletes", Value: []bson.M{del}}})
+}
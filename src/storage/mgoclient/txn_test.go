@@ -0,0 +1,109 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgoclient
+
+import (
+	"configcenter/src/storage"
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestSessionTxID(t *testing.T) {
+	m := &MgoCli{dbName: "cmdb"}
+	tx := &sessionTx{MgoCli: m, lsid: []byte("0123456789abcdef"), txnNumber: 3}
+	resumed, err := m.ResumeTx(context.Background(), tx.ID())
+	if err != nil {
+		t.Fatalf("resume %s failed: %v", tx.ID(), err)
+	}
+	got := resumed.(*sessionTx)
+	if !reflect.DeepEqual(got.lsid, tx.lsid) || got.txnNumber != tx.txnNumber {
+		t.Fatalf("want the session of %s, got %s", tx.ID(), got.ID())
+	}
+
+	for _, txID := range []string{"", "j-0123", "s-0123-1", "s-30313233343536373839616263646566", "s-30313233343536373839616263646566-x"} {
+		if _, err := m.ResumeTx(context.Background(), txID); err != storage.ErrTxNotFound {
+			t.Fatalf("want not found of %q, got %v", txID, err)
+		}
+	}
+}
+
+func TestFindQuery(t *testing.T) {
+	cmd := findQuery("cc_HostBase", []string{"bk_host_id", ""}, nil, "-bk_host_id,bk_host_name", 10, 5)
+	expect := bson.D{
+		{Name: "find", Value: "cc_HostBase"},
+		{Name: "filter", Value: bson.M{}},
+		{Name: "projection", Value: bson.M{"_id": 0, "bk_host_id": 1}},
+		{Name: "sort", Value: bson.D{{Name: "bk_host_id", Value: -1}, {Name: "bk_host_name", Value: 1}}},
+		{Name: "skip", Value: 10},
+		{Name: "limit", Value: 5},
+	}
+	if !reflect.DeepEqual(cmd, expect) {
+		t.Fatalf("want %v, got %v", expect, cmd)
+	}
+}
+
+// TestSessionTx runs the session transactions on the replica set of CC_TEST_MONGO_ADDR
+func TestSessionTx(t *testing.T) {
+	addr := os.Getenv("CC_TEST_MONGO_ADDR")
+	if "" == addr {
+		t.Skip("CC_TEST_MONGO_ADDR is not set")
+	}
+	db, err := NewMgoCli(addr, "", os.Getenv("CC_TEST_MONGO_USR"), os.Getenv("CC_TEST_MONGO_PWD"), "", "cmdb_test")
+	require.NoError(t, err)
+	require.NoError(t, db.Open())
+	ctx := context.Background()
+	tx, err := db.StartTx(ctx)
+	if storage.ErrTxNotSupported == err {
+		t.Skip("the server does not run the transactions")
+	}
+	require.NoError(t, err)
+	const cName = "cc_SessionTxTest"
+	defer db.DropTable(cName)
+	_, err = db.Insert(cName, bson.M{"bk_host_id": 1})
+	require.NoError(t, err)
+
+	count := func(cdb storage.ContextDI) int {
+		cnt, err := cdb.GetCntByConditionCtx(ctx, cName, nil)
+		require.NoError(t, err)
+		return cnt
+	}
+
+	// the writes are seen in the transaction only until it commits, the resumed one joins it
+	require.NoError(t, tx.InsertMutiCtx(ctx, cName, bson.M{"bk_host_id": 2}))
+	resumed, err := db.ResumeTx(ctx, tx.ID())
+	require.NoError(t, err)
+	require.NoError(t, resumed.DelByConditionCtx(ctx, cName, bson.M{"bk_host_id": 1}))
+	assert.Equal(t, 1, count(tx))
+	assert.Equal(t, 1, count(db))
+	result := bson.M{}
+	require.NoError(t, db.GetOneByCondition(cName, nil, nil, &result))
+	assert.Equal(t, 1, result["bk_host_id"])
+	require.NoError(t, tx.Commit(ctx))
+	require.NoError(t, db.GetOneByCondition(cName, nil, nil, &result))
+	assert.Equal(t, 2, result["bk_host_id"])
+
+	// the aborted writes are dropped
+	tx, err = db.StartTx(ctx)
+	require.NoError(t, err)
+	require.NoError(t, tx.UpdateByConditionCtx(ctx, cName, bson.M{"bk_host_name": "changed"}, nil))
+	require.NoError(t, tx.Abort(ctx))
+	cnt, err := db.GetCntByCondition(cName, bson.M{"bk_host_name": "changed"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, cnt)
+}
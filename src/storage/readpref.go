@@ -0,0 +1,62 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// ReadPreference the members of a replica set which the reads are sent to, the writes always go to the primary
+type ReadPreference string
+
+const (
+	// ReadPrimary reads from the primary
+	ReadPrimary ReadPreference = "primary"
+	// ReadPrimaryPreferred reads from the primary, or a secondary if the primary is unavailable
+	ReadPrimaryPreferred ReadPreference = "primaryPreferred"
+	// ReadSecondary reads from a secondary
+	ReadSecondary ReadPreference = "secondary"
+	// ReadSecondaryPreferred reads from a secondary, or the primary if there is not any secondary
+	ReadSecondaryPreferred ReadPreference = "secondaryPreferred"
+	// ReadNearest reads from the nearest member
+	ReadNearest ReadPreference = "nearest"
+)
+
+// ParseReadPreference returns the read preference of the name, the empty name is the empty
+// read preference which means the default one of the storage
+func ParseReadPreference(name string) (ReadPreference, error) {
+	switch pref := ReadPreference(name); pref {
+	case "", ReadPrimary, ReadPrimaryPreferred, ReadSecondary, ReadSecondaryPreferred, ReadNearest:
+		return pref, nil
+	}
+	return "", fmt.Errorf("unknown read preference %s", name)
+}
+
+type readPreferenceKey struct{}
+
+// WithReadPreference returns the context whose reads use the read preference instead of the default one
+// of the storage, the storages without the replicas ignore it and so do the reads in a transaction,
+// ctx is returned as it is if pref is empty
+func WithReadPreference(ctx context.Context, pref ReadPreference) context.Context {
+	if "" == pref {
+		return ctx
+	}
+	return context.WithValue(ctx, readPreferenceKey{}, pref)
+}
+
+// ReadPreferenceOf returns the read preference of the context, it is empty if there is not any
+func ReadPreferenceOf(ctx context.Context) ReadPreference {
+	pref, _ := ctx.Value(readPreferenceKey{}).(ReadPreference)
+	return pref
+}
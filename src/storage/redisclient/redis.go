@@ -17,6 +17,7 @@ import (
 	"configcenter/src/common/blog"
 	"configcenter/src/common/util"
 	"configcenter/src/storage"
+	"context"
 	"errors"
 	"strconv"
 	"strings"
@@ -34,7 +35,7 @@ type Redis struct {
 	session *redis.Client
 }
 
-func NewRedis(host, port, usr, pwd, database string) (storage.DI, error) {
+func NewRedis(host, port, usr, pwd, database string) (storage.ContextDI, error) {
 	RedisConfig := new(Redis)
 	RedisConfig.host = host
 	RedisConfig.port = port
@@ -270,6 +271,11 @@ func (r *Redis) GetMutilByCondition(cName string, fields []string, selector, res
 	return errors.New("no support method")
 }
 
+func (r *Redis) GetMutilByCursor(cName string, fields []string, selector, results interface{}, sort, cursor string, limit int) (string, error) {
+
+	return "", errors.New("no support method")
+}
+
 func (r *Redis) GetCntByCondition(cName string, selector interface{}) (cnt int, err error) {
 
 	return 0, errors.New("no support method")
@@ -366,3 +372,82 @@ func (r *Redis) Close() {
 		r.session.Close()
 	}
 }
+
+// GetIncIDCtx runs GetIncID, ctx is not checked
+func (r *Redis) GetIncIDCtx(ctx context.Context, cName string) (int64, error) {
+	return r.GetIncID(cName)
+}
+
+// InsertCtx runs the write command unless ctx is done, it is never given up once started
+func (r *Redis) InsertCtx(ctx context.Context, cName string, data interface{}) (int, error) {
+	var result int
+	err := storage.RunWrite(ctx, func() error {
+		var fnerr error
+		result, fnerr = r.Insert(cName, data)
+		return fnerr
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result, nil
+}
+
+// InsertMutiCtx runs InsertMuti, ctx is not checked
+func (r *Redis) InsertMutiCtx(ctx context.Context, cName string, data ...interface{}) error {
+	return r.InsertMuti(cName, data...)
+}
+
+// UpdateByConditionCtx runs UpdateByCondition, ctx is not checked
+func (r *Redis) UpdateByConditionCtx(ctx context.Context, cName string, oldobj, newobj interface{}) error {
+	return r.UpdateByCondition(cName, oldobj, newobj)
+}
+
+// GetOneByConditionCtx runs the read command, it is given up when ctx is done and the results are
+// left untouched, and the blocking commands wait no longer than the deadline of ctx
+func (r *Redis) GetOneByConditionCtx(ctx context.Context, cName string, fields []string, selector, results interface{}) error {
+	if deadline, ok := ctx.Deadline(); ok && strings.ToLower(cName) == "blpop" {
+		remain := time.Until(deadline)
+		if remain <= 0 {
+			return context.DeadlineExceeded
+		}
+		if mapData, ok := selector.(common.KvMap); ok {
+			timeout, _ := mapData["expire"].(time.Duration)
+			if timeout == 0 || timeout > remain {
+				capped := common.KvMap{}
+				for key, val := range mapData {
+					capped[key] = val
+				}
+				// redis blocks in second, round up so that the timeout is never 0 which means forever
+				capped["expire"] = (remain + time.Second - 1) / time.Second * time.Second
+				selector = capped
+			}
+		}
+	}
+	return storage.ReadInto(results, func(results interface{}) error {
+		return storage.RunWithContext(ctx, func() error {
+			return r.GetOneByCondition(cName, fields, selector, results)
+		})
+	})
+}
+
+// GetMutilByConditionCtx runs GetMutilByCondition, ctx is not checked
+func (r *Redis) GetMutilByConditionCtx(ctx context.Context, cName string, fields []string, selector, results interface{}, sort string, skip, limit int) error {
+	return r.GetMutilByCondition(cName, fields, selector, results, sort, skip, limit)
+}
+
+// GetMutilByCursorCtx runs GetMutilByCursor, ctx is not checked
+func (r *Redis) GetMutilByCursorCtx(ctx context.Context, cName string, fields []string, selector, results interface{}, sort, cursor string, limit int) (string, error) {
+	return r.GetMutilByCursor(cName, fields, selector, results, sort, cursor, limit)
+}
+
+// GetCntByConditionCtx runs GetCntByCondition, ctx is not checked
+func (r *Redis) GetCntByConditionCtx(ctx context.Context, cName string, selector interface{}) (int, error) {
+	return r.GetCntByCondition(cName, selector)
+}
+
+// DelByConditionCtx runs the delete command unless ctx is done, it is never given up once started
+func (r *Redis) DelByConditionCtx(ctx context.Context, cName string, delselector interface{}) error {
+	return storage.RunWrite(ctx, func() error {
+		return r.DelByCondition(cName, delselector)
+	})
+}
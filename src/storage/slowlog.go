@@ -0,0 +1,377 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"configcenter/src/common/blog"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// DefaultSlowLogSize how many recent slow queries are kept by default
+const DefaultSlowLogSize = 100
+
+// explainTimeout bounds the explain of a slow query, it runs in background
+const explainTimeout = 10 * time.Second
+
+// Wrapper is implemented by the storages which wrap another storage, the optional interfaces
+// such as TxDI are looked up on the wrapped storage
+type Wrapper interface {
+	Unwrap() DI
+}
+
+// Unwrap returns the innermost storage of db
+func Unwrap(db DI) DI {
+	for {
+		w, ok := db.(Wrapper)
+		if !ok {
+			return db
+		}
+		db = w.Unwrap()
+	}
+}
+
+// Explainer is implemented by the storages which are able to explain the query plans
+type Explainer interface {
+	// ExplainCtx returns the plan of the query of condiction sorted by sort
+	ExplainCtx(ctx context.Context, cName string, condiction interface{}, sort string) (map[string]interface{}, error)
+}
+
+// SlowLogOptions the options of the slow query log
+type SlowLogOptions struct {
+	// Threshold the calls which take longer are logged
+	Threshold time.Duration
+	// ExplainSampleRate the fraction of the slow reads whose query plans are captured, 0 captures none
+	ExplainSampleRate float64
+	// Size how many recent slow queries are kept
+	Size int
+}
+
+// SlowQuery the record of a slow call, the values in the condiction are redacted
+type SlowQuery struct {
+	Operation  string                 `json:"operation"`
+	Collection string                 `json:"collection"`
+	Condition  interface{}            `json:"condition,omitempty"`
+	Sort       string                 `json:"sort,omitempty"`
+	Caller     string                 `json:"caller"`
+	StartAt    time.Time              `json:"start_at"`
+	Cost       int64                  `json:"cost_ms"`
+	Error      string                 `json:"error,omitempty"`
+	Explain    map[string]interface{} `json:"explain,omitempty"`
+}
+
+// SlowLog keeps the recent slow queries
+type SlowLog struct {
+	opts SlowLogOptions
+
+	lock    sync.Mutex
+	entries []*SlowQuery
+	next    int
+}
+
+// Recent returns the recent slow queries, the latest first
+func (l *SlowLog) Recent() []SlowQuery {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	result := make([]SlowQuery, 0, len(l.entries))
+	for i := 1; i <= len(l.entries); i++ {
+		result = append(result, *l.entries[(l.next-i+len(l.entries))%len(l.entries)])
+	}
+	return result
+}
+
+func (l *SlowLog) add(entry *SlowQuery) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if len(l.entries) < l.opts.Size {
+		l.entries = append(l.entries, entry)
+		l.next = len(l.entries) % l.opts.Size
+		return
+	}
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % l.opts.Size
+}
+
+func (l *SlowLog) setExplain(entry *SlowQuery, plan map[string]interface{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	entry.Explain = plan
+}
+
+// slowLogDI times the calls of the wrapped storage and logs the ones over the threshold
+type slowLogDI struct {
+	DI
+	db  ContextDI
+	log *SlowLog
+}
+
+// NewSlowLogDI returns db which logs the calls slower than the threshold of opts
+func NewSlowLogDI(db DI, opts SlowLogOptions) DI {
+	if opts.Size <= 0 {
+		opts.Size = DefaultSlowLogSize
+	}
+	cdb, ok := db.(ContextDI)
+	if !ok {
+		cdb = &contextDI{DI: db}
+	}
+	return &slowLogDI{DI: db, db: cdb, log: &SlowLog{opts: opts}}
+}
+
+// SlowLogOf returns the slow query log of db, nil is returned if db does not log the slow queries
+func SlowLogOf(db DI) *SlowLog {
+	for {
+		if s, ok := db.(*slowLogDI); ok {
+			return s.log
+		}
+		w, ok := db.(Wrapper)
+		if !ok {
+			return nil
+		}
+		db = w.Unwrap()
+	}
+}
+
+// Unwrap returns the wrapped storage
+func (s *slowLogDI) Unwrap() DI {
+	return s.DI
+}
+
+// observe logs the call if it took longer than the threshold, read tells the explainable calls
+func (s *slowLogDI) observe(start time.Time, operation, cName string, condiction interface{}, sort string, read bool, err error) {
+	cost := time.Since(start)
+	if cost < s.log.opts.Threshold {
+		return
+	}
+	entry := &SlowQuery{
+		Operation:  operation,
+		Collection: cName,
+		Condition:  Redact(condiction),
+		Sort:       sort,
+		Caller:     caller(),
+		StartAt:    start,
+		Cost:       int64(cost / time.Millisecond),
+	}
+	if nil != err {
+		entry.Error = err.Error()
+	}
+	redacted, _ := json.Marshal(entry.Condition)
+	blog.Warnf("slow query: %s %s cost %v, condition: %s, sort: %s, caller: %s", operation, cName, cost, redacted, sort, entry.Caller)
+	s.log.add(entry)
+
+	if !read || s.log.opts.ExplainSampleRate <= 0 || rand.Float64() >= s.log.opts.ExplainSampleRate {
+		return
+	}
+	explainer, ok := Unwrap(s.DI).(Explainer)
+	if !ok {
+		return
+	}
+	// the callers reuse the condiction once the call returns, the explain runs on a copy
+	cond, err := copyCondition(condiction)
+	if nil != err {
+		blog.Errorf("copy the condition of the slow query on %s failed, err: %v", cName, err)
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), explainTimeout)
+		defer cancel()
+		plan, err := explainer.ExplainCtx(ctx, cName, cond, sort)
+		if nil != err {
+			blog.Errorf("explain the slow query on %s failed, err: %v", cName, err)
+			return
+		}
+		s.log.setExplain(entry, plan)
+		out, _ := json.Marshal(plan)
+		blog.Infof("slow query plan: %s %s, caller: %s, plan: %s", operation, cName, entry.Caller, out)
+	}()
+}
+
+// copyCondition returns a deep copy of the condiction which shares nothing with it
+func copyCondition(condiction interface{}) (interface{}, error) {
+	if nil == condiction {
+		return nil, nil
+	}
+	data, err := bson.Marshal(condiction)
+	if nil != err {
+		return nil, err
+	}
+	cond := bson.M{}
+	if err := bson.Unmarshal(data, &cond); nil != err {
+		return nil, err
+	}
+	return cond, nil
+}
+
+// caller returns the first frame out of the storage packages
+func caller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "configcenter/src/storage") {
+			return fmt.Sprintf("%s:%d %s", frame.File, frame.Line, frame.Function)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// Redact returns the condiction with the field names and the operators kept and the values replaced by ?,
+// an array of values becomes [? x N]
+func Redact(condiction interface{}) interface{} {
+	if nil == condiction {
+		return nil
+	}
+	data, err := json.Marshal(condiction)
+	if nil != err {
+		return "?"
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); nil != err {
+		return "?"
+	}
+	return redact(doc)
+}
+
+func redact(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for key, item := range val {
+			out[key] = redact(item)
+		}
+		return out
+	case []interface{}:
+		docs := make([]interface{}, 0)
+		for _, item := range val {
+			if _, ok := item.(map[string]interface{}); ok {
+				docs = append(docs, redact(item))
+			}
+		}
+		if len(docs) == len(val) && 0 != len(val) {
+			// the condictions of $and, $or and $nor
+			return docs
+		}
+		return fmt.Sprintf("[? x %d]", len(val))
+	default:
+		return "?"
+	}
+}
+
+func (s *slowLogDI) GetIncID(cName string) (int64, error) {
+	return s.GetIncIDCtx(context.Background(), cName)
+}
+
+func (s *slowLogDI) GetIncIDCtx(ctx context.Context, cName string) (int64, error) {
+	start := time.Now()
+	id, err := s.db.GetIncIDCtx(ctx, cName)
+	s.observe(start, "GetIncID", cName, nil, "", false, err)
+	return id, err
+}
+
+func (s *slowLogDI) Insert(cName string, data interface{}) (int, error) {
+	return s.InsertCtx(context.Background(), cName, data)
+}
+
+func (s *slowLogDI) InsertCtx(ctx context.Context, cName string, data interface{}) (int, error) {
+	start := time.Now()
+	id, err := s.db.InsertCtx(ctx, cName, data)
+	s.observe(start, "Insert", cName, nil, "", false, err)
+	return id, err
+}
+
+func (s *slowLogDI) InsertMuti(cName string, data ...interface{}) error {
+	return s.InsertMutiCtx(context.Background(), cName, data...)
+}
+
+func (s *slowLogDI) InsertMutiCtx(ctx context.Context, cName string, data ...interface{}) error {
+	start := time.Now()
+	err := s.db.InsertMutiCtx(ctx, cName, data...)
+	s.observe(start, "InsertMuti", cName, nil, "", false, err)
+	return err
+}
+
+func (s *slowLogDI) UpdateByCondition(cName string, data, condiction interface{}) error {
+	return s.UpdateByConditionCtx(context.Background(), cName, data, condiction)
+}
+
+func (s *slowLogDI) UpdateByConditionCtx(ctx context.Context, cName string, data, condiction interface{}) error {
+	start := time.Now()
+	err := s.db.UpdateByConditionCtx(ctx, cName, data, condiction)
+	s.observe(start, "UpdateByCondition", cName, condiction, "", false, err)
+	return err
+}
+
+func (s *slowLogDI) GetOneByCondition(cName string, fields []string, condiction interface{}, result interface{}) error {
+	return s.GetOneByConditionCtx(context.Background(), cName, fields, condiction, result)
+}
+
+func (s *slowLogDI) GetOneByConditionCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}) error {
+	start := time.Now()
+	err := s.db.GetOneByConditionCtx(ctx, cName, fields, condiction, result)
+	s.observe(start, "GetOneByCondition", cName, condiction, "", true, err)
+	return err
+}
+
+func (s *slowLogDI) GetMutilByCondition(cName string, fields []string, condiction interface{}, result interface{}, sort string, start, limit int) error {
+	return s.GetMutilByConditionCtx(context.Background(), cName, fields, condiction, result, sort, start, limit)
+}
+
+func (s *slowLogDI) GetMutilByConditionCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}, sort string, start, limit int) error {
+	begin := time.Now()
+	err := s.db.GetMutilByConditionCtx(ctx, cName, fields, condiction, result, sort, start, limit)
+	s.observe(begin, "GetMutilByCondition", cName, condiction, sort, true, err)
+	return err
+}
+
+func (s *slowLogDI) GetMutilByCursor(cName string, fields []string, condiction interface{}, result interface{}, sort, cursor string, limit int) (string, error) {
+	return s.GetMutilByCursorCtx(context.Background(), cName, fields, condiction, result, sort, cursor, limit)
+}
+
+func (s *slowLogDI) GetMutilByCursorCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}, sort, cursor string, limit int) (string, error) {
+	start := time.Now()
+	next, err := s.db.GetMutilByCursorCtx(ctx, cName, fields, condiction, result, sort, cursor, limit)
+	s.observe(start, "GetMutilByCursor", cName, condiction, sort, true, err)
+	return next, err
+}
+
+func (s *slowLogDI) GetCntByCondition(cName string, condiction interface{}) (int, error) {
+	return s.GetCntByConditionCtx(context.Background(), cName, condiction)
+}
+
+func (s *slowLogDI) GetCntByConditionCtx(ctx context.Context, cName string, condiction interface{}) (int, error) {
+	start := time.Now()
+	cnt, err := s.db.GetCntByConditionCtx(ctx, cName, condiction)
+	s.observe(start, "GetCntByCondition", cName, condiction, "", true, err)
+	return cnt, err
+}
+
+func (s *slowLogDI) DelByCondition(cName string, condiction interface{}) error {
+	return s.DelByConditionCtx(context.Background(), cName, condiction)
+}
+
+func (s *slowLogDI) DelByConditionCtx(ctx context.Context, cName string, condiction interface{}) error {
+	start := time.Now()
+	err := s.db.DelByConditionCtx(ctx, cName, condiction)
+	s.observe(start, "DelByCondition", cName, condiction, "", false, err)
+	return err
+}
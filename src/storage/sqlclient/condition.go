@@ -0,0 +1,391 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlclient
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// builder translates the mongo style condictions into the SQL conditions, the arguments are
+// collected in the order of the placeholders
+type builder struct {
+	dialect Dialect
+	args    []interface{}
+}
+
+func newBuilder(dialect Dialect) *builder {
+	return &builder{dialect: dialect, args: make([]interface{}, 0)}
+}
+
+// bind adds the argument and returns its placeholder
+func (b *builder) bind(arg interface{}) string {
+	b.args = append(b.args, arg)
+	return b.dialect.Placeholder(len(b.args))
+}
+
+// bindValue adds the value compared with the fields and returns its expression
+func (b *builder) bindValue(v interface{}) (string, error) {
+	val, composite, err := bindValue(v)
+	if err != nil {
+		return "", err
+	}
+	expr, arg := b.dialect.Bind(len(b.args)+1, val, composite)
+	b.args = append(b.args, arg)
+	return expr, nil
+}
+
+// bindJSON adds the JSON text of the value and returns its JSON expression
+func (b *builder) bindJSON(v interface{}) (string, error) {
+	val, composite, err := bindValue(v)
+	if err != nil {
+		return "", err
+	}
+	b.args = append(b.args, jsonText(val, composite))
+	return b.dialect.JSONValue(len(b.args)), nil
+}
+
+// where returns the condition of the condiction
+func (b *builder) where(condiction interface{}) (string, error) {
+	doc, err := toDoc(condiction)
+	if err != nil {
+		return "", err
+	}
+	return b.and(doc)
+}
+
+// and returns the condition of all the fields of the document
+func (b *builder) and(doc bson.M) (string, error) {
+	if 0 == len(doc) {
+		return "1 = 1", nil
+	}
+	keys := make([]string, 0, len(doc))
+	for key := range doc {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	conds := make([]string, 0, len(keys))
+	for _, key := range keys {
+		var cond string
+		var err error
+		switch key {
+		case "$and":
+			cond, err = b.logic(doc[key], " AND ")
+		case "$or":
+			cond, err = b.logic(doc[key], " OR ")
+		case "$nor":
+			if cond, err = b.logic(doc[key], " OR "); nil == err {
+				cond = not(cond)
+			}
+		default:
+			if strings.HasPrefix(key, "$") {
+				return "", fmt.Errorf("unsupported operator %s", key)
+			}
+			cond, err = b.field(key, doc[key])
+		}
+		if err != nil {
+			return "", err
+		}
+		conds = append(conds, cond)
+	}
+	return "(" + strings.Join(conds, " AND ") + ")", nil
+}
+
+// logic joins the condiction documents of the array
+func (b *builder) logic(v interface{}, sep string) (string, error) {
+	items, ok := v.([]interface{})
+	if !ok || 0 == len(items) {
+		return "", errors.New("$and, $or and $nor need a nonempty array")
+	}
+	conds := make([]string, 0, len(items))
+	for _, item := range items {
+		doc, ok := item.(bson.M)
+		if !ok {
+			return "", errors.New("$and, $or and $nor need an array of documents")
+		}
+		cond, err := b.and(doc)
+		if err != nil {
+			return "", err
+		}
+		conds = append(conds, cond)
+	}
+	return "(" + strings.Join(conds, sep) + ")", nil
+}
+
+// operators returns the operator document of the field value, or nil if it is a value
+func operators(v interface{}) bson.M {
+	doc, ok := v.(bson.M)
+	if !ok || 0 == len(doc) {
+		return nil
+	}
+	for key := range doc {
+		if !strings.HasPrefix(key, "$") {
+			return nil
+		}
+	}
+	return doc
+}
+
+// field returns the condition of the field
+func (b *builder) field(name string, v interface{}) (string, error) {
+	var path []string
+	if rowIDField != name {
+		var err error
+		if path, err = checkPath(name); err != nil {
+			return "", err
+		}
+	}
+
+	ops := operators(v)
+	if nil == ops {
+		return b.eq(path, v)
+	}
+	keys := make([]string, 0, len(ops))
+	for key := range ops {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	conds := make([]string, 0, len(keys))
+	for _, op := range keys {
+		arg := ops[op]
+		var cond string
+		var err error
+		switch op {
+		case "$eq":
+			cond, err = b.eq(path, arg)
+		case "$ne":
+			if cond, err = b.eq(path, arg); nil == err {
+				cond = fmt.Sprintf("NOT %s", cond)
+				if nil != arg && nil != path {
+					// mongo takes the missing field as not equal
+					cond = fmt.Sprintf("(%s OR %s)", b.dialect.IsNull(path), cond)
+				}
+			}
+		case "$gt", "$gte", "$lt", "$lte":
+			cond, err = b.compare(path, op, arg)
+		case "$in":
+			cond, err = b.in(path, arg)
+		case "$all":
+			cond, err = b.all(path, arg)
+		case "$nin":
+			if cond, err = b.in(path, arg); nil == err {
+				cond = fmt.Sprintf("NOT %s", cond)
+				if nil != path && !hasNil(arg) {
+					cond = fmt.Sprintf("(%s OR %s)", b.dialect.IsNull(path), cond)
+				}
+			}
+		case "$exists":
+			cond = "1 = 1"
+			if nil != path {
+				cond = b.dialect.Exists(path)
+			}
+			if exists, _ := arg.(bool); !exists {
+				cond = fmt.Sprintf("NOT (%s)", cond)
+			}
+		case "$regex":
+			cond, err = b.regex(path, arg, ops["$options"])
+		case "$options":
+			if _, ok := ops["$regex"]; !ok {
+				err = errors.New("$options needs $regex")
+			}
+			continue
+		case "$not":
+			if cond, err = b.field(name, arg); nil == err {
+				cond = not(cond)
+			}
+		default:
+			err = fmt.Errorf("unsupported operator %s", op)
+		}
+		if err != nil {
+			return "", err
+		}
+		conds = append(conds, cond)
+	}
+	return "(" + strings.Join(conds, " AND ") + ")", nil
+}
+
+// not returns the negation of the condition, the condition of the missing fields is unknown in SQL
+// but false in mongo, so it is taken as false before the negation
+func not(cond string) string {
+	return fmt.Sprintf("(NOT COALESCE(%s, 1 = 0))", cond)
+}
+
+// rowID returns the row identity of the value
+func rowID(v interface{}) (int64, error) {
+	switch val := v.(type) {
+	case int:
+		return int64(val), nil
+	case int32:
+		return int64(val), nil
+	case int64:
+		return val, nil
+	case float64:
+		return int64(val), nil
+	}
+	return 0, fmt.Errorf("invalid row id %v", v)
+}
+
+// eq returns the condition of the field or any item of the array field equal to the value,
+// the nil path is the row identity
+func (b *builder) eq(path []string, v interface{}) (string, error) {
+	if nil == path {
+		id, err := rowID(v)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(id = %s)", b.bind(id)), nil
+	}
+	if nil == v {
+		return b.dialect.IsNull(path), nil
+	}
+	expr, err := b.bindValue(v)
+	if err != nil {
+		return "", err
+	}
+	item, err := b.bindValue(v)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("(%s = %s OR %s)", b.dialect.Field(path), expr, b.dialect.HasItem(path, item)), nil
+}
+
+// compare returns the condition of the comparison operator
+func (b *builder) compare(path []string, op string, v interface{}) (string, error) {
+	sign := map[string]string{"$gt": ">", "$gte": ">=", "$lt": "<", "$lte": "<="}[op]
+	if nil == path {
+		id, err := rowID(v)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(id %s %s)", sign, b.bind(id)), nil
+	}
+	if nil == v {
+		// nothing is greater or less than null
+		return "(1 = 0)", nil
+	}
+	expr, err := b.bindValue(v)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("(%s %s %s)", b.dialect.Field(path), sign, expr), nil
+}
+
+// in returns the condition of the field or any item of the array field equal to any value of the array
+func (b *builder) in(path []string, v interface{}) (string, error) {
+	items, ok := v.([]interface{})
+	if !ok {
+		return "", errors.New("$in and $nin need an array")
+	}
+	if 0 == len(items) {
+		return "(1 = 0)", nil
+	}
+	conds := make([]string, 0, 2)
+	exprs := make([]string, 0, len(items))
+	for _, item := range items {
+		if nil == item {
+			if nil != path {
+				conds = append(conds, b.dialect.IsNull(path))
+			}
+			continue
+		}
+		if nil == path {
+			id, err := rowID(item)
+			if err != nil {
+				return "", err
+			}
+			exprs = append(exprs, b.bind(id))
+			continue
+		}
+		expr, err := b.bindValue(item)
+		if err != nil {
+			return "", err
+		}
+		exprs = append(exprs, expr)
+	}
+	if 0 != len(exprs) {
+		field := "id"
+		if nil != path {
+			field = b.dialect.Field(path)
+		}
+		conds = append(conds, fmt.Sprintf("%s IN (%s)", field, strings.Join(exprs, ", ")))
+	}
+	// the items are bound again after the list, the placeholders of some dialects are positional
+	for _, item := range items {
+		if nil == item || nil == path {
+			continue
+		}
+		expr, err := b.bindValue(item)
+		if err != nil {
+			return "", err
+		}
+		conds = append(conds, b.dialect.HasItem(path, expr))
+	}
+	return "(" + strings.Join(conds, " OR ") + ")", nil
+}
+
+// all returns the condition of the field equal to every value of the array, which is an array
+// field having all the values as its items, the empty array matches nothing like mongo
+func (b *builder) all(path []string, v interface{}) (string, error) {
+	items, ok := v.([]interface{})
+	if !ok {
+		return "", errors.New("$all needs an array")
+	}
+	if 0 == len(items) {
+		return "(1 = 0)", nil
+	}
+	conds := make([]string, 0, len(items))
+	for _, item := range items {
+		cond, err := b.eq(path, item)
+		if err != nil {
+			return "", err
+		}
+		conds = append(conds, cond)
+	}
+	return "(" + strings.Join(conds, " AND ") + ")", nil
+}
+
+// hasNil returns true if the array has nil
+func hasNil(v interface{}) bool {
+	items, _ := v.([]interface{})
+	for _, item := range items {
+		if nil == item {
+			return true
+		}
+	}
+	return false
+}
+
+// regex returns the condition of the field matching the pattern
+func (b *builder) regex(path []string, v, options interface{}) (string, error) {
+	if nil == path {
+		return "", errors.New("$regex is not supported on _id")
+	}
+	pattern, ok := v.(string)
+	if !ok {
+		if re, isRegEx := v.(bson.RegEx); isRegEx {
+			pattern, options, ok = re.Pattern, re.Options, true
+		}
+	}
+	if !ok {
+		return "", errors.New("$regex needs a string")
+	}
+	opts, _ := options.(string)
+	insensitive := strings.Contains(opts, "i")
+	return "(" + b.dialect.Regex(b.dialect.Text(path), b.bind(pattern), insensitive) + ")", nil
+}
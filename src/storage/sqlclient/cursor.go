@@ -0,0 +1,170 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlclient
+
+import (
+	"configcenter/src/storage"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// sortKey a key of the order
+type sortKey struct {
+	field string
+	order int
+}
+
+// cursorToken the content of a cursor, the sort values of the last document of a page
+type cursorToken struct {
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+}
+
+// sortKeys returns the keys of the comma separated sort fields, "-" is the prefix of the descending order
+func sortKeys(sort string) []sortKey {
+	keys := make([]sortKey, 0)
+	for _, field := range strings.Split(sort, ",") {
+		field = strings.TrimSpace(field)
+		order := 1
+		if strings.HasPrefix(field, "-") {
+			order = -1
+		}
+		field = strings.TrimLeft(field, "+-")
+		if "" == field {
+			continue
+		}
+		keys = append(keys, sortKey{field: field, order: order})
+	}
+	return keys
+}
+
+// cursorKeys returns the keyset order of sort, _id is appended to break the ties
+func cursorKeys(sort string) []sortKey {
+	keys := sortKeys(sort)
+	for i, key := range keys {
+		if rowIDField == key.field {
+			// _id is unique, the keys after it never take effect
+			return keys[:i+1]
+		}
+	}
+	return append(keys, sortKey{field: rowIDField, order: 1})
+}
+
+// GetMutilByCursor get multiple document after the cursor
+func (s *SQLCli) GetMutilByCursor(cName string, fields []string, condiction interface{}, result interface{}, sort, cursor string, limit int) (string, error) {
+	return s.GetMutilByCursorCtx(context.Background(), cName, fields, condiction, result, sort, cursor, limit)
+}
+
+// GetMutilByCursorCtx get multiple document after the cursor, the documents are ordered by sort and then _id,
+// the documents missing the fields of sort are taken as null which is before any other value
+func (s *SQLCli) GetMutilByCursorCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}, sort, cursor string, limit int) (string, error) {
+	keys := cursorKeys(sort)
+	cond, err := cursorCondition(condiction, keys, cursor)
+	if err != nil {
+		return "", err
+	}
+	docs, err := s.find(ctx, cName, cond, keys, 0, limit)
+	if err != nil {
+		return "", err
+	}
+	next := ""
+	if 0 < limit && len(docs) == limit {
+		if next, err = encodeCursor(keys, docs[len(docs)-1]); err != nil {
+			return "", err
+		}
+	}
+	for i := range docs {
+		docs[i] = project(docs[i], fields)
+	}
+	return next, decodeDocs(docs, result)
+}
+
+// cursorCondition returns the condiction of the documents after the cursor
+func cursorCondition(condiction interface{}, keys []sortKey, cursor string) (interface{}, error) {
+	if nil == condiction {
+		condiction = bson.M{}
+	}
+	if "" == cursor {
+		return condiction, nil
+	}
+	values, err := decodeCursor(keys, cursor)
+	if err != nil {
+		return nil, err
+	}
+	// (k1 > v1) or (k1 = v1 and k2 > v2) or ..., the missing or null value is the least the same as mongo
+	after := make([]interface{}, 0, len(keys))
+	for i, key := range keys {
+		branch := bson.M{}
+		for j := 0; j < i; j++ {
+			branch[keys[j].field] = values[j]
+		}
+		switch {
+		case key.order > 0 && nil == values[i]:
+			branch[key.field] = bson.M{"$ne": nil}
+		case key.order > 0:
+			branch[key.field] = bson.M{"$gt": values[i]}
+		case nil == values[i]:
+			// nothing is after the null in the descending order
+			continue
+		default:
+			branch["$or"] = []interface{}{bson.M{key.field: bson.M{"$lt": values[i]}}, bson.M{key.field: nil}}
+		}
+		after = append(after, branch)
+	}
+	return bson.M{"$and": []interface{}{condiction, bson.M{"$or": after}}}, nil
+}
+
+func sortText(keys []sortKey) string {
+	fields := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key.order < 0 {
+			fields = append(fields, "-"+key.field)
+		} else {
+			fields = append(fields, key.field)
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+func encodeCursor(keys []sortKey, doc bson.M) (string, error) {
+	token := cursorToken{Sort: sortText(keys), Values: make([]interface{}, 0, len(keys))}
+	for _, key := range keys {
+		val, _ := lookup(doc, strings.Split(key.field, "."))
+		token.Values = append(token.Values, encodeValue(val))
+	}
+	data, err := json.Marshal(&token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(keys []sortKey, cursor string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, storage.ErrInvalidCursor
+	}
+	doc, err := decodeDoc(data)
+	if err != nil {
+		return nil, storage.ErrInvalidCursor
+	}
+	values, ok := doc["v"].([]interface{})
+	if doc["s"] != sortText(keys) || !ok || len(values) != len(keys) {
+		return nil, storage.ErrInvalidCursor
+	}
+	return values, nil
+}
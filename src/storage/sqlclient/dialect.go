@@ -0,0 +1,98 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlclient
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Dialect define the SQL differences of the relational databases, the documents are kept
+// as JSON in the doc column, a path is the dotted field name split into the names
+type Dialect interface {
+	// Name returns the storage type of the dialect
+	Name() string
+	// Open opens the database
+	Open(host, port, usr, pwd, option, database string) (*sql.DB, error)
+	// Quote quotes the identifier
+	Quote(ident string) string
+	// Placeholder returns the placeholder of the nth argument which starts from 1
+	Placeholder(n int) string
+	// CreateTable returns the statement which creates the document table if it does not exist
+	CreateTable(table string) string
+	// HasTable returns the query which counts the table of the name given as the first argument
+	HasTable() string
+	// CreateIndex returns the statement which creates the index on the expressions
+	CreateIndex(name, table string, exprs []string, unique bool) string
+	// IsIndexExist returns true if err means the index is already created
+	IsIndexExist(err error) bool
+	// Limit returns the limit clause, limit is not positive for no limit
+	Limit(start, limit int) string
+	// NextSeq increases the sequence of name by n and returns the new value
+	NextSeq(ctx context.Context, db *sql.DB, table, name string, n int64) (int64, error)
+	// CreateSeqTable returns the statement which creates the sequence table if it does not exist
+	CreateSeqTable(table string) string
+	// InsertDoc inserts the JSON text of the document and returns the row identity given by the database
+	InsertDoc(ctx context.Context, tx *sql.Tx, table, text string) (int64, error)
+
+	// Field returns the expression of the field which is compared with Bind
+	Field(path []string) string
+	// Bind returns the expression and the argument of the value compared with Field,
+	// value is a scalar of the JSON text of a composite value
+	Bind(n int, value interface{}, composite bool) (string, interface{})
+	// Text returns the expression of the field as the text
+	Text(path []string) string
+	// Regex returns the condition of the text matching the pattern
+	Regex(text, pattern string, insensitive bool) string
+	// Exists returns the condition of the field existing
+	Exists(path []string) string
+	// IsNull returns the condition of the field missing or null
+	IsNull(path []string) string
+	// HasItem returns the condition of the field being an array with an item equal to the value
+	// expression given by Bind
+	HasItem(path []string, value string) string
+	// IndexExpr returns the index expression of the field
+	IndexExpr(path []string) string
+
+	// JSONField returns the JSON expression of the field which is set to another field
+	JSONField(path []string) string
+	// JSONValue returns the JSON expression of the JSON text argument
+	JSONValue(n int) string
+	// Set returns the document expression with the field set to the JSON value
+	Set(doc string, path []string, value string) string
+	// Remove returns the document expression with the field removed
+	Remove(doc string, path []string) string
+}
+
+// jsonPath returns the path of the JSON functions of sqlite and mysql, the names are checked by checkPath
+func jsonPath(path []string) string {
+	return `'$."` + strings.Join(path, `"."`) + `"'`
+}
+
+// pgPath returns the text array path of postgres, the names are checked by checkPath
+func pgPath(path []string) string {
+	return "'{" + strings.Join(path, ",") + "}'"
+}
+
+// quote quotes the identifier by the quotation mark
+func quote(ident, mark string) string {
+	return mark + strings.Replace(ident, mark, mark+mark, -1) + mark
+}
+
+// indexName returns the name of the index on the table, the index names are unique in the
+// whole database of some dialects
+func indexName(table, name string) string {
+	return fmt.Sprintf("%s_%s", table, name)
+}
@@ -0,0 +1,236 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// timeLayout the layout of the times kept in the documents, it is fixed width in UTC so that
+// the times compare as the texts, the texts of the layout are read back as the times
+const timeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// rowIDField the field of the row identity in the conditions and the snapshots, the same as mongo
+const rowIDField = "_id"
+
+// toDoc converts the map or the struct into the document of the bson field names, the values are
+// normalized in the same way as they are written to mongo
+func toDoc(v interface{}) (bson.M, error) {
+	doc := bson.M{}
+	if nil == v {
+		return doc, nil
+	}
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// checkPath splits the dotted field name, the names of a path are limited to the letters, the digits,
+// '_' and '-' so that they are safe in the JSON paths of all the dialects
+func checkPath(field string) ([]string, error) {
+	path := strings.Split(field, ".")
+	for _, name := range path {
+		if "" == name {
+			return nil, fmt.Errorf("invalid field name %q", field)
+		}
+		for _, r := range name {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && '_' != r && '-' != r {
+				return nil, fmt.Errorf("invalid field name %q", field)
+			}
+		}
+	}
+	return path, nil
+}
+
+// encodeValue converts the value into the one kept in the JSON document
+func encodeValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case time.Time:
+		return val.UTC().Format(timeLayout)
+	case bson.ObjectId:
+		return val.Hex()
+	case bson.M:
+		doc := make(map[string]interface{}, len(val))
+		for key, item := range val {
+			doc[key] = encodeValue(item)
+		}
+		return doc
+	case map[string]interface{}:
+		return encodeValue(bson.M(val))
+	case []interface{}:
+		arr := make([]interface{}, 0, len(val))
+		for _, item := range val {
+			arr = append(arr, encodeValue(item))
+		}
+		return arr
+	}
+	return v
+}
+
+// encodeDoc returns the JSON text of the document
+func encodeDoc(doc bson.M) (string, error) {
+	data, err := json.Marshal(encodeValue(doc))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// decodeDoc reads the JSON text of the document
+func decodeDoc(data []byte) (bson.M, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var doc map[string]interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return decodeValue(doc).(bson.M), nil
+}
+
+// decodeValue converts the JSON value into the one read from mongo
+func decodeValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		if n, err := val.Int64(); nil == err {
+			if math.MinInt32 <= n && n <= math.MaxInt32 {
+				return int(n)
+			}
+			return n
+		}
+		f, _ := val.Float64()
+		return f
+	case string:
+		if len(val) == len(timeLayout) {
+			if t, err := time.Parse(timeLayout, val); nil == err {
+				return t.Local()
+			}
+		}
+		return val
+	case map[string]interface{}:
+		doc := make(bson.M, len(val))
+		for key, item := range val {
+			doc[key] = decodeValue(item)
+		}
+		return doc
+	case []interface{}:
+		for i, item := range val {
+			val[i] = decodeValue(item)
+		}
+		return val
+	}
+	return v
+}
+
+// jsonText returns the JSON text of the scalar, the composite value is already the JSON text
+func jsonText(value interface{}, composite bool) interface{} {
+	if composite {
+		return value
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// bindValue returns the value compared with the fields, the composite values are given as the JSON text
+func bindValue(v interface{}) (interface{}, bool, error) {
+	v = encodeValue(v)
+	switch val := v.(type) {
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(val)
+		if err != nil {
+			return nil, false, err
+		}
+		return string(data), true, nil
+	case int:
+		return int64(val), false, nil
+	case int32:
+		return int64(val), false, nil
+	}
+	return v, false, nil
+}
+
+// project returns the document of the fields, all the fields are returned if there is not any field,
+// _id is never returned the same as GetMutilByCondition of mongo
+func project(doc bson.M, fields []string) bson.M {
+	wanted := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if "" != field && rowIDField != field {
+			wanted = append(wanted, field)
+		}
+	}
+	if 0 == len(wanted) {
+		delete(doc, rowIDField)
+		return doc
+	}
+	result := bson.M{}
+	for _, field := range wanted {
+		path := strings.Split(field, ".")
+		val, ok := lookup(doc, path)
+		if !ok {
+			continue
+		}
+		parent := result
+		for _, name := range path[:len(path)-1] {
+			child, ok := parent[name].(bson.M)
+			if !ok {
+				child = bson.M{}
+				parent[name] = child
+			}
+			parent = child
+		}
+		parent[path[len(path)-1]] = val
+	}
+	return result
+}
+
+// lookup returns the value of the field of the path
+func lookup(doc bson.M, path []string) (interface{}, bool) {
+	var val interface{} = doc
+	for _, name := range path {
+		sub, ok := val.(bson.M)
+		if !ok {
+			return nil, false
+		}
+		if val, ok = sub[name]; !ok {
+			return nil, false
+		}
+	}
+	return val, true
+}
+
+// decodeDocs decodes the documents into result as an array
+func decodeDocs(docs []bson.M, result interface{}) error {
+	data, err := bson.Marshal(bson.M{"docs": docs})
+	if err != nil {
+		return err
+	}
+	wrapper := struct {
+		Docs bson.Raw `bson:"docs"`
+	}{}
+	if err := bson.Unmarshal(data, &wrapper); err != nil {
+		return err
+	}
+	return wrapper.Docs.Unmarshal(result)
+}
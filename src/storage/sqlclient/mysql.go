@@ -0,0 +1,163 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlclient
+
+import (
+	"configcenter/src/storage"
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// mysqlDupKeyName the error number of creating an index whose name is taken
+const mysqlDupKeyName = 1061
+
+// MySQL the dialect of mysql 8.0.13 and later, which supports the functional indexes
+type MySQL struct{}
+
+func (MySQL) Name() string {
+	return storage.DI_MYSQL
+}
+
+func (MySQL) Open(host, port, usr, pwd, option, database string) (*sql.DB, error) {
+	cfg := mysql.NewConfig()
+	cfg.User = usr
+	cfg.Passwd = pwd
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(host, port)
+	cfg.DBName = database
+	cfg.Params = map[string]string{"charset": "utf8mb4"}
+	return sql.Open("mysql", cfg.FormatDSN())
+}
+
+func (MySQL) Quote(ident string) string {
+	return quote(ident, "`")
+}
+
+func (MySQL) Placeholder(n int) string {
+	return "?"
+}
+
+func (d MySQL) CreateTable(table string) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY, doc JSON NOT NULL)", d.Quote(table))
+}
+
+func (MySQL) HasTable() string {
+	return "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?"
+}
+
+func (d MySQL) CreateIndex(name, table string, exprs []string, unique bool) string {
+	stmt := "CREATE INDEX %s ON %s (%s)"
+	if unique {
+		stmt = "CREATE UNIQUE INDEX %s ON %s (%s)"
+	}
+	return fmt.Sprintf(stmt, d.Quote(name), d.Quote(table), strings.Join(exprs, ", "))
+}
+
+func (MySQL) IsIndexExist(err error) bool {
+	myErr, ok := err.(*mysql.MySQLError)
+	return ok && mysqlDupKeyName == myErr.Number
+}
+
+func (MySQL) Limit(start, limit int) string {
+	if limit <= 0 {
+		if start <= 0 {
+			return ""
+		}
+		// mysql does not take the offset without the limit
+		return fmt.Sprintf(" LIMIT %d, 18446744073709551615", start)
+	}
+	return fmt.Sprintf(" LIMIT %d, %d", start, limit)
+}
+
+func (d MySQL) CreateSeqTable(table string) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (name VARCHAR(255) NOT NULL PRIMARY KEY, seq BIGINT NOT NULL)", d.Quote(table))
+}
+
+func (d MySQL) NextSeq(ctx context.Context, db *sql.DB, table, name string, n int64) (int64, error) {
+	// the value given to LAST_INSERT_ID is returned as the last insert id of the statement
+	stmt := fmt.Sprintf("INSERT INTO %s (name, seq) VALUES (?, LAST_INSERT_ID(?)) ON DUPLICATE KEY UPDATE seq = LAST_INSERT_ID(seq + ?)", d.Quote(table))
+	result, err := db.ExecContext(ctx, stmt, name, n, n)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (d MySQL) InsertDoc(ctx context.Context, tx *sql.Tx, table, text string) (int64, error) {
+	stmt := fmt.Sprintf("INSERT INTO %s (doc) VALUES (%s)", d.Quote(table), d.JSONValue(1))
+	result, err := tx.ExecContext(ctx, stmt, text)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (MySQL) Field(path []string) string {
+	return fmt.Sprintf("JSON_EXTRACT(doc, %s)", jsonPath(path))
+}
+
+func (MySQL) Bind(n int, value interface{}, composite bool) (string, interface{}) {
+	// the JSON values compare by the JSON rules, so the scalars are bound as JSON too
+	return "CAST(? AS JSON)", jsonText(value, composite)
+}
+
+func (d MySQL) Text(path []string) string {
+	return fmt.Sprintf("JSON_UNQUOTE(%s)", d.Field(path))
+}
+
+func (MySQL) Regex(text, pattern string, insensitive bool) string {
+	if insensitive {
+		return fmt.Sprintf("REGEXP_LIKE(%s, %s, 'i')", text, pattern)
+	}
+	return fmt.Sprintf("REGEXP_LIKE(%s, %s, 'c')", text, pattern)
+}
+
+func (MySQL) Exists(path []string) string {
+	return fmt.Sprintf("JSON_CONTAINS_PATH(doc, 'one', %s)", jsonPath(path))
+}
+
+func (d MySQL) IsNull(path []string) string {
+	field := d.Field(path)
+	return fmt.Sprintf("(%s IS NULL OR JSON_TYPE(%s) = 'NULL')", field, field)
+}
+
+func (d MySQL) HasItem(path []string, value string) string {
+	field := d.Field(path)
+	return fmt.Sprintf("(JSON_TYPE(%s) = 'ARRAY' AND %s MEMBER OF(%s))", field, value, field)
+}
+
+func (d MySQL) IndexExpr(path []string) string {
+	// the JSON values are not indexable, the index takes their text
+	return fmt.Sprintf("(CAST(%s AS CHAR(255)))", d.Field(path))
+}
+
+func (d MySQL) JSONField(path []string) string {
+	return d.Field(path)
+}
+
+func (MySQL) JSONValue(n int) string {
+	return "CAST(? AS JSON)"
+}
+
+func (MySQL) Set(doc string, path []string, value string) string {
+	return fmt.Sprintf("JSON_SET(%s, %s, %s)", doc, jsonPath(path), value)
+}
+
+func (MySQL) Remove(doc string, path []string) string {
+	return fmt.Sprintf("JSON_REMOVE(%s, %s)", doc, jsonPath(path))
+}
@@ -0,0 +1,156 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlclient
+
+import (
+	"configcenter/src/storage"
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	_ "github.com/lib/pq"
+)
+
+// Postgres the dialect of postgres 9.5 and later
+type Postgres struct{}
+
+func (Postgres) Name() string {
+	return storage.DI_POSTGRES
+}
+
+// Open opens the database, option is the sslmode which is disable by default
+func (Postgres) Open(host, port, usr, pwd, option, database string) (*sql.DB, error) {
+	if "" == option {
+		option = "disable"
+	}
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(usr, pwd),
+		Host:     net.JoinHostPort(host, port),
+		Path:     "/" + database,
+		RawQuery: url.Values{"sslmode": []string{option}}.Encode(),
+	}
+	return sql.Open("postgres", dsn.String())
+}
+
+func (Postgres) Quote(ident string) string {
+	return quote(ident, `"`)
+}
+
+func (Postgres) Placeholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+func (d Postgres) CreateTable(table string) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id BIGSERIAL PRIMARY KEY, doc JSONB NOT NULL)", d.Quote(table))
+}
+
+func (Postgres) HasTable() string {
+	return "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = $1"
+}
+
+func (d Postgres) CreateIndex(name, table string, exprs []string, unique bool) string {
+	stmt := "CREATE INDEX IF NOT EXISTS %s ON %s (%s)"
+	if unique {
+		stmt = "CREATE UNIQUE INDEX IF NOT EXISTS %s ON %s (%s)"
+	}
+	return fmt.Sprintf(stmt, d.Quote(name), d.Quote(table), strings.Join(exprs, ", "))
+}
+
+func (Postgres) IsIndexExist(err error) bool {
+	return false
+}
+
+func (Postgres) Limit(start, limit int) string {
+	if limit <= 0 {
+		return fmt.Sprintf(" OFFSET %d", start)
+	}
+	return fmt.Sprintf(" LIMIT %d OFFSET %d", limit, start)
+}
+
+func (d Postgres) CreateSeqTable(table string) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (name VARCHAR(255) PRIMARY KEY, seq BIGINT NOT NULL)", d.Quote(table))
+}
+
+func (d Postgres) NextSeq(ctx context.Context, db *sql.DB, table, name string, n int64) (int64, error) {
+	stmt := fmt.Sprintf("INSERT INTO %s AS t (name, seq) VALUES ($1, $2) ON CONFLICT (name) DO UPDATE SET seq = t.seq + excluded.seq RETURNING seq", d.Quote(table))
+	var seq int64
+	err := db.QueryRowContext(ctx, stmt, name, n).Scan(&seq)
+	return seq, err
+}
+
+func (d Postgres) InsertDoc(ctx context.Context, tx *sql.Tx, table, text string) (int64, error) {
+	stmt := fmt.Sprintf("INSERT INTO %s (doc) VALUES (%s) RETURNING id", d.Quote(table), d.JSONValue(1))
+	var id int64
+	err := tx.QueryRowContext(ctx, stmt, text).Scan(&id)
+	return id, err
+}
+
+func (Postgres) Field(path []string) string {
+	return fmt.Sprintf("(doc #> %s)", pgPath(path))
+}
+
+func (d Postgres) Bind(n int, value interface{}, composite bool) (string, interface{}) {
+	// the jsonb values compare by the jsonb rules, so the scalars are bound as jsonb too
+	return d.JSONValue(n), jsonText(value, composite)
+}
+
+func (Postgres) Text(path []string) string {
+	return fmt.Sprintf("(doc #>> %s)", pgPath(path))
+}
+
+func (Postgres) Regex(text, pattern string, insensitive bool) string {
+	if insensitive {
+		return fmt.Sprintf("%s ~* %s", text, pattern)
+	}
+	return fmt.Sprintf("%s ~ %s", text, pattern)
+}
+
+func (d Postgres) Exists(path []string) string {
+	return fmt.Sprintf("%s IS NOT NULL", d.Field(path))
+}
+
+func (d Postgres) IsNull(path []string) string {
+	field := d.Field(path)
+	return fmt.Sprintf("(%s IS NULL OR jsonb_typeof(%s) = 'null')", field, field)
+}
+
+// HasItem checks the type first, jsonb_array_elements fails on the other types
+func (d Postgres) HasItem(path []string, value string) string {
+	field := d.Field(path)
+	return fmt.Sprintf("(CASE WHEN jsonb_typeof(%s) = 'array' THEN EXISTS (SELECT 1 FROM jsonb_array_elements(%s) AS item(value) WHERE item.value = %s) ELSE false END)", field, field, value)
+}
+
+func (d Postgres) IndexExpr(path []string) string {
+	return d.Field(path)
+}
+
+func (d Postgres) JSONField(path []string) string {
+	return d.Field(path)
+}
+
+func (d Postgres) JSONValue(n int) string {
+	return fmt.Sprintf("%s::jsonb", d.Placeholder(n))
+}
+
+// Set sets the field, the parents of the field must exist
+func (Postgres) Set(doc string, path []string, value string) string {
+	return fmt.Sprintf("jsonb_set(%s, %s, %s, true)", doc, pgPath(path), value)
+}
+
+func (Postgres) Remove(doc string, path []string) string {
+	return fmt.Sprintf("(%s #- %s)", doc, pgPath(path))
+}
@@ -0,0 +1,93 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlclient
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// SnapshotCtx returns the documents which match the condiction, _id is the row identity
+func (s *SQLCli) SnapshotCtx(ctx context.Context, cName string, condiction interface{}) ([]map[string]interface{}, error) {
+	docs, err := s.find(ctx, cName, condiction, nil, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]map[string]interface{}, 0, len(docs))
+	for _, doc := range docs {
+		result = append(result, doc)
+	}
+	return result, nil
+}
+
+// RowID returns the _id of the document
+func (s *SQLCli) RowID(doc map[string]interface{}) interface{} {
+	return doc[rowIDField]
+}
+
+// ClaimCtx sets data on the document which matches condiction, the update statement checks
+// the condiction and writes the document at once
+func (s *SQLCli) ClaimCtx(ctx context.Context, cName string, condiction, data interface{}) (bool, error) {
+	cnt, err := s.update(ctx, cName, data, condiction)
+	return 0 < cnt, err
+}
+
+// RestoreCtx removes the rows of ids and inserts the documents back with their row identities
+func (s *SQLCli) RestoreCtx(ctx context.Context, cName string, ids []interface{}, docs []map[string]interface{}) error {
+	if err := s.ensureTable(ctx, cName); err != nil {
+		return err
+	}
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if 0 != len(ids) {
+			b := newBuilder(s.dialect)
+			holders := make([]string, 0, len(ids))
+			for _, id := range ids {
+				rid, err := rowID(id)
+				if err != nil {
+					return err
+				}
+				holders = append(holders, b.bind(rid))
+			}
+			stmt := fmt.Sprintf("DELETE FROM %s WHERE id IN (%s)", s.dialect.Quote(cName), strings.Join(holders, ", "))
+			if _, err := tx.ExecContext(ctx, stmt, b.args...); err != nil {
+				return err
+			}
+		}
+		stmt := fmt.Sprintf("INSERT INTO %s (id, doc) VALUES (%s, %s)",
+			s.dialect.Quote(cName), s.dialect.Placeholder(1), s.dialect.JSONValue(2))
+		for _, doc := range docs {
+			rid, err := rowID(doc[rowIDField])
+			if err != nil {
+				return err
+			}
+			raw := bson.M{}
+			for key, val := range doc {
+				if rowIDField != key {
+					raw[key] = val
+				}
+			}
+			text, err := encodeDoc(raw)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, stmt, rid, text); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
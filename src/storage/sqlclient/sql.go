@@ -0,0 +1,577 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlclient
+
+import (
+	"configcenter/src/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// seqTable the table of the sequences returned by GetIncID, the same name as mongo
+const seqTable = "cc_idgenerator"
+
+// purgeInterval the interval of removing the expired documents of the tables with the ttl index
+const purgeInterval = time.Minute
+
+// errNotFound the same text as mgo.ErrNotFound, the callers compare the text
+var errNotFound = errors.New("not found")
+
+// ttl the expire setting of a table
+type ttl struct {
+	path      []string
+	after     time.Duration
+	lastPurge time.Time
+}
+
+// SQLCli keeps the documents in the relational databases, every collection is a table of the row
+// identity and the JSON document, the table is created by the first write
+type SQLCli struct {
+	dialect Dialect
+	host    string
+	port    string
+	usr     string
+	pwd     string
+	option  string
+	dbName  string
+	db      *sql.DB
+
+	tables sync.Map
+
+	ttlLock sync.Mutex
+	ttls    map[string]*ttl
+}
+
+var (
+	_ storage.ContextDI   = (*SQLCli)(nil)
+	_ storage.Snapshotter = (*SQLCli)(nil)
+)
+
+// NewSQLCli returns the client of the database by the dialect, option is the dialect special connection option
+func NewSQLCli(dialect Dialect, host, port, usr, pwd, option, database string) (*SQLCli, error) {
+	if nil == dialect {
+		return nil, errors.New("the dialect is required")
+	}
+	return &SQLCli{
+		dialect: dialect,
+		host:    host,
+		port:    port,
+		usr:     usr,
+		pwd:     pwd,
+		option:  option,
+		dbName:  database,
+		ttls:    make(map[string]*ttl),
+	}, nil
+}
+
+// Open open the connection
+func (s *SQLCli) Open() error {
+	db, err := s.dialect.Open(s.host, s.port, s.usr, s.pwd, s.option, s.dbName)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return err
+	}
+	if _, err := db.ExecContext(ctx, s.dialect.CreateSeqTable(seqTable)); err != nil {
+		db.Close()
+		return err
+	}
+	s.db = db
+	return nil
+}
+
+// GetSession returns the *sql.DB
+func (s *SQLCli) GetSession() interface{} {
+	return s.db
+}
+
+// Close close the database
+func (s *SQLCli) Close() {
+	if s.db != nil {
+		s.db.Close()
+	}
+}
+
+// GetType returns the storage type of the dialect
+func (s *SQLCli) GetType() string {
+	return s.dialect.Name()
+}
+
+// ensureTable creates the table if it does not exist
+func (s *SQLCli) ensureTable(ctx context.Context, cName string) error {
+	if _, ok := s.tables.Load(cName); ok {
+		return nil
+	}
+	if _, err := s.db.ExecContext(ctx, s.dialect.CreateTable(cName)); err != nil {
+		return err
+	}
+	s.tables.Store(cName, true)
+	return nil
+}
+
+// tableExists returns false if the table does not exist, the reads of the table return nothing
+func (s *SQLCli) tableExists(ctx context.Context, cName string) (bool, error) {
+	if _, ok := s.tables.Load(cName); ok {
+		return true, nil
+	}
+	cnt := 0
+	if err := s.db.QueryRowContext(ctx, s.dialect.HasTable(), cName).Scan(&cnt); err != nil {
+		return false, err
+	}
+	if 0 < cnt {
+		s.tables.Store(cName, true)
+	}
+	return 0 < cnt, nil
+}
+
+// Insert insert one document
+func (s *SQLCli) Insert(cName string, data interface{}) (int, error) {
+	return s.InsertCtx(context.Background(), cName, data)
+}
+
+// InsertCtx insert one document
+func (s *SQLCli) InsertCtx(ctx context.Context, cName string, data interface{}) (int, error) {
+	if err := s.InsertMutiCtx(ctx, cName, data); err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
+
+// InsertMuti insert muti documents
+func (s *SQLCli) InsertMuti(cName string, data ...interface{}) error {
+	return s.InsertMutiCtx(context.Background(), cName, data...)
+}
+
+// InsertMutiCtx insert muti documents, none of the documents is inserted if any one fails
+func (s *SQLCli) InsertMutiCtx(ctx context.Context, cName string, data ...interface{}) error {
+	_, err := s.InsertRowsCtx(ctx, cName, data...)
+	return err
+}
+
+// InsertRowsCtx inserts the documents like InsertMutiCtx and returns their row identities
+func (s *SQLCli) InsertRowsCtx(ctx context.Context, cName string, data ...interface{}) ([]interface{}, error) {
+	if err := s.ensureTable(ctx, cName); err != nil {
+		return nil, err
+	}
+	s.purgeExpired(ctx, cName)
+	texts := make([]string, 0, len(data))
+	for _, item := range data {
+		doc, err := toDoc(item)
+		if err != nil {
+			return nil, err
+		}
+		storage.EscapeHtml(doc)
+		// the row identity is given by the database
+		delete(doc, rowIDField)
+		text, err := encodeDoc(doc)
+		if err != nil {
+			return nil, err
+		}
+		texts = append(texts, text)
+	}
+	ids := make([]interface{}, 0, len(texts))
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, text := range texts {
+			id, err := s.dialect.InsertDoc(ctx, tx, cName, text)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// inTx runs fn in a database transaction
+func (s *SQLCli) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateByCondition update documents by condiction
+func (s *SQLCli) UpdateByCondition(cName string, data, condiction interface{}) error {
+	return s.UpdateByConditionCtx(context.Background(), cName, data, condiction)
+}
+
+// UpdateByConditionCtx sets the fields of data on the documents which match the condiction
+func (s *SQLCli) UpdateByConditionCtx(ctx context.Context, cName string, data, condiction interface{}) error {
+	_, err := s.update(ctx, cName, data, condiction)
+	return err
+}
+
+// update sets data on the documents which match the condiction and returns the number of them
+func (s *SQLCli) update(ctx context.Context, cName string, data, condiction interface{}) (int64, error) {
+	doc, err := toDoc(data)
+	if err != nil {
+		return 0, err
+	}
+	storage.EscapeHtml(doc)
+	delete(doc, rowIDField)
+	if 0 == len(doc) {
+		return 0, nil
+	}
+	if exists, err := s.tableExists(ctx, cName); err != nil || !exists {
+		return 0, err
+	}
+	keys := make([]string, 0, len(doc))
+	for key := range doc {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	b := newBuilder(s.dialect)
+	expr := "doc"
+	for _, key := range keys {
+		path, err := checkPath(key)
+		if err != nil {
+			return 0, err
+		}
+		value, err := b.bindJSON(doc[key])
+		if err != nil {
+			return 0, err
+		}
+		expr = s.dialect.Set(expr, path, value)
+	}
+	where, err := b.where(condiction)
+	if err != nil {
+		return 0, err
+	}
+	stmt := fmt.Sprintf("UPDATE %s SET doc = %s WHERE %s", s.dialect.Quote(cName), expr, where)
+	result, err := s.db.ExecContext(ctx, stmt, b.args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetOneByCondition get one document by condiction
+func (s *SQLCli) GetOneByCondition(cName string, fields []string, condiction interface{}, result interface{}) error {
+	return s.GetOneByConditionCtx(context.Background(), cName, fields, condiction, result)
+}
+
+// GetOneByConditionCtx get one document by condiction, the error text is "not found" if there is not any
+func (s *SQLCli) GetOneByConditionCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}) error {
+	docs, err := s.find(ctx, cName, condiction, nil, 0, 1)
+	if err != nil {
+		return err
+	}
+	if 0 == len(docs) {
+		return errNotFound
+	}
+	data, err := bson.Marshal(project(docs[0], fields))
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(data, result)
+}
+
+// GetMutilByCondition get multiple document by condiction
+func (s *SQLCli) GetMutilByCondition(cName string, fields []string, condiction interface{}, result interface{}, sort string, start, limit int) error {
+	return s.GetMutilByConditionCtx(context.Background(), cName, fields, condiction, result, sort, start, limit)
+}
+
+// GetMutilByConditionCtx get multiple document by condiction, the documents of the same sort values
+// are in the order of the insertion
+func (s *SQLCli) GetMutilByConditionCtx(ctx context.Context, cName string, fields []string, condiction interface{}, result interface{}, sort string, start, limit int) error {
+	docs, err := s.find(ctx, cName, condiction, sortKeys(sort), start, limit)
+	if err != nil {
+		return err
+	}
+	for i := range docs {
+		docs[i] = project(docs[i], fields)
+	}
+	return decodeDocs(docs, result)
+}
+
+// find returns the documents of the condiction, _id is set to the row identity
+func (s *SQLCli) find(ctx context.Context, cName string, condiction interface{}, keys []sortKey, start, limit int) ([]bson.M, error) {
+	b := newBuilder(s.dialect)
+	where, err := b.where(condiction)
+	if err != nil {
+		return nil, err
+	}
+	orders := make([]string, 0, 2*len(keys)+1)
+	for _, key := range keys {
+		dir := " ASC"
+		if key.order < 0 {
+			dir = " DESC"
+		}
+		if rowIDField == key.field {
+			orders = append(orders, "id"+dir)
+			continue
+		}
+		path, err := checkPath(key.field)
+		if err != nil {
+			return nil, err
+		}
+		// the null is the least the same as mongo, the databases differ in the default null order
+		nullDir := " DESC"
+		if key.order < 0 {
+			nullDir = " ASC"
+		}
+		orders = append(orders, "("+s.dialect.IsNull(path)+")"+nullDir, s.dialect.Field(path)+dir)
+	}
+	orders = append(orders, "id ASC")
+
+	if exists, err := s.tableExists(ctx, cName); err != nil || !exists {
+		return []bson.M{}, err
+	}
+	stmt := fmt.Sprintf("SELECT id, doc FROM %s WHERE %s ORDER BY %s%s",
+		s.dialect.Quote(cName), where, strings.Join(orders, ", "), s.dialect.Limit(start, limit))
+	rows, err := s.db.QueryContext(ctx, stmt, b.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	docs := make([]bson.M, 0)
+	for rows.Next() {
+		var id int64
+		var data []byte
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		doc, err := decodeDoc(data)
+		if err != nil {
+			return nil, err
+		}
+		doc[rowIDField] = id
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
+
+// GetCntByCondition returns count number filter by condiction
+func (s *SQLCli) GetCntByCondition(cName string, condiction interface{}) (int, error) {
+	return s.GetCntByConditionCtx(context.Background(), cName, condiction)
+}
+
+// GetCntByConditionCtx returns count number filter by condiction
+func (s *SQLCli) GetCntByConditionCtx(ctx context.Context, cName string, condiction interface{}) (int, error) {
+	b := newBuilder(s.dialect)
+	where, err := b.where(condiction)
+	if err != nil {
+		return 0, err
+	}
+	if exists, err := s.tableExists(ctx, cName); err != nil || !exists {
+		return 0, err
+	}
+	cnt := 0
+	stmt := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", s.dialect.Quote(cName), where)
+	if err := s.db.QueryRowContext(ctx, stmt, b.args...).Scan(&cnt); err != nil {
+		return 0, err
+	}
+	return cnt, nil
+}
+
+// GetIncID returns next sequence ID for cName collection
+func (s *SQLCli) GetIncID(cName string) (int64, error) {
+	return s.GetIncIDCtx(context.Background(), cName)
+}
+
+// GetIncIDCtx returns next sequence ID for cName collection
+func (s *SQLCli) GetIncIDCtx(ctx context.Context, cName string) (int64, error) {
+	return s.ReserveIncIDCtx(ctx, cName, 1)
+}
+
+// ReserveIncIDCtx advances the sequence of cName collection by n and returns the last ID of the block
+func (s *SQLCli) ReserveIncIDCtx(ctx context.Context, cName string, n int64) (int64, error) {
+	return s.dialect.NextSeq(ctx, s.db, seqTable, cName, n)
+}
+
+// DelByCondition delete the documents by condiction
+func (s *SQLCli) DelByCondition(cName string, condiction interface{}) error {
+	return s.DelByConditionCtx(context.Background(), cName, condiction)
+}
+
+// DelByConditionCtx delete the documents by condiction
+func (s *SQLCli) DelByConditionCtx(ctx context.Context, cName string, condiction interface{}) error {
+	b := newBuilder(s.dialect)
+	where, err := b.where(condiction)
+	if err != nil {
+		return err
+	}
+	if exists, err := s.tableExists(ctx, cName); err != nil || !exists {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s", s.dialect.Quote(cName), where), b.args...)
+	return err
+}
+
+// HasTable returns true if the table exists
+func (s *SQLCli) HasTable(cName string) (bool, error) {
+	return s.tableExists(context.Background(), cName)
+}
+
+// CreateTable creates the document table of the name
+func (s *SQLCli) CreateTable(cName string) error {
+	return s.ensureTable(context.Background(), cName)
+}
+
+// ExecSql executes the statement, cmd is the statement text
+func (s *SQLCli) ExecSql(cmd interface{}) error {
+	stmt, ok := cmd.(string)
+	if !ok {
+		return errors.New("the statement must be a string")
+	}
+	_, err := s.db.Exec(stmt)
+	return err
+}
+
+// Index creates the index on the fields of the columns, the "-" prefix of the columns is ignored,
+// the documents are removed ExpireAfter the time of the first column if it is set
+func (s *SQLCli) Index(cName string, index *storage.Index) error {
+	ctx := context.Background()
+	if err := s.ensureTable(ctx, cName); err != nil {
+		return err
+	}
+	if 0 == len(index.Columns) {
+		return errors.New("the index has no column")
+	}
+	if 0 != len(index.PartialFilter) {
+		return errors.New("the partial index is not supported")
+	}
+	names := make([]string, 0, len(index.Columns))
+	exprs := make([]string, 0, len(index.Columns))
+	paths := make([][]string, 0, len(index.Columns))
+	for _, column := range index.Columns {
+		field := strings.TrimLeft(column, "+-")
+		path, err := checkPath(field)
+		if err != nil {
+			return err
+		}
+		names = append(names, strings.Join(path, "_"))
+		exprs = append(exprs, s.dialect.IndexExpr(path))
+		paths = append(paths, path)
+	}
+	name := index.Name
+	if "" == name {
+		name = strings.Join(names, "_")
+	}
+	unique := false
+	switch index.Type {
+	case storage.INDEX_TYPE_UNIQUE, storage.INDEX_TYPE_BACKGROUP_UNIQUE, storage.INDEX_TYPE_PRIMAEY:
+		unique = true
+	}
+
+	stmt := s.dialect.CreateIndex(indexName(cName, name), cName, exprs, unique)
+	if _, err := s.db.ExecContext(ctx, stmt); err != nil && !s.dialect.IsIndexExist(err) {
+		return err
+	}
+	if 0 < index.ExpireAfter {
+		s.ttlLock.Lock()
+		s.ttls[cName] = &ttl{path: paths[0], after: index.ExpireAfter}
+		s.ttlLock.Unlock()
+	}
+	return nil
+}
+
+// purgeExpired removes the expired documents of the table at most once in purgeInterval
+func (s *SQLCli) purgeExpired(ctx context.Context, cName string) {
+	s.ttlLock.Lock()
+	setting, ok := s.ttls[cName]
+	if !ok || time.Since(setting.lastPurge) < purgeInterval {
+		s.ttlLock.Unlock()
+		return
+	}
+	setting.lastPurge = time.Now()
+	s.ttlLock.Unlock()
+
+	b := newBuilder(s.dialect)
+	expr, err := b.bindValue(time.Now().Add(-setting.after))
+	if err != nil {
+		return
+	}
+	stmt := fmt.Sprintf("DELETE FROM %s WHERE %s < %s", s.dialect.Quote(cName), s.dialect.Field(setting.path), expr)
+	s.db.ExecContext(ctx, stmt, b.args...)
+}
+
+// DropTable drops the table
+func (s *SQLCli) DropTable(cName string) error {
+	s.tables.Delete(cName)
+	s.ttlLock.Lock()
+	delete(s.ttls, cName)
+	s.ttlLock.Unlock()
+	_, err := s.db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", s.dialect.Quote(cName)))
+	return err
+}
+
+// HasFields returns true if any document has the field
+func (s *SQLCli) HasFields(cName, field string) (bool, error) {
+	cnt, err := s.GetCntByCondition(cName, bson.M{field: bson.M{"$exists": true}})
+	if err != nil {
+		return false, err
+	}
+	return cnt > 0, nil
+}
+
+// AddColumn sets the field to the value of Ext on the documents which do not have the field
+func (s *SQLCli) AddColumn(cName string, column *storage.Column) error {
+	return s.UpdateByCondition(cName, bson.M{column.Name: column.Ext}, bson.M{column.Name: bson.M{"$exists": false}})
+}
+
+// ModifyColumn renames the field
+func (s *SQLCli) ModifyColumn(cName, oldName, newColumn string) error {
+	oldPath, err := checkPath(oldName)
+	if err != nil {
+		return err
+	}
+	newPath, err := checkPath(newColumn)
+	if err != nil {
+		return err
+	}
+	if exists, err := s.HasTable(cName); err != nil || !exists {
+		return err
+	}
+	expr := s.dialect.Remove(s.dialect.Set("doc", newPath, s.dialect.JSONField(oldPath)), oldPath)
+	stmt := fmt.Sprintf("UPDATE %s SET doc = %s WHERE %s", s.dialect.Quote(cName), expr, s.dialect.Exists(oldPath))
+	_, err = s.db.Exec(stmt)
+	return err
+}
+
+// DropColumn removes the field from all the documents
+func (s *SQLCli) DropColumn(cName, field string) error {
+	path, err := checkPath(field)
+	if err != nil {
+		return err
+	}
+	if exists, err := s.HasTable(cName); err != nil || !exists {
+		return err
+	}
+	stmt := fmt.Sprintf("UPDATE %s SET doc = %s WHERE %s", s.dialect.Quote(cName), s.dialect.Remove("doc", path), s.dialect.Exists(path))
+	_, err = s.db.Exec(stmt)
+	return err
+}
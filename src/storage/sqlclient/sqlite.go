@@ -0,0 +1,184 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlclient
+
+import (
+	"configcenter/src/storage"
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// sqliteDriver the sqlite driver with the regexp function registered for the REGEXP operator
+const sqliteDriver = "cc_sqlite3"
+
+var (
+	sqliteRegexps     = map[string]*regexp.Regexp{}
+	sqliteRegexpsLock sync.RWMutex
+)
+
+func init() {
+	sql.Register(sqliteDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("regexp", sqliteRegexp, true)
+		},
+	})
+}
+
+// sqliteRegexp implements the REGEXP operator, the compiled patterns are cached
+func sqliteRegexp(pattern, text string) (bool, error) {
+	sqliteRegexpsLock.RLock()
+	re, ok := sqliteRegexps[pattern]
+	sqliteRegexpsLock.RUnlock()
+	if !ok {
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			return false, err
+		}
+		sqliteRegexpsLock.Lock()
+		sqliteRegexps[pattern] = re
+		sqliteRegexpsLock.Unlock()
+	}
+	return re.MatchString(text), nil
+}
+
+// SQLite the dialect of sqlite, the database is the file name, ":memory:" for an in memory database
+type SQLite struct{}
+
+func (SQLite) Name() string {
+	return storage.DI_SQLITE
+}
+
+func (SQLite) Open(host, port, usr, pwd, option, database string) (*sql.DB, error) {
+	db, err := sql.Open(sqliteDriver, database)
+	if err != nil {
+		return nil, err
+	}
+	// sqlite locks the whole database on writes, and an in memory database lives in its connection
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+func (SQLite) Quote(ident string) string {
+	return quote(ident, `"`)
+}
+
+func (SQLite) Placeholder(n int) string {
+	return "?"
+}
+
+func (d SQLite) CreateTable(table string) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id INTEGER PRIMARY KEY AUTOINCREMENT, doc TEXT NOT NULL)", d.Quote(table))
+}
+
+func (SQLite) HasTable() string {
+	return "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?"
+}
+
+func (d SQLite) CreateIndex(name, table string, exprs []string, unique bool) string {
+	stmt := "CREATE INDEX IF NOT EXISTS %s ON %s (%s)"
+	if unique {
+		stmt = "CREATE UNIQUE INDEX IF NOT EXISTS %s ON %s (%s)"
+	}
+	return fmt.Sprintf(stmt, d.Quote(name), d.Quote(table), strings.Join(exprs, ", "))
+}
+
+func (SQLite) IsIndexExist(err error) bool {
+	return false
+}
+
+func (SQLite) Limit(start, limit int) string {
+	if limit <= 0 {
+		limit = -1
+	}
+	return fmt.Sprintf(" LIMIT %d OFFSET %d", limit, start)
+}
+
+func (d SQLite) CreateSeqTable(table string) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (name TEXT PRIMARY KEY, seq INTEGER NOT NULL)", d.Quote(table))
+}
+
+func (d SQLite) NextSeq(ctx context.Context, db *sql.DB, table, name string, n int64) (int64, error) {
+	stmt := fmt.Sprintf("INSERT INTO %s (name, seq) VALUES (?, ?) ON CONFLICT(name) DO UPDATE SET seq = seq + excluded.seq RETURNING seq", d.Quote(table))
+	var seq int64
+	err := db.QueryRowContext(ctx, stmt, name, n).Scan(&seq)
+	return seq, err
+}
+
+func (d SQLite) InsertDoc(ctx context.Context, tx *sql.Tx, table, text string) (int64, error) {
+	stmt := fmt.Sprintf("INSERT INTO %s (doc) VALUES (%s) RETURNING id", d.Quote(table), d.JSONValue(1))
+	var id int64
+	err := tx.QueryRowContext(ctx, stmt, text).Scan(&id)
+	return id, err
+}
+
+func (SQLite) Field(path []string) string {
+	return fmt.Sprintf("json_extract(doc, %s)", jsonPath(path))
+}
+
+func (SQLite) Bind(n int, value interface{}, composite bool) (string, interface{}) {
+	if composite {
+		// json_extract returns the minified JSON text of the composite values
+		return "json(?)", value
+	}
+	return "?", value
+}
+
+func (d SQLite) Text(path []string) string {
+	return d.Field(path)
+}
+
+func (SQLite) Regex(text, pattern string, insensitive bool) string {
+	if insensitive {
+		return fmt.Sprintf("%s REGEXP ('(?i)' || %s)", text, pattern)
+	}
+	return fmt.Sprintf("%s REGEXP %s", text, pattern)
+}
+
+func (SQLite) Exists(path []string) string {
+	return fmt.Sprintf("json_type(doc, %s) IS NOT NULL", jsonPath(path))
+}
+
+func (d SQLite) IsNull(path []string) string {
+	return fmt.Sprintf("%s IS NULL", d.Field(path))
+}
+
+func (SQLite) HasItem(path []string, value string) string {
+	p := jsonPath(path)
+	return fmt.Sprintf("(json_type(doc, %s) = 'array' AND EXISTS (SELECT 1 FROM json_each(doc, %s) WHERE json_each.value = %s))", p, p, value)
+}
+
+func (d SQLite) IndexExpr(path []string) string {
+	return d.Field(path)
+}
+
+func (SQLite) JSONField(path []string) string {
+	return fmt.Sprintf("json(doc -> %s)", jsonPath(path))
+}
+
+func (SQLite) JSONValue(n int) string {
+	return "json(?)"
+}
+
+func (SQLite) Set(doc string, path []string, value string) string {
+	return fmt.Sprintf("json_set(%s, %s, %s)", doc, jsonPath(path), value)
+}
+
+func (SQLite) Remove(doc string, path []string) string {
+	return fmt.Sprintf("json_remove(%s, %s)", doc, jsonPath(path))
+}
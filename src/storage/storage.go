@@ -12,6 +12,10 @@
  
 package storage
 
+import (
+	"time"
+)
+
 // DI define storage interface
 type DI interface {
 	GetIncID(cName string) (int64, error)
@@ -20,6 +24,9 @@ type DI interface {
 	UpdateByCondition(cName string, data, condiction interface{}) error
 	GetOneByCondition(cName string, fields []string, condiction interface{}, result interface{}) error
 	GetMutilByCondition(cName string, fields []string, condiction interface{}, result interface{}, sort string, start, limit int) error
+	// GetMutilByCursor get at most limit documents after the cursor in the order of sort, the returned
+	// cursor continues the reading and is empty on the last page, an empty cursor starts from the beginning
+	GetMutilByCursor(cName string, fields []string, condiction interface{}, result interface{}, sort, cursor string, limit int) (string, error)
 	GetCntByCondition(cName string, condiction interface{}) (int, error)
 	DelByCondition(cName string, condiction interface{}) error
 	HasTable(cName string) (bool, error)
@@ -49,6 +56,10 @@ type Index struct {
 	Name    string
 	Columns []string
 	Type    int
+	// ExpireAfter mongo special, the documents expire after the time in the first column
+	ExpireAfter time.Duration
+	// PartialFilter mongo special, only the documents which match the condiction are indexed
+	PartialFilter map[string]interface{}
 }
 
 type Column struct {
@@ -83,7 +94,10 @@ func GetMongoColumn(name string, val interface{}) *Column {
 }
 
 const (
-	DI_MYSQL string = "mysql"
-	DI_MONGO string = "mongodb"
-	DI_REDIS string = "redis"
+	DI_MYSQL    string = "mysql"
+	DI_MONGO    string = "mongodb"
+	DI_REDIS    string = "redis"
+	DI_POSTGRES string = "postgres"
+	DI_SQLITE   string = "sqlite3"
+	DI_MEMORY   string = "memory"
 )
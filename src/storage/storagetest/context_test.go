@@ -0,0 +1,109 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storagetest_test
+
+import (
+	"configcenter/src/storage"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunWithContext(t *testing.T) {
+	errFn := errors.New("fn failed")
+	if err := storage.RunWithContext(context.Background(), func() error { return errFn }); err != errFn {
+		t.Fatalf("want the error of fn, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	called := false
+	if err := storage.RunWithContext(ctx, func() error { called = true; return nil }); err != context.Canceled {
+		t.Fatalf("want context canceled, got %v", err)
+	}
+	if called {
+		t.Fatal("fn should not be called when the context is done")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	release := make(chan struct{})
+	defer close(release)
+	if err := storage.RunWithContext(ctx, func() error { <-release; return nil }); err != context.DeadlineExceeded {
+		t.Fatalf("want deadline exceeded, got %v", err)
+	}
+}
+
+func TestRunWrite(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	called := false
+	if err := storage.RunWrite(ctx, func() error { called = true; return nil }); err != context.Canceled {
+		t.Fatalf("want context canceled, got %v", err)
+	}
+	if called {
+		t.Fatal("the write should not be started when the context is done")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := storage.RunWrite(ctx, func() error { <-ctx.Done(); return nil }); err != nil {
+		t.Fatalf("the started write should not be given up, got %v", err)
+	}
+}
+
+func TestReadInto(t *testing.T) {
+	result := map[string]interface{}{"kept": 1}
+	err := storage.ReadInto(&result, func(private interface{}) error {
+		*private.(*map[string]interface{}) = map[string]interface{}{"read": 2}
+		return nil
+	})
+	if err != nil || result["kept"] != 1 || result["read"] != 2 {
+		t.Fatalf("want the read merged into the map, got %v %v", result, err)
+	}
+
+	items := []string{"old"}
+	errFn := errors.New("read failed")
+	if err := storage.ReadInto(&items, func(private interface{}) error {
+		*private.(*[]string) = []string{"new"}
+		return errFn
+	}); err != errFn {
+		t.Fatalf("want the error of fn, got %v", err)
+	}
+	if len(items) != 1 || items[0] != "old" {
+		t.Fatalf("the failed read should not touch the result, got %v", items)
+	}
+
+	// the read given up keeps writing to its private copy only
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	err = storage.ReadInto(&items, func(private interface{}) error {
+		return storage.RunWithContext(ctx, func() error {
+			<-release
+			*private.(*[]string) = []string{"late"}
+			close(finished)
+			return nil
+		})
+	})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("want deadline exceeded, got %v", err)
+	}
+	close(release)
+	<-finished
+	if len(items) != 1 || items[0] != "old" {
+		t.Fatalf("the read given up should not touch the result, got %v", items)
+	}
+}
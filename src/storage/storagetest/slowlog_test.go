@@ -0,0 +1,71 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storagetest_test
+
+import (
+	"configcenter/src/storage"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// explainer records the conditions of the explained queries
+type explainer struct {
+	storage.DI
+	explained chan interface{}
+}
+
+func (e *explainer) GetMutilByCondition(cName string, fields []string, condiction interface{}, result interface{}, sort string, start, limit int) error {
+	return nil
+}
+
+func (e *explainer) ExplainCtx(ctx context.Context, cName string, condiction interface{}, sort string) (map[string]interface{}, error) {
+	e.explained <- condiction
+	return map[string]interface{}{}, nil
+}
+
+func TestSlowLogExplainCopy(t *testing.T) {
+	db := &explainer{explained: make(chan interface{}, 1)}
+	slow := storage.NewSlowLogDI(db, storage.SlowLogOptions{ExplainSampleRate: 1})
+
+	ids := []interface{}{1, 2}
+	condiction := map[string]interface{}{
+		"bk_host_id": map[string]interface{}{"$in": ids},
+		"bk_os_type": "1",
+	}
+	result := make([]map[string]interface{}, 0)
+	if err := slow.GetMutilByCondition("cc_HostBase", nil, condiction, &result, "", 0, 0); nil != err {
+		t.Fatalf("read failed, %v", err)
+	}
+
+	// the caller reuses its condition once the call returns
+	condiction["bk_os_type"] = "2"
+	condiction["bk_host_id"].(map[string]interface{})["$in"] = []interface{}{3}
+	ids[0] = 4
+
+	select {
+	case cond := <-db.explained:
+		want := bson.M{"bk_host_id": bson.M{"$in": []interface{}{1, 2}}, "bk_os_type": "1"}
+		if !reflect.DeepEqual(want, cond) {
+			t.Fatalf("want %v, got %v", want, cond)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the slow query is not explained")
+	}
+	if recent := storage.SlowLogOf(slow).Recent(); 1 != len(recent) || "cc_HostBase" != recent[0].Collection {
+		t.Fatalf("want the slow query logged, got %v", recent)
+	}
+}
@@ -0,0 +1,633 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package storagetest is the conformance suite of the storage.DI drivers, the controllers
+// count on the same results of the same calls whichever driver is configured
+package storagetest
+
+import (
+	"configcenter/src/storage"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Opener returns an opened driver, the driver is closed by the suite
+type Opener func(t *testing.T) storage.DI
+
+// host the struct document of the suite
+type host struct {
+	ID         int64     `bson:"bk_host_id"`
+	Name       string    `bson:"bk_host_name"`
+	Owner      string    `bson:"bk_supplier_account"`
+	Cpu        int       `bson:"bk_cpu"`
+	Mem        float64   `bson:"bk_mem"`
+	Tags       []string  `bson:"tags"`
+	Ext        ext       `bson:"ext"`
+	CreateTime time.Time `bson:"create_time"`
+}
+
+type ext struct {
+	Region string `bson:"region"`
+	Rack   int    `bson:"rack"`
+}
+
+// Run runs the conformance suite against the driver which open returns, every case works on its own
+// collections which are dropped at the end
+func Run(t *testing.T, open Opener) {
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, db storage.DI, cName string)
+	}{
+		{"InsertAndGet", testInsertAndGet},
+		{"NotFound", testNotFound},
+		{"Operators", testOperators},
+		{"Sort", testSort},
+		{"Page", testPage},
+		{"Projection", testProjection},
+		{"Update", testUpdate},
+		{"EscapeHtml", testEscapeHtml},
+		{"Delete", testDelete},
+		{"IncID", testIncID},
+		{"ReserveID", testReserveID},
+		{"Cursor", testCursor},
+		{"CursorMissingField", testCursorMissingField},
+		{"Iterator", testIterator},
+		{"Column", testColumn},
+		{"Table", testTable},
+		{"UniqueIndex", testUniqueIndex},
+		{"Context", testContext},
+		{"JournalTx", testJournalTx},
+	}
+	prefix := fmt.Sprintf("cc_conformance_%d_", time.Now().UnixNano()%1000000)
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			db := open(t)
+			defer db.Close()
+			cName := prefix + c.name
+			defer db.DropTable(cName)
+			c.fn(t, db, cName)
+		})
+	}
+}
+
+// hosts returns the documents of the hosts of the ids
+func hosts(base time.Time, ids ...int64) []interface{} {
+	docs := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		docs = append(docs, &host{
+			ID:         id,
+			Name:       fmt.Sprintf("host-%02d", id),
+			Owner:      []string{"0", "tencent"}[id%2],
+			Cpu:        int(id) * 2,
+			Mem:        float64(id) + 0.5,
+			Tags:       []string{"tag", fmt.Sprintf("t%d", id)},
+			Ext:        ext{Region: []string{"sz", "gz", "sh"}[id%3], Rack: int(id % 4)},
+			CreateTime: base.Add(time.Duration(id) * time.Hour),
+		})
+	}
+	return docs
+}
+
+// baseTime is truncated to milliseconds which all the drivers keep
+func baseTime() time.Time {
+	return time.Date(2018, 3, 1, 8, 0, 0, 0, time.UTC)
+}
+
+// ids returns the host ids of the result
+func ids(result []host) []int64 {
+	ret := make([]int64, 0, len(result))
+	for _, h := range result {
+		ret = append(ret, h.ID)
+	}
+	return ret
+}
+
+// search returns the ids of the hosts which match the condiction in the order of the ids
+func search(t *testing.T, db storage.DI, cName string, condiction interface{}) []int64 {
+	result := make([]host, 0)
+	require.NoError(t, db.GetMutilByCondition(cName, nil, condiction, &result, "bk_host_id", 0, 0))
+	return ids(result)
+}
+
+func testInsertAndGet(t *testing.T, db storage.DI, cName string) {
+	base := baseTime()
+	_, err := db.Insert(cName, hosts(base, 1)[0])
+	require.NoError(t, err)
+	require.NoError(t, db.InsertMuti(cName, hosts(base, 2, 3)...))
+	_, err = db.Insert(cName, map[string]interface{}{
+		"bk_host_id":   int64(4),
+		"bk_host_name": "host-04",
+		"create_time":  base.Add(4*time.Hour + 123*time.Millisecond),
+		"ext":          map[string]interface{}{"region": "sz", "rack": 0},
+		"null_field":   nil,
+	})
+	require.NoError(t, err)
+
+	got := host{}
+	require.NoError(t, db.GetOneByCondition(cName, nil, map[string]interface{}{"bk_host_id": 2}, &got))
+	assert.True(t, got.CreateTime.Equal(base.Add(2*time.Hour)))
+	// the drivers return the times in the local time zone
+	got.CreateTime = got.CreateTime.UTC()
+	assert.Equal(t, *hosts(base, 2)[0].(*host), got)
+
+	doc := map[string]interface{}{}
+	require.NoError(t, db.GetOneByCondition(cName, nil, map[string]interface{}{"bk_host_id": 4}, &doc))
+	assert.Equal(t, "host-04", doc["bk_host_name"])
+	assert.EqualValues(t, 4, doc["bk_host_id"])
+	createTime, ok := doc["create_time"].(time.Time)
+	require.True(t, ok, "the time is read back as time.Time")
+	assert.True(t, createTime.Equal(base.Add(4*time.Hour+123*time.Millisecond)))
+	assert.Contains(t, doc, "null_field")
+	assert.Nil(t, doc["null_field"])
+	assert.NotContains(t, doc, "_id")
+
+	cnt, err := db.GetCntByCondition(cName, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 4, cnt)
+	cnt, err = db.GetCntByCondition(cName, map[string]interface{}{"ext.region": "sz"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, cnt)
+}
+
+func testNotFound(t *testing.T, db storage.DI, cName string) {
+	result := map[string]interface{}{}
+	err := db.GetOneByCondition(cName, nil, map[string]interface{}{"bk_host_id": 1}, &result)
+	require.Error(t, err)
+	assert.Equal(t, "not found", err.Error())
+
+	require.NoError(t, db.InsertMuti(cName, hosts(baseTime(), 1)...))
+	err = db.GetOneByCondition(cName, nil, map[string]interface{}{"bk_host_id": 2}, &result)
+	require.Error(t, err)
+	assert.Equal(t, "not found", err.Error())
+
+	cnt, err := db.GetCntByCondition(cName+"_missing", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, cnt)
+	missing := make([]host, 0)
+	require.NoError(t, db.GetMutilByCondition(cName+"_missing", nil, nil, &missing, "", 0, 0))
+	assert.Empty(t, missing)
+}
+
+func testOperators(t *testing.T, db storage.DI, cName string) {
+	base := baseTime()
+	require.NoError(t, db.InsertMuti(cName, hosts(base, 1, 2, 3, 4, 5, 6)...))
+	_, err := db.Insert(cName, map[string]interface{}{"bk_host_id": int64(7), "bk_host_name": "Host-07"})
+	require.NoError(t, err)
+
+	type M = map[string]interface{}
+	cases := []struct {
+		name string
+		cond interface{}
+		want []int64
+	}{
+		{"all", nil, []int64{1, 2, 3, 4, 5, 6, 7}},
+		{"empty", M{}, []int64{1, 2, 3, 4, 5, 6, 7}},
+		{"eq", M{"bk_supplier_account": "tencent"}, []int64{1, 3, 5}},
+		{"$eq", M{"bk_cpu": M{"$eq": 4}}, []int64{2}},
+		{"two fields", M{"bk_supplier_account": "0", "bk_cpu": 4}, []int64{2}},
+		{"$ne", M{"bk_supplier_account": M{"$ne": "0"}}, []int64{1, 3, 5, 7}},
+		{"$gt", M{"bk_cpu": M{"$gt": 8}}, []int64{5, 6}},
+		{"$gte", M{"bk_cpu": M{"$gte": 8}}, []int64{4, 5, 6}},
+		{"$lt", M{"bk_mem": M{"$lt": 2.5}}, []int64{1}},
+		{"$lte and $gte", M{"bk_mem": M{"$gte": 2.5, "$lte": 4.5}}, []int64{2, 3, 4}},
+		{"$gt string", M{"bk_host_name": M{"$gt": "host-04"}}, []int64{5, 6}},
+		{"$gt time", M{"create_time": M{"$gt": base.Add(4 * time.Hour)}}, []int64{5, 6}},
+		{"$lte time", M{"create_time": M{"$lte": base.Add(2 * time.Hour)}}, []int64{1, 2}},
+		{"$in", M{"bk_host_id": M{"$in": []int64{2, 4, 8}}}, []int64{2, 4}},
+		{"$in strings", M{"bk_host_name": M{"$in": []string{"host-01", "Host-07"}}}, []int64{1, 7}},
+		{"$in empty", M{"bk_host_id": M{"$in": []int64{}}}, []int64{}},
+		{"$in nil", M{"bk_supplier_account": M{"$in": []interface{}{nil, "tencent"}}}, []int64{1, 3, 5, 7}},
+		{"$nin", M{"bk_host_id": M{"$nin": []int64{1, 2, 3}}}, []int64{4, 5, 6, 7}},
+		{"$nin missing", M{"bk_supplier_account": M{"$nin": []string{"0"}}}, []int64{1, 3, 5, 7}},
+		{"$exists", M{"bk_cpu": M{"$exists": true}}, []int64{1, 2, 3, 4, 5, 6}},
+		{"$exists false", M{"bk_cpu": M{"$exists": false}}, []int64{7}},
+		{"nil", M{"bk_cpu": nil}, []int64{7}},
+		{"$regex", M{"bk_host_name": M{"$regex": "^host-0[12]$"}}, []int64{1, 2}},
+		{"$regex contains", M{"bk_host_name": M{"$regex": "-0[56]"}}, []int64{5, 6}},
+		{"$regex $options", M{"bk_host_name": M{"$regex": "^host-07", "$options": "i"}}, []int64{7}},
+		{"$not", M{"bk_cpu": M{"$not": M{"$gt": 4}}}, []int64{1, 2, 7}},
+		{"dotted", M{"ext.region": "sz", "ext.rack": M{"$gte": 1}}, []int64{3, 6}},
+		{"$or", M{"$or": []interface{}{M{"bk_host_id": 1}, M{"ext.region": "sh"}}}, []int64{1, 2, 5}},
+		{"$and", M{"$and": []interface{}{M{"bk_cpu": M{"$gt": 2}}, M{"bk_cpu": M{"$lt": 10}}}}, []int64{2, 3, 4}},
+		{"$nor", M{"$nor": []interface{}{M{"bk_supplier_account": "0"}, M{"bk_host_id": 7}}}, []int64{1, 3, 5}},
+		{"nested $or", M{"bk_supplier_account": "0", "$or": []interface{}{M{"bk_host_id": 2}, M{"bk_host_id": 3}}}, []int64{2}},
+		{"array item", M{"tags": "t2"}, []int64{2}},
+		{"array whole", M{"tags": []string{"tag", "t4"}}, []int64{4}},
+		{"array $eq", M{"tags": M{"$eq": "t3"}}, []int64{3}},
+		{"array $ne", M{"tags": M{"$ne": "t3"}}, []int64{1, 2, 4, 5, 6, 7}},
+		{"array $ne any", M{"tags": M{"$ne": "tag"}}, []int64{7}},
+		{"array $in", M{"tags": M{"$in": []string{"t1", "t5", "t8"}}}, []int64{1, 5}},
+		{"array $nin", M{"tags": M{"$nin": []string{"t1", "t2"}}}, []int64{3, 4, 5, 6, 7}},
+		{"$all", M{"tags": M{"$all": []string{"tag", "t6"}}}, []int64{6}},
+		{"$all missing item", M{"tags": M{"$all": []string{"t1", "t6"}}}, []int64{}},
+		{"$all scalar", M{"bk_host_name": M{"$all": []string{"host-01"}}}, []int64{1}},
+		{"$all empty", M{"tags": M{"$all": []string{}}}, []int64{}},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, search(t, db, cName, c.cond), c.name)
+	}
+}
+
+func testSort(t *testing.T, db storage.DI, cName string) {
+	require.NoError(t, db.InsertMuti(cName, hosts(baseTime(), 3, 1, 5, 2, 4)...))
+
+	result := make([]host, 0)
+	require.NoError(t, db.GetMutilByCondition(cName, nil, nil, &result, "bk_cpu", 0, 0))
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, ids(result))
+
+	result = make([]host, 0)
+	require.NoError(t, db.GetMutilByCondition(cName, nil, nil, &result, "-bk_host_name", 0, 0))
+	assert.Equal(t, []int64{5, 4, 3, 2, 1}, ids(result))
+
+	result = make([]host, 0)
+	require.NoError(t, db.GetMutilByCondition(cName, nil, nil, &result, "-create_time", 0, 0))
+	assert.Equal(t, []int64{5, 4, 3, 2, 1}, ids(result))
+}
+
+func testPage(t *testing.T, db storage.DI, cName string) {
+	require.NoError(t, db.InsertMuti(cName, hosts(baseTime(), 1, 2, 3, 4, 5)...))
+
+	result := make([]host, 0)
+	require.NoError(t, db.GetMutilByCondition(cName, nil, nil, &result, "bk_host_id", 1, 2))
+	assert.Equal(t, []int64{2, 3}, ids(result))
+
+	result = make([]host, 0)
+	require.NoError(t, db.GetMutilByCondition(cName, nil, nil, &result, "bk_host_id", 3, 0))
+	assert.Equal(t, []int64{4, 5}, ids(result))
+
+	result = make([]host, 0)
+	require.NoError(t, db.GetMutilByCondition(cName, nil, nil, &result, "bk_host_id", 0, 2))
+	assert.Equal(t, []int64{1, 2}, ids(result))
+}
+
+func testProjection(t *testing.T, db storage.DI, cName string) {
+	require.NoError(t, db.InsertMuti(cName, hosts(baseTime(), 1, 2)...))
+
+	docs := make([]map[string]interface{}, 0)
+	require.NoError(t, db.GetMutilByCondition(cName, []string{"bk_host_id", "ext.region"}, nil, &docs, "bk_host_id", 0, 0))
+	require.Len(t, docs, 2)
+	assert.Len(t, docs[0], 2)
+	assert.EqualValues(t, 1, docs[0]["bk_host_id"])
+	assert.Equal(t, map[string]interface{}{"region": "gz"}, docs[0]["ext"])
+
+	doc := map[string]interface{}{}
+	require.NoError(t, db.GetOneByCondition(cName, []string{"bk_host_name"}, map[string]interface{}{"bk_host_id": 2}, &doc))
+	assert.Equal(t, map[string]interface{}{"bk_host_name": "host-02"}, doc)
+
+	docs = make([]map[string]interface{}, 0)
+	require.NoError(t, db.GetMutilByCondition(cName, []string{""}, nil, &docs, "bk_host_id", 0, 0))
+	require.Len(t, docs, 2)
+	assert.NotContains(t, docs[0], "_id")
+	assert.Contains(t, docs[0], "tags")
+}
+
+func testUpdate(t *testing.T, db storage.DI, cName string) {
+	base := baseTime()
+	require.NoError(t, db.InsertMuti(cName, hosts(base, 1, 2, 3)...))
+
+	data := map[string]interface{}{
+		"bk_host_name": "updated",
+		"ext.rack":     9,
+		"tags":         []string{"new"},
+		"last_time":    base,
+	}
+	require.NoError(t, db.UpdateByCondition(cName, data, map[string]interface{}{"bk_host_id": map[string]interface{}{"$in": []int64{1, 3}}}))
+
+	result := make([]host, 0)
+	require.NoError(t, db.GetMutilByCondition(cName, nil, map[string]interface{}{"bk_host_name": "updated"}, &result, "bk_host_id", 0, 0))
+	require.Equal(t, []int64{1, 3}, ids(result))
+	assert.Equal(t, 9, result[0].Ext.Rack)
+	assert.Equal(t, "gz", result[0].Ext.Region, "the sibling fields are kept")
+	assert.Equal(t, []string{"new"}, result[1].Tags)
+	assert.Equal(t, []int64{1, 3}, search(t, db, cName, map[string]interface{}{"last_time": base}))
+
+	got := host{}
+	require.NoError(t, db.GetOneByCondition(cName, nil, map[string]interface{}{"bk_host_id": 2}, &got))
+	assert.Equal(t, "host-02", got.Name)
+}
+
+func testEscapeHtml(t *testing.T, db storage.DI, cName string) {
+	_, err := db.Insert(cName, map[string]interface{}{"bk_host_id": 1, "bk_host_name": "<b>a&b</b>"})
+	require.NoError(t, err)
+	require.NoError(t, db.InsertMuti(cName, &host{ID: 2, Name: "<i>"}))
+
+	got := host{}
+	require.NoError(t, db.GetOneByCondition(cName, nil, map[string]interface{}{"bk_host_id": 1}, &got))
+	assert.Equal(t, "&lt;b&gt;a&amp;b&lt;/b&gt;", got.Name)
+	require.NoError(t, db.GetOneByCondition(cName, nil, map[string]interface{}{"bk_host_id": 2}, &got))
+	assert.Equal(t, "&lt;i&gt;", got.Name)
+
+	require.NoError(t, db.UpdateByCondition(cName, map[string]interface{}{"bk_host_name": "<u>"}, map[string]interface{}{"bk_host_id": 1}))
+	require.NoError(t, db.GetOneByCondition(cName, nil, map[string]interface{}{"bk_host_id": 1}, &got))
+	assert.Equal(t, "&lt;u&gt;", got.Name)
+}
+
+func testDelete(t *testing.T, db storage.DI, cName string) {
+	require.NoError(t, db.InsertMuti(cName, hosts(baseTime(), 1, 2, 3, 4)...))
+	require.NoError(t, db.DelByCondition(cName, map[string]interface{}{"bk_cpu": map[string]interface{}{"$gte": 6}}))
+	assert.Equal(t, []int64{1, 2}, search(t, db, cName, nil))
+	require.NoError(t, db.DelByCondition(cName+"_missing", nil))
+}
+
+func testIncID(t *testing.T, db storage.DI, cName string) {
+	first, err := db.GetIncID(cName)
+	require.NoError(t, err)
+	second, err := db.GetIncID(cName)
+	require.NoError(t, err)
+	assert.Equal(t, first+1, second)
+	other, err := db.GetIncID(cName + "_other")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, other)
+}
+
+func testReserveID(t *testing.T, db storage.DI, cName string) {
+	_, ok := storage.Unwrap(db).(storage.IDReserver)
+	require.True(t, ok, "the driver reserves the blocks of IDs")
+
+	// two allocators stand for two processes leasing from the same sequence
+	seen := map[int64]bool{}
+	take := func(ids ...int64) {
+		for _, id := range ids {
+			assert.False(t, seen[id], "id %d handed out twice", id)
+			seen[id] = true
+		}
+	}
+	ctx := context.Background()
+	a, b := storage.NewIDAllocator(db, 5), storage.NewIDAllocator(db, 5)
+	for i := 0; i < 7; i++ {
+		id, err := a.NextID(ctx, cName)
+		require.NoError(t, err)
+		take(id)
+		id, err = b.NextID(ctx, cName)
+		require.NoError(t, err)
+		take(id)
+		id, err = db.GetIncID(cName)
+		require.NoError(t, err)
+		take(id)
+	}
+
+	batch, err := a.Reserve(ctx, cName, 12)
+	require.NoError(t, err)
+	require.Len(t, batch, 12)
+	take(batch...)
+	for i := 1; i < len(batch); i++ {
+		assert.True(t, batch[i-1] < batch[i])
+	}
+
+	// the allocator of a restarted process starts after all the leased blocks
+	var max int64
+	for id := range seen {
+		if id > max {
+			max = id
+		}
+	}
+	id, err := storage.NewIDAllocator(db, 5).NextID(ctx, cName)
+	require.NoError(t, err)
+	assert.True(t, id > max, "%d after %d", id, max)
+}
+
+func testCursor(t *testing.T, db storage.DI, cName string) {
+	// the sort values repeat so that the pages break in the middle of the ties
+	require.NoError(t, db.InsertMuti(cName, hosts(baseTime(), 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)...))
+
+	got := make([]int64, 0)
+	cursor, pages := "", 0
+	for {
+		result := make([]host, 0)
+		next, err := db.GetMutilByCursor(cName, nil, map[string]interface{}{"bk_host_id": map[string]interface{}{"$gt": 1}}, &result, "-ext.region", cursor, 4)
+		require.NoError(t, err)
+		got = append(got, ids(result)...)
+		pages++
+		if "" == next {
+			break
+		}
+		cursor = next
+	}
+	// sz: 3 6 9, sh: 2 5 8, gz: 4 7 10
+	assert.Equal(t, []int64{3, 6, 9, 2, 5, 8, 4, 7, 10}, got)
+	assert.Equal(t, 3, pages)
+
+	docs := make([]map[string]interface{}, 0)
+	next, err := db.GetMutilByCursor(cName, []string{"bk_host_name"}, nil, &docs, "bk_host_id", "", 2)
+	require.NoError(t, err)
+	require.NotEmpty(t, next)
+	assert.Equal(t, []map[string]interface{}{{"bk_host_name": "host-01"}, {"bk_host_name": "host-02"}}, docs)
+
+	docs = make([]map[string]interface{}, 0)
+	_, err = db.GetMutilByCursor(cName, nil, nil, &docs, "bk_cpu", next, 2)
+	assert.Equal(t, storage.ErrInvalidCursor, err, "the cursor of another sort")
+	_, err = db.GetMutilByCursor(cName, nil, nil, &docs, "bk_host_id", "!not a cursor!", 2)
+	assert.Equal(t, storage.ErrInvalidCursor, err)
+}
+
+func testCursorMissingField(t *testing.T, db storage.DI, cName string) {
+	docs := []interface{}{
+		map[string]interface{}{"bk_host_id": 1},
+		map[string]interface{}{"bk_host_id": 2, "rank": 2},
+		map[string]interface{}{"bk_host_id": 3},
+		map[string]interface{}{"bk_host_id": 4, "rank": 1},
+		map[string]interface{}{"bk_host_id": 5, "rank": nil},
+		map[string]interface{}{"bk_host_id": 6},
+	}
+	for _, doc := range docs {
+		_, err := db.Insert(cName, doc)
+		require.NoError(t, err)
+	}
+
+	read := func(sort string) []int64 {
+		got := make([]int64, 0)
+		cursor := ""
+		for {
+			result := make([]host, 0)
+			next, err := db.GetMutilByCursor(cName, nil, nil, &result, sort, cursor, 2)
+			require.NoError(t, err)
+			got = append(got, ids(result)...)
+			if "" == next {
+				return got
+			}
+			cursor = next
+		}
+	}
+	// the missing and null values are the least and the pages do not stop at them
+	assert.Equal(t, []int64{1, 3, 5, 6, 4, 2}, read("rank"))
+	assert.Equal(t, []int64{2, 4, 1, 3, 5, 6}, read("-rank"))
+}
+
+func testIterator(t *testing.T, db storage.DI, cName string) {
+	require.NoError(t, db.InsertMuti(cName, hosts(baseTime(), 1, 2, 3, 4, 5, 6, 7)...))
+
+	it := storage.NewIterator(context.Background(), db, cName, []string{"bk_host_id"}, nil, "-bk_host_id", 3)
+	got := make([]int64, 0)
+	for it.Next() {
+		id, ok := it.Doc()["bk_host_id"]
+		require.True(t, ok)
+		got = append(got, toInt64(id))
+	}
+	require.NoError(t, it.Err())
+	assert.Equal(t, []int64{7, 6, 5, 4, 3, 2, 1}, got)
+}
+
+func toInt64(v interface{}) int64 {
+	switch val := v.(type) {
+	case int:
+		return int64(val)
+	case int32:
+		return int64(val)
+	case int64:
+		return val
+	case float64:
+		return int64(val)
+	}
+	return -1
+}
+
+func testColumn(t *testing.T, db storage.DI, cName string) {
+	require.NoError(t, db.InsertMuti(cName, hosts(baseTime(), 1, 2)...))
+	_, err := db.Insert(cName, map[string]interface{}{"bk_host_id": int64(3), "bk_os": "linux"})
+	require.NoError(t, err)
+
+	has, err := db.HasFields(cName, "bk_os")
+	require.NoError(t, err)
+	assert.True(t, has)
+	has, err = db.HasFields(cName, "bk_missing")
+	require.NoError(t, err)
+	assert.False(t, has)
+
+	require.NoError(t, db.AddColumn(cName, storage.GetMongoColumn("bk_os", "windows")))
+	assert.Equal(t, []int64{1, 2}, search(t, db, cName, map[string]interface{}{"bk_os": "windows"}))
+	assert.Equal(t, []int64{3}, search(t, db, cName, map[string]interface{}{"bk_os": "linux"}))
+
+	require.NoError(t, db.ModifyColumn(cName, "bk_os", "bk_os_type"))
+	has, err = db.HasFields(cName, "bk_os")
+	require.NoError(t, err)
+	assert.False(t, has)
+	assert.Equal(t, []int64{3}, search(t, db, cName, map[string]interface{}{"bk_os_type": "linux"}))
+
+	require.NoError(t, db.DropColumn(cName, "bk_os_type"))
+	has, err = db.HasFields(cName, "bk_os_type")
+	require.NoError(t, err)
+	assert.False(t, has)
+	assert.Equal(t, []int64{1, 2, 3}, search(t, db, cName, nil))
+}
+
+func testTable(t *testing.T, db storage.DI, cName string) {
+	has, err := db.HasTable(cName)
+	require.NoError(t, err)
+	assert.False(t, has)
+
+	require.NoError(t, db.CreateTable(cName))
+	has, err = db.HasTable(cName)
+	require.NoError(t, err)
+	assert.True(t, has)
+
+	require.NoError(t, db.DropTable(cName))
+	has, err = db.HasTable(cName)
+	require.NoError(t, err)
+	assert.False(t, has)
+
+	require.NoError(t, db.InsertMuti(cName, hosts(baseTime(), 1)...))
+	has, err = db.HasTable(cName)
+	require.NoError(t, err)
+	assert.True(t, has)
+}
+
+func testUniqueIndex(t *testing.T, db storage.DI, cName string) {
+	require.NoError(t, db.InsertMuti(cName, hosts(baseTime(), 1, 2)...))
+	index := storage.GetMongoIndex("idx_unique_name", []string{"bk_host_name", "bk_supplier_account"}, true, false)
+	require.NoError(t, db.Index(cName, index))
+	require.NoError(t, db.Index(cName, index), "the index is created again")
+	require.NoError(t, db.Index(cName, storage.GetMongoIndex("idx_region", []string{"ext.region"}, false, true)))
+
+	assert.Error(t, db.InsertMuti(cName, hosts(baseTime(), 1)...))
+	cnt, err := db.GetCntByCondition(cName, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, cnt)
+}
+
+func testContext(t *testing.T, db storage.DI, cName string) {
+	require.NoError(t, db.InsertMuti(cName, hosts(baseTime(), 1)...))
+	cdb := storage.ContextOf(db)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result := make([]host, 0)
+	assert.Error(t, cdb.GetMutilByConditionCtx(ctx, cName, nil, nil, &result, "", 0, 0))
+	_, err := cdb.InsertCtx(ctx, cName, hosts(baseTime(), 2)[0])
+	assert.Error(t, err)
+	assert.Equal(t, []int64{1}, search(t, db, cName, nil))
+}
+
+func testJournalTx(t *testing.T, db storage.DI, cName string) {
+	if _, ok := storage.Unwrap(db).(storage.Snapshotter); !ok {
+		t.Skip("the driver does not take the snapshots")
+	}
+	defer db.DropTable(storage.TxJournalTable)
+	require.NoError(t, db.InsertMuti(cName, hosts(baseTime(), 1, 2, 3)...))
+	ctx := context.Background()
+
+	write := func(tx storage.Tx) {
+		cdb := storage.ContextOf(tx)
+		_, err := cdb.InsertCtx(ctx, cName, hosts(baseTime(), 4)[0])
+		require.NoError(t, err)
+		require.NoError(t, cdb.UpdateByConditionCtx(ctx, cName, map[string]interface{}{"bk_host_name": "changed"}, map[string]interface{}{"bk_host_id": 1}))
+		require.NoError(t, cdb.DelByConditionCtx(ctx, cName, map[string]interface{}{"bk_host_id": 2}))
+	}
+
+	tx, err := storage.StartJournalTx(ctx, db)
+	require.NoError(t, err)
+	write(tx)
+	assert.Equal(t, []int64{1, 3, 4}, search(t, db, cName, nil))
+	require.NoError(t, tx.Abort(ctx))
+	assert.Equal(t, []int64{1, 2, 3}, search(t, db, cName, nil))
+	got := host{}
+	require.NoError(t, db.GetOneByCondition(cName, nil, map[string]interface{}{"bk_host_id": 1}, &got))
+	got.CreateTime = got.CreateTime.UTC()
+	assert.Equal(t, *hosts(baseTime(), 1)[0].(*host), got)
+
+	tx, err = storage.StartJournalTx(ctx, db)
+	require.NoError(t, err)
+	write(tx)
+	require.NoError(t, tx.Commit(ctx))
+	assert.Equal(t, []int64{1, 3, 4}, search(t, db, cName, nil))
+	assert.Equal(t, []int64{1}, search(t, db, cName, map[string]interface{}{"bk_host_name": "changed"}))
+
+	// the abort removes the inserted rows only, not the same documents inserted by the others
+	tx, err = storage.StartJournalTx(ctx, db)
+	require.NoError(t, err)
+	_, err = storage.ContextOf(tx).InsertCtx(ctx, cName, hosts(baseTime(), 4)[0])
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 3, 4, 4}, search(t, db, cName, nil))
+	require.NoError(t, tx.Abort(ctx))
+	assert.Equal(t, []int64{1, 3, 4}, search(t, db, cName, nil))
+	assert.Equal(t, storage.ErrTxNotFound, tx.Abort(ctx), "the transaction is aborted once")
+	assert.Equal(t, storage.ErrTxNotFound, tx.Commit(ctx))
+	_, err = storage.ContextOf(tx).InsertCtx(ctx, cName, hosts(baseTime(), 5)[0])
+	assert.Equal(t, storage.ErrTxNotFound, err, "the aborted transaction takes no more writes")
+
+	// the expired transaction is aborted by the next one, and then it could not be committed
+	tx, err = storage.StartJournalTx(ctx, db)
+	require.NoError(t, err)
+	require.NoError(t, storage.ContextOf(tx).DelByConditionCtx(ctx, cName, map[string]interface{}{"bk_host_id": 3}))
+	expired := map[string]interface{}{"create_time": time.Now().UTC().Add(-2 * storage.TxTimeout)}
+	require.NoError(t, db.UpdateByCondition(storage.TxJournalTable, expired, map[string]interface{}{"tx_id": tx.ID(), "seq": 0}))
+	next, err := storage.StartJournalTx(ctx, db)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 3, 4}, search(t, db, cName, nil))
+	assert.Equal(t, storage.ErrTxNotFound, tx.Commit(ctx))
+	require.NoError(t, next.Commit(ctx))
+}
@@ -0,0 +1,101 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// TxTimeout the transaction is aborted when it is not finished in the time
+const TxTimeout = 60 * time.Second
+
+var (
+	// ErrTxNotSupported returned when the storage is not able to run transactions
+	ErrTxNotSupported = errors.New("storage: transaction is not supported")
+	// ErrTxNotFound returned when the transaction is finished or expired
+	ErrTxNotFound = errors.New("storage: transaction not found")
+)
+
+// Tx define the transaction interface, the operations called through it join the transaction,
+// the transaction could be resumed by its id in any process which shares the same database
+type Tx interface {
+	ContextDI
+	ID() string
+	Commit(ctx context.Context) error
+	Abort(ctx context.Context) error
+}
+
+// TxDI define the storage which is able to run transactions
+type TxDI interface {
+	StartTx(ctx context.Context) (Tx, error)
+	ResumeTx(ctx context.Context, txID string) (Tx, error)
+}
+
+// StartTx starts a transaction on db, the journal transaction is used
+// when db is not able to run the transactions itself
+func StartTx(ctx context.Context, db DI) (Tx, error) {
+	if tdb, ok := Unwrap(db).(TxDI); ok {
+		tx, err := tdb.StartTx(ctx)
+		if err != ErrTxNotSupported {
+			return tx, err
+		}
+	}
+	return StartJournalTx(ctx, db)
+}
+
+// ResumeTx resumes the transaction of txID on db
+func ResumeTx(ctx context.Context, db DI, txID string) (Tx, error) {
+	if isJournalTxID(txID) {
+		return ResumeJournalTx(ctx, db, txID)
+	}
+	if tdb, ok := Unwrap(db).(TxDI); ok {
+		return tdb.ResumeTx(ctx, txID)
+	}
+	return nil, ErrTxNotFound
+}
+
+// RunInTx runs fn in a new transaction of db, the transaction is committed when fn succeed,
+// otherwise it is aborted, the storage calls of fn join the transaction by the given context
+func RunInTx(ctx context.Context, db DI, fn func(ctx context.Context) error) error {
+	tx, err := StartTx(ctx, db)
+	if err != nil {
+		return err
+	}
+	if err := fn(WithTx(ctx, db, tx)); err != nil {
+		tx.Abort(context.Background())
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+type txContextKey struct{}
+
+type txBinding struct {
+	db DI
+	tx Tx
+}
+
+// WithTx returns a copy of ctx in which the calls to db made by ContextOf join tx
+func WithTx(ctx context.Context, db DI, tx Tx) context.Context {
+	return context.WithValue(ctx, txContextKey{}, &txBinding{db: db, tx: tx})
+}
+
+// TxOf returns the transaction of db bound to ctx, nil is returned if there is not any
+func TxOf(ctx context.Context, db DI) Tx {
+	if binding, ok := ctx.Value(txContextKey{}).(*txBinding); ok && binding.db == db {
+		return binding.tx
+	}
+	return nil
+}
@@ -18,6 +18,7 @@ import (
 	"configcenter/src/common/util"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"strings"
 
@@ -153,7 +154,7 @@ func GetExcelData(sheet *xlsx.Sheet, fields, defFields common.KvMap, isCheckHead
 				host[cols[celIDnex]] = cell.String()
 			case xlsx.CellTypeNumeric:
 
-				cellValue, err := cell.Int()
+				cellValue, err := cell.Float()
 				if nil != err {
 					blog.Errorf("%d row %s column get content error:%s", index+1, cols[celIDnex], err.Error())
 					continue
@@ -162,7 +163,12 @@ func GetExcelData(sheet *xlsx.Sheet, fields, defFields common.KvMap, isCheckHead
 					continue
 				}
 				isEmpty = false
-				host[cols[celIDnex]] = cellValue
+				// the float fields keep the fraction
+				if cellValue == math.Trunc(cellValue) {
+					host[cols[celIDnex]] = int(cellValue)
+				} else {
+					host[cols[celIDnex]] = cellValue
+				}
 			case xlsx.CellTypeBool:
 				cellValue := cell.Bool()
 				isEmpty = false
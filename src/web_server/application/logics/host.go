@@ -29,6 +29,9 @@ import (
 	"github.com/tealeg/xlsx"
 )
 
+// exportPageSize the count of the hosts or insts an export reads in a request
+const exportPageSize = 500
+
 //GetHostData get host data from excel
 func GetHostData(appIDStr, hostIDStr, apiAddr string, header http.Header, kvMap map[string]string) ([]interface{}, error) {
 	hostInfo := make([]interface{}, 0)
@@ -64,20 +67,28 @@ func GetHostData(appIDStr, hostIDStr, apiAddr string, header http.Header, kvMap
 		sHostCond["page"] = make(map[string]interface{})
 
 	}
+	// read the hosts page by page with the cursor
 	url := apiAddr + fmt.Sprintf("/api/%s/hosts/search", webCommon.API_VERSION)
-	result, _ := httpRequest(url, sHostCond, header)
-	blog.Info("search host  url:%s", url)
-	blog.Info("search host  return:%s", result)
-	js, _ := simplejson.NewJson([]byte(result))
-	hostData, _ := js.Map()
-	hostResult := hostData["result"].(bool)
-	if false == hostResult {
-		return hostInfo, errors.New(hostData["bk_error_msg"].(string))
+	cursor := ""
+	for {
+		sHostCond["page"] = map[string]interface{}{"limit": exportPageSize, "cursor": cursor}
+		result, _ := httpRequest(url, sHostCond, header)
+		blog.Info("search host  url:%s", url)
+		blog.Info("search host  return:%s", result)
+		js, _ := simplejson.NewJson([]byte(result))
+		hostData, _ := js.Map()
+		hostResult := hostData["result"].(bool)
+		if false == hostResult {
+			return hostInfo, errors.New(hostData["bk_error_msg"].(string))
+		}
+		hostDataArr := hostData["data"].(map[string]interface{})
+		hostInfo = append(hostInfo, hostDataArr["info"].([]interface{})...)
+		cursor, _ = hostDataArr["next_cursor"].(string)
+		if "" == cursor {
+			break
+		}
 	}
-	hostDataArr := hostData["data"].(map[string]interface{})
-	hostInfo = hostDataArr["info"].([]interface{})
-	hostCnt, _ := hostDataArr["count"].(json.Number).Int64()
-	if !hostResult || 0 == hostCnt {
+	if 0 == len(hostInfo) {
 		return hostInfo, errors.New("no host")
 	}
 
@@ -85,10 +96,10 @@ func GetHostData(appIDStr, hostIDStr, apiAddr string, header http.Header, kvMap
 	attrCond := make(map[string]interface{})
 	attrCond[common.BKObjIDField] = common.BKInnerObjIDHost
 	attrCond[common.BKOwnerIDField] = "0"
-	result, _ = httpRequest(url, attrCond, header)
+	result, _ := httpRequest(url, attrCond, header)
 	blog.Info("get host attr  url:%s", url)
 	blog.Info("get host attr return:%s", result)
-	js, _ = simplejson.NewJson([]byte(result))
+	js, _ := simplejson.NewJson([]byte(result))
 	hostAttr, _ := js.Map()
 	attrData := hostAttr["data"].([]interface{})
 	for _, j := range attrData {
@@ -146,6 +157,26 @@ func getPropertyTypeAliasName(propertyType string) (string, bool) {
 		name = common.FiledTypeBoolName
 	case common.FieldTypeTimeZone:
 		name = common.FiledTypeTimeZoneName
+	case common.FieldTypeFloat:
+		name = common.FieldTypeFloatName
+	case common.FieldTypeIPv4:
+		name = common.FieldTypeIPv4Name
+	case common.FieldTypeIPv6:
+		name = common.FieldTypeIPv6Name
+	case common.FieldTypeCIDR:
+		name = common.FieldTypeCIDRName
+	case common.FieldTypeURL:
+		name = common.FieldTypeURLName
+	case common.FieldTypeEmail:
+		name = common.FieldTypeEmailName
+	case common.FieldTypeList:
+		name = common.FieldTypeListName
+	case common.FieldTypeJSON:
+		name = common.FieldTypeJSONName
+	case common.FieldTypeComputed:
+		// the computed fields are not imported
+		name = common.FieldTypeComputedName
+		skip = true
 	default:
 		name = "not found field type"
 	}
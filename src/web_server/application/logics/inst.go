@@ -16,7 +16,6 @@ import (
 	"configcenter/src/common"
 	"configcenter/src/common/blog"
 	webCommon "configcenter/src/web_server/common"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -66,24 +65,30 @@ func GetInstData(ownerID, objID, instIDStr, apiAddr string, header http.Header,
 		common.BKOwnerIDField: ownerID,
 		common.BKObjIDField:   objID,
 	}
-	sInstCond["page"] = nil
 
-	// read insts
+	// read insts page by page with the cursor
 	url := apiAddr + fmt.Sprintf("/api/%s/inst/search/"+ownerID+"/"+objID, webCommon.API_VERSION)
-	result, _ := httpRequest(url, sInstCond, header)
-	blog.Info("search inst  url:%s", url)
-	blog.Info("search inst  return:%s", result)
-	js, _ := simplejson.NewJson([]byte(result))
-	instData, _ := js.Map()
-	instResult := instData["result"].(bool)
-	if !instResult {
-		return nil, errors.New(instData["bk_error_msg"].(string))
-	}
+	cursor := ""
+	for {
+		sInstCond["page"] = map[string]interface{}{"limit": exportPageSize, "cursor": cursor}
+		result, _ := httpRequest(url, sInstCond, header)
+		blog.Info("search inst  url:%s", url)
+		blog.Info("search inst  return:%s", result)
+		js, _ := simplejson.NewJson([]byte(result))
+		instData, _ := js.Map()
+		instResult := instData["result"].(bool)
+		if !instResult {
+			return nil, errors.New(instData["bk_error_msg"].(string))
+		}
 
-	instDataArr := instData["data"].(map[string]interface{})
-	instInfo = instDataArr["info"].([]interface{})
-	instCnt, _ := instDataArr["count"].(json.Number).Int64()
-	if !instResult || 0 == instCnt {
+		instDataArr := instData["data"].(map[string]interface{})
+		instInfo = append(instInfo, instDataArr["info"].([]interface{})...)
+		cursor, _ = instDataArr["next_cursor"].(string)
+		if "" == cursor {
+			break
+		}
+	}
+	if 0 == len(instInfo) {
 		return instInfo, errors.New("no inst")
 	}
 
@@ -92,10 +97,10 @@ func GetInstData(ownerID, objID, instIDStr, apiAddr string, header http.Header,
 	attrCond := make(map[string]interface{})
 	attrCond[common.BKObjIDField] = objID
 	attrCond[common.BKOwnerIDField] = ownerID
-	result, _ = httpRequest(url, attrCond, header)
+	result, _ := httpRequest(url, attrCond, header)
 	blog.Info("get inst attr  url:%s", url)
 	blog.Info("get inst attr return:%s", result)
-	js, _ = simplejson.NewJson([]byte(result))
+	js, _ := simplejson.NewJson([]byte(result))
 	instAttr, _ := js.Map()
 	attrData := instAttr["data"].([]interface{})
 	for _, j := range attrData {